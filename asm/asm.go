@@ -7,6 +7,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/llir/ll"
 	"github.com/llir/ll/ast"
 	"github.com/llir/llvm/ir"
 	"github.com/pkg/errors"
@@ -21,42 +22,132 @@ var (
 
 // ParseFile parses the given LLVM IR assembly file into an LLVM IR module.
 func ParseFile(path string) (*ir.Module, error) {
+	return (&Config{}).ParseFile(path)
+}
+
+// Parse parses the given LLVM IR assembly file into an LLVM IR module, reading
+// from r. An optional path to the source file may be specified for error
+// reporting.
+func Parse(path string, r io.Reader) (*ir.Module, error) {
+	return (&Config{}).Parse(path, r)
+}
+
+// ParseBytes parses the given LLVM IR assembly file into an LLVM IR module,
+// reading from b. An optional path to the source file may be specified for
+// error reporting.
+func ParseBytes(path string, b []byte) (*ir.Module, error) {
+	return (&Config{}).ParseBytes(path, b)
+}
+
+// ParseString parses the given LLVM IR assembly file into an LLVM IR module,
+// reading from content. An optional path to the source file may be specified
+// for error reporting.
+func ParseString(path, content string) (*ir.Module, error) {
+	return (&Config{}).ParseString(path, content)
+}
+
+// === [ Parser configuration ] ================================================
+
+// Config specifies optional behaviour of the LLVM IR assembly parser. The zero
+// value is the default configuration, as used by ParseFile, Parse, ParseBytes
+// and ParseString.
+type Config struct {
+	// Positions records the source spans of parsed IR entities if non-nil.
+	//
+	// Errors are annotated with source positions regardless of whether source
+	// spans are recorded.
+	Positions *Positions
+	// Diagnostics records the diagnostics (errors and warnings) reported while
+	// parsing if non-nil, sorted by source position. Warnings are recorded also
+	// for successfully parsed modules.
+	//
+	// Errors are returned as Diagnostics regardless of whether diagnostics are
+	// recorded.
+	Diagnostics *Diagnostics
+	// Lazy specifies whether to translate function bodies on first use, rather
+	// than while parsing; similar to lazy loading of LLVM bitcode. Top-level
+	// declarations and definitions (including function headers) are translated
+	// while parsing.
+	//
+	// The body of a lazily parsed function is translated on first use by
+	// ir.Func.LLString and ir.Func.AssignIDs, or explicitly by
+	// ir.Func.Materialize (or ir.Module.Materialize to translate all function
+	// bodies). Errors of function bodies are reported on materialization, and
+	// appended to the recorded diagnostics (if any).
+	//
+	// Function bodies of a lazily parsed module must not be materialized
+	// concurrently.
+	Lazy bool
+}
+
+// ParseFile parses the given LLVM IR assembly file into an LLVM IR module.
+func (cfg *Config) ParseFile(path string) (*ir.Module, error) {
 	buf, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	return ParseBytes(path, buf)
+	return cfg.ParseBytes(path, buf)
 }
 
 // Parse parses the given LLVM IR assembly file into an LLVM IR module, reading
 // from r. An optional path to the source file may be specified for error
 // reporting.
-func Parse(path string, r io.Reader) (*ir.Module, error) {
+func (cfg *Config) Parse(path string, r io.Reader) (*ir.Module, error) {
 	buf, err := ioutil.ReadAll(r)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	return ParseBytes(path, buf)
+	return cfg.ParseBytes(path, buf)
 }
 
 // ParseBytes parses the given LLVM IR assembly file into an LLVM IR module,
 // reading from b. An optional path to the source file may be specified for
 // error reporting.
-func ParseBytes(path string, b []byte) (*ir.Module, error) {
+func (cfg *Config) ParseBytes(path string, b []byte) (*ir.Module, error) {
 	content := string(b)
-	return ParseString(path, content)
+	return cfg.ParseString(path, content)
 }
 
 // ParseString parses the given LLVM IR assembly file into an LLVM IR module,
 // reading from content. An optional path to the source file may be specified
 // for error reporting.
-func ParseString(path, content string) (*ir.Module, error) {
+//
+// Syntax errors and semantic errors (e.g. undefined identifiers, type
+// mismatches and duplicate definitions) are returned as Diagnostics.
+func (cfg *Config) ParseString(path, content string) (*ir.Module, error) {
+	f := newFile(path, content)
 	parseStart := time.Now()
-	tree, err := ast.Parse(path, content)
+	src, opaquePtrs := rewriteOpaquePointers(content)
+	tree, err := ast.Parse(path, src)
 	if err != nil {
+		if e, ok := err.(ll.SyntaxError); ok {
+			diags := Diagnostics{{Pos: f.pos(e.Offset), Severity: SeverityError, Msg: "syntax error"}}
+			cfg.recordDiagnostics(diags)
+			return nil, diags
+		}
 		return nil, errors.Wrapf(err, "unable to parse %q into an AST", path)
 	}
 	dbg.Println("parsing into AST took:", time.Since(parseStart))
 	root := ast.ToLlvmNode(tree.Root())
-	return translate(root.(*ast.Module))
+	gen := newGenerator(f)
+	gen.positions = cfg.Positions
+	gen.lazy = cfg.Lazy
+	gen.opaquePtrs = opaquePtrs
+	m, err := gen.translate(root.(*ast.Module))
+	diags := gen.diagnostics(err)
+	cfg.recordDiagnostics(diags)
+	if err != nil {
+		return nil, diags
+	}
+	// Record diagnostics of lazily parsed function bodies on materialization.
+	gen.diags = cfg.Diagnostics
+	return m, nil
+}
+
+// recordDiagnostics records the given diagnostics, if diagnostics are
+// recorded.
+func (cfg *Config) recordDiagnostics(diags Diagnostics) {
+	if cfg.Diagnostics != nil {
+		*cfg.Diagnostics = diags
+	}
 }
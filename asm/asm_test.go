@@ -1,13 +1,19 @@
 package asm
 
 import (
+	"fmt"
 	"io/ioutil"
 	"log"
 	"path/filepath"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/llir/ll/ast"
 	"github.com/llir/llvm/internal/osutil"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/metadata"
+	"github.com/pkg/errors"
 )
 
 func TestParseFile(t *testing.T) {
@@ -41,6 +47,9 @@ func TestParseFile(t *testing.T) {
 		// global alignment.
 		{path: "testdata/global_align.ll"},
 
+		// opaque pointers.
+		{path: "testdata/opaque_pointer.ll"},
+
 		// LLVM IR compatibility.
 		{path: "../testdata/llvm/test/Bitcode/compatibility.ll"},
 
@@ -575,3 +584,328 @@ func TestParseFile(t *testing.T) {
 		}
 	}
 }
+
+func TestTranslateConcurrent(t *testing.T) {
+	// Translation using several goroutines must produce output identical to
+	// that of sequential translation.
+	golden := []struct {
+		path string
+		// Error message; or empty if translation succeeds.
+		err string
+	}{
+		{path: "testdata/concurrent.ll"},
+		{path: "testdata/invalid/concurrent_error.ll", err: `testdata/invalid/concurrent_error.ll:5:10: unable to locate local identifier "%x" of "@f" (and 1 more error)`},
+		{path: "testdata/inst_other.ll"},
+		{path: "testdata/terminator.ll"},
+		{path: "../ir/testdata/clone.ll"},
+		{path: "../ir/transform/testdata/inline.ll"},
+	}
+	for _, g := range golden {
+		buf, err := ioutil.ReadFile(g.path)
+		if err != nil {
+			t.Errorf("unable to read %q; %+v", g.path, err)
+			continue
+		}
+		tree, err := ast.Parse(g.path, string(buf))
+		if err != nil {
+			t.Errorf("unable to parse %q into AST; %+v", g.path, err)
+			continue
+		}
+		old := ast.ToLlvmNode(tree.Root()).(*ast.Module)
+		var want string
+		// Repeat concurrent translation to exercise different schedules.
+		for _, workers := range []int{1, 4, 4, 4, 16} {
+			gen := newGenerator(newFile(g.path, string(buf)))
+			gen.workers = workers
+			m, err := gen.translate(old)
+			var got string
+			if err != nil {
+				got = errors.Cause(err).Error()
+				if g.err == "" {
+					t.Errorf("%q: unable to translate AST to IR using %d workers; %+v", g.path, workers, err)
+					break
+				}
+			} else {
+				got = m.String()
+			}
+			if workers == 1 {
+				want = got
+				if g.err != "" && got != g.err {
+					t.Errorf("%q: error mismatch; expected %q, got %q", g.path, g.err, got)
+				}
+				continue
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("%q: output using %d workers mismatch (-want +got):\n%s", g.path, workers, diff)
+				break
+			}
+		}
+	}
+}
+
+func TestPositions(t *testing.T) {
+	const path = "testdata/positions.ll"
+	pos := NewPositions()
+	cfg := &Config{Positions: pos}
+	m, err := cfg.ParseFile(path)
+	if err != nil {
+		t.Fatalf("unable to parse %q; %+v", path, err)
+	}
+	f := m.Funcs[0]
+	entry, exit := f.Blocks[0], f.Blocks[1]
+	inlineTuple := m.MetadataDefs[4].(*metadata.Tuple)
+	golden := []struct {
+		// IR entity.
+		v interface{}
+		// Start and end position of source span.
+		start, end string
+	}{
+		{v: m.Globals[0], start: "testdata/positions.ll:3:1", end: "testdata/positions.ll:3:19"},
+		{v: f, start: "testdata/positions.ll:5:1", end: "testdata/positions.ll:12:2"},
+		{v: entry, start: "testdata/positions.ll:6:1", end: "testdata/positions.ll:8:17"},
+		{v: entry.Insts[0], start: "testdata/positions.ll:7:3", end: "testdata/positions.ll:7:30"},
+		{v: entry.Term, start: "testdata/positions.ll:8:3", end: "testdata/positions.ll:8:17"},
+		{v: exit, start: "testdata/positions.ll:10:1", end: "testdata/positions.ll:11:13"},
+		{v: exit.Term, start: "testdata/positions.ll:11:3", end: "testdata/positions.ll:11:13"},
+		{v: m.NamedMetadataDefs["llvm.dbg.cu"], start: "testdata/positions.ll:14:1", end: "testdata/positions.ll:14:21"},
+		{v: m.MetadataDefs[0], start: "testdata/positions.ll:16:1", end: "testdata/positions.ll:16:87"},
+		{v: m.MetadataDefs[5], start: "testdata/positions.ll:21:1", end: "testdata/positions.ll:21:48"},
+		// Metadata node literals.
+		{v: m.MetadataDefs[2].(*metadata.DISubroutineType).Types, start: "testdata/positions.ll:18:31", end: "testdata/positions.ll:18:38"},
+		{v: inlineTuple.Fields[1], start: "testdata/positions.ll:20:19", end: "testdata/positions.ll:20:50"},
+	}
+	for _, g := range golden {
+		span, ok := pos.Span(g.v)
+		if !ok {
+			t.Errorf("unable to locate source span of %T", g.v)
+			continue
+		}
+		if got := span.Start.String(); got != g.start {
+			t.Errorf("start position of %T mismatch; expected %q, got %q", g.v, g.start, got)
+		}
+		if got := span.End.String(); got != g.end {
+			t.Errorf("end position of %T mismatch; expected %q, got %q", g.v, g.end, got)
+		}
+	}
+	// Source positions are not recorded by default.
+	if _, err := (&Config{}).ParseFile(path); err != nil {
+		t.Fatalf("unable to parse %q; %+v", path, err)
+	}
+	if n := pos.Len(); n != 16 {
+		t.Errorf("number of recorded source spans mismatch; expected %d, got %d", 16, n)
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	golden := []struct {
+		// LLVM IR assembly source.
+		src string
+		// Error message.
+		err string
+	}{
+		// Syntax error.
+		{src: "define void @f() {\n\tret void 42\n}\n", err: `test.ll:2:11: syntax error`},
+		// Undefined local identifier.
+		{src: "define i32 @f() {\n\t%x = add i32 1, 2\n\tret i32 %y\n}\n", err: `test.ll:3:10: unable to locate local identifier "%y" of "@f"`},
+		// Duplicate local identifier.
+		{src: "define i32 @f() {\n\t%x = add i32 1, 2\n\t%x = add i32 3, 4\n\tret i32 %x\n}\n", err: "test.ll:3:2: local identifier \"%x\" already present; prev `i32 %x`, new `i32 %x`"},
+		// Duplicate global identifier.
+		{src: "@x = global i32 1\n@x = global i32 2\n", err: "test.ll:2:1: global identifier \"@x\" already present; prev `@x = global i32 1\n`, new `@x = global i32 2\n`"},
+		// Undefined global identifier in constant expression.
+		{src: "@x = global i64 ptrtoint (i32* @y to i64)\n", err: `test.ll:1:32: unable to locate global identifier "@y"`},
+		// Type mismatch of local identifier.
+		{src: "define i64 @f(i32 %a) {\n\t%b = add i64 %a, 1\n\tret i64 %b\n}\n", err: `test.ll:2:15: local identifier "%a" of "@f" type mismatch; expected "i64", got "i32"`},
+		// Type mismatch of constant.
+		{src: "@x = global i32 1\n@y = global i32* bitcast (i32* @x to i8*)\n", err: `test.ll:2:18: constant expression type mismatch; expected "i8*", got "i32*"`},
+		// Undefined named type.
+		{src: "@x = global %T zeroinitializer\n", err: `test.ll:1:13: unable to locate type definition of named type "%T"`},
+		// Undefined basic block of blockaddress constant.
+		{src: "@x = global i8* blockaddress(@f, %foo)\n\ndefine void @f() {\n\tret void\n}\n", err: `test.ll:1:17: unable to locate basic block "%foo" of function "@f"`},
+		// Undefined metadata ID.
+		{src: "define void @f() {\n\tret void, !dbg !42\n}\n", err: `test.ll:2:17: unable to locate metadata ID "!42"`},
+	}
+	for _, g := range golden {
+		_, err := ParseString("test.ll", g.src)
+		if err == nil {
+			t.Errorf("expected error for %q, got nil", g.src)
+			continue
+		}
+		if got := err.Error(); got != g.err {
+			t.Errorf("error mismatch; expected %q, got %q", g.err, got)
+		}
+		if _, ok := err.(Diagnostics); !ok {
+			t.Errorf("expected error of type Diagnostics, got %T", err)
+		}
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	golden := []struct {
+		src string
+		// Diagnostics in the format "line:col: severity: message".
+		diags []string
+		// Error message; or empty if parsing succeeds.
+		err string
+	}{
+		// Semantic errors of global variables, functions and metadata
+		// definitions.
+		{
+			src: "@x = global i32 1\n@y = global i32* bitcast (i32* @x to i8*)\n\ndefine i32 @f() {\n\t%a = add i32 %u, 1\n\t%b = add i32 %a, %v\n\tret i32 %b\n}\n\ndefine void @g() {\n\tret void, !dbg !42\n}\n\n!0 = !{!43}\n",
+			diags: []string{
+				`2:18: error: constant expression type mismatch; expected "i8*", got "i32*"`,
+				`5:15: error: unable to locate local identifier "%u" of "@f"`,
+				`6:19: error: unable to locate local identifier "%v" of "@f"`,
+				`11:17: error: unable to locate metadata ID "!42"`,
+				`14:8: error: unable to locate metadata ID "!43"`,
+			},
+			err: `test.ll:2:18: constant expression type mismatch; expected "i8*", got "i32*" (and 4 more errors)`,
+		},
+		// Duplicate definitions.
+		{
+			src: "@x = global i32 1\n@x = global i32 2\n\ndefine void @f() {\n\t%a = add i32 1, 2\n\t%a = add i32 3, 4\n\tret void\n}\n\ndefine void @f() {\n\tret void\n}\n",
+			diags: []string{
+				"2:1: error: global identifier \"@x\" already present; prev `@x = global i32 1\n`, new `@x = global i32 2\n\n`",
+				"10:1: error: global identifier \"@f\" already present; prev `define void @f() {\n\t%a = add i32 1, 2\n\t%a = add i32 3, 4\n\tret void\n}`, new `define void @f() {\n\tret void\n}`",
+			},
+			err: "test.ll:2:1: global identifier \"@x\" already present; prev `@x = global i32 1\n`, new `@x = global i32 2\n\n` (and 1 more error)",
+		},
+		// Syntax error.
+		{
+			src:   "define void @f() {\n\tret void 42\n}\n",
+			diags: []string{"2:11: error: syntax error"},
+			err:   "test.ll:2:11: syntax error",
+		},
+		// Warnings are reported for successfully parsed modules.
+		{
+			src:   "define void @f() #0 {\n\tret void\n}\n",
+			diags: []string{`1:18: warning: unable to locate attribute group ID "#0"; empty attribute group definition added`},
+		},
+	}
+	for _, g := range golden {
+		var diags Diagnostics
+		cfg := &Config{Diagnostics: &diags}
+		_, err := cfg.ParseString("test.ll", g.src)
+		switch {
+		case err == nil && len(g.err) > 0:
+			t.Errorf("expected error for %q, got nil", g.src)
+		case err != nil && len(g.err) == 0:
+			t.Errorf("unable to parse %q; %+v", g.src, err)
+		case err != nil && err.Error() != g.err:
+			t.Errorf("error mismatch; expected %q, got %q", g.err, err.Error())
+		}
+		var got []string
+		for _, d := range diags {
+			got = append(got, fmt.Sprintf("%d:%d: %v: %s", d.Pos.Line, d.Pos.Col, d.Severity, d.Msg))
+		}
+		if diff := cmp.Diff(g.diags, got); diff != "" {
+			t.Errorf("diagnostics of %q mismatch (-want +got):\n%s", g.src, diff)
+		}
+	}
+}
+
+func TestLazy(t *testing.T) {
+	// Lazily parsed modules must produce output identical to that of eagerly
+	// parsed modules.
+	golden := []struct {
+		path string
+	}{
+		{path: "testdata/lazy.ll"},
+		{path: "testdata/concurrent.ll"},
+		{path: "testdata/inst_other.ll"},
+		{path: "testdata/terminator.ll"},
+		{path: "../ir/testdata/clone.ll"},
+		{path: "../ir/transform/testdata/inline.ll"},
+	}
+	for _, g := range golden {
+		want, err := ParseFile(g.path)
+		if err != nil {
+			t.Errorf("unable to parse %q; %+v", g.path, err)
+			continue
+		}
+		cfg := &Config{Lazy: true}
+		m, err := cfg.ParseFile(g.path)
+		if err != nil {
+			t.Errorf("unable to lazily parse %q; %+v", g.path, err)
+			continue
+		}
+		if diff := cmp.Diff(want.String(), m.String()); diff != "" {
+			t.Errorf("output of %q mismatch (-want +got):\n%s", g.path, diff)
+		}
+		for _, f := range m.Funcs {
+			if !f.IsMaterialized() {
+				t.Errorf("%q: function %s not materialized after use", g.path, f.Ident())
+			}
+		}
+	}
+}
+
+func TestLazyMaterialize(t *testing.T) {
+	const path = "testdata/lazy.ll"
+	cfg := &Config{Lazy: true}
+	m, err := cfg.ParseFile(path)
+	if err != nil {
+		t.Fatalf("unable to parse %q; %+v", path, err)
+	}
+	ext, a, b, c := m.Funcs[0], m.Funcs[1], m.Funcs[2], m.Funcs[3]
+	golden := []struct {
+		// Function to materialize; or nil to check initial state.
+		f *ir.Func
+		// Materialized state of @ext, @a, @b and @c after materialization.
+		want []bool
+	}{
+		{f: nil, want: []bool{true, false, false, false}},
+		// The body of @c is referenced by a blockaddress constant in @b.
+		{f: b, want: []bool{true, false, true, true}},
+		{f: a, want: []bool{true, true, true, true}},
+	}
+	for _, g := range golden {
+		if g.f != nil {
+			if err := g.f.Materialize(); err != nil {
+				t.Errorf("unable to materialize function %s; %+v", g.f.Ident(), err)
+				continue
+			}
+		}
+		var got []bool
+		for _, f := range []*ir.Func{ext, a, b, c} {
+			got = append(got, f.IsMaterialized())
+		}
+		if diff := cmp.Diff(g.want, got); diff != "" {
+			t.Errorf("materialized state mismatch (-want +got):\n%s", diff)
+		}
+	}
+	// blockaddress constants refer to the basic blocks of materialized function
+	// bodies.
+	ret := b.Blocks[0].Term.(*ir.TermRet)
+	if got, want := ret.X.(*constant.BlockAddress).Block, c.Blocks[2]; got != want {
+		t.Errorf("basic block of blockaddress constant mismatch; expected %p, got %p", want, got)
+	}
+}
+
+func TestLazyErrors(t *testing.T) {
+	// Errors of function bodies are reported on materialization.
+	const path = "testdata/invalid/concurrent_error.ll"
+	var diags Diagnostics
+	cfg := &Config{Lazy: true, Diagnostics: &diags}
+	m, err := cfg.ParseFile(path)
+	if err != nil {
+		t.Fatalf("unable to parse %q; %+v", path, err)
+	}
+	if len(diags) != 0 {
+		t.Errorf("expected no diagnostics before materialization, got %v", diags)
+	}
+	f := m.Funcs[0]
+	err = f.Materialize()
+	want := `testdata/invalid/concurrent_error.ll:5:10: unable to locate local identifier "%x" of "@f"`
+	if err == nil {
+		t.Fatalf("expected error on materialization of function %s, got nil", f.Ident())
+	}
+	if got := err.Error(); got != want {
+		t.Errorf("error mismatch; expected %q, got %q", want, got)
+	}
+	if f.IsMaterialized() || len(f.Blocks) != 0 {
+		t.Errorf("expected function %s to remain unmaterialized after error", f.Ident())
+	}
+	if len(diags) != 1 || diags[0].String() != want {
+		t.Errorf("recorded diagnostics mismatch; expected [%s], got %v", want, diags)
+	}
+}
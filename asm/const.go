@@ -50,7 +50,7 @@ func (gen *generator) irConstant(t types.Type, old ast.Constant) (constant.Const
 		ident := globalIdent(*old)
 		c, ok := gen.new.globals[ident]
 		if !ok {
-			return nil, errors.Errorf("unable to locate global identifier %q", ident.Ident())
+			return nil, gen.errorf(old, "unable to locate global identifier %q", ident.Ident())
 		}
 		return c, nil
 	case ast.ConstantExpr:
@@ -79,10 +79,10 @@ func (gen *generator) irTypeConst(old ast.TypeConst) (constant.Constant, error)
 func (gen *generator) irBoolConst(t types.Type, old *ast.BoolConst) (*constant.Int, error) {
 	typ, ok := t.(*types.IntType)
 	if !ok {
-		return nil, errors.Errorf("invalid type of boolean constant; expected *types.IntType, got %T", t)
+		return nil, gen.errorf(old, "invalid type of boolean constant; expected *types.IntType, got %T", t)
 	}
 	if !typ.Equal(types.I1) {
-		return nil, errors.Errorf("boolean type mismatch; expected %q, got %q", types.I1, typ)
+		return nil, gen.errorf(old, "boolean type mismatch; expected %q, got %q", types.I1, typ)
 	}
 	return constant.NewBool(boolLit(old.BoolLit())), nil
 }
@@ -95,7 +95,7 @@ func (gen *generator) irIntConst(t types.Type, old *ast.IntConst) (*constant.Int
 	typ, ok := t.(*types.IntType)
 	if !ok {
 		line, col := old.LineColumn()
-		return nil, errors.Errorf("%d:%d: invalid type of integer constant; expected *types.IntType, got %T", line, col, t)
+		return nil, gen.errorf(old, "%d:%d: invalid type of integer constant; expected *types.IntType, got %T", line, col, t)
 	}
 	s := old.IntLit().Text()
 	return constant.NewIntFromString(typ, s)
@@ -108,7 +108,7 @@ func (gen *generator) irIntConst(t types.Type, old *ast.IntConst) (*constant.Int
 func (gen *generator) irFloatConst(t types.Type, old *ast.FloatConst) (*constant.Float, error) {
 	typ, ok := t.(*types.FloatType)
 	if !ok {
-		return nil, errors.Errorf("invalid type of floating-point constant; expected *types.FloatType, got %T", t)
+		return nil, gen.errorf(old, "invalid type of floating-point constant; expected *types.FloatType, got %T", t)
 	}
 	s := old.FloatLit().Text()
 	return constant.NewFloatFromString(typ, s)
@@ -121,7 +121,7 @@ func (gen *generator) irFloatConst(t types.Type, old *ast.FloatConst) (*constant
 func (gen *generator) irNullConst(t types.Type, old *ast.NullConst) (*constant.Null, error) {
 	typ, ok := t.(*types.PointerType)
 	if !ok {
-		return nil, errors.Errorf("invalid type of null pointer constant; expected *types.PointerType, got %T", t)
+		return nil, gen.errorf(old, "invalid type of null pointer constant; expected *types.PointerType, got %T", t)
 	}
 	return constant.NewNull(typ), nil
 }
@@ -132,7 +132,7 @@ func (gen *generator) irNullConst(t types.Type, old *ast.NullConst) (*constant.N
 // token constant.
 func (gen *generator) irNoneConst(t types.Type, old *ast.NoneConst) (constant.Constant, error) {
 	if !t.Equal(types.Token) {
-		return nil, errors.Errorf("invalid type of none token constant; expected %q, got %q", types.Token, t)
+		return nil, gen.errorf(old, "invalid type of none token constant; expected %q, got %q", types.Token, t)
 	}
 	return constant.None, nil
 }
@@ -144,7 +144,7 @@ func (gen *generator) irNoneConst(t types.Type, old *ast.NoneConst) (constant.Co
 func (gen *generator) irStructConst(t types.Type, old *ast.StructConst) (*constant.Struct, error) {
 	typ, ok := t.(*types.StructType)
 	if !ok {
-		return nil, errors.Errorf("invalid type of struct constant; expected *types.StructType, got %T", t)
+		return nil, gen.errorf(old, "invalid type of struct constant; expected *types.StructType, got %T", t)
 	}
 	var fields []constant.Constant
 	if oldFields := old.Fields(); len(oldFields) > 0 {
@@ -168,13 +168,13 @@ func (gen *generator) irStructConst(t types.Type, old *ast.StructConst) (*consta
 func (gen *generator) irArrayConst(t types.Type, old *ast.ArrayConst) (*constant.Array, error) {
 	typ, ok := t.(*types.ArrayType)
 	if !ok {
-		return nil, errors.Errorf("invalid type of array constant; expected *types.ArrayType, got %T", t)
+		return nil, gen.errorf(old, "invalid type of array constant; expected *types.ArrayType, got %T", t)
 	}
 	oldElems := old.Elems()
 	if len(oldElems) == 0 {
 		typ := types.NewArray(0, typ.ElemType)
 		if !t.Equal(typ) {
-			return nil, errors.Errorf("array type mismatch; expected %q, got %q", typ, t)
+			return nil, gen.errorf(old, "array type mismatch; expected %q, got %q", typ, t)
 		}
 		return &constant.Array{Typ: typ}, nil
 	}
@@ -196,7 +196,7 @@ func (gen *generator) irCharArrayConst(t types.Type, old *ast.CharArrayConst) (*
 	data := enc.Unquote(old.Val().Text())
 	c := constant.NewCharArray(data)
 	if !t.Equal(c.Typ) {
-		return nil, errors.Errorf("character array type mismatch; expected %q, got %q (unquoted_data=`%s`, orig_data=`%s`)", c.Typ, t, data, old.Val().Text())
+		return nil, gen.errorf(old, "character array type mismatch; expected %q, got %q (unquoted_data=`%s`, orig_data=`%s`)", c.Typ, t, data, old.Val().Text())
 	}
 	return c, nil
 }
@@ -208,11 +208,11 @@ func (gen *generator) irCharArrayConst(t types.Type, old *ast.CharArrayConst) (*
 func (gen *generator) irVectorConst(t types.Type, old *ast.VectorConst) (*constant.Vector, error) {
 	typ, ok := t.(*types.VectorType)
 	if !ok {
-		return nil, errors.Errorf("invalid type of vector constant; expected *types.VectorType, got %T", t)
+		return nil, gen.errorf(old, "invalid type of vector constant; expected *types.VectorType, got %T", t)
 	}
 	oldElems := old.Elems()
 	if len(oldElems) == 0 {
-		return nil, errors.New("zero element vector is illegal")
+		return nil, gen.errorf(old, "zero element vector is illegal")
 	}
 	elems := make([]constant.Constant, len(oldElems))
 	for i, oldElem := range oldElems {
@@ -235,11 +235,11 @@ func (gen *generator) irBlockAddressConst(t types.Type, old *ast.BlockAddressCon
 	funcName := globalIdent(old.Func())
 	v, ok := gen.new.globals[funcName]
 	if !ok {
-		return nil, errors.Errorf("unable to locate global identifier %q", funcName)
+		return nil, gen.errorf(old, "unable to locate global identifier %q", funcName)
 	}
 	f, ok := v.(*ir.Func)
 	if !ok {
-		return nil, errors.Errorf("invalid function type; expected *ir.Func, got %T", v)
+		return nil, gen.errorf(old, "invalid function type; expected *ir.Func, got %T", v)
 	}
 	// Basic block.
 	blockIdent := localIdent(old.Block())
@@ -250,9 +250,11 @@ func (gen *generator) irBlockAddressConst(t types.Type, old *ast.BlockAddressCon
 		LocalIdent: blockIdent,
 	}
 	c := constant.NewBlockAddress(f, block)
-	gen.todo = append(gen.todo, c)
+	gen.mu.Lock()
+	gen.todo = append(gen.todo, blockAddressFix{c: c, old: old})
+	gen.mu.Unlock()
 	if typ := c.Type(); !t.Equal(typ) {
-		return nil, errors.Errorf("blockaddress constant type mismatch; expected %q, got %q", typ, t)
+		return nil, gen.errorf(old, "blockaddress constant type mismatch; expected %q, got %q", typ, t)
 	}
 	return c, nil
 }
@@ -266,18 +268,18 @@ func (gen *generator) irDSOLocalEquivalentConst(t types.Type, old *ast.DSOLocalE
 	funcName := globalIdent(old.Func())
 	v, ok := gen.new.globals[funcName]
 	if !ok {
-		return nil, errors.Errorf("unable to locate global identifier %q", funcName)
+		return nil, gen.errorf(old, "unable to locate global identifier %q", funcName)
 	}
 	var f constant.Constant
 	switch v := v.(type) {
 	case *ir.Func, *ir.IFunc, *ir.Alias:
 		f = v
 	default:
-		return nil, errors.Errorf("invalid function type; expected *ir.Func or *ir.IFunc, got %T", v)
+		return nil, gen.errorf(old, "invalid function type; expected *ir.Func or *ir.IFunc, got %T", v)
 	}
 	c := constant.NewDSOLocalEquivalent(f)
 	if typ := c.Type(); !t.Equal(typ) {
-		return nil, errors.Errorf("dso_local_equivalent constant type mismatch; expected %q, got %q", typ, t)
+		return nil, gen.errorf(old, "dso_local_equivalent constant type mismatch; expected %q, got %q", typ, t)
 	}
 	return c, nil
 }
@@ -291,18 +293,18 @@ func (gen *generator) irNoCFIConst(t types.Type, old *ast.NoCFIConst) (*constant
 	funcName := globalIdent(old.Func())
 	v, ok := gen.new.globals[funcName]
 	if !ok {
-		return nil, errors.Errorf("unable to locate global identifier %q", funcName)
+		return nil, gen.errorf(old, "unable to locate global identifier %q", funcName)
 	}
 	var f constant.Constant
 	switch v := v.(type) {
 	case *ir.Func, *ir.IFunc, *ir.Alias:
 		f = v
 	default:
-		return nil, errors.Errorf("invalid function type; expected *ir.Func or *ir.IFunc, got %T", v)
+		return nil, gen.errorf(old, "invalid function type; expected *ir.Func or *ir.IFunc, got %T", v)
 	}
 	c := constant.NewNoCFI(f)
 	if typ := c.Type(); !t.Equal(typ) {
-		return nil, errors.Errorf("no_cfi constant type mismatch; expected %q, got %q", typ, t)
+		return nil, gen.errorf(old, "no_cfi constant type mismatch; expected %q, got %q", typ, t)
 	}
 	return c, nil
 }
@@ -102,7 +102,7 @@ func (gen *generator) irFNegExpr(t types.Type, old *ast.FNegExpr) (*constant.Exp
 	}
 	expr := constant.NewFNeg(x)
 	if !t.Equal(expr.Typ) {
-		return nil, errors.Errorf("constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
+		return nil, gen.errorf(old, "constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
 	}
 	return expr, nil
 }
@@ -128,7 +128,7 @@ func (gen *generator) irAddExpr(t types.Type, old *ast.AddExpr) (*constant.ExprA
 	// (optional) Overflow flags.
 	expr.OverflowFlags = irOverflowFlags(old.OverflowFlags())
 	if !t.Equal(expr.Typ) {
-		return nil, errors.Errorf("constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
+		return nil, gen.errorf(old, "constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
 	}
 	return expr, nil
 }
@@ -152,7 +152,7 @@ func (gen *generator) irSubExpr(t types.Type, old *ast.SubExpr) (*constant.ExprS
 	// (optional) Overflow flags.
 	expr.OverflowFlags = irOverflowFlags(old.OverflowFlags())
 	if !t.Equal(expr.Typ) {
-		return nil, errors.Errorf("constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
+		return nil, gen.errorf(old, "constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
 	}
 	return expr, nil
 }
@@ -176,7 +176,7 @@ func (gen *generator) irMulExpr(t types.Type, old *ast.MulExpr) (*constant.ExprM
 	// (optional) Overflow flags.
 	expr.OverflowFlags = irOverflowFlags(old.OverflowFlags())
 	if !t.Equal(expr.Typ) {
-		return nil, errors.Errorf("constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
+		return nil, gen.errorf(old, "constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
 	}
 	return expr, nil
 }
@@ -202,7 +202,7 @@ func (gen *generator) irShlExpr(t types.Type, old *ast.ShlExpr) (*constant.ExprS
 	// (optional) Overflow flags.
 	expr.OverflowFlags = irOverflowFlags(old.OverflowFlags())
 	if !t.Equal(expr.Typ) {
-		return nil, errors.Errorf("constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
+		return nil, gen.errorf(old, "constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
 	}
 	return expr, nil
 }
@@ -226,7 +226,7 @@ func (gen *generator) irLShrExpr(t types.Type, old *ast.LShrExpr) (*constant.Exp
 	// (optional) Exact.
 	_, expr.Exact = old.Exact()
 	if !t.Equal(expr.Typ) {
-		return nil, errors.Errorf("constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
+		return nil, gen.errorf(old, "constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
 	}
 	return expr, nil
 }
@@ -250,7 +250,7 @@ func (gen *generator) irAShrExpr(t types.Type, old *ast.AShrExpr) (*constant.Exp
 	// (optional) Exact.
 	_, expr.Exact = old.Exact()
 	if !t.Equal(expr.Typ) {
-		return nil, errors.Errorf("constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
+		return nil, gen.errorf(old, "constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
 	}
 	return expr, nil
 }
@@ -272,7 +272,7 @@ func (gen *generator) irAndExpr(t types.Type, old *ast.AndExpr) (*constant.ExprA
 	}
 	expr := constant.NewAnd(x, y)
 	if !t.Equal(expr.Typ) {
-		return nil, errors.Errorf("constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
+		return nil, gen.errorf(old, "constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
 	}
 	return expr, nil
 }
@@ -294,7 +294,7 @@ func (gen *generator) irOrExpr(t types.Type, old *ast.OrExpr) (*constant.ExprOr,
 	}
 	expr := constant.NewOr(x, y)
 	if !t.Equal(expr.Typ) {
-		return nil, errors.Errorf("constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
+		return nil, gen.errorf(old, "constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
 	}
 	return expr, nil
 }
@@ -316,7 +316,7 @@ func (gen *generator) irXorExpr(t types.Type, old *ast.XorExpr) (*constant.ExprX
 	}
 	expr := constant.NewXor(x, y)
 	if !t.Equal(expr.Typ) {
-		return nil, errors.Errorf("constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
+		return nil, gen.errorf(old, "constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
 	}
 	return expr, nil
 }
@@ -340,7 +340,7 @@ func (gen *generator) irExtractElementExpr(t types.Type, old *ast.ExtractElement
 	}
 	expr := constant.NewExtractElement(x, index)
 	if !t.Equal(expr.Typ) {
-		return nil, errors.Errorf("constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
+		return nil, gen.errorf(old, "constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
 	}
 	return expr, nil
 }
@@ -367,7 +367,7 @@ func (gen *generator) irInsertElementExpr(t types.Type, old *ast.InsertElementEx
 	}
 	expr := constant.NewInsertElement(x, elem, index)
 	if !t.Equal(expr.Typ) {
-		return nil, errors.Errorf("constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
+		return nil, gen.errorf(old, "constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
 	}
 	return expr, nil
 }
@@ -394,7 +394,7 @@ func (gen *generator) irShuffleVectorExpr(t types.Type, old *ast.ShuffleVectorEx
 	}
 	expr := constant.NewShuffleVector(x, y, mask)
 	if !t.Equal(expr.Typ) {
-		return nil, errors.Errorf("constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
+		return nil, gen.errorf(old, "constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
 	}
 	return expr, nil
 }
@@ -432,10 +432,10 @@ func (gen *generator) irGetElementPtrExpr(t types.Type, old *ast.GetElementPtrEx
 	// (optional) In-bounds.
 	_, expr.InBounds = old.InBounds()
 	if !elemType.Equal(expr.ElemType) {
-		return nil, errors.Errorf("constant expression element type mismatch; expected %q, got %q", expr.ElemType, elemType)
+		return nil, gen.errorf(old, "constant expression element type mismatch; expected %q, got %q", expr.ElemType, elemType)
 	}
 	if !t.Equal(expr.Typ) {
-		return nil, errors.Errorf("constant expression type mismatch of `%v`; expected %q, got %q", expr, expr.Typ, t)
+		return nil, gen.errorf(old, "constant expression type mismatch of `%v`; expected %q, got %q", expr, expr.Typ, t)
 	}
 	return expr, nil
 }
@@ -473,7 +473,7 @@ func (gen *generator) irTruncExpr(t types.Type, old *ast.TruncExpr) (*constant.E
 	}
 	expr := constant.NewTrunc(from, to)
 	if !t.Equal(expr.To) {
-		return nil, errors.Errorf("constant expression type mismatch; expected %q, got %q", expr.To, t)
+		return nil, gen.errorf(old, "constant expression type mismatch; expected %q, got %q", expr.To, t)
 	}
 	return expr, nil
 }
@@ -495,7 +495,7 @@ func (gen *generator) irZExtExpr(t types.Type, old *ast.ZExtExpr) (*constant.Exp
 	}
 	expr := constant.NewZExt(from, to)
 	if !t.Equal(expr.To) {
-		return nil, errors.Errorf("constant expression type mismatch; expected %q, got %q", expr.To, t)
+		return nil, gen.errorf(old, "constant expression type mismatch; expected %q, got %q", expr.To, t)
 	}
 	return expr, nil
 }
@@ -517,7 +517,7 @@ func (gen *generator) irSExtExpr(t types.Type, old *ast.SExtExpr) (*constant.Exp
 	}
 	expr := constant.NewSExt(from, to)
 	if !t.Equal(expr.To) {
-		return nil, errors.Errorf("constant expression type mismatch; expected %q, got %q", expr.To, t)
+		return nil, gen.errorf(old, "constant expression type mismatch; expected %q, got %q", expr.To, t)
 	}
 	return expr, nil
 }
@@ -539,7 +539,7 @@ func (gen *generator) irFPTruncExpr(t types.Type, old *ast.FPTruncExpr) (*consta
 	}
 	expr := constant.NewFPTrunc(from, to)
 	if !t.Equal(expr.To) {
-		return nil, errors.Errorf("constant expression type mismatch; expected %q, got %q", expr.To, t)
+		return nil, gen.errorf(old, "constant expression type mismatch; expected %q, got %q", expr.To, t)
 	}
 	return expr, nil
 }
@@ -561,7 +561,7 @@ func (gen *generator) irFPExtExpr(t types.Type, old *ast.FPExtExpr) (*constant.E
 	}
 	expr := constant.NewFPExt(from, to)
 	if !t.Equal(expr.To) {
-		return nil, errors.Errorf("constant expression type mismatch; expected %q, got %q", expr.To, t)
+		return nil, gen.errorf(old, "constant expression type mismatch; expected %q, got %q", expr.To, t)
 	}
 	return expr, nil
 }
@@ -583,7 +583,7 @@ func (gen *generator) irFPToUIExpr(t types.Type, old *ast.FPToUIExpr) (*constant
 	}
 	expr := constant.NewFPToUI(from, to)
 	if !t.Equal(expr.To) {
-		return nil, errors.Errorf("constant expression type mismatch; expected %q, got %q", expr.To, t)
+		return nil, gen.errorf(old, "constant expression type mismatch; expected %q, got %q", expr.To, t)
 	}
 	return expr, nil
 }
@@ -605,7 +605,7 @@ func (gen *generator) irFPToSIExpr(t types.Type, old *ast.FPToSIExpr) (*constant
 	}
 	expr := constant.NewFPToSI(from, to)
 	if !t.Equal(expr.To) {
-		return nil, errors.Errorf("constant expression type mismatch; expected %q, got %q", expr.To, t)
+		return nil, gen.errorf(old, "constant expression type mismatch; expected %q, got %q", expr.To, t)
 	}
 	return expr, nil
 }
@@ -627,7 +627,7 @@ func (gen *generator) irUIToFPExpr(t types.Type, old *ast.UIToFPExpr) (*constant
 	}
 	expr := constant.NewUIToFP(from, to)
 	if !t.Equal(expr.To) {
-		return nil, errors.Errorf("constant expression type mismatch; expected %q, got %q", expr.To, t)
+		return nil, gen.errorf(old, "constant expression type mismatch; expected %q, got %q", expr.To, t)
 	}
 	return expr, nil
 }
@@ -649,7 +649,7 @@ func (gen *generator) irSIToFPExpr(t types.Type, old *ast.SIToFPExpr) (*constant
 	}
 	expr := constant.NewSIToFP(from, to)
 	if !t.Equal(expr.To) {
-		return nil, errors.Errorf("constant expression type mismatch; expected %q, got %q", expr.To, t)
+		return nil, gen.errorf(old, "constant expression type mismatch; expected %q, got %q", expr.To, t)
 	}
 	return expr, nil
 }
@@ -671,7 +671,7 @@ func (gen *generator) irPtrToIntExpr(t types.Type, old *ast.PtrToIntExpr) (*cons
 	}
 	expr := constant.NewPtrToInt(from, to)
 	if !t.Equal(expr.To) {
-		return nil, errors.Errorf("constant expression type mismatch; expected %q, got %q", expr.To, t)
+		return nil, gen.errorf(old, "constant expression type mismatch; expected %q, got %q", expr.To, t)
 	}
 	return expr, nil
 }
@@ -693,7 +693,7 @@ func (gen *generator) irIntToPtrExpr(t types.Type, old *ast.IntToPtrExpr) (*cons
 	}
 	expr := constant.NewIntToPtr(from, to)
 	if !t.Equal(expr.To) {
-		return nil, errors.Errorf("constant expression type mismatch; expected %q, got %q", expr.To, t)
+		return nil, gen.errorf(old, "constant expression type mismatch; expected %q, got %q", expr.To, t)
 	}
 	return expr, nil
 }
@@ -715,7 +715,7 @@ func (gen *generator) irBitCastExpr(t types.Type, old *ast.BitCastExpr) (*consta
 	}
 	expr := constant.NewBitCast(from, to)
 	if !t.Equal(expr.To) {
-		return nil, errors.Errorf("constant expression type mismatch; expected %q, got %q", expr.To, t)
+		return nil, gen.errorf(old, "constant expression type mismatch; expected %q, got %q", expr.To, t)
 	}
 	return expr, nil
 }
@@ -737,7 +737,7 @@ func (gen *generator) irAddrSpaceCastExpr(t types.Type, old *ast.AddrSpaceCastEx
 	}
 	expr := constant.NewAddrSpaceCast(from, to)
 	if !t.Equal(expr.To) {
-		return nil, errors.Errorf("constant expression type mismatch; expected %q, got %q", expr.To, t)
+		return nil, gen.errorf(old, "constant expression type mismatch; expected %q, got %q", expr.To, t)
 	}
 	return expr, nil
 }
@@ -763,7 +763,7 @@ func (gen *generator) irICmpExpr(t types.Type, old *ast.ICmpExpr) (*constant.Exp
 	}
 	expr := constant.NewICmp(pred, x, y)
 	if !t.Equal(expr.Typ) {
-		return nil, errors.Errorf("constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
+		return nil, gen.errorf(old, "constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
 	}
 	return expr, nil
 }
@@ -787,7 +787,7 @@ func (gen *generator) irFCmpExpr(t types.Type, old *ast.FCmpExpr) (*constant.Exp
 	}
 	expr := constant.NewFCmp(pred, x, y)
 	if !t.Equal(expr.Typ) {
-		return nil, errors.Errorf("constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
+		return nil, gen.errorf(old, "constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
 	}
 	return expr, nil
 }
@@ -814,7 +814,7 @@ func (gen *generator) irSelectExpr(t types.Type, old *ast.SelectExpr) (*constant
 	}
 	expr := constant.NewSelect(cond, x, y)
 	if !t.Equal(expr.Typ) {
-		return nil, errors.Errorf("constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
+		return nil, gen.errorf(old, "constant expression type mismatch in `%v`; expected %q, got %q", expr, expr.Typ, t)
 	}
 	return expr, nil
 }
@@ -0,0 +1,178 @@
+package asm
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/llir/ll/ast"
+	"github.com/pkg/errors"
+)
+
+// === [ Diagnostics ] =========================================================
+
+// Severity is the severity of a diagnostic.
+type Severity uint8
+
+// Diagnostic severities.
+const (
+	// SeverityError is the severity of errors, which prevent the successful
+	// parsing of an LLVM IR module.
+	SeverityError Severity = iota
+	// SeverityWarning is the severity of warnings, which are reported for
+	// questionable but accepted input.
+	SeverityWarning
+)
+
+// String returns the string representation of the diagnostic severity.
+func (severity Severity) String() string {
+	switch severity {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return fmt.Sprintf("Severity(%d)", uint8(severity))
+	}
+}
+
+// Diagnostic is an error or warning reported at a given position in an LLVM IR
+// assembly source file.
+type Diagnostic struct {
+	// Source position of the diagnostic; or the zero value if unknown.
+	Pos Pos
+	// Severity of the diagnostic.
+	Severity Severity
+	// Diagnostic message.
+	Msg string
+}
+
+// String returns the string representation of the diagnostic, in the format
+// "file:line:col: message" for errors and "file:line:col: warning: message"
+// for warnings.
+func (d *Diagnostic) String() string {
+	msg := d.Msg
+	if d.Severity != SeverityError {
+		msg = fmt.Sprintf("%v: %s", d.Severity, msg)
+	}
+	if pos := d.Pos.String(); len(pos) > 0 {
+		return fmt.Sprintf("%s: %s", pos, msg)
+	}
+	return msg
+}
+
+// Diagnostics is a list of diagnostics, sorted by source position.
+//
+// Diagnostics implements the error interface, as returned by the parser for
+// input containing one or more errors.
+type Diagnostics []*Diagnostic
+
+// Error returns the error message of the first error of the diagnostics, and
+// the number of additional errors.
+func (diags Diagnostics) Error() string {
+	var first *Diagnostic
+	n := 0
+	for _, d := range diags {
+		if d.Severity != SeverityError {
+			continue
+		}
+		if first == nil {
+			first = d
+		}
+		n++
+	}
+	switch n {
+	case 0:
+		return "no errors"
+	case 1:
+		return first.String()
+	case 2:
+		return fmt.Sprintf("%s (and 1 more error)", first)
+	default:
+		return fmt.Sprintf("%s (and %d more errors)", first, n-1)
+	}
+}
+
+// Errors returns the diagnostics of error severity.
+func (diags Diagnostics) Errors() Diagnostics {
+	return diags.filter(SeverityError)
+}
+
+// Warnings returns the diagnostics of warning severity.
+func (diags Diagnostics) Warnings() Diagnostics {
+	return diags.filter(SeverityWarning)
+}
+
+// filter returns the diagnostics of the given severity.
+func (diags Diagnostics) filter(severity Severity) Diagnostics {
+	var ds Diagnostics
+	for _, d := range diags {
+		if d.Severity == severity {
+			ds = append(ds, d)
+		}
+	}
+	return ds
+}
+
+// add adds the given error to the diagnostics. The diagnostics of err are
+// added if err is caused by diagnostics; otherwise, a new error diagnostic is
+// added, positioned at the source position of err (if present).
+func (diags *Diagnostics) add(err error) {
+	if err == nil {
+		return
+	}
+	if ds, ok := errors.Cause(err).(Diagnostics); ok {
+		*diags = append(*diags, ds...)
+		return
+	}
+	d := &Diagnostic{Severity: SeverityError, Msg: err.Error()}
+	for e := err; e != nil; {
+		if e, ok := e.(*Error); ok {
+			d.Pos = e.Pos
+			d.Msg = e.Err.Error()
+			break
+		}
+		cause, ok := e.(interface{ Cause() error })
+		if !ok {
+			break
+		}
+		e = cause.Cause()
+	}
+	*diags = append(*diags, d)
+}
+
+// err returns the diagnostics, sorted by source position, as an error if
+// containing at least one error, and nil otherwise.
+func (diags Diagnostics) err() error {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			diags.sort()
+			return diags
+		}
+	}
+	return nil
+}
+
+// sort sorts the diagnostics by source position.
+func (diags Diagnostics) sort() {
+	less := func(i, j int) bool {
+		return diags[i].Pos.Offset < diags[j].Pos.Offset
+	}
+	sort.SliceStable(diags, less)
+}
+
+// ### [ Helper functions ] ####################################################
+
+// warnf records a warning at the source position of the given AST node,
+// formatted according to the format specifier.
+//
+// pre-condition: gen.mu is held.
+func (gen *generator) warnf(old ast.LlvmNode, format string, args ...interface{}) {
+	d := &Diagnostic{
+		Severity: SeverityWarning,
+		Msg:      fmt.Sprintf(format, args...),
+	}
+	if n := old.LlvmNode(); n != nil {
+		d.Pos = gen.file.pos(n.Offset())
+	}
+	gen.warnings = append(gen.warnings, d)
+}
@@ -1,6 +1,9 @@
 package asm
 
 import (
+	"runtime"
+	"sync"
+
 	"github.com/llir/ll/ast"
 	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/constant"
@@ -17,19 +20,53 @@ type generator struct {
 	old oldIndex
 	// index of IR top-level entities.
 	new newIndex
+	// LLVM IR assembly source file being translated.
+	file *file
+	// Side table of source spans of IR entities; or nil if source positions are
+	// not recorded.
+	positions *Positions
 
-	// TODO: add rw mutex to gen.todo for access to blockaddress constant.
+	// Maximum number of goroutines used to translate function bodies and
+	// metadata definitions concurrently.
+	workers int
+	// lazy specifies whether to translate function bodies on first use.
+	lazy bool
+	// Offsets of opaque pointer types (ptr) in the source file, rewritten into
+	// pointer types with element type before parsing (see
+	// rewriteOpaquePointers).
+	opaquePtrs map[int]bool
+	// Diagnostics recorded for function bodies translated after parsing; or nil
+	// if not recorded.
+	diags *Diagnostics
+	// Depth of nested translation of lazily parsed function bodies.
+	nested int
 
+	// mu protects todo, warnings and the on-demand creation of attribute group
+	// definitions during concurrent translation of top-level entities.
+	mu sync.Mutex
+	// Warnings reported during translation.
+	warnings Diagnostics
 	// Fix dummy basic blocks after translation of function bodies and assignment
 	// of local IDs.
-	todo []*constant.BlockAddress
+	todo []blockAddressFix
+}
+
+// blockAddressFix is a blockaddress constant with a dummy basic block, which is
+// fixed after translation of function bodies.
+type blockAddressFix struct {
+	// IR blockaddress constant.
+	c *constant.BlockAddress
+	// AST blockaddress constant.
+	old *ast.BlockAddressConst
 }
 
 // newGenerator returns a new generator for translating an LLVM IR module from
-// AST to IR representation.
-func newGenerator() *generator {
+// AST to IR representation, based on the given source file.
+func newGenerator(f *file) *generator {
 	return &generator{
-		m: ir.NewModule(),
+		m:       ir.NewModule(),
+		file:    f,
+		workers: runtime.GOMAXPROCS(0),
 		old: oldIndex{
 			typeDefs:          make(map[string]*ast.TypeDef),
 			comdatDefs:        make(map[string]*ast.ComdatDef),
@@ -27,14 +27,17 @@ func (gen *generator) createGlobalEntities() error {
 	//      declarations and definitions, indirect symbol definitions (aliases
 	//      and indirect functions), and function declarations and definitions
 	//      (without bodies but with types).
+	var diags Diagnostics
 	for ident, old := range gen.old.globals {
 		new, err := gen.newGlobalEntity(ident, old)
 		if err != nil {
-			return errors.WithStack(err)
+			diags.add(gen.errorAt(old, err))
+			continue
 		}
+		gen.recordSpan(new, old)
 		gen.new.globals[ident] = new
 	}
-	return nil
+	return diags.err()
 }
 
 // newGlobalEntity returns a new scaffolding IR value (without body but with
@@ -66,7 +69,7 @@ func (gen *generator) newGlobal(ident ir.GlobalIdent, oldContentType ast.Type, o
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	typ := types.NewPointer(contentType)
+	typ := gen.newPointer(contentType)
 	// (optional) Address space.
 	var addrSpace types.AddrSpace
 	if oldAddrSpace.IsValid() {
@@ -84,7 +87,7 @@ func (gen *generator) newIndirectSymbol(ident ir.GlobalIdent, old *ast.IndirectS
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	typ := types.NewPointer(contentType)
+	typ := gen.newPointer(contentType)
 	// Infer address space of pointer type from indirect symbol as no explicit
 	// type/value pair is given for the indirect symbol when aliasee is a
 	// constant expression.
@@ -133,9 +136,9 @@ func (gen *generator) newIndirectSymbol(ident ir.GlobalIdent, old *ast.IndirectS
 	kind := old.IndirectSymbolKind().Text()
 	switch kind {
 	case "alias":
-		return &ir.Alias{GlobalIdent: ident, Typ: typ}, nil
+		return &ir.Alias{GlobalIdent: ident, Typ: typ, ContentType: contentType}, nil
 	case "ifunc":
-		return &ir.IFunc{GlobalIdent: ident, Typ: typ}, nil
+		return &ir.IFunc{GlobalIdent: ident, Typ: typ, ContentType: contentType}, nil
 	default:
 		panic(fmt.Errorf("support for indirect symbol kind %q not yet implemented", kind))
 	}
@@ -149,7 +152,7 @@ func (gen *generator) newFunc(ident ir.GlobalIdent, hdr ast.FuncHeader) (*ir.Fun
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	typ := types.NewPointer(sig)
+	typ := gen.newPointer(sig)
 	// (optional) Address space.
 	var addrSpace types.AddrSpace
 	if n, ok := hdr.AddrSpace(); ok {
@@ -161,6 +164,15 @@ func (gen *generator) newFunc(ident ir.GlobalIdent, hdr ast.FuncHeader) (*ir.Fun
 
 // ### [ Helper functions ] ####################################################
 
+// newPointer returns a new pointer type to the given element type; or a new
+// opaque pointer type if the module uses opaque pointers.
+func (gen *generator) newPointer(elemType types.Type) *types.PointerType {
+	if gen.m.OpaquePointers {
+		return types.NewOpaquePointer(0)
+	}
+	return types.NewPointer(elemType)
+}
+
 // irSigFromHeader translates the AST function signature to an equivalent IR
 // function type.
 func (gen *generator) irSigFromHeader(old ast.FuncHeader) (*types.FuncType, error) {
@@ -193,65 +205,82 @@ func (gen *generator) irSigFromHeader(old ast.FuncHeader) (*types.FuncType, erro
 // translateGlobalEntities translate AST global declarations and definitions,
 // indirect symbol definitions, and function declarations and definitions to IR.
 func (gen *generator) translateGlobalEntities() error {
-	// TODO: make concurrent and benchmark difference in walltime.
-
 	// 4b1. Translate AST global declarations and definitions, indirect symbol
 	//      definitions, and function declarations and definitions to IR.
-	for ident, old := range gen.old.globals {
-		v, ok := gen.new.globals[ident]
+	//
+	// Global entities are translated concurrently, and errors are reported for
+	// all failing entities.
+	translate := func(i int) error {
+		ident := gen.old.globalOrder[i]
+		if err := gen.translateGlobalEntity(ident); err != nil {
+			return gen.errorAt(gen.old.globals[ident], err)
+		}
+		return nil
+	}
+	return parallel(gen.workers, len(gen.old.globalOrder), translate)
+}
+
+// translateGlobalEntity translates the AST global declaration or definition,
+// indirect symbol definition, or function declaration or definition with the
+// given global identifier to IR.
+func (gen *generator) translateGlobalEntity(ident ir.GlobalIdent) error {
+	old, ok := gen.old.globals[ident]
+	if !ok {
+		panic(fmt.Errorf("unable to locate global identifier %q", ident.Ident()))
+	}
+	v, ok := gen.new.globals[ident]
+	if !ok {
+		panic(fmt.Errorf("unable to locate global identifier %q", ident.Ident()))
+	}
+	switch old := old.(type) {
+	case *ast.GlobalDecl:
+		new, ok := v.(*ir.Global)
 		if !ok {
-			panic(fmt.Errorf("unable to locate global identifier %q", ident.Ident()))
+			panic(fmt.Errorf("invalid global declaration type; expected *ir.Global, got %T", v))
 		}
-		switch old := old.(type) {
-		case *ast.GlobalDecl:
-			new, ok := v.(*ir.Global)
-			if !ok {
-				panic(fmt.Errorf("invalid global declaration type; expected *ir.Global, got %T", v))
-			}
-			if err := gen.irGlobal(new, old); err != nil {
-				return errors.WithStack(err)
-			}
-		case *ast.IndirectSymbolDef:
-			kind := old.IndirectSymbolKind().Text()
-			switch kind {
-			case "alias":
-				new, ok := v.(*ir.Alias)
-				if !ok {
-					panic(fmt.Errorf("invalid alias definition type; expected *ir.Alias, got %T", v))
-				}
-				if err := gen.irAlias(new, old); err != nil {
-					return errors.WithStack(err)
-				}
-			case "ifunc":
-				new, ok := v.(*ir.IFunc)
-				if !ok {
-					panic(fmt.Errorf("invalid IFunc definition type; expected *ir.IFunc, got %T", v))
-				}
-				if err := gen.irIFunc(new, old); err != nil {
-					return errors.WithStack(err)
-				}
-			default:
-				panic(fmt.Errorf("support for indirect symbol kind %q not yet implemented", kind))
-			}
-		case *ast.FuncDecl:
-			new, ok := v.(*ir.Func)
+		if err := gen.irGlobal(new, old); err != nil {
+			return errors.WithStack(err)
+		}
+	case *ast.IndirectSymbolDef:
+		kind := old.IndirectSymbolKind().Text()
+		switch kind {
+		case "alias":
+			new, ok := v.(*ir.Alias)
 			if !ok {
-				panic(fmt.Errorf("invalid function declaration type; expected *ir.Func, got %T", v))
+				panic(fmt.Errorf("invalid alias definition type; expected *ir.Alias, got %T", v))
 			}
-			if err := gen.irFuncDecl(new, old); err != nil {
+			if err := gen.irAlias(new, old); err != nil {
 				return errors.WithStack(err)
 			}
-		case *ast.FuncDef:
-			new, ok := v.(*ir.Func)
+		case "ifunc":
+			new, ok := v.(*ir.IFunc)
 			if !ok {
-				panic(fmt.Errorf("invalid function definition type; expected *ir.Func, got %T", v))
+				panic(fmt.Errorf("invalid IFunc definition type; expected *ir.IFunc, got %T", v))
 			}
-			if err := gen.irFuncDef(new, old); err != nil {
+			if err := gen.irIFunc(new, old); err != nil {
 				return errors.WithStack(err)
 			}
 		default:
-			panic(fmt.Errorf("support for global variable, indirect symbol or function %T not yet implemented", old))
+			panic(fmt.Errorf("support for indirect symbol kind %q not yet implemented", kind))
+		}
+	case *ast.FuncDecl:
+		new, ok := v.(*ir.Func)
+		if !ok {
+			panic(fmt.Errorf("invalid function declaration type; expected *ir.Func, got %T", v))
+		}
+		if err := gen.irFuncDecl(new, old); err != nil {
+			return errors.WithStack(err)
+		}
+	case *ast.FuncDef:
+		new, ok := v.(*ir.Func)
+		if !ok {
+			panic(fmt.Errorf("invalid function definition type; expected *ir.Func, got %T", v))
+		}
+		if err := gen.irFuncDef(new, old); err != nil {
+			return errors.WithStack(err)
 		}
+	default:
+		panic(fmt.Errorf("support for global variable, indirect symbol or function %T not yet implemented", old))
 	}
 	return nil
 }
@@ -467,24 +496,76 @@ func (gen *generator) irFuncDef(new *ir.Func, old *ast.FuncDef) error {
 		return errors.WithStack(err)
 	}
 	new.Metadata = md
+	// Function body.
+	if gen.lazy {
+		// Translate function body on first use.
+		materialize := func() error {
+			return gen.materializeFuncBody(new, old)
+		}
+		new.SetMaterializer(materialize)
+		return nil
+	}
+	return gen.irFuncBody(new, old.Body())
+}
+
+// irFuncBody translates the AST function body into an equivalent IR function
+// body.
+func (gen *generator) irFuncBody(new *ir.Func, oldBody ast.FuncBody) error {
 	// Basic blocks.
 	fgen := newFuncGen(gen, new)
-	oldBody := old.Body()
 	if err := fgen.resolveLocals(oldBody); err != nil {
 		return errors.WithStack(err)
 	}
 	// (optional) Use list orders.
+	var diags Diagnostics
 	if oldUseListOrders := oldBody.UseListOrders(); len(oldUseListOrders) > 0 {
 		new.UseListOrders = make([]*ir.UseListOrder, len(oldUseListOrders))
 		for i, oldUseListOrder := range oldUseListOrders {
 			useListOrder, err := fgen.irUseListOrder(oldUseListOrder)
 			if err != nil {
-				return errors.WithStack(err)
+				diags.add(gen.errorAt(oldUseListOrder, err))
+				continue
 			}
 			new.UseListOrders[i] = useListOrder
 		}
 	}
-	return nil
+	return diags.err()
+}
+
+// materializeFuncBody translates the AST function body of the lazily parsed
+// function definition into an equivalent IR function body.
+//
+// The diagnostics of the function body are returned as error, and recorded if
+// diagnostics are recorded.
+func (gen *generator) materializeFuncBody(new *ir.Func, old *ast.FuncDef) error {
+	// Fix basic blocks of blockaddress constants of the function body after
+	// translation; preserving pending fixes of the caller.
+	todo, warnings := gen.todo, len(gen.warnings)
+	gen.todo = nil
+	// Function bodies referenced by blockaddress constants are materialized in
+	// nested calls.
+	gen.nested++
+	err := gen.irFuncBody(new, old.Body())
+	if err == nil {
+		err = gen.fixBlockAddressConsts()
+	}
+	gen.nested--
+	gen.todo = todo
+	var diags Diagnostics
+	diags.add(gen.errorAt(old, err))
+	if err != nil {
+		// Reset partially translated function body.
+		new.Blocks = nil
+		new.UseListOrders = nil
+	}
+	if gen.diags != nil && gen.nested == 0 {
+		// Record diagnostics of function bodies materialized after parsing,
+		// including those of nested calls.
+		*gen.diags = append(*gen.diags, diags...)
+		*gen.diags = append(*gen.diags, gen.warnings[warnings:]...)
+		gen.diags.sort()
+	}
+	return diags.err()
 }
 
 // ~~~ [ Function headers ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
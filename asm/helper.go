@@ -299,7 +299,7 @@ func (fgen *funcGen) irBlock(old ast.Label) (*ir.Block, error) {
 	ident := localIdent(old.Name())
 	v, ok := fgen.locals[ident]
 	if !ok {
-		return nil, errors.Errorf("unable to locate local identifier %q", ident.Ident())
+		return nil, fgen.gen.errorf(old, "unable to locate local identifier %q", ident.Ident())
 	}
 	block, ok := v.(*ir.Block)
 	if !ok {
@@ -386,7 +386,7 @@ func (fgen *funcGen) irExceptionPad(old ast.ExceptionPad) (ir.ExceptionPad, erro
 		ident := localIdent(*old)
 		v, ok := fgen.locals[ident]
 		if !ok {
-			return nil, errors.Errorf("unable to locate local identifier %q", ident.Ident())
+			return nil, fgen.gen.errorf(old, "unable to locate local identifier %q", ident.Ident())
 		}
 		return v, nil
 	default:
@@ -421,6 +421,8 @@ func (gen *generator) irFuncAttribute(old ast.FuncAttribute) ir.FuncAttribute {
 		}
 	case *ast.AttrGroupID:
 		id := attrGroupID(*old)
+		gen.mu.Lock()
+		defer gen.mu.Unlock()
 		def, ok := gen.new.attrGroupDefs[id]
 		if !ok {
 			// Attribute group definition for ID not found.
@@ -433,6 +435,7 @@ func (gen *generator) irFuncAttribute(old ast.FuncAttribute) ir.FuncAttribute {
 			// This issue is tracked at: https://github.com/llir/llvm/issues/37
 			def = &ir.AttrGroupDef{ID: id}
 			gen.new.attrGroupDefs[id] = def
+			gen.warnf(old, "unable to locate attribute group ID %q; empty attribute group definition added", enc.AttrGroupID(id))
 		}
 		return def
 	// TODO: add support for Align.
@@ -514,6 +517,18 @@ func irImmutable(old ast.Immutable) bool {
 	}
 }
 
+// irCallee returns the IR callee corresponding to the given AST callee of a
+// call site with the given function signature. The callee type is pointer to
+// function type, or opaque pointer.
+func (fgen *funcGen) irCallee(sig *types.FuncType, old ast.Value) (value.Value, error) {
+	if old, ok := old.(*ast.LocalIdent); ok {
+		if v, ok := fgen.locals[localIdent(*old)]; ok && types.IsOpaquePointer(v.Type()) {
+			return v, nil
+		}
+	}
+	return fgen.irValue(types.NewPointer(sig), old)
+}
+
 // irIncoming returns the incoming value corresponding to the given AST incoming
 // value.
 func (fgen *funcGen) irIncoming(xType types.Type, oldX ast.Value, oldPred ast.LocalIdent) (*ir.Incoming, error) {
@@ -524,7 +539,7 @@ func (fgen *funcGen) irIncoming(xType types.Type, oldX ast.Value, oldPred ast.Lo
 	predIdent := localIdent(oldPred)
 	v, ok := fgen.locals[predIdent]
 	if !ok {
-		return nil, errors.Errorf("unable to locate local identifier %q", predIdent.Ident())
+		return nil, fgen.gen.errorf(oldPred, "unable to locate local identifier %q", predIdent.Ident())
 	}
 	pred, ok := v.(*ir.Block)
 	if !ok {
@@ -783,6 +798,10 @@ func text(n ast.LlvmNode) string {
 // findBlock returns the basic block with the given local identifier in the
 // function.
 func findBlock(f *ir.Func, blockIdent ir.LocalIdent) (*ir.Block, error) {
+	// Materialize function body if lazily parsed.
+	if err := f.Materialize(); err != nil {
+		return nil, errors.WithStack(err)
+	}
 	for _, block := range f.Blocks {
 		if block.LocalIdent == blockIdent {
 			return block, nil
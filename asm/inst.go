@@ -5,7 +5,6 @@ import (
 
 	"github.com/llir/ll/ast"
 	"github.com/llir/llvm/ir"
-	"github.com/pkg/errors"
 )
 
 // === [ Create IR ] ===========================================================
@@ -158,16 +157,17 @@ func (fgen *funcGen) newValueInst(ident ir.LocalIdent, old ast.ValueInstruction)
 
 // translateInsts translates the AST instructions of the given function to IR.
 func (fgen *funcGen) translateInsts(oldBlocks []ast.BasicBlock) error {
+	var diags Diagnostics
 	for i, oldBlock := range oldBlocks {
 		block := fgen.f.Blocks[i]
 		for j, old := range oldBlock.Insts() {
 			new := block.Insts[j]
 			if err := fgen.irInst(new, old); err != nil {
-				return errors.WithStack(err)
+				diags.add(fgen.gen.errorAt(old, err))
 			}
 		}
 	}
-	return nil
+	return diags.err()
 }
 
 // irInst translates the AST instruction into an equivalent IR instruction.
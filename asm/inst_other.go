@@ -314,13 +314,15 @@ func (fgen *funcGen) irCallInst(new ir.Instruction, old *ast.CallInst) error {
 		}
 		sig = types.NewFunc(typ, paramTypes...)
 	}
-	// The callee type is always pointer to function type.
-	ptrToSig := types.NewPointer(sig)
-	callee, err := fgen.irValue(ptrToSig, old.Callee())
+	callee, err := fgen.irCallee(sig, old.Callee())
 	if err != nil {
 		return errors.WithStack(err)
 	}
 	inst.Callee = callee
+	// Record function signature of callees of opaque pointer type.
+	if types.IsOpaquePointer(callee.Type()) {
+		inst.CalleeSig = sig
+	}
 	// (optional) Tail.
 	if n, ok := old.Tail(); ok {
 		inst.Tail = asmenum.TailFromString(n.Text())
@@ -451,7 +453,7 @@ func (fgen *funcGen) irCatchPadInst(new ir.Instruction, old *ast.CatchPadInst) e
 	ident := localIdent(old.CatchSwitch())
 	v, ok := fgen.locals[ident]
 	if !ok {
-		return errors.Errorf("unable to locate local identifier %q", ident.Ident())
+		return fgen.gen.errorf(old.CatchSwitch(), "unable to locate local identifier %q", ident.Ident())
 	}
 	catchSwitch, ok := v.(*ir.TermCatchSwitch)
 	if !ok {
@@ -56,12 +56,14 @@ func (fgen *funcGen) resolveLocals(old ast.FuncBody) error {
 	if err := fgen.createLocals(oldBlocks); err != nil {
 		return errors.WithStack(err)
 	}
+	// Errors of instructions and terminators are reported for all failing
+	// instructions and terminators.
+	var diags Diagnostics
 	// Translate AST instructions to IR.
-	if err := fgen.translateInsts(oldBlocks); err != nil {
-		return errors.WithStack(err)
-	}
+	diags.add(fgen.translateInsts(oldBlocks))
 	// Translate AST terminators to IR.
-	return fgen.translateTerms(oldBlocks)
+	diags.add(fgen.translateTerms(oldBlocks))
+	return diags.err()
 }
 
 // === [ Create and index IR ] =================================================
@@ -96,7 +98,7 @@ func (fgen *funcGen) createLocals(oldBlocks []ast.BasicBlock) error {
 		return errors.WithStack(err)
 	}
 	// Index local identifiers.
-	return fgen.indexLocals()
+	return fgen.indexLocals(oldBlocks)
 }
 
 // newLocals creates scaffolding IR local variables (without bodies but with
@@ -105,48 +107,62 @@ func (fgen *funcGen) newLocals(oldBlocks []ast.BasicBlock) error {
 	// Note: function parameters are already translated in gen.irFuncHeader.
 	f := fgen.f
 	f.Blocks = make([]*ir.Block, len(oldBlocks))
+	var diags Diagnostics
 	for i, oldBlock := range oldBlocks {
 		block := &ir.Block{}
 		if n, ok := oldBlock.Name(); ok {
 			block.LocalIdent = labelIdent(n)
 		}
+		fgen.gen.recordSpan(block, oldBlock)
 		if oldInsts := oldBlock.Insts(); len(oldInsts) > 0 {
 			block.Insts = make([]ir.Instruction, len(oldInsts))
 			for j, oldInst := range oldInsts {
 				inst, err := fgen.newInst(oldInst)
 				if err != nil {
-					return errors.WithStack(err)
+					diags.add(fgen.gen.errorAt(oldInst, err))
+					continue
 				}
+				fgen.gen.recordSpan(inst, oldInst)
 				block.Insts[j] = inst
 			}
 		}
-		term, err := fgen.newTerm(oldBlock.Term())
+		oldTerm := oldBlock.Term()
+		term, err := fgen.newTerm(oldTerm)
 		if err != nil {
-			return errors.WithStack(err)
+			diags.add(fgen.gen.errorAt(oldTerm, err))
+		} else {
+			fgen.gen.recordSpan(term, oldTerm)
 		}
 		block.Term = term
 		block.Parent = f
 		f.Blocks[i] = block
 	}
-	return nil
+	return diags.err()
 }
 
-// indexLocals indexes local identifiers of the given function.
-func (fgen *funcGen) indexLocals() error {
+// indexLocals indexes local identifiers of the given function. The AST basic
+// blocks of the function are used to annotate errors with source positions.
+func (fgen *funcGen) indexLocals(oldBlocks []ast.BasicBlock) error {
+	//
+	// Duplicate local identifiers are reported, and the first local variable is
+	// kept.
+	var diags Diagnostics
 	// Index function parameters.
 	f := fgen.f
 	for _, param := range f.Params {
 		if err := fgen.addLocal(param.LocalIdent, param); err != nil {
-			return errors.WithStack(err)
+			diags.add(err)
 		}
 	}
 	// Index basic blocks.
-	for _, block := range f.Blocks {
+	for i, block := range f.Blocks {
+		oldBlock := oldBlocks[i]
 		if err := fgen.addLocal(block.LocalIdent, block); err != nil {
-			return errors.WithStack(err)
+			diags.add(fgen.gen.errorAt(oldBlock, err))
 		}
 		// Index instructions.
-		for _, inst := range block.Insts {
+		oldInsts := oldBlock.Insts()
+		for j, inst := range block.Insts {
 			v, ok := inst.(local)
 			if !ok || v.Type().Equal(types.Void) {
 				// Skip non-value instructions.
@@ -154,7 +170,7 @@ func (fgen *funcGen) indexLocals() error {
 			}
 			ident := localIdentOfValue(v)
 			if err := fgen.addLocal(ident, v); err != nil {
-				return errors.WithStack(err)
+				diags.add(fgen.gen.errorAt(oldInsts[j], err))
 			}
 		}
 		// Index terminator.
@@ -165,10 +181,10 @@ func (fgen *funcGen) indexLocals() error {
 		}
 		ident := localIdentOfValue(v)
 		if err := fgen.addLocal(ident, v); err != nil {
-			return errors.WithStack(err)
+			diags.add(fgen.gen.errorAt(oldBlock.Term(), err))
 		}
 	}
-	return nil
+	return diags.err()
 }
 
 // ### [ Helper functions ] ####################################################
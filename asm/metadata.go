@@ -66,6 +66,7 @@ func (gen *generator) irMDTuple(new metadata.Definition, old *ast.MDTuple) (*met
 	if new == nil {
 		tuple = &metadata.Tuple{}
 		tuple.SetID(-1) // tuple literal has no ID.
+		gen.recordSpan(tuple, old)
 	} else if !ok {
 		panic(fmt.Errorf("invalid IR metadata tuple for AST metadata tuple; expected *metadata.Tuple, got %T", new))
 	}
@@ -142,7 +143,12 @@ func (gen *generator) irMetadataNode(old ast.MetadataNode) (metadata.Node, error
 	case *ast.MetadataID:
 		return gen.metadataDefFromID(*old)
 	case *ast.DIExpression:
-		return gen.irDIExpression(nil, old)
+		expr, err := gen.irDIExpression(nil, old)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		gen.recordSpan(expr, old)
+		return expr, nil
 	default:
 		panic(fmt.Errorf("support for metadata node %T not yet implemented", old))
 	}
@@ -172,7 +178,7 @@ func (gen *generator) metadataDefFromID(old ast.MetadataID) (metadata.Definition
 	id := metadataID(old)
 	node, ok := gen.new.metadataDefs[id]
 	if !ok {
-		return nil, errors.Errorf("unable to locate metadata ID %q", enc.MetadataID(id))
+		return nil, gen.errorf(old, "unable to locate metadata ID %q", enc.MetadataID(id))
 	}
 	return node, nil
 }
@@ -15,6 +15,8 @@ import (
 
 // indexTopLevelEntities indexes the AST top-level entities of the given module.
 func (gen *generator) indexTopLevelEntities(old *ast.Module) error {
+	// Duplicate definitions are reported, and the first definition is kept.
+	var diags Diagnostics
 	id := int64(0)
 	// 1. Index AST top-level entities.
 	for _, entity := range old.TopLevelEntities() {
@@ -27,41 +29,47 @@ func (gen *generator) indexTopLevelEntities(old *ast.Module) error {
 			name := getTypeName(ident)
 			if prev, ok := gen.old.typeDefs[name]; ok {
 				if _, ok := prev.Typ().(*ast.OpaqueType); !ok {
-					return errors.Errorf("type identifier %q already present; prev `%s`, new `%s`", enc.TypeName(name), text(prev), text(entity))
+					diags.add(gen.errorf(entity, "type identifier %q already present; prev `%s`, new `%s`", enc.TypeName(name), text(prev), text(entity)))
+					continue
 				}
 			}
 			gen.old.typeDefs[name] = entity
 		case *ast.ComdatDef:
 			name := comdatName(entity.Name())
 			if prev, ok := gen.old.comdatDefs[name]; ok {
-				return errors.Errorf("comdat name %q already present; prev `%s`, new `%s`", enc.ComdatName(name), text(prev), text(entity))
+				diags.add(gen.errorf(entity, "comdat name %q already present; prev `%s`, new `%s`", enc.ComdatName(name), text(prev), text(entity)))
+				continue
 			}
 			gen.old.comdatDefs[name] = entity
 		case *ast.GlobalDecl:
 			ident := giveUnnamedIdentID(globalIdent(entity.Name()), &id)
 			if prev, ok := gen.old.globals[ident]; ok {
-				return errors.Errorf("global identifier %q already present; prev `%s`, new `%s`", ident.Ident(), text(prev), text(entity))
+				diags.add(gen.errorf(entity, "global identifier %q already present; prev `%s`, new `%s`", ident.Ident(), text(prev), text(entity)))
+				continue
 			}
 			gen.old.globals[ident] = entity
 			gen.old.globalOrder = append(gen.old.globalOrder, ident)
 		case *ast.IndirectSymbolDef:
 			ident := giveUnnamedIdentID(globalIdent(entity.Name()), &id)
 			if prev, ok := gen.old.globals[ident]; ok {
-				return errors.Errorf("global identifier %q already present; prev `%s`, new `%s`", ident.Ident(), text(prev), text(entity))
+				diags.add(gen.errorf(entity, "global identifier %q already present; prev `%s`, new `%s`", ident.Ident(), text(prev), text(entity)))
+				continue
 			}
 			gen.old.globals[ident] = entity
 			gen.old.globalOrder = append(gen.old.globalOrder, ident)
 		case *ast.FuncDecl:
 			ident := giveUnnamedIdentID(globalIdent(entity.Header().Name()), &id)
 			if prev, ok := gen.old.globals[ident]; ok {
-				return errors.Errorf("global identifier %q already present; prev `%s`, new `%s`", ident.Ident(), text(prev), text(entity))
+				diags.add(gen.errorf(entity, "global identifier %q already present; prev `%s`, new `%s`", ident.Ident(), text(prev), text(entity)))
+				continue
 			}
 			gen.old.globals[ident] = entity
 			gen.old.globalOrder = append(gen.old.globalOrder, ident)
 		case *ast.FuncDef:
 			ident := giveUnnamedIdentID(globalIdent(entity.Header().Name()), &id)
 			if prev, ok := gen.old.globals[ident]; ok {
-				return errors.Errorf("global identifier %q already present; prev `%s`, new `%s`", ident.Ident(), text(prev), text(entity))
+				diags.add(gen.errorf(entity, "global identifier %q already present; prev `%s`, new `%s`", ident.Ident(), text(prev), text(entity)))
+				continue
 			}
 			gen.old.globals[ident] = entity
 			gen.old.globalOrder = append(gen.old.globalOrder, ident)
@@ -79,7 +87,8 @@ func (gen *generator) indexTopLevelEntities(old *ast.Module) error {
 		case *ast.MetadataDef:
 			id := metadataID(entity.ID())
 			if prev, ok := gen.old.metadataDefs[id]; ok {
-				return errors.Errorf("metadata ID %q already present; prev `%s`, new `%s`", enc.MetadataID(id), text(prev), text(entity))
+				diags.add(gen.errorf(entity, "metadata ID %q already present; prev `%s`, new `%s`", enc.MetadataID(id), text(prev), text(entity)))
+				continue
 			}
 			gen.old.metadataDefs[id] = entity
 		case *ast.UseListOrder:
@@ -90,7 +99,7 @@ func (gen *generator) indexTopLevelEntities(old *ast.Module) error {
 			panic(fmt.Errorf("support for AST top-level entity %T not yet implemented", entity))
 		}
 	}
-	return nil
+	return diags.err()
 }
 
 // giveUnnamedIdentID assigns an unused ID to the global identifier if unnamed.
@@ -159,8 +168,9 @@ func (gen *generator) createAttrGroupDefs() {
 func (gen *generator) createNamedMetadataDefs() {
 	// 4a3. Index metadata names and create scaffolding IR named metadata
 	//      definitions (without bodies).
-	for name := range gen.old.namedMetadataDefs {
+	for name, old := range gen.old.namedMetadataDefs {
 		new := &metadata.NamedDef{Name: name}
+		gen.recordSpan(new, old[0])
 		gen.new.namedMetadataDefs[name] = new
 	}
 }
@@ -175,8 +185,9 @@ func (gen *generator) createNamedMetadataDefs() {
 func (gen *generator) createMetadataDefs() {
 	// 4a4. Index metadata IDs and create scaffolding IR metadata definitions
 	//      (without bodies).
-	for id, md := range gen.old.metadataDefs {
-		new := newMetadataDef(id, md)
+	for id, old := range gen.old.metadataDefs {
+		new := newMetadataDef(id, old)
+		gen.recordSpan(new, old)
 		gen.new.metadataDefs[id] = new
 	}
 }
@@ -288,25 +299,23 @@ func (gen *generator) translateTargetDefs(old *ast.Module) error {
 // translateTopLevelEntities translates the AST top-level declarations and
 // definitions of the given module to IR.
 func (gen *generator) translateTopLevelEntities() error {
-	// TODO: make concurrent and benchmark difference in walltime.
-
 	// 4b. Translate AST top-level declarations and definitions to IR.
 	//
 	// Note: the substeps of 4b can be done concurrently.
 	//
+	// The substeps of 4b are independent, and errors of all substeps are
+	// reported.
+	var diags Diagnostics
 	// 4b1. Translate AST global declarations and definitions, alias and IFunc
 	//      definitions, and function declarations and definitions to IR.
-	if err := gen.translateGlobalEntities(); err != nil {
-		return errors.WithStack(err)
-	}
+	diags.add(gen.translateGlobalEntities())
 	// 4b2. Translate AST attribute group definitions to IR.
 	gen.translateAttrGroupDefs()
 	// 4b3. Translate AST named metadata definitions to IR.
-	if err := gen.translateNamedMetadataDefs(); err != nil {
-		return errors.WithStack(err)
-	}
+	diags.add(gen.translateNamedMetadataDefs())
 	// 4b4. Translate AST metadata definitions to IR.
-	return gen.translateMetadataDefs()
+	diags.add(gen.translateMetadataDefs())
+	return diags.err()
 }
 
 // --- [ Comdat definitions ] --------------------------------------------------
@@ -368,6 +377,7 @@ func (gen *generator) irAttrGroupDef(new *ir.AttrGroupDef, oldDefs []*ast.AttrGr
 // the given module to IR.
 func (gen *generator) translateNamedMetadataDefs() error {
 	// 4b3. Translate AST named metadata definitions to IR.
+	var diags Diagnostics
 	for name, old := range gen.old.namedMetadataDefs {
 		new, ok := gen.new.namedMetadataDefs[name]
 		if !ok {
@@ -375,11 +385,11 @@ func (gen *generator) translateNamedMetadataDefs() error {
 		}
 		for _, oldDef := range old {
 			if err := gen.irNamedMetadataDef(new, oldDef); err != nil {
-				return errors.WithStack(err)
+				diags.add(gen.errorAt(oldDef, err))
 			}
 		}
 	}
-	return nil
+	return diags.err()
 }
 
 // irNamedMetadataDef translates the given AST named metadata definition to an
@@ -402,16 +412,23 @@ func (gen *generator) irNamedMetadataDef(new *metadata.NamedDef, old *ast.NamedM
 // module to IR.
 func (gen *generator) translateMetadataDefs() error {
 	// 4b4. Translate AST metadata definitions to IR.
-	for id, old := range gen.old.metadataDefs {
+	//
+	// Metadata definitions are translated concurrently, and errors are reported
+	// for all failing definitions.
+	metadataIDs := gen.metadataIDs()
+	translate := func(i int) error {
+		id := metadataIDs[i]
 		new, ok := gen.new.metadataDefs[id]
 		if !ok {
 			panic(fmt.Errorf("unable to locate metadata ID %q", enc.MetadataID(id)))
 		}
+		old := gen.old.metadataDefs[id]
 		if err := gen.irMetadataDef(new, old); err != nil {
-			return errors.WithStack(err)
+			return gen.errorAt(old, err)
 		}
+		return nil
 	}
-	return nil
+	return parallel(gen.workers, len(metadataIDs), translate)
 }
 
 // irMetadataDef translates the given AST metadata definition to an equivalent
@@ -444,17 +461,19 @@ func (gen *generator) irMetadataDef(new metadata.Definition, old *ast.MetadataDe
 // module to IR.
 func (gen *generator) translateUseListOrders() error {
 	// 5. Translate use-list orders.
+	var diags Diagnostics
 	if len(gen.old.useListOrders) > 0 {
 		gen.m.UseListOrders = make([]*ir.UseListOrder, len(gen.old.useListOrders))
 		for i, oldUseListOrder := range gen.old.useListOrders {
 			useListOrder, err := gen.irUseListOrder(oldUseListOrder)
 			if err != nil {
-				return errors.WithStack(err)
+				diags.add(gen.errorAt(oldUseListOrder, err))
+				continue
 			}
 			gen.m.UseListOrders[i] = useListOrder
 		}
 	}
-	return nil
+	return diags.err()
 }
 
 // irUseListOrder returns the IR use-list order corresponding to the given AST
@@ -489,17 +508,19 @@ func (gen *generator) irUseListOrder(old *ast.UseListOrder) (*ir.UseListOrder, e
 // orders of the given module to IR.
 func (gen *generator) translateUseListOrderBBs() error {
 	// 6. Translate basic block specific use-list orders.
+	var diags Diagnostics
 	if len(gen.old.useListOrderBBs) > 0 {
 		gen.m.UseListOrderBBs = make([]*ir.UseListOrderBB, len(gen.old.useListOrderBBs))
 		for i, oldUseListOrderBB := range gen.old.useListOrderBBs {
 			useListOrderBB, err := gen.irUseListOrderBB(oldUseListOrderBB)
 			if err != nil {
-				return errors.WithStack(err)
+				diags.add(gen.errorAt(oldUseListOrderBB, err))
+				continue
 			}
 			gen.m.UseListOrderBBs[i] = useListOrderBB
 		}
 	}
-	return nil
+	return diags.err()
 }
 
 // irUseListOrderBB translates the given AST basic block specific use-list order
@@ -509,7 +530,7 @@ func (gen *generator) irUseListOrderBB(old *ast.UseListOrderBB) (*ir.UseListOrde
 	funcIdent := globalIdent(old.Func())
 	v, ok := gen.new.globals[funcIdent]
 	if !ok {
-		return nil, errors.Errorf("unable to locate global identifier %q", funcIdent.Ident())
+		return nil, gen.errorf(old.Func(), "unable to locate global identifier %q", funcIdent.Ident())
 	}
 	f, ok := v.(*ir.Func)
 	if !ok {
@@ -519,7 +540,7 @@ func (gen *generator) irUseListOrderBB(old *ast.UseListOrderBB) (*ir.UseListOrde
 	blockIdent := localIdent(old.Block())
 	block, err := findBlock(f, blockIdent)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, gen.errorAt(old.Block(), err)
 	}
 	// Indices.
 	indices := uintSlice(old.Indices())
@@ -0,0 +1,250 @@
+package asm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/llir/ll/ast"
+	"github.com/pkg/errors"
+)
+
+// === [ Source positions ] ====================================================
+
+// Pos is a position in an LLVM IR assembly source file.
+type Pos struct {
+	// Path of the source file; or empty if not specified.
+	Path string
+	// Line number (1-based).
+	Line int
+	// Column number in bytes (1-based).
+	Col int
+	// Byte offset (0-based).
+	Offset int
+}
+
+// String returns the string representation of the source position, in the
+// format "file:line:col" (or "line:col" if the path is not specified). Only the
+// path is returned if the line is unknown.
+func (pos Pos) String() string {
+	if pos.Line == 0 {
+		return pos.Path
+	}
+	if len(pos.Path) == 0 {
+		return fmt.Sprintf("%d:%d", pos.Line, pos.Col)
+	}
+	return fmt.Sprintf("%s:%d:%d", pos.Path, pos.Line, pos.Col)
+}
+
+// Span is a source span in an LLVM IR assembly source file, ranging from the
+// start position (inclusive) to the end position (exclusive).
+type Span struct {
+	// Start position of the span.
+	Start Pos
+	// End position of the span.
+	End Pos
+}
+
+// String returns the string representation of the source span, in the format
+// "file:line:col" of its start position.
+func (span Span) String() string {
+	return span.Start.String()
+}
+
+// --- [ Side table ] ----------------------------------------------------------
+
+// Positions is a side table recording the source spans of IR entities of
+// parsed LLVM IR modules. The zero value is an empty table ready to use.
+//
+// Source spans are recorded for global variables, indirect symbols, functions,
+// basic blocks, instructions, terminators, named metadata definitions and
+// metadata nodes (e.g. *ir.Global, *ir.Func, *ir.Block, ir.Instruction,
+// ir.Terminator, *metadata.NamedDef and metadata.MDNode).
+//
+// A Positions table is safe for concurrent use.
+type Positions struct {
+	// mu protects spans.
+	mu sync.RWMutex
+	// spans maps from IR entity to source span.
+	spans map[interface{}]Span
+}
+
+// NewPositions returns a new empty side table of source spans.
+func NewPositions() *Positions {
+	return &Positions{}
+}
+
+// Span returns the source span of the given IR entity, and a boolean
+// indicating if the source span of the IR entity was recorded.
+func (p *Positions) Span(v interface{}) (Span, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	span, ok := p.spans[v]
+	return span, ok
+}
+
+// Len returns the number of IR entities with recorded source spans.
+func (p *Positions) Len() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.spans)
+}
+
+// add records the source span of the given IR entity.
+func (p *Positions) add(v interface{}, span Span) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.spans == nil {
+		p.spans = make(map[interface{}]Span)
+	}
+	p.spans[v] = span
+}
+
+// --- [ Errors ] --------------------------------------------------------------
+
+// Error is an error at a given position in an LLVM IR assembly source file.
+//
+// The error message is formatted as "file:line:col: message".
+type Error struct {
+	// Source position of the error.
+	Pos Pos
+	// Underlying error.
+	Err error
+}
+
+// Error returns the error message of the error, prefixed by its source
+// position.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v: %v", e.Pos, e.Err)
+}
+
+// Cause returns the underlying error of the error.
+func (e *Error) Cause() error {
+	return e.Err
+}
+
+// Unwrap returns the underlying error of the error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Format implements fmt.Formatter, retaining the stack trace of the underlying
+// error when formatted with "%+v".
+func (e *Error) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			fmt.Fprintf(s, "%v: %+v", e.Pos, e.Err)
+			return
+		}
+		fmt.Fprint(s, e.Error())
+	case 's':
+		fmt.Fprint(s, e.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	}
+}
+
+// ### [ Helper functions ] ####################################################
+
+// file is an LLVM IR assembly source file.
+type file struct {
+	// Path of the source file; or empty if not specified.
+	path string
+	// Contents of the source file.
+	content string
+	// once guards the lazy computation of lines.
+	once sync.Once
+	// lines records the byte offset of the start of each line.
+	lines []int
+}
+
+// newFile returns a new source file based on the given path and contents.
+func newFile(path, content string) *file {
+	return &file{path: path, content: content}
+}
+
+// pos returns the source position of the given byte offset.
+func (f *file) pos(offset int) Pos {
+	f.once.Do(func() {
+		f.lines = append(f.lines, 0)
+		for i := 0; i < len(f.content); i++ {
+			if f.content[i] == '\n' {
+				f.lines = append(f.lines, i+1)
+			}
+		}
+	})
+	line := sort.Search(len(f.lines), func(i int) bool {
+		return f.lines[i] > offset
+	}) - 1
+	return Pos{
+		Path:   f.path,
+		Line:   line + 1,
+		Col:    offset - f.lines[line] + 1,
+		Offset: offset,
+	}
+}
+
+// span returns the source span of the given AST node, excluding trailing
+// whitespace.
+func (f *file) span(n *ast.Node) Span {
+	start, end := n.Offset(), n.Endoffset()
+	for end > start && strings.IndexByte(" \t\r\n", f.content[end-1]) != -1 {
+		end--
+	}
+	return Span{
+		Start: f.pos(start),
+		End:   f.pos(end),
+	}
+}
+
+// recordSpan records the source span of the given AST node for the IR entity,
+// if source positions are recorded.
+func (gen *generator) recordSpan(v interface{}, old ast.LlvmNode) {
+	if gen.positions == nil {
+		return
+	}
+	n := old.LlvmNode()
+	if n == nil {
+		return
+	}
+	gen.positions.add(v, gen.file.span(n))
+}
+
+// errorAt annotates the given error with the source position of the AST node,
+// unless the error is nil or already annotated with a source position.
+func (gen *generator) errorAt(old ast.LlvmNode, err error) error {
+	if err == nil || hasPos(err) || old == nil {
+		return err
+	}
+	n := old.LlvmNode()
+	if n == nil {
+		return err
+	}
+	return errors.WithStack(&Error{Pos: gen.file.pos(n.Offset()), Err: err})
+}
+
+// errorf returns a new error annotated with the source position of the AST
+// node, formatted according to the format specifier.
+func (gen *generator) errorf(old ast.LlvmNode, format string, args ...interface{}) error {
+	return gen.errorAt(old, errors.Errorf(format, args...))
+}
+
+// hasPos reports whether the given error is annotated with a source position.
+// Diagnostics are considered annotated, as each diagnostic has its own source
+// position.
+func hasPos(err error) bool {
+	for err != nil {
+		switch err.(type) {
+		case *Error, Diagnostics:
+			return true
+		}
+		cause, ok := err.(interface{ Cause() error })
+		if !ok {
+			return false
+		}
+		err = cause.Cause()
+	}
+	return false
+}
@@ -16,6 +16,14 @@ import (
 // irSpecializedMDNode returns the IR specialized metadata node corresponding to
 // the given AST specialized metadata node.
 func (gen *generator) irSpecializedMDNode(new metadata.Definition, old ast.SpecializedMDNode) (metadata.SpecializedNode, error) {
+	if new == nil {
+		// Create specialized metadata node literal (without ID), and record its
+		// source span.
+		node := newSpecializedMDNode(old)
+		node.SetID(-1)
+		gen.recordSpan(node, old)
+		new = node
+	}
 	switch old := old.(type) {
 	case *ast.DIBasicType:
 		return gen.irDIBasicType(new, old)
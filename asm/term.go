@@ -95,14 +95,15 @@ func (fgen *funcGen) newCallBrTerm(ident ir.LocalIdent, old *ast.CallBrTerm) (*i
 
 // translateTerms translates the AST terminators of the given function to IR.
 func (fgen *funcGen) translateTerms(oldBlocks []ast.BasicBlock) error {
+	var diags Diagnostics
 	for i, oldBlock := range oldBlocks {
 		block := fgen.f.Blocks[i]
 		old := oldBlock.Term()
 		if err := fgen.irTerm(block.Term, old); err != nil {
-			return errors.WithStack(err)
+			diags.add(fgen.gen.errorAt(old, err))
 		}
 	}
-	return nil
+	return diags.err()
 }
 
 // irTerm translates the AST terminator into an equivalent IR terminator.
@@ -361,13 +362,15 @@ func (fgen *funcGen) irInvokeTerm(new ir.Terminator, old *ast.InvokeTerm) error
 		}
 		sig = types.NewFunc(typ, paramTypes...)
 	}
-	// The invokee type is always pointer to function type.
-	ptrToSig := types.NewPointer(sig)
-	invokee, err := fgen.irValue(ptrToSig, old.Invokee())
+	invokee, err := fgen.irCallee(sig, old.Invokee())
 	if err != nil {
 		return errors.WithStack(err)
 	}
 	term.Invokee = invokee
+	// Record function signature of invokees of opaque pointer type.
+	if types.IsOpaquePointer(invokee.Type()) {
+		term.InvokeeSig = sig
+	}
 	// Normal control flow return point.
 	normalRetTarget, err := fgen.irBlock(old.NormalRetTarget())
 	if err != nil {
@@ -462,13 +465,15 @@ func (fgen *funcGen) irCallBrTerm(new ir.Terminator, old *ast.CallBrTerm) error
 		}
 		sig = types.NewFunc(typ, paramTypes...)
 	}
-	// The callee type is always pointer to function type.
-	ptrToSig := types.NewPointer(sig)
-	callee, err := fgen.irValue(ptrToSig, old.Callee())
+	callee, err := fgen.irCallee(sig, old.Callee())
 	if err != nil {
 		return errors.WithStack(err)
 	}
 	term.Callee = callee
+	// Record function signature of callees of opaque pointer type.
+	if types.IsOpaquePointer(callee.Type()) {
+		term.CalleeSig = sig
+	}
 	// Normal control flow return point.
 	normalRetTarget, err := fgen.irBlock(old.NormalRetTarget())
 	if err != nil {
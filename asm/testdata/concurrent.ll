@@ -0,0 +1,55 @@
+; Translation of function bodies and metadata definitions is done concurrently.
+
+@targets = global [2 x i8*] [i8* blockaddress(@g, %b), i8* blockaddress(@f, %a)]
+
+define i32 @f(i32 %x) #0 !dbg !4 {
+entry:
+	%cond = icmp eq i32 %x, 0, !dbg !7
+	br i1 %cond, label %a, label %b
+
+a:
+	%y = call i32 @g(i32 %x) #1, !dbg !7
+	ret i32 %y
+
+b:
+	ret i32 0
+}
+
+define i32 @g(i32 %x) #1 !dbg !8 {
+entry:
+	indirectbr i8* blockaddress(@f, %b), [label %b]
+
+b:
+	%0 = call i32 @h(i32 %x) #2
+	ret i32 %0
+}
+
+define i32 @h(i32 %x) #2 {
+	%1 = add i32 %x, 1
+	br label %2
+
+2:
+	%3 = phi i32 [ %1, %0 ]
+	ret i32 %3
+}
+
+define i8* @k() {
+	ret i8* blockaddress(@g, %b)
+}
+
+attributes #0 = { nounwind }
+attributes #1 = { noinline }
+attributes #2 = { readnone }
+
+!llvm.dbg.cu = !{!0}
+!llvm.module.flags = !{!3}
+
+!0 = distinct !DICompileUnit(language: DW_LANG_C99, file: !1, producer: "clang", isOptimized: false, runtimeVersion: 0, emissionKind: FullDebug, enums: !2)
+!1 = !DIFile(filename: "foo.c", directory: "/tmp")
+!2 = !{}
+!3 = !{i32 2, !"Debug Info Version", i32 3}
+!4 = distinct !DISubprogram(name: "f", scope: !1, file: !1, line: 1, type: !5, scopeLine: 1, spFlags: DISPFlagDefinition, unit: !0, retainedNodes: !2)
+!5 = !DISubroutineType(types: !6)
+!6 = !{null}
+!7 = !DILocation(line: 2, column: 3, scope: !4)
+!8 = distinct !DISubprogram(name: "g", scope: !1, file: !1, line: 5, type: !5, scopeLine: 5, spFlags: DISPFlagDefinition, unit: !0, retainedNodes: !2)
@@ -0,0 +1,12 @@
+; Undefined local variables in two functions; the errors of both functions are
+; reported, in order of occurrence.
+
+define i32 @f() {
+	ret i32 %x
+}
+
+define i32 @g() {
+	ret i32 %y
+}
+
+!0 = !{i32 1}
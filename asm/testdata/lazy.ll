@@ -0,0 +1,26 @@
+; Function bodies are translated on first use when parsed lazily. The body of
+; @c is translated along with that of @b, as referenced by a blockaddress
+; constant.
+
+declare i32 @ext(i32)
+
+define i32 @a(i32 %x) {
+	%1 = call i32 @ext(i32 %x)
+	ret i32 %1
+}
+
+define i8* @b() {
+	ret i8* blockaddress(@c, %exit)
+}
+
+define i32 @c(i1 %cond) {
+entry:
+	br i1 %cond, label %exit, label %0
+
+0:
+	br label %exit
+
+exit:
+	%1 = phi i32 [ 1, %entry ], [ 2, %0 ]
+	ret i32 %1
+}
@@ -0,0 +1,57 @@
+%struct.pair = type { i32, ptr }
+
+@g = global i32 42
+@p = global ptr @g
+@q = global ptr addrspace(1) null
+@s = global %struct.pair { i32 1, ptr @g }
+@e = global ptr getelementptr (%struct.pair, ptr @s, i64 0, i32 1)
+
+@a = alias i32, ptr @g
+
+define i32 @load(ptr %p) {
+entry:
+	%v = load i32, ptr %p
+	ret i32 %v
+}
+
+define ptr @gep(ptr %s, i64 %i) {
+entry:
+	%f = getelementptr %struct.pair, ptr %s, i64 %i, i32 1
+	%x = load ptr, ptr %f
+	ret ptr %x
+}
+
+define void @memory(ptr addrspace(1) %p, i32 %v) {
+entry:
+	%x = alloca i32
+	store i32 %v, ptr %x
+	%y = alloca ptr, addrspace(1)
+	store ptr addrspace(1) %p, ptr addrspace(1) %y
+	%old = atomicrmw add ptr %x, i32 1 seq_cst
+	%pair = cmpxchg ptr %x, i32 %old, i32 %v acq_rel monotonic
+	%c = addrspacecast ptr addrspace(1) %p to ptr
+	%i = ptrtoint ptr %c to i64
+	%n = icmp eq ptr %c, null
+	ret void
+}
+
+define i32 @call(ptr %f, ptr %s) personality ptr @personality {
+entry:
+	%r = call i32 %f(i32 1)
+	%n = call i32 (ptr, ...) @printf(ptr %s, i32 %r)
+	%m = invoke i32 %f(i32 2)
+		to label %exit unwind label %lpad
+
+lpad:
+	%lp = landingpad { ptr, i32 }
+		cleanup
+	resume { ptr, i32 } %lp
+
+exit:
+	%sum = add i32 %r, %m
+	ret i32 %sum
+}
+
+declare i32 @printf(ptr %format, ...)
+
+declare i32 @personality(...)
@@ -0,0 +1,21 @@
+; Source positions of IR entities.
+
+@x = global i32 42
+
+define i32 @f(i32 %a) !dbg !3 {
+entry:
+  %b = add i32 %a, 1, !dbg !5
+  br label %exit
+
+exit:
+  ret i32 %b
+}
+
+!llvm.dbg.cu = !{!0}
+
+!0 = distinct !DICompileUnit(language: DW_LANG_C99, file: !1, emissionKind: FullDebug)
+!1 = !DIFile(filename: "foo.c", directory: "/tmp")
+!2 = !DISubroutineType(types: !{null})
+!3 = distinct !DISubprogram(name: "f", scope: !1, file: !1, line: 1, type: !2, unit: !0, spFlags: DISPFlagDefinition)
+!4 = !{!"inline", !DILocation(line: 3, scope: !3)}
+!5 = !DILocation(line: 2, column: 7, scope: !3)
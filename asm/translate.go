@@ -47,12 +47,18 @@
 //       1. Translate AST global declarations and definitions, indirect symbol
 //          definitions, and function declarations and definitions to IR.
 //
+//          Note: global entities (e.g. function bodies) are translated
+//          concurrently by a bounded pool of goroutines.
+//
 //       2. Translate AST attribute group definitions to IR.
 //
 //       3. Translate AST named metadata definitions to IR.
 //
 //       4. Translate AST metadata definitions to IR.
 //
+//          Note: metadata definitions are translated concurrently by a bounded
+//          pool of goroutines.
+//
 // Note: steps 5-7 can be done concurrenty.
 //
 // 5. Translate use-list orders.
@@ -85,8 +91,11 @@ package asm
 import (
 	"fmt"
 	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/llir/ll"
 	"github.com/llir/ll/ast"
 	"github.com/llir/llvm/internal/enc"
 	"github.com/llir/llvm/internal/natsort"
@@ -98,8 +107,14 @@ import (
 )
 
 // translate translates the given AST module into an equivalent IR module.
-func translate(old *ast.Module) (*ir.Module, error) {
-	gen := newGenerator()
+func (gen *generator) translate(old *ast.Module) (*ir.Module, error) {
+	// Translation proceeds past errors within each step, to report all errors
+	// of the step. Subsequent steps are skipped if errors were reported, as
+	// they depend on the successful completion of previous steps.
+	//
+	// Opaque pointer types of global variables, functions and alloca
+	// instructions are used if the module contains ptr types.
+	gen.m.OpaquePointers = len(gen.opaquePtrs) > 0
 	// 1. Index AST top-level entities.
 	indexStart := time.Now()
 	if err := gen.translateTargetDefs(old); err != nil {
@@ -136,21 +151,17 @@ func translate(old *ast.Module) (*ir.Module, error) {
 		return nil, errors.WithStack(err)
 	}
 	dbg.Println("translate AST to IR took:", time.Since(translateStart))
+	var diags Diagnostics
 	// Note: step 5-7 can be done concurrenty.
 	//
 	// 5. Translate use-list orders.
-	if err := gen.translateUseListOrders(); err != nil {
-		return nil, errors.WithStack(err)
-	}
+	diags.add(gen.translateUseListOrders())
 	// 6. Translate basic block specific use-list orders.
-	if err := gen.translateUseListOrderBBs(); err != nil {
-		return nil, errors.WithStack(err)
-	}
+	diags.add(gen.translateUseListOrderBBs())
 	// 7. Fix basic block references in blockaddress constants.
-	for _, c := range gen.todo {
-		if err := fixBlockAddressConst(c); err != nil {
-			return nil, errors.WithStack(err)
-		}
+	diags.add(gen.fixBlockAddressConsts())
+	if err := diags.err(); err != nil {
+		return nil, errors.WithStack(err)
 	}
 	// 8. Add IR top-level declarations and definitions to the IR module in order
 	//    of occurrence in the input.
@@ -162,6 +173,35 @@ func translate(old *ast.Module) (*ir.Module, error) {
 	return gen.m, nil
 }
 
+// fixBlockAddressConsts fixes the basic block references in blockaddress
+// constants.
+func (gen *generator) fixBlockAddressConsts() error {
+	// 7. Fix basic block references in blockaddress constants.
+	//
+	// Sort blockaddress constants by order of occurrence in the input, as they
+	// are recorded in nondeterministic order during concurrent translation.
+	sort.SliceStable(gen.todo, func(i, j int) bool {
+		return gen.todo[i].old.Offset() < gen.todo[j].old.Offset()
+	})
+	var diags Diagnostics
+	for _, fix := range gen.todo {
+		if err := fixBlockAddressConst(fix.c); err != nil {
+			diags.add(gen.errorAt(fix.old, err))
+		}
+	}
+	return diags.err()
+}
+
+// diagnostics returns the diagnostics of the given translation error (if any)
+// and the warnings reported during translation, sorted by source position.
+func (gen *generator) diagnostics(err error) Diagnostics {
+	var diags Diagnostics
+	diags.add(err)
+	diags = append(diags, gen.warnings...)
+	diags.sort()
+	return diags
+}
+
 // addDefsToModule adds IR top-level declarations and definitions to the IR
 // module in order of occurrence in the input.
 func (gen *generator) addDefsToModule() {
@@ -291,14 +331,7 @@ func (gen *generator) addNamedMetadataDefsToModule() {
 // addMetadataDefsToModule adds IR metadata definitions to the IR module in
 // numeric order.
 func (gen *generator) addMetadataDefsToModule() {
-	metadataIDs := make([]int64, 0, len(gen.old.metadataDefs))
-	for id := range gen.old.metadataDefs {
-		metadataIDs = append(metadataIDs, id)
-	}
-	less := func(i, j int) bool {
-		return metadataIDs[i] < metadataIDs[j]
-	}
-	sort.Slice(metadataIDs, less)
+	metadataIDs := gen.metadataIDs()
 	if len(metadataIDs) > 0 {
 		gen.m.MetadataDefs = make([]metadata.Definition, len(metadataIDs))
 		for i, id := range metadataIDs {
@@ -313,6 +346,124 @@ func (gen *generator) addMetadataDefsToModule() {
 
 // ### [ Helper functions ] ####################################################
 
+// metadataIDs returns the metadata IDs of the AST metadata definitions in
+// numeric order.
+func (gen *generator) metadataIDs() []int64 {
+	metadataIDs := make([]int64, 0, len(gen.old.metadataDefs))
+	for id := range gen.old.metadataDefs {
+		metadataIDs = append(metadataIDs, id)
+	}
+	less := func(i, j int) bool {
+		return metadataIDs[i] < metadataIDs[j]
+	}
+	sort.Slice(metadataIDs, less)
+	return metadataIDs
+}
+
+// parallel invokes f for each index in [0, n) using at most the given number
+// of goroutines. The errors of all invocations are collected as diagnostics in
+// index order, which is thereby independent of scheduling and identical to the
+// result of sequential invocation. The panic of the lowest panicking index (if
+// any) is propagated to the calling goroutine.
+func parallel(workers, n int, f func(i int) error) error {
+	errs := make([]error, n)
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			errs[i] = f(i)
+		}
+	} else {
+		panics := make([]interface{}, n)
+		next := int64(-1)
+		wg := &sync.WaitGroup{}
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					i := int(atomic.AddInt64(&next, 1))
+					if i >= n {
+						return
+					}
+					panics[i], errs[i] = try(f, i)
+				}
+			}()
+		}
+		wg.Wait()
+		for _, p := range panics {
+			if p != nil {
+				panic(p)
+			}
+		}
+	}
+	var diags Diagnostics
+	for _, err := range errs {
+		diags.add(err)
+	}
+	return diags.err()
+}
+
+// try invokes f with the given index, recovering from any panic so that it may
+// be propagated to the calling goroutine.
+func try(f func(i int) error, i int) (p interface{}, err error) {
+	defer func() {
+		p = recover()
+	}()
+	return nil, f(i)
+}
+
+// rewriteOpaquePointers rewrites the opaque pointer types (ptr) of the given
+// LLVM IR assembly source, which are not supported by the grammar of the
+// llir/ll parser, into pointer types with element type of the same length
+// (i.e. ptr into i8*, and ptr addrspace(N) into i8 addrspace(N)*), so that
+// source positions are preserved. The rewritten source is returned, along
+// with the offsets of the rewritten pointer types.
+func rewriteOpaquePointers(content string) (string, map[int]bool) {
+	var (
+		buf     []byte
+		offsets map[int]bool
+		toks    []ll.Token
+		starts  []int
+		ends    []int
+	)
+	l := &ll.Lexer{}
+	l.Init(content)
+	for tok := l.Next(); tok != ll.EOI; tok = l.Next() {
+		if tok == ll.COMMENT || tok == ll.WHITESPACE {
+			continue
+		}
+		start, end := l.Pos()
+		toks = append(toks, tok)
+		starts = append(starts, start)
+		ends = append(ends, end)
+	}
+	for i, tok := range toks {
+		if tok != ll.PTR {
+			continue
+		}
+		if buf == nil {
+			buf = []byte(content)
+			offsets = make(map[int]bool)
+		}
+		start, end := starts[i], ends[i]
+		offsets[start] = true
+		// ptr addrspace(N)
+		if i+4 < len(toks) && toks[i+1] == ll.ADDRSPACE && toks[i+2] == ll.LPAREN && toks[i+3] == ll.INT_LIT_TOK && toks[i+4] == ll.RPAREN {
+			rparenEnd := ends[i+4]
+			s := "i8" + content[end:rparenEnd] + "*"
+			copy(buf[start:rparenEnd], s)
+			continue
+		}
+		copy(buf[start:end], "i8*")
+	}
+	if buf == nil {
+		return content, nil
+	}
+	return string(buf), offsets
+}
+
 // fixBlockAddressConst fixes the basic block of the given blockaddress
 // constant. During translation of constants, blockaddress constants are
 // assigned dummy basic blocks since function bodies have yet to be translated.
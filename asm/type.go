@@ -38,16 +38,18 @@ func (gen *generator) createTypeDefs() error {
 	// 2a. Index type identifiers and create scaffolding IR type definitions
 	//     (without bodies).
 	gen.new.typeDefs = make(map[string]types.Type)
+	var diags Diagnostics
 	for typeName, old := range gen.old.typeDefs {
 		// track is used to identify self-referential named types.
 		track := make(map[string]bool)
 		t, err := newType(typeName, old.Typ(), gen.old.typeDefs, track)
 		if err != nil {
-			return errors.WithStack(err)
+			diags.add(gen.errorAt(old, err))
+			continue
 		}
 		gen.new.typeDefs[typeName] = t
 	}
-	return nil
+	return diags.err()
 }
 
 // newType returns a new IR type (without body) based on the given AST type.
@@ -122,13 +124,14 @@ func newType(typeName string, old ast.LlvmNode, index map[string]*ast.TypeDef, t
 // IR.
 func (gen *generator) translateTypeDefs() error {
 	// 2b. Translate AST type definitions to IR.
+	var diags Diagnostics
 	for typeName, old := range gen.old.typeDefs {
 		t := gen.new.typeDefs[typeName]
 		if _, err := gen.irTypeDef(t, old.Typ()); err != nil {
-			return errors.WithStack(err)
+			diags.add(gen.errorAt(old, err))
 		}
 	}
-	return nil
+	return diags.err()
 }
 
 // irTypeDef translates the AST type into an equivalent IR type. A new IR type
@@ -301,12 +304,14 @@ func (gen *generator) irPointerType(t types.Type, old *ast.PointerType) (types.T
 	} else if !ok {
 		panic(fmt.Errorf("invalid IR type for AST pointer type; expected *types.PointerType, got %T", t))
 	}
-	// Element type.
-	elemType, err := gen.irType(old.Elem())
-	if err != nil {
-		return nil, errors.WithStack(err)
+	// Element type; or nil if opaque pointer type.
+	if !gen.isOpaquePointer(old) {
+		elemType, err := gen.irType(old.Elem())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		typ.ElemType = elemType
 	}
-	typ.ElemType = elemType
 	// Address space.
 	if n, ok := old.AddrSpace(); ok {
 		typ.AddrSpace = irAddrSpace(n)
@@ -314,6 +319,12 @@ func (gen *generator) irPointerType(t types.Type, old *ast.PointerType) (types.T
 	return typ, nil
 }
 
+// isOpaquePointer reports whether the given AST pointer type is an opaque
+// pointer type (i.e. ptr, rewritten before parsing by rewriteOpaquePointers).
+func (gen *generator) isOpaquePointer(old *ast.PointerType) bool {
+	return gen.opaquePtrs[old.Offset()]
+}
+
 // --- [ Vector types ] --------------------------------------------------------
 
 // irVectorType translates the AST vector type into an equivalent IR type. A new
@@ -514,7 +525,7 @@ func (gen *generator) irNamedType(t types.Type, old *ast.NamedType) (types.Type,
 	name := getTypeName(ident)
 	typ, ok := gen.new.typeDefs[name]
 	if !ok {
-		return nil, errors.Errorf("unable to locate type definition of named type %q", enc.TypeName(name))
+		return nil, gen.errorf(old, "unable to locate type definition of named type %q", enc.TypeName(name))
 	}
 	return typ, nil
 }
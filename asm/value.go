@@ -16,14 +16,17 @@ func (fgen *funcGen) irValue(typ types.Type, old ast.Value) (value.Value, error)
 		ident := globalIdent(*old)
 		v, ok := fgen.gen.new.globals[ident]
 		if !ok {
-			return nil, errors.Errorf("unable to locate global identifier %q", ident.Ident())
+			return nil, fgen.gen.errorf(old, "unable to locate global identifier %q", ident.Ident())
 		}
 		return v, nil
 	case *ast.LocalIdent:
 		ident := localIdent(*old)
 		v, ok := fgen.locals[ident]
 		if !ok {
-			return nil, errors.Errorf("unable to locate local identifier %q of %q", ident.Ident(), fgen.f.Ident())
+			return nil, fgen.gen.errorf(old, "unable to locate local identifier %q of %q", ident.Ident(), fgen.f.Ident())
+		}
+		if t := v.Type(); !t.Equal(typ) {
+			return nil, fgen.gen.errorf(old, "local identifier %q of %q type mismatch; expected %q, got %q", ident.Ident(), fgen.f.Ident(), typ, t)
 		}
 		return v, nil
 	case *ast.InlineAsm:
@@ -0,0 +1,440 @@
+package bitcode
+
+import (
+	"fmt"
+
+	"github.com/llir/llvm/internal/bitstream"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/pkg/errors"
+)
+
+// Attribute group indices.
+const (
+	// attrIndexReturn is the attribute index of return attributes.
+	attrIndexReturn = 0
+	// attrIndexFunc is the attribute index of function attributes.
+	attrIndexFunc = 0xFFFFFFFF
+	// Parameter attributes of the i:th parameter have attribute index i+1.
+)
+
+// attrGroup is an attribute group of a given attribute index.
+type attrGroup struct {
+	// Attribute index; 0 for return attributes, 0xFFFFFFFF for function
+	// attributes and i+1 for attributes of the i:th parameter.
+	idx uint64
+	// Attributes of the group; the underlying type is ir.FuncAttribute,
+	// ir.ParamAttribute or ir.ReturnAttribute depending on the attribute index.
+	attrs []interface{}
+	// Unique key of the attribute set; used to identify equivalent attribute
+	// groups.
+	key string
+}
+
+// translateAttrGroups translates the given parameter attribute group block.
+//
+//	ENTRY: [grpid, idx, attr0, attr1, ...]
+func (r *reader) translateAttrGroups(block *bitstream.Block) error {
+	for _, record := range block.Records() {
+		if record.Code != paramAttrGroupCodeEntry {
+			dbg.Printf("ignoring unknown parameter attribute group record with code %d", record.Code)
+			continue
+		}
+		if len(record.Ops) < 2 {
+			return errors.New("invalid parameter attribute group record; missing group ID and attribute index")
+		}
+		id, idx := record.Ops[0], record.Ops[1]
+		g := &attrGroup{idx: idx, key: fmt.Sprint(record.Ops[2:])}
+		ops := record.Ops[2:]
+		for len(ops) > 0 {
+			attr, n, err := r.translateAttr(idx, ops)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if !isValidAttr(idx, attr) {
+				return errors.Errorf("invalid attribute %v (%T) at attribute index %d", attr, attr, idx)
+			}
+			g.attrs = append(g.attrs, attr)
+			ops = ops[n:]
+		}
+		r.attrGroups[id] = g
+	}
+	return nil
+}
+
+// translateAttrLists translates the given parameter attribute block.
+//
+//	ENTRY: [attrgrp0, attrgrp1, ...]
+func (r *reader) translateAttrLists(block *bitstream.Block) error {
+	for _, record := range block.Records() {
+		if record.Code != paramAttrCodeEntry {
+			return errors.Errorf("support for parameter attribute record with code %d not yet implemented", record.Code)
+		}
+		var list []*attrGroup
+		for _, id := range record.Ops {
+			g, ok := r.attrGroups[id]
+			if !ok {
+				return errors.Errorf("unable to locate attribute group with ID %d", id)
+			}
+			list = append(list, g)
+		}
+		r.attrLists = append(r.attrLists, list)
+	}
+	return nil
+}
+
+// attrList returns the attribute list with the given 1-based attribute list
+// ID.
+func (r *reader) attrList(id uint64) ([]*attrGroup, error) {
+	if id == 0 {
+		return nil, nil
+	}
+	if id > uint64(len(r.attrLists)) {
+		return nil, errors.Errorf("invalid attribute list ID %d; expected < %d", id, len(r.attrLists)+1)
+	}
+	return r.attrLists[id-1], nil
+}
+
+// fnAttrGroupDef returns the attribute group definition of the function
+// attributes of the given attribute list; or nil if not present. Equivalent
+// sets of function attributes share the same attribute group definition.
+func (r *reader) fnAttrGroupDef(list []*attrGroup) *ir.AttrGroupDef {
+	for _, g := range list {
+		if g.idx != attrIndexFunc {
+			continue
+		}
+		if def, ok := r.attrGroupDefs[g.key]; ok {
+			return def
+		}
+		def := &ir.AttrGroupDef{ID: -1}
+		for _, attr := range g.attrs {
+			def.FuncAttrs = append(def.FuncAttrs, attr.(ir.FuncAttribute))
+		}
+		r.attrGroupDefs[g.key] = def
+		return def
+	}
+	return nil
+}
+
+// applyFuncAttrs applies the given attribute list to the given function.
+func (r *reader) applyFuncAttrs(f *ir.Func, list []*attrGroup) error {
+	for _, g := range list {
+		switch g.idx {
+		case attrIndexFunc:
+			// handled below.
+		case attrIndexReturn:
+			for _, attr := range g.attrs {
+				f.ReturnAttrs = append(f.ReturnAttrs, attr.(ir.ReturnAttribute))
+			}
+		default:
+			i := g.idx - 1
+			if i >= uint64(len(f.Params)) {
+				return errors.Errorf("invalid attribute index %d of function %q with %d parameters", g.idx, f.Ident(), len(f.Params))
+			}
+			param := f.Params[i]
+			for _, attr := range g.attrs {
+				param.Attrs = append(param.Attrs, attr.(ir.ParamAttribute))
+			}
+		}
+	}
+	if def := r.fnAttrGroupDef(list); def != nil {
+		f.FuncAttrs = append(f.FuncAttrs, def)
+	}
+	return nil
+}
+
+// callAttrs returns the function attributes, return attributes and parameter
+// attributes (indexed by argument index) of the given call site attribute
+// list.
+func (r *reader) callAttrs(list []*attrGroup) (fnAttrs []ir.FuncAttribute, retAttrs []ir.ReturnAttribute, paramAttrs map[int][]ir.ParamAttribute) {
+	paramAttrs = make(map[int][]ir.ParamAttribute)
+	for _, g := range list {
+		switch g.idx {
+		case attrIndexFunc:
+			// handled below.
+		case attrIndexReturn:
+			for _, attr := range g.attrs {
+				retAttrs = append(retAttrs, attr.(ir.ReturnAttribute))
+			}
+		default:
+			i := int(g.idx - 1)
+			for _, attr := range g.attrs {
+				paramAttrs[i] = append(paramAttrs[i], attr.(ir.ParamAttribute))
+			}
+		}
+	}
+	if def := r.fnAttrGroupDef(list); def != nil {
+		fnAttrs = append(fnAttrs, def)
+	}
+	return fnAttrs, retAttrs, paramAttrs
+}
+
+// translateAttr translates the attribute at the start of the given operands
+// of an attribute group with the given attribute index, returning the
+// attribute and the number of operands consumed.
+func (r *reader) translateAttr(idx uint64, ops []uint64) (interface{}, int, error) {
+	kind := ops[0]
+	switch kind {
+	case attrKindEnum, attrKindTypeNone:
+		// [kind, attr]
+		if len(ops) < 2 {
+			return nil, 0, errors.New("invalid enum attribute; missing attribute kind")
+		}
+		attr, err := r.irEnumAttr(idx, ops[1])
+		if err != nil {
+			return nil, 0, errors.WithStack(err)
+		}
+		return attr, 2, nil
+	case attrKindInt:
+		// [kind, attr, value]
+		if len(ops) < 3 {
+			return nil, 0, errors.New("invalid integer attribute; missing attribute kind and value")
+		}
+		attr, err := irIntAttr(ops[1], ops[2])
+		if err != nil {
+			return nil, 0, errors.WithStack(err)
+		}
+		return attr, 3, nil
+	case attrKindString, attrKindStringValue:
+		// [kind, key..., 0]
+		// [kind, key..., 0, value..., 0]
+		n := 1
+		key, m := nullTerminatedString(ops[n:])
+		n += m
+		if kind == attrKindString {
+			return ir.AttrString(key), n, nil
+		}
+		val, m := nullTerminatedString(ops[n:])
+		n += m
+		return ir.AttrPair{Key: key, Value: val}, n, nil
+	case attrKindType:
+		// [kind, attr, typeid]
+		if len(ops) < 3 {
+			return nil, 0, errors.New("invalid type attribute; missing attribute kind and type")
+		}
+		t, err := r.typeOf(ops[2])
+		if err != nil {
+			return nil, 0, errors.WithStack(err)
+		}
+		attr, err := irTypeAttr(ops[1], t)
+		if err != nil {
+			return nil, 0, errors.WithStack(err)
+		}
+		return attr, 3, nil
+	default:
+		return nil, 0, errors.Errorf("support for attribute encoding %d not yet implemented", kind)
+	}
+}
+
+// irEnumAttr returns the IR attribute corresponding to the given bitcode enum
+// attribute kind at the given attribute index.
+func (r *reader) irEnumAttr(idx, kind uint64) (interface{}, error) {
+	switch kind {
+	case attrUWTable:
+		return ir.UnwindTable{Kind: enum.UnwindTableKindNone}, nil
+	case attrByVal:
+		// byval without type.
+		return ir.Byval{}, nil
+	}
+	name, ok := attrNames[kind]
+	if !ok {
+		return nil, errors.Errorf("support for attribute kind %d not yet implemented", kind)
+	}
+	switch idx {
+	case attrIndexFunc:
+		if attr, ok := funcAttrFromName[name]; ok {
+			return attr, nil
+		}
+	case attrIndexReturn:
+		if attr, ok := returnAttrFromName[name]; ok {
+			return attr, nil
+		}
+	default:
+		if attr, ok := paramAttrFromName[name]; ok {
+			return attr, nil
+		}
+	}
+	return nil, errors.Errorf("support for attribute %q at attribute index %d not yet implemented", name, idx)
+}
+
+// irIntAttr returns the IR attribute corresponding to the given bitcode
+// integer attribute kind and value.
+func irIntAttr(kind, v uint64) (interface{}, error) {
+	switch kind {
+	case attrAlignment:
+		return ir.Align(v), nil
+	case attrStackAlignment:
+		return ir.AlignStack(v), nil
+	case attrDereferenceable:
+		return ir.Dereferenceable{N: v}, nil
+	case attrDereferenceableOrNull:
+		return ir.Dereferenceable{N: v, DerefOrNull: true}, nil
+	case attrAllocSize:
+		elemSizeIndex := int(v >> 32)
+		nelemsIndex := int(v & 0xFFFFFFFF)
+		if nelemsIndex == 0xFFFFFFFF {
+			nelemsIndex = -1
+		}
+		return ir.AllocSize{ElemSizeIndex: elemSizeIndex, NElemsIndex: nelemsIndex}, nil
+	case attrVScaleRange:
+		return ir.VectorScaleRange{Min: int(v >> 32), Max: int(v & 0xFFFFFFFF)}, nil
+	case attrUWTable:
+		return ir.UnwindTable{Kind: enum.UnwindTableKind(v)}, nil
+	case attrAllocKind:
+		return &ir.AllocKind{Kind: enum.AllocKind(v)}, nil
+	default:
+		return nil, errors.Errorf("support for integer attribute kind %d not yet implemented", kind)
+	}
+}
+
+// irTypeAttr returns the IR attribute corresponding to the given bitcode type
+// attribute kind and type.
+func irTypeAttr(kind uint64, t types.Type) (interface{}, error) {
+	switch kind {
+	case attrByVal:
+		return ir.Byval{Typ: t}, nil
+	case attrByRef:
+		return ir.ByRef{Typ: t}, nil
+	case attrStructRet:
+		return ir.SRet{Typ: t}, nil
+	case attrPreallocated:
+		return ir.Preallocated{Typ: t}, nil
+	case attrInAlloca:
+		return ir.InAlloca{Typ: t}, nil
+	case attrElementType:
+		return ir.ElementType{Typ: t}, nil
+	default:
+		return nil, errors.Errorf("support for type attribute kind %d not yet implemented", kind)
+	}
+}
+
+// attrNames maps from bitcode enum attribute kind to attribute name.
+//
+// ref: include/llvm/Bitcode/LLVMBitCodes.h (enum AttributeKindCodes)
+var attrNames = map[uint64]string{
+	attrAlwaysInline:                    "alwaysinline",
+	attrInlineHint:                      "inlinehint",
+	attrInReg:                           "inreg",
+	attrMinSize:                         "minsize",
+	attrNaked:                           "naked",
+	attrNest:                            "nest",
+	attrNoAlias:                         "noalias",
+	attrNoBuiltin:                       "nobuiltin",
+	attrNoCapture:                       "nocapture",
+	attrNoDuplicate:                     "noduplicate",
+	attrNoImplicitFloat:                 "noimplicitfloat",
+	attrNoInline:                        "noinline",
+	attrNonLazyBind:                     "nonlazybind",
+	attrNoRedZone:                       "noredzone",
+	attrNoReturn:                        "noreturn",
+	attrNoUnwind:                        "nounwind",
+	attrOptimizeForSize:                 "optsize",
+	attrReadNone:                        "readnone",
+	attrReadOnly:                        "readonly",
+	attrReturned:                        "returned",
+	attrReturnsTwice:                    "returns_twice",
+	attrSExt:                            "signext",
+	attrStackProtect:                    "ssp",
+	attrStackProtectReq:                 "sspreq",
+	attrStackProtectStrong:              "sspstrong",
+	attrSanitizeAddress:                 "sanitize_address",
+	attrSanitizeThread:                  "sanitize_thread",
+	attrSanitizeMemory:                  "sanitize_memory",
+	attrZExt:                            "zeroext",
+	attrBuiltin:                         "builtin",
+	attrCold:                            "cold",
+	attrOptimizeNone:                    "optnone",
+	attrNonNull:                         "nonnull",
+	attrJumpTable:                       "jumptable",
+	attrConvergent:                      "convergent",
+	attrSafeStack:                       "safestack",
+	attrArgMemOnly:                      "argmemonly",
+	attrSwiftSelf:                       "swiftself",
+	attrSwiftError:                      "swifterror",
+	attrNoRecurse:                       "norecurse",
+	attrInaccessibleMemOnly:             "inaccessiblememonly",
+	attrInaccessibleMemOrArgMemOnly:     "inaccessiblemem_or_argmemonly",
+	attrWriteOnly:                       "writeonly",
+	attrSpeculatable:                    "speculatable",
+	attrStrictFP:                        "strictfp",
+	attrSanitizeHWAddress:               "sanitize_hwaddress",
+	attrNoCFCheck:                       "nocf_check",
+	attrOptForFuzzing:                   "optforfuzzing",
+	attrShadowCallStack:                 "shadowcallstack",
+	attrSpeculativeLoadHardening:        "speculative_load_hardening",
+	attrImmArg:                          "immarg",
+	attrWillReturn:                      "willreturn",
+	attrNoFree:                          "nofree",
+	attrNoSync:                          "nosync",
+	attrSanitizeMemTag:                  "sanitize_memtag",
+	attrNoMerge:                         "nomerge",
+	attrNullPointerIsValid:              "null_pointer_is_valid",
+	attrNoUndef:                         "noundef",
+	attrMustProgress:                    "mustprogress",
+	attrNoCallback:                      "nocallback",
+	attrHot:                             "hot",
+	attrNoProfile:                       "noprofile",
+	attrSwiftAsync:                      "swiftasync",
+	attrNoSanitizeCoverage:              "nosanitize_coverage",
+	attrDisableSanitizerInstrumentation: "disable_sanitizer_instrumentation",
+	attrNoSanitizeBounds:                "nosanitize_bounds",
+	attrAllocAlign:                      "allocalign",
+	attrAllocatedPointer:                "allocptr",
+	attrPresplitCoroutine:               "presplitcoroutine",
+	attrFnRetThunkExtern:                "fn_ret_thunk_extern",
+}
+
+// Maps from attribute name to IR enum attribute.
+var (
+	// funcAttrFromName maps from attribute name to function attribute.
+	funcAttrFromName = make(map[string]enum.FuncAttr)
+	// paramAttrFromName maps from attribute name to parameter attribute.
+	paramAttrFromName = make(map[string]enum.ParamAttr)
+	// returnAttrFromName maps from attribute name to return attribute.
+	returnAttrFromName = make(map[string]enum.ReturnAttr)
+)
+
+func init() {
+	for attr := enum.FuncAttrAlwaysInline; attr <= enum.FuncAttrWriteOnly; attr++ {
+		funcAttrFromName[attr.String()] = attr
+	}
+	for attr := enum.ParamAttrAllocAlign; attr <= enum.ParamAttrZeroExt; attr++ {
+		paramAttrFromName[attr.String()] = attr
+	}
+	for attr := enum.ReturnAttrInReg; attr <= enum.ReturnAttrZeroExt; attr++ {
+		returnAttrFromName[attr.String()] = attr
+	}
+}
+
+// ### [ Helper functions ] ####################################################
+
+// isValidAttr reports whether the given attribute is valid at the given
+// attribute index.
+func isValidAttr(idx uint64, attr interface{}) bool {
+	switch idx {
+	case attrIndexFunc:
+		_, ok := attr.(ir.FuncAttribute)
+		return ok
+	case attrIndexReturn:
+		_, ok := attr.(ir.ReturnAttribute)
+		return ok
+	default:
+		_, ok := attr.(ir.ParamAttribute)
+		return ok
+	}
+}
+
+// nullTerminatedString returns the string of the given null-terminated
+// characters, and the number of operands consumed (including the terminating
+// null character).
+func nullTerminatedString(ops []uint64) (string, int) {
+	buf := make([]byte, 0, len(ops))
+	for i, op := range ops {
+		if op == 0 {
+			return string(buf), i + 1
+		}
+		buf = append(buf, byte(op))
+	}
+	return string(buf), len(ops)
+}
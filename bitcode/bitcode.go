@@ -0,0 +1,137 @@
+// Package bitcode implements a reader and writer for LLVM IR bitcode files.
+//
+// The bitcode reader produces the same in-memory representation of LLVM IR
+// modules as the asm package does for LLVM IR assembly files, so that the ir
+// API may be used unchanged on bitcode input. Conversely, the bitcode writer
+// encodes in-memory LLVM IR modules as LLVM IR bitcode, e.g. to be consumed by
+// llc or lld without the need for llvm-as.
+//
+// References:
+//
+//	https://llvm.org/docs/BitCodeFormat.html
+package bitcode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"log"
+
+	"github.com/llir/llvm/internal/bitstream"
+	"github.com/llir/llvm/ir"
+	"github.com/pkg/errors"
+)
+
+var (
+	// dbg is a logger which logs debug messages with "bitcode:" prefix to
+	// standard error.
+	dbg = log.New(ioutil.Discard, "", 0)
+	//dbg = log.New(os.Stderr, term.MagentaBold("bitcode:")+" ", 0)
+)
+
+// Magic numbers of LLVM bitcode files.
+var (
+	// magic is the magic number of raw LLVM IR bitcode files ('BC' 0xC0DE).
+	magic = []byte{'B', 'C', 0xC0, 0xDE}
+	// wrapperMagic is the magic number of LLVM IR bitcode wrapper headers, as
+	// used on Darwin (0x0B17C0DE in little-endian).
+	wrapperMagic = []byte{0xDE, 0xC0, 0x17, 0x0B}
+)
+
+// ParseFile parses the given LLVM IR bitcode file into an LLVM IR module.
+func ParseFile(path string) (*ir.Module, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return ParseBytes(path, buf)
+}
+
+// Parse parses the given LLVM IR bitcode file into an LLVM IR module, reading
+// from r. An optional path to the source file may be specified for error
+// reporting.
+func Parse(path string, r io.Reader) (*ir.Module, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return ParseBytes(path, buf)
+}
+
+// ParseBytes parses the given LLVM IR bitcode file into an LLVM IR module,
+// reading from b. An optional path to the source file may be specified for
+// error reporting.
+func ParseBytes(path string, b []byte) (*ir.Module, error) {
+	buf, err := stripHeader(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse %q", path)
+	}
+	blocks, err := bitstream.Parse(buf)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse bitstream of %q", path)
+	}
+	m, err := translate(blocks)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to translate bitcode of %q into an LLVM IR module", path)
+	}
+	return m, nil
+}
+
+// WriteFile writes the given LLVM IR module to the given file as LLVM IR
+// bitcode.
+func WriteFile(path string, m *ir.Module) error {
+	buf, err := encode(m)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := ioutil.WriteFile(path, buf, 0644); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// Write writes the given LLVM IR module to w as LLVM IR bitcode.
+func Write(w io.Writer, m *ir.Module) error {
+	buf, err := encode(m)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := w.Write(buf); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// IsBitcode reports whether the given contents start with the magic number of
+// an LLVM IR bitcode file (or bitcode wrapper header).
+func IsBitcode(b []byte) bool {
+	return bytes.HasPrefix(b, magic) || bytes.HasPrefix(b, wrapperMagic)
+}
+
+// ### [ Helper functions ] ####################################################
+
+// stripHeader returns the bitstream of the given LLVM IR bitcode file, with
+// the optional wrapper header and the magic number removed.
+func stripHeader(b []byte) ([]byte, error) {
+	if bytes.HasPrefix(b, wrapperMagic) {
+		// Bitcode wrapper header.
+		//
+		//    [Magic_{32}, Version_{32}, Offset_{32}, Size_{32}, CPUType_{32}]
+		const headerSize = 5 * 4
+		if len(b) < headerSize {
+			return nil, errors.New("truncated bitcode wrapper header")
+		}
+		offset := binary.LittleEndian.Uint32(b[8:])
+		size := binary.LittleEndian.Uint32(b[12:])
+		end := uint64(offset) + uint64(size)
+		if end > uint64(len(b)) {
+			return nil, errors.Errorf("invalid bitcode wrapper header; offset (%d) and size (%d) out of bounds (file size %d)", offset, size, len(b))
+		}
+		b = b[offset:end]
+	}
+	if !bytes.HasPrefix(b, magic) {
+		return nil, errors.New("invalid bitcode magic number")
+	}
+	return b[len(magic):], nil
+}
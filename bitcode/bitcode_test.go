@@ -0,0 +1,322 @@
+package bitcode
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/llir/llvm/asm"
+)
+
+func TestParseFile(t *testing.T) {
+	// Each bitcode file is compared against the LLVM IR assembly produced by
+	// llvm-dis, as parsed by the asm package.
+	golden := []struct {
+		path string
+	}{
+		{path: "testdata/hexfloat.bc"},
+		{path: "testdata/hexint.bc"},
+		{path: "testdata/inst_aggregate.bc"},
+		{path: "testdata/inst_binary.bc"},
+		{path: "testdata/inst_bitwise.bc"},
+		{path: "testdata/inst_conversion.bc"},
+		{path: "testdata/inst_memory.bc"},
+		{path: "testdata/inst_other.bc"},
+		{path: "testdata/inst_vector.bc"},
+		{path: "testdata/terminator.bc"},
+
+		// DIExpression used in named metdata definition.
+		{path: "testdata/diexpression.bc"},
+
+		// Multiple named metadata definitions with the same metadata name.
+		{path: "testdata/multiple_named_metadata_defs.bc"},
+
+		// function alignment.
+		{path: "testdata/func_align.bc"},
+
+		// global alignment.
+		{path: "testdata/global_align.bc"},
+
+		// Debug information, attribute groups, comdats and metadata
+		// attachments.
+		{path: "testdata/debug_info.bc"},
+
+		// Aliases, IFuncs, module-level inline assembly, constant expressions,
+		// exception handling, inline assembly and operand bundles.
+		{path: "testdata/misc.bc"},
+
+		// Type attributes (byval, sret and elementtype).
+		{path: "testdata/type_attr.bc"},
+	}
+	for _, g := range golden {
+		log.Printf("=== [ %s ] ===", g.path)
+		m, err := ParseFile(g.path)
+		if err != nil {
+			t.Errorf("unable to parse %q into LLVM IR module; %+v", g.path, err)
+			continue
+		}
+		llPath := strings.TrimSuffix(g.path, ".bc") + ".ll"
+		want, err := asm.ParseFile(llPath)
+		if err != nil {
+			t.Errorf("unable to parse %q into LLVM IR module; %+v", llPath, err)
+			continue
+		}
+		if diff := cmp.Diff(want.String(), m.String()); diff != "" {
+			t.Errorf("module %q mismatch (-want +got):\n%s", g.path, diff)
+			continue
+		}
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	// Each bitcode file is parsed, written and parsed again, and the resulting
+	// module is compared against the LLVM IR assembly produced by llvm-dis, as
+	// parsed by the asm package.
+	golden := []struct {
+		path string
+	}{
+		{path: "testdata/hexfloat.bc"},
+		{path: "testdata/hexint.bc"},
+		{path: "testdata/inst_aggregate.bc"},
+		{path: "testdata/inst_binary.bc"},
+		{path: "testdata/inst_bitwise.bc"},
+		{path: "testdata/inst_conversion.bc"},
+		{path: "testdata/inst_memory.bc"},
+		{path: "testdata/inst_other.bc"},
+		{path: "testdata/inst_vector.bc"},
+		{path: "testdata/terminator.bc"},
+		{path: "testdata/diexpression.bc"},
+		{path: "testdata/multiple_named_metadata_defs.bc"},
+		{path: "testdata/func_align.bc"},
+		{path: "testdata/global_align.bc"},
+		{path: "testdata/debug_info.bc"},
+		{path: "testdata/misc.bc"},
+		{path: "testdata/type_attr.bc"},
+	}
+	for _, g := range golden {
+		log.Printf("=== [ %s ] ===", g.path)
+		m, err := ParseFile(g.path)
+		if err != nil {
+			t.Errorf("unable to parse %q into LLVM IR module; %+v", g.path, err)
+			continue
+		}
+		buf := &bytes.Buffer{}
+		if err := Write(buf, m); err != nil {
+			t.Errorf("unable to write %q as LLVM IR bitcode; %+v", g.path, err)
+			continue
+		}
+		got, err := ParseBytes(g.path, buf.Bytes())
+		if err != nil {
+			t.Errorf("unable to parse bitcode written for %q; %+v", g.path, err)
+			continue
+		}
+		llPath := strings.TrimSuffix(g.path, ".bc") + ".ll"
+		want, err := asm.ParseFile(llPath)
+		if err != nil {
+			t.Errorf("unable to parse %q into LLVM IR module; %+v", llPath, err)
+			continue
+		}
+		if diff := cmp.Diff(want.String(), got.String()); diff != "" {
+			t.Errorf("module %q mismatch (-want +got):\n%s", g.path, diff)
+			continue
+		}
+	}
+}
+
+func TestWriteFileLLVM(t *testing.T) {
+	// Each bitcode file is parsed and written, and the written bitcode is
+	// disassembled by llvm-dis. The disassembled module is compared against the
+	// LLVM IR assembly produced by llvm-dis for the original bitcode file.
+	llvmDis, err := exec.LookPath("llvm-dis")
+	if err != nil {
+		t.Skip("llvm-dis not found")
+	}
+	paths, err := filepath.Glob("testdata/*.bc")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	dir, err := ioutil.TempDir("", "bitcode")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, path := range paths {
+		m, err := ParseFile(path)
+		if err != nil {
+			t.Errorf("unable to parse %q into LLVM IR module; %+v", path, err)
+			continue
+		}
+		bcPath := filepath.Join(dir, filepath.Base(path))
+		if err := WriteFile(bcPath, m); err != nil {
+			t.Errorf("unable to write %q as LLVM IR bitcode; %+v", path, err)
+			continue
+		}
+		out, err := exec.Command(llvmDis, "-o", "-", bcPath).CombinedOutput()
+		if err != nil {
+			t.Errorf("llvm-dis rejected bitcode written for %q; %v\n%s", path, err, out)
+			continue
+		}
+		got, err := asm.ParseBytes(path, out)
+		if err != nil {
+			t.Errorf("unable to parse llvm-dis output for %q; %+v", path, err)
+			continue
+		}
+		llPath := strings.TrimSuffix(path, ".bc") + ".ll"
+		want, err := asm.ParseFile(llPath)
+		if err != nil {
+			t.Errorf("unable to parse %q into LLVM IR module; %+v", llPath, err)
+			continue
+		}
+		if diff := cmp.Diff(want.String(), got.String()); diff != "" {
+			t.Errorf("module %q mismatch (-want +got):\n%s", path, diff)
+			continue
+		}
+	}
+}
+
+func TestWriteFileAsm(t *testing.T) {
+	// Each LLVM IR assembly file of the asm package is parsed and written, and
+	// the written bitcode is parsed again. If llvm-as and llvm-dis are present,
+	// the written bitcode is disassembled by llvm-dis and compared against the
+	// disassembly of the bitcode produced by llvm-as for the same module; thus
+	// entities not preserved in bitcode (e.g. parameter names of function
+	// declarations, and attribute groups and metadata not referenced) are lost
+	// as in LLVM.
+	paths, err := filepath.Glob("../asm/testdata/*.ll")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	dir, err := ioutil.TempDir("", "bitcode")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer os.RemoveAll(dir)
+	llvmAs, llvmDis, ok := findLLVMTools()
+	if !ok {
+		t.Log("llvm-as and llvm-dis of LLVM 15.0 or later not found; skipping comparison against LLVM")
+	}
+	for _, path := range paths {
+		m, err := asm.ParseFile(path)
+		if err != nil {
+			t.Errorf("unable to parse %q into LLVM IR module; %+v", path, err)
+			continue
+		}
+		name := filepath.Base(path)
+		bcPath := filepath.Join(dir, strings.TrimSuffix(name, ".ll")+".bc")
+		if err := WriteFile(bcPath, m); err != nil {
+			t.Errorf("unable to write %q as LLVM IR bitcode; %+v", path, err)
+			continue
+		}
+		if _, err := ParseFile(bcPath); err != nil {
+			t.Errorf("unable to parse bitcode written for %q; %+v", path, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		llPath := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(llPath, []byte(m.String()), 0644); err != nil {
+			t.Errorf("unable to write %q; %+v", llPath, err)
+			continue
+		}
+		// Note, verification is disabled as some test cases are deliberately
+		// invalid (e.g. invalid DIExpression operations).
+		refPath := filepath.Join(dir, strings.TrimSuffix(name, ".ll")+".ref.bc")
+		if out, err := exec.Command(llvmAs, "-disable-verify", "-o", refPath, llPath).CombinedOutput(); err != nil {
+			t.Errorf("llvm-as rejected %q; %v\n%s", path, err, out)
+			continue
+		}
+		want, err := disassemble(llvmDis, refPath)
+		if err != nil {
+			t.Errorf("llvm-dis rejected bitcode produced by llvm-as for %q; %v", path, err)
+			continue
+		}
+		got, err := disassemble(llvmDis, bcPath)
+		if err != nil {
+			t.Errorf("llvm-dis rejected bitcode written for %q; %v", path, err)
+			continue
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("module %q mismatch (-want +got):\n%s", path, diff)
+			continue
+		}
+	}
+}
+
+func TestIsBitcode(t *testing.T) {
+	golden := []struct {
+		in   []byte
+		want bool
+	}{
+		{in: []byte{'B', 'C', 0xC0, 0xDE, 0x35, 0x14}, want: true},
+		{in: []byte{0xDE, 0xC0, 0x17, 0x0B, 0x00, 0x00}, want: true},
+		{in: []byte("; ModuleID = 'foo'"), want: false},
+		{in: nil, want: false},
+	}
+	for _, g := range golden {
+		got := IsBitcode(g.in)
+		if got != g.want {
+			t.Errorf("IsBitcode(%q) mismatch; expected %v, got %v", g.in, g.want, got)
+		}
+	}
+}
+
+// ### [ Helper functions ] ####################################################
+
+// findLLVMTools returns the paths of llvm-as and llvm-dis, and reports whether
+// both are present in PATH with a version of at least LLVM 15.0, the version
+// targeted by the bitcode writer.
+func findLLVMTools() (llvmAs, llvmDis string, ok bool) {
+	llvmAs, err := exec.LookPath("llvm-as")
+	if err != nil {
+		return "", "", false
+	}
+	llvmDis, err = exec.LookPath("llvm-dis")
+	if err != nil {
+		return "", "", false
+	}
+	for _, tool := range []string{llvmAs, llvmDis} {
+		out, err := exec.Command(tool, "--version").Output()
+		if err != nil {
+			return "", "", false
+		}
+		m := reLLVMVersion.FindSubmatch(out)
+		if m == nil {
+			return "", "", false
+		}
+		if major, err := strconv.Atoi(string(m[1])); err != nil || major < 15 {
+			return "", "", false
+		}
+	}
+	return llvmAs, llvmDis, true
+}
+
+// reLLVMVersion matches the major version of LLVM in the version output of
+// LLVM tools.
+var reLLVMVersion = regexp.MustCompile(`LLVM version ([0-9]+)`)
+
+// disassemble disassembles the given bitcode file using llvm-dis, and returns
+// the disassembly without the module identifier and source filename, which
+// depend on the path of the bitcode file.
+func disassemble(llvmDis, bcPath string) (string, error) {
+	out, err := exec.Command(llvmDis, "-o", "-", bcPath).Output()
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "; ModuleID = ") || strings.HasPrefix(line, "source_filename = ") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
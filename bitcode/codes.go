@@ -0,0 +1,462 @@
+package bitcode
+
+// Block IDs of LLVM bitcode.
+//
+// ref: include/llvm/Bitcode/LLVMBitCodes.h
+const (
+	blockIDModule              = 8
+	blockIDParamAttr           = 9
+	blockIDParamAttrGroup      = 10
+	blockIDConstants           = 11
+	blockIDFunction            = 12
+	blockIDIdentification      = 13
+	blockIDValueSymtab         = 14
+	blockIDMetadata            = 15
+	blockIDMetadataAttachment  = 16
+	blockIDTypeNew             = 17
+	blockIDUseList             = 18
+	blockIDModuleStrtab        = 19
+	blockIDGlobalValSummary    = 20
+	blockIDOperandBundleTags   = 21
+	blockIDMetadataKind        = 22
+	blockIDStrtab              = 23
+	blockIDFullLTOGlobalValSum = 24
+	blockIDSymtab              = 25
+	blockIDSyncScopeNames      = 26
+)
+
+// Identification block record codes.
+const (
+	identCodeString = 1 // IDENTIFICATION: [strchr x N]
+	identCodeEpoch  = 2 // EPOCH: [epoch#]
+)
+
+// Module block record codes.
+const (
+	moduleCodeVersion        = 1  // VERSION: [version#]
+	moduleCodeTriple         = 2  // TRIPLE: [strchr x N]
+	moduleCodeDataLayout     = 3  // DATALAYOUT: [strchr x N]
+	moduleCodeAsm            = 4  // ASM: [strchr x N]
+	moduleCodeSectionName    = 5  // SECTIONNAME: [strchr x N]
+	moduleCodeDepLib         = 6  // DEPLIB: [strchr x N]
+	moduleCodeGlobalVar      = 7  // GLOBALVAR: [strtab offset, strtab size, ...]
+	moduleCodeFunction       = 8  // FUNCTION: [strtab offset, strtab size, ...]
+	moduleCodeAliasOld       = 9  // ALIAS: [alias type, aliasee val#, linkage, ...]
+	moduleCodeGCName         = 11 // GCNAME: [strchr x N]
+	moduleCodeComdat         = 12 // COMDAT: [strtab offset, strtab size, selection_kind]
+	moduleCodeVSTOffset      = 13 // VSTOFFSET: [offset]
+	moduleCodeAlias          = 14 // ALIAS: [strtab offset, strtab size, ...]
+	moduleCodeMetadataValues = 15 // METADATA_VALUES: [numvals]
+	moduleCodeSourceFilename = 16 // SOURCE_FILENAME: [namechar x N]
+	moduleCodeHash           = 17 // HASH: [5 x i32]
+	moduleCodeIFunc          = 18 // IFUNC: [strtab offset, strtab size, ...]
+)
+
+// Parameter attribute block record codes.
+const (
+	paramAttrCodeEntryOld   = 1 // ENTRY: [paramidx0, attr0, paramidx1, attr1...]
+	paramAttrCodeEntry      = 2 // ENTRY: [attrgrp0, attrgrp1, ...]
+	paramAttrGroupCodeEntry = 3 // ENTRY: [grpid, idx, attr0, attr1, ...]
+)
+
+// Attribute encoding kinds of parameter attribute group entries.
+const (
+	attrKindEnum        = 0 // [kind]
+	attrKindInt         = 1 // [kind, value]
+	attrKindString      = 3 // [strchr x N, 0]
+	attrKindStringValue = 4 // [strchr x N, 0, strchr x N, 0]
+	attrKindTypeNone    = 5 // [kind]
+	attrKindType        = 6 // [kind, typeid]
+)
+
+// Attribute kind codes.
+const (
+	attrAlignment                       = 1
+	attrAlwaysInline                    = 2
+	attrByVal                           = 3
+	attrInlineHint                      = 4
+	attrInReg                           = 5
+	attrMinSize                         = 6
+	attrNaked                           = 7
+	attrNest                            = 8
+	attrNoAlias                         = 9
+	attrNoBuiltin                       = 10
+	attrNoCapture                       = 11
+	attrNoDuplicate                     = 12
+	attrNoImplicitFloat                 = 13
+	attrNoInline                        = 14
+	attrNonLazyBind                     = 15
+	attrNoRedZone                       = 16
+	attrNoReturn                        = 17
+	attrNoUnwind                        = 18
+	attrOptimizeForSize                 = 19
+	attrReadNone                        = 20
+	attrReadOnly                        = 21
+	attrReturned                        = 22
+	attrReturnsTwice                    = 23
+	attrSExt                            = 24
+	attrStackAlignment                  = 25
+	attrStackProtect                    = 26
+	attrStackProtectReq                 = 27
+	attrStackProtectStrong              = 28
+	attrStructRet                       = 29
+	attrSanitizeAddress                 = 30
+	attrSanitizeThread                  = 31
+	attrSanitizeMemory                  = 32
+	attrUWTable                         = 33
+	attrZExt                            = 34
+	attrBuiltin                         = 35
+	attrCold                            = 36
+	attrOptimizeNone                    = 37
+	attrInAlloca                        = 38
+	attrNonNull                         = 39
+	attrJumpTable                       = 40
+	attrDereferenceable                 = 41
+	attrDereferenceableOrNull           = 42
+	attrConvergent                      = 43
+	attrSafeStack                       = 44
+	attrArgMemOnly                      = 45
+	attrSwiftSelf                       = 46
+	attrSwiftError                      = 47
+	attrNoRecurse                       = 48
+	attrInaccessibleMemOnly             = 49
+	attrInaccessibleMemOrArgMemOnly     = 50
+	attrAllocSize                       = 51
+	attrWriteOnly                       = 52
+	attrSpeculatable                    = 53
+	attrStrictFP                        = 54
+	attrSanitizeHWAddress               = 55
+	attrNoCFCheck                       = 56
+	attrOptForFuzzing                   = 57
+	attrShadowCallStack                 = 58
+	attrSpeculativeLoadHardening        = 59
+	attrImmArg                          = 60
+	attrWillReturn                      = 61
+	attrNoFree                          = 62
+	attrNoSync                          = 63
+	attrSanitizeMemTag                  = 64
+	attrPreallocated                    = 65
+	attrNoMerge                         = 66
+	attrNullPointerIsValid              = 67
+	attrNoUndef                         = 68
+	attrByRef                           = 69
+	attrMustProgress                    = 70
+	attrNoCallback                      = 71
+	attrHot                             = 72
+	attrNoProfile                       = 73
+	attrVScaleRange                     = 74
+	attrSwiftAsync                      = 75
+	attrNoSanitizeCoverage              = 76
+	attrElementType                     = 77
+	attrDisableSanitizerInstrumentation = 78
+	attrNoSanitizeBounds                = 79
+	attrAllocAlign                      = 80
+	attrAllocatedPointer                = 81
+	attrAllocKind                       = 82
+	attrPresplitCoroutine               = 83
+	attrFnRetThunkExtern                = 84
+)
+
+// Type block record codes.
+const (
+	typeCodeNumEntry      = 1  // NUMENTRY: [numentries]
+	typeCodeVoid          = 2  // VOID
+	typeCodeFloat         = 3  // FLOAT
+	typeCodeDouble        = 4  // DOUBLE
+	typeCodeLabel         = 5  // LABEL
+	typeCodeOpaque        = 6  // OPAQUE
+	typeCodeInteger       = 7  // INTEGER: [width]
+	typeCodePointer       = 8  // POINTER: [pointee type, address space]
+	typeCodeFunctionOld   = 9  // FUNCTION: [vararg, attrid, retty, paramty x N]
+	typeCodeHalf          = 10 // HALF
+	typeCodeArray         = 11 // ARRAY: [numelts, eltty]
+	typeCodeVector        = 12 // VECTOR: [numelts, eltty]
+	typeCodeX86FP80       = 13 // X86 LONG DOUBLE
+	typeCodeFP128         = 14 // LONG DOUBLE (112 bit mantissa)
+	typeCodePPCFP128      = 15 // PPC LONG DOUBLE (2 doubles)
+	typeCodeMetadata      = 16 // METADATA
+	typeCodeX86MMX        = 17 // X86 MMX
+	typeCodeStructAnon    = 18 // STRUCT_ANON: [ispacked, eltty x N]
+	typeCodeStructName    = 19 // STRUCT_NAME: [strchr x N]
+	typeCodeStructNamed   = 20 // STRUCT_NAMED: [ispacked, eltty x N]
+	typeCodeFunction      = 21 // FUNCTION: [vararg, retty, paramty x N]
+	typeCodeToken         = 22 // TOKEN
+	typeCodeBFloat        = 23 // BRAIN FLOATING POINT
+	typeCodeX86AMX        = 24 // X86 AMX
+	typeCodeOpaquePointer = 25 // OPAQUE_POINTER: [address space]
+)
+
+// Value symbol table block record codes.
+const (
+	vstCodeEntry   = 1 // VST_ENTRY: [valueid, namechar x N]
+	vstCodeBBEntry = 2 // VST_BBENTRY: [bbid, namechar x N]
+	vstCodeFnEntry = 3 // VST_FNENTRY: [valueid, offset, namechar x N]
+)
+
+// Metadata block record codes.
+const (
+	metadataCodeStringOld            = 1  // MDSTRING: [values]
+	metadataCodeValue                = 2  // VALUE: [type num, value num]
+	metadataCodeNode                 = 3  // NODE: [n x md num]
+	metadataCodeName                 = 4  // STRING: [values]
+	metadataCodeDistinctNode         = 5  // DISTINCT_NODE: [n x md num]
+	metadataCodeKind                 = 6  // [n x [id, name]]
+	metadataCodeLocation             = 7  // [distinct, line, col, scope, inlined-at?]
+	metadataCodeOldNode              = 8  // OLD_NODE: [n x (type num, value num)]
+	metadataCodeOldFnNode            = 9  // OLD_FN_NODE: [n x (type num, value num)]
+	metadataCodeNamedNode            = 10 // NAMED_NODE: [n x mdnodes]
+	metadataCodeAttachment           = 11 // [m x [value, [n x [id, mdnode]]]
+	metadataCodeGenericDebug         = 12 // [distinct, tag, vers, header, n x md num]
+	metadataCodeSubrange             = 13 // [distinct, count, lo]
+	metadataCodeEnumerator           = 14 // [isUnsigned|distinct, value, name]
+	metadataCodeBasicType            = 15 // [distinct, tag, name, size, align, enc]
+	metadataCodeFile                 = 16 // [distinct, filename, directory, checksumkind, checksum]
+	metadataCodeDerivedType          = 17 // [distinct, ...]
+	metadataCodeCompositeType        = 18 // [distinct, ...]
+	metadataCodeSubroutineType       = 19 // [distinct, flags, types, cc]
+	metadataCodeCompileUnit          = 20 // [distinct, ...]
+	metadataCodeSubprogram           = 21 // [distinct, ...]
+	metadataCodeLexicalBlock         = 22 // [distinct, scope, file, line, column]
+	metadataCodeLexicalBlockFile     = 23 // [distinct, scope, file, discriminator]
+	metadataCodeNamespace            = 24 // [distinct, scope, file, name, line, exportSymbols]
+	metadataCodeTemplateType         = 25 // [distinct, scope, name, type, ...]
+	metadataCodeTemplateValue        = 26 // [distinct, scope, name, type, value, ...]
+	metadataCodeGlobalVar            = 27 // [distinct, ...]
+	metadataCodeLocalVar             = 28 // [distinct, ...]
+	metadataCodeExpression           = 29 // [distinct, n x element]
+	metadataCodeObjCProperty         = 30 // [distinct, name, file, line, ...]
+	metadataCodeImportedEntity       = 31 // [distinct, tag, scope, entity, line, name]
+	metadataCodeModule               = 32 // [distinct, scope, name, ...]
+	metadataCodeMacro                = 33 // [distinct, macinfo, line, name, value]
+	metadataCodeMacroFile            = 34 // [distinct, macinfo, line, file, ...]
+	metadataCodeStrings              = 35 // [count, offset] blob([lengths][chars])
+	metadataCodeGlobalDeclAttachment = 36 // [valueid, n x [id, mdnode]]
+	metadataCodeGlobalVarExpr        = 37 // [distinct, var, expr]
+	metadataCodeIndexOffset          = 38 // [offset]
+	metadataCodeIndex                = 39 // [bitpos]
+	metadataCodeLabel                = 40 // [distinct, scope, name, file, line]
+	metadataCodeStringType           = 41 // [distinct, name, size, align,...]
+	metadataCodeCommonBlock          = 44 // [distinct, scope, name, variable,...]
+	metadataCodeGenericSubrange      = 45 // [distinct, count, lo, up, stride]
+	metadataCodeArgList              = 46 // [n x [type num, value num]]
+)
+
+// Constants block record codes.
+const (
+	constCodeSetType            = 1  // SETTYPE: [typeid]
+	constCodeNull               = 2  // NULL
+	constCodeUndef              = 3  // UNDEF
+	constCodeInteger            = 4  // INTEGER: [intval]
+	constCodeWideInteger        = 5  // WIDE_INTEGER: [n x intval]
+	constCodeFloat              = 6  // FLOAT: [fpval]
+	constCodeAggregate          = 7  // AGGREGATE: [n x value number]
+	constCodeString             = 8  // STRING: [values]
+	constCodeCString            = 9  // CSTRING: [values]
+	constCodeCEBinop            = 10 // CE_BINOP: [opcode, opval, opval]
+	constCodeCECast             = 11 // CE_CAST: [opcode, opty, opval]
+	constCodeCEGEP              = 12 // CE_GEP: [n x operands]
+	constCodeCESelect           = 13 // CE_SELECT: [opval, opval, opval]
+	constCodeCEExtractElt       = 14 // CE_EXTRACTELT: [opty, opval, opval]
+	constCodeCEInsertElt        = 15 // CE_INSERTELT: [opval, opval, opval]
+	constCodeCEShuffleVec       = 16 // CE_SHUFFLEVEC: [opval, opval, opval]
+	constCodeCECmp              = 17 // CE_CMP: [opty, opval, opval, pred]
+	constCodeInlineAsmOld       = 18 // INLINEASM: [sideeffect|alignstack, asmstr, conststr]
+	constCodeCEShufVecEx        = 19 // SHUFVEC_EX: [opty, opval, opval, opval]
+	constCodeCEInboundsGEP      = 20 // INBOUNDS_GEP: [n x operands]
+	constCodeBlockAddress       = 21 // BLOCKADDRESS: [fnty, fnval, bb#]
+	constCodeData               = 22 // DATA: [n x elements]
+	constCodeInlineAsmOld2      = 23 // INLINEASM: [sideeffect|alignstack|asmdialect, asmstr, conststr]
+	constCodeCEGEPWithInrange   = 24 // CE_GEP_WITH_INRANGE_INDEX: [opty, flags, n x operands]
+	constCodeCEUnop             = 25 // CE_UNOP: [opcode, opval]
+	constCodePoison             = 26 // POISON
+	constCodeDSOLocalEquivalent = 27 // DSO_LOCAL_EQUIVALENT: [gvty, gv]
+	constCodeInlineAsmOld3      = 28 // INLINEASM: [sideeffect|alignstack|asmdialect|unwind, asmstr, conststr]
+	constCodeNoCFIValue         = 29 // NO_CFI: [fty, f]
+	constCodeInlineAsm          = 30 // INLINEASM: [fnty, sideeffect|alignstack|asmdialect|unwind, asmstr, conststr]
+)
+
+// Cast opcodes.
+const (
+	castTrunc         = 0
+	castZExt          = 1
+	castSExt          = 2
+	castFPToUI        = 3
+	castFPToSI        = 4
+	castUIToFP        = 5
+	castSIToFP        = 6
+	castFPTrunc       = 7
+	castFPExt         = 8
+	castPtrToInt      = 9
+	castIntToPtr      = 10
+	castBitCast       = 11
+	castAddrSpaceCast = 12
+)
+
+// Unary opcodes.
+const (
+	unopFNeg = 0
+)
+
+// Binary opcodes.
+const (
+	binopAdd  = 0
+	binopSub  = 1
+	binopMul  = 2
+	binopUDiv = 3
+	binopSDiv = 4 // overloaded for FP
+	binopURem = 5
+	binopSRem = 6 // overloaded for FP
+	binopShl  = 7
+	binopLShr = 8
+	binopAShr = 9
+	binopAnd  = 10
+	binopOr   = 11
+	binopXor  = 12
+)
+
+// Atomic read-modify-write operations.
+const (
+	rmwXchg = 0
+	rmwAdd  = 1
+	rmwSub  = 2
+	rmwAnd  = 3
+	rmwNand = 4
+	rmwOr   = 5
+	rmwXor  = 6
+	rmwMax  = 7
+	rmwMin  = 8
+	rmwUMax = 9
+	rmwUMin = 10
+	rmwFAdd = 11
+	rmwFSub = 12
+	rmwFMax = 13
+	rmwFMin = 14
+)
+
+// Overflow and exact flags of binary operations.
+const (
+	oboNoUnsignedWrap = 0
+	oboNoSignedWrap   = 1
+	peoExact          = 0
+)
+
+// Fast-math flags.
+const (
+	fmfUnsafeAlgebra   = 1 << 0 // legacy
+	fmfNoNaNs          = 1 << 1
+	fmfNoInfs          = 1 << 2
+	fmfNoSignedZeros   = 1 << 3
+	fmfAllowReciprocal = 1 << 4
+	fmfAllowContract   = 1 << 5
+	fmfApproxFunc      = 1 << 6
+	fmfAllowReassoc    = 1 << 7
+)
+
+// Atomic orderings.
+const (
+	orderingNotAtomic = 0
+	orderingUnordered = 1
+	orderingMonotonic = 2
+	orderingAcquire   = 3
+	orderingRelease   = 4
+	orderingAcqRel    = 5
+	orderingSeqCst    = 6
+)
+
+// Synchronization scope IDs.
+const (
+	syncScopeSingleThread = 0
+	syncScopeSystem       = 1
+)
+
+// Function block record codes.
+const (
+	funcCodeDeclareBlocks      = 1  // DECLAREBLOCKS: [n]
+	funcCodeInstBinop          = 2  // BINOP: [opcode, ty, opval, opval]
+	funcCodeInstCast           = 3  // CAST: [opcode, ty, opty, opval]
+	funcCodeInstGEPOld         = 4  // GEP: [n x operands]
+	funcCodeInstSelect         = 5  // SELECT: [ty, opval, opval, opval]
+	funcCodeInstExtractElt     = 6  // EXTRACTELT: [opty, opval, opval]
+	funcCodeInstInsertElt      = 7  // INSERTELT: [ty, opval, opval, opval]
+	funcCodeInstShuffleVec     = 8  // SHUFFLEVEC: [ty, opval, opval, opval]
+	funcCodeInstCmp            = 9  // CMP: [opty, opval, opval, pred]
+	funcCodeInstRet            = 10 // RET: [opty, opval<both optional>]
+	funcCodeInstBr             = 11 // BR: [bb#, bb#, cond] or [bb#]
+	funcCodeInstSwitch         = 12 // SWITCH: [opty, op0, op1, ...]
+	funcCodeInstInvoke         = 13 // INVOKE: [attr, fnty, op0, op1, ...]
+	funcCodeInstUnreachable    = 15 // UNREACHABLE
+	funcCodeInstPhi            = 16 // PHI: [ty, val0, bb0, ...]
+	funcCodeInstAlloca         = 19 // ALLOCA: [instty, opty, op, align]
+	funcCodeInstLoad           = 20 // LOAD: [opty, op, align, vol]
+	funcCodeInstVAArg          = 23 // VAARG: [valistty, valist, instty]
+	funcCodeInstStoreOld       = 24 // STORE: [ptrty, ptr, val, align, vol]
+	funcCodeInstExtractVal     = 26 // EXTRACTVAL: [n x operands]
+	funcCodeInstInsertVal      = 27 // INSERTVAL: [n x operands]
+	funcCodeInstCmp2           = 28 // CMP2: [opty, opval, opval, pred]
+	funcCodeInstVSelect        = 29 // VSELECT: [ty, opval, opval, predty, pred]
+	funcCodeInstInboundsGEPOld = 30 // INBOUNDS_GEP: [n x operands]
+	funcCodeInstIndirectBr     = 31 // INDIRECTBR: [opty, op0, op1, ...]
+	funcCodeDebugLocAgain      = 33 // DEBUG_LOC_AGAIN
+	funcCodeInstCall           = 34 // CALL: [attr, cc, fnty, fnid, args...]
+	funcCodeDebugLoc           = 35 // DEBUG_LOC: [Line, Col, ScopeVal, IAVal]
+	funcCodeInstFence          = 36 // FENCE: [ordering, synchscope]
+	funcCodeInstCmpXchgOld     = 37 // CMPXCHG: [ptrty, ptr, cmp, val, vol, ordering, synchscope, failure_ordering?, weak?]
+	funcCodeInstAtomicRMWOld   = 38 // ATOMICRMW: [ptrty, ptr, val, operation, align, vol, ordering, synchscope]
+	funcCodeInstResume         = 39 // RESUME: [opval]
+	funcCodeInstLandingPadOld  = 40 // LANDINGPAD: [ty, val, val, num, id0, val0...]
+	funcCodeInstLoadAtomic     = 41 // LOAD: [opty, op, align, vol, ordering, synchscope]
+	funcCodeInstStoreAtomicOld = 42 // STORE: [ptrty, ptr, val, align, vol, ordering, synchscope]
+	funcCodeInstGEP            = 43 // GEP: [inbounds, n x operands]
+	funcCodeInstStore          = 44 // STORE: [ptrty, ptr, valty, val, align, vol]
+	funcCodeInstStoreAtomic    = 45 // STORE: [ptrty, ptr, val, align, vol]
+	funcCodeInstCmpXchg        = 46 // CMPXCHG: [ptrty, ptr, cmp, val, vol, success_ordering, synchscope, failure_ordering, weak]
+	funcCodeInstLandingPad     = 47 // LANDINGPAD: [ty, val, num, id0, val0...]
+	funcCodeInstCleanupRet     = 48 // CLEANUPRET: [val] or [val, bb#]
+	funcCodeInstCatchRet       = 49 // CATCHRET: [val, bb#]
+	funcCodeInstCatchPad       = 50 // CATCHPAD: [bb#, bb#, num, args...]
+	funcCodeInstCleanupPad     = 51 // CLEANUPPAD: [num, args...]
+	funcCodeInstCatchSwitch    = 52 // CATCHSWITCH: [num, args...] or [num, args..., bb]
+	funcCodeOperandBundle      = 55 // OPERAND_BUNDLE: [tag#, value...]
+	funcCodeInstUnop           = 56 // UNOP: [opcode, ty, opval]
+	funcCodeInstCallBr         = 57 // CALLBR: [attr, cc, norm, transfs, fnty, fnid, args]
+	funcCodeInstFreeze         = 58 // FREEZE: [opty, opval]
+	funcCodeInstAtomicRMW      = 59 // ATOMICRMW: [ptrty, ptr, valty, val, operation, align, vol, ordering, synchscope]
+)
+
+// Calling convention flags of call instructions.
+const (
+	callTail         = 0
+	callCConv        = 1
+	callMustTail     = 14
+	callExplicitType = 15
+	callNoTail       = 16
+	callFMF          = 17
+)
+
+// Packed alignment and flags of alloca instructions.
+const (
+	allocaAlignLowerMask = 1<<5 - 1
+	allocaInAlloca       = 1 << 5
+	allocaExplicitType   = 1 << 6
+	allocaSwiftError     = 1 << 7
+	allocaAlignUpperBit  = 8
+)
+
+// explicitTypeFlag is set in the calling convention field of invoke
+// instructions with an explicit function type.
+const explicitTypeFlag = 1 << 13
+
+// Operand bundle tags block record codes.
+const (
+	operandBundleTagCode = 1 // OPERAND_BUNDLE_TAG: [strchr x N]
+)
+
+// Sync scope names block record codes.
+const (
+	syncScopeNameCode = 1 // SYNC_SCOPE_NAME: [strchr x N]
+)
+
+// String table block record codes.
+const (
+	strtabBlob = 1 // BLOB: [blob]
+)
@@ -0,0 +1,652 @@
+package bitcode
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/llir/llvm/internal/bitstream"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+	"github.com/pkg/errors"
+)
+
+// translateConstants translates the given constants block, adding the
+// constants to the given value list. The constants are translated on demand;
+// see valueList.get.
+func (r *reader) translateConstants(block *bitstream.Block, list *valueList) error {
+	var curType types.Type = types.I32
+	for _, record := range block.Records() {
+		if record.Code == constCodeSetType {
+			if len(record.Ops) < 1 {
+				return errors.New("invalid SETTYPE record; missing type")
+			}
+			t, err := r.typeOf(record.Ops[0])
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			curType = t
+			continue
+		}
+		list.addPending(curType, record)
+	}
+	return nil
+}
+
+// translateConst translates the given constant record of the given type,
+// defined in the given value list.
+func (r *reader) translateConst(list *valueList, typ types.Type, record *bitstream.Record) (value.Value, error) {
+	ops := record.Ops
+	// operand returns the constant of the given value ID and type.
+	operand := func(id uint64) (constant.Constant, error) {
+		v, err := list.get(r, id)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		c, ok := v.(constant.Constant)
+		if !ok {
+			return nil, errors.Errorf("invalid constant operand with value ID %d; expected constant.Constant, got %T", id, v)
+		}
+		return c, nil
+	}
+	// minOps checks the number of operands of the record.
+	minOps := func(n int) error {
+		if len(ops) < n {
+			return errors.Errorf("invalid constant record with code %d; expected at least %d operands, got %d", record.Code, n, len(ops))
+		}
+		return nil
+	}
+	switch record.Code {
+	case constCodeNull:
+		return nullValue(typ)
+	case constCodeUndef:
+		return constant.NewUndef(typ), nil
+	case constCodePoison:
+		return constant.NewPoison(typ), nil
+	case constCodeInteger:
+		if err := minOps(1); err != nil {
+			return nil, err
+		}
+		t, ok := typ.(*types.IntType)
+		if !ok {
+			return nil, errors.Errorf("invalid type of INTEGER constant; expected *types.IntType, got %T", typ)
+		}
+		x := decodeSigned(ops[0])
+		if t.BitSize == 1 {
+			// i1 constants are printed as true or false.
+			x &= 1
+		}
+		return constant.NewInt(t, x), nil
+	case constCodeWideInteger:
+		if err := minOps(1); err != nil {
+			return nil, err
+		}
+		t, ok := typ.(*types.IntType)
+		if !ok {
+			return nil, errors.Errorf("invalid type of WIDE_INTEGER constant; expected *types.IntType, got %T", typ)
+		}
+		return &constant.Int{Typ: t, X: wideInt(ops, t.BitSize)}, nil
+	case constCodeFloat:
+		if err := minOps(1); err != nil {
+			return nil, err
+		}
+		t, ok := typ.(*types.FloatType)
+		if !ok {
+			return nil, errors.Errorf("invalid type of FLOAT constant; expected *types.FloatType, got %T", typ)
+		}
+		return floatFromBits(t, ops)
+	case constCodeAggregate:
+		elems := make([]constant.Constant, len(ops))
+		for i, op := range ops {
+			elem, err := operand(op)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			elems[i] = elem
+		}
+		return aggregate(typ, elems)
+	case constCodeString, constCodeCString:
+		t, ok := typ.(*types.ArrayType)
+		if !ok {
+			return nil, errors.Errorf("invalid type of STRING constant; expected *types.ArrayType, got %T", typ)
+		}
+		buf := make([]byte, 0, len(ops)+1)
+		for _, op := range ops {
+			buf = append(buf, byte(op))
+		}
+		if record.Code == constCodeCString {
+			buf = append(buf, 0)
+		}
+		return &constant.CharArray{Typ: t, X: buf}, nil
+	case constCodeData:
+		return dataConst(typ, ops)
+	case constCodeCEUnop:
+		// [opcode, opval]
+		if err := minOps(2); err != nil {
+			return nil, err
+		}
+		x, err := operand(ops[1])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if ops[0] != unopFNeg {
+			return nil, errors.Errorf("support for unary constant expression opcode %d not yet implemented", ops[0])
+		}
+		return constant.NewFNeg(x), nil
+	case constCodeCEBinop:
+		// [opcode, lhs, rhs, flags?]
+		if err := minOps(3); err != nil {
+			return nil, err
+		}
+		x, err := operand(ops[1])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		y, err := operand(ops[2])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		var flags uint64
+		if len(ops) > 3 {
+			flags = ops[3]
+		}
+		return binaryExpr(ops[0], x, y, flags)
+	case constCodeCECast:
+		// [opcode, opty, opval]
+		if err := minOps(3); err != nil {
+			return nil, err
+		}
+		from, err := operand(ops[2])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return castExpr(ops[0], from, typ)
+	case constCodeCEGEP, constCodeCEInboundsGEP, constCodeCEGEPWithInrange:
+		return r.gepExpr(record, operand)
+	case constCodeCESelect:
+		// [opval, opval, opval]
+		if err := minOps(3); err != nil {
+			return nil, err
+		}
+		cond, err := operand(ops[0])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		x, err := operand(ops[1])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		y, err := operand(ops[2])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return constant.NewSelect(cond, x, y), nil
+	case constCodeCEExtractElt:
+		// [opty, opval, opty, opval]
+		if err := minOps(4); err != nil {
+			return nil, err
+		}
+		x, err := operand(ops[1])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		index, err := operand(ops[3])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return constant.NewExtractElement(x, index), nil
+	case constCodeCEInsertElt:
+		// [opval, opval, opty, opval]
+		if err := minOps(4); err != nil {
+			return nil, err
+		}
+		x, err := operand(ops[0])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		elem, err := operand(ops[1])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		index, err := operand(ops[3])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return constant.NewInsertElement(x, elem, index), nil
+	case constCodeCEShuffleVec, constCodeCEShufVecEx:
+		// SHUFFLEVEC: [opval, opval, opval]
+		// SHUFVEC_EX: [opty, opval, opval, opval]
+		if record.Code == constCodeCEShufVecEx {
+			ops = ops[1:]
+		}
+		if len(ops) < 3 {
+			return nil, errors.New("invalid SHUFFLEVEC constant record; expected 3 operands")
+		}
+		x, err := operand(ops[0])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		y, err := operand(ops[1])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		mask, err := operand(ops[2])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return constant.NewShuffleVector(x, y, mask), nil
+	case constCodeCECmp:
+		// [opty, opval, opval, pred]
+		if err := minOps(4); err != nil {
+			return nil, err
+		}
+		x, err := operand(ops[1])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		y, err := operand(ops[2])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if isFPred(ops[3]) {
+			pred, err := irFPred(ops[3])
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			return constant.NewFCmp(pred, x, y), nil
+		}
+		pred, err := irIPred(ops[3])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return constant.NewICmp(pred, x, y), nil
+	case constCodeBlockAddress:
+		// [fnty, fnval, bb#]
+		if err := minOps(3); err != nil {
+			return nil, err
+		}
+		c, err := operand(ops[1])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		f, ok := c.(*ir.Func)
+		if !ok {
+			return nil, errors.Errorf("invalid function of blockaddress constant; expected *ir.Func, got %T", c)
+		}
+		return constant.NewBlockAddress(f, r.blockOf(f, ops[2])), nil
+	case constCodeDSOLocalEquivalent:
+		// [gvty, gv]
+		if err := minOps(2); err != nil {
+			return nil, err
+		}
+		c, err := operand(ops[1])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return constant.NewDSOLocalEquivalent(c), nil
+	case constCodeNoCFIValue:
+		// [fty, f]
+		if err := minOps(2); err != nil {
+			return nil, err
+		}
+		c, err := operand(ops[1])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return constant.NewNoCFI(c), nil
+	case constCodeInlineAsm, constCodeInlineAsmOld3, constCodeInlineAsmOld2, constCodeInlineAsmOld:
+		return r.inlineAsm(typ, record)
+	default:
+		return nil, errors.Errorf("support for constant record with code %d not yet implemented", record.Code)
+	}
+}
+
+// nullValue returns the null value of the given type.
+func nullValue(typ types.Type) (constant.Constant, error) {
+	switch t := typ.(type) {
+	case *types.IntType:
+		return constant.NewInt(t, 0), nil
+	case *types.FloatType:
+		return floatFromBits(t, []uint64{0, 0})
+	case *types.PointerType:
+		return constant.NewNull(t), nil
+	case *types.TokenType:
+		return constant.None, nil
+	case *types.StructType, *types.ArrayType, *types.VectorType:
+		return constant.NewZeroInitializer(t), nil
+	default:
+		return nil, errors.Errorf("support for null value of type %v not yet implemented", typ)
+	}
+}
+
+// wideInt returns the signed integer of the given bit size represented by the
+// given sign-rotated 64-bit words, in little-endian order.
+func wideInt(ops []uint64, bitSize uint64) *big.Int {
+	x := new(big.Int)
+	for i := len(ops) - 1; i >= 0; i-- {
+		x.Lsh(x, 64)
+		x.Or(x, new(big.Int).SetUint64(uint64(decodeSigned(ops[i]))))
+	}
+	// Truncate to bit size.
+	mask := new(big.Int).Lsh(big.NewInt(1), uint(bitSize))
+	x.Mod(x, mask)
+	// Interpret as signed integer.
+	if x.Bit(int(bitSize)-1) == 1 {
+		x.Sub(x, mask)
+	}
+	return x
+}
+
+// floatFromBits returns the floating-point constant of the given type
+// represented by the given bits, as stored in FLOAT records.
+func floatFromBits(t *types.FloatType, words []uint64) (*constant.Float, error) {
+	var s string
+	switch t.Kind {
+	case types.FloatKindHalf:
+		s = fmt.Sprintf("0xH%04X", words[0]&0xFFFF)
+	case types.FloatKindFloat:
+		// Float constants are represented using the bit representation of the
+		// corresponding double.
+		f := float64(math.Float32frombits(uint32(words[0])))
+		if math.IsNaN(f) {
+			// Preserve the NaN payload.
+			bits := words[0]
+			sign := (bits >> 31) & 1
+			mant := bits & 0x7FFFFF
+			s = fmt.Sprintf("0x%016X", sign<<63|0x7FF<<52|mant<<29)
+		} else {
+			s = fmt.Sprintf("0x%016X", math.Float64bits(f))
+		}
+	case types.FloatKindDouble:
+		s = fmt.Sprintf("0x%016X", words[0])
+	case types.FloatKindX86_FP80:
+		if len(words) < 2 {
+			return nil, errors.New("invalid x86_fp80 constant; expected 2 words")
+		}
+		se := words[0] >> 48
+		m := words[0]<<16 | words[1]&0xFFFF
+		s = fmt.Sprintf("0xK%04X%016X", se, m)
+	case types.FloatKindFP128:
+		if len(words) < 2 {
+			return nil, errors.New("invalid fp128 constant; expected 2 words")
+		}
+		s = fmt.Sprintf("0xL%016X%016X", words[0], words[1])
+	case types.FloatKindPPC_FP128:
+		if len(words) < 2 {
+			return nil, errors.New("invalid ppc_fp128 constant; expected 2 words")
+		}
+		s = fmt.Sprintf("0xM%016X%016X", words[0], words[1])
+	default:
+		return nil, errors.Errorf("support for floating-point kind %v not yet implemented", t.Kind)
+	}
+	c, err := constant.NewFloatFromString(t, s)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return c, nil
+}
+
+// aggregate returns the aggregate constant of the given type and elements.
+func aggregate(typ types.Type, elems []constant.Constant) (constant.Constant, error) {
+	switch t := typ.(type) {
+	case *types.StructType:
+		return constant.NewStruct(t, elems...), nil
+	case *types.ArrayType:
+		return constant.NewArray(t, elems...), nil
+	case *types.VectorType:
+		return constant.NewVector(t, elems...), nil
+	default:
+		return nil, errors.Errorf("invalid type of AGGREGATE constant; expected struct, array or vector type, got %v", typ)
+	}
+}
+
+// dataConst returns the constant data array or vector of the given type and
+// raw element values.
+func dataConst(typ types.Type, ops []uint64) (constant.Constant, error) {
+	var elemType types.Type
+	switch t := typ.(type) {
+	case *types.ArrayType:
+		elemType = t.ElemType
+		if et, ok := elemType.(*types.IntType); ok && et.BitSize == 8 {
+			// Arrays of i8 are printed as character arrays.
+			buf := make([]byte, len(ops))
+			for i, op := range ops {
+				buf[i] = byte(op)
+			}
+			return &constant.CharArray{Typ: t, X: buf}, nil
+		}
+	case *types.VectorType:
+		elemType = t.ElemType
+	default:
+		return nil, errors.Errorf("invalid type of DATA constant; expected array or vector type, got %v", typ)
+	}
+	elems := make([]constant.Constant, len(ops))
+	for i, op := range ops {
+		switch et := elemType.(type) {
+		case *types.IntType:
+			// Sign-extend element value.
+			x := int64(op)
+			if et.BitSize < 64 {
+				shift := 64 - et.BitSize
+				x = int64(op<<shift) >> shift
+			}
+			if et.BitSize == 1 {
+				x &= 1
+			}
+			elems[i] = constant.NewInt(et, x)
+		case *types.FloatType:
+			elem, err := floatFromBits(et, []uint64{op})
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			elems[i] = elem
+		default:
+			return nil, errors.Errorf("invalid element type of DATA constant; expected integer or floating-point type, got %v", elemType)
+		}
+	}
+	return aggregate(typ, elems)
+}
+
+// binaryExpr returns the binary constant expression of the given bitcode
+// opcode, operands and flags.
+func binaryExpr(opcode uint64, x, y constant.Constant, flags uint64) (constant.Constant, error) {
+	switch opcode {
+	case binopAdd:
+		if types.IsFloat(x.Type()) || isFloatVector(x.Type()) {
+			return nil, errors.New("support for fadd constant expression not yet implemented")
+		}
+		e := constant.NewAdd(x, y)
+		e.OverflowFlags = irOverflowFlags(flags)
+		return e, nil
+	case binopSub:
+		if types.IsFloat(x.Type()) || isFloatVector(x.Type()) {
+			return nil, errors.New("support for fsub constant expression not yet implemented")
+		}
+		e := constant.NewSub(x, y)
+		e.OverflowFlags = irOverflowFlags(flags)
+		return e, nil
+	case binopMul:
+		if types.IsFloat(x.Type()) || isFloatVector(x.Type()) {
+			return nil, errors.New("support for fmul constant expression not yet implemented")
+		}
+		e := constant.NewMul(x, y)
+		e.OverflowFlags = irOverflowFlags(flags)
+		return e, nil
+	case binopShl:
+		e := constant.NewShl(x, y)
+		e.OverflowFlags = irOverflowFlags(flags)
+		return e, nil
+	case binopLShr:
+		e := constant.NewLShr(x, y)
+		e.Exact = flags&(1<<peoExact) != 0
+		return e, nil
+	case binopAShr:
+		e := constant.NewAShr(x, y)
+		e.Exact = flags&(1<<peoExact) != 0
+		return e, nil
+	case binopAnd:
+		return constant.NewAnd(x, y), nil
+	case binopOr:
+		return constant.NewOr(x, y), nil
+	case binopXor:
+		return constant.NewXor(x, y), nil
+	default:
+		// udiv, sdiv, urem, srem, fdiv and frem constant expressions are not
+		// supported by the ir/constant package.
+		return nil, errors.Errorf("support for binary constant expression opcode %d not yet implemented", opcode)
+	}
+}
+
+// castExpr returns the conversion constant expression of the given bitcode
+// opcode, operand and result type.
+func castExpr(opcode uint64, from constant.Constant, to types.Type) (constant.Constant, error) {
+	switch opcode {
+	case castTrunc:
+		return constant.NewTrunc(from, to), nil
+	case castZExt:
+		return constant.NewZExt(from, to), nil
+	case castSExt:
+		return constant.NewSExt(from, to), nil
+	case castFPToUI:
+		return constant.NewFPToUI(from, to), nil
+	case castFPToSI:
+		return constant.NewFPToSI(from, to), nil
+	case castUIToFP:
+		return constant.NewUIToFP(from, to), nil
+	case castSIToFP:
+		return constant.NewSIToFP(from, to), nil
+	case castFPTrunc:
+		return constant.NewFPTrunc(from, to), nil
+	case castFPExt:
+		return constant.NewFPExt(from, to), nil
+	case castPtrToInt:
+		return constant.NewPtrToInt(from, to), nil
+	case castIntToPtr:
+		return constant.NewIntToPtr(from, to), nil
+	case castBitCast:
+		return constant.NewBitCast(from, to), nil
+	case castAddrSpaceCast:
+		return constant.NewAddrSpaceCast(from, to), nil
+	default:
+		return nil, errors.Errorf("support for cast constant expression opcode %d not yet implemented", opcode)
+	}
+}
+
+// gepExpr translates the given getelementptr constant expression record.
+//
+//	CE_GEP: [pointee type, n x (opty, opval)]
+//	CE_INBOUNDS_GEP: [pointee type, n x (opty, opval)]
+//	CE_GEP_WITH_INRANGE_INDEX: [pointee type, flags, n x (opty, opval)]
+func (r *reader) gepExpr(record *bitstream.Record, operand func(id uint64) (constant.Constant, error)) (constant.Constant, error) {
+	ops := record.Ops
+	if len(ops)%2 == 0 && record.Code != constCodeCEGEPWithInrange {
+		return nil, errors.New("support for getelementptr constant expression without explicit element type not yet implemented")
+	}
+	elemType, err := r.typeOf(ops[0])
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	ops = ops[1:]
+	inBounds := record.Code == constCodeCEInboundsGEP
+	inRangeIndex := -1
+	if record.Code == constCodeCEGEPWithInrange {
+		if len(ops) < 1 {
+			return nil, errors.New("invalid CE_GEP_WITH_INRANGE_INDEX record; missing flags")
+		}
+		inBounds = ops[0]&1 != 0
+		inRangeIndex = int(ops[0] >> 1)
+		ops = ops[1:]
+	}
+	if len(ops) < 2 || len(ops)%2 != 0 {
+		return nil, errors.Errorf("invalid number of operands (%d) of getelementptr constant expression", len(ops))
+	}
+	var operands []constant.Constant
+	for i := 0; i < len(ops); i += 2 {
+		c, err := operand(ops[i+1])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		operands = append(operands, c)
+	}
+	src := operands[0]
+	var indices []constant.Constant
+	for i, index := range operands[1:] {
+		// The inrange index is relative to the operand list (including the
+		// source address).
+		if i+1 == inRangeIndex {
+			index = &constant.Index{Constant: index, InRange: true}
+		}
+		indices = append(indices, index)
+	}
+	e := constant.NewGetElementPtr(elemType, src, indices...)
+	e.InBounds = inBounds
+	return e, nil
+}
+
+// inlineAsm translates the given inline assembly record.
+//
+//	INLINEASM: [fnty, sideeffect|alignstack|asmdialect|unwind, asmstr, conststr]
+//	INLINEASM (old): [sideeffect|alignstack|asmdialect|unwind, asmstr, conststr]
+//
+// The strings are encoded as [len, chars...].
+func (r *reader) inlineAsm(typ types.Type, record *bitstream.Record) (*ir.InlineAsm, error) {
+	ops := record.Ops
+	if record.Code == constCodeInlineAsm {
+		if len(ops) < 1 {
+			return nil, errors.New("invalid INLINEASM record; missing function type")
+		}
+		sig, err := r.typeOf(ops[0])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		typ = r.pointerTo(sig, 0)
+		ops = ops[1:]
+	}
+	if len(ops) < 1 {
+		return nil, errors.New("invalid INLINEASM record; missing flags")
+	}
+	flags := ops[0]
+	ops = ops[1:]
+	// str returns the length-prefixed string at the start of ops.
+	str := func() (string, error) {
+		if len(ops) < 1 || uint64(len(ops)-1) < ops[0] {
+			return "", errors.New("invalid INLINEASM record; truncated string")
+		}
+		n := ops[0]
+		buf := make([]byte, n)
+		for i := range buf {
+			buf[i] = byte(ops[1+i])
+		}
+		ops = ops[1+n:]
+		return string(buf), nil
+	}
+	asm, err := str()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	constraint, err := str()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if flags&0x8 != 0 {
+		return nil, errors.New("support for inline assembly with unwind flag not yet implemented")
+	}
+	v := ir.NewInlineAsm(typ, asm, constraint)
+	v.SideEffect = flags&0x1 != 0
+	v.AlignStack = flags&0x2 != 0
+	v.IntelDialect = flags&0x4 != 0
+	return v, nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// isFloatVector reports whether the given type is a vector of floating-point
+// elements.
+func isFloatVector(t types.Type) bool {
+	if vt, ok := t.(*types.VectorType); ok {
+		return types.IsFloat(vt.ElemType)
+	}
+	return false
+}
@@ -0,0 +1,457 @@
+package bitcode
+
+import (
+	"github.com/llir/llvm/internal/bitstream"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/metadata"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+	"github.com/pkg/errors"
+)
+
+// funcReader keeps track of the state of the translation of a function block
+// into the body of an LLVM IR function.
+type funcReader struct {
+	r *reader
+	// Function being translated.
+	f *ir.Func
+	// Values of the function, indexed by value ID; starting with module-level
+	// values, followed by function parameters, function-level constants and
+	// instructions.
+	values *valueList
+	// Basic blocks of the function, indexed by basic block ID.
+	blocks []*ir.Block
+	// Index of the current basic block.
+	cur int
+	// Instructions and terminators of the function, in order of occurrence;
+	// used to locate the instruction of metadata attachment records.
+	insts []mdAttacher
+	// Last translated debug location; used by DEBUG_LOC_AGAIN records.
+	lastLoc *metadata.DILocation
+	// Operand bundles of the next call instruction.
+	bundles []*ir.OperandBundle
+}
+
+// mdAttacher is an instruction or terminator with metadata attachments.
+type mdAttacher interface {
+	// MDAttachments returns the metadata attachments of the value.
+	MDAttachments() []*metadata.Attachment
+	// SetMDAttachments sets the metadata attachments of the value.
+	SetMDAttachments(attachments []*metadata.Attachment)
+}
+
+// translateFuncBodies translates the function blocks of the module into the
+// bodies of the corresponding function definitions.
+func (r *reader) translateFuncBodies() error {
+	if len(r.funcBlocks) != len(r.funcs) {
+		return errors.Errorf("mismatch between number of function blocks (%d) and function definitions (%d)", len(r.funcBlocks), len(r.funcs))
+	}
+	for i, block := range r.funcBlocks {
+		f := r.funcs[i]
+		if err := r.translateFuncBody(f, block); err != nil {
+			return errors.Wrapf(err, "unable to translate body of function %q", f.Ident())
+		}
+	}
+	return nil
+}
+
+// translateFuncBody translates the given function block into the body of the
+// given function.
+func (r *reader) translateFuncBody(f *ir.Func, block *bitstream.Block) error {
+	fr := &funcReader{
+		r:      r,
+		f:      f,
+		values: newFuncValueList(r.values),
+	}
+	for _, param := range f.Params {
+		fr.values.add(param)
+	}
+	for _, entry := range block.Entries {
+		switch entry := entry.(type) {
+		case *bitstream.Block:
+			if err := fr.translateSubblock(entry); err != nil {
+				return errors.WithStack(err)
+			}
+		case *bitstream.Record:
+			if err := fr.translateRecord(entry); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	if fr.cur != len(fr.blocks) {
+		return errors.Errorf("missing terminator of basic block %d", fr.cur)
+	}
+	if err := fr.resolveFwdRefs(); err != nil {
+		return errors.WithStack(err)
+	}
+	f.Blocks = fr.blocks
+	// Drop function-level metadata.
+	r.mds.entries = r.mds.entries[:r.numModuleMDs]
+	return nil
+}
+
+// translateSubblock translates the given sub-block of a function block.
+func (fr *funcReader) translateSubblock(block *bitstream.Block) error {
+	switch block.ID {
+	case blockIDConstants:
+		return fr.r.translateConstants(block, fr.values)
+	case blockIDMetadata:
+		return fr.r.translateMetadata(block, fr.values)
+	case blockIDValueSymtab:
+		return fr.translateSymtab(block)
+	case blockIDMetadataAttachment:
+		return fr.translateAttachments(block)
+	case blockIDUseList:
+		// Use-list orders are not represented in the IR.
+		return nil
+	default:
+		dbg.Printf("ignoring unknown function sub-block with ID %d", block.ID)
+		return nil
+	}
+}
+
+// translateRecord translates the given record of a function block.
+func (fr *funcReader) translateRecord(record *bitstream.Record) error {
+	switch record.Code {
+	case funcCodeDeclareBlocks:
+		// [n]
+		if len(record.Ops) < 1 {
+			return errors.New("invalid DECLAREBLOCKS record; missing number of basic blocks")
+		}
+		n := record.Ops[0]
+		if n == 0 {
+			return errors.New("invalid DECLAREBLOCKS record; function with no basic blocks")
+		}
+		if uint64(len(fr.r.blocks[fr.f])) > n {
+			return errors.Errorf("invalid basic block ID %d referenced by blockaddress constant; function has %d basic blocks", len(fr.r.blocks[fr.f])-1, n)
+		}
+		fr.r.blockOf(fr.f, n-1)
+		fr.blocks = fr.r.blocks[fr.f]
+		return nil
+	case funcCodeDebugLoc:
+		// [line, col, scope, inlined-at, isImplicitCode]
+		if len(record.Ops) < 4 {
+			return errors.Errorf("invalid DEBUG_LOC record; expected at least 4 operands, got %d", len(record.Ops))
+		}
+		ops := record.Ops
+		scope, err := fr.r.mdFieldOrNull(ops[2])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		var inlinedAt *metadata.DILocation
+		if ops[3] != 0 {
+			field, err := fr.r.mdNode(ops[3] - 1)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			loc, ok := field.(*metadata.DILocation)
+			if !ok {
+				return errors.Errorf("invalid inlinedAt of debug location; expected *metadata.DILocation, got %T", field)
+			}
+			inlinedAt = loc
+		}
+		implicit := len(ops) > 4 && ops[4] != 0
+		fr.lastLoc = fr.r.debugLoc(int64(ops[0]), int64(ops[1]), scope, inlinedAt, implicit)
+		return fr.attachDebugLoc(fr.lastLoc)
+	case funcCodeDebugLocAgain:
+		if fr.lastLoc == nil {
+			return errors.New("invalid DEBUG_LOC_AGAIN record; no previous debug location")
+		}
+		return fr.attachDebugLoc(fr.lastLoc)
+	case funcCodeOperandBundle:
+		// [tag, n x (ty, val)]
+		if len(record.Ops) < 1 {
+			return errors.New("invalid OPERAND_BUNDLE record; missing tag")
+		}
+		tag := record.Ops[0]
+		if tag >= uint64(len(fr.r.bundleTags)) {
+			return errors.Errorf("invalid operand bundle tag ID %d; expected < %d", tag, len(fr.r.bundleTags))
+		}
+		bundle := &ir.OperandBundle{Tag: fr.r.bundleTags[tag]}
+		for i := 1; i < len(record.Ops); {
+			v, err := fr.valueTypePair(record.Ops, &i)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			bundle.Inputs = append(bundle.Inputs, v)
+		}
+		fr.bundles = append(fr.bundles, bundle)
+		return nil
+	default:
+		return fr.translateInst(record)
+	}
+}
+
+// attachDebugLoc attaches the given debug location to the last translated
+// instruction.
+func (fr *funcReader) attachDebugLoc(loc *metadata.DILocation) error {
+	if len(fr.insts) == 0 {
+		return errors.New("invalid debug location record; no preceding instruction")
+	}
+	inst := fr.insts[len(fr.insts)-1]
+	md := &metadata.Attachment{Name: "dbg", Node: loc}
+	inst.SetMDAttachments(append([]*metadata.Attachment{md}, inst.MDAttachments()...))
+	return nil
+}
+
+// translateSymtab translates the given value symbol table block of a
+// function.
+//
+//	VST_ENTRY: [valueid, namechar x N]
+//	VST_BBENTRY: [bbid, namechar x N]
+func (fr *funcReader) translateSymtab(block *bitstream.Block) error {
+	for _, record := range block.Records() {
+		if len(record.Ops) < 1 {
+			return errors.Errorf("invalid value symbol table record with code %d; missing value ID", record.Code)
+		}
+		id, name := record.Ops[0], record.String(1)
+		switch record.Code {
+		case vstCodeEntry:
+			v, err := fr.values.getFwd(fr.r, id, nil)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			named, ok := v.(value.Named)
+			if !ok {
+				return errors.Errorf("invalid value symbol table entry %q; expected named value, got %T", name, v)
+			}
+			named.SetName(name)
+		case vstCodeBBEntry:
+			block, err := fr.block(id)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			block.SetName(name)
+		}
+	}
+	return nil
+}
+
+// translateAttachments translates the given metadata attachment block of a
+// function.
+//
+//	[m x [value, [n x [id, mdnode]]]
+func (fr *funcReader) translateAttachments(block *bitstream.Block) error {
+	for _, record := range block.Records() {
+		if record.Code != metadataCodeAttachment {
+			continue
+		}
+		ops := record.Ops
+		if len(ops)%2 == 0 {
+			// Metadata attachments of function.
+			mds, err := fr.r.mdAttachments(ops)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			fr.f.Metadata = append(fr.f.Metadata, mds...)
+			continue
+		}
+		// Metadata attachments of instruction.
+		if ops[0] >= uint64(len(fr.insts)) {
+			return errors.Errorf("invalid instruction ID %d of metadata attachment; expected < %d", ops[0], len(fr.insts))
+		}
+		inst := fr.insts[ops[0]]
+		mds, err := fr.r.mdAttachments(ops[1:])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		inst.SetMDAttachments(append(inst.MDAttachments(), mds...))
+	}
+	return nil
+}
+
+// resolveFwdRefs replaces forward references of the function body with the
+// values they refer to.
+func (fr *funcReader) resolveFwdRefs() error {
+	if len(fr.values.fwds) == 0 {
+		return nil
+	}
+	var err error
+	var resolve func(v *value.Value)
+	resolve = func(v *value.Value) {
+		switch x := (*v).(type) {
+		case *fwdRef:
+			if x.id >= uint64(len(fr.values.values)) || fr.values.values[x.id] == nil {
+				err = errors.Errorf("unable to locate value with value ID %d referenced before definition", x.id)
+				return
+			}
+			*v = fr.values.values[x.id]
+		case *ir.Arg:
+			resolve(&x.Value)
+		case *metadata.Value:
+			switch md := x.Value.(type) {
+			case *fwdRef:
+				var y value.Value = md
+				resolve(&y)
+				x.Value = y
+			case *metadata.DIArgList:
+				for i := range md.Fields {
+					resolve(&md.Fields[i])
+				}
+			}
+		}
+	}
+	resolveBundles := func(bundles []*ir.OperandBundle) {
+		for _, bundle := range bundles {
+			for i := range bundle.Inputs {
+				resolve(&bundle.Inputs[i])
+			}
+		}
+	}
+	for _, block := range fr.blocks {
+		for _, inst := range block.Insts {
+			for _, op := range inst.Operands() {
+				resolve(op)
+			}
+			if call, ok := inst.(*ir.InstCall); ok {
+				resolveBundles(call.OperandBundles)
+			}
+		}
+		for _, op := range block.Term.Operands() {
+			resolve(op)
+		}
+		switch term := block.Term.(type) {
+		case *ir.TermInvoke:
+			resolveBundles(term.OperandBundles)
+		case *ir.TermCallBr:
+			resolveBundles(term.OperandBundles)
+		}
+	}
+	// Function-local metadata referring to values of the function.
+	for _, entry := range fr.r.mds.entries[fr.r.numModuleMDs:] {
+		if md, ok := entry.(*metadata.DIArgList); ok {
+			for i := range md.Fields {
+				resolve(&md.Fields[i])
+			}
+		}
+	}
+	return err
+}
+
+// --- [ Basic blocks ] --------------------------------------------------------
+
+// blockOf returns the basic block with the given basic block ID of the given
+// function, creating basic blocks as needed.
+func (r *reader) blockOf(f *ir.Func, id uint64) *ir.Block {
+	blocks := r.blocks[f]
+	for uint64(len(blocks)) <= id {
+		blocks = append(blocks, &ir.Block{Parent: f})
+	}
+	r.blocks[f] = blocks
+	return blocks[id]
+}
+
+// block returns the basic block with the given basic block ID.
+func (fr *funcReader) block(id uint64) (*ir.Block, error) {
+	if id >= uint64(len(fr.blocks)) {
+		return nil, errors.Errorf("invalid basic block ID %d; expected < %d", id, len(fr.blocks))
+	}
+	return fr.blocks[id], nil
+}
+
+// --- [ Operands ] ------------------------------------------------------------
+
+// nextID returns the value ID of the next instruction.
+func (fr *funcReader) nextID() uint64 {
+	return fr.values.nextID()
+}
+
+// absID returns the absolute value ID of the given relative value ID.
+func (fr *funcReader) absID(rel uint64) uint64 {
+	return uint64(uint32(fr.nextID() - rel))
+}
+
+// valueTypePair returns the value of the relative value ID at index *i of the
+// given operands, followed by the type of the value if it is a forward
+// reference. The index is advanced past the consumed operands.
+func (fr *funcReader) valueTypePair(ops []uint64, i *int) (value.Value, error) {
+	if *i >= len(ops) {
+		return nil, errors.New("invalid instruction record; missing value operand")
+	}
+	id := fr.absID(ops[*i])
+	*i++
+	if id < fr.nextID() {
+		return fr.values.getFwd(fr.r, id, nil)
+	}
+	// Forward reference with explicit type.
+	if *i >= len(ops) {
+		return nil, errors.New("invalid instruction record; missing type of forward referenced value")
+	}
+	typ, err := fr.r.typeOf(ops[*i])
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	*i++
+	if isMetadataType(typ) {
+		return fr.metadataValue(id)
+	}
+	return fr.values.getFwd(fr.r, id, typ)
+}
+
+// value returns the value of the given type with the relative value ID at
+// index *i of the given operands. The index is advanced past the consumed
+// operand.
+func (fr *funcReader) value(ops []uint64, i *int, typ types.Type) (value.Value, error) {
+	if *i >= len(ops) {
+		return nil, errors.New("invalid instruction record; missing value operand")
+	}
+	id := fr.absID(ops[*i])
+	*i++
+	if isMetadataType(typ) {
+		return fr.metadataValue(id)
+	}
+	return fr.values.getFwd(fr.r, id, typ)
+}
+
+// signedValue returns the value of the given type with the given signed
+// relative value ID, as used by phi instructions.
+func (fr *funcReader) signedValue(op uint64, typ types.Type) (value.Value, error) {
+	id := uint64(int64(fr.nextID()) - decodeSigned(op))
+	return fr.values.getFwd(fr.r, id, typ)
+}
+
+// metadataValue returns the metadata value (used as a function argument) of
+// the given metadata ID.
+func (fr *funcReader) metadataValue(id uint64) (value.Value, error) {
+	md, err := fr.r.mdNode(id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &metadata.Value{Value: md}, nil
+}
+
+// addInst adds the given instruction to the current basic block, assigning it
+// a value ID if it produces a value.
+func (fr *funcReader) addInst(inst ir.Instruction) error {
+	if fr.cur >= len(fr.blocks) {
+		return errors.New("invalid instruction record; instruction outside of basic block")
+	}
+	block := fr.blocks[fr.cur]
+	block.Insts = append(block.Insts, inst)
+	fr.insts = append(fr.insts, inst.(mdAttacher))
+	fr.addValue(inst)
+	return nil
+}
+
+// addTerm sets the given terminator of the current basic block, assigning it a
+// value ID if it produces a value.
+func (fr *funcReader) addTerm(term ir.Terminator) error {
+	if fr.cur >= len(fr.blocks) {
+		return errors.New("invalid terminator record; terminator outside of basic block")
+	}
+	fr.blocks[fr.cur].Term = term
+	fr.insts = append(fr.insts, term.(mdAttacher))
+	fr.addValue(term)
+	fr.cur++
+	return nil
+}
+
+// addValue assigns a value ID to the given instruction or terminator if it
+// produces a value.
+func (fr *funcReader) addValue(inst interface{}) {
+	v, ok := inst.(value.Value)
+	if !ok || v.Type().Equal(types.Void) {
+		return
+	}
+	fr.values.add(v)
+}
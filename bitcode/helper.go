@@ -0,0 +1,653 @@
+package bitcode
+
+import (
+	"math/bits"
+	"strings"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/pkg/errors"
+)
+
+// === [ Global values ] =======================================================
+
+// irLinkage returns the IR linkage corresponding to the given bitcode linkage.
+func irLinkage(v uint64) (enum.Linkage, error) {
+	switch v {
+	case 0, 5, 6, 15:
+		// external, dllimport (legacy), dllexport (legacy), external_weak
+		// (legacy; obsolete).
+		return enum.LinkageNone, nil
+	case 2:
+		return enum.LinkageAppending, nil
+	case 3:
+		return enum.LinkageInternal, nil
+	case 7:
+		return enum.LinkageExternWeak, nil
+	case 8:
+		return enum.LinkageCommon, nil
+	case 9, 13, 14:
+		// private, linker_private (legacy), linker_private_weak (legacy).
+		return enum.LinkagePrivate, nil
+	case 12:
+		return enum.LinkageAvailableExternally, nil
+	case 1, 16:
+		// weak (legacy), weak.
+		return enum.LinkageWeak, nil
+	case 4, 18:
+		// linkonce (legacy), linkonce.
+		return enum.LinkageLinkOnce, nil
+	case 10, 17:
+		// weak_odr (legacy), weak_odr.
+		return enum.LinkageWeakODR, nil
+	case 11, 19:
+		// linkonce_odr (legacy), linkonce_odr.
+		return enum.LinkageLinkOnceODR, nil
+	default:
+		return 0, errors.Errorf("support for linkage %d not yet implemented", v)
+	}
+}
+
+// bcLinkage returns the bitcode linkage corresponding to the given IR linkage.
+func bcLinkage(linkage enum.Linkage) (uint64, error) {
+	switch linkage {
+	case enum.LinkageNone, enum.LinkageExternal:
+		return 0, nil
+	case enum.LinkageAppending:
+		return 2, nil
+	case enum.LinkageInternal:
+		return 3, nil
+	case enum.LinkageExternWeak:
+		return 7, nil
+	case enum.LinkageCommon:
+		return 8, nil
+	case enum.LinkagePrivate:
+		return 9, nil
+	case enum.LinkageAvailableExternally:
+		return 12, nil
+	case enum.LinkageWeak:
+		return 16, nil
+	case enum.LinkageWeakODR:
+		return 17, nil
+	case enum.LinkageLinkOnce:
+		return 18, nil
+	case enum.LinkageLinkOnceODR:
+		return 19, nil
+	default:
+		return 0, errors.Errorf("support for linkage %v not yet implemented", linkage)
+	}
+}
+
+// isLocalLinkage reports whether the given linkage is local (i.e. internal or
+// private).
+func isLocalLinkage(linkage enum.Linkage) bool {
+	return linkage == enum.LinkageInternal || linkage == enum.LinkagePrivate
+}
+
+// isImplicitDSOLocal reports whether a global value of the given linkage and
+// visibility is implicitly dso_local, and as such not printed as dso_local.
+func isImplicitDSOLocal(linkage enum.Linkage, visibility enum.Visibility) bool {
+	if isLocalLinkage(linkage) {
+		return true
+	}
+	return visibility != enum.VisibilityNone && visibility != enum.VisibilityDefault && linkage != enum.LinkageExternWeak
+}
+
+// bcDSOLocal returns the bitcode dso_local flag of a global value with the
+// given preemption specifier, linkage and visibility.
+func bcDSOLocal(preemption enum.Preemption, linkage enum.Linkage, visibility enum.Visibility) uint64 {
+	if preemption == enum.PreemptionDSOLocal || isImplicitDSOLocal(linkage, visibility) {
+		return 1
+	}
+	return 0
+}
+
+// irVisibility returns the IR visibility corresponding to the given bitcode
+// visibility.
+func irVisibility(v uint64) (enum.Visibility, error) {
+	switch v {
+	case 0:
+		// default visibility is not printed.
+		return enum.VisibilityNone, nil
+	case 1:
+		return enum.VisibilityHidden, nil
+	case 2:
+		return enum.VisibilityProtected, nil
+	default:
+		return 0, errors.Errorf("support for visibility %d not yet implemented", v)
+	}
+}
+
+// bcVisibility returns the bitcode visibility corresponding to the given IR
+// visibility.
+func bcVisibility(visibility enum.Visibility) (uint64, error) {
+	switch visibility {
+	case enum.VisibilityNone, enum.VisibilityDefault:
+		return 0, nil
+	case enum.VisibilityHidden:
+		return 1, nil
+	case enum.VisibilityProtected:
+		return 2, nil
+	default:
+		return 0, errors.Errorf("support for visibility %v not yet implemented", visibility)
+	}
+}
+
+// irDLLStorageClass returns the IR DLL storage class corresponding to the
+// given bitcode DLL storage class.
+func irDLLStorageClass(v uint64) (enum.DLLStorageClass, error) {
+	switch v {
+	case 0:
+		return enum.DLLStorageClassNone, nil
+	case 1:
+		return enum.DLLStorageClassDLLImport, nil
+	case 2:
+		return enum.DLLStorageClassDLLExport, nil
+	default:
+		return 0, errors.Errorf("support for DLL storage class %d not yet implemented", v)
+	}
+}
+
+// bcDLLStorageClass returns the bitcode DLL storage class corresponding to the
+// given IR DLL storage class.
+func bcDLLStorageClass(dllStorageClass enum.DLLStorageClass) (uint64, error) {
+	switch dllStorageClass {
+	case enum.DLLStorageClassNone:
+		return 0, nil
+	case enum.DLLStorageClassDLLImport:
+		return 1, nil
+	case enum.DLLStorageClassDLLExport:
+		return 2, nil
+	default:
+		return 0, errors.Errorf("support for DLL storage class %v not yet implemented", dllStorageClass)
+	}
+}
+
+// irTLSModel returns the IR thread local storage model corresponding to the
+// given bitcode thread local storage model.
+func irTLSModel(v uint64) (enum.TLSModel, error) {
+	switch v {
+	case 0:
+		return enum.TLSModelNone, nil
+	case 1:
+		return enum.TLSModelGeneric, nil
+	case 2:
+		return enum.TLSModelLocalDynamic, nil
+	case 3:
+		return enum.TLSModelInitialExec, nil
+	case 4:
+		return enum.TLSModelLocalExec, nil
+	default:
+		return 0, errors.Errorf("support for thread local storage model %d not yet implemented", v)
+	}
+}
+
+// bcTLSModel returns the bitcode thread local storage model corresponding to
+// the given IR thread local storage model.
+func bcTLSModel(tlsModel enum.TLSModel) (uint64, error) {
+	switch tlsModel {
+	case enum.TLSModelNone:
+		return 0, nil
+	case enum.TLSModelGeneric:
+		return 1, nil
+	case enum.TLSModelLocalDynamic:
+		return 2, nil
+	case enum.TLSModelInitialExec:
+		return 3, nil
+	case enum.TLSModelLocalExec:
+		return 4, nil
+	default:
+		return 0, errors.Errorf("support for thread local storage model %v not yet implemented", tlsModel)
+	}
+}
+
+// irUnnamedAddr returns the IR unnamed address specifier corresponding to the
+// given bitcode unnamed address specifier.
+func irUnnamedAddr(v uint64) (enum.UnnamedAddr, error) {
+	switch v {
+	case 0:
+		return enum.UnnamedAddrNone, nil
+	case 1:
+		return enum.UnnamedAddrUnnamedAddr, nil
+	case 2:
+		return enum.UnnamedAddrLocalUnnamedAddr, nil
+	default:
+		return 0, errors.Errorf("support for unnamed address specifier %d not yet implemented", v)
+	}
+}
+
+// bcUnnamedAddr returns the bitcode unnamed address specifier corresponding to
+// the given IR unnamed address specifier.
+func bcUnnamedAddr(unnamedAddr enum.UnnamedAddr) (uint64, error) {
+	switch unnamedAddr {
+	case enum.UnnamedAddrNone:
+		return 0, nil
+	case enum.UnnamedAddrUnnamedAddr:
+		return 1, nil
+	case enum.UnnamedAddrLocalUnnamedAddr:
+		return 2, nil
+	default:
+		return 0, errors.Errorf("support for unnamed address specifier %v not yet implemented", unnamedAddr)
+	}
+}
+
+// irSelectionKind returns the IR comdat selection kind corresponding to the
+// given bitcode comdat selection kind.
+func irSelectionKind(v uint64) (enum.SelectionKind, error) {
+	switch v {
+	case 1:
+		return enum.SelectionKindAny, nil
+	case 2:
+		return enum.SelectionKindExactMatch, nil
+	case 3:
+		return enum.SelectionKindLargest, nil
+	case 4:
+		return enum.SelectionKindNoDeduplicate, nil
+	case 5:
+		return enum.SelectionKindSameSize, nil
+	default:
+		return 0, errors.Errorf("support for comdat selection kind %d not yet implemented", v)
+	}
+}
+
+// bcSelectionKind returns the bitcode comdat selection kind corresponding to
+// the given IR comdat selection kind.
+func bcSelectionKind(kind enum.SelectionKind) (uint64, error) {
+	switch kind {
+	case enum.SelectionKindAny:
+		return 1, nil
+	case enum.SelectionKindExactMatch:
+		return 2, nil
+	case enum.SelectionKindLargest:
+		return 3, nil
+	case enum.SelectionKindNoDeduplicate:
+		return 4, nil
+	case enum.SelectionKindSameSize:
+		return 5, nil
+	default:
+		return 0, errors.Errorf("support for comdat selection kind %v not yet implemented", kind)
+	}
+}
+
+// irCallingConv returns the IR calling convention corresponding to the given
+// bitcode calling convention.
+func irCallingConv(v uint64) (enum.CallingConv, error) {
+	if v > 1023 {
+		return 0, errors.Errorf("invalid calling convention %d", v)
+	}
+	// The C calling convention (0 in LLVM) is the default calling convention,
+	// and is not printed.
+	if v == 0 {
+		return enum.CallingConvNone, nil
+	}
+	return enum.CallingConv(v), nil
+}
+
+// bcCallingConv returns the bitcode calling convention corresponding to the
+// given IR calling convention.
+func bcCallingConv(callingConv enum.CallingConv) uint64 {
+	if callingConv == enum.CallingConvNone || callingConv == enum.CallingConvC {
+		return 0
+	}
+	return uint64(callingConv)
+}
+
+// irAlign returns the IR alignment corresponding to the given bitcode
+// alignment, which is encoded as log2(align)+1 with 0 denoting no alignment.
+func irAlign(v uint64) ir.Align {
+	if v == 0 || v > 64 {
+		return 0
+	}
+	return ir.Align(uint64(1) << (v - 1))
+}
+
+// bcAlign returns the bitcode alignment corresponding to the given IR
+// alignment, encoded as log2(align)+1 with 0 denoting no alignment.
+func bcAlign(align ir.Align) uint64 {
+	if align == 0 {
+		return 0
+	}
+	return uint64(bits.Len64(uint64(align)))
+}
+
+// splitModuleAsm splits the given module-level inline assembly into lines.
+func splitModuleAsm(s string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// === [ Instructions ] ========================================================
+
+// irAtomicOrdering returns the IR atomic ordering corresponding to the given
+// bitcode atomic ordering.
+func irAtomicOrdering(v uint64) (enum.AtomicOrdering, error) {
+	switch v {
+	case orderingNotAtomic:
+		return enum.AtomicOrderingNone, nil
+	case orderingUnordered:
+		return enum.AtomicOrderingUnordered, nil
+	case orderingMonotonic:
+		return enum.AtomicOrderingMonotonic, nil
+	case orderingAcquire:
+		return enum.AtomicOrderingAcquire, nil
+	case orderingRelease:
+		return enum.AtomicOrderingRelease, nil
+	case orderingAcqRel:
+		return enum.AtomicOrderingAcquireRelease, nil
+	case orderingSeqCst:
+		return enum.AtomicOrderingSequentiallyConsistent, nil
+	default:
+		return 0, errors.Errorf("support for atomic ordering %d not yet implemented", v)
+	}
+}
+
+// bcAtomicOrdering returns the bitcode atomic ordering corresponding to the
+// given IR atomic ordering.
+func bcAtomicOrdering(ordering enum.AtomicOrdering) (uint64, error) {
+	switch ordering {
+	case enum.AtomicOrderingNone:
+		return orderingNotAtomic, nil
+	case enum.AtomicOrderingUnordered:
+		return orderingUnordered, nil
+	case enum.AtomicOrderingMonotonic:
+		return orderingMonotonic, nil
+	case enum.AtomicOrderingAcquire:
+		return orderingAcquire, nil
+	case enum.AtomicOrderingRelease:
+		return orderingRelease, nil
+	case enum.AtomicOrderingAcquireRelease:
+		return orderingAcqRel, nil
+	case enum.AtomicOrderingSequentiallyConsistent:
+		return orderingSeqCst, nil
+	default:
+		return 0, errors.Errorf("support for atomic ordering %v not yet implemented", ordering)
+	}
+}
+
+// irAtomicOp returns the IR atomicrmw binary operation corresponding to the
+// given bitcode atomicrmw binary operation.
+func irAtomicOp(v uint64) (enum.AtomicOp, error) {
+	switch v {
+	case rmwXchg:
+		return enum.AtomicOpXChg, nil
+	case rmwAdd:
+		return enum.AtomicOpAdd, nil
+	case rmwSub:
+		return enum.AtomicOpSub, nil
+	case rmwAnd:
+		return enum.AtomicOpAnd, nil
+	case rmwNand:
+		return enum.AtomicOpNAnd, nil
+	case rmwOr:
+		return enum.AtomicOpOr, nil
+	case rmwXor:
+		return enum.AtomicOpXor, nil
+	case rmwMax:
+		return enum.AtomicOpMax, nil
+	case rmwMin:
+		return enum.AtomicOpMin, nil
+	case rmwUMax:
+		return enum.AtomicOpUMax, nil
+	case rmwUMin:
+		return enum.AtomicOpUMin, nil
+	case rmwFAdd:
+		return enum.AtomicOpFAdd, nil
+	case rmwFSub:
+		return enum.AtomicOpFSub, nil
+	case rmwFMax:
+		return enum.AtomicOpFMax, nil
+	case rmwFMin:
+		return enum.AtomicOpFMin, nil
+	default:
+		return 0, errors.Errorf("support for atomicrmw operation %d not yet implemented", v)
+	}
+}
+
+// bcAtomicOp returns the bitcode atomicrmw binary operation corresponding to
+// the given IR atomicrmw binary operation.
+func bcAtomicOp(op enum.AtomicOp) (uint64, error) {
+	switch op {
+	case enum.AtomicOpXChg:
+		return rmwXchg, nil
+	case enum.AtomicOpAdd:
+		return rmwAdd, nil
+	case enum.AtomicOpSub:
+		return rmwSub, nil
+	case enum.AtomicOpAnd:
+		return rmwAnd, nil
+	case enum.AtomicOpNAnd:
+		return rmwNand, nil
+	case enum.AtomicOpOr:
+		return rmwOr, nil
+	case enum.AtomicOpXor:
+		return rmwXor, nil
+	case enum.AtomicOpMax:
+		return rmwMax, nil
+	case enum.AtomicOpMin:
+		return rmwMin, nil
+	case enum.AtomicOpUMax:
+		return rmwUMax, nil
+	case enum.AtomicOpUMin:
+		return rmwUMin, nil
+	case enum.AtomicOpFAdd:
+		return rmwFAdd, nil
+	case enum.AtomicOpFSub:
+		return rmwFSub, nil
+	case enum.AtomicOpFMax:
+		return rmwFMax, nil
+	case enum.AtomicOpFMin:
+		return rmwFMin, nil
+	default:
+		return 0, errors.Errorf("support for atomicrmw operation %v not yet implemented", op)
+	}
+}
+
+// irIPred returns the IR integer comparison predicate corresponding to the
+// given bitcode predicate.
+func irIPred(v uint64) (enum.IPred, error) {
+	// ref: include/llvm/IR/InstrTypes.h (enum CmpInst::Predicate)
+	switch v {
+	case 32:
+		return enum.IPredEQ, nil
+	case 33:
+		return enum.IPredNE, nil
+	case 34:
+		return enum.IPredUGT, nil
+	case 35:
+		return enum.IPredUGE, nil
+	case 36:
+		return enum.IPredULT, nil
+	case 37:
+		return enum.IPredULE, nil
+	case 38:
+		return enum.IPredSGT, nil
+	case 39:
+		return enum.IPredSGE, nil
+	case 40:
+		return enum.IPredSLT, nil
+	case 41:
+		return enum.IPredSLE, nil
+	default:
+		return 0, errors.Errorf("support for integer predicate %d not yet implemented", v)
+	}
+}
+
+// bcIPred returns the bitcode predicate corresponding to the given IR integer
+// comparison predicate.
+func bcIPred(pred enum.IPred) (uint64, error) {
+	for v := uint64(32); v <= 41; v++ {
+		if p, _ := irIPred(v); p == pred {
+			return v, nil
+		}
+	}
+	return 0, errors.Errorf("support for integer predicate %v not yet implemented", pred)
+}
+
+// irFPred returns the IR floating-point comparison predicate corresponding to
+// the given bitcode predicate.
+func irFPred(v uint64) (enum.FPred, error) {
+	// ref: include/llvm/IR/InstrTypes.h (enum CmpInst::Predicate)
+	preds := []enum.FPred{
+		enum.FPredFalse,
+		enum.FPredOEQ,
+		enum.FPredOGT,
+		enum.FPredOGE,
+		enum.FPredOLT,
+		enum.FPredOLE,
+		enum.FPredONE,
+		enum.FPredORD,
+		enum.FPredUNO,
+		enum.FPredUEQ,
+		enum.FPredUGT,
+		enum.FPredUGE,
+		enum.FPredULT,
+		enum.FPredULE,
+		enum.FPredUNE,
+		enum.FPredTrue,
+	}
+	if v >= uint64(len(preds)) {
+		return 0, errors.Errorf("support for floating-point predicate %d not yet implemented", v)
+	}
+	return preds[v], nil
+}
+
+// bcFPred returns the bitcode predicate corresponding to the given IR
+// floating-point comparison predicate.
+func bcFPred(pred enum.FPred) (uint64, error) {
+	for v := uint64(0); isFPred(v); v++ {
+		if p, _ := irFPred(v); p == pred {
+			return v, nil
+		}
+	}
+	return 0, errors.Errorf("support for floating-point predicate %v not yet implemented", pred)
+}
+
+// isFPred reports whether the given bitcode predicate is a floating-point
+// comparison predicate.
+func isFPred(v uint64) bool {
+	return v < 16
+}
+
+// irFastMathFlags returns the IR fast-math flags corresponding to the given
+// bitcode fast-math flags.
+func irFastMathFlags(v uint64) []enum.FastMathFlag {
+	if v&fmfUnsafeAlgebra != 0 {
+		// Legacy representation of the fast flag.
+		v |= fmfNoNaNs | fmfNoInfs | fmfNoSignedZeros | fmfAllowReciprocal | fmfAllowContract | fmfApproxFunc | fmfAllowReassoc
+	}
+	const all = fmfNoNaNs | fmfNoInfs | fmfNoSignedZeros | fmfAllowReciprocal | fmfAllowContract | fmfApproxFunc | fmfAllowReassoc
+	if v&all == all {
+		return []enum.FastMathFlag{enum.FastMathFlagFast}
+	}
+	// Order of flags as printed by LLVM.
+	var flags []enum.FastMathFlag
+	if v&fmfAllowReassoc != 0 {
+		flags = append(flags, enum.FastMathFlagReassoc)
+	}
+	if v&fmfNoNaNs != 0 {
+		flags = append(flags, enum.FastMathFlagNNaN)
+	}
+	if v&fmfNoInfs != 0 {
+		flags = append(flags, enum.FastMathFlagNInf)
+	}
+	if v&fmfNoSignedZeros != 0 {
+		flags = append(flags, enum.FastMathFlagNSZ)
+	}
+	if v&fmfAllowReciprocal != 0 {
+		flags = append(flags, enum.FastMathFlagARcp)
+	}
+	if v&fmfAllowContract != 0 {
+		flags = append(flags, enum.FastMathFlagContract)
+	}
+	if v&fmfApproxFunc != 0 {
+		flags = append(flags, enum.FastMathFlagAFn)
+	}
+	return flags
+}
+
+// bcFastMathFlags returns the bitcode fast-math flags corresponding to the
+// given IR fast-math flags.
+func bcFastMathFlags(flags []enum.FastMathFlag) uint64 {
+	var v uint64
+	for _, flag := range flags {
+		switch flag {
+		case enum.FastMathFlagFast:
+			v |= fmfNoNaNs | fmfNoInfs | fmfNoSignedZeros | fmfAllowReciprocal | fmfAllowContract | fmfApproxFunc | fmfAllowReassoc
+		case enum.FastMathFlagReassoc:
+			v |= fmfAllowReassoc
+		case enum.FastMathFlagNNaN:
+			v |= fmfNoNaNs
+		case enum.FastMathFlagNInf:
+			v |= fmfNoInfs
+		case enum.FastMathFlagNSZ:
+			v |= fmfNoSignedZeros
+		case enum.FastMathFlagARcp:
+			v |= fmfAllowReciprocal
+		case enum.FastMathFlagContract:
+			v |= fmfAllowContract
+		case enum.FastMathFlagAFn:
+			v |= fmfApproxFunc
+		}
+	}
+	return v
+}
+
+// irOverflowFlags returns the IR overflow flags corresponding to the given
+// bitcode flags of an add, sub, mul or shl instruction.
+func irOverflowFlags(v uint64) []enum.OverflowFlag {
+	// Order of flags as printed by LLVM.
+	var flags []enum.OverflowFlag
+	if v&(1<<oboNoUnsignedWrap) != 0 {
+		flags = append(flags, enum.OverflowFlagNUW)
+	}
+	if v&(1<<oboNoSignedWrap) != 0 {
+		flags = append(flags, enum.OverflowFlagNSW)
+	}
+	return flags
+}
+
+// bcOverflowFlags returns the bitcode flags of an add, sub, mul or shl
+// instruction corresponding to the given IR overflow flags.
+func bcOverflowFlags(flags []enum.OverflowFlag) uint64 {
+	var v uint64
+	for _, flag := range flags {
+		switch flag {
+		case enum.OverflowFlagNUW:
+			v |= 1 << oboNoUnsignedWrap
+		case enum.OverflowFlagNSW:
+			v |= 1 << oboNoSignedWrap
+		}
+	}
+	return v
+}
+
+// ### [ Helper functions ] ####################################################
+
+// decodeSigned returns the signed value of the given sign-rotated value, where
+// the sign bit is stored in the least significant bit.
+func decodeSigned(v uint64) int64 {
+	if v&1 == 0 {
+		return int64(v >> 1)
+	}
+	if v != 1 {
+		return -int64(v >> 1)
+	}
+	// There is no such thing as -0 with integers; "-0" really means
+	// MININT.
+	return -1 << 63
+}
+
+// encodeSigned returns the sign-rotated value of the given signed value, where
+// the sign bit is stored in the least significant bit.
+func encodeSigned(v int64) uint64 {
+	if v >= 0 {
+		return uint64(v) << 1
+	}
+	if v == -1<<63 {
+		// MININT is encoded as "-0".
+		return 1
+	}
+	return uint64(-v)<<1 | 1
+}
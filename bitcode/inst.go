@@ -0,0 +1,1127 @@
+package bitcode
+
+import (
+	"github.com/llir/llvm/internal/bitstream"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+	"github.com/pkg/errors"
+)
+
+// translateInst translates the given instruction record of a function block.
+func (fr *funcReader) translateInst(record *bitstream.Record) error {
+	ops := record.Ops
+	i := 0
+	switch record.Code {
+	// Unary and binary instructions.
+	case funcCodeInstUnop:
+		// [opval, opcode, flags?]
+		x, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if i >= len(ops) || ops[i] != unopFNeg {
+			return errors.New("invalid UNOP record; expected fneg opcode")
+		}
+		inst := ir.NewFNeg(x)
+		if i+1 < len(ops) {
+			inst.FastMathFlags = irFastMathFlags(ops[i+1])
+		}
+		return fr.addInst(inst)
+	case funcCodeInstBinop:
+		// [opval, opval, opcode, flags?]
+		x, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		y, err := fr.value(ops, &i, x.Type())
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if i >= len(ops) {
+			return errors.New("invalid BINOP record; missing opcode")
+		}
+		var flags uint64
+		if i+1 < len(ops) {
+			flags = ops[i+1]
+		}
+		inst, err := binaryInst(ops[i], x, y, flags)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return fr.addInst(inst)
+	// Conversion instructions.
+	case funcCodeInstCast:
+		// [opval, destty, castopc]
+		from, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if i+1 >= len(ops) {
+			return errors.New("invalid CAST record; missing destination type or opcode")
+		}
+		to, err := fr.r.typeOf(ops[i])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		inst, err := castInst(ops[i+1], from, to)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return fr.addInst(inst)
+	// Memory instructions.
+	case funcCodeInstAlloca:
+		return fr.translateAlloca(ops)
+	case funcCodeInstLoad, funcCodeInstLoadAtomic:
+		// LOAD: [op, ty, align, vol]
+		// LOAD_ATOMIC: [op, ty, align, vol, ordering, ssid]
+		src, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		n := 3
+		if record.Code == funcCodeInstLoadAtomic {
+			n = 5
+		}
+		if len(ops)-i < n-1 {
+			return errors.Errorf("invalid LOAD record; expected at least %d operands, got %d", i+n-1, len(ops))
+		}
+		var elemType types.Type
+		if len(ops)-i == n {
+			// Explicit type.
+			if elemType, err = fr.r.typeOf(ops[i]); err != nil {
+				return errors.WithStack(err)
+			}
+			i++
+		} else {
+			elemType, err = elemTypeOf(src.Type())
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		inst := ir.NewLoad(elemType, src)
+		inst.Align = irAlign(ops[i])
+		inst.Volatile = ops[i+1] != 0
+		if record.Code == funcCodeInstLoadAtomic {
+			inst.Atomic = true
+			if inst.Ordering, err = irAtomicOrdering(ops[i+2]); err != nil {
+				return errors.WithStack(err)
+			}
+			if inst.SyncScope, err = fr.syncScope(ops[i+3]); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		return fr.addInst(inst)
+	case funcCodeInstStore, funcCodeInstStoreAtomic:
+		// STORE: [ptr, val, align, vol]
+		// STORE_ATOMIC: [ptr, val, align, vol, ordering, ssid]
+		dst, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		src, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		n := 2
+		if record.Code == funcCodeInstStoreAtomic {
+			n = 4
+		}
+		if len(ops)-i < n {
+			return errors.Errorf("invalid STORE record; expected at least %d operands, got %d", i+n, len(ops))
+		}
+		inst := ir.NewStore(src, dst)
+		inst.Align = irAlign(ops[i])
+		inst.Volatile = ops[i+1] != 0
+		if record.Code == funcCodeInstStoreAtomic {
+			inst.Atomic = true
+			if inst.Ordering, err = irAtomicOrdering(ops[i+2]); err != nil {
+				return errors.WithStack(err)
+			}
+			if inst.SyncScope, err = fr.syncScope(ops[i+3]); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		return fr.addInst(inst)
+	case funcCodeInstFence:
+		// [ordering, ssid]
+		if len(ops) < 2 {
+			return errors.Errorf("invalid FENCE record; expected 2 operands, got %d", len(ops))
+		}
+		ordering, err := irAtomicOrdering(ops[0])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		inst := ir.NewFence(ordering)
+		if inst.SyncScope, err = fr.syncScope(ops[1]); err != nil {
+			return errors.WithStack(err)
+		}
+		return fr.addInst(inst)
+	case funcCodeInstCmpXchg:
+		// [ptr, cmp, new, vol, success_ordering, ssid, failure_ordering, weak, align?]
+		ptr, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		cmp, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		new, err := fr.value(ops, &i, cmp.Type())
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if len(ops)-i < 5 {
+			return errors.Errorf("invalid CMPXCHG record; expected at least %d operands, got %d", i+5, len(ops))
+		}
+		successOrdering, err := irAtomicOrdering(ops[i+1])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		failureOrdering, err := irAtomicOrdering(ops[i+3])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		inst := ir.NewCmpXchg(ptr, cmp, new, successOrdering, failureOrdering)
+		inst.Typ = types.NewStruct(cmp.Type(), types.I1)
+		inst.Volatile = ops[i] != 0
+		if inst.SyncScope, err = fr.syncScope(ops[i+2]); err != nil {
+			return errors.WithStack(err)
+		}
+		inst.Weak = ops[i+4] != 0
+		return fr.addInst(inst)
+	case funcCodeInstAtomicRMW, funcCodeInstAtomicRMWOld:
+		// ATOMICRMW: [ptr, val, op, vol, ordering, ssid, align?]
+		// ATOMICRMW (old): [ptr, valnotype, op, vol, ordering, ssid, align?]
+		dst, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		var x value.Value
+		if record.Code == funcCodeInstAtomicRMW {
+			x, err = fr.valueTypePair(ops, &i)
+		} else {
+			var elemType types.Type
+			if elemType, err = elemTypeOf(dst.Type()); err != nil {
+				return errors.WithStack(err)
+			}
+			x, err = fr.value(ops, &i, elemType)
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if len(ops)-i < 4 {
+			return errors.Errorf("invalid ATOMICRMW record; expected at least %d operands, got %d", i+4, len(ops))
+		}
+		op, err := irAtomicOp(ops[i])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		ordering, err := irAtomicOrdering(ops[i+2])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		inst := ir.NewAtomicRMW(op, dst, x, ordering)
+		inst.Typ = x.Type()
+		inst.Volatile = ops[i+1] != 0
+		if inst.SyncScope, err = fr.syncScope(ops[i+3]); err != nil {
+			return errors.WithStack(err)
+		}
+		return fr.addInst(inst)
+	case funcCodeInstGEP:
+		// [inbounds, ty, n x operands]
+		if len(ops) < 2 {
+			return errors.Errorf("invalid GEP record; expected at least 2 operands, got %d", len(ops))
+		}
+		elemType, err := fr.r.typeOf(ops[1])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		i = 2
+		src, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		var indices []value.Value
+		for i < len(ops) {
+			index, err := fr.valueTypePair(ops, &i)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			indices = append(indices, index)
+		}
+		inst := ir.NewGetElementPtr(elemType, src, indices...)
+		inst.InBounds = ops[0] != 0
+		return fr.addInst(inst)
+	// Vector instructions.
+	case funcCodeInstExtractElt:
+		// [opval, opval]
+		x, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		index, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return fr.addInst(ir.NewExtractElement(x, index))
+	case funcCodeInstInsertElt:
+		// [opval, opval, opval]
+		x, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		vt, ok := x.Type().(*types.VectorType)
+		if !ok {
+			return errors.Errorf("invalid vector type of insertelement; expected *types.VectorType, got %T", x.Type())
+		}
+		elem, err := fr.value(ops, &i, vt.ElemType)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		index, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return fr.addInst(ir.NewInsertElement(x, elem, index))
+	case funcCodeInstShuffleVec:
+		// [opval, opval, opval]
+		x, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		y, err := fr.value(ops, &i, x.Type())
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		mask, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return fr.addInst(ir.NewShuffleVector(x, y, mask))
+	// Aggregate instructions.
+	case funcCodeInstExtractVal:
+		// [opval, n x indices]
+		x, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return fr.addInst(ir.NewExtractValue(x, ops[i:]...))
+	case funcCodeInstInsertVal:
+		// [opval, opval, n x indices]
+		x, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		elem, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return fr.addInst(ir.NewInsertValue(x, elem, ops[i:]...))
+	// Other instructions.
+	case funcCodeInstCmp, funcCodeInstCmp2:
+		// [opval, opval, pred, flags?]
+		x, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		y, err := fr.value(ops, &i, x.Type())
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if i >= len(ops) {
+			return errors.New("invalid CMP record; missing predicate")
+		}
+		if isFPred(ops[i]) {
+			pred, err := irFPred(ops[i])
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			inst := ir.NewFCmp(pred, x, y)
+			if i+1 < len(ops) {
+				inst.FastMathFlags = irFastMathFlags(ops[i+1])
+			}
+			return fr.addInst(inst)
+		}
+		pred, err := irIPred(ops[i])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return fr.addInst(ir.NewICmp(pred, x, y))
+	case funcCodeInstPhi:
+		return fr.translatePhi(ops)
+	case funcCodeInstVSelect:
+		// [opval, opval, pred]
+		valueTrue, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		valueFalse, err := fr.value(ops, &i, valueTrue.Type())
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		cond, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		inst := ir.NewSelect(cond, valueTrue, valueFalse)
+		if i < len(ops) {
+			inst.FastMathFlags = irFastMathFlags(ops[i])
+		}
+		return fr.addInst(inst)
+	case funcCodeInstFreeze:
+		// [opval]
+		x, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return fr.addInst(ir.NewInstFreeze(x))
+	case funcCodeInstCall:
+		return fr.translateCall(ops)
+	case funcCodeInstVAArg:
+		// [valistty, valist, instty]
+		if len(ops) < 3 {
+			return errors.Errorf("invalid VAARG record; expected 3 operands, got %d", len(ops))
+		}
+		argListType, err := fr.r.typeOf(ops[0])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		i = 1
+		argList, err := fr.value(ops, &i, argListType)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		argType, err := fr.r.typeOf(ops[2])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return fr.addInst(ir.NewVAArg(argList, argType))
+	case funcCodeInstLandingPad:
+		return fr.translateLandingPad(ops)
+	case funcCodeInstCatchPad, funcCodeInstCleanupPad:
+		// [parentpad, num, n x args]
+		if len(ops) < 2 {
+			return errors.Errorf("invalid exception pad record; expected at least 2 operands, got %d", len(ops))
+		}
+		parentPad, err := fr.value(ops, &i, types.Token)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		n := ops[i]
+		i++
+		var args []value.Value
+		for j := uint64(0); j < n; j++ {
+			arg, err := fr.valueTypePair(ops, &i)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			args = append(args, arg)
+		}
+		if record.Code == funcCodeInstCatchPad {
+			return fr.addInst(&ir.InstCatchPad{CatchSwitch: parentPad, Args: args})
+		}
+		return fr.addInst(&ir.InstCleanupPad{ParentPad: parentPad, Args: args})
+	// Terminators.
+	case funcCodeInstRet:
+		// [opval?]
+		if len(ops) == 0 {
+			return fr.addTerm(ir.NewRet(nil))
+		}
+		x, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return fr.addTerm(ir.NewRet(x))
+	case funcCodeInstBr:
+		// [bb#, bb#, cond] or [bb#]
+		if len(ops) != 1 && len(ops) != 3 {
+			return errors.Errorf("invalid BR record; expected 1 or 3 operands, got %d", len(ops))
+		}
+		targetTrue, err := fr.block(ops[0])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if len(ops) == 1 {
+			return fr.addTerm(ir.NewBr(targetTrue))
+		}
+		targetFalse, err := fr.block(ops[1])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		i = 2
+		cond, err := fr.value(ops, &i, types.I1)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return fr.addTerm(ir.NewCondBr(cond, targetTrue, targetFalse))
+	case funcCodeInstSwitch:
+		return fr.translateSwitch(ops)
+	case funcCodeInstIndirectBr:
+		// [opty, op, n x bb#]
+		if len(ops) < 2 {
+			return errors.Errorf("invalid INDIRECTBR record; expected at least 2 operands, got %d", len(ops))
+		}
+		addrType, err := fr.r.typeOf(ops[0])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		i = 1
+		addr, err := fr.value(ops, &i, addrType)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		var targets []*ir.Block
+		for _, id := range ops[i:] {
+			target, err := fr.block(id)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			targets = append(targets, target)
+		}
+		return fr.addTerm(ir.NewIndirectBr(addr, targets...))
+	case funcCodeInstInvoke:
+		return fr.translateInvoke(ops)
+	case funcCodeInstCallBr:
+		return fr.translateCallBr(ops)
+	case funcCodeInstResume:
+		// [opval]
+		x, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return fr.addTerm(ir.NewResume(x))
+	case funcCodeInstCatchSwitch:
+		// [parentpad, num, n x bb#, unwinddest?]
+		if len(ops) < 2 {
+			return errors.Errorf("invalid CATCHSWITCH record; expected at least 2 operands, got %d", len(ops))
+		}
+		parentPad, err := fr.value(ops, &i, types.Token)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		n := ops[i]
+		i++
+		if uint64(len(ops)-i) < n {
+			return errors.Errorf("invalid CATCHSWITCH record; expected %d handlers, got %d", n, len(ops)-i)
+		}
+		term := &ir.TermCatchSwitch{ParentPad: parentPad}
+		for j := uint64(0); j < n; j++ {
+			handler, err := fr.block(ops[i])
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			term.Handlers = append(term.Handlers, handler)
+			i++
+		}
+		if i < len(ops) {
+			unwindTarget, err := fr.block(ops[i])
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			term.DefaultUnwindTarget = unwindTarget
+		}
+		return fr.addTerm(term)
+	case funcCodeInstCatchRet:
+		// [catchpad, bb#]
+		if len(ops) < 2 {
+			return errors.Errorf("invalid CATCHRET record; expected 2 operands, got %d", len(ops))
+		}
+		catchPad, err := fr.value(ops, &i, types.Token)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		target, err := fr.block(ops[i])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return fr.addTerm(&ir.TermCatchRet{CatchPad: catchPad, Target: target})
+	case funcCodeInstCleanupRet:
+		// [cleanuppad, bb#?]
+		if len(ops) < 1 {
+			return errors.New("invalid CLEANUPRET record; missing cleanup pad")
+		}
+		cleanupPad, err := fr.value(ops, &i, types.Token)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		term := &ir.TermCleanupRet{CleanupPad: cleanupPad}
+		if i < len(ops) {
+			unwindTarget, err := fr.block(ops[i])
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			term.UnwindTarget = unwindTarget
+		}
+		return fr.addTerm(term)
+	case funcCodeInstUnreachable:
+		return fr.addTerm(ir.NewUnreachable())
+	default:
+		return errors.Errorf("support for function record with code %d not yet implemented", record.Code)
+	}
+}
+
+// translateAlloca translates the given alloca instruction record.
+//
+//	[instty, opty, op, align, addrspace?]
+func (fr *funcReader) translateAlloca(ops []uint64) error {
+	if len(ops) < 4 {
+		return errors.Errorf("invalid ALLOCA record; expected at least 4 operands, got %d", len(ops))
+	}
+	elemType, err := fr.r.typeOf(ops[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	nelemsType, err := fr.r.typeOf(ops[1])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// Note, the number of elements is stored as an absolute value ID.
+	nelems, err := fr.values.getFwd(fr.r, ops[2], nelemsType)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	packed := ops[3]
+	if packed&allocaExplicitType == 0 {
+		if elemType, err = elemTypeOf(elemType); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	inst := ir.NewAlloca(elemType)
+	if c, ok := nelems.(*constant.Int); !ok || c.X.Cmp(one) != 0 {
+		inst.NElems = nelems
+	}
+	inst.InAlloca = packed&allocaInAlloca != 0
+	inst.SwiftError = packed&allocaSwiftError != 0
+	align := packed&allocaAlignLowerMask | packed>>allocaAlignUpperBit<<5
+	inst.Align = irAlign(align)
+	if len(ops) > 4 {
+		inst.AddrSpace = types.AddrSpace(ops[4])
+	}
+	inst.Typ = fr.r.pointerTo(elemType, inst.AddrSpace)
+	return fr.addInst(inst)
+}
+
+// translatePhi translates the given phi instruction record.
+//
+//	[ty, n x [val, bb#], flags?]
+func (fr *funcReader) translatePhi(ops []uint64) error {
+	if len(ops) < 1 {
+		return errors.New("invalid PHI record; missing type")
+	}
+	typ, err := fr.r.typeOf(ops[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	n := len(ops) - 1
+	var flags uint64
+	if n%2 == 1 {
+		flags = ops[len(ops)-1]
+		n--
+	}
+	var incs []*ir.Incoming
+	for i := 1; i < 1+n; i += 2 {
+		x, err := fr.signedValue(ops[i], typ)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		pred, err := fr.block(ops[i+1])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		incs = append(incs, ir.NewIncoming(x, pred))
+	}
+	inst := ir.NewPhi(incs...)
+	inst.Typ = typ
+	inst.FastMathFlags = irFastMathFlags(flags)
+	return fr.addInst(inst)
+}
+
+// translateLandingPad translates the given landingpad instruction record.
+//
+//	[ty, iscleanup, num, n x [clausetype, val]]
+func (fr *funcReader) translateLandingPad(ops []uint64) error {
+	if len(ops) < 3 {
+		return errors.Errorf("invalid LANDINGPAD record; expected at least 3 operands, got %d", len(ops))
+	}
+	resultType, err := fr.r.typeOf(ops[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	inst := ir.NewLandingPad(resultType)
+	inst.Cleanup = ops[1] != 0
+	n := ops[2]
+	i := 3
+	for j := uint64(0); j < n; j++ {
+		if i >= len(ops) {
+			return errors.New("invalid LANDINGPAD record; missing clause")
+		}
+		var clauseType enum.ClauseType
+		switch ops[i] {
+		case 0:
+			clauseType = enum.ClauseTypeCatch
+		case 1:
+			clauseType = enum.ClauseTypeFilter
+		default:
+			return errors.Errorf("invalid landingpad clause type %d", ops[i])
+		}
+		i++
+		x, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		inst.Clauses = append(inst.Clauses, ir.NewClause(clauseType, x))
+	}
+	return fr.addInst(inst)
+}
+
+// translateSwitch translates the given switch terminator record.
+//
+//	[opty, cond, defaultbb#, n x [caseval, bb#]]
+func (fr *funcReader) translateSwitch(ops []uint64) error {
+	if len(ops) < 3 || len(ops)%2 == 0 {
+		return errors.Errorf("invalid SWITCH record; expected odd number of operands (at least 3), got %d", len(ops))
+	}
+	condType, err := fr.r.typeOf(ops[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	i := 1
+	cond, err := fr.value(ops, &i, condType)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	targetDefault, err := fr.block(ops[2])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var cases []*ir.Case
+	for i := 3; i+1 < len(ops); i += 2 {
+		// Note, case values are stored as absolute value IDs.
+		v, err := fr.values.get(fr.r, ops[i])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		x, ok := v.(constant.Constant)
+		if !ok {
+			return errors.Errorf("invalid switch case value; expected constant.Constant, got %T", v)
+		}
+		target, err := fr.block(ops[i+1])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		cases = append(cases, ir.NewCase(x, target))
+	}
+	return fr.addTerm(ir.NewSwitch(cond, targetDefault, cases...))
+}
+
+// translateCall translates the given call instruction record.
+//
+//	[paramattrs, cc, fmf?, fnty, fnid, args...]
+func (fr *funcReader) translateCall(ops []uint64) error {
+	if len(ops) < 3 {
+		return errors.Errorf("invalid CALL record; expected at least 3 operands, got %d", len(ops))
+	}
+	attrs, err := fr.r.attrList(ops[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	ccInfo := ops[1]
+	i := 2
+	var fmf uint64
+	if ccInfo&(1<<callFMF) != 0 {
+		fmf = ops[i]
+		i++
+	}
+	var sig *types.FuncType
+	if ccInfo&(1<<callExplicitType) != 0 {
+		if sig, err = fr.funcType(ops[i]); err != nil {
+			return errors.WithStack(err)
+		}
+		i++
+	}
+	callee, err := fr.valueTypePair(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if sig == nil {
+		if sig, err = calleeFuncType(callee); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	args, err := fr.callArgs(ops, i, sig)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	inst := &ir.InstCall{Callee: callee, Args: args, Typ: sig.RetType}
+	if types.IsOpaquePointer(callee.Type()) {
+		inst.CalleeSig = sig
+	}
+	switch {
+	case ccInfo&(1<<callMustTail) != 0:
+		inst.Tail = enum.TailMustTail
+	case ccInfo&(1<<callNoTail) != 0:
+		inst.Tail = enum.TailNoTail
+	case ccInfo&(1<<callTail) != 0:
+		inst.Tail = enum.TailTail
+	}
+	inst.FastMathFlags = irFastMathFlags(fmf)
+	if inst.CallingConv, err = irCallingConv(ccInfo >> callCConv & 0x3FF); err != nil {
+		return errors.WithStack(err)
+	}
+	fnAttrs, retAttrs, paramAttrs := fr.r.callAttrs(attrs)
+	inst.FuncAttrs = fnAttrs
+	inst.ReturnAttrs = retAttrs
+	applyArgAttrs(inst.Args, paramAttrs)
+	inst.OperandBundles = fr.bundles
+	fr.bundles = nil
+	return fr.addInst(inst)
+}
+
+// translateInvoke translates the given invoke terminator record.
+//
+//	[attrs, cc, normbb#, unwindbb#, fnty, fnid, args...]
+func (fr *funcReader) translateInvoke(ops []uint64) error {
+	if len(ops) < 5 {
+		return errors.Errorf("invalid INVOKE record; expected at least 5 operands, got %d", len(ops))
+	}
+	attrs, err := fr.r.attrList(ops[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	ccInfo := ops[1]
+	normal, err := fr.block(ops[2])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	unwind, err := fr.block(ops[3])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	i := 4
+	var sig *types.FuncType
+	if ccInfo&explicitTypeFlag != 0 {
+		if sig, err = fr.funcType(ops[i]); err != nil {
+			return errors.WithStack(err)
+		}
+		i++
+	}
+	invokee, err := fr.valueTypePair(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if sig == nil {
+		if sig, err = calleeFuncType(invokee); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	args, err := fr.callArgs(ops, i, sig)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	term := &ir.TermInvoke{Invokee: invokee, Args: args, NormalRetTarget: normal, ExceptionRetTarget: unwind, Typ: sig.RetType}
+	if types.IsOpaquePointer(invokee.Type()) {
+		term.InvokeeSig = sig
+	}
+	if term.CallingConv, err = irCallingConv(ccInfo & 0x3FF); err != nil {
+		return errors.WithStack(err)
+	}
+	fnAttrs, retAttrs, paramAttrs := fr.r.callAttrs(attrs)
+	term.FuncAttrs = fnAttrs
+	term.ReturnAttrs = retAttrs
+	applyArgAttrs(term.Args, paramAttrs)
+	term.OperandBundles = fr.bundles
+	fr.bundles = nil
+	return fr.addTerm(term)
+}
+
+// translateCallBr translates the given callbr terminator record.
+//
+//	[attrs, cc, normbb#, numindirect, n x indirectbb#, fnty, fnid, args...]
+func (fr *funcReader) translateCallBr(ops []uint64) error {
+	if len(ops) < 5 {
+		return errors.Errorf("invalid CALLBR record; expected at least 5 operands, got %d", len(ops))
+	}
+	attrs, err := fr.r.attrList(ops[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	ccInfo := ops[1]
+	normal, err := fr.block(ops[2])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	n := ops[3]
+	i := 4
+	var others []*ir.Block
+	for j := uint64(0); j < n; j++ {
+		if i >= len(ops) {
+			return errors.New("invalid CALLBR record; missing indirect destination")
+		}
+		other, err := fr.block(ops[i])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		others = append(others, other)
+		i++
+	}
+	var sig *types.FuncType
+	if ccInfo&(1<<callExplicitType) != 0 {
+		if i >= len(ops) {
+			return errors.New("invalid CALLBR record; missing function type")
+		}
+		if sig, err = fr.funcType(ops[i]); err != nil {
+			return errors.WithStack(err)
+		}
+		i++
+	}
+	callee, err := fr.valueTypePair(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if sig == nil {
+		if sig, err = calleeFuncType(callee); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	args, err := fr.callArgs(ops, i, sig)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	term := &ir.TermCallBr{Callee: callee, Args: args, NormalRetTarget: normal, Typ: sig.RetType}
+	for _, other := range others {
+		term.OtherRetTargets = append(term.OtherRetTargets, other)
+	}
+	if types.IsOpaquePointer(callee.Type()) {
+		term.CalleeSig = sig
+	}
+	if term.CallingConv, err = irCallingConv(ccInfo >> callCConv & 0x3FF); err != nil {
+		return errors.WithStack(err)
+	}
+	fnAttrs, retAttrs, paramAttrs := fr.r.callAttrs(attrs)
+	term.FuncAttrs = fnAttrs
+	term.ReturnAttrs = retAttrs
+	applyArgAttrs(term.Args, paramAttrs)
+	term.OperandBundles = fr.bundles
+	fr.bundles = nil
+	return fr.addTerm(term)
+}
+
+// callArgs returns the arguments of a call instruction with the given
+// function signature, stored in the given operands starting at index i.
+func (fr *funcReader) callArgs(ops []uint64, i int, sig *types.FuncType) ([]value.Value, error) {
+	var args []value.Value
+	for _, paramType := range sig.Params {
+		var arg value.Value
+		var err error
+		if types.Equal(paramType, types.Label) {
+			// Basic block arguments are stored as basic block IDs.
+			if i >= len(ops) {
+				return nil, errors.New("invalid call record; missing argument")
+			}
+			arg, err = fr.block(ops[i])
+			i++
+		} else {
+			arg, err = fr.value(ops, &i, paramType)
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		args = append(args, arg)
+	}
+	// Variadic arguments.
+	if i < len(ops) && !sig.Variadic {
+		return nil, errors.Errorf("invalid call record; %d arguments of non-variadic function", len(args)+len(ops)-i)
+	}
+	for i < len(ops) {
+		arg, err := fr.valueTypePair(ops, &i)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// funcType returns the function type of the given type ID.
+func (fr *funcReader) funcType(id uint64) (*types.FuncType, error) {
+	t, err := fr.r.typeOf(id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sig, ok := t.(*types.FuncType)
+	if !ok {
+		return nil, errors.Errorf("invalid function type; expected *types.FuncType, got %T", t)
+	}
+	return sig, nil
+}
+
+// syncScope returns the synchronization scope name of the given bitcode
+// synchronization scope ID.
+func (fr *funcReader) syncScope(id uint64) (string, error) {
+	if len(fr.r.syncScopes) == 0 {
+		// Synchronization scope names block not present.
+		switch id {
+		case syncScopeSingleThread:
+			return "singlethread", nil
+		case syncScopeSystem:
+			return "", nil
+		}
+	}
+	if id >= uint64(len(fr.r.syncScopes)) {
+		return "", errors.Errorf("invalid synchronization scope ID %d; expected < %d", id, len(fr.r.syncScopes))
+	}
+	return fr.r.syncScopes[id], nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// one is the integer constant 1.
+var one = constant.NewInt(types.I64, 1).X
+
+// binaryInst returns the binary instruction of the given bitcode opcode,
+// operands and flags.
+func binaryInst(opcode uint64, x, y value.Value, flags uint64) (ir.Instruction, error) {
+	isFloat := types.IsFloat(x.Type()) || isFloatVector(x.Type())
+	switch opcode {
+	case binopAdd:
+		if isFloat {
+			inst := ir.NewFAdd(x, y)
+			inst.FastMathFlags = irFastMathFlags(flags)
+			return inst, nil
+		}
+		inst := ir.NewAdd(x, y)
+		inst.OverflowFlags = irOverflowFlags(flags)
+		return inst, nil
+	case binopSub:
+		if isFloat {
+			inst := ir.NewFSub(x, y)
+			inst.FastMathFlags = irFastMathFlags(flags)
+			return inst, nil
+		}
+		inst := ir.NewSub(x, y)
+		inst.OverflowFlags = irOverflowFlags(flags)
+		return inst, nil
+	case binopMul:
+		if isFloat {
+			inst := ir.NewFMul(x, y)
+			inst.FastMathFlags = irFastMathFlags(flags)
+			return inst, nil
+		}
+		inst := ir.NewMul(x, y)
+		inst.OverflowFlags = irOverflowFlags(flags)
+		return inst, nil
+	case binopUDiv:
+		inst := ir.NewUDiv(x, y)
+		inst.Exact = flags&(1<<peoExact) != 0
+		return inst, nil
+	case binopSDiv:
+		if isFloat {
+			inst := ir.NewFDiv(x, y)
+			inst.FastMathFlags = irFastMathFlags(flags)
+			return inst, nil
+		}
+		inst := ir.NewSDiv(x, y)
+		inst.Exact = flags&(1<<peoExact) != 0
+		return inst, nil
+	case binopURem:
+		return ir.NewURem(x, y), nil
+	case binopSRem:
+		if isFloat {
+			inst := ir.NewFRem(x, y)
+			inst.FastMathFlags = irFastMathFlags(flags)
+			return inst, nil
+		}
+		return ir.NewSRem(x, y), nil
+	case binopShl:
+		inst := ir.NewShl(x, y)
+		inst.OverflowFlags = irOverflowFlags(flags)
+		return inst, nil
+	case binopLShr:
+		inst := ir.NewLShr(x, y)
+		inst.Exact = flags&(1<<peoExact) != 0
+		return inst, nil
+	case binopAShr:
+		inst := ir.NewAShr(x, y)
+		inst.Exact = flags&(1<<peoExact) != 0
+		return inst, nil
+	case binopAnd:
+		return ir.NewAnd(x, y), nil
+	case binopOr:
+		return ir.NewOr(x, y), nil
+	case binopXor:
+		return ir.NewXor(x, y), nil
+	default:
+		return nil, errors.Errorf("support for binary instruction opcode %d not yet implemented", opcode)
+	}
+}
+
+// castInst returns the conversion instruction of the given bitcode opcode,
+// operand and result type.
+func castInst(opcode uint64, from value.Value, to types.Type) (ir.Instruction, error) {
+	switch opcode {
+	case castTrunc:
+		return ir.NewTrunc(from, to), nil
+	case castZExt:
+		return ir.NewZExt(from, to), nil
+	case castSExt:
+		return ir.NewSExt(from, to), nil
+	case castFPToUI:
+		return ir.NewFPToUI(from, to), nil
+	case castFPToSI:
+		return ir.NewFPToSI(from, to), nil
+	case castUIToFP:
+		return ir.NewUIToFP(from, to), nil
+	case castSIToFP:
+		return ir.NewSIToFP(from, to), nil
+	case castFPTrunc:
+		return ir.NewFPTrunc(from, to), nil
+	case castFPExt:
+		return ir.NewFPExt(from, to), nil
+	case castPtrToInt:
+		return ir.NewPtrToInt(from, to), nil
+	case castIntToPtr:
+		return ir.NewIntToPtr(from, to), nil
+	case castBitCast:
+		return ir.NewBitCast(from, to), nil
+	case castAddrSpaceCast:
+		return ir.NewAddrSpaceCast(from, to), nil
+	default:
+		return nil, errors.Errorf("support for conversion instruction opcode %d not yet implemented", opcode)
+	}
+}
+
+// applyArgAttrs applies the given parameter attributes (indexed by argument
+// index) to the arguments of a call instruction.
+func applyArgAttrs(args []value.Value, paramAttrs map[int][]ir.ParamAttribute) {
+	for i, attrs := range paramAttrs {
+		if i < len(args) {
+			args[i] = &ir.Arg{Value: args[i], Attrs: attrs}
+		}
+	}
+}
+
+// calleeFuncType returns the function type of the given callee of typed
+// pointer type.
+func calleeFuncType(callee value.Value) (*types.FuncType, error) {
+	elemType, err := elemTypeOf(callee.Type())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sig, ok := elemType.(*types.FuncType)
+	if !ok {
+		return nil, errors.Errorf("invalid callee type; expected pointer to function type, got %v", callee.Type())
+	}
+	return sig, nil
+}
+
+// elemTypeOf returns the element type of the given typed pointer type.
+func elemTypeOf(t types.Type) (types.Type, error) {
+	ptr, ok := t.(*types.PointerType)
+	if !ok || ptr.ElemType == nil {
+		return nil, errors.Errorf("invalid type; expected typed pointer type, got %v", t)
+	}
+	return ptr.ElemType, nil
+}
@@ -0,0 +1,1302 @@
+package bitcode
+
+import (
+	"math/big"
+
+	"github.com/llir/llvm/internal/bitstream"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/metadata"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+	"github.com/pkg/errors"
+)
+
+// mdList is a list of metadata indexed by metadata ID.
+//
+// Each entry is either a metadata string (*metadata.String), a value (e.g. a
+// constant or a function-local value), or a metadata node.
+type mdList struct {
+	entries []metadata.Field
+}
+
+// mdNodeRecord is a metadata node pending translation of its fields.
+type mdNodeRecord struct {
+	// Metadata node; created before translation of its fields to allow cyclic
+	// references.
+	node metadata.Field
+	// Metadata record of the node.
+	record *bitstream.Record
+}
+
+// locKey is the contents of a uniqued (non-distinct) DILocation.
+type locKey struct {
+	line, column int64
+	scope        metadata.Field
+	inlinedAt    *metadata.DILocation
+	implicit     bool
+}
+
+// translateMetadataKinds translates the given metadata kind block.
+//
+//	KIND: [n x [id, name]]
+func (r *reader) translateMetadataKinds(block *bitstream.Block) error {
+	for _, record := range block.Records() {
+		if record.Code != metadataCodeKind {
+			continue
+		}
+		if len(record.Ops) < 1 {
+			return errors.New("invalid KIND record; missing metadata kind ID")
+		}
+		r.mdKinds[record.Ops[0]] = record.String(1)
+	}
+	return nil
+}
+
+// translateMetadata translates the given metadata block. The values of VALUE
+// records are located in the given value list; either the module-level value
+// list or the value list of a function.
+//
+// Metadata nodes may refer to nodes defined later in the same block, so
+// translation is done in two steps. First, all metadata entries are created,
+// with empty metadata nodes. Second, the fields of metadata nodes are
+// translated.
+func (r *reader) translateMetadata(block *bitstream.Block, values *valueList) error {
+	var (
+		nodes       []*mdNodeRecord
+		namedNodes  []*bitstream.Record
+		names       []string
+		attachments []*bitstream.Record
+		name        string
+	)
+	for _, record := range block.Records() {
+		switch record.Code {
+		case metadataCodeStrings:
+			ss, err := metadataStrings(record)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			for _, s := range ss {
+				r.mds.entries = append(r.mds.entries, &metadata.String{Value: s})
+			}
+		case metadataCodeStringOld:
+			r.mds.entries = append(r.mds.entries, &metadata.String{Value: record.String(0)})
+		case metadataCodeValue:
+			// [ty, val]
+			if len(record.Ops) < 2 {
+				return errors.Errorf("invalid VALUE record; expected 2 operands, got %d", len(record.Ops))
+			}
+			typ, err := r.typeOf(record.Ops[0])
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			v, err := values.getFwd(r, record.Ops[1], typ)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			r.mds.entries = append(r.mds.entries, v)
+		case metadataCodeName:
+			name = record.String(0)
+		case metadataCodeNamedNode:
+			names = append(names, name)
+			namedNodes = append(namedNodes, record)
+		case metadataCodeKind:
+			if len(record.Ops) < 1 {
+				return errors.New("invalid KIND record; missing metadata kind ID")
+			}
+			r.mdKinds[record.Ops[0]] = record.String(1)
+		case metadataCodeGlobalDeclAttachment:
+			attachments = append(attachments, record)
+		case metadataCodeIndexOffset, metadataCodeIndex:
+			// Only used for lazy loading of metadata.
+		case metadataCodeOldNode, metadataCodeOldFnNode:
+			return errors.Errorf("support for old-style metadata node records (code %d) not yet implemented", record.Code)
+		case metadataCodeGenericSubrange:
+			return errors.New("support for DIGenericSubrange metadata not yet implemented")
+		default:
+			node, err := newMDNode(record)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			r.mds.entries = append(r.mds.entries, node)
+			nodes = append(nodes, &mdNodeRecord{node: node, record: record})
+		}
+	}
+	// Translate fields of metadata nodes.
+	for _, n := range nodes {
+		if err := r.translateMDNode(n.node, n.record); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	// Keep track of uniqued debug locations, as they may be reused by debug
+	// location records of function bodies.
+	for _, n := range nodes {
+		if loc, ok := n.node.(*metadata.DILocation); ok && !loc.Distinct {
+			r.locs[newLocKey(loc)] = loc
+		}
+	}
+	// Named metadata.
+	for i, record := range namedNodes {
+		def := &metadata.NamedDef{Name: names[i]}
+		for _, id := range record.Ops {
+			field, err := r.mdNode(id)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			node, ok := field.(metadata.Node)
+			if !ok {
+				return errors.Errorf("invalid operand of named metadata %q; expected metadata.Node, got %T", def.Name, field)
+			}
+			def.Nodes = append(def.Nodes, node)
+		}
+		r.namedMDs = append(r.namedMDs, def)
+		r.m.NamedMetadataDefs[def.Name] = def
+	}
+	// Metadata attachments of global variables and function declarations.
+	for _, record := range attachments {
+		if err := r.translateGlobalAttachment(record); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// translateGlobalAttachment translates the given metadata attachment record of
+// a global variable or function declaration.
+//
+//	GLOBAL_DECL_ATTACHMENT: [valueid, n x [id, mdnode]]
+func (r *reader) translateGlobalAttachment(record *bitstream.Record) error {
+	if len(record.Ops)%2 != 1 {
+		return errors.Errorf("invalid GLOBAL_DECL_ATTACHMENT record; expected odd number of operands, got %d", len(record.Ops))
+	}
+	v, err := r.values.get(r, record.Ops[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	mds, err := r.mdAttachments(record.Ops[1:])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	switch v := v.(type) {
+	case *ir.Global:
+		v.Metadata = append(v.Metadata, mds...)
+	case *ir.Func:
+		v.Metadata = append(v.Metadata, mds...)
+	default:
+		return errors.Errorf("invalid metadata attachment of value with value ID %d; expected global variable or function, got %T", record.Ops[0], v)
+	}
+	return nil
+}
+
+// mdAttachments returns the metadata attachments of the given operands.
+//
+//	[n x [id, mdnode]]
+func (r *reader) mdAttachments(ops []uint64) ([]*metadata.Attachment, error) {
+	var mds []*metadata.Attachment
+	for i := 0; i+1 < len(ops); i += 2 {
+		name, ok := r.mdKinds[ops[i]]
+		if !ok {
+			return nil, errors.Errorf("unable to locate metadata kind with ID %d", ops[i])
+		}
+		node, err := r.mdNode(ops[i+1])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		mdNode, ok := node.(metadata.MDNode)
+		if !ok {
+			return nil, errors.Errorf("invalid metadata attachment %q; expected metadata node, got %T", name, node)
+		}
+		mds = append(mds, &metadata.Attachment{Name: name, Node: mdNode})
+	}
+	return mds, nil
+}
+
+// debugLoc returns the uniqued debug location with the given contents.
+func (r *reader) debugLoc(line, column int64, scope metadata.Field, inlinedAt *metadata.DILocation, implicit bool) *metadata.DILocation {
+	key := locKey{line: line, column: column, scope: scope, inlinedAt: inlinedAt, implicit: implicit}
+	if loc, ok := r.locs[key]; ok {
+		return loc
+	}
+	loc := &metadata.DILocation{
+		MetadataID:     -1,
+		Line:           line,
+		Column:         column,
+		Scope:          scope,
+		InlinedAt:      inlinedAt,
+		IsImplicitCode: implicit,
+	}
+	r.locs[key] = loc
+	return loc
+}
+
+// newLocKey returns the uniquing key of the given debug location.
+func newLocKey(loc *metadata.DILocation) locKey {
+	return locKey{
+		line:      loc.Line,
+		column:    loc.Column,
+		scope:     loc.Scope,
+		inlinedAt: loc.InlinedAt,
+		implicit:  loc.IsImplicitCode,
+	}
+}
+
+// --- [ Metadata nodes ] ------------------------------------------------------
+
+// newMDNode returns a new empty metadata node of the given metadata record.
+func newMDNode(record *bitstream.Record) (metadata.Field, error) {
+	switch record.Code {
+	case metadataCodeNode, metadataCodeDistinctNode:
+		return &metadata.Tuple{MetadataID: -1}, nil
+	case metadataCodeLocation:
+		return &metadata.DILocation{MetadataID: -1}, nil
+	case metadataCodeGenericDebug:
+		return &metadata.GenericDINode{MetadataID: -1}, nil
+	case metadataCodeSubrange:
+		return &metadata.DISubrange{MetadataID: -1}, nil
+	case metadataCodeEnumerator:
+		return &metadata.DIEnumerator{MetadataID: -1}, nil
+	case metadataCodeBasicType:
+		return &metadata.DIBasicType{MetadataID: -1}, nil
+	case metadataCodeStringType:
+		return &metadata.DIStringType{MetadataID: -1}, nil
+	case metadataCodeFile:
+		return &metadata.DIFile{MetadataID: -1}, nil
+	case metadataCodeDerivedType:
+		return &metadata.DIDerivedType{MetadataID: -1}, nil
+	case metadataCodeCompositeType:
+		return &metadata.DICompositeType{MetadataID: -1}, nil
+	case metadataCodeSubroutineType:
+		return &metadata.DISubroutineType{MetadataID: -1}, nil
+	case metadataCodeCompileUnit:
+		return &metadata.DICompileUnit{MetadataID: -1}, nil
+	case metadataCodeSubprogram:
+		return &metadata.DISubprogram{MetadataID: -1}, nil
+	case metadataCodeLexicalBlock:
+		return &metadata.DILexicalBlock{MetadataID: -1}, nil
+	case metadataCodeLexicalBlockFile:
+		return &metadata.DILexicalBlockFile{MetadataID: -1}, nil
+	case metadataCodeCommonBlock:
+		return &metadata.DICommonBlock{MetadataID: -1}, nil
+	case metadataCodeNamespace:
+		return &metadata.DINamespace{MetadataID: -1}, nil
+	case metadataCodeMacro:
+		return &metadata.DIMacro{MetadataID: -1}, nil
+	case metadataCodeMacroFile:
+		return &metadata.DIMacroFile{MetadataID: -1}, nil
+	case metadataCodeModule:
+		return &metadata.DIModule{MetadataID: -1}, nil
+	case metadataCodeTemplateType:
+		return &metadata.DITemplateTypeParameter{MetadataID: -1}, nil
+	case metadataCodeTemplateValue:
+		return &metadata.DITemplateValueParameter{MetadataID: -1}, nil
+	case metadataCodeGlobalVar:
+		return &metadata.DIGlobalVariable{MetadataID: -1}, nil
+	case metadataCodeLocalVar:
+		return &metadata.DILocalVariable{MetadataID: -1}, nil
+	case metadataCodeLabel:
+		return &metadata.DILabel{MetadataID: -1}, nil
+	case metadataCodeExpression:
+		return &metadata.DIExpression{MetadataID: -1}, nil
+	case metadataCodeGlobalVarExpr:
+		return &metadata.DIGlobalVariableExpression{MetadataID: -1}, nil
+	case metadataCodeObjCProperty:
+		return &metadata.DIObjCProperty{MetadataID: -1}, nil
+	case metadataCodeImportedEntity:
+		return &metadata.DIImportedEntity{MetadataID: -1}, nil
+	case metadataCodeArgList:
+		return &metadata.DIArgList{}, nil
+	default:
+		return nil, errors.Errorf("support for metadata record with code %d not yet implemented", record.Code)
+	}
+}
+
+// translateMDNode translates the fields of the given metadata node from the
+// given metadata record.
+func (r *reader) translateMDNode(node metadata.Field, record *bitstream.Record) error {
+	ops := record.Ops
+	minOps := func(n int) error {
+		if len(ops) < n {
+			return errors.Errorf("invalid metadata record with code %d; expected at least %d operands, got %d", record.Code, n, len(ops))
+		}
+		return nil
+	}
+	// op returns the operand at the given index; or zero if not present.
+	op := func(i int) uint64 {
+		if i < len(ops) {
+			return ops[i]
+		}
+		return 0
+	}
+	var err error
+	switch md := node.(type) {
+	case *metadata.Tuple:
+		// NODE: [n x md num]
+		md.Distinct = record.Code == metadataCodeDistinctNode
+		for _, id := range ops {
+			field, err := r.mdFieldOrNull(id)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			md.Fields = append(md.Fields, field)
+		}
+	case *metadata.DILocation:
+		// [distinct, line, col, scope, inlined-at?, isImplicitCode]
+		if err := minOps(5); err != nil {
+			return err
+		}
+		md.Distinct = ops[0] != 0
+		md.Line = int64(ops[1])
+		md.Column = int64(ops[2])
+		if md.Scope, err = r.mdNode(ops[3]); err != nil {
+			return errors.WithStack(err)
+		}
+		if ops[4] != 0 {
+			inlinedAt, err := r.mdNode(ops[4] - 1)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			loc, ok := inlinedAt.(*metadata.DILocation)
+			if !ok {
+				return errors.Errorf("invalid inlinedAt of DILocation; expected *metadata.DILocation, got %T", inlinedAt)
+			}
+			md.InlinedAt = loc
+		}
+		md.IsImplicitCode = op(5) != 0
+	case *metadata.GenericDINode:
+		// [distinct, tag, version, header, n x md num]
+		if err := minOps(4); err != nil {
+			return err
+		}
+		md.Distinct = ops[0] != 0
+		md.Tag = enum.DwarfTag(ops[1])
+		if md.Header, err = r.mdString(ops[3]); err != nil {
+			return errors.WithStack(err)
+		}
+		for _, id := range ops[4:] {
+			field, err := r.mdFieldOrNull(id)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			md.Operands = append(md.Operands, field)
+		}
+	case *metadata.DISubrange:
+		// version 0: [distinct, count, lo]
+		// version 1: [distinct|1<<1, count, lo]
+		// version 2: [distinct|2<<1, count, lo, up, stride]
+		if err := minOps(3); err != nil {
+			return err
+		}
+		md.Distinct = ops[0]&1 != 0
+		switch version := ops[0] >> 1; version {
+		case 0:
+			md.Count = metadata.IntLit(int64(ops[1]))
+			md.LowerBound = metadata.IntLit(decodeSigned(ops[2]))
+		case 1:
+			if md.Count, err = r.mdFieldOrInt(ops[1]); err != nil {
+				return errors.WithStack(err)
+			}
+			md.LowerBound = metadata.IntLit(decodeSigned(ops[2]))
+		case 2:
+			if err := minOps(5); err != nil {
+				return err
+			}
+			if md.Count, err = r.mdFieldOrInt(ops[1]); err != nil {
+				return errors.WithStack(err)
+			}
+			if md.LowerBound, err = r.mdFieldOrInt(ops[2]); err != nil {
+				return errors.WithStack(err)
+			}
+			if md.UpperBound, err = r.mdFieldOrInt(ops[3]); err != nil {
+				return errors.WithStack(err)
+			}
+			if md.Stride, err = r.mdFieldOrInt(ops[4]); err != nil {
+				return errors.WithStack(err)
+			}
+		default:
+			return errors.Errorf("support for DISubrange record version %d not yet implemented", version)
+		}
+	case *metadata.DIEnumerator:
+		// [isBigInt<<2|isUnsigned<<1|distinct, bitwidth, name, n x word]
+		// [isUnsigned<<1|distinct, value, name]
+		if err := minOps(3); err != nil {
+			return err
+		}
+		md.Distinct = ops[0]&1 != 0
+		md.IsUnsigned = ops[0]&2 != 0
+		if md.Name, err = r.mdString(ops[2]); err != nil {
+			return errors.WithStack(err)
+		}
+		if ops[0]&4 != 0 {
+			if err := minOps(4); err != nil {
+				return err
+			}
+			x := wideInt(ops[3:], ops[1])
+			if md.IsUnsigned {
+				if x.Sign() < 0 {
+					// Zero-extend unsigned values.
+					x.Add(x, new(big.Int).Lsh(big.NewInt(1), uint(ops[1])))
+				}
+				md.Value = int64(x.Uint64())
+			} else {
+				md.Value = x.Int64()
+			}
+		} else {
+			// Sign rotated value.
+			v := ops[1]
+			if v&1 != 0 {
+				md.Value = int64(^(v >> 1))
+			} else {
+				md.Value = int64(v >> 1)
+			}
+		}
+	case *metadata.DIBasicType:
+		// [distinct, tag, name, size, align, encoding, flags]
+		if err := minOps(6); err != nil {
+			return err
+		}
+		md.Distinct = ops[0] != 0
+		if tag := enum.DwarfTag(ops[1]); tag != enum.DwarfTagBaseType {
+			// DW_TAG_base_type is the default tag of DIBasicType.
+			md.Tag = tag
+		}
+		if md.Name, err = r.mdString(ops[2]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.Size = ops[3]
+		md.Align = ops[4]
+		md.Encoding = enum.DwarfAttEncoding(ops[5])
+		md.Flags = enum.DIFlag(op(6))
+	case *metadata.DIStringType:
+		// [distinct, tag, name, stringLength, stringLengthExp,
+		//  stringLocationExp, size, align, encoding]
+		if err := minOps(8); err != nil {
+			return err
+		}
+		md.Distinct = ops[0] != 0
+		md.Tag = enum.DwarfTag(ops[1])
+		if md.Name, err = r.mdString(ops[2]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.StringLength, err = r.mdField(ops[3]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.StringLengthExpression, err = r.mdField(ops[4]); err != nil {
+			return errors.WithStack(err)
+		}
+		i := 5
+		if len(ops) > 8 {
+			if md.StringLocationExpression, err = r.mdField(ops[5]); err != nil {
+				return errors.WithStack(err)
+			}
+			i++
+		}
+		md.Size = ops[i]
+		md.Align = ops[i+1]
+		md.Encoding = enum.DwarfAttEncoding(ops[i+2])
+	case *metadata.DIFile:
+		// [distinct, filename, directory, checksumkind, checksum, source]
+		if err := minOps(3); err != nil {
+			return err
+		}
+		md.Distinct = ops[0] != 0
+		if md.Filename, err = r.mdString(ops[1]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Directory, err = r.mdString(ops[2]); err != nil {
+			return errors.WithStack(err)
+		}
+		if len(ops) > 4 && ops[3] != 0 {
+			md.Checksumkind = enum.ChecksumKind(ops[3])
+			if md.Checksum, err = r.mdString(ops[4]); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		if md.Source, err = r.mdString(op(5)); err != nil {
+			return errors.WithStack(err)
+		}
+	case *metadata.DIDerivedType:
+		// [distinct, tag, name, file, line, scope, baseType, size, align,
+		//  offset, flags, extraData, dwarfAddressSpace+1, annotations]
+		if err := minOps(12); err != nil {
+			return err
+		}
+		md.Distinct = ops[0] != 0
+		md.Tag = enum.DwarfTag(ops[1])
+		if md.Name, err = r.mdString(ops[2]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.File, err = r.mdFile(ops[3]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.Line = int64(ops[4])
+		if md.Scope, err = r.mdField(ops[5]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.BaseType, err = r.mdFieldOrNull(ops[6]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.Size = ops[7]
+		md.Align = ops[8]
+		md.Offset = ops[9]
+		md.Flags = enum.DIFlag(ops[10])
+		if md.ExtraData, err = r.mdField(ops[11]); err != nil {
+			return errors.WithStack(err)
+		}
+		if dwarfAddrSpace := op(12); dwarfAddrSpace != 0 {
+			md.DwarfAddressSpace = dwarfAddrSpace - 1
+		}
+		if md.Annotations, err = r.mdField(op(13)); err != nil {
+			return errors.WithStack(err)
+		}
+	case *metadata.DICompositeType:
+		// [distinct, tag, name, file, line, scope, baseType, size, align,
+		//  offset, flags, elements, runtimeLang, vtableHolder, templateParams,
+		//  identifier, discriminator, dataLocation, associated, allocated,
+		//  rank, annotations]
+		if err := minOps(16); err != nil {
+			return err
+		}
+		md.Distinct = ops[0]&1 != 0
+		md.Tag = enum.DwarfTag(ops[1])
+		if md.Name, err = r.mdString(ops[2]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.File, err = r.mdFile(ops[3]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.Line = int64(ops[4])
+		if md.Scope, err = r.mdField(ops[5]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.BaseType, err = r.mdField(ops[6]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.Size = ops[7]
+		md.Align = ops[8]
+		md.Offset = ops[9]
+		md.Flags = enum.DIFlag(ops[10])
+		if md.Elements, err = r.mdTuple(ops[11]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.RuntimeLang = enum.DwarfLang(ops[12])
+		if md.VtableHolder, err = r.mdField(ops[13]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.TemplateParams, err = r.mdTuple(ops[14]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Identifier, err = r.mdString(ops[15]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Discriminator, err = r.mdField(op(16)); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.DataLocation, err = r.mdField(op(17)); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Associated, err = r.mdField(op(18)); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Allocated, err = r.mdField(op(19)); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Rank, err = r.mdFieldOrInt(op(20)); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Annotations, err = r.mdField(op(21)); err != nil {
+			return errors.WithStack(err)
+		}
+	case *metadata.DISubroutineType:
+		// [distinct|hasNoOldTypeRefs<<1, flags, types, cc]
+		if err := minOps(3); err != nil {
+			return err
+		}
+		md.Distinct = ops[0]&1 != 0
+		md.Flags = enum.DIFlag(ops[1])
+		if md.Types, err = r.mdTuple(ops[2]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.CC = enum.DwarfCC(op(3))
+	case *metadata.DICompileUnit:
+		// [distinct, lang, file, producer, isOpt, flags, runtimeVersion,
+		//  splitDebugFilename, emissionKind, enums, retainedTypes,
+		//  subprograms, globals, imports, dwoId, macros, splitDebugInlining,
+		//  debugInfoForProfiling, nameTableKind, rangesBaseAddress, sysroot,
+		//  sdk]
+		if err := minOps(14); err != nil {
+			return err
+		}
+		md.Distinct = ops[0] != 0
+		md.Language = enum.DwarfLang(ops[1])
+		if md.File, err = r.mdFile(ops[2]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Producer, err = r.mdString(ops[3]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.IsOptimized = ops[4] != 0
+		if md.Flags, err = r.mdString(ops[5]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.RuntimeVersion = ops[6]
+		if md.SplitDebugFilename, err = r.mdString(ops[7]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.EmissionKind = enum.EmissionKind(ops[8])
+		if md.Enums, err = r.mdTuple(ops[9]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.RetainedTypes, err = r.mdTuple(ops[10]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Globals, err = r.mdTuple(ops[12]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Imports, err = r.mdTuple(ops[13]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.DwoID = op(14)
+		if md.Macros, err = r.mdTuple(op(15)); err != nil {
+			return errors.WithStack(err)
+		}
+		// Note, splitDebugInlining (operand 16) defaults to true in LLVM, and is
+		// only printed in LLVM IR assembly when false; which is also the zero
+		// value of the IR field.
+		md.DebugInfoForProfiling = op(17) != 0
+		md.NameTableKind = enum.NameTableKind(op(18))
+		md.RangesBaseAddress = op(19) != 0
+		if md.Sysroot, err = r.mdString(op(20)); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.SDK, err = r.mdString(op(21)); err != nil {
+			return errors.WithStack(err)
+		}
+	case *metadata.DISubprogram:
+		// [distinct|hasUnit<<1|hasSPFlags<<2, scope, name, linkageName, file,
+		//  line, type, scopeLine, containingType, spFlags, virtualIndex,
+		//  flags, unit, templateParams, declaration, retainedNodes,
+		//  thisAdjustment, thrownTypes, annotations]
+		if err := minOps(16); err != nil {
+			return err
+		}
+		if ops[0]&4 == 0 {
+			return errors.New("support for DISubprogram records without subprogram flags not yet implemented")
+		}
+		md.Distinct = ops[0]&1 != 0
+		if md.Scope, err = r.mdField(ops[1]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Name, err = r.mdString(ops[2]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.LinkageName, err = r.mdString(ops[3]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.File, err = r.mdFile(ops[4]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.Line = int64(ops[5])
+		if md.Type, err = r.mdField(ops[6]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.ScopeLine = int64(ops[7])
+		if md.ContainingType, err = r.mdField(ops[8]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.SPFlags = enum.DISPFlag(ops[9])
+		md.VirtualIndex = ops[10]
+		md.Flags = enum.DIFlag(ops[11])
+		if ops[12] != 0 {
+			unit, err := r.mdNode(ops[12] - 1)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			cu, ok := unit.(*metadata.DICompileUnit)
+			if !ok {
+				return errors.Errorf("invalid unit of DISubprogram; expected *metadata.DICompileUnit, got %T", unit)
+			}
+			md.Unit = cu
+		}
+		if md.TemplateParams, err = r.mdTuple(ops[13]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Declaration, err = r.mdField(ops[14]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.RetainedNodes, err = r.mdTuple(ops[15]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.ThisAdjustment = int64(op(16))
+		if md.ThrownTypes, err = r.mdTuple(op(17)); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Annotations, err = r.mdField(op(18)); err != nil {
+			return errors.WithStack(err)
+		}
+	case *metadata.DILexicalBlock:
+		// [distinct, scope, file, line, column]
+		if err := minOps(5); err != nil {
+			return err
+		}
+		md.Distinct = ops[0] != 0
+		if md.Scope, err = r.mdFieldOrNull(ops[1]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.File, err = r.mdFile(ops[2]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.Line = int64(ops[3])
+		md.Column = int64(ops[4])
+	case *metadata.DILexicalBlockFile:
+		// [distinct, scope, file, discriminator]
+		if err := minOps(4); err != nil {
+			return err
+		}
+		md.Distinct = ops[0] != 0
+		if md.Scope, err = r.mdFieldOrNull(ops[1]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.File, err = r.mdFile(ops[2]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.Discriminator = ops[3]
+	case *metadata.DICommonBlock:
+		// [distinct, scope, decl, name, file, line]
+		if err := minOps(6); err != nil {
+			return err
+		}
+		md.Distinct = ops[0] != 0
+		if md.Scope, err = r.mdFieldOrNull(ops[1]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Declaration, err = r.mdField(ops[2]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Name, err = r.mdString(ops[3]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.File, err = r.mdFile(ops[4]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.Line = int64(ops[5])
+	case *metadata.DINamespace:
+		// [distinct|exportSymbols<<1, scope, name]
+		// [distinct|exportSymbols<<1, scope, file, name, line]
+		if err := minOps(3); err != nil {
+			return err
+		}
+		md.Distinct = ops[0]&1 != 0
+		md.ExportSymbols = ops[0]&2 != 0
+		if md.Scope, err = r.mdFieldOrNull(ops[1]); err != nil {
+			return errors.WithStack(err)
+		}
+		nameID := ops[2]
+		if len(ops) == 5 {
+			nameID = ops[3]
+		}
+		if md.Name, err = r.mdString(nameID); err != nil {
+			return errors.WithStack(err)
+		}
+	case *metadata.DIMacro:
+		// [distinct, macinfo, line, name, value]
+		if err := minOps(5); err != nil {
+			return err
+		}
+		md.Distinct = ops[0] != 0
+		md.Type = enum.DwarfMacinfo(ops[1])
+		md.Line = int64(ops[2])
+		if md.Name, err = r.mdString(ops[3]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Value, err = r.mdString(ops[4]); err != nil {
+			return errors.WithStack(err)
+		}
+	case *metadata.DIMacroFile:
+		// [distinct, macinfo, line, file, elements]
+		if err := minOps(5); err != nil {
+			return err
+		}
+		md.Distinct = ops[0] != 0
+		md.Type = enum.DwarfMacinfo(ops[1])
+		md.Line = int64(ops[2])
+		if md.File, err = r.mdFile(ops[3]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Nodes, err = r.mdTuple(ops[4]); err != nil {
+			return errors.WithStack(err)
+		}
+	case *metadata.DIModule:
+		// [distinct, file, scope, name, configurationMacros, includePath,
+		//  apinotes, line, isDecl]
+		if err := minOps(6); err != nil {
+			return err
+		}
+		md.Distinct = ops[0] != 0
+		// Old records lack the file operand.
+		i := 1
+		if len(ops) > 8 {
+			if md.File, err = r.mdField(ops[1]); err != nil {
+				return errors.WithStack(err)
+			}
+			i++
+		}
+		if md.Scope, err = r.mdFieldOrNull(ops[i]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Name, err = r.mdString(ops[i+1]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.ConfigMacros, err = r.mdString(ops[i+2]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.IncludePath, err = r.mdString(ops[i+3]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.APINotes, err = r.mdString(op(i + 4)); err != nil {
+			return errors.WithStack(err)
+		}
+		md.Line = int64(op(i + 5))
+		md.IsDecl = op(i+6) != 0
+	case *metadata.DITemplateTypeParameter:
+		// [distinct, name, type, isDefault]
+		if err := minOps(3); err != nil {
+			return err
+		}
+		md.Distinct = ops[0] != 0
+		if md.Name, err = r.mdString(ops[1]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Type, err = r.mdFieldOrNull(ops[2]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.Defaulted = op(3) != 0
+	case *metadata.DITemplateValueParameter:
+		// [distinct, tag, name, type, isDefault, value]
+		// [distinct, tag, name, type, value]
+		if err := minOps(5); err != nil {
+			return err
+		}
+		md.Distinct = ops[0] != 0
+		md.Tag = enum.DwarfTag(ops[1])
+		if md.Name, err = r.mdString(ops[2]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Type, err = r.mdField(ops[3]); err != nil {
+			return errors.WithStack(err)
+		}
+		valueID := ops[4]
+		if len(ops) > 5 {
+			md.Defaulted = ops[4] != 0
+			valueID = ops[5]
+		}
+		if md.Value, err = r.mdFieldOrNull(valueID); err != nil {
+			return errors.WithStack(err)
+		}
+	case *metadata.DIGlobalVariable:
+		// [distinct|version<<1, scope, name, linkageName, file, line, type,
+		//  isLocal, isDefinition, staticDataMemberDeclaration, templateParams,
+		//  alignInBits, annotations]
+		if err := minOps(11); err != nil {
+			return err
+		}
+		if version := ops[0] >> 1; version < 2 {
+			return errors.Errorf("support for DIGlobalVariable record version %d not yet implemented", version)
+		}
+		md.Distinct = ops[0]&1 != 0
+		if md.Scope, err = r.mdField(ops[1]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Name, err = r.mdString(ops[2]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.LinkageName, err = r.mdString(ops[3]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.File, err = r.mdFile(ops[4]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.Line = int64(ops[5])
+		if md.Type, err = r.mdField(ops[6]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.IsLocal = ops[7] != 0
+		md.IsDefinition = ops[8] != 0
+		if md.Declaration, err = r.mdField(ops[9]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.TemplateParams, err = r.mdTuple(ops[10]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.Align = op(11)
+		if md.Annotations, err = r.mdField(op(12)); err != nil {
+			return errors.WithStack(err)
+		}
+	case *metadata.DILocalVariable:
+		// [distinct|hasAlignment<<1, scope, name, file, line, type, arg, flags,
+		//  align, annotations]
+		if err := minOps(8); err != nil {
+			return err
+		}
+		md.Distinct = ops[0]&1 != 0
+		hasAlign := ops[0]&2 != 0
+		// Old records have an artificial tag as second operand.
+		i := 1
+		if !hasAlign && len(ops) > 8 {
+			i++
+		}
+		if md.Scope, err = r.mdFieldOrNull(ops[i]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Name, err = r.mdString(ops[i+1]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.File, err = r.mdFile(ops[i+2]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.Line = int64(ops[i+3])
+		if md.Type, err = r.mdField(ops[i+4]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.Arg = ops[i+5]
+		md.Flags = enum.DIFlag(ops[i+6])
+		if hasAlign {
+			md.Align = op(i + 7)
+			if md.Annotations, err = r.mdField(op(i + 8)); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	case *metadata.DILabel:
+		// [distinct, scope, name, file, line]
+		if err := minOps(5); err != nil {
+			return err
+		}
+		md.Distinct = ops[0] != 0
+		if md.Scope, err = r.mdFieldOrNull(ops[1]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Name, err = r.mdString(ops[2]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.File, err = r.mdFile(ops[3]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.Line = int64(ops[4])
+	case *metadata.DIExpression:
+		// [distinct|version<<1, n x element]
+		if err := minOps(1); err != nil {
+			return err
+		}
+		md.Distinct = ops[0]&1 != 0
+		if version := ops[0] >> 1; version < 3 {
+			return errors.Errorf("support for DIExpression record version %d not yet implemented", version)
+		}
+		md.Fields = diExpressionFields(ops[1:])
+	case *metadata.DIGlobalVariableExpression:
+		// [distinct, var, expr]
+		if err := minOps(3); err != nil {
+			return err
+		}
+		md.Distinct = ops[0] != 0
+		v, err := r.mdFieldOrNull(ops[1])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		gv, ok := v.(*metadata.DIGlobalVariable)
+		if !ok {
+			return errors.Errorf("invalid variable of DIGlobalVariableExpression; expected *metadata.DIGlobalVariable, got %T", v)
+		}
+		md.Var = gv
+		e, err := r.mdFieldOrNull(ops[2])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		expr, ok := e.(*metadata.DIExpression)
+		if !ok {
+			return errors.Errorf("invalid expression of DIGlobalVariableExpression; expected *metadata.DIExpression, got %T", e)
+		}
+		md.Expr = expr
+	case *metadata.DIObjCProperty:
+		// [distinct, name, file, line, getter, setter, attributes, type]
+		if err := minOps(8); err != nil {
+			return err
+		}
+		md.Distinct = ops[0] != 0
+		if md.Name, err = r.mdString(ops[1]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.File, err = r.mdFile(ops[2]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.Line = int64(ops[3])
+		if md.Getter, err = r.mdString(ops[4]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Setter, err = r.mdString(ops[5]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.Attributes = ops[6]
+		if md.Type, err = r.mdField(ops[7]); err != nil {
+			return errors.WithStack(err)
+		}
+	case *metadata.DIImportedEntity:
+		// [distinct, tag, scope, entity, line, name, file, elements]
+		if err := minOps(6); err != nil {
+			return err
+		}
+		md.Distinct = ops[0] != 0
+		md.Tag = enum.DwarfTag(ops[1])
+		if md.Scope, err = r.mdFieldOrNull(ops[2]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Entity, err = r.mdField(ops[3]); err != nil {
+			return errors.WithStack(err)
+		}
+		md.Line = int64(ops[4])
+		if md.Name, err = r.mdString(ops[5]); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.File, err = r.mdFile(op(6)); err != nil {
+			return errors.WithStack(err)
+		}
+		if md.Elements, err = r.mdTuple(op(7)); err != nil {
+			return errors.WithStack(err)
+		}
+	case *metadata.DIArgList:
+		// [n x md num]
+		for _, id := range ops {
+			field, err := r.mdNode(id)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			v, ok := field.(value.Value)
+			if !ok {
+				return errors.Errorf("invalid DIArgList argument; expected value, got %T", field)
+			}
+			md.Fields = append(md.Fields, v)
+		}
+	default:
+		panic(errors.Errorf("support for metadata node %T not yet implemented", node))
+	}
+	return nil
+}
+
+// diExpressionFields returns the DIExpression fields of the given elements.
+// The elements are represented as DWARF operations with arguments if the
+// expression is valid, and as plain integers otherwise; as printed by LLVM.
+func diExpressionFields(elems []uint64) []metadata.DIExpressionField {
+	if len(elems) == 0 {
+		return nil
+	}
+	var fields []metadata.DIExpressionField
+	if !isValidDIExpression(elems) {
+		for _, elem := range elems {
+			fields = append(fields, metadata.UintLit(elem))
+		}
+		return fields
+	}
+	for i := 0; i < len(elems); {
+		op := enum.DwarfOp(elems[i])
+		fields = append(fields, op)
+		n := dwarfOpSize(op)
+		for j := 1; j < n; j++ {
+			if op == enum.DwarfOpLLVMConvert && j == 2 {
+				fields = append(fields, enum.DwarfAttEncoding(elems[i+j]))
+				continue
+			}
+			fields = append(fields, metadata.UintLit(elems[i+j]))
+		}
+		i += n
+	}
+	return fields
+}
+
+// dwarfOpSize returns the size of the given DWARF operation in a DIExpression,
+// including its arguments.
+func dwarfOpSize(op enum.DwarfOp) int {
+	switch op {
+	case enum.DwarfOpLLVMConvert, enum.DwarfOpLLVMFragment, enum.DwarfOpBregx:
+		return 3
+	case enum.DwarfOpConstu, enum.DwarfOpConsts, enum.DwarfOpDerefSize, enum.DwarfOpPlusUconst, enum.DwarfOpLLVMTagOffset, enum.DwarfOpLLVMEntryValue, enum.DwarfOpLLVMArg, enum.DwarfOpRegx:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// isValidDIExpression reports whether the given DIExpression elements form a
+// valid expression; mirroring DIExpression::isValid of LLVM.
+func isValidDIExpression(elems []uint64) bool {
+	for i := 0; i < len(elems); {
+		op := enum.DwarfOp(elems[i])
+		n := dwarfOpSize(op)
+		// Check that there is enough room for the operation arguments.
+		if i+n > len(elems) {
+			return false
+		}
+		next := i + n
+		switch op {
+		case enum.DwarfOpLLVMFragment:
+			// A fragment operator must appear at the end.
+			if next != len(elems) {
+				return false
+			}
+		case enum.DwarfOpStackValue:
+			// A stack value operator must appear at the end, or be followed by a
+			// fragment operator.
+			if next != len(elems) && enum.DwarfOp(elems[next]) != enum.DwarfOpLLVMFragment {
+				return false
+			}
+		case enum.DwarfOpLLVMEntryValue:
+			// An entry value operator must appear at the beginning, and the
+			// number of operations it covers must be one.
+			if i != 0 || elems[i+1] != 1 {
+				return false
+			}
+		case enum.DwarfOpLLVMImplicitPointer:
+			// An implicit pointer operator must appear at the beginning and be
+			// the only operator.
+			if i != 0 || len(elems) != 1 {
+				return false
+			}
+		case enum.DwarfOpLLVMConvert, enum.DwarfOpLLVMTagOffset, enum.DwarfOpLLVMArg,
+			enum.DwarfOpConstu, enum.DwarfOpPlusUconst, enum.DwarfOpPlus,
+			enum.DwarfOpMinus, enum.DwarfOpMul, enum.DwarfOpDiv, enum.DwarfOpMod,
+			enum.DwarfOpOr, enum.DwarfOpAnd, enum.DwarfOpXor, enum.DwarfOpShl,
+			enum.DwarfOpShr, enum.DwarfOpShra, enum.DwarfOpDeref,
+			enum.DwarfOpDerefSize, enum.DwarfOpXderef, enum.DwarfOpLit0,
+			enum.DwarfOpNot, enum.DwarfOpDup, enum.DwarfOpRegx, enum.DwarfOpBregx,
+			enum.DwarfOpPushObjectAddress, enum.DwarfOpOver, enum.DwarfOpConsts,
+			enum.DwarfOpSwap:
+			// valid.
+		default:
+			return false
+		}
+		i = next
+	}
+	return true
+}
+
+// ### [ Helper functions ] ####################################################
+
+// metadataStrings returns the strings of the given METADATA_STRINGS record.
+//
+//	[count, offset] blob([lengths][chars])
+func metadataStrings(record *bitstream.Record) ([]string, error) {
+	if len(record.Ops) < 2 {
+		return nil, errors.Errorf("invalid STRINGS record; expected 2 operands, got %d", len(record.Ops))
+	}
+	count, offset := record.Ops[0], record.Ops[1]
+	if offset > uint64(len(record.Blob)) {
+		return nil, errors.Errorf("invalid offset %d of STRINGS record; blob of size %d", offset, len(record.Blob))
+	}
+	lengths, err := bitstream.ReadVBRs(record.Blob[:offset], int(count), 6)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	chars := record.Blob[offset:]
+	ss := make([]string, 0, count)
+	for _, length := range lengths {
+		if length > uint64(len(chars)) {
+			return nil, errors.Errorf("invalid string length %d of STRINGS record; %d characters remaining", length, len(chars))
+		}
+		ss = append(ss, string(chars[:length]))
+		chars = chars[length:]
+	}
+	return ss, nil
+}
+
+// mdNode returns the metadata with the given 0-based metadata ID.
+func (r *reader) mdNode(id uint64) (metadata.Field, error) {
+	if id >= uint64(len(r.mds.entries)) {
+		return nil, errors.Errorf("invalid metadata ID %d; expected < %d", id, len(r.mds.entries))
+	}
+	return r.mds.entries[id], nil
+}
+
+// mdField returns the metadata with the given 1-based metadata ID; or nil if
+// zero.
+func (r *reader) mdField(id uint64) (metadata.Field, error) {
+	if id == 0 {
+		return nil, nil
+	}
+	return r.mdNode(id - 1)
+}
+
+// mdFieldOrNull returns the metadata with the given 1-based metadata ID; or
+// null if zero.
+func (r *reader) mdFieldOrNull(id uint64) (metadata.Field, error) {
+	if id == 0 {
+		return metadata.Null, nil
+	}
+	return r.mdNode(id - 1)
+}
+
+// mdFieldOrInt returns the metadata with the given 1-based metadata ID; or nil
+// if zero. Integer constants are returned as integer literals.
+func (r *reader) mdFieldOrInt(id uint64) (metadata.FieldOrInt, error) {
+	field, err := r.mdField(id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if c, ok := field.(*constant.Int); ok {
+		return metadata.IntLit(c.X.Int64()), nil
+	}
+	return field, nil
+}
+
+// mdString returns the metadata string with the given 1-based metadata ID; or
+// the empty string if zero.
+func (r *reader) mdString(id uint64) (string, error) {
+	field, err := r.mdField(id)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if field == nil {
+		return "", nil
+	}
+	s, ok := field.(*metadata.String)
+	if !ok {
+		return "", errors.Errorf("invalid metadata string with metadata ID %d; expected *metadata.String, got %T", id-1, field)
+	}
+	return s.Value, nil
+}
+
+// mdFile returns the DIFile with the given 1-based metadata ID; or nil if zero.
+func (r *reader) mdFile(id uint64) (*metadata.DIFile, error) {
+	field, err := r.mdField(id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if field == nil {
+		return nil, nil
+	}
+	file, ok := field.(*metadata.DIFile)
+	if !ok {
+		return nil, errors.Errorf("invalid DIFile with metadata ID %d; expected *metadata.DIFile, got %T", id-1, field)
+	}
+	return file, nil
+}
+
+// mdTuple returns the metadata tuple with the given 1-based metadata ID; or nil
+// if zero.
+func (r *reader) mdTuple(id uint64) (*metadata.Tuple, error) {
+	field, err := r.mdField(id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if field == nil {
+		return nil, nil
+	}
+	tuple, ok := field.(*metadata.Tuple)
+	if !ok {
+		return nil, errors.Errorf("invalid metadata tuple with metadata ID %d; expected *metadata.Tuple, got %T", id-1, field)
+	}
+	return tuple, nil
+}
+
+// isMetadataType reports whether the given type is the metadata type.
+func isMetadataType(t types.Type) bool {
+	_, ok := t.(*types.MetadataType)
+	return ok
+}
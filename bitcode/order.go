@@ -0,0 +1,246 @@
+// Order of metadata definitions, attribute group definitions and comdat
+// definitions.
+//
+// Bitcode does not record the IDs used when printing a module in LLVM IR
+// assembly syntax. To produce the same module as parsing the output of
+// llvm-dis, IDs are assigned in the order used by the slot tracker of the LLVM
+// assembly writer.
+
+package bitcode
+
+import (
+	"strings"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/metadata"
+)
+
+// orderMetadata assigns metadata IDs to the metadata nodes of the module and
+// adds them to the metadata definitions of the module, in order of metadata
+// ID.
+//
+// Metadata nodes not referenced by the module are dropped.
+func (r *reader) orderMetadata() {
+	o := &mdOrder{slots: make(map[metadata.Definition]bool)}
+	// Metadata attachments of global variables.
+	for _, g := range r.m.Globals {
+		o.addAttachments(g.Metadata)
+	}
+	// Metadata of named metadata definitions.
+	for _, def := range r.namedMDs {
+		for _, node := range def.Nodes {
+			o.add(node)
+		}
+	}
+	// Metadata of functions.
+	for _, f := range r.m.Funcs {
+		o.addAttachments(f.Metadata)
+		for _, block := range f.Blocks {
+			for _, inst := range block.Insts {
+				o.addInst(inst)
+			}
+			o.addInst(block.Term)
+		}
+	}
+	for i, def := range o.defs {
+		def.SetID(int64(i))
+	}
+	r.m.MetadataDefs = o.defs
+}
+
+// mdOrder keeps track of the order of metadata definitions.
+type mdOrder struct {
+	// Metadata definitions in order of metadata ID.
+	defs []metadata.Definition
+	// Metadata definitions already assigned a metadata ID.
+	slots map[metadata.Definition]bool
+}
+
+// addInst assigns metadata IDs to the metadata used by the given instruction;
+// metadata arguments of intrinsic function calls and metadata attachments.
+func (o *mdOrder) addInst(inst interface{}) {
+	if call, ok := inst.(*ir.InstCall); ok {
+		if callee, ok := call.Callee.(*ir.Func); ok && strings.HasPrefix(callee.Name(), "llvm.") {
+			for _, arg := range call.Args {
+				if a, ok := arg.(*ir.Arg); ok {
+					arg = a.Value
+				}
+				if md, ok := arg.(*metadata.Value); ok {
+					o.add(md.Value)
+				}
+			}
+		}
+	}
+	if inst, ok := inst.(mdAttacher); ok {
+		o.addAttachments(inst.MDAttachments())
+	}
+}
+
+// addAttachments assigns metadata IDs to the given metadata attachments.
+func (o *mdOrder) addAttachments(mds []*metadata.Attachment) {
+	for _, md := range mds {
+		o.add(md.Node)
+	}
+}
+
+// add assigns a metadata ID to the given metadata node (if not already
+// assigned), and recursively to the metadata nodes it refers to.
+func (o *mdOrder) add(node interface{}) {
+	def, ok := node.(metadata.Definition)
+	if !ok || isNil(def) {
+		return
+	}
+	switch def.(type) {
+	case *metadata.DIExpression:
+		// DIExpression is printed inline.
+		return
+	}
+	if o.slots[def] {
+		return
+	}
+	o.slots[def] = true
+	o.defs = append(o.defs, def)
+	for _, op := range mdOperands(def) {
+		o.add(op)
+	}
+}
+
+// mdOperands returns the metadata operands of the given metadata node, in the
+// order of operands used by LLVM.
+func mdOperands(def metadata.Definition) []metadata.Field {
+	switch def := def.(type) {
+	case *metadata.Tuple:
+		return def.Fields
+	case *metadata.DILocation:
+		return []metadata.Field{def.Scope, def.InlinedAt}
+	case *metadata.GenericDINode:
+		return def.Operands
+	case *metadata.DISubrange:
+		return []metadata.Field{def.Count, def.LowerBound, def.UpperBound, def.Stride}
+	case *metadata.DIStringType:
+		return []metadata.Field{def.StringLength, def.StringLengthExpression, def.StringLocationExpression}
+	case *metadata.DIDerivedType:
+		return []metadata.Field{def.File, def.Scope, def.BaseType, def.ExtraData, def.Annotations}
+	case *metadata.DICompositeType:
+		return []metadata.Field{def.File, def.Scope, def.BaseType, def.Elements, def.VtableHolder, def.TemplateParams, def.Discriminator, def.DataLocation, def.Associated, def.Allocated, def.Rank, def.Annotations}
+	case *metadata.DISubroutineType:
+		return []metadata.Field{def.Types}
+	case *metadata.DICompileUnit:
+		return []metadata.Field{def.File, def.Enums, def.RetainedTypes, def.Globals, def.Imports, def.Macros}
+	case *metadata.DISubprogram:
+		return []metadata.Field{def.File, def.Scope, def.Type, def.Unit, def.Declaration, def.RetainedNodes, def.ContainingType, def.TemplateParams, def.ThrownTypes, def.Annotations}
+	case *metadata.DILexicalBlock:
+		return []metadata.Field{def.File, def.Scope}
+	case *metadata.DILexicalBlockFile:
+		return []metadata.Field{def.File, def.Scope}
+	case *metadata.DICommonBlock:
+		return []metadata.Field{def.Scope, def.Declaration, def.File}
+	case *metadata.DINamespace:
+		return []metadata.Field{def.Scope}
+	case *metadata.DIMacroFile:
+		return []metadata.Field{def.File, def.Nodes}
+	case *metadata.DIModule:
+		return []metadata.Field{def.File, def.Scope}
+	case *metadata.DITemplateTypeParameter:
+		return []metadata.Field{def.Type}
+	case *metadata.DITemplateValueParameter:
+		return []metadata.Field{def.Type, def.Value}
+	case *metadata.DIGlobalVariable:
+		return []metadata.Field{def.Scope, def.File, def.Type, def.Declaration, def.TemplateParams, def.Annotations}
+	case *metadata.DILocalVariable:
+		return []metadata.Field{def.Scope, def.File, def.Type, def.Annotations}
+	case *metadata.DILabel:
+		return []metadata.Field{def.Scope, def.File}
+	case *metadata.DIGlobalVariableExpression:
+		return []metadata.Field{def.Var, def.Expr}
+	case *metadata.DIObjCProperty:
+		return []metadata.Field{def.File, def.Type}
+	case *metadata.DIImportedEntity:
+		return []metadata.Field{def.Scope, def.Entity, def.File, def.Elements}
+	default:
+		// DIBasicType, DIEnumerator, DIFile and DIMacro have no metadata node
+		// operands.
+		return nil
+	}
+}
+
+// orderAttrGroups assigns attribute group IDs to the attribute group
+// definitions of the module and adds them to the attribute group definitions
+// of the module, in order of attribute group ID.
+//
+// Attribute groups are numbered in order of use; first by global variables,
+// then by functions and then by call instructions of function bodies.
+func (r *reader) orderAttrGroups() {
+	var defs []*ir.AttrGroupDef
+	add := func(attrs []ir.FuncAttribute) {
+		for _, attr := range attrs {
+			if def, ok := attr.(*ir.AttrGroupDef); ok && def.ID == -1 {
+				def.ID = int64(len(defs))
+				defs = append(defs, def)
+			}
+		}
+	}
+	for _, g := range r.m.Globals {
+		add(g.FuncAttrs)
+	}
+	for _, f := range r.m.Funcs {
+		add(f.FuncAttrs)
+	}
+	for _, f := range r.m.Funcs {
+		for _, block := range f.Blocks {
+			for _, inst := range block.Insts {
+				if call, ok := inst.(*ir.InstCall); ok {
+					add(call.FuncAttrs)
+				}
+			}
+			switch term := block.Term.(type) {
+			case *ir.TermInvoke:
+				add(term.FuncAttrs)
+			case *ir.TermCallBr:
+				add(term.FuncAttrs)
+			}
+		}
+	}
+	r.m.AttrGroupDefs = defs
+}
+
+// orderComdats orders the comdat definitions of the module by first use;
+// first by functions and then by global variables.
+//
+// Comdat definitions not referenced by the module are dropped.
+func (r *reader) orderComdats() {
+	var defs []*ir.ComdatDef
+	seen := make(map[*ir.ComdatDef]bool)
+	add := func(def *ir.ComdatDef) {
+		if def != nil && !seen[def] {
+			seen[def] = true
+			defs = append(defs, def)
+		}
+	}
+	for _, f := range r.m.Funcs {
+		add(f.Comdat)
+	}
+	for _, g := range r.m.Globals {
+		add(g.Comdat)
+	}
+	r.m.ComdatDefs = defs
+}
+
+// ### [ Helper functions ] ####################################################
+
+// isNil reports whether the given metadata definition is a typed nil pointer.
+func isNil(def metadata.Definition) bool {
+	switch def := def.(type) {
+	case *metadata.DIFile:
+		return def == nil
+	case *metadata.Tuple:
+		return def == nil
+	case *metadata.DILocation:
+		return def == nil
+	case *metadata.DICompileUnit:
+		return def == nil
+	case *metadata.DIGlobalVariable:
+		return def == nil
+	}
+	return false
+}
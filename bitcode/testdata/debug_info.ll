@@ -0,0 +1,120 @@
+; ModuleID = 'debug_info.bc'
+source_filename = "debug_info.c"
+target datalayout = "e-m:e-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128"
+target triple = "x86_64-pc-linux-gnu"
+
+%struct.point = type { i32, i32 }
+%struct.opaque = type opaque
+
+$comdat_any = comdat any
+
+$comdat_largest = comdat largest
+
+@g = dso_local global i32 42, align 4, !dbg !0
+@h = internal thread_local(initialexec) global %struct.point zeroinitializer, section ".mydata", comdat($comdat_any), align 8
+@s = private unnamed_addr constant [6 x i8] c"hello\00", align 1
+@ext = external global %struct.opaque
+@c = linkonce_odr global i64 7, comdat($comdat_largest)
+@arr = global [2 x i32*] [i32* @g, i32* getelementptr inbounds (%struct.point, %struct.point* @h, i64 0, i32 1)]
+
+@a = alias i32, i32* @g
+
+; Function Attrs: noinline nounwind optnone uwtable
+define dso_local i32 @add(i32 %a, i32 %b) #0 !dbg !16 {
+entry:
+  %a.addr = alloca i32, align 4
+  %b.addr = alloca i32, align 4
+  store i32 %a, i32* %a.addr, align 4
+  call void @llvm.dbg.declare(metadata i32* %a.addr, metadata !20, metadata !DIExpression()), !dbg !21
+  store i32 %b, i32* %b.addr, align 4
+  call void @llvm.dbg.declare(metadata i32* %b.addr, metadata !22, metadata !DIExpression(DW_OP_plus_uconst, 4, DW_OP_deref)), !dbg !28
+  %0 = load i32, i32* %a.addr, align 4, !dbg !29
+  %1 = load i32, i32* %b.addr, align 4, !dbg !30, !tbaa !32
+  %add = add nsw i32 %0, %1, !dbg !36
+  %call = call i32 @ext_fn(i32 %add) #3, !dbg !37
+  ret i32 %call, !dbg !39
+}
+
+declare i32 @ext_fn(i32 noundef) #1
+
+define linkonce_odr void @in_comdat() comdat($comdat_any) {
+in_comdat.entry0:
+  %x = fadd fast float 1.000000e+00, 2.000000e+00
+  %y = atomicrmw add i32* @g, i32 1 seq_cst, align 4
+  %z = cmpxchg weak i32* @g, i32 1, i32 2 acq_rel monotonic, align 4
+  fence syncscope("singlethread") acquire
+  %l = load atomic i32, i32* @g unordered, align 4
+  store atomic volatile i32 3, i32* @g release, align 4
+  %p = getelementptr inbounds %struct.point, %struct.point* @h, i32 0, i32 1
+  %sw = load i32, i32* %p, align 4
+  switch i32 %sw, label %d [
+    i32 0, label %one
+    i32 1, label %two
+  ]
+
+one:                                              ; preds = %in_comdat.entry0
+  br label %d
+
+two:                                              ; preds = %in_comdat.entry, %in_comdat.entry0
+  %ph = phi float [ %x, %in_comdat.entry0 ], [ 2.000000e+00, %in_comdat.entry ]
+  br label %d
+
+d:                                                ; preds = %two, %one, %in_comdat.entry0
+  ret void
+
+in_comdat.entry:                                  ; No predecessors!
+  br label %two
+}
+
+; Function Attrs: nofree nosync nounwind readnone speculatable willreturn
+declare void @llvm.dbg.declare(metadata, metadata, metadata) #2
+
+attributes #0 = { noinline nounwind optnone uwtable "frame-pointer"="all" "target-cpu"="x86-64" }
+attributes #1 = { "no-trapping-math"="true" }
+attributes #2 = { nofree nosync nounwind readnone speculatable willreturn }
+attributes #3 = { nounwind readnone }
+
+!llvm.dbg.cu = !{!2}
+!llvm.module.flags = !{!12, !13, !14}
+!llvm.ident = !{!15}
+
+!0 = !DIGlobalVariableExpression(var: !1, expr: !DIExpression())
+!1 = distinct !DIGlobalVariable(name: "g", scope: !2, file: !3, line: 1, type: !6, isLocal: false, isDefinition: true)
+!2 = distinct !DICompileUnit(language: DW_LANG_C99, file: !3, producer: "clang version 14.0.0", isOptimized: false, runtimeVersion: 0, emissionKind: FullDebug, enums: !4, globals: !11, splitDebugInlining: false, nameTableKind: None)
+!3 = !DIFile(filename: "debug_info.c", directory: "/tmp", checksumkind: CSK_MD5, checksum: "0123456789abcdef0123456789abcdef")
+!4 = !{!5}
+!5 = !DICompositeType(tag: DW_TAG_enumeration_type, name: "color", file: !3, line: 1, baseType: !6, size: 32, elements: !7)
+!6 = !DIBasicType(name: "int", size: 32, encoding: DW_ATE_signed)
+!7 = !{!8, !9, !10}
+!8 = !DIEnumerator(name: "RED", value: 0)
+!9 = !DIEnumerator(name: "GREEN", value: -1)
+!10 = !DIEnumerator(name: "BIG", value: 18446744073709551615, isUnsigned: true)
+!11 = !{!0}
+!12 = !{i32 7, !"Dwarf Version", i32 5}
+!13 = !{i32 2, !"Debug Info Version", i32 3}
+!14 = !{i32 1, !"wchar_size", i32 4}
+!15 = !{!"clang version 14.0.0"}
+!16 = distinct !DISubprogram(name: "add", scope: !3, file: !3, line: 3, type: !17, scopeLine: 3, flags: DIFlagPrototyped, spFlags: DISPFlagDefinition, unit: !2, retainedNodes: !19)
+!17 = !DISubroutineType(types: !18)
+!18 = !{!6, !6, !6}
+!19 = !{}
+!20 = !DILocalVariable(name: "a", arg: 1, scope: !16, file: !3, line: 3, type: !6)
+!21 = !DILocation(line: 3, column: 13, scope: !16)
+!22 = !DILocalVariable(name: "b", arg: 2, scope: !16, file: !3, line: 3, type: !23)
+!23 = !DIDerivedType(tag: DW_TAG_typedef, name: "myint", file: !3, line: 2, baseType: !24)
+!24 = !DICompositeType(tag: DW_TAG_structure_type, name: "point", file: !3, line: 1, size: 64, elements: !25)
+!25 = !{!26, !27}
+!26 = !DIDerivedType(tag: DW_TAG_member, name: "x", scope: !24, file: !3, line: 1, baseType: !6, size: 32)
+!27 = !DIDerivedType(tag: DW_TAG_member, name: "y", scope: !24, file: !3, line: 1, baseType: !6, size: 32, offset: 32)
+!28 = !DILocation(line: 3, column: 20, scope: !16)
+!29 = !DILocation(line: 4, column: 10, scope: !16)
+!30 = !DILocation(line: 4, column: 14, scope: !31)
+!31 = distinct !DILexicalBlock(scope: !16, file: !3, line: 4, column: 5)
+!32 = !{!33, !33, i64 0}
+!33 = !{!"int", !34, i64 0}
+!34 = !{!"omnipotent char", !35, i64 0}
+!35 = !{!"Simple C/C++ TBAA"}
+!36 = !DILocation(line: 4, column: 12, scope: !16)
+!37 = !DILocation(line: 4, column: 3, scope: !16, inlinedAt: !38)
+!38 = distinct !DILocation(line: 9, column: 1, scope: !16)
+!39 = !DILocation(line: 4, column: 3, scope: !16)
@@ -0,0 +1,6 @@
+; ModuleID = 'diexpression.bc'
+source_filename = "diexpression.ll"
+
+!bar = !{!DIExpression(42)}
+!baz = !{!DIExpression(42, 3)}
+!foo = !{!DIExpression()}
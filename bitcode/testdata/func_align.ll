@@ -0,0 +1,18 @@
+; ModuleID = 'func_align.bc'
+source_filename = "func_align.ll"
+
+$g = comdat any
+
+$h = comdat any
+
+define void @f() align 2 {
+  ret void
+}
+
+define void @g() comdat align 2 {
+  ret void
+}
+
+define void @h() comdat align 2 {
+  ret void
+}
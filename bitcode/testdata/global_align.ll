@@ -0,0 +1,5 @@
+; ModuleID = 'global_align.bc'
+source_filename = "global_align.ll"
+
+@g = global i32 0, section "foo", align 1
+@h = global i32 0, section "foo", align 1
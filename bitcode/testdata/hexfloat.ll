@@ -0,0 +1,5 @@
+; ModuleID = 'hexfloat.bc'
+source_filename = "hexfloat.ll"
+
+@a = global half 0xH4400
+@b = global half 0xH2E66
@@ -0,0 +1,8 @@
+; ModuleID = 'inst_aggregate.bc'
+source_filename = "inst_aggregate.ll"
+
+define void @f() {
+  %1 = extractvalue { i8, { i32, i64 } } { i8 1, { i32, i64 } { i32 2, i64 3 } }, 1, 1
+  %2 = insertvalue { i8, { i32, i64 } } { i8 1, { i32, i64 } { i32 2, i64 3 } }, i64 4, 1, 1
+  ret void
+}
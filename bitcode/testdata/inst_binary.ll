@@ -0,0 +1,18 @@
+; ModuleID = 'inst_binary.bc'
+source_filename = "inst_binary.ll"
+
+define void @f() {
+  %1 = add i32 1, 2
+  %2 = fadd double 3.000000e+00, 4.000000e+00
+  %3 = sub i32 5, 6
+  %4 = fsub double 7.000000e+00, 8.000000e+00
+  %5 = mul i32 9, 10
+  %6 = fmul double 1.100000e+01, 1.200000e+01
+  %7 = udiv i32 13, 14
+  %8 = sdiv i32 15, 16
+  %9 = fdiv double 1.700000e+01, 1.800000e+01
+  %10 = urem i32 19, 20
+  %11 = srem i32 21, 22
+  %12 = frem double 2.300000e+01, 2.400000e+01
+  ret void
+}
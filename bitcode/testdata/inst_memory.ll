@@ -0,0 +1,15 @@
+; ModuleID = 'inst_memory.bc'
+source_filename = "inst_memory.ll"
+
+@s = constant [4 x i8] c"foo\00"
+
+define void @f() {
+  %ptr = alloca i32, align 4
+  %1 = load i32, i32* %ptr, align 4
+  store i32 42, i32* %ptr, align 4
+  fence acquire
+  %2 = cmpxchg i32* %ptr, i32 10, i32 20 acquire monotonic, align 4
+  %3 = atomicrmw add i32* %ptr, i32 30 acq_rel, align 4
+  %4 = getelementptr [4 x i8], [4 x i8]* @s, i64 0, i64 0
+  ret void
+}
@@ -0,0 +1,43 @@
+; ModuleID = 'inst_other.bc'
+source_filename = "inst_other.ll"
+
+define i32 @g() {
+  ret i32 42
+}
+
+define void @h(i32 %x) {
+  ret void
+}
+
+define void @f() {
+  %1 = icmp eq i32 1, 2
+  br i1 %1, label %foo, label %baz
+
+foo:                                              ; preds = %0
+  %2 = fcmp oeq double 3.000000e+00, 4.000000e+00
+  br i1 %2, label %bar, label %baz
+
+bar:                                              ; preds = %foo
+  br label %baz
+
+baz:                                              ; preds = %bar, %foo, %0
+  %3 = phi i32 [ 10, %foo ], [ 20, %bar ], [ 30, %baz ]
+  %4 = select i1 true, i32 11, i32 22
+  %5 = call i32 @g()
+  call void @h(i32 30)
+  %6 = va_arg i8* null, i32
+  %7 = landingpad { i8*, i32 }
+          catch i8** null
+  ret void
+
+handler0:                                         ; preds = %dispatch
+  %8 = catchpad within %cs [i8** null]
+  ret void
+
+handler1:                                         ; preds = %dispatch
+  %9 = cleanuppad within %cs [i8** null]
+  ret void
+
+dispatch:                                         ; No predecessors!
+  %cs = catchswitch within none [label %handler0, label %handler1] unwind to caller
+}
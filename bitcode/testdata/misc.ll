@@ -0,0 +1,138 @@
+; ModuleID = 'misc.bc'
+source_filename = "misc.c"
+
+module asm "foo:"
+module asm "  ret"
+
+%0 = type { i32, float }
+%T = type { i8, %T* }
+
+@x = global i32 1, partition "part"
+@ptrs = global [1 x i8*] [i8* blockaddress(@ind, %b2)]
+@u = global %0 { i32 1, float 2.500000e+00 }
+@t = global %T zeroinitializer
+@f16 = global half 0xH3C00
+@x86 = global x86_fp80 0xK4000C000000000000000
+@fq = global fp128 0xL00000000000000004000000000000000
+@nan = global double 0x7FF8000000000001
+@fnan = global float 0x7FF8000020000000
+@vec = global <4 x i32> <i32 1, i32 2, i32 3, i32 4>
+@str = global [3 x i8] c"ab\00"
+@ce = global i64 add (i64 ptrtoint (i32* @x to i64), i64 8)
+@ce2 = global i1 false
+@sel = global i32 2
+@undefv = global i32 undef
+@poisonv = global i32 poison
+@big = global i128 170141183460469231731687303715884105727
+
+@al = weak alias i32, i32* @x
+
+@ifn = ifunc void (), void ()* ()* @resolver
+
+define void ()* @resolver() {
+  ret void ()* null
+}
+
+define void @ind(i8* %addr) gc "statepoint-example" prefix i32 123 prologue i8 1 personality i32 (...)* @pers {
+  indirectbr i8* %addr, [label %b1, label %b2]
+
+b1:                                               ; preds = %0
+  ret void
+
+b2:                                               ; preds = %0
+  ret void
+}
+
+declare i32 @pers(...)
+
+declare void @may_throw(i32)
+
+define i32 @eh() personality i32 (...)* @pers {
+entry:
+  invoke void @may_throw(i32 1)
+          to label %ok unwind label %lp
+
+ok:                                               ; preds = %entry
+  %r = invoke i32 (...) @pers(i32 2, i8 3)
+          to label %ok2 unwind label %lp
+
+ok2:                                              ; preds = %ok
+  ret i32 %r
+
+lp:                                               ; preds = %ok, %entry
+  %l = landingpad { i8*, i32 }
+          cleanup
+          catch i8* null
+          filter [1 x i8*] zeroinitializer
+  resume { i8*, i32 } %l
+}
+
+define void @wineh() personality i32 (...)* @pers {
+entry:
+  invoke void @may_throw(i32 1)
+          to label %cont unwind label %cs
+
+cont:                                             ; preds = %h, %entry
+  ret void
+
+cs:                                               ; preds = %entry
+  %sw = catchswitch within none [label %h] unwind label %cleanup
+
+h:                                                ; preds = %cs
+  %cp = catchpad within %sw [i8* null, i32 64]
+  catchret from %cp to label %cont
+
+cleanup:                                          ; preds = %cs
+  %cl = cleanuppad within none []
+  cleanupret from %cl unwind to caller
+}
+
+define <4 x i32> @vecs(<4 x i32> %a, <4 x i32> %b, { i32, float } %agg) {
+  %s = shufflevector <4 x i32> %a, <4 x i32> %b, <4 x i32> <i32 0, i32 5, i32 undef, i32 7>
+  %e = extractelement <4 x i32> %s, i32 1
+  %i = insertelement <4 x i32> %s, i32 %e, i64 0
+  %ev = extractvalue { i32, float } %agg, 1
+  %iv = insertvalue { i32, float } %agg, float %ev, 1
+  %n = fneg nnan float %ev
+  %fr = freeze i32 %e
+  %c = fcmp fast olt float %n, %ev
+  %sel = select nsz i1 %c, float %n, float %ev
+  %va = va_arg i8** null, i32
+  %shl = shl nuw nsw i32 %fr, 1
+  %sd = sdiv exact i32 %shl, 2
+  %ls = lshr exact i32 %sd, 1
+  %rem = srem i32 %ls, 3
+  %fd = fdiv arcp float %sel, 2.000000e+00
+  %vbin = add <4 x i32> %i, <i32 1, i32 1, i32 1, i32 1>
+  ret <4 x i32> %vbin
+}
+
+define void @calls(i8* %p) {
+  call void asm sideeffect "nop", "~{dirflag}"()
+  %r = call i32 asm "mov $1, $0", "=r,r"(i32 1)
+  call void @may_throw(i32 1) [ "deopt"(i32 1, i64 2), "foo"(i8* %p) ]
+  tail call void @may_throw(i32 signext 2) #0
+  musttail call void @calls(i8* nonnull %p)
+  ret void
+}
+
+define fastcc i32 @cc(i32 %x) {
+  %a = alloca i32, i32 %x, align 16
+  %b = alloca inalloca i64, align 8
+  %c = alloca [4 x i8], align 1024
+  %y = notail call fastcc i32 @cc(i32 %x)
+  ret i32 %y
+}
+
+define void @cbr() {
+  callbr void asm "", "r,X"(i32 0, i8* blockaddress(@cbr, %other))
+          to label %normal [label %other]
+
+normal:                                           ; preds = %0
+  ret void
+
+other:                                            ; preds = %0
+  ret void
+}
+
+attributes #0 = { cold }
@@ -0,0 +1,4 @@
+; ModuleID = 'multiple_named_metadata_defs.bc'
+source_filename = "multiple_named_metadata_defs.ll"
+
+!foo = !{!DIExpression(1), !DIExpression(2)}
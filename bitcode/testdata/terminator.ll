@@ -0,0 +1,12 @@
+; ModuleID = 'terminator.bc'
+source_filename = "terminator.ll"
+
+define void @f(i8* %target) {
+  indirectbr i8* %target, [label %foo]
+
+foo:                                              ; preds = %0
+  br label %bar
+
+bar:                                              ; preds = %foo
+  ret void
+}
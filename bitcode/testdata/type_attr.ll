@@ -0,0 +1,20 @@
+; ModuleID = 'type_attr.bc'
+source_filename = "type_attr.ll"
+
+%struct.S = type { i32, i64 }
+
+declare void @by_value(%struct.S* byval(%struct.S) align 8)
+
+declare void @struct_ret(%struct.S* noalias sret(%struct.S) align 8)
+
+; Function Attrs: nofree nounwind willreturn
+declare i64 @llvm.aarch64.ldxr.p0i32(i32*) #0
+
+define void @f(%struct.S* %p, i32* %q) {
+  call void @by_value(%struct.S* byval(%struct.S) align 8 %p)
+  call void @struct_ret(%struct.S* sret(%struct.S) align 8 %p)
+  %1 = call i64 @llvm.aarch64.ldxr.p0i32(i32* elementtype(i32) %q)
+  ret void
+}
+
+attributes #0 = { nofree nounwind willreturn }
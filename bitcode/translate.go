@@ -0,0 +1,753 @@
+// Translation of the bitstream blocks of LLVM IR bitcode into an LLVM IR
+// module.
+//
+// The translation is done in a number of steps.
+//
+// 1. Locate the module block and the string table block.
+//
+// 2. Translate the module block.
+//
+//    a) Translate attribute groups and attribute lists.
+//
+//    b) Translate the type table.
+//
+//    c) Translate global variable, function, alias and IFunc records into
+//       IR values (without initializers, aliasees and bodies but with types),
+//       indexed by value ID.
+//
+//    d) Translate module-level constants and metadata.
+//
+//    e) Resolve initializers, aliasees, resolvers, prefix data, prologue data
+//       and personality functions.
+//
+// 3. Translate function bodies, in order of occurrence in the module.
+//
+// 4. Assign metadata IDs and attribute group IDs, and order comdats, in the
+//    order used by LLVM when printing the module in LLVM IR assembly syntax.
+
+package bitcode
+
+import (
+	"github.com/llir/llvm/internal/bitstream"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/metadata"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+	"github.com/pkg/errors"
+)
+
+// translate translates the given top-level bitstream blocks of an LLVM IR
+// bitcode file into an LLVM IR module.
+func translate(blocks []*bitstream.Block) (*ir.Module, error) {
+	// 1. Locate the module block and the string table block.
+	var moduleBlock *bitstream.Block
+	r := newReader()
+	for _, block := range blocks {
+		switch block.ID {
+		case blockIDModule:
+			if moduleBlock != nil {
+				return nil, errors.New("multiple modules in bitcode file not supported")
+			}
+			moduleBlock = block
+		case blockIDStrtab:
+			for _, record := range block.Records() {
+				if record.Code == strtabBlob {
+					r.strtab = record.Blob
+				}
+			}
+		}
+	}
+	if moduleBlock == nil {
+		return nil, errors.New("unable to locate module block")
+	}
+	// 2. Translate the module block.
+	if err := r.translateModule(moduleBlock); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// 3. Translate function bodies.
+	if err := r.translateFuncBodies(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// 4. Assign metadata IDs and attribute group IDs, and order comdats.
+	r.orderMetadata()
+	r.orderAttrGroups()
+	r.orderComdats()
+	return r.m, nil
+}
+
+// reader keeps track of the state of the translation of an LLVM IR bitcode
+// module into an LLVM IR module.
+type reader struct {
+	// Output LLVM IR module.
+	m *ir.Module
+
+	// Bitcode version of the module block.
+	version uint64
+	// String table.
+	strtab []byte
+
+	// Type table, indexed by type ID.
+	types []types.Type
+	// Type table records, indexed by type ID; used for lazy resolution of type
+	// references.
+	typeRecords []*typeRecord
+
+	// Attribute groups, indexed by group ID.
+	attrGroups map[uint64]*attrGroup
+	// Attribute lists (as referenced by functions and call instructions),
+	// indexed by attribute list ID - 1.
+	attrLists [][]*attrGroup
+	// Attribute group definitions of function attributes, indexed by the
+	// contents of the attribute group.
+	attrGroupDefs map[string]*ir.AttrGroupDef
+
+	// Module-level values, indexed by value ID.
+	values *valueList
+	// Global values in order of occurrence, with the record from which they
+	// were created.
+	globals []*globalRecord
+	// Functions with bodies, in order of occurrence.
+	funcs []*ir.Func
+	// Function blocks, in order of occurrence.
+	funcBlocks []*bitstream.Block
+	// Basic blocks of functions, indexed by basic block ID; created on demand
+	// as they may be referred to (e.g. by blockaddress constants) before the
+	// function body is translated.
+	blocks map[*ir.Func][]*ir.Block
+
+	// Section names, indexed by section ID - 1.
+	sections []string
+	// Garbage collector names, indexed by GC ID - 1.
+	gcNames []string
+	// Comdats, indexed by comdat ID - 1.
+	comdats []*ir.ComdatDef
+
+	// Metadata, indexed by metadata ID.
+	mds *mdList
+	// Number of module-level metadata.
+	numModuleMDs int
+	// Metadata kind names, indexed by metadata kind ID.
+	mdKinds map[uint64]string
+	// Unique debug locations.
+	locs map[locKey]*metadata.DILocation
+	// Named metadata definitions, in order of occurrence.
+	namedMDs []*metadata.NamedDef
+
+	// Operand bundle tags, indexed by tag ID.
+	bundleTags []string
+	// Synchronization scope names, indexed by sync scope ID.
+	syncScopes []string
+}
+
+// newReader returns a new bitcode reader.
+func newReader() *reader {
+	return &reader{
+		m:             ir.NewModule(),
+		attrGroups:    make(map[uint64]*attrGroup),
+		attrGroupDefs: make(map[string]*ir.AttrGroupDef),
+		blocks:        make(map[*ir.Func][]*ir.Block),
+		values:        &valueList{},
+		mds:           &mdList{},
+		mdKinds:       make(map[uint64]string),
+		locs:          make(map[locKey]*metadata.DILocation),
+	}
+}
+
+// globalRecord is a global value and the record from which it was created.
+type globalRecord struct {
+	// Global variable, function, alias or IFunc.
+	v value.Value
+	// Record operands (with the string table offset and size removed).
+	ops []uint64
+}
+
+// === [ Module block ] ========================================================
+
+// translateModule translates the given module block.
+func (r *reader) translateModule(block *bitstream.Block) error {
+	// Records and sub-blocks are translated in order of occurrence, as later
+	// entries refer to earlier ones (e.g. the type table is referenced by
+	// global variable records).
+	for _, entry := range block.Entries {
+		switch entry := entry.(type) {
+		case *bitstream.Block:
+			if err := r.translateModuleSubblock(entry); err != nil {
+				return errors.WithStack(err)
+			}
+		case *bitstream.Record:
+			if err := r.translateModuleRecord(entry); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	// Translate module-level constants not yet referenced.
+	if err := r.values.resolveAll(r); err != nil {
+		return errors.WithStack(err)
+	}
+	// Resolve initializers, aliasees, resolvers, prefix data, prologue data and
+	// personality functions.
+	for _, g := range r.globals {
+		if err := r.resolveGlobal(g); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// translateModuleSubblock translates the given sub-block of the module block.
+func (r *reader) translateModuleSubblock(block *bitstream.Block) error {
+	switch block.ID {
+	case bitstream.BlockInfoBlockID:
+		// Abbreviations already applied by the bitstream reader.
+		return nil
+	case blockIDParamAttrGroup:
+		return r.translateAttrGroups(block)
+	case blockIDParamAttr:
+		return r.translateAttrLists(block)
+	case blockIDTypeNew:
+		return r.translateTypeTable(block)
+	case blockIDConstants:
+		return r.translateConstants(block, r.values)
+	case blockIDMetadataKind:
+		return r.translateMetadataKinds(block)
+	case blockIDMetadata:
+		if err := r.translateMetadata(block, r.values); err != nil {
+			return errors.WithStack(err)
+		}
+		r.numModuleMDs = len(r.mds.entries)
+		return nil
+	case blockIDOperandBundleTags:
+		for _, record := range block.Records() {
+			if record.Code == operandBundleTagCode {
+				r.bundleTags = append(r.bundleTags, record.String(0))
+			}
+		}
+		return nil
+	case blockIDSyncScopeNames:
+		for _, record := range block.Records() {
+			if record.Code == syncScopeNameCode {
+				r.syncScopes = append(r.syncScopes, record.String(0))
+			}
+		}
+		return nil
+	case blockIDFunction:
+		r.funcBlocks = append(r.funcBlocks, block)
+		return nil
+	case blockIDValueSymtab, blockIDUseList, blockIDIdentification, blockIDSymtab, blockIDGlobalValSummary, blockIDFullLTOGlobalValSum:
+		// Names are stored in the string table (bitcode version 2), function
+		// offsets are not needed as all blocks are read, and use-list orders
+		// and summaries are not represented in the IR.
+		return nil
+	default:
+		dbg.Printf("ignoring unknown module sub-block with ID %d", block.ID)
+		return nil
+	}
+}
+
+// translateModuleRecord translates the given record of the module block.
+func (r *reader) translateModuleRecord(record *bitstream.Record) error {
+	switch record.Code {
+	case moduleCodeVersion:
+		if len(record.Ops) < 1 {
+			return errors.New("invalid VERSION record; missing version")
+		}
+		r.version = record.Ops[0]
+		if r.version < 2 {
+			return errors.Errorf("support for bitcode version %d not yet implemented; only version 2 (with string table) supported", r.version)
+		}
+	case moduleCodeTriple:
+		r.m.TargetTriple = record.String(0)
+	case moduleCodeDataLayout:
+		r.m.DataLayout = record.String(0)
+	case moduleCodeAsm:
+		r.m.ModuleAsms = splitModuleAsm(record.String(0))
+	case moduleCodeSectionName:
+		r.sections = append(r.sections, record.String(0))
+	case moduleCodeGCName:
+		r.gcNames = append(r.gcNames, record.String(0))
+	case moduleCodeSourceFilename:
+		r.m.SourceFilename = record.String(0)
+	case moduleCodeComdat:
+		return r.translateComdat(record)
+	case moduleCodeGlobalVar:
+		return r.translateGlobalVar(record)
+	case moduleCodeFunction:
+		return r.translateFunc(record)
+	case moduleCodeAlias:
+		return r.translateAlias(record)
+	case moduleCodeIFunc:
+		return r.translateIFunc(record)
+	case moduleCodeAliasOld:
+		return errors.New("support for old-style alias records not yet implemented")
+	case moduleCodeVSTOffset, moduleCodeHash, moduleCodeDepLib, moduleCodeMetadataValues:
+		// ignore.
+	default:
+		dbg.Printf("ignoring unknown module record with code %d", record.Code)
+	}
+	return nil
+}
+
+// --- [ Comdat definitions ] --------------------------------------------------
+
+// translateComdat translates the given comdat record.
+//
+//	[strtab_offset, strtab_size, selection_kind]
+func (r *reader) translateComdat(record *bitstream.Record) error {
+	name, ops, err := r.nameFromStrtab(record)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(ops) < 1 {
+		return errors.New("invalid COMDAT record; missing selection kind")
+	}
+	kind, err := irSelectionKind(ops[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	def := &ir.ComdatDef{Name: name, Kind: kind}
+	r.comdats = append(r.comdats, def)
+	r.m.ComdatDefs = append(r.m.ComdatDefs, def)
+	return nil
+}
+
+// --- [ Global variables ] ----------------------------------------------------
+
+// translateGlobalVar translates the given global variable record.
+//
+//	[strtab_offset, strtab_size, pointer type, isconst|explicitType<<1|addrspace<<2,
+//	 initid, linkage, alignment, section, visibility, threadlocal,
+//	 unnamed_addr, externally_initialized, dllstorageclass, comdat,
+//	 attributes, preemption, partition strtab offset, partition strtab size]
+func (r *reader) translateGlobalVar(record *bitstream.Record) error {
+	name, ops, err := r.nameFromStrtab(record)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(ops) < 6 {
+		return errors.Errorf("invalid GLOBALVAR record; expected at least 6 operands, got %d", len(ops))
+	}
+	g := &ir.Global{}
+	g.SetName(name)
+	contentType, err := r.typeOf(ops[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var addrSpace types.AddrSpace
+	if ops[1]&0x2 != 0 {
+		// Explicit content type.
+		addrSpace = types.AddrSpace(ops[1] >> 2)
+	} else {
+		// Pointer type of global variable.
+		ptr, ok := contentType.(*types.PointerType)
+		if !ok || ptr.ElemType == nil {
+			return errors.Errorf("invalid type of global variable %q; expected typed pointer type, got %v", name, contentType)
+		}
+		contentType, addrSpace = ptr.ElemType, ptr.AddrSpace
+	}
+	g.ContentType = contentType
+	g.Immutable = ops[1]&0x1 != 0
+	g.AddrSpace = addrSpace
+	g.Typ = r.pointerTo(contentType, addrSpace)
+	isDecl := ops[2] == 0
+	linkage, err := irLinkage(ops[3])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	g.Linkage = linkage
+	if linkage == enum.LinkageNone && isDecl {
+		// Global variable declarations of external linkage are printed as
+		// "external global".
+		g.Linkage = enum.LinkageExternal
+	}
+	g.Align = irAlign(ops[4])
+	if g.Section, err = r.section(ops[5]); err != nil {
+		return errors.WithStack(err)
+	}
+	if len(ops) > 6 && !isLocalLinkage(linkage) {
+		if g.Visibility, err = irVisibility(ops[6]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if len(ops) > 7 {
+		if g.TLSModel, err = irTLSModel(ops[7]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if len(ops) > 8 {
+		if g.UnnamedAddr, err = irUnnamedAddr(ops[8]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if len(ops) > 9 {
+		g.ExternallyInitialized = ops[9] != 0
+	}
+	if len(ops) > 10 {
+		if g.DLLStorageClass, err = irDLLStorageClass(ops[10]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if len(ops) > 11 {
+		if g.Comdat, err = r.comdat(ops[11]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if len(ops) > 12 && ops[12] != 0 {
+		attrs, err := r.attrList(ops[12])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if def := r.fnAttrGroupDef(attrs); def != nil {
+			g.FuncAttrs = append(g.FuncAttrs, def)
+		}
+	}
+	if len(ops) > 13 && ops[13] != 0 && !isImplicitDSOLocal(linkage, g.Visibility) {
+		g.Preemption = enum.PreemptionDSOLocal
+	}
+	if len(ops) > 15 {
+		if g.Partition, err = r.strtabString(ops[14], ops[15]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	r.addGlobal(g, ops)
+	r.m.Globals = append(r.m.Globals, g)
+	return nil
+}
+
+// --- [ Functions ] -----------------------------------------------------------
+
+// translateFunc translates the given function record.
+//
+//	[strtab_offset, strtab_size, type, callingconv, isproto, linkage,
+//	 paramattrs, alignment, section, visibility, gc, unnamed_addr,
+//	 prologuedata, dllstorageclass, comdat, prefixdata, personalityfn,
+//	 preemption, addrspace, partition strtab offset, partition strtab size]
+func (r *reader) translateFunc(record *bitstream.Record) error {
+	name, ops, err := r.nameFromStrtab(record)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(ops) < 8 {
+		return errors.Errorf("invalid FUNCTION record; expected at least 8 operands, got %d", len(ops))
+	}
+	t, err := r.typeOf(ops[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	sig, ok := t.(*types.FuncType)
+	if !ok {
+		if ptr, ok := t.(*types.PointerType); ok {
+			sig, _ = ptr.ElemType.(*types.FuncType)
+		}
+		if sig == nil {
+			return errors.Errorf("invalid type of function %q; expected function type, got %v", name, t)
+		}
+	}
+	f := &ir.Func{Sig: sig, Parent: r.m}
+	f.SetName(name)
+	if f.CallingConv, err = irCallingConv(ops[1]); err != nil {
+		return errors.WithStack(err)
+	}
+	isProto := ops[2] != 0
+	if f.Linkage, err = irLinkage(ops[3]); err != nil {
+		return errors.WithStack(err)
+	}
+	// Function parameters.
+	for _, paramType := range sig.Params {
+		param := ir.NewParam("", paramType)
+		f.Params = append(f.Params, param)
+	}
+	// Attributes.
+	if ops[4] != 0 {
+		attrs, err := r.attrList(ops[4])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if err := r.applyFuncAttrs(f, attrs); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	f.Align = irAlign(ops[5])
+	if f.Section, err = r.section(ops[6]); err != nil {
+		return errors.WithStack(err)
+	}
+	if !isLocalLinkage(f.Linkage) {
+		if f.Visibility, err = irVisibility(ops[7]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if len(ops) > 8 && ops[8] != 0 {
+		if ops[8] > uint64(len(r.gcNames)) {
+			return errors.Errorf("invalid garbage collector ID %d; expected < %d", ops[8], len(r.gcNames)+1)
+		}
+		f.GC = r.gcNames[ops[8]-1]
+	}
+	if len(ops) > 9 {
+		if f.UnnamedAddr, err = irUnnamedAddr(ops[9]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if len(ops) > 11 {
+		if f.DLLStorageClass, err = irDLLStorageClass(ops[11]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if len(ops) > 12 {
+		if f.Comdat, err = r.comdat(ops[12]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if len(ops) > 15 && ops[15] != 0 && !isImplicitDSOLocal(f.Linkage, f.Visibility) {
+		f.Preemption = enum.PreemptionDSOLocal
+	}
+	if len(ops) > 16 {
+		f.AddrSpace = types.AddrSpace(ops[16])
+	}
+	if len(ops) > 18 {
+		if f.Partition, err = r.strtabString(ops[17], ops[18]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	f.Typ = r.pointerTo(sig, f.AddrSpace)
+	if !isProto {
+		// Function definition; body is translated from the corresponding
+		// function block.
+		r.funcs = append(r.funcs, f)
+	}
+	r.addGlobal(f, ops)
+	r.m.Funcs = append(r.m.Funcs, f)
+	return nil
+}
+
+// --- [ Aliases and IFuncs ] --------------------------------------------------
+
+// translateAlias translates the given alias record.
+//
+//	[strtab_offset, strtab_size, alias value type, addrspace, aliasee val#,
+//	 linkage, visibility, dllstorageclass, threadlocal, unnamed_addr,
+//	 preemption, partition strtab offset, partition strtab size]
+func (r *reader) translateAlias(record *bitstream.Record) error {
+	name, ops, err := r.nameFromStrtab(record)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(ops) < 4 {
+		return errors.Errorf("invalid ALIAS record; expected at least 4 operands, got %d", len(ops))
+	}
+	contentType, err := r.typeOf(ops[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	a := &ir.Alias{ContentType: contentType}
+	a.SetName(name)
+	a.Typ = r.pointerTo(contentType, types.AddrSpace(ops[1]))
+	if a.Linkage, err = irLinkage(ops[3]); err != nil {
+		return errors.WithStack(err)
+	}
+	if len(ops) > 4 && !isLocalLinkage(a.Linkage) {
+		if a.Visibility, err = irVisibility(ops[4]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if len(ops) > 5 {
+		if a.DLLStorageClass, err = irDLLStorageClass(ops[5]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if len(ops) > 6 {
+		if a.TLSModel, err = irTLSModel(ops[6]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if len(ops) > 7 {
+		if a.UnnamedAddr, err = irUnnamedAddr(ops[7]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if len(ops) > 8 && ops[8] != 0 && !isImplicitDSOLocal(a.Linkage, a.Visibility) {
+		a.Preemption = enum.PreemptionDSOLocal
+	}
+	if len(ops) > 10 {
+		if a.Partition, err = r.strtabString(ops[9], ops[10]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	r.addGlobal(a, ops)
+	r.m.Aliases = append(r.m.Aliases, a)
+	return nil
+}
+
+// translateIFunc translates the given IFunc record.
+//
+//	[strtab_offset, strtab_size, ifunc value type, addrspace, resolver val#,
+//	 linkage, visibility, preemption, partition strtab offset,
+//	 partition strtab size]
+func (r *reader) translateIFunc(record *bitstream.Record) error {
+	name, ops, err := r.nameFromStrtab(record)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(ops) < 4 {
+		return errors.Errorf("invalid IFUNC record; expected at least 4 operands, got %d", len(ops))
+	}
+	contentType, err := r.typeOf(ops[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	i := &ir.IFunc{ContentType: contentType}
+	i.SetName(name)
+	i.Typ = r.pointerTo(contentType, types.AddrSpace(ops[1]))
+	if i.Linkage, err = irLinkage(ops[3]); err != nil {
+		return errors.WithStack(err)
+	}
+	if len(ops) > 4 && !isLocalLinkage(i.Linkage) {
+		if i.Visibility, err = irVisibility(ops[4]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if len(ops) > 5 && ops[5] != 0 && !isImplicitDSOLocal(i.Linkage, i.Visibility) {
+		i.Preemption = enum.PreemptionDSOLocal
+	}
+	if len(ops) > 7 {
+		if i.Partition, err = r.strtabString(ops[6], ops[7]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	r.addGlobal(i, ops)
+	r.m.IFuncs = append(r.m.IFuncs, i)
+	return nil
+}
+
+// --- [ Global value resolution ] ---------------------------------------------
+
+// addGlobal adds the given global value to the module-level value list.
+func (r *reader) addGlobal(v value.Value, ops []uint64) {
+	r.values.add(v)
+	r.globals = append(r.globals, &globalRecord{v: v, ops: ops})
+}
+
+// resolveGlobal resolves the initializer, aliasee, resolver, prefix data,
+// prologue data and personality function of the given global value.
+func (r *reader) resolveGlobal(g *globalRecord) error {
+	switch v := g.v.(type) {
+	case *ir.Global:
+		if initID := g.ops[2]; initID != 0 {
+			init, err := r.constant(initID - 1)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			v.Init = init
+		}
+	case *ir.Func:
+		// Prologue data.
+		if len(g.ops) > 10 && g.ops[10] != 0 {
+			c, err := r.constant(g.ops[10] - 1)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			v.Prologue = c
+		}
+		// Prefix data.
+		if len(g.ops) > 13 && g.ops[13] != 0 {
+			c, err := r.constant(g.ops[13] - 1)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			v.Prefix = c
+		}
+		// Personality function.
+		if len(g.ops) > 14 && g.ops[14] != 0 {
+			c, err := r.constant(g.ops[14] - 1)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			v.Personality = c
+		}
+	case *ir.Alias:
+		c, err := r.constant(g.ops[2])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		v.Aliasee = c
+	case *ir.IFunc:
+		c, err := r.constant(g.ops[2])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		v.Resolver = c
+	}
+	return nil
+}
+
+// constant returns the module-level constant with the given value ID.
+func (r *reader) constant(id uint64) (constant.Constant, error) {
+	v, err := r.values.get(r, id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	c, ok := v.(constant.Constant)
+	if !ok {
+		return nil, errors.Errorf("invalid constant with value ID %d; expected constant.Constant, got %T", id, v)
+	}
+	return c, nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// nameFromStrtab returns the name stored in the string table at the offset and
+// size specified by the first two operands of the given record, and the
+// remaining operands of the record.
+func (r *reader) nameFromStrtab(record *bitstream.Record) (string, []uint64, error) {
+	if len(record.Ops) < 2 {
+		return "", nil, errors.Errorf("invalid record with code %d; missing string table offset and size", record.Code)
+	}
+	name, err := r.strtabString(record.Ops[0], record.Ops[1])
+	if err != nil {
+		return "", nil, errors.WithStack(err)
+	}
+	return name, record.Ops[2:], nil
+}
+
+// strtabString returns the string of the given size stored at the given offset
+// of the string table.
+func (r *reader) strtabString(offset, size uint64) (string, error) {
+	if offset+size > uint64(len(r.strtab)) {
+		return "", errors.Errorf("invalid string table reference (offset %d, size %d); string table of size %d", offset, size, len(r.strtab))
+	}
+	return string(r.strtab[offset : offset+size]), nil
+}
+
+// section returns the section name of the given 1-based section ID; or the
+// empty string if zero.
+func (r *reader) section(id uint64) (string, error) {
+	if id == 0 {
+		return "", nil
+	}
+	if id > uint64(len(r.sections)) {
+		return "", errors.Errorf("invalid section ID %d; expected < %d", id, len(r.sections)+1)
+	}
+	return r.sections[id-1], nil
+}
+
+// comdat returns the comdat of the given 1-based comdat ID; or nil if zero.
+func (r *reader) comdat(id uint64) (*ir.ComdatDef, error) {
+	if id == 0 {
+		return nil, nil
+	}
+	if id > uint64(len(r.comdats)) {
+		return nil, errors.Errorf("invalid comdat ID %d; expected < %d", id, len(r.comdats)+1)
+	}
+	return r.comdats[id-1], nil
+}
+
+// pointerTo returns a pointer type in the given address space with the given
+// element type; or an opaque pointer type if the module uses opaque pointers.
+func (r *reader) pointerTo(elemType types.Type, addrSpace types.AddrSpace) *types.PointerType {
+	if r.m.OpaquePointers {
+		return types.NewOpaquePointer(addrSpace)
+	}
+	t := types.NewPointer(elemType)
+	t.AddrSpace = addrSpace
+	return t
+}
@@ -0,0 +1,235 @@
+package bitcode
+
+import (
+	"strconv"
+
+	"github.com/llir/llvm/internal/bitstream"
+	"github.com/llir/llvm/internal/natsort"
+	"github.com/llir/llvm/ir/types"
+	"github.com/pkg/errors"
+)
+
+// typeRecord is a record of the type table, with the name of the type if
+// identified.
+type typeRecord struct {
+	*bitstream.Record
+	// Name of identified struct type; empty if not identified.
+	name string
+}
+
+// translateTypeTable translates the given type table block.
+//
+// Type records may refer to types defined later in the table (e.g. the element
+// type of a pointer to a struct type), so translation is done in two steps.
+// First, identified struct types are created, so they may be referred to by
+// other types. Second, the remaining types are translated on demand.
+func (r *reader) translateTypeTable(block *bitstream.Block) error {
+	// Collect type records; associating names with identified struct types.
+	var name string
+	hasName := false
+	nunnamed := 0
+	for _, record := range block.Records() {
+		switch record.Code {
+		case typeCodeNumEntry:
+			// ignore.
+		case typeCodeStructName:
+			name, hasName = record.String(0), true
+		default:
+			t := &typeRecord{Record: record}
+			if record.Code == typeCodeStructNamed || record.Code == typeCodeOpaque {
+				if hasName {
+					t.name = name
+				} else {
+					// Unnamed identified struct types are given numeric names in
+					// order of occurrence.
+					t.name = strconv.Itoa(nunnamed)
+					nunnamed++
+				}
+			}
+			name, hasName = "", false
+			r.typeRecords = append(r.typeRecords, t)
+		}
+	}
+	r.types = make([]types.Type, len(r.typeRecords))
+	// Create identified struct types.
+	var typeNames []string
+	typeDefs := make(map[string]types.Type)
+	for i, t := range r.typeRecords {
+		if len(t.name) == 0 {
+			continue
+		}
+		typ := &types.StructType{TypeName: t.name}
+		if t.Code == typeCodeOpaque {
+			typ.Opaque = true
+		}
+		r.types[i] = typ
+		typeNames = append(typeNames, t.name)
+		typeDefs[t.name] = typ
+	}
+	// Translate remaining types.
+	for i, t := range r.typeRecords {
+		if t.Code == typeCodeOpaquePointer {
+			r.m.OpaquePointers = true
+		}
+		if _, err := r.typeOf(uint64(i)); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	// Translate bodies of identified struct types.
+	for i, t := range r.typeRecords {
+		if t.Code != typeCodeStructNamed {
+			continue
+		}
+		typ := r.types[i].(*types.StructType)
+		if err := r.translateStructBody(typ, t.Record); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	// Add type definitions to the module in natural sorting order.
+	natsort.Strings(typeNames)
+	for _, name := range typeNames {
+		r.m.TypeDefs = append(r.m.TypeDefs, typeDefs[name])
+	}
+	return nil
+}
+
+// typeOf returns the type of the given type ID.
+func (r *reader) typeOf(id uint64) (types.Type, error) {
+	if id >= uint64(len(r.types)) {
+		return nil, errors.Errorf("invalid type ID %d; expected < %d", id, len(r.types))
+	}
+	if t := r.types[id]; t != nil {
+		return t, nil
+	}
+	t, err := r.translateType(r.typeRecords[id].Record)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	r.types[id] = t
+	return t, nil
+}
+
+// translateType translates the given type record (not an identified struct
+// type).
+func (r *reader) translateType(record *bitstream.Record) (types.Type, error) {
+	ops := record.Ops
+	switch record.Code {
+	case typeCodeVoid:
+		return types.Void, nil
+	case typeCodeHalf:
+		return &types.FloatType{Kind: types.FloatKindHalf}, nil
+	case typeCodeFloat:
+		return &types.FloatType{Kind: types.FloatKindFloat}, nil
+	case typeCodeDouble:
+		return &types.FloatType{Kind: types.FloatKindDouble}, nil
+	case typeCodeX86FP80:
+		return &types.FloatType{Kind: types.FloatKindX86_FP80}, nil
+	case typeCodeFP128:
+		return &types.FloatType{Kind: types.FloatKindFP128}, nil
+	case typeCodePPCFP128:
+		return &types.FloatType{Kind: types.FloatKindPPC_FP128}, nil
+	case typeCodeLabel:
+		return types.Label, nil
+	case typeCodeMetadata:
+		return types.Metadata, nil
+	case typeCodeX86MMX:
+		return types.MMX, nil
+	case typeCodeToken:
+		return types.Token, nil
+	case typeCodeInteger:
+		if len(ops) < 1 {
+			return nil, errors.New("invalid INTEGER type record; missing width")
+		}
+		return types.NewInt(ops[0]), nil
+	case typeCodePointer:
+		if len(ops) < 1 {
+			return nil, errors.New("invalid POINTER type record; missing element type")
+		}
+		elemType, err := r.typeOf(ops[0])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		t := types.NewPointer(elemType)
+		if len(ops) > 1 {
+			t.AddrSpace = types.AddrSpace(ops[1])
+		}
+		return t, nil
+	case typeCodeOpaquePointer:
+		var addrSpace types.AddrSpace
+		if len(ops) > 0 {
+			addrSpace = types.AddrSpace(ops[0])
+		}
+		return types.NewOpaquePointer(addrSpace), nil
+	case typeCodeFunction, typeCodeFunctionOld:
+		// FUNCTION: [vararg, retty, paramty x N]
+		// FUNCTION (old): [vararg, attrid, retty, paramty x N]
+		start := 1
+		if record.Code == typeCodeFunctionOld {
+			start = 2
+		}
+		if len(ops) < start+1 {
+			return nil, errors.New("invalid FUNCTION type record; missing return type")
+		}
+		retType, err := r.typeOf(ops[start])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		var params []types.Type
+		for _, op := range ops[start+1:] {
+			param, err := r.typeOf(op)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			params = append(params, param)
+		}
+		t := types.NewFunc(retType, params...)
+		t.Variadic = ops[0] != 0
+		return t, nil
+	case typeCodeArray, typeCodeVector:
+		// ARRAY: [numelts, eltty]
+		// VECTOR: [numelts, eltty, scalable]
+		if len(ops) < 2 {
+			return nil, errors.Errorf("invalid type record with code %d; expected at least 2 operands, got %d", record.Code, len(ops))
+		}
+		elemType, err := r.typeOf(ops[1])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if record.Code == typeCodeArray {
+			return types.NewArray(ops[0], elemType), nil
+		}
+		t := types.NewVector(ops[0], elemType)
+		t.Scalable = len(ops) > 2 && ops[2] != 0
+		return t, nil
+	case typeCodeStructAnon:
+		t := &types.StructType{}
+		if err := r.translateStructBody(t, record); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return t, nil
+	case typeCodeBFloat:
+		return nil, errors.New("support for bfloat type not yet implemented")
+	case typeCodeX86AMX:
+		return nil, errors.New("support for x86_amx type not yet implemented")
+	default:
+		return nil, errors.Errorf("support for type record with code %d not yet implemented", record.Code)
+	}
+}
+
+// translateStructBody translates the body of the given struct type record.
+//
+//	[ispacked, eltty x N]
+func (r *reader) translateStructBody(t *types.StructType, record *bitstream.Record) error {
+	if len(record.Ops) < 1 {
+		return errors.New("invalid STRUCT type record; missing packed flag")
+	}
+	t.Packed = record.Ops[0] != 0
+	for _, op := range record.Ops[1:] {
+		field, err := r.typeOf(op)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		t.Fields = append(t.Fields, field)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package bitcode
+
+import (
+	"fmt"
+
+	"github.com/llir/llvm/internal/bitstream"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+	"github.com/pkg/errors"
+)
+
+// valueList is a list of values indexed by value ID.
+//
+// Constants are translated on demand, as constant records may refer to
+// constants defined later in the same constants block. Values of function
+// bodies may be referred to before they are defined (e.g. by phi
+// instructions), in which case a forward reference placeholder is used until
+// the value is defined.
+type valueList struct {
+	// Values indexed by value ID; nil if not yet translated.
+	values []value.Value
+	// Pending constant records, indexed by value ID.
+	pending map[uint64]*constRecord
+	// Forward references, indexed by value ID.
+	fwds map[uint64]*fwdRef
+}
+
+// constRecord is a constant record with its type, pending translation.
+type constRecord struct {
+	// Type of the constant.
+	typ types.Type
+	// Constant record.
+	record *bitstream.Record
+	// Value list in which the constant is defined.
+	list *valueList
+	// Translation in progress; used to detect cycles.
+	inProgress bool
+}
+
+// newFuncValueList returns a new value list of a function body, based on the
+// given module-level value list.
+func newFuncValueList(moduleValues *valueList) *valueList {
+	values := make([]value.Value, len(moduleValues.values))
+	copy(values, moduleValues.values)
+	return &valueList{values: values}
+}
+
+// add appends the given value to the value list, assigning it the next value
+// ID.
+func (l *valueList) add(v value.Value) {
+	l.values = append(l.values, v)
+}
+
+// addPending appends the given constant record, pending translation, to the
+// value list, assigning it the next value ID.
+func (l *valueList) addPending(typ types.Type, record *bitstream.Record) {
+	if l.pending == nil {
+		l.pending = make(map[uint64]*constRecord)
+	}
+	id := uint64(len(l.values))
+	l.pending[id] = &constRecord{typ: typ, record: record, list: l}
+	l.values = append(l.values, nil)
+}
+
+// nextID returns the next value ID of the value list.
+func (l *valueList) nextID() uint64 {
+	return uint64(len(l.values))
+}
+
+// get returns the value with the given value ID, translating pending constants
+// on demand.
+func (l *valueList) get(r *reader, id uint64) (value.Value, error) {
+	if id < uint64(len(l.values)) {
+		if v := l.values[id]; v != nil {
+			return v, nil
+		}
+	}
+	c, ok := l.pending[id]
+	if !ok {
+		return nil, errors.Errorf("unable to locate value with value ID %d", id)
+	}
+	if c.inProgress {
+		return nil, errors.Errorf("invalid cyclic reference of constant with value ID %d", id)
+	}
+	c.inProgress = true
+	v, err := r.translateConst(c.list, c.typ, c.record)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	delete(l.pending, id)
+	l.values[id] = v
+	return v, nil
+}
+
+// getFwd returns the value with the given value ID, translating pending
+// constants on demand. A forward reference placeholder of the given type is
+// returned for values not yet defined.
+func (l *valueList) getFwd(r *reader, id uint64, typ types.Type) (value.Value, error) {
+	if id < uint64(len(l.values)) && l.values[id] != nil {
+		return l.values[id], nil
+	}
+	if _, ok := l.pending[id]; ok {
+		return l.get(r, id)
+	}
+	if fwd, ok := l.fwds[id]; ok {
+		return fwd, nil
+	}
+	if typ == nil {
+		return nil, errors.Errorf("invalid forward reference to value with value ID %d; missing type", id)
+	}
+	if l.fwds == nil {
+		l.fwds = make(map[uint64]*fwdRef)
+	}
+	fwd := &fwdRef{id: id, typ: typ}
+	l.fwds[id] = fwd
+	return fwd, nil
+}
+
+// resolveAll translates all pending constants of the value list.
+func (l *valueList) resolveAll(r *reader) error {
+	for id := range l.values {
+		if _, err := l.get(r, uint64(id)); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// fwdRef is a forward reference placeholder of a value not yet defined.
+type fwdRef struct {
+	// Value ID of the forward reference.
+	id uint64
+	// Type of the value.
+	typ types.Type
+}
+
+// String returns the LLVM syntax representation of the value as a type-value
+// pair.
+func (fwd *fwdRef) String() string {
+	return fmt.Sprintf("%s %s", fwd.Type(), fwd.Ident())
+}
+
+// Type returns the type of the value.
+func (fwd *fwdRef) Type() types.Type {
+	return fwd.typ
+}
+
+// Ident returns the identifier associated with the value.
+func (fwd *fwdRef) Ident() string {
+	return fmt.Sprintf("<forward reference to value ID %d>", fwd.id)
+}
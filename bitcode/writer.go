@@ -0,0 +1,812 @@
+// Translation of an LLVM IR module into the bitstream blocks of LLVM IR
+// bitcode.
+//
+// The module is written in one or more passes. Types, attribute lists, values,
+// metadata and other entities are assigned IDs on first use, and as such an
+// entity may be referenced after the block defining it has been written (e.g.
+// a constant only used by metadata of a function body). Entities discovered
+// during a pass are written by the next pass, and passes are repeated until no
+// new entities are discovered; at which point the output of the last pass is
+// complete.
+//
+// 1. Write the identification block.
+//
+// 2. Write the module block.
+//
+//    a) Write the type table, attribute groups and attribute lists.
+//
+//    b) Write module-level records (target triple, data layout, module-level
+//       inline assembly, section names, garbage collector names, comdats and
+//       source filename).
+//
+//    c) Write global variable, function, alias and IFunc records.
+//
+//    d) Write module-level constants and metadata.
+//
+//    e) Write function bodies, in order of occurrence in the module.
+//
+// 3. Write the string table block.
+
+package bitcode
+
+import (
+	"strings"
+
+	"github.com/llir/llvm/internal/bitstream"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/metadata"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+	"github.com/pkg/errors"
+)
+
+// encode encodes the given LLVM IR module into LLVM IR bitcode.
+func encode(m *ir.Module) ([]byte, error) {
+	// Materialize function bodies if lazily parsed.
+	if err := m.Materialize(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	w := newWriter(m)
+	for {
+		n := w.numEntities()
+		buf, err := w.writePass()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if w.numEntities() == n {
+			return buf, nil
+		}
+		// Metadata strings are stored before other metadata.
+		w.sortMetadata()
+	}
+}
+
+// writer keeps track of the state of the translation of an LLVM IR module into
+// LLVM IR bitcode.
+type writer struct {
+	// Input LLVM IR module.
+	m *ir.Module
+	// Output bitstream of the current pass.
+	bs *bitstream.Writer
+	// String table of the current pass.
+	strtab []byte
+
+	// Type table, indexed by type ID.
+	types []types.Type
+	// Type IDs, indexed by type key; see typeKey.
+	typeIDs map[string]uint64
+	// Identified struct types currently being added to the type table.
+	typeVisiting map[string]bool
+
+	// Attribute groups, indexed by group ID - 1; each group is stored as its
+	// attribute index followed by the encoded attributes.
+	attrGroups [][]uint64
+	// Attribute group IDs, indexed by the contents of the attribute group.
+	attrGroupIDs map[string]uint64
+	// Attribute lists, indexed by attribute list ID - 1.
+	attrLists [][]uint64
+	// Attribute list IDs, indexed by the contents of the attribute list.
+	attrListIDs map[string]uint64
+
+	// Module-level values, indexed by value ID; starting with global values,
+	// followed by module-level constants.
+	values []value.Value
+	// Module-level value IDs.
+	valueIDs map[value.Value]uint64
+	// Number of global values.
+	numGlobals int
+	// Integer constant 1 of type i32; used as number of elements of alloca
+	// instructions without explicit number of elements.
+	one *constant.Int
+
+	// Module-level metadata, indexed by metadata ID; each entry is an
+	// mdString, a metadata node or a value.
+	mds []interface{}
+	// Module-level metadata IDs.
+	mdIDs map[interface{}]uint64
+	// Integer constants of integer literals in metadata.
+	intLits map[metadata.IntLit]*constant.Int
+	// Metadata kind names, indexed by metadata kind ID.
+	mdKinds *nameList
+	// Operand bundle tags, indexed by tag ID.
+	bundleTags *nameList
+	// Synchronization scope names, indexed by sync scope ID.
+	syncScopes *nameList
+
+	// Section names, indexed by section ID - 1.
+	sections *nameList
+	// Garbage collector names, indexed by GC ID - 1.
+	gcNames *nameList
+	// Comdats, indexed by comdat ID - 1.
+	comdats []*ir.ComdatDef
+	// Comdat IDs (1-based).
+	comdatIDs map[*ir.ComdatDef]uint64
+
+	// Function being written; or nil if writing module-level records.
+	fw *funcWriter
+}
+
+// newWriter returns a new writer for the given LLVM IR module.
+func newWriter(m *ir.Module) *writer {
+	w := &writer{
+		m:            m,
+		typeIDs:      make(map[string]uint64),
+		typeVisiting: make(map[string]bool),
+		attrGroupIDs: make(map[string]uint64),
+		attrListIDs:  make(map[string]uint64),
+		valueIDs:     make(map[value.Value]uint64),
+		one:          constant.NewInt(types.I32, 1),
+		mdIDs:        make(map[interface{}]uint64),
+		intLits:      make(map[metadata.IntLit]*constant.Int),
+		mdKinds:      newNameList(fixedMDKinds...),
+		bundleTags:   newNameList(fixedBundleTags...),
+		syncScopes:   newNameList("singlethread", ""),
+		sections:     newNameList(),
+		gcNames:      newNameList(),
+		comdatIDs:    make(map[*ir.ComdatDef]uint64),
+	}
+	// Global values are assigned value IDs in order of occurrence of their
+	// records.
+	for _, g := range m.Globals {
+		w.addValue(g)
+	}
+	for _, f := range m.Funcs {
+		w.addValue(f)
+	}
+	for _, alias := range m.Aliases {
+		w.addValue(alias)
+	}
+	for _, ifunc := range m.IFuncs {
+		w.addValue(ifunc)
+	}
+	w.numGlobals = len(w.values)
+	// Identified struct types are stored in the type table, even if not used.
+	for _, t := range m.TypeDefs {
+		w.typeID(t)
+	}
+	if m.OpaquePointers {
+		w.typeID(types.NewOpaquePointer(0))
+	}
+	for _, def := range m.ComdatDefs {
+		w.comdatID(def)
+	}
+	return w
+}
+
+// numEntities returns the number of entities assigned IDs by the writer.
+func (w *writer) numEntities() [10]int {
+	return [...]int{
+		len(w.types),
+		len(w.attrGroups),
+		len(w.attrLists),
+		len(w.values),
+		len(w.mds),
+		len(w.mdKinds.names),
+		len(w.bundleTags.names),
+		len(w.syncScopes.names),
+		len(w.sections.names) + len(w.gcNames.names),
+		len(w.comdats),
+	}
+}
+
+// writePass writes the module, returning the contents of the bitcode file.
+func (w *writer) writePass() ([]byte, error) {
+	w.bs = bitstream.NewWriter()
+	w.strtab = w.strtab[:0]
+	// 1. Write the identification block.
+	w.writeIdentification()
+	// 2. Write the module block.
+	if err := w.writeModule(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// 3. Write the string table block.
+	if err := w.writeStrtab(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	buf := append([]byte(nil), magic...)
+	return append(buf, w.bs.Bytes()...), nil
+}
+
+// writeIdentification writes the identification block.
+//
+//	STRING: [strchr x N]
+//	EPOCH: [epoch]
+func (w *writer) writeIdentification() {
+	w.bs.EnterBlock(blockIDIdentification, 5)
+	w.writeRecord(identCodeString, stringOps("llir/llvm")...)
+	w.writeRecord(identCodeEpoch, 0)
+	// Note, the identification block has no sub-blocks, and as such exiting it
+	// never fails.
+	_ = w.bs.ExitBlock()
+}
+
+// writeModule writes the module block.
+func (w *writer) writeModule() error {
+	w.bs.EnterBlock(blockIDModule, 3)
+	// Version 2 stores names of global values in the string table.
+	w.writeRecord(moduleCodeVersion, 2)
+	// a) Write the type table, attribute groups and attribute lists.
+	if err := w.writeTypeTable(); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := w.writeAttrGroups(); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := w.writeAttrLists(); err != nil {
+		return errors.WithStack(err)
+	}
+	// b) Write module-level records.
+	if err := w.writeModuleInfo(); err != nil {
+		return errors.WithStack(err)
+	}
+	// c) Write global variable, function, alias and IFunc records.
+	if err := w.writeGlobalValues(); err != nil {
+		return errors.WithStack(err)
+	}
+	// d) Write module-level constants and metadata.
+	if err := w.writeConstants(w.values[w.numGlobals:]); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := w.writeMetadataKinds(); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := w.writeModuleMetadata(); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := w.writeNames(blockIDOperandBundleTags, operandBundleTagCode, w.bundleTags); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := w.writeNames(blockIDSyncScopeNames, syncScopeNameCode, w.syncScopes); err != nil {
+		return errors.WithStack(err)
+	}
+	// e) Write function bodies.
+	for _, f := range w.m.Funcs {
+		if len(f.Blocks) == 0 {
+			continue
+		}
+		if err := w.writeFunc(f); err != nil {
+			return errors.Wrapf(err, "unable to write body of function %q", f.Ident())
+		}
+	}
+	return w.bs.ExitBlock()
+}
+
+// writeModuleInfo writes the module-level records of the module, preceding the
+// global value records.
+//
+//	TRIPLE: [strchr x N]
+//	DATALAYOUT: [strchr x N]
+//	ASM: [strchr x N]
+//	SECTIONNAME: [strchr x N]
+//	GCNAME: [strchr x N]
+//	COMDAT: [strtab_offset, strtab_size, selection_kind]
+//	SOURCE_FILENAME: [namechar x N]
+func (w *writer) writeModuleInfo() error {
+	if len(w.m.TargetTriple) > 0 {
+		w.writeRecord(moduleCodeTriple, stringOps(w.m.TargetTriple)...)
+	}
+	if len(w.m.DataLayout) > 0 {
+		w.writeRecord(moduleCodeDataLayout, stringOps(w.m.DataLayout)...)
+	}
+	if len(w.m.ModuleAsms) > 0 {
+		asm := strings.Join(w.m.ModuleAsms, "\n")
+		w.writeRecord(moduleCodeAsm, stringOps(asm)...)
+	}
+	for _, name := range w.sections.names {
+		w.writeRecord(moduleCodeSectionName, stringOps(name)...)
+	}
+	for _, name := range w.gcNames.names {
+		w.writeRecord(moduleCodeGCName, stringOps(name)...)
+	}
+	for _, def := range w.comdats {
+		kind, err := bcSelectionKind(def.Kind)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		offset, size := w.strtabString(def.Name)
+		w.writeRecord(moduleCodeComdat, offset, size, kind)
+	}
+	if len(w.m.SourceFilename) > 0 {
+		w.writeRecord(moduleCodeSourceFilename, stringOps(w.m.SourceFilename)...)
+	}
+	return nil
+}
+
+// writeNames writes a block of the given block ID with a record of the given
+// code for each name of the given list.
+func (w *writer) writeNames(blockID, code uint64, list *nameList) error {
+	w.bs.EnterBlock(blockID, 3)
+	for _, name := range list.names {
+		w.writeRecord(code, stringOps(name)...)
+	}
+	return w.bs.ExitBlock()
+}
+
+// writeStrtab writes the string table block.
+//
+//	BLOB: [blob]
+func (w *writer) writeStrtab() error {
+	w.bs.EnterBlock(blockIDStrtab, 3)
+	abbrev := &bitstream.Abbrev{Ops: []bitstream.AbbrevOp{
+		{Kind: bitstream.OpLiteral, Value: strtabBlob},
+		{Kind: bitstream.OpBlob},
+	}}
+	abbrevID, err := w.bs.DefineAbbrev(abbrev)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	record := &bitstream.Record{Code: strtabBlob, Blob: w.strtab}
+	if err := w.bs.WriteAbbrevRecord(abbrevID, record); err != nil {
+		return errors.WithStack(err)
+	}
+	return w.bs.ExitBlock()
+}
+
+// strtabString adds the given string to the string table, returning its
+// offset and size.
+func (w *writer) strtabString(s string) (offset, size uint64) {
+	if len(s) == 0 {
+		return 0, 0
+	}
+	offset = uint64(len(w.strtab))
+	w.strtab = append(w.strtab, s...)
+	return offset, uint64(len(s))
+}
+
+// === [ Global values ] =======================================================
+
+// writeGlobalValues writes the global variable, function, alias and IFunc
+// records of the module.
+func (w *writer) writeGlobalValues() error {
+	for _, g := range w.m.Globals {
+		if err := w.writeGlobalVar(g); err != nil {
+			return errors.Wrapf(err, "unable to write global variable %q", g.Ident())
+		}
+	}
+	for _, f := range w.m.Funcs {
+		if err := w.writeFuncRecord(f); err != nil {
+			return errors.Wrapf(err, "unable to write function %q", f.Ident())
+		}
+	}
+	for _, a := range w.m.Aliases {
+		if err := w.writeAlias(a); err != nil {
+			return errors.Wrapf(err, "unable to write alias %q", a.Ident())
+		}
+	}
+	for _, i := range w.m.IFuncs {
+		if err := w.writeIFunc(i); err != nil {
+			return errors.Wrapf(err, "unable to write IFunc %q", i.Ident())
+		}
+	}
+	return nil
+}
+
+// --- [ Global variables ] ----------------------------------------------------
+
+// writeGlobalVar writes the given global variable record.
+//
+//	[strtab_offset, strtab_size, pointer type, isconst|explicitType<<1|addrspace<<2,
+//	 initid, linkage, alignment, section, visibility, threadlocal,
+//	 unnamed_addr, externally_initialized, dllstorageclass, comdat,
+//	 attributes, preemption, partition strtab offset, partition strtab size]
+func (w *writer) writeGlobalVar(g *ir.Global) error {
+	r := w.newRecord(moduleCodeGlobalVar)
+	r.uint(w.strtabString(g.Name()))
+	r.typ(g.ContentType)
+	r.uint(boolOp(g.Immutable) | 0x2 | uint64(g.AddrSpace)<<2)
+	if g.Init != nil {
+		id, err := w.valueID(g.Init)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		r.uint(id + 1)
+	} else {
+		r.uint(0)
+	}
+	linkage, err := bcLinkage(g.Linkage)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r.uint(linkage, bcAlign(g.Align), w.sectionID(g.Section))
+	visibility, err := bcVisibility(g.Visibility)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	tlsModel, err := bcTLSModel(g.TLSModel)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	unnamedAddr, err := bcUnnamedAddr(g.UnnamedAddr)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	dllStorageClass, err := bcDLLStorageClass(g.DLLStorageClass)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	attrs, err := w.attrList(g.FuncAttrs, nil, nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r.uint(visibility, tlsModel, unnamedAddr, boolOp(g.ExternallyInitialized), dllStorageClass, w.comdatID(g.Comdat), attrs)
+	r.uint(bcDSOLocal(g.Preemption, g.Linkage, g.Visibility))
+	r.uint(w.strtabString(g.Partition))
+	return r.write()
+}
+
+// --- [ Functions ] -----------------------------------------------------------
+
+// writeFuncRecord writes the given function record.
+//
+//	[strtab_offset, strtab_size, type, callingconv, isproto, linkage,
+//	 paramattrs, alignment, section, visibility, gc, unnamed_addr,
+//	 prologuedata, dllstorageclass, comdat, prefixdata, personalityfn,
+//	 preemption, addrspace, partition strtab offset, partition strtab size]
+func (w *writer) writeFuncRecord(f *ir.Func) error {
+	r := w.newRecord(moduleCodeFunction)
+	r.uint(w.strtabString(f.Name()))
+	r.typ(f.Sig)
+	linkage, err := bcLinkage(f.Linkage)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r.uint(bcCallingConv(f.CallingConv), boolOp(len(f.Blocks) == 0), linkage)
+	var paramAttrs [][]ir.ParamAttribute
+	for _, param := range f.Params {
+		paramAttrs = append(paramAttrs, param.Attrs)
+	}
+	attrs, err := w.attrList(f.FuncAttrs, f.ReturnAttrs, paramAttrs)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	visibility, err := bcVisibility(f.Visibility)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r.uint(attrs, bcAlign(f.Align), w.sectionID(f.Section), visibility)
+	if len(f.GC) > 0 {
+		r.uint(w.gcNames.id(f.GC) + 1)
+	} else {
+		r.uint(0)
+	}
+	unnamedAddr, err := bcUnnamedAddr(f.UnnamedAddr)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r.uint(unnamedAddr)
+	r.optConst(f.Prologue)
+	dllStorageClass, err := bcDLLStorageClass(f.DLLStorageClass)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r.uint(dllStorageClass, w.comdatID(f.Comdat))
+	r.optConst(f.Prefix)
+	r.optConst(f.Personality)
+	r.uint(bcDSOLocal(f.Preemption, f.Linkage, f.Visibility), uint64(f.AddrSpace))
+	r.uint(w.strtabString(f.Partition))
+	return r.write()
+}
+
+// --- [ Aliases and IFuncs ] --------------------------------------------------
+
+// writeAlias writes the given alias record.
+//
+//	[strtab_offset, strtab_size, alias value type, addrspace, aliasee val#,
+//	 linkage, visibility, dllstorageclass, threadlocal, unnamed_addr,
+//	 preemption, partition strtab offset, partition strtab size]
+func (w *writer) writeAlias(a *ir.Alias) error {
+	r := w.newRecord(moduleCodeAlias)
+	r.uint(w.strtabString(a.Name()))
+	typ := a.Type().(*types.PointerType)
+	r.typ(indirectSymbolContentType(a.ContentType, typ))
+	r.uint(uint64(typ.AddrSpace))
+	r.absValue(a.Aliasee)
+	linkage, err := bcLinkage(a.Linkage)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	visibility, err := bcVisibility(a.Visibility)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	dllStorageClass, err := bcDLLStorageClass(a.DLLStorageClass)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	tlsModel, err := bcTLSModel(a.TLSModel)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	unnamedAddr, err := bcUnnamedAddr(a.UnnamedAddr)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r.uint(linkage, visibility, dllStorageClass, tlsModel, unnamedAddr)
+	r.uint(bcDSOLocal(a.Preemption, a.Linkage, a.Visibility))
+	r.uint(w.strtabString(a.Partition))
+	return r.write()
+}
+
+// writeIFunc writes the given IFunc record.
+//
+//	[strtab_offset, strtab_size, ifunc value type, addrspace, resolver val#,
+//	 linkage, visibility, preemption, partition strtab offset,
+//	 partition strtab size]
+func (w *writer) writeIFunc(i *ir.IFunc) error {
+	r := w.newRecord(moduleCodeIFunc)
+	r.uint(w.strtabString(i.Name()))
+	typ := i.Type().(*types.PointerType)
+	r.typ(indirectSymbolContentType(i.ContentType, typ))
+	r.uint(uint64(typ.AddrSpace))
+	r.absValue(i.Resolver)
+	linkage, err := bcLinkage(i.Linkage)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	visibility, err := bcVisibility(i.Visibility)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r.uint(linkage, visibility)
+	r.uint(bcDSOLocal(i.Preemption, i.Linkage, i.Visibility))
+	r.uint(w.strtabString(i.Partition))
+	return r.write()
+}
+
+// sectionID returns the 1-based section ID of the given section name; or 0 if
+// empty.
+func (w *writer) sectionID(name string) uint64 {
+	if len(name) == 0 {
+		return 0
+	}
+	return w.sections.id(name) + 1
+}
+
+// comdatID returns the 1-based comdat ID of the given comdat; or 0 if nil.
+func (w *writer) comdatID(def *ir.ComdatDef) uint64 {
+	if def == nil {
+		return 0
+	}
+	if id, ok := w.comdatIDs[def]; ok {
+		return id
+	}
+	w.comdats = append(w.comdats, def)
+	id := uint64(len(w.comdats))
+	w.comdatIDs[def] = id
+	return id
+}
+
+// --- [ Records ] -------------------------------------------------------------
+
+// writeRecord writes an unabbreviated record with the given code and operands.
+func (w *writer) writeRecord(code uint64, ops ...uint64) {
+	w.bs.WriteRecord(&bitstream.Record{Code: code, Ops: ops})
+}
+
+// record is a record under construction. Errors encountered while appending
+// operands are recorded, and the first error is reported when the record is
+// written.
+type record struct {
+	w *writer
+	// Record code.
+	code uint64
+	// Record operands.
+	ops []uint64
+	// First error encountered while appending operands.
+	err error
+}
+
+// newRecord returns a new record with the given code.
+func (w *writer) newRecord(code uint64) *record {
+	return &record{w: w, code: code}
+}
+
+// write writes the record; or returns the first error encountered while
+// appending operands.
+func (r *record) write() error {
+	if r.err != nil {
+		return r.err
+	}
+	r.w.writeRecord(r.code, r.ops...)
+	return nil
+}
+
+// fail records the given error, unless an error has already been recorded.
+func (r *record) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// uint appends the given unsigned integer operands.
+func (r *record) uint(vs ...uint64) {
+	r.ops = append(r.ops, vs...)
+}
+
+// bool appends the given boolean operand.
+func (r *record) bool(v bool) {
+	r.ops = append(r.ops, boolOp(v))
+}
+
+// typ appends the type ID of the given type.
+func (r *record) typ(t types.Type) {
+	r.ops = append(r.ops, r.w.typeID(t))
+}
+
+// absValue appends the absolute value ID of the given value.
+func (r *record) absValue(v value.Value) {
+	id, err := r.w.valueID(v)
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	r.ops = append(r.ops, id)
+}
+
+// optConst appends the value ID + 1 of the given constant; or 0 if nil.
+func (r *record) optConst(c constant.Constant) {
+	if c == nil {
+		r.uint(0)
+		return
+	}
+	id, err := r.w.valueID(c)
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	r.uint(id + 1)
+}
+
+// --- [ Value IDs ] -----------------------------------------------------------
+
+// addValue assigns the next module-level value ID to the given value.
+func (w *writer) addValue(v value.Value) uint64 {
+	id := uint64(len(w.values))
+	w.values = append(w.values, v)
+	w.valueIDs[v] = id
+	return id
+}
+
+// valueID returns the absolute value ID of the given value. Module-level
+// constants are assigned value IDs on first use.
+func (w *writer) valueID(v value.Value) (uint64, error) {
+	v = unwrapValue(v)
+	if w.fw != nil {
+		if id, ok := w.fw.ids[v]; ok {
+			return id, nil
+		}
+	}
+	if id, ok := w.valueIDs[v]; ok {
+		return id, nil
+	}
+	if c, ok := v.(constant.Constant); ok && w.fw == nil {
+		return w.addConst(c), nil
+	}
+	return 0, errors.Errorf("unable to locate value ID of %q (%T)", v.Ident(), v)
+}
+
+// --- [ Name lists ] ----------------------------------------------------------
+
+// nameList is a list of names, indexed by ID.
+type nameList struct {
+	// Names, indexed by ID.
+	names []string
+	// IDs, indexed by name.
+	ids map[string]uint64
+}
+
+// newNameList returns a new list of names, initialized with the given names.
+func newNameList(names ...string) *nameList {
+	list := &nameList{ids: make(map[string]uint64)}
+	for _, name := range names {
+		list.id(name)
+	}
+	return list
+}
+
+// id returns the ID of the given name, adding it to the list if not present.
+func (list *nameList) id(name string) uint64 {
+	if id, ok := list.ids[name]; ok {
+		return id
+	}
+	id := uint64(len(list.names))
+	list.names = append(list.names, name)
+	list.ids[name] = id
+	return id
+}
+
+// fixedMDKinds specifies the metadata kinds with fixed metadata kind IDs, as
+// predefined by LLVM.
+//
+// ref: include/llvm/IR/FixedMetadataKinds.def
+var fixedMDKinds = []string{
+	"dbg",
+	"tbaa",
+	"prof",
+	"fpmath",
+	"range",
+	"tbaa.struct",
+	"invariant.load",
+	"alias.scope",
+	"noalias",
+	"nontemporal",
+	"llvm.mem.parallel_loop_access",
+	"nonnull",
+	"dereferenceable",
+	"dereferenceable_or_null",
+	"make.implicit",
+	"unpredictable",
+	"invariant.group",
+	"align",
+	"llvm.loop",
+	"type",
+	"section_prefix",
+	"absolute_symbol",
+	"associated",
+	"callees",
+	"irr_loop",
+	"llvm.access.group",
+	"callback",
+	"llvm.preserve.access.index",
+	"vcall_visibility",
+	"noundef",
+	"annotation",
+}
+
+// fixedBundleTags specifies the operand bundle tags with fixed tag IDs, as
+// predefined by LLVM.
+//
+// ref: include/llvm/IR/LLVMContext.h (enum LLVMContext::OperandBundle)
+var fixedBundleTags = []string{
+	"deopt",
+	"funclet",
+	"gc-transition",
+	"cfguardtarget",
+	"preallocated",
+	"gc-live",
+	"clang.arc.attachedcall",
+}
+
+// ### [ Helper functions ] ####################################################
+
+// stringOps returns the characters of the given string as record operands.
+func stringOps(s string) []uint64 {
+	ops := make([]uint64, len(s))
+	for i := 0; i < len(s); i++ {
+		ops[i] = uint64(s[i])
+	}
+	return ops
+}
+
+// boolOp returns the record operand of the given boolean.
+func boolOp(v bool) uint64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// indirectSymbolContentType returns the content type of an alias or IFunc with
+// the given content type and pointer type.
+func indirectSymbolContentType(contentType types.Type, typ *types.PointerType) types.Type {
+	if contentType != nil {
+		return contentType
+	}
+	return typ.ElemType
+}
+
+// unwrapValue returns the underlying value of the given function argument or
+// getelementptr index.
+func unwrapValue(v value.Value) value.Value {
+	for {
+		switch x := v.(type) {
+		case *ir.Arg:
+			v = x.Value
+		case *constant.Index:
+			v = x.Constant
+		default:
+			return v
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package bitcode
+
+import (
+	"fmt"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/pkg/errors"
+)
+
+// attrList returns the 1-based attribute list ID of the given function, return
+// and parameter attributes (indexed by parameter index); or 0 if no attributes
+// are present.
+func (w *writer) attrList(funcAttrs []ir.FuncAttribute, retAttrs []ir.ReturnAttribute, paramAttrs [][]ir.ParamAttribute) (uint64, error) {
+	var list []uint64
+	add := func(idx uint64, attrs []interface{}) error {
+		if len(attrs) == 0 {
+			return nil
+		}
+		group := []uint64{idx}
+		for _, attr := range attrs {
+			ops, err := w.encodeAttr(attr)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			group = append(group, ops...)
+		}
+		list = append(list, w.attrGroupID(group))
+		return nil
+	}
+	var fnAttrs []interface{}
+	for _, attr := range funcAttrs {
+		// Attributes of attribute group definitions are stored inline.
+		if def, ok := attr.(*ir.AttrGroupDef); ok {
+			for _, attr := range def.FuncAttrs {
+				fnAttrs = append(fnAttrs, attr)
+			}
+			continue
+		}
+		fnAttrs = append(fnAttrs, attr)
+	}
+	if err := add(attrIndexFunc, fnAttrs); err != nil {
+		return 0, errors.WithStack(err)
+	}
+	var rAttrs []interface{}
+	for _, attr := range retAttrs {
+		rAttrs = append(rAttrs, attr)
+	}
+	if err := add(attrIndexReturn, rAttrs); err != nil {
+		return 0, errors.WithStack(err)
+	}
+	for i, attrs := range paramAttrs {
+		var pAttrs []interface{}
+		for _, attr := range attrs {
+			pAttrs = append(pAttrs, attr)
+		}
+		if err := add(uint64(i+1), pAttrs); err != nil {
+			return 0, errors.WithStack(err)
+		}
+	}
+	if len(list) == 0 {
+		return 0, nil
+	}
+	key := fmt.Sprint(list)
+	if id, ok := w.attrListIDs[key]; ok {
+		return id, nil
+	}
+	w.attrLists = append(w.attrLists, list)
+	id := uint64(len(w.attrLists))
+	w.attrListIDs[key] = id
+	return id, nil
+}
+
+// attrGroupID returns the attribute group ID of the given attribute group,
+// stored as its attribute index followed by the encoded attributes.
+func (w *writer) attrGroupID(group []uint64) uint64 {
+	key := fmt.Sprint(group)
+	if id, ok := w.attrGroupIDs[key]; ok {
+		return id
+	}
+	w.attrGroups = append(w.attrGroups, group)
+	id := uint64(len(w.attrGroups))
+	w.attrGroupIDs[key] = id
+	return id
+}
+
+// writeAttrGroups writes the parameter attribute group block.
+//
+//	ENTRY: [grpid, idx, attr0, attr1, ...]
+func (w *writer) writeAttrGroups() error {
+	if len(w.attrGroups) == 0 {
+		return nil
+	}
+	w.bs.EnterBlock(blockIDParamAttrGroup, 3)
+	for i, group := range w.attrGroups {
+		ops := append([]uint64{uint64(i + 1)}, group...)
+		w.writeRecord(paramAttrGroupCodeEntry, ops...)
+	}
+	return w.bs.ExitBlock()
+}
+
+// writeAttrLists writes the parameter attribute block.
+//
+//	ENTRY: [attrgrp0, attrgrp1, ...]
+func (w *writer) writeAttrLists() error {
+	if len(w.attrLists) == 0 {
+		return nil
+	}
+	w.bs.EnterBlock(blockIDParamAttr, 3)
+	for _, list := range w.attrLists {
+		w.writeRecord(paramAttrCodeEntry, list...)
+	}
+	return w.bs.ExitBlock()
+}
+
+// encodeAttr returns the operands of the given attribute in an attribute group
+// entry.
+func (w *writer) encodeAttr(attr interface{}) ([]uint64, error) {
+	switch attr := attr.(type) {
+	case ir.AttrString:
+		// [kind, key..., 0]
+		ops := append([]uint64{attrKindString}, stringOps(string(attr))...)
+		return append(ops, 0), nil
+	case ir.AttrPair:
+		// [kind, key..., 0, value..., 0]
+		ops := append([]uint64{attrKindStringValue}, stringOps(attr.Key)...)
+		ops = append(ops, 0)
+		ops = append(ops, stringOps(attr.Value)...)
+		return append(ops, 0), nil
+	case ir.Align:
+		return []uint64{attrKindInt, attrAlignment, uint64(attr)}, nil
+	case ir.AlignStack:
+		return []uint64{attrKindInt, attrStackAlignment, uint64(attr)}, nil
+	case ir.Dereferenceable:
+		if attr.DerefOrNull {
+			return []uint64{attrKindInt, attrDereferenceableOrNull, attr.N}, nil
+		}
+		return []uint64{attrKindInt, attrDereferenceable, attr.N}, nil
+	case ir.AllocSize:
+		v := uint64(uint32(attr.ElemSizeIndex))<<32 | uint64(uint32(attr.NElemsIndex))
+		return []uint64{attrKindInt, attrAllocSize, v}, nil
+	case ir.VectorScaleRange:
+		v := uint64(uint32(attr.Min))<<32 | uint64(uint32(attr.Max))
+		return []uint64{attrKindInt, attrVScaleRange, v}, nil
+	case ir.UnwindTable:
+		if attr.Kind == enum.UnwindTableKindNone {
+			return []uint64{attrKindEnum, attrUWTable}, nil
+		}
+		return []uint64{attrKindInt, attrUWTable, uint64(attr.Kind)}, nil
+	case ir.AllocKind:
+		return []uint64{attrKindInt, attrAllocKind, uint64(attr.Kind)}, nil
+	case *ir.AllocKind:
+		return []uint64{attrKindInt, attrAllocKind, uint64(attr.Kind)}, nil
+	case ir.Byval:
+		if attr.Typ == nil {
+			// byval without type.
+			return []uint64{attrKindTypeNone, attrByVal}, nil
+		}
+		return []uint64{attrKindType, attrByVal, w.typeID(attr.Typ)}, nil
+	case ir.ByRef:
+		return []uint64{attrKindType, attrByRef, w.typeID(attr.Typ)}, nil
+	case ir.SRet:
+		return []uint64{attrKindType, attrStructRet, w.typeID(attr.Typ)}, nil
+	case ir.Preallocated:
+		return []uint64{attrKindType, attrPreallocated, w.typeID(attr.Typ)}, nil
+	case ir.InAlloca:
+		return []uint64{attrKindType, attrInAlloca, w.typeID(attr.Typ)}, nil
+	case ir.ElementType:
+		return []uint64{attrKindType, attrElementType, w.typeID(attr.Typ)}, nil
+	case enum.FuncAttr, enum.ParamAttr, enum.ReturnAttr:
+		name := attr.(fmt.Stringer).String()
+		kind, ok := attrKinds[name]
+		if !ok {
+			return nil, errors.Errorf("support for attribute %q not yet implemented", name)
+		}
+		return []uint64{attrKindEnum, kind}, nil
+	default:
+		return nil, errors.Errorf("support for attribute %v (%T) not yet implemented", attr, attr)
+	}
+}
+
+// attrKinds maps from attribute name to bitcode enum attribute kind.
+var attrKinds = make(map[string]uint64)
+
+func init() {
+	for kind, name := range attrNames {
+		attrKinds[name] = kind
+	}
+}
@@ -0,0 +1,548 @@
+package bitcode
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+	"github.com/mewmew/float/binary128"
+	"github.com/mewmew/float/binary16"
+	"github.com/mewmew/float/float128ppc"
+	"github.com/mewmew/float/float80x86"
+	"github.com/pkg/errors"
+)
+
+// addConst assigns module-level value IDs to the operands of the given
+// constant (if not already present) and then to the constant itself; thus
+// constants are only refered to by constants of later value IDs.
+func (w *writer) addConst(c constant.Constant) uint64 {
+	for _, op := range constOperands(c) {
+		if _, ok := w.valueIDs[op]; !ok {
+			w.addConst(op)
+		}
+	}
+	return w.addValue(c)
+}
+
+// writeConstants writes a constants block of the given constants (and inline
+// assembler expressions).
+//
+//	SETTYPE: [typeid]
+func (w *writer) writeConstants(consts []value.Value) error {
+	if len(consts) == 0 {
+		return nil
+	}
+	w.bs.EnterBlock(blockIDConstants, 4)
+	// The type of constants defaults to i32.
+	curType := w.typeID(types.I32)
+	for _, c := range consts {
+		if t := w.typeID(c.Type()); t != curType {
+			w.writeRecord(constCodeSetType, t)
+			curType = t
+		}
+		if err := w.writeConst(c); err != nil {
+			return errors.Wrapf(err, "unable to write constant %q", c.Ident())
+		}
+	}
+	return w.bs.ExitBlock()
+}
+
+// writeConst writes the constant record of the given constant (or inline
+// assembler expression).
+func (w *writer) writeConst(c value.Value) error {
+	switch c := c.(type) {
+	case *constant.Null, *constant.ZeroInitializer, *constant.NoneToken:
+		w.writeRecord(constCodeNull)
+	case *constant.Undef:
+		w.writeRecord(constCodeUndef)
+	case *constant.Poison:
+		w.writeRecord(constCodePoison)
+	case *constant.Int:
+		// INTEGER: [intval]
+		// WIDE_INTEGER: [n x intval]
+		if c.Typ.BitSize <= 64 {
+			w.writeRecord(constCodeInteger, encodeSigned(intWords(c.X, c.Typ.BitSize)[0]))
+			break
+		}
+		var ops []uint64
+		for _, word := range intWords(c.X, c.Typ.BitSize) {
+			ops = append(ops, encodeSigned(word))
+		}
+		w.writeRecord(constCodeWideInteger, ops...)
+	case *constant.Float:
+		// FLOAT: [fpval]
+		ops, err := floatBits(c)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		w.writeRecord(constCodeFloat, ops...)
+	case *constant.CharArray:
+		// STRING: [values]
+		// CSTRING: [values]
+		if isCString(c.X) {
+			w.writeRecord(constCodeCString, bytesOps(c.X[:len(c.X)-1])...)
+			break
+		}
+		w.writeRecord(constCodeString, bytesOps(c.X)...)
+	case *constant.Array:
+		return w.writeAggregate(c.Elems)
+	case *constant.Vector:
+		return w.writeAggregate(c.Elems)
+	case *constant.Struct:
+		r := w.newRecord(constCodeAggregate)
+		for _, field := range c.Fields {
+			r.absValue(field)
+		}
+		return r.write()
+	case *constant.BlockAddress:
+		// BLOCKADDRESS: [fnty, fnval, bb#]
+		f, ok := c.Func.(*ir.Func)
+		if !ok {
+			return errors.Errorf("invalid function of blockaddress constant; expected *ir.Func, got %T", c.Func)
+		}
+		index := -1
+		for i, block := range f.Blocks {
+			if block == c.Block {
+				index = i
+				break
+			}
+		}
+		if index == -1 {
+			return errors.Errorf("unable to locate basic block %q in function %q", c.Block.Ident(), f.Ident())
+		}
+		r := w.newRecord(constCodeBlockAddress)
+		r.typ(f.Type())
+		r.absValue(f)
+		r.uint(uint64(index))
+		return r.write()
+	case *constant.DSOLocalEquivalent:
+		// DSO_LOCAL_EQUIVALENT: [gvty, gv]
+		r := w.newRecord(constCodeDSOLocalEquivalent)
+		r.typ(c.Func.Type())
+		r.absValue(c.Func)
+		return r.write()
+	case *constant.NoCFI:
+		// NO_CFI: [fty, f]
+		r := w.newRecord(constCodeNoCFIValue)
+		r.typ(c.Func.Type())
+		r.absValue(c.Func)
+		return r.write()
+	case *ir.InlineAsm:
+		return w.writeInlineAsm(c)
+	case constant.Expression:
+		return w.writeConstExpr(c)
+	default:
+		return errors.Errorf("support for constant %T not yet implemented", c)
+	}
+	return nil
+}
+
+// writeAggregate writes the constant record of an aggregate constant with the
+// given elements. Arrays and vectors of integer and floating-point elements
+// are stored as data records.
+//
+//	AGGREGATE: [n x value number]
+//	DATA: [n x elements]
+func (w *writer) writeAggregate(elems []constant.Constant) error {
+	if ops, ok := dataOps(elems); ok {
+		w.writeRecord(constCodeData, ops...)
+		return nil
+	}
+	r := w.newRecord(constCodeAggregate)
+	for _, elem := range elems {
+		r.absValue(elem)
+	}
+	return r.write()
+}
+
+// writeInlineAsm writes the constant record of the given inline assembler
+// expression.
+//
+//	INLINEASM: [fnty, sideeffect|alignstack|asmdialect|unwind, asmstr, conststr]
+//
+// The strings are encoded as [len, chars...].
+func (w *writer) writeInlineAsm(asm *ir.InlineAsm) error {
+	sig, err := w.inlineAsmSig(asm)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r := w.newRecord(constCodeInlineAsm)
+	r.typ(sig)
+	r.uint(boolOp(asm.SideEffect) | boolOp(asm.AlignStack)<<1 | boolOp(asm.IntelDialect)<<2)
+	r.uint(uint64(len(asm.Asm)))
+	r.uint(stringOps(asm.Asm)...)
+	r.uint(uint64(len(asm.Constraint)))
+	r.uint(stringOps(asm.Constraint)...)
+	return r.write()
+}
+
+// inlineAsmSig returns the function type of the given inline assembler
+// expression; either from its typed pointer type, or from the call site
+// invoking it.
+func (w *writer) inlineAsmSig(asm *ir.InlineAsm) (*types.FuncType, error) {
+	if t, ok := asm.Typ.(*types.PointerType); ok {
+		if sig, ok := t.ElemType.(*types.FuncType); ok {
+			return sig, nil
+		}
+	}
+	if w.fw != nil {
+		if sig, ok := w.fw.asmSigs[asm]; ok {
+			return sig, nil
+		}
+	}
+	return nil, errors.Errorf("unable to locate function type of inline assembler expression %q", asm.Asm)
+}
+
+// writeConstExpr writes the constant record of the given constant expression.
+func (w *writer) writeConstExpr(e constant.Expression) error {
+	switch e := e.(type) {
+	// Unary expressions.
+	case *constant.ExprFNeg:
+		// CE_UNOP: [opcode, opval]
+		r := w.newRecord(constCodeCEUnop)
+		r.uint(unopFNeg)
+		r.absValue(e.X)
+		return r.write()
+	// Binary expressions.
+	case *constant.ExprAdd:
+		return w.writeBinaryExpr(binopAdd, e.X, e.Y, bcOverflowFlags(e.OverflowFlags))
+	case *constant.ExprSub:
+		return w.writeBinaryExpr(binopSub, e.X, e.Y, bcOverflowFlags(e.OverflowFlags))
+	case *constant.ExprMul:
+		return w.writeBinaryExpr(binopMul, e.X, e.Y, bcOverflowFlags(e.OverflowFlags))
+	// Bitwise expressions.
+	case *constant.ExprShl:
+		return w.writeBinaryExpr(binopShl, e.X, e.Y, bcOverflowFlags(e.OverflowFlags))
+	case *constant.ExprLShr:
+		return w.writeBinaryExpr(binopLShr, e.X, e.Y, boolOp(e.Exact)<<peoExact)
+	case *constant.ExprAShr:
+		return w.writeBinaryExpr(binopAShr, e.X, e.Y, boolOp(e.Exact)<<peoExact)
+	case *constant.ExprAnd:
+		return w.writeBinaryExpr(binopAnd, e.X, e.Y, 0)
+	case *constant.ExprOr:
+		return w.writeBinaryExpr(binopOr, e.X, e.Y, 0)
+	case *constant.ExprXor:
+		return w.writeBinaryExpr(binopXor, e.X, e.Y, 0)
+	// Vector expressions.
+	case *constant.ExprExtractElement:
+		// CE_EXTRACTELT: [opty, opval, opty, opval]
+		r := w.newRecord(constCodeCEExtractElt)
+		r.typ(e.X.Type())
+		r.absValue(e.X)
+		r.typ(e.Index.Type())
+		r.absValue(e.Index)
+		return r.write()
+	case *constant.ExprInsertElement:
+		// CE_INSERTELT: [opval, opval, opty, opval]
+		r := w.newRecord(constCodeCEInsertElt)
+		r.absValue(e.X)
+		r.absValue(e.Elem)
+		r.typ(e.Index.Type())
+		r.absValue(e.Index)
+		return r.write()
+	case *constant.ExprShuffleVector:
+		// CE_SHUFFLEVEC: [opval, opval, opval]
+		// CE_SHUFVEC_EX: [opty, opval, opval, opval]
+		r := w.newRecord(constCodeCEShuffleVec)
+		if !e.Type().Equal(e.X.Type()) {
+			r.code = constCodeCEShufVecEx
+			r.typ(e.X.Type())
+		}
+		r.absValue(e.X)
+		r.absValue(e.Y)
+		r.absValue(e.Mask)
+		return r.write()
+	// Memory expressions.
+	case *constant.ExprGetElementPtr:
+		return w.writeGEPExpr(e)
+	// Conversion expressions.
+	case *constant.ExprTrunc:
+		return w.writeCastExpr(castTrunc, e.From)
+	case *constant.ExprZExt:
+		return w.writeCastExpr(castZExt, e.From)
+	case *constant.ExprSExt:
+		return w.writeCastExpr(castSExt, e.From)
+	case *constant.ExprFPTrunc:
+		return w.writeCastExpr(castFPTrunc, e.From)
+	case *constant.ExprFPExt:
+		return w.writeCastExpr(castFPExt, e.From)
+	case *constant.ExprFPToUI:
+		return w.writeCastExpr(castFPToUI, e.From)
+	case *constant.ExprFPToSI:
+		return w.writeCastExpr(castFPToSI, e.From)
+	case *constant.ExprUIToFP:
+		return w.writeCastExpr(castUIToFP, e.From)
+	case *constant.ExprSIToFP:
+		return w.writeCastExpr(castSIToFP, e.From)
+	case *constant.ExprPtrToInt:
+		return w.writeCastExpr(castPtrToInt, e.From)
+	case *constant.ExprIntToPtr:
+		return w.writeCastExpr(castIntToPtr, e.From)
+	case *constant.ExprBitCast:
+		return w.writeCastExpr(castBitCast, e.From)
+	case *constant.ExprAddrSpaceCast:
+		return w.writeCastExpr(castAddrSpaceCast, e.From)
+	// Other expressions.
+	case *constant.ExprICmp:
+		pred, err := bcIPred(e.Pred)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return w.writeCmpExpr(pred, e.X, e.Y)
+	case *constant.ExprFCmp:
+		pred, err := bcFPred(e.Pred)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return w.writeCmpExpr(pred, e.X, e.Y)
+	case *constant.ExprSelect:
+		// CE_SELECT: [opval, opval, opval]
+		r := w.newRecord(constCodeCESelect)
+		r.absValue(e.Cond)
+		r.absValue(e.X)
+		r.absValue(e.Y)
+		return r.write()
+	default:
+		return errors.Errorf("support for constant expression %T not yet implemented", e)
+	}
+}
+
+// writeBinaryExpr writes the constant record of a binary constant expression.
+//
+//	CE_BINOP: [opcode, opval, opval, flags?]
+func (w *writer) writeBinaryExpr(opcode uint64, x, y constant.Constant, flags uint64) error {
+	r := w.newRecord(constCodeCEBinop)
+	r.uint(opcode)
+	r.absValue(x)
+	r.absValue(y)
+	if flags != 0 {
+		r.uint(flags)
+	}
+	return r.write()
+}
+
+// writeCastExpr writes the constant record of a conversion constant expression.
+//
+//	CE_CAST: [opcode, opty, opval]
+func (w *writer) writeCastExpr(opcode uint64, from constant.Constant) error {
+	r := w.newRecord(constCodeCECast)
+	r.uint(opcode)
+	r.typ(from.Type())
+	r.absValue(from)
+	return r.write()
+}
+
+// writeCmpExpr writes the constant record of a comparison constant expression.
+//
+//	CE_CMP: [opty, opval, opval, pred]
+func (w *writer) writeCmpExpr(pred uint64, x, y constant.Constant) error {
+	r := w.newRecord(constCodeCECmp)
+	r.typ(x.Type())
+	r.absValue(x)
+	r.absValue(y)
+	r.uint(pred)
+	return r.write()
+}
+
+// writeGEPExpr writes the constant record of the given getelementptr constant
+// expression.
+//
+//	CE_GEP: [pointee type, n x (opty, opval)]
+//	CE_INBOUNDS_GEP: [pointee type, n x (opty, opval)]
+//	CE_GEP_WITH_INRANGE_INDEX: [pointee type, flags, n x (opty, opval)]
+func (w *writer) writeGEPExpr(e *constant.ExprGetElementPtr) error {
+	r := w.newRecord(constCodeCEGEP)
+	r.typ(e.ElemType)
+	inRangeIndex := -1
+	for i, index := range e.Indices {
+		if index, ok := index.(*constant.Index); ok && index.InRange {
+			// The inrange index is relative to the operand list (including the
+			// source address).
+			inRangeIndex = i + 1
+		}
+	}
+	switch {
+	case inRangeIndex != -1:
+		r.code = constCodeCEGEPWithInrange
+		r.uint(uint64(inRangeIndex)<<1 | boolOp(e.InBounds))
+	case e.InBounds:
+		r.code = constCodeCEInboundsGEP
+	}
+	r.typ(e.Src.Type())
+	r.absValue(e.Src)
+	for _, index := range e.Indices {
+		r.typ(index.Type())
+		r.absValue(index)
+	}
+	return r.write()
+}
+
+// ### [ Helper functions ] ####################################################
+
+// constOperands returns the constant operands of the given constant; with
+// getelementptr indices unwrapped.
+func constOperands(c value.Value) []constant.Constant {
+	cc, ok := c.(constant.Constant)
+	if !ok {
+		return nil
+	}
+	var ops []constant.Constant
+	for _, op := range constant.Operands(cc) {
+		ops = append(ops, unwrapValue(*op).(constant.Constant))
+	}
+	return ops
+}
+
+// intWords returns the 64-bit words of the given integer of the given bit size
+// in two's complement representation, in little-endian order.
+func intWords(x *big.Int, bitSize uint64) []int64 {
+	n := (bitSize + 63) / 64
+	// Truncate to n words.
+	mask := new(big.Int).Lsh(big.NewInt(1), uint(n*64))
+	v := new(big.Int).Mod(x, mask)
+	words := make([]int64, n)
+	for i := range words {
+		words[i] = int64(new(big.Int).And(v, new(big.Int).SetUint64(math.MaxUint64)).Uint64())
+		v.Rsh(v, 64)
+	}
+	if bitSize < 64 {
+		// Sign-extend from bit size.
+		shift := 64 - bitSize
+		words[0] = words[0] << shift >> shift
+	}
+	return words
+}
+
+// floatBits returns the bits of the given floating-point constant, as stored
+// in FLOAT records.
+func floatBits(c *constant.Float) ([]uint64, error) {
+	switch c.Typ.Kind {
+	case types.FloatKindHalf:
+		if c.NaN {
+			if c.X != nil && c.X.Signbit() {
+				return []uint64{uint64(binary16.NegNaN.Bits())}, nil
+			}
+			return []uint64{uint64(binary16.NaN.Bits())}, nil
+		}
+		f, _ := binary16.NewFromBig(c.X)
+		return []uint64{uint64(f.Bits())}, nil
+	case types.FloatKindFloat:
+		if c.NaN {
+			bits := uint64(0x7FC00000)
+			if c.X != nil && c.X.Signbit() {
+				bits |= 1 << 31
+			}
+			return []uint64{bits}, nil
+		}
+		f, _ := c.X.Float32()
+		return []uint64{uint64(math.Float32bits(f))}, nil
+	case types.FloatKindDouble:
+		if c.NaN {
+			bits := uint64(0x7FF8000000000000)
+			if c.X != nil && c.X.Signbit() {
+				bits |= 1 << 63
+			}
+			return []uint64{bits}, nil
+		}
+		f, _ := c.X.Float64()
+		return []uint64{math.Float64bits(f)}, nil
+	case types.FloatKindX86_FP80:
+		se, m := float80x86.NaN.Bits()
+		switch {
+		case c.NaN && c.X != nil && c.X.Signbit():
+			se, m = float80x86.NegNaN.Bits()
+		case !c.NaN:
+			f, _ := float80x86.NewFromBig(c.X)
+			se, m = f.Bits()
+		}
+		return []uint64{uint64(se)<<48 | m>>16, m & 0xFFFF}, nil
+	case types.FloatKindFP128:
+		a, b := binary128.NaN.Bits()
+		switch {
+		case c.NaN && c.X != nil && c.X.Signbit():
+			a, b = binary128.NegNaN.Bits()
+		case !c.NaN:
+			f, _ := binary128.NewFromBig(c.X)
+			a, b = f.Bits()
+		}
+		return []uint64{a, b}, nil
+	case types.FloatKindPPC_FP128:
+		a, b := float128ppc.NaN.Bits()
+		switch {
+		case c.NaN && c.X != nil && c.X.Signbit():
+			a, b = float128ppc.NegNaN.Bits()
+		case !c.NaN:
+			f, _ := float128ppc.NewFromBig(c.X)
+			a, b = f.Bits()
+		}
+		return []uint64{a, b}, nil
+	default:
+		return nil, errors.Errorf("support for floating-point kind %v not yet implemented", c.Typ.Kind)
+	}
+}
+
+// dataOps returns the operands of a data record of the given elements, and a
+// boolean indicating whether the elements may be stored in a data record;
+// i.e. whether all elements are 8-, 16-, 32- or 64-bit integer constants or
+// half, float or double constants.
+func dataOps(elems []constant.Constant) ([]uint64, bool) {
+	if len(elems) == 0 {
+		return nil, false
+	}
+	ops := make([]uint64, len(elems))
+	for i, elem := range elems {
+		switch elem := elem.(type) {
+		case *constant.Int:
+			switch elem.Typ.BitSize {
+			case 8, 16, 32, 64:
+			default:
+				return nil, false
+			}
+			word := uint64(intWords(elem.X, elem.Typ.BitSize)[0])
+			if elem.Typ.BitSize < 64 {
+				word &= 1<<elem.Typ.BitSize - 1
+			}
+			ops[i] = word
+		case *constant.Float:
+			switch elem.Typ.Kind {
+			case types.FloatKindHalf, types.FloatKindFloat, types.FloatKindDouble:
+			default:
+				return nil, false
+			}
+			bits, err := floatBits(elem)
+			if err != nil {
+				return nil, false
+			}
+			ops[i] = bits[0]
+		default:
+			return nil, false
+		}
+	}
+	return ops, true
+}
+
+// isCString reports whether the given character array is null-terminated and
+// contains no other null characters.
+func isCString(buf []byte) bool {
+	if len(buf) == 0 || buf[len(buf)-1] != 0 {
+		return false
+	}
+	for _, b := range buf[:len(buf)-1] {
+		if b == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bytesOps returns the given bytes as record operands.
+func bytesOps(buf []byte) []uint64 {
+	ops := make([]uint64, len(buf))
+	for i, b := range buf {
+		ops[i] = uint64(b)
+	}
+	return ops
+}
@@ -0,0 +1,1057 @@
+package bitcode
+
+import (
+	"strconv"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/metadata"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+	"github.com/pkg/errors"
+)
+
+// funcWriter keeps track of the state of the translation of the body of an
+// LLVM IR function into a function block.
+type funcWriter struct {
+	w *writer
+	// Function being written.
+	f *ir.Func
+	// Value IDs of function-local values (parameters, function-level constants
+	// and instructions).
+	ids map[value.Value]uint64
+	// Function-level constants and inline assembler expressions, in order of
+	// value ID.
+	consts []value.Value
+	// Value ID of the next instruction.
+	nextID uint64
+	// Basic block IDs.
+	blockIDs map[*ir.Block]uint64
+	// Function types of inline assembler expressions, as specified by the call
+	// sites invoking them.
+	asmSigs map[*ir.InlineAsm]*types.FuncType
+	// Function-level metadata, with metadata IDs following module-level
+	// metadata.
+	mds []interface{}
+	// Function-level metadata IDs.
+	mdIDs map[interface{}]uint64
+	// Metadata ID of the first function-level metadata.
+	mdBase uint64
+	// Last written debug location; used by DEBUG_LOC_AGAIN records.
+	lastLoc *metadata.DILocation
+}
+
+// newFuncWriter returns a new writer for the body of the given function.
+func newFuncWriter(w *writer, f *ir.Func) *funcWriter {
+	return &funcWriter{
+		w:        w,
+		f:        f,
+		ids:      make(map[value.Value]uint64),
+		blockIDs: make(map[*ir.Block]uint64),
+		asmSigs:  make(map[*ir.InlineAsm]*types.FuncType),
+		mdIDs:    make(map[interface{}]uint64),
+	}
+}
+
+// writeFunc writes the function block of the given function definition.
+//
+//	DECLAREBLOCKS: [n]
+func (w *writer) writeFunc(f *ir.Func) error {
+	fw := newFuncWriter(w, f)
+	w.fw = fw
+	defer func() {
+		w.fw = nil
+	}()
+	if err := fw.assignIDs(); err != nil {
+		return errors.WithStack(err)
+	}
+	w.bs.EnterBlock(blockIDFunction, 4)
+	w.writeRecord(funcCodeDeclareBlocks, uint64(len(f.Blocks)))
+	if err := w.writeConstants(fw.consts); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := fw.writeMetadata(); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			if err := fw.writeInst(inst); err != nil {
+				return errors.Wrapf(err, "unable to write instruction %q", inst.LLString())
+			}
+			if err := fw.writeDebugLoc(inst.(mdAttacher)); err != nil {
+				return errors.WithStack(err)
+			}
+			fw.advance(inst)
+		}
+		if err := fw.writeTerm(block.Term); err != nil {
+			return errors.Wrapf(err, "unable to write terminator %q", block.Term.LLString())
+		}
+		if err := fw.writeDebugLoc(block.Term.(mdAttacher)); err != nil {
+			return errors.WithStack(err)
+		}
+		fw.advance(block.Term)
+	}
+	if err := fw.writeSymtab(); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := fw.writeAttachments(); err != nil {
+		return errors.WithStack(err)
+	}
+	return w.bs.ExitBlock()
+}
+
+// assignIDs assigns value IDs to the parameters, function-level constants and
+// instructions of the function, basic block IDs to its basic blocks, and
+// metadata IDs to the metadata used as function arguments.
+func (fw *funcWriter) assignIDs() error {
+	// Metadata arguments may refer to module-level constants, and are as such
+	// located before value IDs are assigned.
+	fw.mdBase = uint64(len(fw.w.mds))
+	var insts []value.User
+	for i, block := range fw.f.Blocks {
+		fw.blockIDs[block] = uint64(i)
+		for _, inst := range block.Insts {
+			insts = append(insts, inst)
+		}
+		insts = append(insts, block.Term)
+	}
+	for _, inst := range insts {
+		sig, callee, args := callOf(inst)
+		if sig == nil {
+			continue
+		}
+		if asm, ok := callee.(*ir.InlineAsm); ok {
+			fw.asmSigs[asm] = sig
+		}
+		for _, arg := range args {
+			if md, ok := unwrapValue(arg).(*metadata.Value); ok {
+				if _, err := fw.w.mdID(fw.w.mdEntry(md)); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+		}
+	}
+	next := uint64(len(fw.w.values))
+	add := func(v value.Value) {
+		fw.ids[v] = next
+		next++
+	}
+	for _, param := range fw.f.Params {
+		add(param)
+	}
+	// Function-level constants.
+	var addConst func(c value.Value)
+	addConst = func(c value.Value) {
+		if _, ok := fw.w.valueIDs[c]; ok {
+			return
+		}
+		if _, ok := fw.ids[c]; ok {
+			return
+		}
+		for _, op := range constOperands(c) {
+			addConst(op)
+		}
+		fw.consts = append(fw.consts, c)
+		add(c)
+	}
+	addOperand := func(v value.Value) {
+		switch v := unwrapValue(v).(type) {
+		case *ir.InlineAsm:
+			addConst(v)
+		case *ir.Func, *ir.Global, *ir.Alias, *ir.IFunc:
+			// Global values have module-level value IDs.
+		case constant.Constant:
+			addConst(v)
+		}
+	}
+	for _, inst := range insts {
+		for _, op := range inst.Operands() {
+			if *op != nil {
+				addOperand(*op)
+			}
+		}
+		for _, bundle := range operandBundlesOf(inst) {
+			for _, input := range bundle.Inputs {
+				addOperand(input)
+			}
+		}
+		if inst, ok := inst.(*ir.InstAlloca); ok && inst.NElems == nil {
+			addConst(fw.w.one)
+		}
+	}
+	// Instructions producing values.
+	fw.nextID = next
+	for _, inst := range insts {
+		if v, ok := inst.(value.Value); ok && !v.Type().Equal(types.Void) {
+			add(v)
+		}
+	}
+	return nil
+}
+
+// advance updates the value ID of the next instruction, following the given
+// instruction.
+func (fw *funcWriter) advance(inst interface{}) {
+	if v, ok := inst.(value.Value); ok && !v.Type().Equal(types.Void) {
+		fw.nextID = fw.ids[v] + 1
+	}
+}
+
+// mdID returns the metadata ID of the given function-local metadata entry,
+// assigning metadata IDs on first use.
+func (fw *funcWriter) mdID(entry interface{}) uint64 {
+	if id, ok := fw.mdIDs[entry]; ok {
+		return id
+	}
+	if md, ok := entry.(*metadata.DIArgList); ok {
+		// Arguments are stored before the argument list.
+		for _, arg := range md.Fields {
+			if e := fw.w.mdEntry(arg); e != nil {
+				// Errors are reported when writing the argument list.
+				_, _ = fw.w.mdID(e)
+			}
+		}
+	}
+	id := fw.mdBase + uint64(len(fw.mds))
+	fw.mds = append(fw.mds, entry)
+	fw.mdIDs[entry] = id
+	return id
+}
+
+// writeMetadata writes the function-level metadata block.
+func (fw *funcWriter) writeMetadata() error {
+	if len(fw.mds) == 0 {
+		return nil
+	}
+	fw.w.bs.EnterBlock(blockIDMetadata, 3)
+	for _, entry := range fw.mds {
+		if err := fw.w.writeMDEntry(entry); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return fw.w.bs.ExitBlock()
+}
+
+// writeDebugLoc writes the debug location record of the given instruction, if
+// it has a (non-distinct) debug location attached.
+//
+//	DEBUG_LOC: [line, col, scope, inlined-at, isImplicitCode]
+//	DEBUG_LOC_AGAIN
+func (fw *funcWriter) writeDebugLoc(inst mdAttacher) error {
+	loc := debugLocOf(inst)
+	if loc == nil {
+		return nil
+	}
+	if loc == fw.lastLoc {
+		fw.w.writeRecord(funcCodeDebugLocAgain)
+		return nil
+	}
+	fw.lastLoc = loc
+	r := fw.w.newRecord(funcCodeDebugLoc)
+	r.uint(uint64(loc.Line), uint64(loc.Column))
+	r.md(loc.Scope)
+	r.md(loc.InlinedAt)
+	r.bool(loc.IsImplicitCode)
+	return r.write()
+}
+
+// writeSymtab writes the value symbol table of the function, containing the
+// names of named parameters, instructions and basic blocks.
+//
+//	VST_ENTRY: [valueid, namechar x N]
+//	VST_BBENTRY: [bbid, namechar x N]
+func (fw *funcWriter) writeSymtab() error {
+	type entry struct {
+		code, id uint64
+		name     string
+	}
+	var entries []entry
+	addValue := func(v value.Value) {
+		if name, ok := localName(v); ok {
+			entries = append(entries, entry{code: vstCodeEntry, id: fw.ids[v], name: name})
+		}
+	}
+	for _, param := range fw.f.Params {
+		addValue(param)
+	}
+	for _, block := range fw.f.Blocks {
+		if name, ok := localName(block); ok {
+			entries = append(entries, entry{code: vstCodeBBEntry, id: fw.blockIDs[block], name: name})
+		}
+		for _, inst := range block.Insts {
+			if v, ok := inst.(value.Value); ok && !v.Type().Equal(types.Void) {
+				addValue(v)
+			}
+		}
+		if v, ok := block.Term.(value.Value); ok && !v.Type().Equal(types.Void) {
+			addValue(v)
+		}
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	fw.w.bs.EnterBlock(blockIDValueSymtab, 4)
+	for _, e := range entries {
+		ops := append([]uint64{e.id}, stringOps(e.name)...)
+		fw.w.writeRecord(e.code, ops...)
+	}
+	return fw.w.bs.ExitBlock()
+}
+
+// writeAttachments writes the metadata attachment block of the function,
+// containing the metadata attachments of the function and of its
+// instructions (except for debug locations stored in DEBUG_LOC records).
+//
+//	ATTACHMENT: [m x [value, [n x [id, mdnode]]]
+func (fw *funcWriter) writeAttachments() error {
+	var records [][]uint64
+	if len(fw.f.Metadata) > 0 {
+		ops, err := fw.w.mdAttachments(fw.f.Metadata)
+		if err != nil {
+			return errors.Wrap(err, "unable to write metadata attachments of function")
+		}
+		records = append(records, ops)
+	}
+	var i uint64
+	add := func(inst mdAttacher) error {
+		defer func() { i++ }()
+		var mds []*metadata.Attachment
+		loc := debugLocOf(inst)
+		for _, md := range inst.MDAttachments() {
+			if loc != nil && md.Node == loc {
+				// Stored in DEBUG_LOC record.
+				loc = nil
+				continue
+			}
+			mds = append(mds, md)
+		}
+		if len(mds) == 0 {
+			return nil
+		}
+		ops, err := fw.w.mdAttachments(mds)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		records = append(records, append([]uint64{i}, ops...))
+		return nil
+	}
+	for _, block := range fw.f.Blocks {
+		for _, inst := range block.Insts {
+			if err := add(inst.(mdAttacher)); err != nil {
+				return errors.Wrapf(err, "unable to write metadata attachments of instruction %q", inst.LLString())
+			}
+		}
+		if err := add(block.Term.(mdAttacher)); err != nil {
+			return errors.Wrapf(err, "unable to write metadata attachments of terminator %q", block.Term.LLString())
+		}
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	fw.w.bs.EnterBlock(blockIDMetadataAttachment, 3)
+	for _, ops := range records {
+		fw.w.writeRecord(metadataCodeAttachment, ops...)
+	}
+	return fw.w.bs.ExitBlock()
+}
+
+// --- [ Instructions ] --------------------------------------------------------
+
+// writeInst writes the record of the given instruction.
+func (fw *funcWriter) writeInst(inst ir.Instruction) error {
+	w := fw.w
+	switch inst := inst.(type) {
+	// Unary instructions.
+	case *ir.InstFNeg:
+		// UNOP: [opval, opcode, flags?]
+		r := w.newRecord(funcCodeInstUnop)
+		r.valueTypePair(inst.X)
+		r.uint(unopFNeg)
+		if flags := bcFastMathFlags(inst.FastMathFlags); flags != 0 {
+			r.uint(flags)
+		}
+		return r.write()
+	// Binary instructions.
+	case *ir.InstAdd:
+		return fw.writeBinary(binopAdd, inst.X, inst.Y, bcOverflowFlags(inst.OverflowFlags))
+	case *ir.InstFAdd:
+		return fw.writeBinary(binopAdd, inst.X, inst.Y, bcFastMathFlags(inst.FastMathFlags))
+	case *ir.InstSub:
+		return fw.writeBinary(binopSub, inst.X, inst.Y, bcOverflowFlags(inst.OverflowFlags))
+	case *ir.InstFSub:
+		return fw.writeBinary(binopSub, inst.X, inst.Y, bcFastMathFlags(inst.FastMathFlags))
+	case *ir.InstMul:
+		return fw.writeBinary(binopMul, inst.X, inst.Y, bcOverflowFlags(inst.OverflowFlags))
+	case *ir.InstFMul:
+		return fw.writeBinary(binopMul, inst.X, inst.Y, bcFastMathFlags(inst.FastMathFlags))
+	case *ir.InstUDiv:
+		return fw.writeBinary(binopUDiv, inst.X, inst.Y, boolOp(inst.Exact)<<peoExact)
+	case *ir.InstSDiv:
+		return fw.writeBinary(binopSDiv, inst.X, inst.Y, boolOp(inst.Exact)<<peoExact)
+	case *ir.InstFDiv:
+		return fw.writeBinary(binopSDiv, inst.X, inst.Y, bcFastMathFlags(inst.FastMathFlags))
+	case *ir.InstURem:
+		return fw.writeBinary(binopURem, inst.X, inst.Y, 0)
+	case *ir.InstSRem:
+		return fw.writeBinary(binopSRem, inst.X, inst.Y, 0)
+	case *ir.InstFRem:
+		return fw.writeBinary(binopSRem, inst.X, inst.Y, bcFastMathFlags(inst.FastMathFlags))
+	// Bitwise instructions.
+	case *ir.InstShl:
+		return fw.writeBinary(binopShl, inst.X, inst.Y, bcOverflowFlags(inst.OverflowFlags))
+	case *ir.InstLShr:
+		return fw.writeBinary(binopLShr, inst.X, inst.Y, boolOp(inst.Exact)<<peoExact)
+	case *ir.InstAShr:
+		return fw.writeBinary(binopAShr, inst.X, inst.Y, boolOp(inst.Exact)<<peoExact)
+	case *ir.InstAnd:
+		return fw.writeBinary(binopAnd, inst.X, inst.Y, 0)
+	case *ir.InstOr:
+		return fw.writeBinary(binopOr, inst.X, inst.Y, 0)
+	case *ir.InstXor:
+		return fw.writeBinary(binopXor, inst.X, inst.Y, 0)
+	// Vector instructions.
+	case *ir.InstExtractElement:
+		// EXTRACTELT: [opval, opval]
+		r := w.newRecord(funcCodeInstExtractElt)
+		r.valueTypePair(inst.X)
+		r.valueTypePair(inst.Index)
+		return r.write()
+	case *ir.InstInsertElement:
+		// INSERTELT: [opval, opval, opval]
+		r := w.newRecord(funcCodeInstInsertElt)
+		r.valueTypePair(inst.X)
+		r.value(inst.Elem)
+		r.valueTypePair(inst.Index)
+		return r.write()
+	case *ir.InstShuffleVector:
+		// SHUFFLEVEC: [opval, opval, opval]
+		r := w.newRecord(funcCodeInstShuffleVec)
+		r.valueTypePair(inst.X)
+		r.value(inst.Y)
+		r.valueTypePair(inst.Mask)
+		return r.write()
+	// Aggregate instructions.
+	case *ir.InstExtractValue:
+		// EXTRACTVAL: [opval, n x indices]
+		r := w.newRecord(funcCodeInstExtractVal)
+		r.valueTypePair(inst.X)
+		r.uint(inst.Indices...)
+		return r.write()
+	case *ir.InstInsertValue:
+		// INSERTVAL: [opval, opval, n x indices]
+		r := w.newRecord(funcCodeInstInsertVal)
+		r.valueTypePair(inst.X)
+		r.valueTypePair(inst.Elem)
+		r.uint(inst.Indices...)
+		return r.write()
+	// Memory instructions.
+	case *ir.InstAlloca:
+		return fw.writeAlloca(inst)
+	case *ir.InstLoad:
+		// LOAD: [op, ty, align, vol]
+		// LOAD_ATOMIC: [op, ty, align, vol, ordering, ssid]
+		r := w.newRecord(funcCodeInstLoad)
+		r.valueTypePair(inst.Src)
+		r.typ(inst.ElemType)
+		r.uint(bcAlign(inst.Align))
+		r.bool(inst.Volatile)
+		if inst.Atomic {
+			r.code = funcCodeInstLoadAtomic
+			fw.atomic(r, inst.Ordering, inst.SyncScope)
+		}
+		return r.write()
+	case *ir.InstStore:
+		// STORE: [ptr, val, align, vol]
+		// STORE_ATOMIC: [ptr, val, align, vol, ordering, ssid]
+		r := w.newRecord(funcCodeInstStore)
+		r.valueTypePair(inst.Dst)
+		r.valueTypePair(inst.Src)
+		r.uint(bcAlign(inst.Align))
+		r.bool(inst.Volatile)
+		if inst.Atomic {
+			r.code = funcCodeInstStoreAtomic
+			fw.atomic(r, inst.Ordering, inst.SyncScope)
+		}
+		return r.write()
+	case *ir.InstFence:
+		// FENCE: [ordering, ssid]
+		r := w.newRecord(funcCodeInstFence)
+		fw.atomic(r, inst.Ordering, inst.SyncScope)
+		return r.write()
+	case *ir.InstCmpXchg:
+		// CMPXCHG: [ptr, cmp, new, vol, success_ordering, ssid,
+		//           failure_ordering, weak]
+		r := w.newRecord(funcCodeInstCmpXchg)
+		r.valueTypePair(inst.Ptr)
+		r.valueTypePair(inst.Cmp)
+		r.value(inst.New)
+		r.bool(inst.Volatile)
+		fw.atomic(r, inst.SuccessOrdering, inst.SyncScope)
+		failureOrdering, err := bcAtomicOrdering(inst.FailureOrdering)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		r.uint(failureOrdering)
+		r.bool(inst.Weak)
+		return r.write()
+	case *ir.InstAtomicRMW:
+		// ATOMICRMW: [ptr, val, op, vol, ordering, ssid]
+		r := w.newRecord(funcCodeInstAtomicRMW)
+		r.valueTypePair(inst.Dst)
+		r.valueTypePair(inst.X)
+		op, err := bcAtomicOp(inst.Op)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		r.uint(op)
+		r.bool(inst.Volatile)
+		fw.atomic(r, inst.Ordering, inst.SyncScope)
+		return r.write()
+	case *ir.InstGetElementPtr:
+		// GEP: [inbounds, ty, n x operands]
+		r := w.newRecord(funcCodeInstGEP)
+		r.bool(inst.InBounds)
+		r.typ(inst.ElemType)
+		r.valueTypePair(inst.Src)
+		for _, index := range inst.Indices {
+			r.valueTypePair(index)
+		}
+		return r.write()
+	// Conversion instructions.
+	case *ir.InstTrunc:
+		return fw.writeCast(castTrunc, inst.From, inst.To)
+	case *ir.InstZExt:
+		return fw.writeCast(castZExt, inst.From, inst.To)
+	case *ir.InstSExt:
+		return fw.writeCast(castSExt, inst.From, inst.To)
+	case *ir.InstFPTrunc:
+		return fw.writeCast(castFPTrunc, inst.From, inst.To)
+	case *ir.InstFPExt:
+		return fw.writeCast(castFPExt, inst.From, inst.To)
+	case *ir.InstFPToUI:
+		return fw.writeCast(castFPToUI, inst.From, inst.To)
+	case *ir.InstFPToSI:
+		return fw.writeCast(castFPToSI, inst.From, inst.To)
+	case *ir.InstUIToFP:
+		return fw.writeCast(castUIToFP, inst.From, inst.To)
+	case *ir.InstSIToFP:
+		return fw.writeCast(castSIToFP, inst.From, inst.To)
+	case *ir.InstPtrToInt:
+		return fw.writeCast(castPtrToInt, inst.From, inst.To)
+	case *ir.InstIntToPtr:
+		return fw.writeCast(castIntToPtr, inst.From, inst.To)
+	case *ir.InstBitCast:
+		return fw.writeCast(castBitCast, inst.From, inst.To)
+	case *ir.InstAddrSpaceCast:
+		return fw.writeCast(castAddrSpaceCast, inst.From, inst.To)
+	// Other instructions.
+	case *ir.InstICmp:
+		pred, err := bcIPred(inst.Pred)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return fw.writeCmp(pred, inst.X, inst.Y, 0)
+	case *ir.InstFCmp:
+		pred, err := bcFPred(inst.Pred)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return fw.writeCmp(pred, inst.X, inst.Y, bcFastMathFlags(inst.FastMathFlags))
+	case *ir.InstPhi:
+		// PHI: [ty, n x [val, bb#], flags?]
+		r := w.newRecord(funcCodeInstPhi)
+		r.typ(inst.Typ)
+		for _, inc := range inst.Incs {
+			r.signedValue(inc.X)
+			r.block(inc.Pred)
+		}
+		if flags := bcFastMathFlags(inst.FastMathFlags); flags != 0 {
+			r.uint(flags)
+		}
+		return r.write()
+	case *ir.InstSelect:
+		// VSELECT: [opval, opval, pred, flags?]
+		r := w.newRecord(funcCodeInstVSelect)
+		r.valueTypePair(inst.ValueTrue)
+		r.value(inst.ValueFalse)
+		r.valueTypePair(inst.Cond)
+		if flags := bcFastMathFlags(inst.FastMathFlags); flags != 0 {
+			r.uint(flags)
+		}
+		return r.write()
+	case *ir.InstFreeze:
+		// FREEZE: [opval]
+		r := w.newRecord(funcCodeInstFreeze)
+		r.valueTypePair(inst.X)
+		return r.write()
+	case *ir.InstCall:
+		return fw.writeCall(inst)
+	case *ir.InstVAArg:
+		// VAARG: [valistty, valist, instty]
+		r := w.newRecord(funcCodeInstVAArg)
+		r.typ(inst.ArgList.Type())
+		r.value(inst.ArgList)
+		r.typ(inst.ArgType)
+		return r.write()
+	case *ir.InstLandingPad:
+		// LANDINGPAD: [ty, iscleanup, num, n x [clausetype, val]]
+		r := w.newRecord(funcCodeInstLandingPad)
+		r.typ(inst.ResultType)
+		r.bool(inst.Cleanup)
+		r.uint(uint64(len(inst.Clauses)))
+		for _, clause := range inst.Clauses {
+			switch clause.Type {
+			case enum.ClauseTypeCatch:
+				r.uint(0)
+			case enum.ClauseTypeFilter:
+				r.uint(1)
+			default:
+				return errors.Errorf("support for landingpad clause type %v not yet implemented", clause.Type)
+			}
+			r.valueTypePair(clause.X)
+		}
+		return r.write()
+	case *ir.InstCatchPad:
+		// CATCHPAD: [parentpad, num, n x args]
+		return fw.writePad(funcCodeInstCatchPad, inst.CatchSwitch, inst.Args)
+	case *ir.InstCleanupPad:
+		// CLEANUPPAD: [parentpad, num, n x args]
+		return fw.writePad(funcCodeInstCleanupPad, inst.ParentPad, inst.Args)
+	default:
+		return errors.Errorf("support for instruction %T not yet implemented", inst)
+	}
+}
+
+// writeBinary writes the record of a binary instruction.
+//
+//	BINOP: [opval, opval, opcode, flags?]
+func (fw *funcWriter) writeBinary(opcode uint64, x, y value.Value, flags uint64) error {
+	r := fw.w.newRecord(funcCodeInstBinop)
+	r.valueTypePair(x)
+	r.value(y)
+	r.uint(opcode)
+	if flags != 0 {
+		r.uint(flags)
+	}
+	return r.write()
+}
+
+// writeCast writes the record of a conversion instruction.
+//
+//	CAST: [opval, destty, castopc]
+func (fw *funcWriter) writeCast(opcode uint64, from value.Value, to types.Type) error {
+	r := fw.w.newRecord(funcCodeInstCast)
+	r.valueTypePair(from)
+	r.typ(to)
+	r.uint(opcode)
+	return r.write()
+}
+
+// writeCmp writes the record of a comparison instruction.
+//
+//	CMP2: [opval, opval, pred, flags?]
+func (fw *funcWriter) writeCmp(pred uint64, x, y value.Value, flags uint64) error {
+	r := fw.w.newRecord(funcCodeInstCmp2)
+	r.valueTypePair(x)
+	r.value(y)
+	r.uint(pred)
+	if flags != 0 {
+		r.uint(flags)
+	}
+	return r.write()
+}
+
+// writePad writes the record of an exception pad instruction.
+//
+//	[parentpad, num, n x args]
+func (fw *funcWriter) writePad(code uint64, parentPad value.Value, args []value.Value) error {
+	r := fw.w.newRecord(code)
+	r.value(parentPad)
+	r.uint(uint64(len(args)))
+	for _, arg := range args {
+		r.valueTypePair(arg)
+	}
+	return r.write()
+}
+
+// writeAlloca writes the record of the given alloca instruction.
+//
+//	ALLOCA: [instty, opty, op, align, addrspace?]
+func (fw *funcWriter) writeAlloca(inst *ir.InstAlloca) error {
+	r := fw.w.newRecord(funcCodeInstAlloca)
+	r.typ(inst.ElemType)
+	nelems := inst.NElems
+	if nelems == nil {
+		nelems = fw.w.one
+	}
+	r.typ(nelems.Type())
+	// Note, the number of elements is stored as an absolute value ID.
+	r.absValue(nelems)
+	align := bcAlign(inst.Align)
+	packed := align&allocaAlignLowerMask | align>>5<<allocaAlignUpperBit | allocaExplicitType
+	if inst.InAlloca {
+		packed |= allocaInAlloca
+	}
+	if inst.SwiftError {
+		packed |= allocaSwiftError
+	}
+	r.uint(packed)
+	if inst.AddrSpace != 0 {
+		r.uint(uint64(inst.AddrSpace))
+	}
+	return r.write()
+}
+
+// atomic appends the atomic ordering and synchronization scope operands of an
+// atomic memory instruction.
+func (fw *funcWriter) atomic(r *record, ordering enum.AtomicOrdering, syncScope string) {
+	v, err := bcAtomicOrdering(ordering)
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	r.uint(v, fw.w.syncScopes.id(syncScope))
+}
+
+// writeCall writes the record of the given call instruction, preceded by its
+// operand bundles.
+//
+//	CALL: [paramattrs, cc, fmf?, fnty, fnid, args...]
+func (fw *funcWriter) writeCall(inst *ir.InstCall) error {
+	if err := fw.writeOperandBundles(inst.OperandBundles); err != nil {
+		return errors.WithStack(err)
+	}
+	attrs, err := fw.callAttrs(inst.FuncAttrs, inst.ReturnAttrs, inst.Args)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r := fw.w.newRecord(funcCodeInstCall)
+	r.uint(attrs)
+	ccInfo := bcCallingConv(inst.CallingConv)<<callCConv | 1<<callExplicitType
+	switch inst.Tail {
+	case enum.TailTail:
+		ccInfo |= 1 << callTail
+	case enum.TailMustTail:
+		ccInfo |= 1<<callMustTail | 1<<callTail
+	case enum.TailNoTail:
+		ccInfo |= 1 << callNoTail
+	}
+	fmf := bcFastMathFlags(inst.FastMathFlags)
+	if fmf != 0 {
+		ccInfo |= 1 << callFMF
+	}
+	r.uint(ccInfo)
+	if fmf != 0 {
+		r.uint(fmf)
+	}
+	sig := inst.Sig()
+	r.typ(sig)
+	r.valueTypePair(inst.Callee)
+	r.callArgs(sig, inst.Args)
+	return r.write()
+}
+
+// --- [ Terminators ] ---------------------------------------------------------
+
+// writeTerm writes the record of the given terminator.
+func (fw *funcWriter) writeTerm(term ir.Terminator) error {
+	w := fw.w
+	switch term := term.(type) {
+	case *ir.TermRet:
+		// RET: [opval?]
+		r := w.newRecord(funcCodeInstRet)
+		if term.X != nil {
+			r.valueTypePair(term.X)
+		}
+		return r.write()
+	case *ir.TermBr:
+		// BR: [bb#]
+		r := w.newRecord(funcCodeInstBr)
+		r.block(term.Target)
+		return r.write()
+	case *ir.TermCondBr:
+		// BR: [bb#, bb#, cond]
+		r := w.newRecord(funcCodeInstBr)
+		r.block(term.TargetTrue)
+		r.block(term.TargetFalse)
+		r.value(term.Cond)
+		return r.write()
+	case *ir.TermSwitch:
+		// SWITCH: [opty, cond, defaultbb#, n x [caseval, bb#]]
+		r := w.newRecord(funcCodeInstSwitch)
+		r.typ(term.X.Type())
+		r.value(term.X)
+		r.block(term.TargetDefault)
+		for _, c := range term.Cases {
+			// Note, case values are stored as absolute value IDs.
+			r.absValue(c.X)
+			r.block(c.Target)
+		}
+		return r.write()
+	case *ir.TermIndirectBr:
+		// INDIRECTBR: [opty, op, n x bb#]
+		r := w.newRecord(funcCodeInstIndirectBr)
+		r.typ(term.Addr.Type())
+		r.value(term.Addr)
+		for _, target := range term.ValidTargets {
+			r.block(target)
+		}
+		return r.write()
+	case *ir.TermInvoke:
+		// INVOKE: [attrs, cc, normbb#, unwindbb#, fnty, fnid, args...]
+		if err := fw.writeOperandBundles(term.OperandBundles); err != nil {
+			return errors.WithStack(err)
+		}
+		attrs, err := fw.callAttrs(term.FuncAttrs, term.ReturnAttrs, term.Args)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		r := w.newRecord(funcCodeInstInvoke)
+		r.uint(attrs, bcCallingConv(term.CallingConv)|explicitTypeFlag)
+		r.block(term.NormalRetTarget)
+		r.block(term.ExceptionRetTarget)
+		sig := term.Sig()
+		r.typ(sig)
+		r.valueTypePair(term.Invokee)
+		r.callArgs(sig, term.Args)
+		return r.write()
+	case *ir.TermCallBr:
+		// CALLBR: [attrs, cc, normbb#, numindirect, n x indirectbb#, fnty,
+		//          fnid, args...]
+		if err := fw.writeOperandBundles(term.OperandBundles); err != nil {
+			return errors.WithStack(err)
+		}
+		attrs, err := fw.callAttrs(term.FuncAttrs, term.ReturnAttrs, term.Args)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		r := w.newRecord(funcCodeInstCallBr)
+		r.uint(attrs, bcCallingConv(term.CallingConv)<<callCConv|1<<callExplicitType)
+		r.block(term.NormalRetTarget)
+		r.uint(uint64(len(term.OtherRetTargets)))
+		for _, target := range term.OtherRetTargets {
+			r.block(target)
+		}
+		sig := term.Sig()
+		r.typ(sig)
+		r.valueTypePair(term.Callee)
+		r.callArgs(sig, term.Args)
+		return r.write()
+	case *ir.TermResume:
+		// RESUME: [opval]
+		r := w.newRecord(funcCodeInstResume)
+		r.valueTypePair(term.X)
+		return r.write()
+	case *ir.TermCatchSwitch:
+		// CATCHSWITCH: [parentpad, num, n x bb#, unwinddest?]
+		r := w.newRecord(funcCodeInstCatchSwitch)
+		r.value(term.ParentPad)
+		r.uint(uint64(len(term.Handlers)))
+		for _, handler := range term.Handlers {
+			r.block(handler)
+		}
+		if term.DefaultUnwindTarget != nil {
+			r.block(term.DefaultUnwindTarget)
+		}
+		return r.write()
+	case *ir.TermCatchRet:
+		// CATCHRET: [catchpad, bb#]
+		r := w.newRecord(funcCodeInstCatchRet)
+		r.value(term.CatchPad)
+		r.block(term.Target)
+		return r.write()
+	case *ir.TermCleanupRet:
+		// CLEANUPRET: [cleanuppad, bb#?]
+		r := w.newRecord(funcCodeInstCleanupRet)
+		r.value(term.CleanupPad)
+		if term.UnwindTarget != nil {
+			r.block(term.UnwindTarget)
+		}
+		return r.write()
+	case *ir.TermUnreachable:
+		w.writeRecord(funcCodeInstUnreachable)
+		return nil
+	default:
+		return errors.Errorf("support for terminator %T not yet implemented", term)
+	}
+}
+
+// writeOperandBundles writes the operand bundle records of a call instruction.
+//
+//	OPERAND_BUNDLE: [tag, n x (ty, val)]
+func (fw *funcWriter) writeOperandBundles(bundles []*ir.OperandBundle) error {
+	for _, bundle := range bundles {
+		r := fw.w.newRecord(funcCodeOperandBundle)
+		r.uint(fw.w.bundleTags.id(bundle.Tag))
+		for _, input := range bundle.Inputs {
+			r.valueTypePair(input)
+		}
+		if err := r.write(); err != nil {
+			return errors.Wrapf(err, "unable to write operand bundle %q", bundle.Tag)
+		}
+	}
+	return nil
+}
+
+// callAttrs returns the attribute list ID of a call instruction with the given
+// function and return attributes and arguments.
+func (fw *funcWriter) callAttrs(funcAttrs []ir.FuncAttribute, retAttrs []ir.ReturnAttribute, args []value.Value) (uint64, error) {
+	var paramAttrs [][]ir.ParamAttribute
+	for _, arg := range args {
+		var attrs []ir.ParamAttribute
+		if arg, ok := arg.(*ir.Arg); ok {
+			attrs = arg.Attrs
+		}
+		paramAttrs = append(paramAttrs, attrs)
+	}
+	return fw.w.attrList(funcAttrs, retAttrs, paramAttrs)
+}
+
+// --- [ Operands ] ------------------------------------------------------------
+
+// relID returns the value ID of the given value relative to the value ID of
+// the next instruction, and reports whether it is a forward reference.
+// Metadata values are referred to by metadata ID.
+func (r *record) relID(v value.Value) (rel uint64, fwd bool, ok bool) {
+	fw := r.w.fw
+	var id uint64
+	var err error
+	if md, isMD := unwrapValue(v).(*metadata.Value); isMD {
+		id, err = r.w.mdID(r.w.mdEntry(md))
+	} else {
+		id, err = r.w.valueID(v)
+	}
+	if err != nil {
+		r.fail(err)
+		return 0, false, false
+	}
+	return uint64(uint32(fw.nextID - id)), id >= fw.nextID, true
+}
+
+// value appends the relative value ID of the given value.
+func (r *record) value(v value.Value) {
+	if rel, _, ok := r.relID(v); ok {
+		r.uint(rel)
+	}
+}
+
+// valueTypePair appends the relative value ID of the given value, followed by
+// its type if forward referenced.
+func (r *record) valueTypePair(v value.Value) {
+	rel, fwd, ok := r.relID(v)
+	if !ok {
+		return
+	}
+	r.uint(rel)
+	if fwd {
+		r.typ(v.Type())
+	}
+}
+
+// signedValue appends the signed relative value ID of the given value, as used
+// by phi instructions.
+func (r *record) signedValue(v value.Value) {
+	id, err := r.w.valueID(v)
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	r.uint(encodeSigned(int64(r.w.fw.nextID) - int64(id)))
+}
+
+// block appends the basic block ID of the given basic block.
+func (r *record) block(v value.Value) {
+	block, ok := v.(*ir.Block)
+	if !ok {
+		r.fail(errors.Errorf("invalid basic block operand; expected *ir.Block, got %T", v))
+		return
+	}
+	id, ok := r.w.fw.blockIDs[block]
+	if !ok {
+		r.fail(errors.Errorf("unable to locate basic block %q in function", block.Ident()))
+		return
+	}
+	r.uint(id)
+}
+
+// callArgs appends the arguments of a call instruction with the given function
+// signature.
+func (r *record) callArgs(sig *types.FuncType, args []value.Value) {
+	for i, arg := range args {
+		switch {
+		case i >= len(sig.Params):
+			// Variadic arguments.
+			r.valueTypePair(arg)
+		case types.Equal(sig.Params[i], types.Label):
+			// Basic block arguments are stored as basic block IDs.
+			r.block(unwrapValue(arg))
+		default:
+			r.value(arg)
+		}
+	}
+}
+
+// ### [ Helper functions ] ####################################################
+
+// callOf returns the function signature, callee and arguments of the given
+// call, invoke or callbr instruction; or a nil signature otherwise.
+func callOf(inst interface{}) (*types.FuncType, value.Value, []value.Value) {
+	switch inst := inst.(type) {
+	case *ir.InstCall:
+		return inst.Sig(), inst.Callee, inst.Args
+	case *ir.TermInvoke:
+		return inst.Sig(), inst.Invokee, inst.Args
+	case *ir.TermCallBr:
+		return inst.Sig(), inst.Callee, inst.Args
+	default:
+		return nil, nil, nil
+	}
+}
+
+// operandBundlesOf returns the operand bundles of the given instruction.
+func operandBundlesOf(inst interface{}) []*ir.OperandBundle {
+	switch inst := inst.(type) {
+	case *ir.InstCall:
+		return inst.OperandBundles
+	case *ir.TermInvoke:
+		return inst.OperandBundles
+	case *ir.TermCallBr:
+		return inst.OperandBundles
+	default:
+		return nil
+	}
+}
+
+// debugLocOf returns the (non-distinct) debug location attached to the given
+// instruction; or nil if not present.
+func debugLocOf(inst mdAttacher) *metadata.DILocation {
+	for _, md := range inst.MDAttachments() {
+		if md.Name != "dbg" {
+			continue
+		}
+		if loc, ok := md.Node.(*metadata.DILocation); ok && loc != nil && !loc.Distinct {
+			return loc
+		}
+		return nil
+	}
+	return nil
+}
+
+// localName returns the name of the given local value, and reports whether it
+// is named.
+func localName(v interface{}) (string, bool) {
+	named, ok := v.(interface {
+		Name() string
+		IsUnnamed() bool
+	})
+	if !ok || named.IsUnnamed() {
+		return "", false
+	}
+	name := named.Name()
+	// Numeric names are quoted to distinguish them from local IDs.
+	if s, err := strconv.Unquote(name); err == nil && isNumeric(s) {
+		return s, true
+	}
+	return name, true
+}
@@ -0,0 +1,752 @@
+package bitcode
+
+import (
+	"sort"
+
+	"github.com/llir/llvm/internal/bitstream"
+	"github.com/llir/llvm/internal/natsort"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/metadata"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+	"github.com/pkg/errors"
+)
+
+// mdString is the metadata entry of a metadata string.
+type mdString string
+
+// mdEntry returns the metadata entry of the given metadata field; or nil if the
+// field is null. Each metadata entry is an mdString, a metadata node or a
+// value.
+func (w *writer) mdEntry(field metadata.Field) interface{} {
+	switch f := field.(type) {
+	case nil, *metadata.NullLit:
+		return nil
+	case *metadata.String:
+		return mdString(f.Value)
+	case *metadata.Value:
+		return w.mdEntry(f.Value)
+	case *ir.Arg:
+		return w.mdEntry(f.Value)
+	case metadata.IntLit:
+		// Integer literals are stored as i64 constants.
+		c, ok := w.intLits[f]
+		if !ok {
+			c = constant.NewInt(types.I64, int64(f))
+			w.intLits[f] = c
+		}
+		return c
+	case metadata.Definition:
+		if isNil(f) {
+			return nil
+		}
+	}
+	return field
+}
+
+// mdID returns the 0-based metadata ID of the given metadata entry, assigning
+// metadata IDs on first use. Function-local metadata is assigned metadata IDs
+// of the function being written.
+func (w *writer) mdID(entry interface{}) (uint64, error) {
+	if entry == nil {
+		return 0, errors.New("invalid null metadata")
+	}
+	if id, ok := w.mdIDs[entry]; ok {
+		return id, nil
+	}
+	if isLocalMD(entry) {
+		if w.fw == nil {
+			return 0, errors.Errorf("invalid use of function-local metadata (%T) outside of function", entry)
+		}
+		return w.fw.mdID(entry), nil
+	}
+	// Constants of metadata values are stored at module-level.
+	if c, ok := entry.(constant.Constant); ok {
+		if _, ok := w.valueIDs[c]; !ok {
+			w.addConst(c)
+		}
+	}
+	id := uint64(len(w.mds))
+	w.mds = append(w.mds, entry)
+	w.mdIDs[entry] = id
+	return id, nil
+}
+
+// sortMetadata sorts the module-level metadata, so that metadata strings are
+// stored before other metadata.
+func (w *writer) sortMetadata() {
+	sort.SliceStable(w.mds, func(i, j int) bool {
+		_, si := w.mds[i].(mdString)
+		_, sj := w.mds[j].(mdString)
+		return si && !sj
+	})
+	for id, entry := range w.mds {
+		w.mdIDs[entry] = uint64(id)
+	}
+}
+
+// writeMetadataKinds writes the metadata kind block.
+//
+//	KIND: [n x [id, name]]
+func (w *writer) writeMetadataKinds() error {
+	w.bs.EnterBlock(blockIDMetadataKind, 3)
+	for id, name := range w.mdKinds.names {
+		ops := append([]uint64{uint64(id)}, stringOps(name)...)
+		w.writeRecord(metadataCodeKind, ops...)
+	}
+	return w.bs.ExitBlock()
+}
+
+// writeModuleMetadata writes the module-level metadata block.
+//
+//	NAME: [namechar x N]
+//	NAMED_NODE: [n x mdnodes]
+//	GLOBAL_DECL_ATTACHMENT: [valueid, n x [id, mdnode]]
+func (w *writer) writeModuleMetadata() error {
+	// Assign metadata IDs to the metadata attachments of global variables and
+	// function declarations, and to named metadata.
+	var attachments [][]uint64
+	addAttachments := func(v value.Value, mds []*metadata.Attachment) error {
+		if len(mds) == 0 {
+			return nil
+		}
+		id, err := w.valueID(v)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		ops, err := w.mdAttachments(mds)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		attachments = append(attachments, append([]uint64{id}, ops...))
+		return nil
+	}
+	for _, g := range w.m.Globals {
+		if err := addAttachments(g, g.Metadata); err != nil {
+			return errors.Wrapf(err, "unable to write metadata attachments of global variable %q", g.Ident())
+		}
+	}
+	for _, f := range w.m.Funcs {
+		if len(f.Blocks) > 0 {
+			// Metadata attachments of function definitions are stored in the
+			// function block.
+			continue
+		}
+		if err := addAttachments(f, f.Metadata); err != nil {
+			return errors.Wrapf(err, "unable to write metadata attachments of function %q", f.Ident())
+		}
+	}
+	var names []string
+	for name := range w.m.NamedMetadataDefs {
+		names = append(names, name)
+	}
+	natsort.Strings(names)
+	// Named metadata is not ordered in the in-memory representation; keep the
+	// order of their first metadata node, as assigned by the slot tracker when
+	// parsed from LLVM IR assembly or bitcode.
+	sort.SliceStable(names, func(i, j int) bool {
+		return firstNodeID(w.m.NamedMetadataDefs[names[i]]) < firstNodeID(w.m.NamedMetadataDefs[names[j]])
+	})
+	namedNodes := make([][]uint64, len(names))
+	for i, name := range names {
+		for _, node := range w.m.NamedMetadataDefs[name].Nodes {
+			field, ok := node.(metadata.Field)
+			if !ok {
+				return errors.Errorf("invalid node of named metadata %q; expected metadata.Field, got %T", name, node)
+			}
+			id, err := w.mdID(w.mdEntry(field))
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			namedNodes[i] = append(namedNodes[i], id)
+		}
+	}
+	// Metadata discovered while writing the metadata block is written by the
+	// next pass.
+	mds := w.mds[:len(w.mds):len(w.mds)]
+	if len(mds) == 0 && len(names) == 0 && len(attachments) == 0 {
+		return nil
+	}
+	w.bs.EnterBlock(blockIDMetadata, 3)
+	if err := w.writeMetadataEntries(mds); err != nil {
+		return errors.WithStack(err)
+	}
+	for i, name := range names {
+		w.writeRecord(metadataCodeName, stringOps(name)...)
+		w.writeRecord(metadataCodeNamedNode, namedNodes[i]...)
+	}
+	for _, ops := range attachments {
+		w.writeRecord(metadataCodeGlobalDeclAttachment, ops...)
+	}
+	return w.bs.ExitBlock()
+}
+
+// writeMetadataEntries writes the records of the given metadata entries. The
+// leading metadata strings are stored in a single METADATA_STRINGS record.
+//
+//	STRINGS: [count, offsetsize] blob([lengths][chars])
+func (w *writer) writeMetadataEntries(mds []interface{}) error {
+	var (
+		lengths []uint64
+		chars   []byte
+	)
+	for _, entry := range mds {
+		s, ok := entry.(mdString)
+		if !ok {
+			break
+		}
+		lengths = append(lengths, uint64(len(s)))
+		chars = append(chars, s...)
+	}
+	if len(lengths) > 0 {
+		abbrev := &bitstream.Abbrev{Ops: []bitstream.AbbrevOp{
+			{Kind: bitstream.OpLiteral, Value: metadataCodeStrings},
+			{Kind: bitstream.OpVBR, Value: 6},
+			{Kind: bitstream.OpVBR, Value: 6},
+			{Kind: bitstream.OpBlob},
+		}}
+		abbrevID, err := w.bs.DefineAbbrev(abbrev)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		blob := bitstream.WriteVBRs(lengths, 6)
+		offset := uint64(len(blob))
+		record := &bitstream.Record{
+			Code: metadataCodeStrings,
+			Ops:  []uint64{uint64(len(lengths)), offset},
+			Blob: append(blob, chars...),
+		}
+		if err := w.bs.WriteAbbrevRecord(abbrevID, record); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	for _, entry := range mds[len(lengths):] {
+		if err := w.writeMDEntry(entry); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// mdAttachments returns the operands of the given metadata attachments.
+//
+//	[n x [id, mdnode]]
+func (w *writer) mdAttachments(mds []*metadata.Attachment) ([]uint64, error) {
+	var ops []uint64
+	for _, md := range mds {
+		field, ok := md.Node.(metadata.Field)
+		if !ok {
+			return nil, errors.Errorf("invalid metadata attachment %q; expected metadata.Field, got %T", md.Name, md.Node)
+		}
+		id, err := w.mdID(w.mdEntry(field))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		ops = append(ops, w.mdKinds.id(md.Name), id)
+	}
+	return ops, nil
+}
+
+// --- [ Metadata entries ] ----------------------------------------------------
+
+// writeMDEntry writes the metadata record of the given metadata entry.
+func (w *writer) writeMDEntry(entry interface{}) error {
+	switch entry := entry.(type) {
+	case mdString:
+		// Metadata strings following other metadata (only present in passes
+		// which are later redone).
+		//
+		//	STRING_OLD: [strchr x N]
+		w.writeRecord(metadataCodeStringOld, stringOps(string(entry))...)
+		return nil
+	case *metadata.DIArgList:
+		// ARG_LIST: [n x [md num]]
+		r := w.newRecord(metadataCodeArgList)
+		for _, v := range entry.Fields {
+			r.mdNode(v)
+		}
+		return r.write()
+	case value.Value:
+		// VALUE: [ty, val]
+		r := w.newRecord(metadataCodeValue)
+		r.typ(entry.Type())
+		r.absValue(entry)
+		return r.write()
+	case metadata.Field:
+		if err := w.writeMDNode(entry); err != nil {
+			return errors.Wrapf(err, "unable to write metadata node %q", entry.String())
+		}
+		return nil
+	default:
+		panic(errors.Errorf("support for metadata entry %T not yet implemented", entry))
+	}
+}
+
+// writeMDNode writes the metadata record of the given metadata node.
+func (w *writer) writeMDNode(node metadata.Field) error {
+	switch md := node.(type) {
+	case *metadata.Tuple:
+		// NODE: [n x md num]
+		code := uint64(metadataCodeNode)
+		if md.Distinct {
+			code = metadataCodeDistinctNode
+		}
+		r := w.newRecord(code)
+		for _, field := range md.Fields {
+			r.md(field)
+		}
+		return r.write()
+	case *metadata.DILocation:
+		// [distinct, line, col, scope, inlined-at?, isImplicitCode]
+		r := w.newRecord(metadataCodeLocation)
+		r.bool(md.Distinct)
+		r.uint(uint64(md.Line), uint64(md.Column))
+		r.mdNode(md.Scope)
+		r.md(md.InlinedAt)
+		r.bool(md.IsImplicitCode)
+		return r.write()
+	case *metadata.GenericDINode:
+		// [distinct, tag, version, header, n x md num]
+		r := w.newRecord(metadataCodeGenericDebug)
+		r.bool(md.Distinct)
+		r.uint(uint64(md.Tag), 0)
+		r.mdString(md.Header)
+		for _, field := range md.Operands {
+			r.md(field)
+		}
+		return r.write()
+	case *metadata.DISubrange:
+		// [distinct|2<<1, count, lo, up, stride]
+		r := w.newRecord(metadataCodeSubrange)
+		r.uint(boolOp(md.Distinct) | 2<<1)
+		r.md(md.Count)
+		r.md(md.LowerBound)
+		r.md(md.UpperBound)
+		r.md(md.Stride)
+		return r.write()
+	case *metadata.DIEnumerator:
+		// [isBigInt<<2|isUnsigned<<1|distinct, bitwidth, name, n x word]
+		r := w.newRecord(metadataCodeEnumerator)
+		r.uint(1<<2|boolOp(md.IsUnsigned)<<1|boolOp(md.Distinct), 64)
+		r.mdString(md.Name)
+		r.uint(encodeSigned(md.Value))
+		return r.write()
+	case *metadata.DIBasicType:
+		// [distinct, tag, name, size, align, encoding, flags]
+		r := w.newRecord(metadataCodeBasicType)
+		r.bool(md.Distinct)
+		tag := md.Tag
+		if tag == 0 {
+			tag = enum.DwarfTagBaseType
+		}
+		r.uint(uint64(tag))
+		r.mdString(md.Name)
+		r.uint(md.Size, md.Align, uint64(md.Encoding), uint64(md.Flags))
+		return r.write()
+	case *metadata.DIStringType:
+		// [distinct, tag, name, stringLength, stringLengthExp,
+		//  stringLocationExp, size, align, encoding]
+		r := w.newRecord(metadataCodeStringType)
+		r.bool(md.Distinct)
+		r.uint(uint64(md.Tag))
+		r.mdString(md.Name)
+		r.md(md.StringLength)
+		r.md(md.StringLengthExpression)
+		r.md(md.StringLocationExpression)
+		r.uint(md.Size, md.Align, uint64(md.Encoding))
+		return r.write()
+	case *metadata.DIFile:
+		// [distinct, filename, directory, checksumkind, checksum, source]
+		r := w.newRecord(metadataCodeFile)
+		r.bool(md.Distinct)
+		r.mdString(md.Filename)
+		r.mdString(md.Directory)
+		if md.Checksumkind != 0 {
+			r.uint(uint64(md.Checksumkind))
+			r.mdString(md.Checksum)
+		} else {
+			r.uint(0, 0)
+		}
+		// The source operand is only present if the source is specified, as an
+		// empty source differs from no source in LLVM.
+		if len(md.Source) > 0 {
+			r.mdString(md.Source)
+		}
+		return r.write()
+	case *metadata.DIDerivedType:
+		// [distinct, tag, name, file, line, scope, baseType, size, align,
+		//  offset, flags, extraData, dwarfAddressSpace+1, annotations]
+		r := w.newRecord(metadataCodeDerivedType)
+		r.bool(md.Distinct)
+		r.uint(uint64(md.Tag))
+		r.mdString(md.Name)
+		r.md(md.File)
+		r.uint(uint64(md.Line))
+		r.md(md.Scope)
+		r.md(md.BaseType)
+		r.uint(md.Size, md.Align, md.Offset, uint64(md.Flags))
+		r.md(md.ExtraData)
+		if md.DwarfAddressSpace != 0 {
+			r.uint(md.DwarfAddressSpace + 1)
+		} else {
+			r.uint(0)
+		}
+		r.md(md.Annotations)
+		return r.write()
+	case *metadata.DICompositeType:
+		// [distinct|notUsedInOldTypeRef<<1, tag, name, file, line, scope,
+		//  baseType, size, align, offset, flags, elements, runtimeLang,
+		//  vtableHolder, templateParams, identifier, discriminator,
+		//  dataLocation, associated, allocated, rank, annotations]
+		r := w.newRecord(metadataCodeCompositeType)
+		r.uint(boolOp(md.Distinct) | 1<<1)
+		r.uint(uint64(md.Tag))
+		r.mdString(md.Name)
+		r.md(md.File)
+		r.uint(uint64(md.Line))
+		r.md(md.Scope)
+		r.md(md.BaseType)
+		r.uint(md.Size, md.Align, md.Offset, uint64(md.Flags))
+		r.md(md.Elements)
+		r.uint(uint64(md.RuntimeLang))
+		r.md(md.VtableHolder)
+		r.md(md.TemplateParams)
+		r.mdString(md.Identifier)
+		r.md(md.Discriminator)
+		r.md(md.DataLocation)
+		r.md(md.Associated)
+		r.md(md.Allocated)
+		r.md(md.Rank)
+		r.md(md.Annotations)
+		return r.write()
+	case *metadata.DISubroutineType:
+		// [distinct|hasNoOldTypeRefs<<1, flags, types, cc]
+		r := w.newRecord(metadataCodeSubroutineType)
+		r.uint(boolOp(md.Distinct)|1<<1, uint64(md.Flags))
+		r.md(md.Types)
+		r.uint(uint64(md.CC))
+		return r.write()
+	case *metadata.DICompileUnit:
+		// [distinct, lang, file, producer, isOpt, flags, runtimeVersion,
+		//  splitDebugFilename, emissionKind, enums, retainedTypes,
+		//  subprograms, globals, imports, dwoId, macros, splitDebugInlining,
+		//  debugInfoForProfiling, nameTableKind, rangesBaseAddress, sysroot,
+		//  sdk]
+		r := w.newRecord(metadataCodeCompileUnit)
+		r.bool(md.Distinct)
+		r.uint(uint64(md.Language))
+		r.md(md.File)
+		r.mdString(md.Producer)
+		r.bool(md.IsOptimized)
+		r.mdString(md.Flags)
+		r.uint(md.RuntimeVersion)
+		r.mdString(md.SplitDebugFilename)
+		r.uint(uint64(md.EmissionKind))
+		r.md(md.Enums)
+		r.md(md.RetainedTypes)
+		// Subprograms refer to their compile unit, and not the other way around.
+		r.uint(0)
+		r.md(md.Globals)
+		r.md(md.Imports)
+		r.uint(md.DwoID)
+		r.md(md.Macros)
+		// splitDebugInlining defaults to true in LLVM.
+		r.uint(1)
+		r.bool(md.DebugInfoForProfiling)
+		r.uint(uint64(md.NameTableKind))
+		r.bool(md.RangesBaseAddress)
+		r.mdString(md.Sysroot)
+		r.mdString(md.SDK)
+		return r.write()
+	case *metadata.DISubprogram:
+		// [distinct|hasUnit<<1|hasSPFlags<<2, scope, name, linkageName, file,
+		//  line, type, scopeLine, containingType, spFlags, virtualIndex,
+		//  flags, unit, templateParams, declaration, retainedNodes,
+		//  thisAdjustment, thrownTypes, annotations]
+		r := w.newRecord(metadataCodeSubprogram)
+		r.uint(boolOp(md.Distinct) | 1<<1 | 1<<2)
+		r.md(md.Scope)
+		r.mdString(md.Name)
+		r.mdString(md.LinkageName)
+		r.md(md.File)
+		r.uint(uint64(md.Line))
+		r.md(md.Type)
+		r.uint(uint64(md.ScopeLine))
+		r.md(md.ContainingType)
+		r.uint(uint64(spFlags(md)), md.VirtualIndex, uint64(md.Flags))
+		r.md(md.Unit)
+		r.md(md.TemplateParams)
+		r.md(md.Declaration)
+		r.md(md.RetainedNodes)
+		r.uint(uint64(md.ThisAdjustment))
+		r.md(md.ThrownTypes)
+		r.md(md.Annotations)
+		return r.write()
+	case *metadata.DILexicalBlock:
+		// [distinct, scope, file, line, column]
+		r := w.newRecord(metadataCodeLexicalBlock)
+		r.bool(md.Distinct)
+		r.md(md.Scope)
+		r.md(md.File)
+		r.uint(uint64(md.Line), uint64(md.Column))
+		return r.write()
+	case *metadata.DILexicalBlockFile:
+		// [distinct, scope, file, discriminator]
+		r := w.newRecord(metadataCodeLexicalBlockFile)
+		r.bool(md.Distinct)
+		r.md(md.Scope)
+		r.md(md.File)
+		r.uint(md.Discriminator)
+		return r.write()
+	case *metadata.DICommonBlock:
+		// [distinct, scope, decl, name, file, line]
+		r := w.newRecord(metadataCodeCommonBlock)
+		r.bool(md.Distinct)
+		r.md(md.Scope)
+		r.md(md.Declaration)
+		r.mdString(md.Name)
+		r.md(md.File)
+		r.uint(uint64(md.Line))
+		return r.write()
+	case *metadata.DINamespace:
+		// [distinct|exportSymbols<<1, scope, name]
+		r := w.newRecord(metadataCodeNamespace)
+		r.uint(boolOp(md.Distinct) | boolOp(md.ExportSymbols)<<1)
+		r.md(md.Scope)
+		r.mdString(md.Name)
+		return r.write()
+	case *metadata.DIMacro:
+		// [distinct, macinfo, line, name, value]
+		r := w.newRecord(metadataCodeMacro)
+		r.bool(md.Distinct)
+		r.uint(uint64(md.Type), uint64(md.Line))
+		r.mdString(md.Name)
+		r.mdString(md.Value)
+		return r.write()
+	case *metadata.DIMacroFile:
+		// [distinct, macinfo, line, file, elements]
+		r := w.newRecord(metadataCodeMacroFile)
+		r.bool(md.Distinct)
+		r.uint(uint64(md.Type), uint64(md.Line))
+		r.md(md.File)
+		r.md(md.Nodes)
+		return r.write()
+	case *metadata.DIModule:
+		// [distinct, file, scope, name, configurationMacros, includePath,
+		//  apinotes, line, isDecl]
+		r := w.newRecord(metadataCodeModule)
+		r.bool(md.Distinct)
+		r.md(md.File)
+		r.md(md.Scope)
+		r.mdString(md.Name)
+		r.mdString(md.ConfigMacros)
+		r.mdString(md.IncludePath)
+		r.mdString(md.APINotes)
+		r.uint(uint64(md.Line))
+		r.bool(md.IsDecl)
+		return r.write()
+	case *metadata.DITemplateTypeParameter:
+		// [distinct, name, type, isDefault]
+		r := w.newRecord(metadataCodeTemplateType)
+		r.bool(md.Distinct)
+		r.mdString(md.Name)
+		r.md(md.Type)
+		r.bool(md.Defaulted)
+		return r.write()
+	case *metadata.DITemplateValueParameter:
+		// [distinct, tag, name, type, isDefault, value]
+		r := w.newRecord(metadataCodeTemplateValue)
+		r.bool(md.Distinct)
+		r.uint(uint64(md.Tag))
+		r.mdString(md.Name)
+		r.md(md.Type)
+		r.bool(md.Defaulted)
+		r.md(md.Value)
+		return r.write()
+	case *metadata.DIGlobalVariable:
+		// [distinct|version<<1, scope, name, linkageName, file, line, type,
+		//  isLocal, isDefinition, staticDataMemberDeclaration, templateParams,
+		//  alignInBits, annotations]
+		r := w.newRecord(metadataCodeGlobalVar)
+		r.uint(boolOp(md.Distinct) | 2<<1)
+		r.md(md.Scope)
+		r.mdString(md.Name)
+		r.mdString(md.LinkageName)
+		r.md(md.File)
+		r.uint(uint64(md.Line))
+		r.md(md.Type)
+		r.bool(md.IsLocal)
+		r.bool(md.IsDefinition)
+		r.md(md.Declaration)
+		r.md(md.TemplateParams)
+		r.uint(md.Align)
+		r.md(md.Annotations)
+		return r.write()
+	case *metadata.DILocalVariable:
+		// [distinct|hasAlignment<<1, scope, name, file, line, type, arg, flags,
+		//  align, annotations]
+		r := w.newRecord(metadataCodeLocalVar)
+		r.uint(boolOp(md.Distinct) | 1<<1)
+		r.md(md.Scope)
+		r.mdString(md.Name)
+		r.md(md.File)
+		r.uint(uint64(md.Line))
+		r.md(md.Type)
+		r.uint(md.Arg, uint64(md.Flags), md.Align)
+		r.md(md.Annotations)
+		return r.write()
+	case *metadata.DILabel:
+		// [distinct, scope, name, file, line]
+		r := w.newRecord(metadataCodeLabel)
+		r.bool(md.Distinct)
+		r.md(md.Scope)
+		r.mdString(md.Name)
+		r.md(md.File)
+		r.uint(uint64(md.Line))
+		return r.write()
+	case *metadata.DIExpression:
+		// [distinct|version<<1, n x element]
+		r := w.newRecord(metadataCodeExpression)
+		r.uint(boolOp(md.Distinct) | 3<<1)
+		for _, field := range md.Fields {
+			switch field := field.(type) {
+			case metadata.UintLit:
+				r.uint(uint64(field))
+			case enum.DwarfOp:
+				r.uint(uint64(field))
+			case enum.DwarfAttEncoding:
+				r.uint(uint64(field))
+			default:
+				return errors.Errorf("support for DIExpression field %T not yet implemented", field)
+			}
+		}
+		return r.write()
+	case *metadata.DIGlobalVariableExpression:
+		// [distinct, var, expr]
+		r := w.newRecord(metadataCodeGlobalVarExpr)
+		r.bool(md.Distinct)
+		r.md(md.Var)
+		r.md(md.Expr)
+		return r.write()
+	case *metadata.DIObjCProperty:
+		// [distinct, name, file, line, getter, setter, attributes, type]
+		r := w.newRecord(metadataCodeObjCProperty)
+		r.bool(md.Distinct)
+		r.mdString(md.Name)
+		r.md(md.File)
+		r.uint(uint64(md.Line))
+		r.mdString(md.Getter)
+		r.mdString(md.Setter)
+		r.uint(md.Attributes)
+		r.md(md.Type)
+		return r.write()
+	case *metadata.DIImportedEntity:
+		// [distinct, tag, scope, entity, line, name, file, elements]
+		r := w.newRecord(metadataCodeImportedEntity)
+		r.bool(md.Distinct)
+		r.uint(uint64(md.Tag))
+		r.md(md.Scope)
+		r.md(md.Entity)
+		r.uint(uint64(md.Line))
+		r.mdString(md.Name)
+		r.md(md.File)
+		r.md(md.Elements)
+		return r.write()
+	default:
+		return errors.Errorf("support for metadata node %T not yet implemented", node)
+	}
+}
+
+// --- [ Metadata operands ] ---------------------------------------------------
+
+// md appends the 1-based metadata ID of the given metadata field; or 0 if null.
+func (r *record) md(field metadata.Field) {
+	entry := r.w.mdEntry(field)
+	if entry == nil {
+		r.uint(0)
+		return
+	}
+	id, err := r.w.mdID(entry)
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	r.uint(id + 1)
+}
+
+// mdNode appends the 0-based metadata ID of the given metadata field.
+func (r *record) mdNode(field metadata.Field) {
+	entry := r.w.mdEntry(field)
+	if entry == nil {
+		r.fail(errors.New("invalid null metadata operand"))
+		return
+	}
+	id, err := r.w.mdID(entry)
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	r.uint(id)
+}
+
+// mdString appends the 1-based metadata ID of the given metadata string; or 0
+// if empty.
+func (r *record) mdString(s string) {
+	if len(s) == 0 {
+		r.uint(0)
+		return
+	}
+	id, err := r.w.mdID(mdString(s))
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	r.uint(id + 1)
+}
+
+// ### [ Helper functions ] ####################################################
+
+// isLocalMD reports whether the given metadata entry is function-local; i.e.
+// a function-local value or an argument list.
+func isLocalMD(entry interface{}) bool {
+	switch entry.(type) {
+	case *metadata.DIArgList:
+		return true
+	case constant.Constant:
+		return false
+	case value.Value:
+		return true
+	default:
+		return false
+	}
+}
+
+// spFlags returns the subprogram flags of the given DISubprogram, including
+// the flags of its (legacy) virtuality, isLocal, isDefinition and isOptimized
+// fields.
+func spFlags(md *metadata.DISubprogram) enum.DISPFlag {
+	flags := md.SPFlags | enum.DISPFlag(md.Virtuality)&enum.DISPFlagVirtuality
+	if md.IsLocal {
+		flags |= enum.DISPFlagLocalToUnit
+	}
+	if md.IsDefinition {
+		flags |= enum.DISPFlagDefinition
+	}
+	if md.IsOptimized {
+		flags |= enum.DISPFlagOptimized
+	}
+	return flags
+}
+
+// firstNodeID returns the metadata ID of the first node of the given named
+// metadata definition, or -1 if not present.
+func firstNodeID(def *metadata.NamedDef) int64 {
+	for _, node := range def.Nodes {
+		if node, ok := node.(metadata.Definition); ok && !isNil(node) {
+			return node.ID()
+		}
+	}
+	return -1
+}
@@ -0,0 +1,180 @@
+package bitcode
+
+import (
+	"github.com/llir/llvm/ir/types"
+	"github.com/pkg/errors"
+)
+
+// typeID returns the type ID of the given type, adding it to the type table if
+// not present.
+//
+// Type records may only refer to types defined earlier in the type table,
+// with the exception of identified struct types. As such, types are assigned
+// type IDs after their element types, and identified struct types are marked
+// as visited while adding their fields to allow for recursive types.
+func (w *writer) typeID(t types.Type) uint64 {
+	key := typeKey(t)
+	if id, ok := w.typeIDs[key]; ok {
+		return id
+	}
+	switch t := t.(type) {
+	case *types.StructType:
+		if len(t.TypeName) > 0 {
+			if w.typeVisiting[key] {
+				// Forward reference; the type ID of the struct type is only used
+				// when writing the type table.
+				return 0
+			}
+			w.typeVisiting[key] = true
+			defer delete(w.typeVisiting, key)
+		}
+		for _, field := range t.Fields {
+			w.typeID(field)
+		}
+	case *types.PointerType:
+		if !t.IsOpaque() {
+			w.typeID(t.ElemType)
+		}
+	case *types.FuncType:
+		w.typeID(t.RetType)
+		for _, param := range t.Params {
+			w.typeID(param)
+		}
+	case *types.ArrayType:
+		w.typeID(t.ElemType)
+	case *types.VectorType:
+		w.typeID(t.ElemType)
+	}
+	id := uint64(len(w.types))
+	w.types = append(w.types, t)
+	w.typeIDs[key] = id
+	return id
+}
+
+// writeTypeTable writes the type table block.
+//
+//	NUMENTRY: [numentries]
+func (w *writer) writeTypeTable() error {
+	w.bs.EnterBlock(blockIDTypeNew, 4)
+	w.writeRecord(typeCodeNumEntry, uint64(len(w.types)))
+	for _, t := range w.types {
+		if err := w.writeType(t); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return w.bs.ExitBlock()
+}
+
+// writeType writes the type record of the given type.
+func (w *writer) writeType(t types.Type) error {
+	switch t := t.(type) {
+	case *types.VoidType:
+		w.writeRecord(typeCodeVoid)
+	case *types.FloatType:
+		switch t.Kind {
+		case types.FloatKindHalf:
+			w.writeRecord(typeCodeHalf)
+		case types.FloatKindFloat:
+			w.writeRecord(typeCodeFloat)
+		case types.FloatKindDouble:
+			w.writeRecord(typeCodeDouble)
+		case types.FloatKindFP128:
+			w.writeRecord(typeCodeFP128)
+		case types.FloatKindX86_FP80:
+			w.writeRecord(typeCodeX86FP80)
+		case types.FloatKindPPC_FP128:
+			w.writeRecord(typeCodePPCFP128)
+		default:
+			return errors.Errorf("support for floating-point kind %v not yet implemented", t.Kind)
+		}
+	case *types.LabelType:
+		w.writeRecord(typeCodeLabel)
+	case *types.MetadataType:
+		w.writeRecord(typeCodeMetadata)
+	case *types.MMXType:
+		w.writeRecord(typeCodeX86MMX)
+	case *types.TokenType:
+		w.writeRecord(typeCodeToken)
+	case *types.IntType:
+		// INTEGER: [width]
+		w.writeRecord(typeCodeInteger, t.BitSize)
+	case *types.PointerType:
+		// OPAQUE_POINTER: [address space]
+		// POINTER: [pointee type, address space]
+		if t.IsOpaque() {
+			w.writeRecord(typeCodeOpaquePointer, uint64(t.AddrSpace))
+			break
+		}
+		w.writeRecord(typeCodePointer, w.typeID(t.ElemType), uint64(t.AddrSpace))
+	case *types.FuncType:
+		// FUNCTION: [vararg, retty, paramty x N]
+		ops := []uint64{boolOp(t.Variadic), w.typeID(t.RetType)}
+		for _, param := range t.Params {
+			ops = append(ops, w.typeID(param))
+		}
+		w.writeRecord(typeCodeFunction, ops...)
+	case *types.ArrayType:
+		// ARRAY: [numelts, eltty]
+		w.writeRecord(typeCodeArray, t.Len, w.typeID(t.ElemType))
+	case *types.VectorType:
+		// VECTOR: [numelts, eltty, scalable]
+		ops := []uint64{t.Len, w.typeID(t.ElemType)}
+		if t.Scalable {
+			ops = append(ops, 1)
+		}
+		w.writeRecord(typeCodeVector, ops...)
+	case *types.StructType:
+		w.writeStructType(t)
+	default:
+		return errors.Errorf("support for type %T not yet implemented", t)
+	}
+	return nil
+}
+
+// writeStructType writes the type records of the given struct type.
+//
+//	STRUCT_ANON: [ispacked, eltty x N]
+//	STRUCT_NAME: [strchr x N]
+//	STRUCT_NAMED: [ispacked, eltty x N]
+//	OPAQUE: [ispacked]
+func (w *writer) writeStructType(t *types.StructType) {
+	ops := []uint64{boolOp(t.Packed)}
+	for _, field := range t.Fields {
+		ops = append(ops, w.typeID(field))
+	}
+	if len(t.TypeName) == 0 {
+		w.writeRecord(typeCodeStructAnon, ops...)
+		return
+	}
+	// Unnamed identified struct types (e.g. %0) are given numeric names in
+	// order of occurrence by the reader, and as such their names are omitted.
+	if !isNumeric(t.TypeName) {
+		w.writeRecord(typeCodeStructName, stringOps(t.TypeName)...)
+	}
+	if t.Opaque {
+		w.writeRecord(typeCodeOpaque, 0)
+		return
+	}
+	w.writeRecord(typeCodeStructNamed, ops...)
+}
+
+// ### [ Helper functions ] ####################################################
+
+// typeKey returns a unique key of the given type; identified struct types are
+// uniqued by name and other types by structural identity.
+func typeKey(t types.Type) string {
+	if t, ok := t.(*types.StructType); ok && len(t.TypeName) > 0 {
+		return t.String()
+	}
+	return t.LLString()
+}
+
+// isNumeric reports whether the given name consists of decimal digits only.
+func isNumeric(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if name[i] < '0' || name[i] > '9' {
+			return false
+		}
+	}
+	return len(name) > 0
+}
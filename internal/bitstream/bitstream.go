@@ -0,0 +1,221 @@
+// Package bitstream implements reading and writing of the LLVM bitstream
+// container format, as used by LLVM bitcode files.
+//
+// A bitstream is a sequence of blocks and records. Records are either
+// unabbreviated (each operand encoded as a 6-bit VBR) or encoded using an
+// abbreviation, which specifies the encoding of each operand. Abbreviations
+// are either defined locally within a block, or shared between all blocks with
+// a given block ID through the BLOCKINFO block.
+//
+// References:
+//
+//	https://llvm.org/docs/BitCodeFormat.html
+package bitstream
+
+// Standard abbreviation IDs.
+const (
+	// END_BLOCK marks the end of the current block.
+	AbbrevIDEndBlock = 0
+	// ENTER_SUBBLOCK marks the start of a new sub-block.
+	AbbrevIDEnterSubblock = 1
+	// DEFINE_ABBREV defines a new abbreviation.
+	AbbrevIDDefineAbbrev = 2
+	// UNABBREV_RECORD is an unabbreviated record.
+	AbbrevIDUnabbrevRecord = 3
+	// FirstApplicationAbbrevID is the first abbreviation ID available for
+	// abbreviations defined in BLOCKINFO or within a block.
+	FirstApplicationAbbrevID = 4
+)
+
+// Standard block IDs.
+const (
+	// BlockInfoBlockID is the block ID of the BLOCKINFO block.
+	BlockInfoBlockID = 0
+)
+
+// BLOCKINFO record codes.
+const (
+	// BlockInfoCodeSetBID sets the block ID to which subsequent abbreviations
+	// apply; [blockid]
+	BlockInfoCodeSetBID = 1
+	// BlockInfoCodeBlockName specifies the name of the block; [namechar x N]
+	BlockInfoCodeBlockName = 2
+	// BlockInfoCodeSetRecordName specifies the name of a record code;
+	// [code, namechar x N]
+	BlockInfoCodeSetRecordName = 3
+)
+
+// Widths of fixed and VBR fields used by the bitstream format.
+const (
+	// topLevelAbbrevWidth is the abbreviation ID width of top-level blocks.
+	topLevelAbbrevWidth = 2
+	// blockIDWidth is the VBR width of block IDs.
+	blockIDWidth = 8
+	// codeLenWidth is the VBR width of abbreviation ID widths of blocks.
+	codeLenWidth = 4
+	// blockSizeWidth is the fixed width of block sizes (in 32-bit words).
+	blockSizeWidth = 32
+	// unabbrevWidth is the VBR width of codes and operands of unabbreviated
+	// records.
+	unabbrevWidth = 6
+	// abbrevOpCountWidth is the VBR width of the number of operands of an
+	// abbreviation definition.
+	abbrevOpCountWidth = 5
+	// abbrevOpKindWidth is the fixed width of the encoding of an abbreviation
+	// operand.
+	abbrevOpKindWidth = 3
+	// abbrevOpValueWidth is the VBR width of the value of an abbreviation
+	// operand (the width of fixed and VBR operands).
+	abbrevOpValueWidth = 5
+	// literalWidth is the VBR width of literal abbreviation operands.
+	literalWidth = 8
+	// arrayLenWidth is the VBR width of the length of array operands.
+	arrayLenWidth = 6
+	// blobLenWidth is the VBR width of the length of blob operands.
+	blobLenWidth = 6
+)
+
+// === [ Blocks and records ] ==================================================
+
+// Entry is a bitstream entry.
+//
+// An Entry has one of the following underlying types.
+//
+//   - [*bitstream.Block]
+//   - [*bitstream.Record]
+type Entry interface {
+	// isEntry ensures that only bitstream entries can be assigned to the
+	// bitstream.Entry interface.
+	isEntry()
+}
+
+// Block is a bitstream block.
+type Block struct {
+	// Block ID.
+	ID uint64
+	// Records and sub-blocks of the block, in order of occurrence.
+	Entries []Entry
+}
+
+// Records returns the records of the block, in order of occurrence.
+func (b *Block) Records() []*Record {
+	var records []*Record
+	for _, entry := range b.Entries {
+		if record, ok := entry.(*Record); ok {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// Blocks returns the sub-blocks of the block with the given block ID, in order
+// of occurrence.
+func (b *Block) Blocks(id uint64) []*Block {
+	var blocks []*Block
+	for _, entry := range b.Entries {
+		if block, ok := entry.(*Block); ok && block.ID == id {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// Record is a bitstream record.
+type Record struct {
+	// Record code.
+	Code uint64
+	// Record operands. Elements of array operands are stored inline.
+	Ops []uint64
+	// (optional) Blob operand; nil if not present.
+	Blob []byte
+}
+
+// String returns the characters of the record operands starting at index
+// start, as used by string records.
+func (r *Record) String(start int) string {
+	if start >= len(r.Ops) {
+		return ""
+	}
+	buf := make([]byte, 0, len(r.Ops)-start)
+	for _, op := range r.Ops[start:] {
+		buf = append(buf, byte(op))
+	}
+	return string(buf)
+}
+
+// isEntry ensures that only bitstream entries can be assigned to the
+// bitstream.Entry interface.
+func (*Block) isEntry()  {}
+func (*Record) isEntry() {}
+
+// === [ Abbreviations ] =======================================================
+
+// OpKind is the encoding kind of an abbreviation operand.
+type OpKind uint8
+
+// Abbreviation operand encoding kinds.
+const (
+	// OpLiteral is a literal value.
+	OpLiteral OpKind = 0
+	// OpFixed is a fixed width field.
+	OpFixed OpKind = 1
+	// OpVBR is a variable bit rate field.
+	OpVBR OpKind = 2
+	// OpArray is an array of elements, the encoding of which is given by the
+	// next operand of the abbreviation.
+	OpArray OpKind = 3
+	// OpChar6 is a 6-bit character, in the range [a-zA-Z0-9._].
+	OpChar6 OpKind = 4
+	// OpBlob is a 32-bit aligned sequence of bytes.
+	OpBlob OpKind = 5
+)
+
+// AbbrevOp is an abbreviation operand.
+type AbbrevOp struct {
+	// Operand encoding kind.
+	Kind OpKind
+	// Literal value of OpLiteral, or bit width of OpFixed and OpVBR.
+	Value uint64
+}
+
+// Abbrev is an abbreviation, specifying the encoding of the code and operands
+// of records.
+type Abbrev struct {
+	// Abbreviation operands; the first operand specifies the encoding of the
+	// record code.
+	Ops []AbbrevOp
+}
+
+// ### [ Helper functions ] ####################################################
+
+// char6Chars specifies the character set of 6-bit characters.
+const char6Chars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789._"
+
+// decodeChar6 returns the character encoded by the given 6-bit value.
+func decodeChar6(v uint64) byte {
+	return char6Chars[v&0x3F]
+}
+
+// encodeChar6 returns the 6-bit value encoding the given character.
+func encodeChar6(c byte) (uint64, bool) {
+	switch {
+	case 'a' <= c && c <= 'z':
+		return uint64(c - 'a'), true
+	case 'A' <= c && c <= 'Z':
+		return uint64(c-'A') + 26, true
+	case '0' <= c && c <= '9':
+		return uint64(c-'0') + 52, true
+	case c == '.':
+		return 62, true
+	case c == '_':
+		return 63, true
+	}
+	return 0, false
+}
+
+// IsChar6 reports whether the given character may be encoded as a 6-bit
+// character.
+func IsChar6(c byte) bool {
+	_, ok := encodeChar6(c)
+	return ok
+}
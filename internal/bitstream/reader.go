@@ -0,0 +1,360 @@
+package bitstream
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Parse parses the given bitstream (without magic number) into its top-level
+// blocks. The abbreviations defined in BLOCKINFO blocks are applied to
+// subsequent blocks; the BLOCKINFO blocks themselves are included in the
+// output.
+func Parse(buf []byte) ([]*Block, error) {
+	r := &reader{
+		buf:       buf,
+		blockInfo: make(map[uint64][]*Abbrev),
+	}
+	var blocks []*Block
+	for !r.atEnd() {
+		// Top-level entries must be sub-blocks.
+		abbrevID, err := r.read(topLevelAbbrevWidth)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if abbrevID != AbbrevIDEnterSubblock {
+			// Trailing garbage; e.g. padding at the end of the file.
+			if abbrevID == AbbrevIDEndBlock && r.remainingZero() {
+				break
+			}
+			return nil, errors.Errorf("invalid top-level abbreviation ID %d at bit offset %d; expected ENTER_SUBBLOCK", abbrevID, r.pos-topLevelAbbrevWidth)
+		}
+		block, err := r.readBlock()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// ReadVBRs reads n variable bit-rate values of the given width from the given
+// bit-packed buffer, as used by blobs of METADATA_STRINGS records.
+func ReadVBRs(buf []byte, n int, width uint) ([]uint64, error) {
+	r := &reader{buf: buf}
+	vs := make([]uint64, n)
+	for i := range vs {
+		v, err := r.readVBR(width)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		vs[i] = v
+	}
+	return vs, nil
+}
+
+// reader is a bitstream reader.
+type reader struct {
+	// Bitstream contents.
+	buf []byte
+	// Current bit position.
+	pos uint64
+	// Abbreviations defined in BLOCKINFO, indexed by block ID.
+	blockInfo map[uint64][]*Abbrev
+}
+
+// readBlock reads the block following an ENTER_SUBBLOCK abbreviation ID.
+func (r *reader) readBlock() (*Block, error) {
+	id, err := r.readVBR(blockIDWidth)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	abbrevWidth, err := r.readVBR(codeLenWidth)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if abbrevWidth < 1 || abbrevWidth > 32 {
+		return nil, errors.Errorf("invalid abbreviation ID width %d of block %d", abbrevWidth, id)
+	}
+	r.align32()
+	numWords, err := r.read(blockSizeWidth)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	end := r.pos + numWords*32
+	if end > uint64(len(r.buf))*8 {
+		return nil, errors.Errorf("block %d of %d words extends past end of bitstream", id, numWords)
+	}
+	block := &Block{ID: id}
+	// Abbreviations of the block; starting with those defined in BLOCKINFO.
+	abbrevs := append([]*Abbrev(nil), r.blockInfo[id]...)
+	// Current block ID of BLOCKINFO records.
+	curBID, hasCurBID := uint64(0), false
+	for {
+		abbrevID, err := r.read(uint(abbrevWidth))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		switch abbrevID {
+		case AbbrevIDEndBlock:
+			r.align32()
+			if r.pos != end {
+				return nil, errors.Errorf("size mismatch of block %d; expected end at bit offset %d, got %d", id, end, r.pos)
+			}
+			return block, nil
+		case AbbrevIDEnterSubblock:
+			sub, err := r.readBlock()
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			block.Entries = append(block.Entries, sub)
+		case AbbrevIDDefineAbbrev:
+			abbrev, err := r.readAbbrev()
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			if id == BlockInfoBlockID {
+				if !hasCurBID {
+					return nil, errors.New("abbreviation defined in BLOCKINFO before SETBID record")
+				}
+				r.blockInfo[curBID] = append(r.blockInfo[curBID], abbrev)
+				continue
+			}
+			abbrevs = append(abbrevs, abbrev)
+		case AbbrevIDUnabbrevRecord:
+			record, err := r.readUnabbrevRecord()
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			if id == BlockInfoBlockID && record.Code == BlockInfoCodeSetBID {
+				if len(record.Ops) < 1 {
+					return nil, errors.New("invalid SETBID record; missing block ID")
+				}
+				curBID, hasCurBID = record.Ops[0], true
+			}
+			block.Entries = append(block.Entries, record)
+		default:
+			i := abbrevID - FirstApplicationAbbrevID
+			if i >= uint64(len(abbrevs)) {
+				return nil, errors.Errorf("invalid abbreviation ID %d in block %d", abbrevID, id)
+			}
+			record, err := r.readAbbrevRecord(abbrevs[i])
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			block.Entries = append(block.Entries, record)
+		}
+	}
+}
+
+// readAbbrev reads the abbreviation definition following a DEFINE_ABBREV
+// abbreviation ID.
+func (r *reader) readAbbrev() (*Abbrev, error) {
+	n, err := r.readVBR(abbrevOpCountWidth)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	abbrev := &Abbrev{}
+	for i := uint64(0); i < n; i++ {
+		isLiteral, err := r.read(1)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if isLiteral == 1 {
+			v, err := r.readVBR(literalWidth)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			abbrev.Ops = append(abbrev.Ops, AbbrevOp{Kind: OpLiteral, Value: v})
+			continue
+		}
+		kind, err := r.read(abbrevOpKindWidth)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		op := AbbrevOp{Kind: OpKind(kind)}
+		switch op.Kind {
+		case OpFixed, OpVBR:
+			width, err := r.readVBR(abbrevOpValueWidth)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			if width > 64 {
+				return nil, errors.Errorf("invalid bit width %d of abbreviation operand", width)
+			}
+			op.Value = width
+			// A fixed or VBR operand of zero width is a literal zero.
+			if width == 0 {
+				op = AbbrevOp{Kind: OpLiteral, Value: 0}
+			}
+		case OpArray, OpChar6, OpBlob:
+			// no value.
+		default:
+			return nil, errors.Errorf("invalid abbreviation operand encoding %d", kind)
+		}
+		abbrev.Ops = append(abbrev.Ops, op)
+	}
+	if len(abbrev.Ops) == 0 {
+		return nil, errors.New("invalid abbreviation; no operands")
+	}
+	return abbrev, nil
+}
+
+// readUnabbrevRecord reads the record following an UNABBREV_RECORD
+// abbreviation ID.
+func (r *reader) readUnabbrevRecord() (*Record, error) {
+	code, err := r.readVBR(unabbrevWidth)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	n, err := r.readVBR(unabbrevWidth)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if n > uint64(len(r.buf))*8-r.pos {
+		return nil, errors.Errorf("invalid number of operands (%d) of record with code %d", n, code)
+	}
+	record := &Record{Code: code, Ops: make([]uint64, n)}
+	for i := range record.Ops {
+		if record.Ops[i], err = r.readVBR(unabbrevWidth); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return record, nil
+}
+
+// readAbbrevRecord reads a record encoded using the given abbreviation.
+func (r *reader) readAbbrevRecord(abbrev *Abbrev) (*Record, error) {
+	code, err := r.readScalar(abbrev.Ops[0])
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	record := &Record{Code: code}
+	for i := 1; i < len(abbrev.Ops); i++ {
+		op := abbrev.Ops[i]
+		switch op.Kind {
+		case OpArray:
+			if i+1 >= len(abbrev.Ops) {
+				return nil, errors.New("invalid array abbreviation operand; missing element encoding")
+			}
+			n, err := r.readVBR(arrayLenWidth)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			if n > uint64(len(r.buf))*8-r.pos {
+				return nil, errors.Errorf("invalid array length %d of record with code %d", n, code)
+			}
+			elem := abbrev.Ops[i+1]
+			for j := uint64(0); j < n; j++ {
+				v, err := r.readScalar(elem)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				record.Ops = append(record.Ops, v)
+			}
+			// The element encoding is consumed by the array.
+			i++
+		case OpBlob:
+			n, err := r.readVBR(blobLenWidth)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			r.align32()
+			start := r.pos / 8
+			if start+n > uint64(len(r.buf)) {
+				return nil, errors.Errorf("blob of %d bytes extends past end of bitstream", n)
+			}
+			record.Blob = r.buf[start : start+n]
+			r.pos += n * 8
+			r.align32()
+		default:
+			v, err := r.readScalar(op)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			record.Ops = append(record.Ops, v)
+		}
+	}
+	return record, nil
+}
+
+// readScalar reads a scalar value encoded using the given abbreviation
+// operand.
+func (r *reader) readScalar(op AbbrevOp) (uint64, error) {
+	switch op.Kind {
+	case OpLiteral:
+		return op.Value, nil
+	case OpFixed:
+		return r.read(uint(op.Value))
+	case OpVBR:
+		return r.readVBR(uint(op.Value))
+	case OpChar6:
+		v, err := r.read(6)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		return uint64(decodeChar6(v)), nil
+	default:
+		return 0, errors.Errorf("invalid scalar abbreviation operand encoding %d", op.Kind)
+	}
+}
+
+// read reads a fixed width value of n bits.
+func (r *reader) read(n uint) (uint64, error) {
+	if r.pos+uint64(n) > uint64(len(r.buf))*8 {
+		return 0, errors.Errorf("unexpected end of bitstream at bit offset %d", r.pos)
+	}
+	var v uint64
+	for i := uint(0); i < n; {
+		b := uint64(r.buf[r.pos/8])
+		off := uint(r.pos % 8)
+		m := 8 - off
+		if m > n-i {
+			m = n - i
+		}
+		v |= ((b >> off) & (1<<m - 1)) << i
+		i += m
+		r.pos += uint64(m)
+	}
+	return v, nil
+}
+
+// readVBR reads a variable bit rate value with chunks of n bits.
+func (r *reader) readVBR(n uint) (uint64, error) {
+	if n < 2 {
+		return 0, errors.Errorf("invalid VBR width %d", n)
+	}
+	hi := uint64(1) << (n - 1)
+	var v uint64
+	for shift := uint(0); ; shift += n - 1 {
+		chunk, err := r.read(n)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		if shift >= 64 {
+			return 0, errors.Errorf("VBR value overflows 64 bits at bit offset %d", r.pos)
+		}
+		v |= (chunk &^ hi) << shift
+		if chunk&hi == 0 {
+			return v, nil
+		}
+	}
+}
+
+// align32 skips to the next 32-bit boundary.
+func (r *reader) align32() {
+	r.pos = (r.pos + 31) &^ 31
+}
+
+// atEnd reports whether the end of the bitstream has been reached.
+func (r *reader) atEnd() bool {
+	return r.pos >= uint64(len(r.buf))*8
+}
+
+// remainingZero reports whether the remaining bits of the bitstream are zero.
+func (r *reader) remainingZero() bool {
+	for _, b := range r.buf[r.pos/8:] {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,252 @@
+package bitstream
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Writer is a bitstream writer.
+//
+// Blocks are entered and exited explicitly, and records are written to the
+// innermost open block, either unabbreviated or using an abbreviation defined
+// within the block.
+type Writer struct {
+	// Bitstream contents.
+	buf []byte
+	// Current bit position.
+	pos uint64
+	// Open blocks, innermost last.
+	blocks []*openBlock
+}
+
+// openBlock is a block which has been entered but not yet exited.
+type openBlock struct {
+	// Block ID.
+	id uint64
+	// Width of abbreviation IDs.
+	abbrevWidth uint
+	// Byte offset of the 32-bit block size word.
+	sizeOffset int
+	// Abbreviations defined within the block.
+	abbrevs []*Abbrev
+}
+
+// NewWriter returns a new bitstream writer.
+func NewWriter() *Writer {
+	return &Writer{}
+}
+
+// Bytes returns the contents of the bitstream, padded to a 32-bit boundary.
+func (w *Writer) Bytes() []byte {
+	w.align32()
+	return w.buf
+}
+
+// EnterBlock enters a new sub-block with the given block ID and abbreviation
+// ID width.
+func (w *Writer) EnterBlock(id uint64, abbrevWidth uint) {
+	w.write(AbbrevIDEnterSubblock, w.abbrevWidth())
+	w.writeVBR(id, blockIDWidth)
+	w.writeVBR(uint64(abbrevWidth), codeLenWidth)
+	w.align32()
+	block := &openBlock{
+		id:          id,
+		abbrevWidth: abbrevWidth,
+		sizeOffset:  len(w.buf),
+	}
+	// Placeholder of block size; patched on exit.
+	w.write(0, blockSizeWidth)
+	w.blocks = append(w.blocks, block)
+}
+
+// ExitBlock exits the innermost open block.
+func (w *Writer) ExitBlock() error {
+	if len(w.blocks) == 0 {
+		return errors.New("unable to exit block; no open block")
+	}
+	block := w.blocks[len(w.blocks)-1]
+	w.write(AbbrevIDEndBlock, block.abbrevWidth)
+	w.align32()
+	w.blocks = w.blocks[:len(w.blocks)-1]
+	// Patch block size (in 32-bit words, excluding the size word).
+	numWords := uint32((len(w.buf) - block.sizeOffset - 4) / 4)
+	w.buf[block.sizeOffset] = byte(numWords)
+	w.buf[block.sizeOffset+1] = byte(numWords >> 8)
+	w.buf[block.sizeOffset+2] = byte(numWords >> 16)
+	w.buf[block.sizeOffset+3] = byte(numWords >> 24)
+	return nil
+}
+
+// DefineAbbrev defines the given abbreviation within the innermost open block,
+// and returns its abbreviation ID.
+func (w *Writer) DefineAbbrev(abbrev *Abbrev) (uint64, error) {
+	if len(w.blocks) == 0 {
+		return 0, errors.New("unable to define abbreviation; no open block")
+	}
+	block := w.blocks[len(w.blocks)-1]
+	w.write(AbbrevIDDefineAbbrev, block.abbrevWidth)
+	w.writeVBR(uint64(len(abbrev.Ops)), abbrevOpCountWidth)
+	for _, op := range abbrev.Ops {
+		if op.Kind == OpLiteral {
+			w.write(1, 1)
+			w.writeVBR(op.Value, literalWidth)
+			continue
+		}
+		w.write(0, 1)
+		w.write(uint64(op.Kind), abbrevOpKindWidth)
+		switch op.Kind {
+		case OpFixed, OpVBR:
+			w.writeVBR(op.Value, abbrevOpValueWidth)
+		}
+	}
+	block.abbrevs = append(block.abbrevs, abbrev)
+	return FirstApplicationAbbrevID + uint64(len(block.abbrevs)-1), nil
+}
+
+// WriteRecord writes the given unabbreviated record to the innermost open
+// block. The blob operand of the record is ignored, as blobs may only be
+// written using abbreviations.
+func (w *Writer) WriteRecord(record *Record) {
+	w.write(AbbrevIDUnabbrevRecord, w.abbrevWidth())
+	w.writeVBR(record.Code, unabbrevWidth)
+	w.writeVBR(uint64(len(record.Ops)), unabbrevWidth)
+	for _, op := range record.Ops {
+		w.writeVBR(op, unabbrevWidth)
+	}
+}
+
+// WriteAbbrevRecord writes the given record to the innermost open block, using
+// the abbreviation with the given abbreviation ID.
+func (w *Writer) WriteAbbrevRecord(abbrevID uint64, record *Record) error {
+	if len(w.blocks) == 0 {
+		return errors.New("unable to write abbreviated record; no open block")
+	}
+	block := w.blocks[len(w.blocks)-1]
+	i := abbrevID - FirstApplicationAbbrevID
+	if abbrevID < FirstApplicationAbbrevID || i >= uint64(len(block.abbrevs)) {
+		return errors.Errorf("invalid abbreviation ID %d in block %d", abbrevID, block.id)
+	}
+	abbrev := block.abbrevs[i]
+	w.write(abbrevID, block.abbrevWidth)
+	if err := w.writeScalar(abbrev.Ops[0], record.Code); err != nil {
+		return errors.WithStack(err)
+	}
+	ops := record.Ops
+	for i := 1; i < len(abbrev.Ops); i++ {
+		op := abbrev.Ops[i]
+		switch op.Kind {
+		case OpArray:
+			if i+1 >= len(abbrev.Ops) {
+				return errors.New("invalid array abbreviation operand; missing element encoding")
+			}
+			// The array consumes all remaining operands.
+			elem := abbrev.Ops[i+1]
+			w.writeVBR(uint64(len(ops)), arrayLenWidth)
+			for _, v := range ops {
+				if err := w.writeScalar(elem, v); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+			ops = nil
+			i++
+		case OpBlob:
+			w.writeVBR(uint64(len(record.Blob)), blobLenWidth)
+			w.align32()
+			w.buf = append(w.buf, record.Blob...)
+			w.pos = uint64(len(w.buf)) * 8
+			w.align32()
+		default:
+			if len(ops) == 0 {
+				return errors.Errorf("invalid record with code %d; too few operands for abbreviation", record.Code)
+			}
+			if err := w.writeScalar(op, ops[0]); err != nil {
+				return errors.WithStack(err)
+			}
+			ops = ops[1:]
+		}
+	}
+	if len(ops) > 0 {
+		return errors.Errorf("invalid record with code %d; %d operands not covered by abbreviation", record.Code, len(ops))
+	}
+	return nil
+}
+
+// WriteVBRs returns the given values as variable bit-rate values of the given
+// width in a bit-packed buffer padded to a 32-bit boundary, as used by blobs of
+// METADATA_STRINGS records.
+func WriteVBRs(vs []uint64, width uint) []byte {
+	w := &Writer{}
+	for _, v := range vs {
+		w.writeVBR(v, width)
+	}
+	return w.Bytes()
+}
+
+// writeScalar writes the given value using the given abbreviation operand.
+func (w *Writer) writeScalar(op AbbrevOp, v uint64) error {
+	switch op.Kind {
+	case OpLiteral:
+		if v != op.Value {
+			return errors.Errorf("invalid value %d of literal abbreviation operand; expected %d", v, op.Value)
+		}
+	case OpFixed:
+		if op.Value < 64 && v>>op.Value != 0 {
+			return errors.Errorf("value %d does not fit in fixed width field of %d bits", v, op.Value)
+		}
+		w.write(v, uint(op.Value))
+	case OpVBR:
+		w.writeVBR(v, uint(op.Value))
+	case OpChar6:
+		c, ok := encodeChar6(byte(v))
+		if !ok || v > 0xFF {
+			return errors.Errorf("invalid 6-bit character %q", rune(v))
+		}
+		w.write(c, 6)
+	default:
+		return errors.Errorf("invalid scalar abbreviation operand encoding %d", op.Kind)
+	}
+	return nil
+}
+
+// abbrevWidth returns the abbreviation ID width of the innermost open block.
+func (w *Writer) abbrevWidth() uint {
+	if len(w.blocks) == 0 {
+		return topLevelAbbrevWidth
+	}
+	return w.blocks[len(w.blocks)-1].abbrevWidth
+}
+
+// write writes the given value as a fixed width value of n bits.
+func (w *Writer) write(v uint64, n uint) {
+	for i := uint(0); i < n; {
+		if w.pos/8 >= uint64(len(w.buf)) {
+			w.buf = append(w.buf, 0)
+		}
+		off := uint(w.pos % 8)
+		m := 8 - off
+		if m > n-i {
+			m = n - i
+		}
+		w.buf[w.pos/8] |= byte((v >> i) & (1<<m - 1) << off)
+		i += m
+		w.pos += uint64(m)
+	}
+}
+
+// writeVBR writes the given value as a variable bit rate value with chunks of
+// n bits.
+func (w *Writer) writeVBR(v uint64, n uint) {
+	hi := uint64(1) << (n - 1)
+	for v >= hi {
+		w.write(v&(hi-1)|hi, n)
+		v >>= n - 1
+	}
+	w.write(v, n)
+}
+
+// align32 pads the bitstream with zero bits to the next 32-bit boundary.
+func (w *Writer) align32() {
+	w.pos = (w.pos + 31) &^ 31
+	for uint64(len(w.buf)) < w.pos/8 {
+		w.buf = append(w.buf, 0)
+	}
+}
@@ -48,6 +48,8 @@ func ResultType(elemType, src types.Type, indices []Index) types.Type {
 		addrSpace types.AddrSpace
 		// Length of vector of pointers result type; or 0 if pointer result type.
 		resultVectorLength uint64
+		// Opaque pointer src type or src vector element type.
+		opaque bool
 	)
 	// ref: https://llvm.org/docs/LangRef.html#getelementptr-instruction
 	//
@@ -55,12 +57,14 @@ func ResultType(elemType, src types.Type, indices []Index) types.Type {
 	switch src := src.(type) {
 	case *types.PointerType:
 		addrSpace = src.AddrSpace
+		opaque = src.IsOpaque()
 	case *types.VectorType:
 		vectorElemType, ok := src.ElemType.(*types.PointerType)
 		if !ok {
 			panic(fmt.Errorf("invalid gep source vector element type; expected *types.PointerType, got %T", src.ElemType))
 		}
 		addrSpace = vectorElemType.AddrSpace
+		opaque = vectorElemType.IsOpaque()
 		resultVectorLength = src.Len
 	default:
 		panic(fmt.Errorf("invalid gep source type; expected pointer or vector of pointers type, got %T", src))
@@ -119,6 +123,11 @@ func ResultType(elemType, src types.Type, indices []Index) types.Type {
 			panic(fmt.Errorf("cannot index into type %T using gep", e))
 		}
 	}
+	// The result of indexing from an opaque pointer is an opaque pointer in the
+	// same address space.
+	if opaque {
+		e = nil
+	}
 	ptr := types.NewPointer(e)
 	ptr.AddrSpace = addrSpace
 	if resultVectorLength != 0 {
@@ -0,0 +1,19 @@
+// Package irtest implements utility functions for testing analyses of LLVM IR
+// functions.
+package irtest
+
+import (
+	"fmt"
+
+	"github.com/llir/llvm/ir"
+)
+
+// Idents returns a string representation of the identifiers of the given basic
+// blocks; e.g. "[%entry %exit]".
+func Idents(blocks []*ir.Block) string {
+	var ss []string
+	for _, block := range blocks {
+		ss = append(ss, block.Ident())
+	}
+	return fmt.Sprintf("%v", ss)
+}
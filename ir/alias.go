@@ -20,6 +20,9 @@ type Alias struct {
 
 	// Pointer type of aliasee.
 	Typ *types.PointerType
+	// (optional) Content type of aliasee; or nil to use the element type of
+	// Typ. Required if Typ is an opaque pointer type.
+	ContentType types.Type
 	// (optional) Linkage; zero value if not present.
 	Linkage enum.Linkage
 	// (optional) Preemption; zero value if not present.
@@ -42,6 +45,9 @@ func NewAlias(name string, aliasee constant.Constant) *Alias {
 	alias.SetName(name)
 	// Compute type.
 	alias.Type()
+	if alias.Typ.IsOpaque() {
+		alias.ContentType = valueContentType(aliasee)
+	}
 	return alias
 }
 
@@ -88,7 +94,7 @@ func (a *Alias) LLString() string {
 		fmt.Fprintf(buf, " %s", a.UnnamedAddr)
 	}
 	buf.WriteString(" alias")
-	fmt.Fprintf(buf, " %s, ", a.Typ.ElemType)
+	fmt.Fprintf(buf, " %s, ", indirectSymbolContentType(a.Ident(), a.ContentType, a.Typ))
 	if expr, ok := a.Aliasee.(constant.Expression); ok {
 		buf.WriteString(expr.Ident())
 	} else {
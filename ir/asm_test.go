@@ -1,14 +1,21 @@
 package ir_test
 
 import (
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/llir/llvm/asm"
 	"github.com/llir/llvm/internal/osutil"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/metadata"
+	"github.com/llir/llvm/ir/types"
 )
 
 func TestModule(t *testing.T) {
@@ -50,3 +57,264 @@ func TestModule(t *testing.T) {
 		}
 	}
 }
+
+func TestWriteModuleConcurrent(t *testing.T) {
+	// Writing functions concurrently must produce output identical to that of
+	// sequential writing.
+	golden := []struct {
+		path string
+	}{
+		{path: "../asm/testdata/concurrent.ll"},
+		{path: "../asm/testdata/inst_other.ll"},
+		{path: "../asm/testdata/terminator.ll"},
+		{path: "../asm/testdata/lazy.ll"},
+		{path: "testdata/clone.ll"},
+		{path: "transform/testdata/inline.ll"},
+	}
+	for _, g := range golden {
+		m, err := asm.ParseFile(g.path)
+		if err != nil {
+			t.Errorf("unable to parse %q; %+v", g.path, err)
+			continue
+		}
+		want := m.String()
+		for _, workers := range []int{2, 4, 16} {
+			// Lazily parsed function bodies are materialized while writing.
+			cfg := &asm.Config{Lazy: true}
+			m, err := cfg.ParseFile(g.path)
+			if err != nil {
+				t.Errorf("unable to lazily parse %q; %+v", g.path, err)
+				break
+			}
+			buf := &strings.Builder{}
+			wcfg := &ir.WriteConfig{Workers: workers}
+			n, err := wcfg.WriteModule(buf, m)
+			if err != nil {
+				t.Errorf("%q: unable to write module using %d workers; %+v", g.path, workers, err)
+				break
+			}
+			got := buf.String()
+			if n != int64(len(got)) {
+				t.Errorf("%q: number of bytes written mismatch; expected %d, got %d", g.path, len(got), n)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("%q: output using %d workers mismatch (-want +got):\n%s", g.path, workers, diff)
+				break
+			}
+		}
+	}
+}
+
+func TestWriteModuleSharedConstant(t *testing.T) {
+	// Constant expressions shared between functions written concurrently have
+	// their types computed on first use; run with -race to detect data races.
+	m := ir.NewModule()
+	arr := types.NewArray(4, types.I32)
+	g := m.NewGlobalDef("g", constant.NewZeroInitializer(arr))
+	zero := constant.NewInt(types.I64, 0)
+	gep := &constant.ExprGetElementPtr{ElemType: arr, Src: g, Indices: []constant.Constant{zero, constant.NewInt(types.I64, 1)}}
+	cast := &constant.ExprBitCast{From: gep, To: types.I8Ptr}
+	for i := 0; i < 16; i++ {
+		f := m.NewFunc(fmt.Sprintf("f%d", i), types.I32)
+		entry := f.NewBlock("")
+		entry.NewLoad(types.I8, cast)
+		v := entry.NewLoad(types.I32, gep)
+		entry.NewRet(v)
+	}
+	// Clear the type cached by the instruction constructors.
+	gep.Typ = nil
+	buf := &strings.Builder{}
+	cfg := &ir.WriteConfig{Workers: 4}
+	if _, err := cfg.WriteModule(buf, m); err != nil {
+		t.Fatalf("unable to write module; %+v", err)
+	}
+	want := m.String()
+	got := buf.String()
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("output using 4 workers mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteModuleSharedMetadataConstant(t *testing.T) {
+	// Constant expressions of metadata attachments shared between functions
+	// written concurrently have their types computed on first use; run with
+	// -race to detect data races.
+	m := ir.NewModule()
+	arr := types.NewArray(4, types.I32)
+	g := m.NewGlobalDef("g", constant.NewZeroInitializer(arr))
+	zero := constant.NewInt(types.I64, 0)
+	gep := &constant.ExprGetElementPtr{ElemType: arr, Src: g, Indices: []constant.Constant{zero, constant.NewInt(types.I64, 1)}}
+	node := &metadata.Tuple{MetadataID: -1, Fields: []metadata.Field{gep}}
+	for i := 0; i < 16; i++ {
+		f := m.NewFunc(fmt.Sprintf("f%d", i), types.Void)
+		entry := f.NewBlock("")
+		ret := entry.NewRet(nil)
+		ret.Metadata = append(ret.Metadata, &metadata.Attachment{Name: "foo", Node: node})
+	}
+	buf := &strings.Builder{}
+	cfg := &ir.WriteConfig{Workers: 4}
+	if _, err := cfg.WriteModule(buf, m); err != nil {
+		t.Fatalf("unable to write module; %+v", err)
+	}
+	want := m.String()
+	got := buf.String()
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("output using 4 workers mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteModulePanic(t *testing.T) {
+	// Panics while preparing functions for being written concurrently are
+	// propagated to the calling goroutine.
+	m := ir.NewModule()
+	arr := types.NewArray(4, types.I32)
+	g := m.NewGlobalDef("g", constant.NewZeroInitializer(arr))
+	// Invalid gep index of floating-point type.
+	gep := &constant.ExprGetElementPtr{ElemType: arr, Src: g, Indices: []constant.Constant{constant.NewFloat(types.Double, 1)}}
+	for i := 0; i < 16; i++ {
+		f := m.NewFunc(fmt.Sprintf("f%d", i), types.Void)
+		entry := f.NewBlock("")
+		ret := entry.NewRet(nil)
+		if i == 8 {
+			node := &metadata.Tuple{MetadataID: -1, Fields: []metadata.Field{gep}}
+			ret.Metadata = append(ret.Metadata, &metadata.Attachment{Name: "foo", Node: node})
+		}
+	}
+	defer func() {
+		if e := recover(); e == nil {
+			t.Errorf("expected panic while writing module")
+		}
+	}()
+	cfg := &ir.WriteConfig{Workers: 4}
+	cfg.WriteModule(ioutil.Discard, m)
+}
+
+func TestWriteModuleError(t *testing.T) {
+	const path = "../asm/testdata/concurrent.ll"
+	m, err := asm.ParseFile(path)
+	if err != nil {
+		t.Fatalf("unable to parse %q; %+v", path, err)
+	}
+	size := int64(len(m.String()))
+	golden := []struct {
+		// Number of bytes accepted by the writer before failing.
+		limit   int64
+		workers int
+	}{
+		{limit: 0, workers: 1},
+		{limit: size / 2, workers: 1},
+		{limit: size - 1, workers: 1},
+		{limit: size / 2, workers: 4},
+		{limit: size - 1, workers: 4},
+	}
+	for _, g := range golden {
+		w := &limitWriter{limit: g.limit}
+		cfg := &ir.WriteConfig{Workers: g.workers}
+		n, err := cfg.WriteModule(w, m)
+		if err != errLimit {
+			t.Errorf("limit %d using %d workers: error mismatch; expected %v, got %v", g.limit, g.workers, errLimit, err)
+		}
+		if n != w.n {
+			t.Errorf("limit %d using %d workers: number of bytes written mismatch; expected %d, got %d", g.limit, g.workers, w.n, n)
+		}
+	}
+}
+
+func TestLazyMaterialize(t *testing.T) {
+	// Function bodies of lazily parsed modules are materialized by the APIs
+	// operating on function bodies.
+	const src = `@g = global i32 0
+@h = global i32 1
+
+define i32 @f() {
+entry:
+	%x = load i32, i32* @g
+	ret i32 %x
+}
+`
+	const replaced = `@g = global i32 0
+@h = global i32 1
+
+define i32 @f() {
+entry:
+	%x = load i32, i32* @h
+	ret i32 %x
+}
+`
+	golden := []struct {
+		name string
+		// Operation on the lazily parsed module; returns the number of uses of
+		// @g known by the operation, if any.
+		op func(m *ir.Module) int
+		// Number of uses of @g.
+		nUses int
+		want  string
+	}{
+		{
+			name: "NewModuleUseIndex",
+			op:   func(m *ir.Module) int { return ir.NewModuleUseIndex(m).NumUses(m.Globals[0]) },
+			// Uses by the load instruction.
+			nUses: 1,
+			want:  src,
+		},
+		{
+			name:  "NewFuncUseIndex",
+			op:    func(m *ir.Module) int { return ir.NewFuncUseIndex(m.Funcs[0]).NumUses(m.Globals[0]) },
+			nUses: 1,
+			want:  src,
+		},
+		{
+			name: "Module.ReplaceAllUsesWith",
+			op: func(m *ir.Module) int {
+				m.ReplaceAllUsesWith(m.Globals[0], m.Globals[1])
+				return 0
+			},
+			want: replaced,
+		},
+		{
+			name: "Func.ReplaceAllUsesWith",
+			op: func(m *ir.Module) int {
+				m.Funcs[0].ReplaceAllUsesWith(m.Globals[0], m.Globals[1])
+				return 0
+			},
+			want: replaced,
+		},
+	}
+	for _, g := range golden {
+		cfg := &asm.Config{Lazy: true}
+		m, err := cfg.ParseString("<stdin>", src)
+		if err != nil {
+			t.Fatalf("unable to lazily parse module; %+v", err)
+		}
+		if n := g.op(m); n != g.nUses {
+			t.Errorf("%s: number of uses of @g mismatch; expected %d, got %d", g.name, g.nUses, n)
+		}
+		got := m.String()
+		if diff := cmp.Diff(g.want, got); diff != "" {
+			t.Errorf("%s: module mismatch (-want +got):\n%s", g.name, diff)
+		}
+	}
+}
+
+// errLimit is returned by limitWriter when exceeding its limit.
+var errLimit = errors.New("write limit exceeded")
+
+// limitWriter is a writer which fails after accepting a limited number of
+// bytes.
+type limitWriter struct {
+	// Number of bytes accepted before failing.
+	limit int64
+	// Number of bytes written.
+	n int64
+}
+
+// Write writes p to the writer, failing if exceeding its limit.
+func (w *limitWriter) Write(p []byte) (int, error) {
+	if w.n+int64(len(p)) > w.limit {
+		n := int(w.limit - w.n)
+		w.n = w.limit
+		return n, errLimit
+	}
+	w.n += int64(len(p))
+	return len(p), nil
+}
@@ -5,6 +5,7 @@ package ir
 
 import (
 	"fmt"
+	"io"
 	"strings"
 
 	"github.com/llir/llvm/internal/enc"
@@ -51,22 +52,213 @@ func (block *Block) Type() types.Type {
 // LLString returns the LLVM syntax representation of the basic block
 // definition.
 func (block *Block) LLString() string {
-	// Name=LabelIdentopt Insts=Instruction* Term=Terminator
 	buf := &strings.Builder{}
+	if _, err := block.WriteTo(buf); err != nil {
+		panic(fmt.Errorf("unable to write to string buffer; %v", err))
+	}
+	return buf.String()
+}
+
+// WriteTo writes the LLVM syntax representation of the basic block to w, one
+// instruction at a time.
+func (block *Block) WriteTo(w io.Writer) (n int64, err error) {
+	// Name=LabelIdentopt Insts=Instruction* Term=Terminator
+	fw := &fmtWriter{w: w}
+	if block.Term == nil {
+		buf := &strings.Builder{}
+		block.writeInsts(&fmtWriter{w: buf})
+		panic(fmt.Sprintf("missing terminator in basic block %q.\ncurrent instructions:\n%s", block.Name(), buf.String()))
+	}
+	block.writeInsts(fw)
+	fw.Fprintf("\t%s", block.Term.LLString())
+	return fw.size, fw.err
+}
+
+// writeInsts writes the label and the instructions of the basic block to fw.
+func (block *Block) writeInsts(fw *fmtWriter) {
 	if block.IsUnnamed() {
-		//fmt.Fprintf(buf, "; <label>:%d\n", block.LocalID)
+		//fw.Fprintf("; <label>:%d\n", block.LocalID)
 		// Explicitly print basic block label to conform with Clang 9.0, and
 		// because it's the sane thing to do.
-		fmt.Fprintf(buf, "%s\n", enc.LabelID(block.LocalID))
+		fw.Fprintf("%s\n", enc.LabelID(block.LocalID))
 	} else {
-		fmt.Fprintf(buf, "%s\n", enc.LabelName(block.LocalName))
+		fw.Fprintf("%s\n", enc.LabelName(block.LocalName))
 	}
 	for _, inst := range block.Insts {
-		fmt.Fprintf(buf, "\t%s\n", inst.LLString())
+		fw.Fprintf("\t%s\n", inst.LLString())
 	}
-	if block.Term == nil {
-		panic(fmt.Sprintf("missing terminator in basic block %q.\ncurrent instructions:\n%s", block.Name(), buf.String()))
+}
+
+// --- [ Instruction insertion and removal ] -----------------------------------
+
+// InsertBefore inserts the given instruction into the basic block, directly
+// before the instruction before.
+//
+// Unnamed local variables of the parent function are renumbered by the next
+// call to Func.AssignIDs.
+func (block *Block) InsertBefore(inst, before Instruction) {
+	pos := indexOfInst(block.Insts, before)
+	if pos == -1 {
+		panic(fmt.Errorf("unable to insert instruction %q; instruction %q not present in basic block %s", inst.LLString(), before.LLString(), block.Ident()))
 	}
-	fmt.Fprintf(buf, "\t%s", block.Term.LLString())
-	return buf.String()
+	block.insert(pos, inst)
+}
+
+// InsertAfter inserts the given instruction into the basic block, directly
+// after the instruction after.
+func (block *Block) InsertAfter(inst, after Instruction) {
+	pos := indexOfInst(block.Insts, after)
+	if pos == -1 {
+		panic(fmt.Errorf("unable to insert instruction %q; instruction %q not present in basic block %s", inst.LLString(), after.LLString(), block.Ident()))
+	}
+	block.insert(pos+1, inst)
+}
+
+// InsertAt inserts the given instruction at position pos of the instructions
+// of the basic block; i.e. a pos of 0 inserts the instruction at the beginning
+// of the basic block, and a pos of len(block.Insts) inserts the instruction at
+// the end of the basic block, directly before its terminator.
+//
+// Unnamed local variables of the parent function are renumbered by the next
+// call to Func.AssignIDs.
+func (block *Block) InsertAt(inst Instruction, pos int) {
+	if pos < 0 || pos > len(block.Insts) {
+		panic(fmt.Errorf("unable to insert instruction %q; position %d out of bounds of basic block %s with %d instructions", inst.LLString(), pos, block.Ident(), len(block.Insts)))
+	}
+	block.insert(pos, inst)
+}
+
+// Remove removes the given instruction from the basic block.
+//
+// Uses of the instruction are not updated; remaining uses should be replaced
+// by the caller (e.g. using Func.ReplaceAllUsesWith) before the instruction is
+// removed. The uses of the operands of the instruction are removed from the
+// use index registered on the parent function, if any.
+//
+// Unnamed local variables of the parent function are renumbered by the next
+// call to Func.AssignIDs.
+func (block *Block) Remove(inst Instruction) {
+	pos := indexOfInst(block.Insts, inst)
+	if pos == -1 {
+		panic(fmt.Errorf("unable to remove instruction %q; not present in basic block %s", inst.LLString(), block.Ident()))
+	}
+	block.remove(pos)
+}
+
+// RemoveIf removes the instructions of the basic block for which remove reports
+// true, in a single pass over the instructions of the basic block, and returns
+// the number of removed instructions.
+//
+// As for Remove, uses of the removed instructions are not updated, and the
+// uses of the operands of the removed instructions are removed from the use
+// index registered on the parent function, if any.
+//
+// Unnamed local variables of the parent function are renumbered by the next
+// call to Func.AssignIDs.
+func (block *Block) RemoveIf(remove func(inst Instruction) bool) int {
+	idx := block.useIndex()
+	insts := block.Insts[:0]
+	for _, inst := range block.Insts {
+		if !remove(inst) {
+			insts = append(insts, inst)
+			continue
+		}
+		block.renumber(inst)
+		if idx != nil {
+			idx.RemoveUser(inst)
+		}
+	}
+	n := len(block.Insts) - len(insts)
+	// Clear tail to allow garbage collection of the removed instructions.
+	for i := len(insts); i < len(block.Insts); i++ {
+		block.Insts[i] = nil
+	}
+	block.Insts = insts
+	return n
+}
+
+// MoveTo moves the given instruction of the basic block to position pos of the
+// instructions of the destination basic block, where pos is the index of the
+// instruction after the move; i.e. a pos of 0 moves the instruction to the
+// beginning of dst, and a pos of len(dst.Insts) moves the instruction to the
+// end of dst, directly before its terminator. The destination basic block may
+// be the same as the source basic block.
+//
+// Unnamed local variables of the parent functions are renumbered by the next
+// calls to Func.AssignIDs.
+func (block *Block) MoveTo(inst Instruction, dst *Block, pos int) {
+	i := indexOfInst(block.Insts, inst)
+	if i == -1 {
+		panic(fmt.Errorf("unable to move instruction %q; not present in basic block %s", inst.LLString(), block.Ident()))
+	}
+	n := len(dst.Insts)
+	if dst == block {
+		n--
+	}
+	if pos < 0 || pos > n {
+		panic(fmt.Errorf("unable to move instruction %q; position %d out of bounds of basic block %s with %d instructions", inst.LLString(), pos, dst.Ident(), n))
+	}
+	block.remove(i)
+	dst.insert(pos, inst)
+}
+
+// insert inserts the given instruction at position pos of the instructions of
+// the basic block.
+func (block *Block) insert(pos int, inst Instruction) {
+	block.Insts = append(block.Insts, nil)
+	copy(block.Insts[pos+1:], block.Insts[pos:])
+	block.Insts[pos] = inst
+	block.renumber(inst)
+	if idx := block.useIndex(); idx != nil {
+		idx.AddUser(inst)
+	}
+}
+
+// remove removes the instruction at position pos of the instructions of the
+// basic block.
+func (block *Block) remove(pos int) {
+	inst := block.Insts[pos]
+	copy(block.Insts[pos:], block.Insts[pos+1:])
+	// Clear tail to allow garbage collection of the removed instruction.
+	block.Insts[len(block.Insts)-1] = nil
+	block.Insts = block.Insts[:len(block.Insts)-1]
+	block.renumber(inst)
+	if idx := block.useIndex(); idx != nil {
+		idx.RemoveUser(inst)
+	}
+}
+
+// renumber marks the unnamed local variables of the parent function of the
+// basic block for renumbering if the given inserted or removed instruction is
+// unnamed, as the local IDs of subsequent unnamed local variables are no longer
+// sequential.
+func (block *Block) renumber(inst Instruction) {
+	if block.Parent == nil {
+		return
+	}
+	if n, ok := inst.(namedVar); ok && n.IsUnnamed() {
+		block.Parent.renumber = true
+	}
+}
+
+// useIndex returns the use index registered on the parent function of the basic
+// block; or nil if not present.
+func (block *Block) useIndex() *UseIndex {
+	if block.Parent == nil {
+		return nil
+	}
+	return block.Parent.useIndex
+}
+
+// ### [ Helper functions ] ####################################################
+
+// indexOfInst returns the index of the given instruction in insts; or -1 if not
+// present.
+func indexOfInst(insts []Instruction, inst Instruction) int {
+	for i, v := range insts {
+		if v == inst {
+			return i
+		}
+	}
+	return -1
 }
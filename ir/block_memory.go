@@ -14,6 +14,9 @@ import (
 // given element type.
 func (block *Block) NewAlloca(elemType types.Type) *InstAlloca {
 	inst := NewAlloca(elemType)
+	if f := block.Parent; f != nil && f.Parent != nil && f.Parent.OpaquePointers {
+		inst.Typ = types.NewOpaquePointer(inst.AddrSpace)
+	}
 	block.Insts = append(block.Insts, inst)
 	return inst
 }
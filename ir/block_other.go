@@ -61,6 +61,14 @@ func (block *Block) NewCall(callee value.Value, args ...value.Value) *InstCall {
 	return inst
 }
 
+// NewCallSig appends a new call instruction to the basic block based on the
+// given function signature, callee and function arguments.
+func (block *Block) NewCallSig(sig *types.FuncType, callee value.Value, args ...value.Value) *InstCall {
+	inst := NewCallSig(sig, callee, args...)
+	block.Insts = append(block.Insts, inst)
+	return inst
+}
+
 // ~~~ [ va_arg ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // NewVAArg appends a new va_arg instruction to the basic block based on the
@@ -1,6 +1,7 @@
 package ir
 
 import (
+	"github.com/llir/llvm/ir/types"
 	"github.com/llir/llvm/ir/value"
 )
 
@@ -72,6 +73,15 @@ func (block *Block) NewInvoke(invokee value.Value, args []value.Value, normalRet
 	return term
 }
 
+// NewInvokeSig sets the terminator of the basic block to a new invoke
+// terminator based on the given function signature, invokee, function arguments
+// and control flow return points for normal and exceptional execution.
+func (block *Block) NewInvokeSig(sig *types.FuncType, invokee value.Value, args []value.Value, normalRetTarget, exceptionRetTarget *Block) *TermInvoke {
+	term := NewInvokeSig(sig, invokee, args, normalRetTarget, exceptionRetTarget)
+	block.Term = term
+	return term
+}
+
 // ~~~ [ callbr ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // TODO: specify the set of underlying types of callee in Block.NewCallBr.
@@ -85,6 +95,15 @@ func (block *Block) NewCallBr(callee value.Value, args []value.Value, normalRetT
 	return term
 }
 
+// NewCallBrSig sets the terminator of the basic block to a new callbr
+// terminator based on the given function signature, callee, function arguments
+// and control flow return points for normal and exceptional execution.
+func (block *Block) NewCallBrSig(sig *types.FuncType, callee value.Value, args []value.Value, normalRetTarget *Block, otherRetTargets ...*Block) *TermCallBr {
+	term := NewCallBrSig(sig, callee, args, normalRetTarget, otherRetTargets...)
+	block.Term = term
+	return term
+}
+
 // ~~~ [ resume ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // NewResume sets the terminator of the basic block to a new resume terminator
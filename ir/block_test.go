@@ -0,0 +1,174 @@
+package ir
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+)
+
+func TestBlockInsertRemove(t *testing.T) {
+	x := NewParam("x", types.I32)
+	f := NewFunc("f", types.I32, x)
+	entry := f.NewBlock("entry")
+	exit := f.NewBlock("exit")
+	a := entry.NewAdd(x, constant.NewInt(types.I32, 1))
+	b := entry.NewMul(a, a)
+	entry.NewBr(exit)
+	c := exit.NewSub(b, x)
+	exit.NewRet(c)
+	// Assign local IDs before editing, to ensure that unnamed local variables
+	// are renumbered.
+	if err := f.AssignIDs(); err != nil {
+		t.Fatalf("unable to assign IDs; %+v", err)
+	}
+
+	shl := NewShl(x, constant.NewInt(types.I32, 2))
+	entry.InsertBefore(shl, a)
+	xor := NewXor(shl, a)
+	xor.SetName("xor")
+	entry.InsertAfter(xor, b)
+	entry.Remove(b)
+	c.X = xor
+	entry.MoveTo(a, exit, 0)
+	exit.MoveTo(c, exit, 1)
+	and := NewAnd(x, c)
+	and.SetName("and")
+	exit.InsertAt(and, 2)
+
+	const want = `define i32 @f(i32 %x) {
+entry:
+	%0 = shl i32 %x, 2
+	%xor = xor i32 %0, %1
+	br label %exit
+
+exit:
+	%1 = add i32 %x, 1
+	%2 = sub i32 %xor, %x
+	%and = and i32 %x, %2
+	ret i32 %2
+}`
+	got := f.LLString()
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("function mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBlockRemoveIf(t *testing.T) {
+	x := NewParam("x", types.I32)
+	f := NewFunc("f", types.I32, x)
+	entry := f.NewBlock("")
+	a := entry.NewAdd(x, constant.NewInt(types.I32, 1))
+	b := entry.NewMul(x, x)
+	c := entry.NewSub(x, constant.NewInt(types.I32, 2))
+	entry.NewRet(c)
+	if err := f.AssignIDs(); err != nil {
+		t.Fatalf("unable to assign IDs; %+v", err)
+	}
+
+	dead := map[Instruction]bool{a: true, b: true}
+	n := entry.RemoveIf(func(inst Instruction) bool {
+		return dead[inst]
+	})
+	if n != 2 {
+		t.Errorf("number of removed instructions mismatch; expected 2, got %d", n)
+	}
+
+	const want = `define i32 @f(i32 %x) {
+0:
+	%1 = sub i32 %x, 2
+	ret i32 %1
+}`
+	got := f.LLString()
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("function mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFuncSplitBlock(t *testing.T) {
+	x := NewParam("x", types.I32)
+	f := NewFunc("f", types.I32, x)
+	entry := f.NewBlock("")
+	loop := f.NewBlock("")
+	exit := f.NewBlock("")
+	cond := entry.NewICmp(enum.IPredSLT, x, constant.NewInt(types.I32, 0))
+	a := entry.NewAdd(x, constant.NewInt(types.I32, 1))
+	entry.NewCondBr(cond, loop, exit)
+	phi := loop.NewPhi(NewIncoming(a, entry))
+	b := loop.NewAdd(phi, constant.NewInt(types.I32, 1))
+	phi.Incs = append(phi.Incs, NewIncoming(b, loop))
+	loop.NewBr(loop)
+	exit.NewPhi(NewIncoming(x, entry))
+	exit.NewRet(a)
+	if err := f.AssignIDs(); err != nil {
+		t.Fatalf("unable to assign IDs; %+v", err)
+	}
+
+	split := f.SplitBlock(a)
+	if split.Parent != f {
+		t.Errorf("parent mismatch; expected %s, got %v", f.Ident(), split.Parent)
+	}
+	f.SplitBlock(b)
+
+	const want = `define i32 @f(i32 %x) {
+0:
+	%1 = icmp slt i32 %x, 0
+	br label %2
+
+2:
+	%3 = add i32 %x, 1
+	br i1 %1, label %4, label %8
+
+4:
+	%5 = phi i32 [ %3, %2 ], [ %7, %6 ]
+	br label %6
+
+6:
+	%7 = add i32 %5, 1
+	br label %4
+
+8:
+	%9 = phi i32 [ %x, %2 ]
+	ret i32 %3
+}`
+	got := f.LLString()
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("function mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFuncRemoveBlock(t *testing.T) {
+	x := NewParam("x", types.I32)
+	f := NewFunc("f", types.I32, x)
+	entry := f.NewBlock("")
+	dead := f.NewBlock("")
+	exit := f.NewBlock("")
+	entry.NewBr(exit)
+	a := dead.NewAdd(x, constant.NewInt(types.I32, 1))
+	dead.NewBr(exit)
+	phi := exit.NewPhi(NewIncoming(x, entry), NewIncoming(a, dead))
+	exit.NewRet(phi)
+	if err := f.AssignIDs(); err != nil {
+		t.Fatalf("unable to assign IDs; %+v", err)
+	}
+
+	f.RemoveBlock(dead)
+	if dead.Parent != nil {
+		t.Errorf("parent mismatch; expected nil, got %s", dead.Parent.Ident())
+	}
+
+	const want = `define i32 @f(i32 %x) {
+0:
+	br label %1
+
+1:
+	%2 = phi i32 [ %x, %0 ]
+	ret i32 %2
+}`
+	got := f.LLString()
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("function mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,214 @@
+package ir
+
+import (
+	"fmt"
+
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/metadata"
+	"github.com/llir/llvm/ir/types"
+)
+
+// === [ Instruction builder ] =================================================
+
+// Builder is an LLVM IR instruction builder, which creates instructions at an
+// insertion point of a basic block.
+//
+// The insertion point is either before an instruction of the basic block, or
+// at the end of the basic block (after the last instruction, before the
+// terminator). The insertion point is stable; instructions created by the
+// builder are inserted in order of creation, before the instruction of the
+// insertion point.
+//
+// Instructions and terminators created by the builder are given the default
+// debug location and fast-math flags of the builder.
+type Builder struct {
+	// (optional) Default debug location; attached as !dbg metadata to the
+	// instructions and terminators created by the builder.
+	DebugLoc *metadata.DILocation
+	// (optional) Default fast-math flags; set on the floating-point operations
+	// created by the builder (fneg, floating-point binary instructions, fcmp,
+	// and phi, select and call instructions of floating-point type).
+	FastMathFlags []enum.FastMathFlag
+
+	// Basic block of the insertion point; or nil if not set.
+	block *Block
+	// Instruction before which new instructions are inserted; or nil to insert
+	// at the end of the basic block.
+	before Instruction
+}
+
+// NewBuilder returns a new instruction builder with an insertion point at the
+// end of the given basic block. A nil basic block indicates that the insertion
+// point has not yet been set.
+func NewBuilder(block *Block) *Builder {
+	return &Builder{block: block}
+}
+
+// Block returns the basic block of the insertion point of the builder; or nil
+// if not set.
+func (b *Builder) Block() *Block {
+	return b.block
+}
+
+// Func returns the parent function of the basic block of the insertion point
+// of the builder; or nil if not present.
+func (b *Builder) Func() *Func {
+	if b.block == nil {
+		return nil
+	}
+	return b.block.Parent
+}
+
+// SetInsertPointAtEnd sets the insertion point of the builder to the end of the
+// given basic block; after the last instruction, before the terminator.
+func (b *Builder) SetInsertPointAtEnd(block *Block) {
+	b.block = block
+	b.before = nil
+}
+
+// SetInsertPointBefore sets the insertion point of the builder to before the
+// given instruction. The instruction is located in the basic block of the
+// current insertion point, or in any basic block of its parent function.
+func (b *Builder) SetInsertPointBefore(inst Instruction) {
+	block, pos := b.locate(inst)
+	b.block = block
+	b.before = block.Insts[pos]
+}
+
+// SetInsertPointAfter sets the insertion point of the builder to after the
+// given instruction. The instruction is located in the basic block of the
+// current insertion point, or in any basic block of its parent function.
+func (b *Builder) SetInsertPointAfter(inst Instruction) {
+	block, pos := b.locate(inst)
+	b.block = block
+	b.before = nil
+	if pos+1 < len(block.Insts) {
+		b.before = block.Insts[pos+1]
+	}
+}
+
+// NewBlock appends a new basic block to the parent function of the basic block
+// of the insertion point, based on the given label name. An empty label name
+// indicates an unnamed basic block. The insertion point of the builder is left
+// unchanged.
+func (b *Builder) NewBlock(name string) *Block {
+	f := b.Func()
+	if f == nil {
+		panic(fmt.Errorf("unable to create basic block %q; insertion point of builder not within a function", name))
+	}
+	return f.NewBlock(name)
+}
+
+// insert inserts the given instruction at the insertion point of the builder.
+func (b *Builder) insert(inst Instruction) {
+	if b.block == nil {
+		panic(fmt.Errorf("unable to insert instruction %q; insertion point of builder not set", inst.LLString()))
+	}
+	b.setDefaults(inst)
+	if b.before != nil {
+		b.block.InsertBefore(inst, b.before)
+		return
+	}
+	b.block.insert(len(b.block.Insts), inst)
+}
+
+// setTerm sets the terminator of the basic block of the insertion point of the
+// builder.
+func (b *Builder) setTerm(term Terminator) {
+	if b.block == nil {
+		panic(fmt.Errorf("unable to set terminator %q; insertion point of builder not set", term.LLString()))
+	}
+	b.setDefaults(term)
+	if idx := b.block.useIndex(); idx != nil {
+		if b.block.Term != nil {
+			idx.RemoveUser(b.block.Term)
+		}
+		idx.AddUser(term)
+	}
+	b.block.Term = term
+}
+
+// setDefaults sets the default debug location and fast-math flags of the
+// builder on the given instruction or terminator.
+func (b *Builder) setDefaults(inst interface{}) {
+	if b.DebugLoc != nil {
+		if md, ok := inst.(mdAttacher); ok {
+			md.SetMDAttachments(append(md.MDAttachments(), &metadata.Attachment{Name: "dbg", Node: b.DebugLoc}))
+		}
+	}
+	if len(b.FastMathFlags) == 0 {
+		return
+	}
+	// Each instruction is given its own copy of the fast-math flags.
+	flags := func() []enum.FastMathFlag {
+		return append([]enum.FastMathFlag(nil), b.FastMathFlags...)
+	}
+	switch inst := inst.(type) {
+	case *InstFNeg:
+		inst.FastMathFlags = flags()
+	case *InstFAdd:
+		inst.FastMathFlags = flags()
+	case *InstFSub:
+		inst.FastMathFlags = flags()
+	case *InstFMul:
+		inst.FastMathFlags = flags()
+	case *InstFDiv:
+		inst.FastMathFlags = flags()
+	case *InstFRem:
+		inst.FastMathFlags = flags()
+	case *InstFCmp:
+		inst.FastMathFlags = flags()
+	case *InstPhi:
+		// The type of phi instructions without incoming values is not yet known.
+		if len(inst.Incs) > 0 && isFloatingPoint(inst.Type()) {
+			inst.FastMathFlags = flags()
+		}
+	case *InstSelect:
+		if isFloatingPoint(inst.Type()) {
+			inst.FastMathFlags = flags()
+		}
+	case *InstCall:
+		if isFloatingPoint(inst.Type()) {
+			inst.FastMathFlags = flags()
+		}
+	}
+}
+
+// locate returns the basic block and index of the given instruction, searching
+// the basic block of the insertion point of the builder and the basic blocks of
+// its parent function.
+func (b *Builder) locate(inst Instruction) (*Block, int) {
+	if b.block != nil {
+		if pos := indexOfInst(b.block.Insts, inst); pos != -1 {
+			return b.block, pos
+		}
+	}
+	if f := b.Func(); f != nil {
+		for _, block := range f.Blocks {
+			if pos := indexOfInst(block.Insts, inst); pos != -1 {
+				return block, pos
+			}
+		}
+	}
+	panic(fmt.Errorf("unable to locate basic block of instruction %q", inst.LLString()))
+}
+
+// mdAttacher is an instruction or terminator with metadata attachments.
+type mdAttacher interface {
+	// MDAttachments returns the metadata attachments of the value.
+	MDAttachments() []*metadata.Attachment
+	// SetMDAttachments sets the metadata attachments of the value.
+	SetMDAttachments(attachments []*metadata.Attachment)
+}
+
+// ### [ Helper functions ] ####################################################
+
+// isFloatingPoint reports whether the given type is a floating-point type or a
+// vector of floating-point type.
+func isFloatingPoint(t types.Type) bool {
+	if vt, ok := t.(*types.VectorType); ok {
+		t = vt.ElemType
+	}
+	_, ok := t.(*types.FloatType)
+	return ok
+}
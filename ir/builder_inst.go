@@ -0,0 +1,732 @@
+package ir
+
+import (
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// --- [ Unary instructions ] --------------------------------------------------
+
+// ~~~ [ fneg ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewFNeg inserts a new fneg instruction at the insertion point of the builder
+// based on the given operand.
+func (b *Builder) NewFNeg(x value.Value) *InstFNeg {
+	inst := NewFNeg(x)
+	b.insert(inst)
+	return inst
+}
+
+// --- [ Binary instructions ] -------------------------------------------------
+
+// ~~~ [ add ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewAdd inserts a new add instruction at the insertion point of the builder
+// based on the given operands.
+func (b *Builder) NewAdd(x, y value.Value) *InstAdd {
+	inst := NewAdd(x, y)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ fadd ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewFAdd inserts a new fadd instruction at the insertion point of the builder
+// based on the given operands.
+func (b *Builder) NewFAdd(x, y value.Value) *InstFAdd {
+	inst := NewFAdd(x, y)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ sub ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewSub inserts a new sub instruction at the insertion point of the builder
+// based on the given operands.
+func (b *Builder) NewSub(x, y value.Value) *InstSub {
+	inst := NewSub(x, y)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ fsub ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewFSub inserts a new fsub instruction at the insertion point of the builder
+// based on the given operands.
+func (b *Builder) NewFSub(x, y value.Value) *InstFSub {
+	inst := NewFSub(x, y)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ mul ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewMul inserts a new mul instruction at the insertion point of the builder
+// based on the given operands.
+func (b *Builder) NewMul(x, y value.Value) *InstMul {
+	inst := NewMul(x, y)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ fmul ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewFMul inserts a new fmul instruction at the insertion point of the builder
+// based on the given operands.
+func (b *Builder) NewFMul(x, y value.Value) *InstFMul {
+	inst := NewFMul(x, y)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ udiv ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewUDiv inserts a new udiv instruction at the insertion point of the builder
+// based on the given operands.
+func (b *Builder) NewUDiv(x, y value.Value) *InstUDiv {
+	inst := NewUDiv(x, y)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ sdiv ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewSDiv inserts a new sdiv instruction at the insertion point of the builder
+// based on the given operands.
+func (b *Builder) NewSDiv(x, y value.Value) *InstSDiv {
+	inst := NewSDiv(x, y)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ fdiv ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewFDiv inserts a new fdiv instruction at the insertion point of the builder
+// based on the given operands.
+func (b *Builder) NewFDiv(x, y value.Value) *InstFDiv {
+	inst := NewFDiv(x, y)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ urem ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewURem inserts a new urem instruction at the insertion point of the builder
+// based on the given operands.
+func (b *Builder) NewURem(x, y value.Value) *InstURem {
+	inst := NewURem(x, y)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ srem ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewSRem inserts a new srem instruction at the insertion point of the builder
+// based on the given operands.
+func (b *Builder) NewSRem(x, y value.Value) *InstSRem {
+	inst := NewSRem(x, y)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ frem ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewFRem inserts a new frem instruction at the insertion point of the builder
+// based on the given operands.
+func (b *Builder) NewFRem(x, y value.Value) *InstFRem {
+	inst := NewFRem(x, y)
+	b.insert(inst)
+	return inst
+}
+
+// --- [ Bitwise instructions ] ------------------------------------------------
+
+// ~~~ [ shl ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewShl inserts a new shl instruction at the insertion point of the builder
+// based on the given operands.
+func (b *Builder) NewShl(x, y value.Value) *InstShl {
+	inst := NewShl(x, y)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ lshr ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewLShr inserts a new lshr instruction at the insertion point of the builder
+// based on the given operands.
+func (b *Builder) NewLShr(x, y value.Value) *InstLShr {
+	inst := NewLShr(x, y)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ ashr ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewAShr inserts a new ashr instruction at the insertion point of the builder
+// based on the given operands.
+func (b *Builder) NewAShr(x, y value.Value) *InstAShr {
+	inst := NewAShr(x, y)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ and ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewAnd inserts a new and instruction at the insertion point of the builder
+// based on the given operands.
+func (b *Builder) NewAnd(x, y value.Value) *InstAnd {
+	inst := NewAnd(x, y)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ or ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewOr inserts a new or instruction at the insertion point of the builder
+// based on the given operands.
+func (b *Builder) NewOr(x, y value.Value) *InstOr {
+	inst := NewOr(x, y)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ xor ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewXor inserts a new xor instruction at the insertion point of the builder
+// based on the given operands.
+func (b *Builder) NewXor(x, y value.Value) *InstXor {
+	inst := NewXor(x, y)
+	b.insert(inst)
+	return inst
+}
+
+// --- [ Vector instructions ] -------------------------------------------------
+
+// ~~~ [ extractelement ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewExtractElement inserts a new extractelement instruction at the insertion
+// point of the builder based on the given vector and element index.
+func (b *Builder) NewExtractElement(x, index value.Value) *InstExtractElement {
+	inst := NewExtractElement(x, index)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ insertelement ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewInsertElement inserts a new insertelement instruction at the insertion
+// point of the builder based on the given vector, element and element index.
+func (b *Builder) NewInsertElement(x, elem, index value.Value) *InstInsertElement {
+	inst := NewInsertElement(x, elem, index)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ shufflevector ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewShuffleVector inserts a new shufflevector instruction at the insertion
+// point of the builder based on the given vectors and shuffle mask.
+func (b *Builder) NewShuffleVector(x, y, mask value.Value) *InstShuffleVector {
+	inst := NewShuffleVector(x, y, mask)
+	b.insert(inst)
+	return inst
+}
+
+// --- [ Aggregate instructions ] ----------------------------------------------
+
+// ~~~ [ extractvalue ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewExtractValue inserts a new extractvalue instruction at the insertion point
+// of the builder based on the given aggregate value and indicies.
+func (b *Builder) NewExtractValue(x value.Value, indices ...uint64) *InstExtractValue {
+	inst := NewExtractValue(x, indices...)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ insertvalue ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewInsertValue inserts a new insertvalue instruction at the insertion point
+// of the builder based on the given aggregate value, element and indicies.
+func (b *Builder) NewInsertValue(x, elem value.Value, indices ...uint64) *InstInsertValue {
+	inst := NewInsertValue(x, elem, indices...)
+	b.insert(inst)
+	return inst
+}
+
+// --- [ Memory instructions ] -------------------------------------------------
+
+// ~~~ [ alloca ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewAlloca inserts a new alloca instruction at the insertion point of the
+// builder based on the given element type.
+func (b *Builder) NewAlloca(elemType types.Type) *InstAlloca {
+	inst := NewAlloca(elemType)
+	if f := b.Func(); f != nil && f.Parent != nil && f.Parent.OpaquePointers {
+		inst.Typ = types.NewOpaquePointer(inst.AddrSpace)
+	}
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ load ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewLoad inserts a new load instruction at the insertion point of the builder
+// based on the given element type and source address.
+func (b *Builder) NewLoad(elemType types.Type, src value.Value) *InstLoad {
+	inst := NewLoad(elemType, src)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ store ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewStore inserts a new store instruction at the insertion point of the
+// builder based on the given source value and destination address.
+func (b *Builder) NewStore(src, dst value.Value) *InstStore {
+	inst := NewStore(src, dst)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ fence ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewFence inserts a new fence instruction at the insertion point of the
+// builder based on the given atomic ordering.
+func (b *Builder) NewFence(ordering enum.AtomicOrdering) *InstFence {
+	inst := NewFence(ordering)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ cmpxchg ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewCmpXchg inserts a new cmpxchg instruction at the insertion point of the
+// builder based on the given address, value to compare against, new value to
+// store, and atomic orderings for success and failure.
+func (b *Builder) NewCmpXchg(ptr, cmp, new value.Value, successOrdering, failureOrdering enum.AtomicOrdering) *InstCmpXchg {
+	inst := NewCmpXchg(ptr, cmp, new, successOrdering, failureOrdering)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ atomicrmw ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewAtomicRMW inserts a new atomicrmw instruction at the insertion point of
+// the builder based on the given atomic operation, destination address, operand
+// and atomic ordering.
+func (b *Builder) NewAtomicRMW(op enum.AtomicOp, dst, x value.Value, ordering enum.AtomicOrdering) *InstAtomicRMW {
+	inst := NewAtomicRMW(op, dst, x, ordering)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ getelementptr ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewGetElementPtr inserts a new getelementptr instruction at the insertion
+// point of the builder based on the given element type, source address and
+// element indices.
+func (b *Builder) NewGetElementPtr(elemType types.Type, src value.Value, indices ...value.Value) *InstGetElementPtr {
+	inst := NewGetElementPtr(elemType, src, indices...)
+	b.insert(inst)
+	return inst
+}
+
+// --- [ Conversion instructions ] ---------------------------------------------
+
+// ~~~ [ trunc ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewTrunc inserts a new trunc instruction at the insertion point of the
+// builder based on the given source value and target type.
+func (b *Builder) NewTrunc(from value.Value, to types.Type) *InstTrunc {
+	inst := NewTrunc(from, to)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ zext ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewZExt inserts a new zext instruction at the insertion point of the builder
+// based on the given source value and target type.
+func (b *Builder) NewZExt(from value.Value, to types.Type) *InstZExt {
+	inst := NewZExt(from, to)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ sext ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewSExt inserts a new sext instruction at the insertion point of the builder
+// based on the given source value and target type.
+func (b *Builder) NewSExt(from value.Value, to types.Type) *InstSExt {
+	inst := NewSExt(from, to)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ fptrunc ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewFPTrunc inserts a new fptrunc instruction at the insertion point of the
+// builder based on the given source value and target type.
+func (b *Builder) NewFPTrunc(from value.Value, to types.Type) *InstFPTrunc {
+	inst := NewFPTrunc(from, to)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ fpext ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewFPExt inserts a new fpext instruction at the insertion point of the
+// builder based on the given source value and target type.
+func (b *Builder) NewFPExt(from value.Value, to types.Type) *InstFPExt {
+	inst := NewFPExt(from, to)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ fptoui ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewFPToUI inserts a new fptoui instruction at the insertion point of the
+// builder based on the given source value and target type.
+func (b *Builder) NewFPToUI(from value.Value, to types.Type) *InstFPToUI {
+	inst := NewFPToUI(from, to)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ fptosi ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewFPToSI inserts a new fptosi instruction at the insertion point of the
+// builder based on the given source value and target type.
+func (b *Builder) NewFPToSI(from value.Value, to types.Type) *InstFPToSI {
+	inst := NewFPToSI(from, to)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ uitofp ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewUIToFP inserts a new uitofp instruction at the insertion point of the
+// builder based on the given source value and target type.
+func (b *Builder) NewUIToFP(from value.Value, to types.Type) *InstUIToFP {
+	inst := NewUIToFP(from, to)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ sitofp ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewSIToFP inserts a new sitofp instruction at the insertion point of the
+// builder based on the given source value and target type.
+func (b *Builder) NewSIToFP(from value.Value, to types.Type) *InstSIToFP {
+	inst := NewSIToFP(from, to)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ ptrtoint ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewPtrToInt inserts a new ptrtoint instruction at the insertion point of the
+// builder based on the given source value and target type.
+func (b *Builder) NewPtrToInt(from value.Value, to types.Type) *InstPtrToInt {
+	inst := NewPtrToInt(from, to)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ inttoptr ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewIntToPtr inserts a new inttoptr instruction at the insertion point of the
+// builder based on the given source value and target type.
+func (b *Builder) NewIntToPtr(from value.Value, to types.Type) *InstIntToPtr {
+	inst := NewIntToPtr(from, to)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ bitcast ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewBitCast inserts a new bitcast instruction at the insertion point of the
+// builder based on the given source value and target type.
+func (b *Builder) NewBitCast(from value.Value, to types.Type) *InstBitCast {
+	inst := NewBitCast(from, to)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ addrspacecast ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewAddrSpaceCast inserts a new addrspacecast instruction at the insertion
+// point of the builder based on the given source value and target type.
+func (b *Builder) NewAddrSpaceCast(from value.Value, to types.Type) *InstAddrSpaceCast {
+	inst := NewAddrSpaceCast(from, to)
+	b.insert(inst)
+	return inst
+}
+
+// --- [ Other instructions ] --------------------------------------------------
+
+// ~~~ [ icmp ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewICmp inserts a new icmp instruction at the insertion point of the builder
+// based on the given integer comparison predicate and integer scalar or vector
+// operands.
+func (b *Builder) NewICmp(pred enum.IPred, x, y value.Value) *InstICmp {
+	inst := NewICmp(pred, x, y)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ fcmp ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewFCmp inserts a new fcmp instruction at the insertion point of the builder
+// based on the given floating-point comparison predicate and floating-point
+// scalar or vector operands.
+func (b *Builder) NewFCmp(pred enum.FPred, x, y value.Value) *InstFCmp {
+	inst := NewFCmp(pred, x, y)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ phi ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewPhi inserts a new phi instruction at the insertion point of the builder
+// based on the given incoming values.
+func (b *Builder) NewPhi(incs ...*Incoming) *InstPhi {
+	inst := NewPhi(incs...)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ select ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewSelect inserts a new select instruction at the insertion point of the
+// builder based on the given selection condition and true and false condition
+// values.
+func (b *Builder) NewSelect(cond, valueTrue, valueFalse value.Value) *InstSelect {
+	inst := NewSelect(cond, valueTrue, valueFalse)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ call ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// TODO: specify the set of underlying types of callee in Block.NewCall.
+
+// NewCall inserts a new call instruction at the insertion point of the builder
+// based on the given callee and function arguments.
+func (b *Builder) NewCall(callee value.Value, args ...value.Value) *InstCall {
+	inst := NewCall(callee, args...)
+	b.insert(inst)
+	return inst
+}
+
+// NewCallSig inserts a new call instruction at the insertion point of the
+// builder based on the given function signature, callee and function
+// arguments.
+func (b *Builder) NewCallSig(sig *types.FuncType, callee value.Value, args ...value.Value) *InstCall {
+	inst := NewCallSig(sig, callee, args...)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ va_arg ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewVAArg inserts a new va_arg instruction at the insertion point of the
+// builder based on the given variable argument list and argument type.
+func (b *Builder) NewVAArg(vaList value.Value, argType types.Type) *InstVAArg {
+	inst := NewVAArg(vaList, argType)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ landingpad ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewLandingPad inserts a new landingpad instruction at the insertion point of
+// the builder based on the given result type and filter/catch clauses.
+func (b *Builder) NewLandingPad(resultType types.Type, clauses ...*Clause) *InstLandingPad {
+	inst := NewLandingPad(resultType, clauses...)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ catchpad ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewCatchPad inserts a new catchpad instruction at the insertion point of the
+// builder based on the given parent catchswitch terminator and exception
+// arguments.
+func (b *Builder) NewCatchPad(catchSwitch *TermCatchSwitch, args ...value.Value) *InstCatchPad {
+	inst := NewCatchPad(catchSwitch, args...)
+	b.insert(inst)
+	return inst
+}
+
+// ~~~ [ cleanuppad ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewCleanupPad inserts a new cleanuppad instruction at the insertion point of
+// the builder based on the given parent exception pad and exception arguments.
+func (b *Builder) NewCleanupPad(parentPad ExceptionPad, args ...value.Value) *InstCleanupPad {
+	inst := NewCleanupPad(parentPad, args...)
+	b.insert(inst)
+	return inst
+}
+
+// --- [ Terminators ] ---------------------------------------------------------
+
+// ~~~ [ ret ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewRet sets the terminator of the current basic block of the builder to a new
+// ret terminator based on the given return value. A nil return value indicates
+// a void return.
+func (b *Builder) NewRet(x value.Value) *TermRet {
+	term := NewRet(x)
+	b.setTerm(term)
+	return term
+}
+
+// ~~~ [ br ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewBr sets the terminator of the current basic block of the builder to a new
+// unconditional br terminator based on the given target basic block.
+func (b *Builder) NewBr(target *Block) *TermBr {
+	term := NewBr(target)
+	b.setTerm(term)
+	return term
+}
+
+// ~~~ [ conditional br ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewCondBr sets the terminator of the current basic block of the builder to a
+// new conditional br terminator based on the given branching condition and
+// conditional target basic blocks.
+func (b *Builder) NewCondBr(cond value.Value, targetTrue, targetFalse *Block) *TermCondBr {
+	term := NewCondBr(cond, targetTrue, targetFalse)
+	b.setTerm(term)
+	return term
+}
+
+// ~~~ [ switch ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewSwitch sets the terminator of the current basic block of the builder to a
+// new switch terminator based on the given control variable, default target
+// basic block and switch cases.
+func (b *Builder) NewSwitch(x value.Value, targetDefault *Block, cases ...*Case) *TermSwitch {
+	term := NewSwitch(x, targetDefault, cases...)
+	b.setTerm(term)
+	return term
+}
+
+// ~~~ [ indirectbr ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewIndirectBr sets the terminator of the current basic block of the builder
+// to a new indirectbr terminator based on the given target address (derived
+// from a blockaddress constant of type i8*) and set of valid target basic
+// blocks.
+func (b *Builder) NewIndirectBr(addr value.Value, validTargets ...*Block) *TermIndirectBr {
+	term := NewIndirectBr(addr, validTargets...)
+	b.setTerm(term)
+	return term
+}
+
+// ~~~ [ invoke ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// TODO: specify the set of underlying types of invokee in Block.NewInvoke.
+
+// NewInvoke sets the terminator of the current basic block of the builder to a
+// new invoke terminator based on the given invokee, function arguments and
+// control flow return points for normal and exceptional execution.
+func (b *Builder) NewInvoke(invokee value.Value, args []value.Value, normalRetTarget, exceptionRetTarget *Block) *TermInvoke {
+	term := NewInvoke(invokee, args, normalRetTarget, exceptionRetTarget)
+	b.setTerm(term)
+	return term
+}
+
+// NewInvokeSig sets the terminator of the current basic block of the builder to
+// a new invoke terminator based on the given function signature, invokee,
+// function arguments and control flow return points for normal and exceptional
+// execution.
+func (b *Builder) NewInvokeSig(sig *types.FuncType, invokee value.Value, args []value.Value, normalRetTarget, exceptionRetTarget *Block) *TermInvoke {
+	term := NewInvokeSig(sig, invokee, args, normalRetTarget, exceptionRetTarget)
+	b.setTerm(term)
+	return term
+}
+
+// ~~~ [ callbr ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// TODO: specify the set of underlying types of callee in Block.NewCallBr.
+
+// NewCallBr sets the terminator of the current basic block of the builder to a
+// new callbr terminator based on the given callee, function arguments and
+// control flow return points for normal and exceptional execution.
+func (b *Builder) NewCallBr(callee value.Value, args []value.Value, normalRetTarget *Block, otherRetTargets ...*Block) *TermCallBr {
+	term := NewCallBr(callee, args, normalRetTarget, otherRetTargets...)
+	b.setTerm(term)
+	return term
+}
+
+// NewCallBrSig sets the terminator of the current basic block of the builder to
+// a new callbr terminator based on the given function signature, callee,
+// function arguments and control flow return points for normal and exceptional
+// execution.
+func (b *Builder) NewCallBrSig(sig *types.FuncType, callee value.Value, args []value.Value, normalRetTarget *Block, otherRetTargets ...*Block) *TermCallBr {
+	term := NewCallBrSig(sig, callee, args, normalRetTarget, otherRetTargets...)
+	b.setTerm(term)
+	return term
+}
+
+// ~~~ [ resume ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewResume sets the terminator of the current basic block of the builder to a
+// new resume terminator based on the given exception argument to propagate.
+func (b *Builder) NewResume(x value.Value) *TermResume {
+	term := NewResume(x)
+	b.setTerm(term)
+	return term
+}
+
+// ~~~ [ catchswitch ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewCatchSwitch sets the terminator of the current basic block of the builder
+// to a new catchswitch terminator based on the given parent exception pad,
+// exception handlers and optional default unwind target. If defaultUnwindTarget
+// is nil, catchswitch unwinds to caller function.
+func (b *Builder) NewCatchSwitch(parentPad ExceptionPad, handlers []*Block, defaultUnwindTarget *Block) *TermCatchSwitch {
+	term := NewCatchSwitch(parentPad, handlers, defaultUnwindTarget)
+	b.setTerm(term)
+	return term
+}
+
+// ~~~ [ catchret ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewCatchRet sets the terminator of the current basic block of the builder to
+// a new catchret terminator based on the given exit catchpad and target basic
+// block.
+func (b *Builder) NewCatchRet(catchPad *InstCatchPad, target *Block) *TermCatchRet {
+	term := NewCatchRet(catchPad, target)
+	b.setTerm(term)
+	return term
+}
+
+// ~~~ [ cleanupret ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewCleanupRet sets the terminator of the current basic block of the builder
+// to a new cleanupret terminator based on the given exit cleanuppad and
+// optional unwind target. If unwindTarget is nil, cleanupret unwinds to caller
+// function.
+func (b *Builder) NewCleanupRet(cleanupPad *InstCleanupPad, unwindTarget *Block) *TermCleanupRet {
+	term := NewCleanupRet(cleanupPad, unwindTarget)
+	b.setTerm(term)
+	return term
+}
+
+// ~~~ [ unreachable ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewUnreachable sets the terminator of the current basic block of the builder
+// to a new unreachable terminator.
+func (b *Builder) NewUnreachable() *TermUnreachable {
+	term := NewUnreachable()
+	b.setTerm(term)
+	return term
+}
@@ -0,0 +1,75 @@
+package ir
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/metadata"
+	"github.com/llir/llvm/ir/types"
+)
+
+func TestBuilder(t *testing.T) {
+	m := NewModule()
+	sqrt := m.NewFunc("sqrt", types.Double, NewParam("", types.Double))
+	x := NewParam("x", types.Double)
+	n := NewParam("n", types.I32)
+	f := m.NewFunc("f", types.Double, x, n)
+	b := NewBuilder(f.NewBlock("entry"))
+	// Instructions are appended to the end of the basic block by default.
+	one := constant.NewFloat(types.Double, 1)
+	a := b.NewFAdd(x, one)
+	cond := b.NewICmp(enum.IPredSGT, n, constant.NewInt(types.I32, 0))
+	// Blocks are created in the parent function of the insertion point.
+	loop := b.NewBlock("loop")
+	exit := b.NewBlock("exit")
+	b.NewCondBr(cond, loop, exit)
+	// Insert instructions before an existing instruction, in order of creation.
+	b.SetInsertPointBefore(cond)
+	b.FastMathFlags = []enum.FastMathFlag{enum.FastMathFlagNNaN, enum.FastMathFlagNInf}
+	c := b.NewFMul(a, a)
+	d := b.NewCall(sqrt, c)
+	b.FastMathFlags = nil
+	// Insert instructions after an existing instruction.
+	b.SetInsertPointAfter(a)
+	b.NewFNeg(a)
+	b.SetInsertPointAtEnd(loop)
+	b.DebugLoc = &metadata.DILocation{MetadataID: -1, Line: 3, Column: 7, Scope: &metadata.DISubprogram{MetadataID: 0}}
+	i := b.NewPhi(NewIncoming(constant.NewInt(types.I32, 0), f.Blocks[0]))
+	i1 := b.NewAdd(i, constant.NewInt(types.I32, 1))
+	i.Incs = append(i.Incs, NewIncoming(i1, loop))
+	done := b.NewICmp(enum.IPredEQ, i1, n)
+	b.NewCondBr(done, exit, loop)
+	b.DebugLoc = nil
+	b.SetInsertPointAtEnd(exit)
+	b.NewRet(d)
+	if got := b.Block(); got != exit {
+		t.Errorf("basic block mismatch; expected %s, got %v", exit.Ident(), got)
+	}
+	want := `define double @f(double %x, i32 %n) {
+entry:
+	%0 = fadd double %x, 1.0
+	%1 = fneg double %0
+	%2 = fmul nnan ninf double %0, %0
+	%3 = call nnan ninf double @sqrt(double %2)
+	%4 = icmp sgt i32 %n, 0
+	br i1 %4, label %loop, label %exit
+
+loop:
+	%5 = phi i32 [ 0, %entry ], [ %6, %loop ], !dbg !DILocation(line: 3, column: 7, scope: !0)
+	%6 = add i32 %5, 1, !dbg !DILocation(line: 3, column: 7, scope: !0)
+	%7 = icmp eq i32 %6, %n, !dbg !DILocation(line: 3, column: 7, scope: !0)
+	br i1 %7, label %exit, label %loop, !dbg !DILocation(line: 3, column: 7, scope: !0)
+
+exit:
+	ret double %3
+}`
+	if err := f.AssignIDs(); err != nil {
+		t.Fatalf("unable to assign IDs; %v", err)
+	}
+	got := f.LLString()
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("function mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,317 @@
+// Package cfg provides control flow graphs of LLVM IR functions.
+//
+// The control flow graph of a function has one node per basic block, and one
+// directed edge from each basic block to each of its successors. Besides
+// predecessors and successors, the graph provides a depth-first numbering of
+// the basic blocks reachable from the entry basic block, reverse post-order
+// traversal, and detection of critical edges and unreachable basic blocks.
+//
+// The graph implements the interfaces of gonum.org/v1/gonum/graph in spirit
+// (see Directed), to facilitate the use of generic graph algorithms. The
+// github.com/llir/llvm/ir/cfg/gonum module adapts the graph to gonum.
+package cfg
+
+import (
+	"fmt"
+
+	"github.com/llir/llvm/ir"
+)
+
+// === [ Control flow graph ] ==================================================
+
+// Graph is the control flow graph of a function.
+//
+// The graph is a snapshot of the control flow of the function at the time of
+// creation; it is not updated as the function is modified.
+type Graph struct {
+	// Function of the control flow graph.
+	Func *ir.Func
+	// Nodes of the graph, in order of Func.Blocks; indexed by node ID.
+	nodes []*BlockNode
+	// Node of each basic block.
+	nodeOf map[*ir.Block]*BlockNode
+	// Basic blocks reachable from the entry basic block, in post-order.
+	postOrder []*BlockNode
+}
+
+// New returns the control flow graph of the given function. Successors not
+// part of the function are ignored.
+//
+// The body of the function is materialized if lazily parsed (see
+// ir.Func.Materialize); New panics if materialization fails.
+func New(f *ir.Func) *Graph {
+	if err := f.Materialize(); err != nil {
+		panic(fmt.Errorf("unable to materialize function %q; %v", f.Ident(), err))
+	}
+	g := &Graph{
+		Func:   f,
+		nodes:  make([]*BlockNode, len(f.Blocks)),
+		nodeOf: make(map[*ir.Block]*BlockNode, len(f.Blocks)),
+	}
+	for i, block := range f.Blocks {
+		n := &BlockNode{Block: block, id: int64(i), pre: -1, post: -1}
+		g.nodes[i] = n
+		g.nodeOf[block] = n
+	}
+	for _, n := range g.nodes {
+		for _, succ := range succsOf(n.Block) {
+			s, ok := g.nodeOf[succ]
+			if !ok {
+				// Successors not part of the function (malformed IR) are ignored.
+				continue
+			}
+			if n.hasSucc(s) {
+				// Multiple edges to the same successor (e.g. switch cases with the
+				// same target) are represented by a single edge.
+				continue
+			}
+			n.succs = append(n.succs, s)
+			s.preds = append(s.preds, n)
+		}
+	}
+	if len(g.nodes) > 0 {
+		g.dfs(g.nodes[0])
+	}
+	return g
+}
+
+// Entry returns the entry basic block of the function; or nil if the function
+// has no body.
+func (g *Graph) Entry() *ir.Block {
+	if len(g.nodes) == 0 {
+		return nil
+	}
+	return g.nodes[0].Block
+}
+
+// BlockNode returns the node of the given basic block; or nil if the basic
+// block is not part of the graph.
+func (g *Graph) BlockNode(block *ir.Block) *BlockNode {
+	return g.nodeOf[block]
+}
+
+// Succs returns the unique successor basic blocks of the given basic block, in
+// operand order of its terminator.
+func (g *Graph) Succs(block *ir.Block) []*ir.Block {
+	return blocksOf(g.node(block).succs)
+}
+
+// Preds returns the unique predecessor basic blocks of the given basic block,
+// in order of Func.Blocks.
+func (g *Graph) Preds(block *ir.Block) []*ir.Block {
+	return blocksOf(g.node(block).preds)
+}
+
+// PostOrder returns the basic blocks reachable from the entry basic block, in
+// depth-first post-order.
+func (g *Graph) PostOrder() []*ir.Block {
+	return blocksOf(g.postOrder)
+}
+
+// ReversePostOrder returns the basic blocks reachable from the entry basic
+// block, in reverse depth-first post-order; each basic block is visited before
+// its successors, except for successors reached through back edges.
+func (g *Graph) ReversePostOrder() []*ir.Block {
+	blocks := make([]*ir.Block, len(g.postOrder))
+	for i, n := range g.postOrder {
+		blocks[len(blocks)-1-i] = n.Block
+	}
+	return blocks
+}
+
+// PreNum returns the depth-first pre-order number of the given basic block; or
+// -1 if unreachable from the entry basic block.
+func (g *Graph) PreNum(block *ir.Block) int {
+	return g.node(block).pre
+}
+
+// PostNum returns the depth-first post-order number of the given basic block;
+// or -1 if unreachable from the entry basic block.
+func (g *Graph) PostNum(block *ir.Block) int {
+	return g.node(block).post
+}
+
+// Reachable reports whether the given basic block is reachable from the entry
+// basic block.
+func (g *Graph) Reachable(block *ir.Block) bool {
+	return g.node(block).pre != -1
+}
+
+// Unreachable returns the basic blocks unreachable from the entry basic block,
+// in order of Func.Blocks.
+func (g *Graph) Unreachable() []*ir.Block {
+	var blocks []*ir.Block
+	for _, n := range g.nodes {
+		if n.pre == -1 {
+			blocks = append(blocks, n.Block)
+		}
+	}
+	return blocks
+}
+
+// IsBackEdge reports whether the edge from -> to is a retreating edge of the
+// depth-first traversal; i.e. to is an ancestor of from in the depth-first
+// spanning tree (or from is to). Both basic blocks must be reachable.
+func (g *Graph) IsBackEdge(from, to *ir.Block) bool {
+	f, t := g.node(from), g.node(to)
+	if f.pre == -1 || t.pre == -1 || !f.hasSucc(t) {
+		return false
+	}
+	// to is an ancestor of from if visited before and finished after from.
+	return t.pre <= f.pre && t.post >= f.post
+}
+
+// IsCriticalEdge reports whether the edge from -> to is a critical edge; i.e.
+// from has multiple successors and to has multiple predecessors.
+func (g *Graph) IsCriticalEdge(from, to *ir.Block) bool {
+	f, t := g.node(from), g.node(to)
+	return f.hasSucc(t) && len(f.succs) > 1 && len(t.preds) > 1
+}
+
+// CriticalEdges returns the critical edges of the graph, in order of
+// Func.Blocks and successor order.
+func (g *Graph) CriticalEdges() []*BlockEdge {
+	var edges []*BlockEdge
+	for _, f := range g.nodes {
+		if len(f.succs) < 2 {
+			continue
+		}
+		for _, t := range f.succs {
+			if len(t.preds) > 1 {
+				edges = append(edges, &BlockEdge{F: f, T: t})
+			}
+		}
+	}
+	return edges
+}
+
+// node returns the node of the given basic block, panicking if not part of the
+// graph.
+func (g *Graph) node(block *ir.Block) *BlockNode {
+	n, ok := g.nodeOf[block]
+	if !ok {
+		panic(fmt.Errorf("basic block %s not part of function %s", block.Ident(), g.Func.Ident()))
+	}
+	return n
+}
+
+// dfs performs an iterative depth-first traversal from the given entry node,
+// assigning pre-order and post-order numbers.
+func (g *Graph) dfs(entry *BlockNode) {
+	type frame struct {
+		n    *BlockNode
+		next int // index of next successor to visit
+	}
+	pre := 0
+	entry.pre = pre
+	pre++
+	stack := []frame{{n: entry}}
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		if top.next < len(top.n.succs) {
+			succ := top.n.succs[top.next]
+			top.next++
+			if succ.pre == -1 {
+				succ.pre = pre
+				pre++
+				stack = append(stack, frame{n: succ})
+			}
+			continue
+		}
+		top.n.post = len(g.postOrder)
+		g.postOrder = append(g.postOrder, top.n)
+		stack = stack[:len(stack)-1]
+	}
+}
+
+// --- [ Nodes ] ---------------------------------------------------------------
+
+// BlockNode is a basic block node of a control flow graph.
+type BlockNode struct {
+	// Basic block of the node.
+	Block *ir.Block
+	// Node ID; index of the basic block in Func.Blocks.
+	id int64
+	// Unique successors, in operand order of the terminator.
+	succs []*BlockNode
+	// Unique predecessors, in order of Func.Blocks.
+	preds []*BlockNode
+	// Depth-first pre-order and post-order numbers; -1 if unreachable.
+	pre, post int
+}
+
+// ID returns the ID of the node; the index of the basic block in Func.Blocks.
+func (n *BlockNode) ID() int64 {
+	return n.id
+}
+
+// String returns a string representation of the node.
+func (n *BlockNode) String() string {
+	return n.Block.Ident()
+}
+
+// hasSucc reports whether s is a successor of n.
+func (n *BlockNode) hasSucc(s *BlockNode) bool {
+	for _, succ := range n.succs {
+		if succ == s {
+			return true
+		}
+	}
+	return false
+}
+
+// --- [ Edges ] ---------------------------------------------------------------
+
+// BlockEdge is a control flow edge between two basic blocks.
+type BlockEdge struct {
+	// Source and target nodes.
+	F, T *BlockNode
+}
+
+// From returns the source node of the edge.
+func (e *BlockEdge) From() Node {
+	return e.F
+}
+
+// To returns the target node of the edge.
+func (e *BlockEdge) To() Node {
+	return e.T
+}
+
+// ReversedEdge returns a new edge with source and target swapped.
+func (e *BlockEdge) ReversedEdge() Edge {
+	return &BlockEdge{F: e.T, T: e.F}
+}
+
+// String returns a string representation of the edge.
+func (e *BlockEdge) String() string {
+	return fmt.Sprintf("%s -> %s", e.F, e.T)
+}
+
+// ### [ Helper functions ] ####################################################
+
+// succsOf returns the successor basic blocks of the given basic block, in
+// operand order of its terminator (including duplicates). The successors are
+// computed from the operands of the terminator, as cached successors of
+// terminators may be stale.
+func succsOf(block *ir.Block) []*ir.Block {
+	if block.Term == nil {
+		return nil
+	}
+	var succs []*ir.Block
+	for _, op := range block.Term.Operands() {
+		if succ, ok := (*op).(*ir.Block); ok {
+			succs = append(succs, succ)
+		}
+	}
+	return succs
+}
+
+// blocksOf returns the basic blocks of the given nodes.
+func blocksOf(nodes []*BlockNode) []*ir.Block {
+	blocks := make([]*ir.Block, len(nodes))
+	for i, n := range nodes {
+		blocks[i] = n.Block
+	}
+	return blocks
+}
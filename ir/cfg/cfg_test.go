@@ -0,0 +1,146 @@
+package cfg_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/llir/llvm/asm"
+	"github.com/llir/llvm/internal/irtest"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/cfg"
+)
+
+func TestGraph(t *testing.T) {
+	m, err := asm.ParseFile("testdata/cfg.ll")
+	if err != nil {
+		t.Fatalf("unable to parse module; %+v", err)
+	}
+	f := m.Funcs[0]
+	g := cfg.New(f)
+	blocks := make(map[string]*ir.Block)
+	for _, block := range f.Blocks {
+		blocks[block.Name()] = block
+	}
+
+	// Predecessors and successors.
+	golden := []struct {
+		block        string
+		preds, succs string
+	}{
+		{block: "entry", preds: "[]", succs: "[%loop %a %exit]"},
+		{block: "a", preds: "[%entry]", succs: "[%exit]"},
+		{block: "loop", preds: "[%entry %loop]", succs: "[%loop %exit]"},
+		{block: "dead", preds: "[]", succs: "[%exit]"},
+		{block: "exit", preds: "[%entry %a %loop %dead]", succs: "[]"},
+	}
+	for _, gold := range golden {
+		block := blocks[gold.block]
+		if got := irtest.Idents(g.Preds(block)); got != gold.preds {
+			t.Errorf("predecessors of %%%s mismatch; expected %s, got %s", gold.block, gold.preds, got)
+		}
+		if got := irtest.Idents(g.Succs(block)); got != gold.succs {
+			t.Errorf("successors of %%%s mismatch; expected %s, got %s", gold.block, gold.succs, got)
+		}
+	}
+
+	// Traversal order and depth-first numbering.
+	if got, want := irtest.Idents(g.ReversePostOrder()), "[%entry %a %loop %exit]"; got != want {
+		t.Errorf("reverse post-order mismatch; expected %s, got %s", want, got)
+	}
+	if got, want := irtest.Idents(g.PostOrder()), "[%exit %loop %a %entry]"; got != want {
+		t.Errorf("post-order mismatch; expected %s, got %s", want, got)
+	}
+	wantNums := map[string][2]int{
+		"entry": {0, 3},
+		"loop":  {1, 1},
+		"exit":  {2, 0},
+		"a":     {3, 2},
+		"dead":  {-1, -1},
+	}
+	for name, want := range wantNums {
+		got := [2]int{g.PreNum(blocks[name]), g.PostNum(blocks[name])}
+		if got != want {
+			t.Errorf("depth-first numbers of %%%s mismatch; expected %v, got %v", name, want, got)
+		}
+	}
+	if !g.IsBackEdge(blocks["loop"], blocks["loop"]) {
+		t.Errorf("expected %%loop -> %%loop to be a back edge")
+	}
+	if g.IsBackEdge(blocks["a"], blocks["exit"]) {
+		t.Errorf("expected %%a -> %%exit not to be a back edge")
+	}
+
+	// Unreachable basic blocks.
+	if got, want := irtest.Idents(g.Unreachable()), "[%dead]"; got != want {
+		t.Errorf("unreachable basic blocks mismatch; expected %s, got %s", want, got)
+	}
+	if g.Reachable(blocks["dead"]) {
+		t.Errorf("expected %%dead to be unreachable")
+	}
+
+	// Critical edges.
+	var edges []string
+	for _, e := range g.CriticalEdges() {
+		edges = append(edges, e.String())
+	}
+	wantEdges := []string{"%entry -> %loop", "%entry -> %exit", "%loop -> %loop", "%loop -> %exit"}
+	if diff := cmp.Diff(wantEdges, edges); diff != "" {
+		t.Errorf("critical edges mismatch (-want +got):\n%s", diff)
+	}
+	if g.IsCriticalEdge(blocks["entry"], blocks["a"]) {
+		t.Errorf("expected %%entry -> %%a not to be a critical edge")
+	}
+}
+
+func TestGraphLazy(t *testing.T) {
+	// Function bodies of lazily parsed modules are materialized by New.
+	c := &asm.Config{Lazy: true}
+	m, err := c.ParseFile("testdata/cfg.ll")
+	if err != nil {
+		t.Fatalf("unable to lazily parse module; %+v", err)
+	}
+	g := cfg.New(m.Funcs[0])
+	if got, want := irtest.Idents(g.ReversePostOrder()), "[%entry %a %loop %exit]"; got != want {
+		t.Errorf("reverse post-order mismatch; expected %s, got %s", want, got)
+	}
+}
+
+func TestDirected(t *testing.T) {
+	m, err := asm.ParseFile("testdata/cfg.ll")
+	if err != nil {
+		t.Fatalf("unable to parse module; %+v", err)
+	}
+	var g cfg.Directed = cfg.New(m.Funcs[0])
+	nodes := g.Nodes()
+	if got, want := nodes.Len(), 5; got != want {
+		t.Errorf("number of nodes mismatch; expected %d, got %d", want, got)
+	}
+	var ids []int64
+	for nodes.Next() {
+		ids = append(ids, nodes.Node().ID())
+	}
+	if diff := cmp.Diff([]int64{0, 1, 2, 3, 4}, ids); diff != "" {
+		t.Errorf("node IDs mismatch (-want +got):\n%s", diff)
+	}
+	if nodes.Len() != 0 || nodes.Node() != nil {
+		t.Errorf("expected exhausted iterator")
+	}
+	// entry (0) -> a (1)
+	if !g.HasEdgeFromTo(0, 1) || g.HasEdgeFromTo(1, 0) || !g.HasEdgeBetween(1, 0) {
+		t.Errorf("edge mismatch between nodes 0 and 1")
+	}
+	e := g.Edge(0, 1)
+	if e == nil || e.From().ID() != 0 || e.To().ID() != 1 {
+		t.Errorf("invalid edge from node 0 to node 1; got %v", e)
+	}
+	if r := e.ReversedEdge(); r.From().ID() != 1 || r.To().ID() != 0 {
+		t.Errorf("invalid reversed edge; got %v", r)
+	}
+	if g.Edge(1, 0) != nil || g.Node(5) != nil {
+		t.Errorf("expected nil edge and node")
+	}
+	// exit (4) has 4 predecessors.
+	if got := g.To(4).Len(); got != 4 {
+		t.Errorf("number of predecessors of node 4 mismatch; expected 4, got %d", got)
+	}
+}
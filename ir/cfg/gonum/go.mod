@@ -0,0 +1,17 @@
+module github.com/llir/llvm/ir/cfg/gonum
+
+go 1.18
+
+require (
+	github.com/llir/llvm v0.3.7-0.20261017040946-944095704704
+	gonum.org/v1/gonum v0.12.0
+)
+
+require (
+	github.com/mewmew/float v0.0.0-20201204173432-505706aa38fa // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+)
+
+// The llvm module of the enclosing repository is used during development;
+// users of the adapter depend on the version of the llvm module required above.
+replace github.com/llir/llvm => ../../..
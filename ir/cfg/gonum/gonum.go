@@ -0,0 +1,107 @@
+// Package gonum adapts control flow graphs of LLVM IR functions to the graph
+// interfaces of gonum.org/v1/gonum/graph, so that the graph algorithms of gonum
+// (e.g. topological sort, strongly connected components, shortest paths) may be
+// used on control flow graphs.
+//
+// The adapter is provided as a separate module, so that users of the cfg
+// package do not depend on gonum.
+package gonum
+
+import (
+	"github.com/llir/llvm/ir/cfg"
+	"gonum.org/v1/gonum/graph"
+)
+
+// === [ Directed graph ] ======================================================
+
+// Graph is a control flow graph implementing the graph.Directed interface of
+// gonum. Nodes of the graph are of type *cfg.BlockNode.
+type Graph struct {
+	// Control flow graph.
+	*cfg.Graph
+}
+
+// Assert that Graph implements the graph.Directed interface.
+var _ graph.Directed = (*Graph)(nil)
+
+// New returns a gonum directed graph of the given control flow graph.
+func New(g *cfg.Graph) *Graph {
+	return &Graph{Graph: g}
+}
+
+// Node returns the node with the given ID if it exists in the graph, and nil
+// otherwise.
+func (g *Graph) Node(id int64) graph.Node {
+	n := g.Graph.Node(id)
+	if n == nil {
+		return nil
+	}
+	return n
+}
+
+// Nodes returns all the nodes in the graph, in order of Func.Blocks.
+func (g *Graph) Nodes() graph.Nodes {
+	return &nodes{Nodes: g.Graph.Nodes()}
+}
+
+// From returns all nodes that can be reached directly from the node with the
+// given ID.
+func (g *Graph) From(id int64) graph.Nodes {
+	return &nodes{Nodes: g.Graph.From(id)}
+}
+
+// To returns all nodes that can reach directly to the node with the given ID.
+func (g *Graph) To(id int64) graph.Nodes {
+	return &nodes{Nodes: g.Graph.To(id)}
+}
+
+// Edge returns the edge from u to v, with IDs uid and vid, if such an edge
+// exists and nil otherwise.
+func (g *Graph) Edge(uid, vid int64) graph.Edge {
+	e := g.Graph.Edge(uid, vid)
+	if e == nil {
+		return nil
+	}
+	return edge{Edge: e}
+}
+
+// --- [ Edge ] ----------------------------------------------------------------
+
+// edge is a control flow edge implementing the graph.Edge interface of gonum.
+type edge struct {
+	// Control flow edge.
+	cfg.Edge
+}
+
+// From returns the source node of the edge.
+func (e edge) From() graph.Node {
+	return e.Edge.From()
+}
+
+// To returns the target node of the edge.
+func (e edge) To() graph.Node {
+	return e.Edge.To()
+}
+
+// ReversedEdge returns the edge reversal of the receiver.
+func (e edge) ReversedEdge() graph.Edge {
+	return edge{Edge: e.Edge.ReversedEdge()}
+}
+
+// --- [ Node iterator ] -------------------------------------------------------
+
+// nodes is a node iterator implementing the graph.Nodes interface of gonum.
+type nodes struct {
+	// Node iterator of control flow graph.
+	cfg.Nodes
+}
+
+// Node returns the current node of the iterator; or nil if the iterator is not
+// positioned at a node.
+func (it *nodes) Node() graph.Node {
+	n := it.Nodes.Node()
+	if n == nil {
+		return nil
+	}
+	return n
+}
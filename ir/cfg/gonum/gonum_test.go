@@ -0,0 +1,68 @@
+package gonum
+
+import (
+	"testing"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/cfg"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"gonum.org/v1/gonum/graph/topo"
+)
+
+func TestTopoSort(t *testing.T) {
+	// define void @f(i1 %cond) {
+	// entry:
+	//    br i1 %cond, label %then, label %else
+	//
+	// then:
+	//    br label %exit
+	//
+	// else:
+	//    br label %exit
+	//
+	// exit:
+	//    ret void
+	// }
+	m := ir.NewModule()
+	cond := ir.NewParam("cond", types.I1)
+	f := m.NewFunc("f", types.Void, cond)
+	entry := f.NewBlock("entry")
+	then := f.NewBlock("then")
+	els := f.NewBlock("else")
+	exit := f.NewBlock("exit")
+	entry.NewCondBr(cond, then, els)
+	then.NewBr(exit)
+	els.NewBr(exit)
+	exit.NewRet(nil)
+
+	g := New(cfg.New(f))
+	sorted, err := topo.Sort(g)
+	if err != nil {
+		t.Fatalf("unable to sort control flow graph topologically; %v", err)
+	}
+	if len(sorted) != len(f.Blocks) {
+		t.Fatalf("number of sorted nodes mismatch; expected %d, got %d", len(f.Blocks), len(sorted))
+	}
+	pos := make(map[*ir.Block]int)
+	for i, n := range sorted {
+		pos[n.(*cfg.BlockNode).Block] = i
+	}
+	edges := []struct{ from, to *ir.Block }{
+		{from: entry, to: then},
+		{from: entry, to: els},
+		{from: then, to: exit},
+		{from: els, to: exit},
+	}
+	for _, e := range edges {
+		if pos[e.from] >= pos[e.to] {
+			t.Errorf("basic block %s sorted after successor %s", e.from.Ident(), e.to.Ident())
+		}
+	}
+
+	// Add loop back edge from exit to entry; the graph is no longer acyclic.
+	exit.Term = ir.NewCondBr(constant.False, entry, exit)
+	if _, err := topo.Sort(New(cfg.New(f))); err == nil {
+		t.Errorf("expected error for topological sort of cyclic control flow graph")
+	}
+}
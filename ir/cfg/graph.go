@@ -0,0 +1,181 @@
+package cfg
+
+// === [ Graph interfaces ] ====================================================
+
+// The following interfaces mirror the interfaces of the same name in
+// gonum.org/v1/gonum/graph, so that generic graph algorithms may be used on
+// control flow graphs. As Go requires identical method signatures, a thin
+// adapter is needed to pass a control flow graph to gonum directly; see package
+// github.com/llir/llvm/ir/cfg/gonum.
+
+// Node is a graph node.
+type Node interface {
+	// ID returns a graph-unique integer ID.
+	ID() int64
+}
+
+// Edge is a directed graph edge.
+type Edge interface {
+	// From returns the source node of the edge.
+	From() Node
+	// To returns the target node of the edge.
+	To() Node
+	// ReversedEdge returns the edge reversal of the receiver.
+	ReversedEdge() Edge
+}
+
+// Iterator is an item iterator.
+type Iterator interface {
+	// Next advances the iterator and returns whether the next call to the item
+	// method will return a non-nil item.
+	Next() bool
+	// Len returns the number of items remaining in the iterator.
+	Len() int
+	// Reset returns the iterator to its start position.
+	Reset()
+}
+
+// Nodes is a node iterator.
+type Nodes interface {
+	Iterator
+	// Node returns the current node of the iterator.
+	Node() Node
+}
+
+// Directed is a directed graph.
+type Directed interface {
+	// Node returns the node with the given ID if it exists in the graph, and nil
+	// otherwise.
+	Node(id int64) Node
+	// Nodes returns all the nodes in the graph.
+	Nodes() Nodes
+	// From returns all nodes that can be reached directly from the node with
+	// the given ID.
+	From(id int64) Nodes
+	// HasEdgeBetween returns whether an edge exists between nodes with IDs xid
+	// and yid without considering direction.
+	HasEdgeBetween(xid, yid int64) bool
+	// Edge returns the edge from u to v, with IDs uid and vid, if such an edge
+	// exists and nil otherwise.
+	Edge(uid, vid int64) Edge
+	// HasEdgeFromTo returns whether an edge exists in the graph from u to v
+	// with IDs uid and vid.
+	HasEdgeFromTo(uid, vid int64) bool
+	// To returns all nodes that can reach directly to the node with the given
+	// ID.
+	To(id int64) Nodes
+}
+
+// Assert that Graph implements the Directed interface.
+var _ Directed = (*Graph)(nil)
+
+// --- [ Directed graph implementation ] ---------------------------------------
+
+// Node returns the node with the given ID if it exists in the graph, and nil
+// otherwise.
+func (g *Graph) Node(id int64) Node {
+	if n := g.nodeByID(id); n != nil {
+		return n
+	}
+	return nil
+}
+
+// Nodes returns all the nodes in the graph, in order of Func.Blocks.
+func (g *Graph) Nodes() Nodes {
+	return newNodeIterator(g.nodes)
+}
+
+// From returns all nodes that can be reached directly from the node with the
+// given ID.
+func (g *Graph) From(id int64) Nodes {
+	n := g.nodeByID(id)
+	if n == nil {
+		return newNodeIterator(nil)
+	}
+	return newNodeIterator(n.succs)
+}
+
+// To returns all nodes that can reach directly to the node with the given ID.
+func (g *Graph) To(id int64) Nodes {
+	n := g.nodeByID(id)
+	if n == nil {
+		return newNodeIterator(nil)
+	}
+	return newNodeIterator(n.preds)
+}
+
+// HasEdgeBetween returns whether an edge exists between nodes with IDs xid and
+// yid without considering direction.
+func (g *Graph) HasEdgeBetween(xid, yid int64) bool {
+	return g.HasEdgeFromTo(xid, yid) || g.HasEdgeFromTo(yid, xid)
+}
+
+// HasEdgeFromTo returns whether an edge exists in the graph from u to v with
+// IDs uid and vid.
+func (g *Graph) HasEdgeFromTo(uid, vid int64) bool {
+	u, v := g.nodeByID(uid), g.nodeByID(vid)
+	return u != nil && v != nil && u.hasSucc(v)
+}
+
+// Edge returns the edge from u to v, with IDs uid and vid, if such an edge
+// exists and nil otherwise.
+func (g *Graph) Edge(uid, vid int64) Edge {
+	if !g.HasEdgeFromTo(uid, vid) {
+		return nil
+	}
+	return &BlockEdge{F: g.nodes[uid], T: g.nodes[vid]}
+}
+
+// nodeByID returns the node with the given ID; or nil if not present.
+func (g *Graph) nodeByID(id int64) *BlockNode {
+	if id < 0 || id >= int64(len(g.nodes)) {
+		return nil
+	}
+	return g.nodes[id]
+}
+
+// --- [ Node iterator ] -------------------------------------------------------
+
+// nodeIterator is an iterator over a list of nodes.
+type nodeIterator struct {
+	// Nodes to iterate over.
+	nodes []*BlockNode
+	// Index of the current node; -1 before the first call to Next.
+	cur int
+}
+
+// newNodeIterator returns a new iterator over the given nodes.
+func newNodeIterator(nodes []*BlockNode) *nodeIterator {
+	return &nodeIterator{nodes: nodes, cur: -1}
+}
+
+// Next advances the iterator and returns whether the next call to Node will
+// return a non-nil node.
+func (it *nodeIterator) Next() bool {
+	if it.cur < len(it.nodes) {
+		it.cur++
+	}
+	return it.cur < len(it.nodes)
+}
+
+// Len returns the number of nodes remaining in the iterator.
+func (it *nodeIterator) Len() int {
+	if it.cur >= len(it.nodes) {
+		return 0
+	}
+	return len(it.nodes) - (it.cur + 1)
+}
+
+// Reset returns the iterator to its start position.
+func (it *nodeIterator) Reset() {
+	it.cur = -1
+}
+
+// Node returns the current node of the iterator; or nil if the iterator is not
+// positioned at a node.
+func (it *nodeIterator) Node() Node {
+	if it.cur < 0 || it.cur >= len(it.nodes) {
+		return nil
+	}
+	return it.nodes[it.cur]
+}
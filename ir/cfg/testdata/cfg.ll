@@ -0,0 +1,22 @@
+define i32 @f(i32 %x, i1 %cond) {
+entry:
+	switch i32 %x, label %loop [
+		i32 1, label %a
+		i32 2, label %a
+		i32 3, label %exit
+	]
+
+a:
+	br label %exit
+
+loop:
+	%i = phi i32 [ 0, %entry ], [ %j, %loop ]
+	%j = add i32 %i, 1
+	br i1 %cond, label %loop, label %exit
+
+dead:
+	br label %exit
+
+exit:
+	ret i32 0
+}
@@ -0,0 +1,547 @@
+package ir
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/metadata"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// === [ Cloning ] =============================================================
+
+// Clone returns a deep copy of the function, and a map from the parameters,
+// basic blocks and instructions of f to their counterparts in the copy.
+//
+// The copy has the same name and parent module as f, but is not added to the
+// functions of the parent module. Values defined outside of the function (e.g.
+// global variables and functions, including f itself in recursive calls),
+// types and metadata nodes are shared between f and the copy. Metadata
+// attachments are copied, and refer to the same metadata nodes.
+func (f *Func) Clone() (*Func, map[value.Value]value.Value) {
+	c := newCloner(false)
+	dup := c.newFunc(f)
+	dup.Parent = f.Parent
+	users := c.newBody(f, dup)
+	c.remapBody(f, dup, users)
+	return dup, c.vmap
+}
+
+// Clone returns a deep copy of the module, and a map from the global
+// variables, functions, aliases and IFuncs of m, and the parameters, basic
+// blocks and instructions of its functions, to their counterparts in the copy.
+//
+// Metadata nodes, comdat definitions and attribute group definitions are
+// copied. Types are shared between m and the copy.
+func (m *Module) Clone() (*Module, map[value.Value]value.Value) {
+	c := newCloner(true)
+	dup := &Module{
+		TypeDefs:          append([]types.Type(nil), m.TypeDefs...),
+		SourceFilename:    m.SourceFilename,
+		DataLayout:        m.DataLayout,
+		TargetTriple:      m.TargetTriple,
+		ModuleAsms:        append([]string(nil), m.ModuleAsms...),
+		NamedMetadataDefs: make(map[string]*metadata.NamedDef),
+		OpaquePointers:    m.OpaquePointers,
+	}
+	for _, def := range m.ComdatDefs {
+		dup.ComdatDefs = append(dup.ComdatDefs, c.comdat(def))
+	}
+	for _, def := range m.AttrGroupDefs {
+		dup.AttrGroupDefs = append(dup.AttrGroupDefs, c.attrGroup(def))
+	}
+	// Create global values before cloning their initializers and bodies, so
+	// that references between global values are remapped.
+	for _, g := range m.Globals {
+		newGlobal := *g
+		newGlobal.Comdat = c.comdat(g.Comdat)
+		newGlobal.FuncAttrs = c.funcAttrs(g.FuncAttrs)
+		c.vmap[g] = &newGlobal
+		dup.Globals = append(dup.Globals, &newGlobal)
+	}
+	for _, f := range m.Funcs {
+		newFunc := c.newFunc(f)
+		newFunc.Parent = dup
+		c.vmap[f] = newFunc
+		dup.Funcs = append(dup.Funcs, newFunc)
+	}
+	for _, a := range m.Aliases {
+		newAlias := *a
+		c.vmap[a] = &newAlias
+		dup.Aliases = append(dup.Aliases, &newAlias)
+	}
+	for _, i := range m.IFuncs {
+		newIFunc := *i
+		c.vmap[i] = &newIFunc
+		dup.IFuncs = append(dup.IFuncs, &newIFunc)
+	}
+	// Create basic blocks and instructions of functions before remapping
+	// initializers, aliasees, resolvers and function bodies, so that references
+	// to basic blocks (e.g. blockaddress constants) are remapped.
+	bodies := make([][]value.User, len(m.Funcs))
+	for i, f := range m.Funcs {
+		bodies[i] = c.newBody(f, dup.Funcs[i])
+	}
+	for _, g := range dup.Globals {
+		if g.Init != nil {
+			g.Init = c.remapConst(g.Init)
+		}
+		g.Metadata = c.attachments(g.Metadata)
+	}
+	for i, f := range m.Funcs {
+		c.remapBody(f, dup.Funcs[i], bodies[i])
+	}
+	for _, a := range dup.Aliases {
+		a.Aliasee = c.remapConst(a.Aliasee)
+	}
+	for _, i := range dup.IFuncs {
+		i.Resolver = c.remapConst(i.Resolver)
+	}
+	for name, def := range m.NamedMetadataDefs {
+		dup.NamedMetadataDefs[name] = c.remapMetadata(def).(*metadata.NamedDef)
+	}
+	for _, def := range m.MetadataDefs {
+		dup.MetadataDefs = append(dup.MetadataDefs, c.remapMetadata(def).(metadata.Definition))
+	}
+	dup.UseListOrders = c.useListOrders(m.UseListOrders)
+	for _, u := range m.UseListOrderBBs {
+		newUseListOrderBB := &UseListOrderBB{
+			Func:    c.vmap[u.Func].(*Func),
+			Block:   c.remapValue(u.Block).(*Block),
+			Indices: append([]uint64(nil), u.Indices...),
+		}
+		dup.UseListOrderBBs = append(dup.UseListOrderBBs, newUseListOrderBB)
+	}
+	return dup, c.vmap
+}
+
+// cloner creates deep copies of functions and modules.
+type cloner struct {
+	// Map from original values to their copies.
+	vmap map[value.Value]value.Value
+	// Remapped constants, indexed by original constant; or the original
+	// constant if unchanged.
+	consts map[constant.Constant]constant.Constant
+	// module specifies whether an entire module is cloned, in which case
+	// metadata nodes, comdat definitions and attribute group definitions are
+	// copied; otherwise they are shared with the original.
+	module bool
+	// Copies of metadata nodes, indexed by original metadata node.
+	mds map[interface{}]interface{}
+	// Copies of comdat definitions, indexed by original comdat definition.
+	comdats map[*ComdatDef]*ComdatDef
+	// Copies of attribute group definitions, indexed by original attribute
+	// group definition.
+	attrGroups map[*AttrGroupDef]*AttrGroupDef
+}
+
+// newCloner returns a new cloner. The module parameter specifies whether an
+// entire module is cloned.
+func newCloner(module bool) *cloner {
+	return &cloner{
+		vmap:       make(map[value.Value]value.Value),
+		consts:     make(map[constant.Constant]constant.Constant),
+		module:     module,
+		mds:        make(map[interface{}]interface{}),
+		comdats:    make(map[*ComdatDef]*ComdatDef),
+		attrGroups: make(map[*AttrGroupDef]*AttrGroupDef),
+	}
+}
+
+// newFunc returns a copy of the given function header, including parameters
+// but excluding basic blocks.
+func (c *cloner) newFunc(f *Func) *Func {
+	dup := &Func{
+		GlobalIdent:     f.GlobalIdent,
+		Sig:             f.Sig,
+		Typ:             f.Typ,
+		Linkage:         f.Linkage,
+		Preemption:      f.Preemption,
+		Visibility:      f.Visibility,
+		DLLStorageClass: f.DLLStorageClass,
+		CallingConv:     f.CallingConv,
+		ReturnAttrs:     append([]ReturnAttribute(nil), f.ReturnAttrs...),
+		UnnamedAddr:     f.UnnamedAddr,
+		AddrSpace:       f.AddrSpace,
+		FuncAttrs:       c.funcAttrs(f.FuncAttrs),
+		Section:         f.Section,
+		Partition:       f.Partition,
+		Comdat:          c.comdat(f.Comdat),
+		Align:           f.Align,
+		GC:              f.GC,
+	}
+	for _, param := range f.Params {
+		newParam := &Param{
+			LocalIdent: param.LocalIdent,
+			Typ:        param.Typ,
+			Attrs:      append([]ParamAttribute(nil), param.Attrs...),
+		}
+		c.vmap[param] = newParam
+		dup.Params = append(dup.Params, newParam)
+	}
+	return dup
+}
+
+// newBody creates copies of the basic blocks, instructions and terminators of f
+// in dup, and returns the copied instructions and terminators, the operands of
+// which are remapped by remapBody.
+func (c *cloner) newBody(f, dup *Func) []value.User {
+	// Materialize function body if lazily parsed.
+	mustMaterialize(f)
+	// Create basic blocks, instructions and terminators before remapping
+	// operands, so that forward references are remapped.
+	var users []value.User
+	for _, block := range f.Blocks {
+		newBlock := &Block{LocalIdent: block.LocalIdent, Parent: dup}
+		c.vmap[block] = newBlock
+		dup.Blocks = append(dup.Blocks, newBlock)
+		for _, inst := range block.Insts {
+			newInst := cloneUser(inst).(Instruction)
+			if v, ok := inst.(value.Value); ok {
+				c.vmap[v] = newInst.(value.Value)
+			}
+			newBlock.Insts = append(newBlock.Insts, newInst)
+			users = append(users, newInst)
+		}
+		if block.Term != nil {
+			newTerm := cloneUser(block.Term).(Terminator)
+			if v, ok := block.Term.(value.Value); ok {
+				c.vmap[v] = newTerm.(value.Value)
+			}
+			resetSuccs(newTerm)
+			newBlock.Term = newTerm
+			users = append(users, newTerm)
+		}
+	}
+	return users
+}
+
+// remapBody remaps the operands of the given copied instructions and
+// terminators of dup, along with the remaining function properties of f
+// referring to values (e.g. prefix data and metadata attachments).
+func (c *cloner) remapBody(f, dup *Func, users []value.User) {
+	for _, user := range users {
+		c.remapUser(user)
+	}
+	if f.Prefix != nil {
+		dup.Prefix = c.remapConst(f.Prefix)
+	}
+	if f.Prologue != nil {
+		dup.Prologue = c.remapConst(f.Prologue)
+	}
+	if f.Personality != nil {
+		dup.Personality = c.remapConst(f.Personality)
+	}
+	dup.UseListOrders = c.useListOrders(f.UseListOrders)
+	dup.Metadata = c.attachments(f.Metadata)
+}
+
+// remapUser remaps the operands, operand bundles, attribute groups and
+// metadata attachments of the given copied instruction or terminator.
+func (c *cloner) remapUser(user value.User) {
+	for _, op := range user.Operands() {
+		*op = c.remapValue(*op)
+	}
+	switch user := user.(type) {
+	case *InstCall:
+		user.FuncAttrs = c.funcAttrs(user.FuncAttrs)
+		c.remapBundles(user.OperandBundles)
+	case *TermInvoke:
+		user.FuncAttrs = c.funcAttrs(user.FuncAttrs)
+		c.remapBundles(user.OperandBundles)
+	case *TermCallBr:
+		user.FuncAttrs = c.funcAttrs(user.FuncAttrs)
+		c.remapBundles(user.OperandBundles)
+	}
+	if md, ok := user.(mdAttacher); ok {
+		md.SetMDAttachments(c.attachments(md.MDAttachments()))
+	}
+}
+
+// remapBundles remaps the inputs of the given copied operand bundles.
+func (c *cloner) remapBundles(bundles []*OperandBundle) {
+	for _, bundle := range bundles {
+		for i, input := range bundle.Inputs {
+			bundle.Inputs[i] = c.remapValue(input)
+		}
+	}
+}
+
+// remapValue returns the counterpart of the given value in the copy. Wrapper
+// values (e.g. function arguments) are copied.
+func (c *cloner) remapValue(v value.Value) value.Value {
+	if v == nil {
+		return nil
+	}
+	if new, ok := c.vmap[v]; ok {
+		return new
+	}
+	switch v := v.(type) {
+	case *Arg:
+		return &Arg{Value: c.remapValue(v.Value), Attrs: append([]ParamAttribute(nil), v.Attrs...)}
+	case *metadata.Value:
+		return c.remapMetadata(v).(value.Value)
+	case constant.Constant:
+		return c.remapConst(v)
+	}
+	return v
+}
+
+// remapConst returns the counterpart of the given constant in the copy; an
+// updated copy if the constant refers to copied values, and the constant
+// itself otherwise.
+func (c *cloner) remapConst(x constant.Constant) constant.Constant {
+	if new, ok := c.vmap[x]; ok {
+		return new.(constant.Constant)
+	}
+	if v, ok := c.consts[x]; ok {
+		return v
+	}
+	var dup constant.Constant
+	ensureCopy := func() constant.Constant {
+		if dup == nil {
+			dup = cloneConst(x)
+		}
+		return dup
+	}
+	for i, op := range constant.Operands(x) {
+		if *op == nil {
+			continue
+		}
+		if v := c.remapConst(*op); v != *op {
+			*constant.Operands(ensureCopy())[i] = v
+		}
+	}
+	switch x := x.(type) {
+	case *constant.Index:
+		if v := c.remapConst(x.Constant); v != x.Constant {
+			ensureCopy().(*constant.Index).Constant = v
+		}
+	case *constant.BlockAddress:
+		if new, ok := c.vmap[x.Block]; ok {
+			block, ok := new.(*Block)
+			if !ok {
+				panic(fmt.Errorf("invalid basic block %q of blockaddress constant; expected *ir.Block, got %T", x.Block.Ident(), new))
+			}
+			addr := ensureCopy().(*constant.BlockAddress)
+			addr.Block = block
+			// The blockaddress refers to the copy of its parent function, also
+			// when only the function is cloned.
+			if block.Parent != nil {
+				addr.Func = block.Parent
+			}
+		}
+	}
+	if dup == nil {
+		c.consts[x] = x
+		return x
+	}
+	c.consts[x] = dup
+	return dup
+}
+
+// remapMetadata returns the counterpart of the given metadata node or metadata
+// field in the copy. When cloning a module, metadata nodes are copied;
+// otherwise, only metadata values wrapping remapped values are copied.
+func (c *cloner) remapMetadata(md interface{}) interface{} {
+	if c.module {
+		return c.copyMetadata(reflect.ValueOf(md)).Interface()
+	}
+	switch md := md.(type) {
+	case *metadata.Value:
+		if v, ok := md.Value.(value.Value); ok {
+			if new := c.remapValue(v); new != v {
+				return &metadata.Value{Value: new}
+			}
+		}
+	case value.Value:
+		return c.remapValue(md)
+	}
+	return md
+}
+
+// copyMetadata returns a deep copy of the given metadata node or metadata
+// field. Values referred to by metadata (e.g. global variables) are remapped.
+func (c *cloner) copyMetadata(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		dup := reflect.New(v.Type()).Elem()
+		dup.Set(c.copyMetadata(v.Elem()))
+		return dup
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		x := v.Interface()
+		if v.Type().Elem().PkgPath() != metadataPkgPath {
+			if val, ok := x.(value.Value); ok {
+				return reflect.ValueOf(c.remapValue(val))
+			}
+			return v
+		}
+		if dup, ok := c.mds[x]; ok {
+			return reflect.ValueOf(dup)
+		}
+		dup := reflect.New(v.Type().Elem())
+		// Record copy before copying fields, to handle cyclic metadata.
+		c.mds[x] = dup.Interface()
+		dup.Elem().Set(c.copyMetadata(v.Elem()))
+		return dup
+	case reflect.Struct:
+		dup := reflect.New(v.Type()).Elem()
+		dup.Set(v)
+		for i := 0; i < dup.NumField(); i++ {
+			if field := dup.Field(i); field.CanSet() {
+				field.Set(c.copyMetadata(v.Field(i)))
+			}
+		}
+		return dup
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		dup := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			dup.Index(i).Set(c.copyMetadata(v.Index(i)))
+		}
+		return dup
+	}
+	return v
+}
+
+// attachments returns a copy of the given metadata attachments.
+func (c *cloner) attachments(mds []*metadata.Attachment) []*metadata.Attachment {
+	if mds == nil {
+		return nil
+	}
+	dup := make([]*metadata.Attachment, len(mds))
+	for i, md := range mds {
+		dup[i] = &metadata.Attachment{
+			Name: md.Name,
+			Node: c.remapMetadata(md.Node).(metadata.MDNode),
+		}
+	}
+	return dup
+}
+
+// comdat returns the counterpart of the given comdat definition in the copy;
+// or nil if not present.
+func (c *cloner) comdat(def *ComdatDef) *ComdatDef {
+	if def == nil || !c.module {
+		return def
+	}
+	if dup, ok := c.comdats[def]; ok {
+		return dup
+	}
+	dup := &ComdatDef{Name: def.Name, Kind: def.Kind}
+	c.comdats[def] = dup
+	return dup
+}
+
+// attrGroup returns the counterpart of the given attribute group definition in
+// the copy.
+func (c *cloner) attrGroup(def *AttrGroupDef) *AttrGroupDef {
+	if !c.module {
+		return def
+	}
+	if dup, ok := c.attrGroups[def]; ok {
+		return dup
+	}
+	dup := &AttrGroupDef{ID: def.ID, FuncAttrs: append([]FuncAttribute(nil), def.FuncAttrs...)}
+	c.attrGroups[def] = dup
+	return dup
+}
+
+// funcAttrs returns a copy of the given function attributes, with attribute
+// groups remapped.
+func (c *cloner) funcAttrs(attrs []FuncAttribute) []FuncAttribute {
+	if attrs == nil {
+		return nil
+	}
+	dup := make([]FuncAttribute, len(attrs))
+	for i, attr := range attrs {
+		if def, ok := attr.(*AttrGroupDef); ok {
+			attr = c.attrGroup(def)
+		}
+		dup[i] = attr
+	}
+	return dup
+}
+
+// useListOrders returns a copy of the given use-list order directives.
+func (c *cloner) useListOrders(us []*UseListOrder) []*UseListOrder {
+	var dup []*UseListOrder
+	for _, u := range us {
+		newUseListOrder := &UseListOrder{
+			Value:   c.remapValue(u.Value),
+			Indices: append([]uint64(nil), u.Indices...),
+		}
+		dup = append(dup, newUseListOrder)
+	}
+	return dup
+}
+
+// ### [ Helper functions ] ####################################################
+
+// metadataPkgPath is the import path of the metadata package.
+var metadataPkgPath = reflect.TypeOf(metadata.Tuple{}).PkgPath()
+
+// ownedTypes is the set of operand container types owned by instructions and
+// terminators, which are copied along with the instruction or terminator.
+var ownedTypes = map[reflect.Type]bool{
+	reflect.TypeOf(&Incoming{}):      true,
+	reflect.TypeOf(&Case{}):          true,
+	reflect.TypeOf(&Clause{}):        true,
+	reflect.TypeOf(&OperandBundle{}): true,
+}
+
+// cloneUser returns a shallow copy of the given instruction or terminator.
+// Slices (e.g. of arguments) and owned operand containers (e.g. phi incoming
+// values and switch cases) are copied, so that operands of the copy may be
+// updated independently.
+func cloneUser(user value.User) value.User {
+	orig := reflect.ValueOf(user).Elem()
+	dup := reflect.New(orig.Type())
+	dup.Elem().Set(orig)
+	copySlices(dup.Elem())
+	return dup.Interface().(value.User)
+}
+
+// copySlices replaces the slices of the given struct value with copies, and
+// copies owned operand containers of the slices.
+func copySlices(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		switch field.Kind() {
+		case reflect.Struct:
+			copySlices(field)
+		case reflect.Slice:
+			if field.IsNil() {
+				continue
+			}
+			dup := reflect.MakeSlice(field.Type(), field.Len(), field.Len())
+			reflect.Copy(dup, field)
+			if ownedTypes[field.Type().Elem()] {
+				for j := 0; j < dup.Len(); j++ {
+					elem := dup.Index(j)
+					if elem.IsNil() {
+						continue
+					}
+					newElem := reflect.New(elem.Type().Elem())
+					newElem.Elem().Set(elem.Elem())
+					copySlices(newElem.Elem())
+					elem.Set(newElem)
+				}
+			}
+			field.Set(dup)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package ir_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/llir/llvm/asm"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+)
+
+func TestModuleClone(t *testing.T) {
+	paths, err := filepath.Glob("../asm/testdata/*.ll")
+	if err != nil {
+		t.Fatalf("unable to locate test cases; %+v", err)
+	}
+	paths = append(paths, "testdata/clone.ll")
+	for _, path := range paths {
+		m, err := asm.ParseFile(path)
+		if err != nil {
+			t.Errorf("unable to parse %q; %+v", path, err)
+			continue
+		}
+		want := m.String()
+		dup, vmap := m.Clone()
+		if diff := cmp.Diff(want, dup.String()); diff != "" {
+			t.Errorf("%q: cloned module mismatch (-want +got):\n%s", path, diff)
+			continue
+		}
+		// The blockaddress constants of the copy refer to the functions and
+		// basic blocks of the copy.
+		for i, g := range m.Globals {
+			addr, ok := g.Init.(*constant.BlockAddress)
+			if !ok {
+				continue
+			}
+			got := dup.Globals[i].Init.(*constant.BlockAddress)
+			if got.Func != vmap[addr.Func] || got.Block != vmap[addr.Block] {
+				t.Errorf("%q: blockaddress of cloned global %s refers to the original module", path, g.Ident())
+			}
+		}
+		for i, f := range m.Funcs {
+			for j, block := range f.Blocks {
+				term, ok := block.Term.(*ir.TermIndirectBr)
+				if !ok {
+					continue
+				}
+				addr, ok := term.Addr.(*constant.BlockAddress)
+				if !ok {
+					continue
+				}
+				got := dup.Funcs[i].Blocks[j].Term.(*ir.TermIndirectBr).Addr.(*constant.BlockAddress)
+				if got.Func != vmap[addr.Func] || got.Block != vmap[addr.Block] {
+					t.Errorf("%q: blockaddress of cloned function %s refers to the original module", path, f.Ident())
+				}
+			}
+		}
+		// Rename all values and metadata definitions of the copy, to ensure that
+		// the original module is unaffected.
+		renameModule(dup)
+		if diff := cmp.Diff(want, m.String()); diff != "" {
+			t.Errorf("%q: original module changed by update of copy (-want +got):\n%s", path, diff)
+		}
+	}
+}
+
+func TestFuncClone(t *testing.T) {
+	const path = "testdata/clone.ll"
+	m, err := asm.ParseFile(path)
+	if err != nil {
+		t.Fatalf("unable to parse %q; %+v", path, err)
+	}
+	f := m.Funcs[0]
+	nfuncs := len(m.Funcs)
+	want := f.LLString()
+	dup, vmap := f.Clone()
+	if diff := cmp.Diff(want, dup.LLString()); diff != "" {
+		t.Errorf("%q: cloned function mismatch (-want +got):\n%s", path, diff)
+	}
+	if dup.Parent != m {
+		t.Errorf("%q: parent module of cloned function mismatch", path)
+	}
+	if len(m.Funcs) != nfuncs {
+		t.Errorf("%q: number of functions mismatch; expected %d, got %d", path, nfuncs, len(m.Funcs))
+	}
+	for _, param := range f.Params {
+		if vmap[param] != dup.Params[0] {
+			t.Errorf("%q: value map of parameter %s mismatch", path, param.Ident())
+		}
+	}
+	// The blockaddress of the indirectbr terminator refers to the copy.
+	for i, block := range f.Blocks {
+		if vmap[block] != dup.Blocks[i] {
+			t.Errorf("%q: value map of basic block %s mismatch", path, block.Ident())
+		}
+		term, ok := dup.Blocks[i].Term.(*ir.TermIndirectBr)
+		if !ok {
+			continue
+		}
+		addr := term.Addr.(*constant.BlockAddress)
+		if addr.Func != dup || addr.Block != vmap[f.Blocks[1]] {
+			t.Errorf("%q: blockaddress of cloned function mismatch; expected %s and %s, got %s and %s", path, dup.Ident(), dup.Blocks[1].Ident(), addr.Func.Ident(), addr.Block.Ident())
+		}
+	}
+	// Metadata attachments are copied, and refer to the same metadata nodes.
+	if got, want := dup.Metadata[0], f.Metadata[0]; got == want || got.Node != want.Node {
+		t.Errorf("%q: metadata attachment of cloned function mismatch", path)
+	}
+	dup.SetName("g")
+	renameFunc(dup)
+	if diff := cmp.Diff(want, f.LLString()); diff != "" {
+		t.Errorf("%q: original function changed by update of copy (-want +got):\n%s", path, diff)
+	}
+}
+
+// ### [ Helper functions ] ####################################################
+
+// renameModule renames the global values, local variables and metadata
+// definitions of the given module.
+func renameModule(m *ir.Module) {
+	for _, g := range m.Globals {
+		g.SetName("clone_" + g.Name())
+	}
+	for _, f := range m.Funcs {
+		f.SetName("clone_" + f.Name())
+		renameFunc(f)
+	}
+	for _, a := range m.Aliases {
+		a.SetName("clone_" + a.Name())
+	}
+	for _, i := range m.IFuncs {
+		i.SetName("clone_" + i.Name())
+	}
+	for _, def := range m.ComdatDefs {
+		def.Name = "clone_" + def.Name
+	}
+	for _, def := range m.AttrGroupDefs {
+		def.ID += 100
+	}
+	for _, def := range m.NamedMetadataDefs {
+		def.Name = "clone_" + def.Name
+	}
+	for _, def := range m.MetadataDefs {
+		def.SetID(def.ID() + 100)
+	}
+}
+
+// renameFunc renames the named parameters, basic blocks and local variables of
+// the given function.
+func renameFunc(f *ir.Func) {
+	type named interface {
+		Name() string
+		SetName(name string)
+		IsUnnamed() bool
+	}
+	rename := func(v interface{}) {
+		if n, ok := v.(named); ok && !n.IsUnnamed() {
+			n.SetName("clone_" + n.Name())
+		}
+	}
+	for _, param := range f.Params {
+		rename(param)
+	}
+	for _, block := range f.Blocks {
+		rename(block)
+		for _, inst := range block.Insts {
+			rename(inst)
+		}
+		rename(block.Term)
+	}
+}
@@ -0,0 +1,184 @@
+package constant
+
+import (
+	"math/big"
+
+	"github.com/llir/llvm/ir/types"
+)
+
+// === [ Constant folding ] ====================================================
+
+// Fold returns the result of evaluating the given constant expression, after
+// folding its operands. Integer and floating-point expressions are evaluated
+// to *constant.Int and *constant.Float constants, and vector expressions to
+// vector constants; with the wrap-around semantics of LLVM IR integers, IEEE
+// 754 semantics of the floating-point kind, and poison values for operations
+// (and overflow flags) specified to produce poison.
+//
+// Fold returns c unchanged if c is not a constant expression, or if it cannot
+// be folded (e.g. expressions referring to the address of global variables,
+// expressions with undef operands and expressions with undefined behaviour).
+//
+// The FoldFoo functions fold individual operations, and may be used to fold
+// instructions with constant operands; they return nil if the operation cannot
+// be folded.
+func Fold(c Constant) Constant {
+	var folded Constant
+	switch c := c.(type) {
+	// Unary expressions.
+	case *ExprFNeg:
+		folded = FoldFNeg(Fold(c.X))
+	// Binary expressions.
+	case *ExprAdd:
+		folded = FoldAdd(Fold(c.X), Fold(c.Y), c.OverflowFlags)
+	case *ExprSub:
+		folded = FoldSub(Fold(c.X), Fold(c.Y), c.OverflowFlags)
+	case *ExprMul:
+		folded = FoldMul(Fold(c.X), Fold(c.Y), c.OverflowFlags)
+	// Bitwise expressions.
+	case *ExprShl:
+		folded = FoldShl(Fold(c.X), Fold(c.Y), c.OverflowFlags)
+	case *ExprLShr:
+		folded = FoldLShr(Fold(c.X), Fold(c.Y), c.Exact)
+	case *ExprAShr:
+		folded = FoldAShr(Fold(c.X), Fold(c.Y), c.Exact)
+	case *ExprAnd:
+		folded = FoldAnd(Fold(c.X), Fold(c.Y))
+	case *ExprOr:
+		folded = FoldOr(Fold(c.X), Fold(c.Y))
+	case *ExprXor:
+		folded = FoldXor(Fold(c.X), Fold(c.Y))
+	// Vector expressions.
+	case *ExprExtractElement:
+		folded = FoldExtractElement(Fold(c.X), Fold(c.Index))
+	case *ExprInsertElement:
+		folded = FoldInsertElement(Fold(c.X), Fold(c.Elem), Fold(c.Index))
+	case *ExprShuffleVector:
+		folded = FoldShuffleVector(Fold(c.X), Fold(c.Y), Fold(c.Mask))
+	// Conversion expressions.
+	case *ExprTrunc:
+		folded = FoldTrunc(Fold(c.From), c.To)
+	case *ExprZExt:
+		folded = FoldZExt(Fold(c.From), c.To)
+	case *ExprSExt:
+		folded = FoldSExt(Fold(c.From), c.To)
+	case *ExprFPTrunc:
+		folded = FoldFPTrunc(Fold(c.From), c.To)
+	case *ExprFPExt:
+		folded = FoldFPExt(Fold(c.From), c.To)
+	case *ExprFPToUI:
+		folded = FoldFPToUI(Fold(c.From), c.To)
+	case *ExprFPToSI:
+		folded = FoldFPToSI(Fold(c.From), c.To)
+	case *ExprUIToFP:
+		folded = FoldUIToFP(Fold(c.From), c.To)
+	case *ExprSIToFP:
+		folded = FoldSIToFP(Fold(c.From), c.To)
+	case *ExprPtrToInt:
+		folded = FoldPtrToInt(Fold(c.From), c.To)
+	case *ExprIntToPtr:
+		folded = FoldIntToPtr(Fold(c.From), c.To)
+	case *ExprBitCast:
+		folded = FoldBitCast(Fold(c.From), c.To)
+	// Other expressions.
+	case *ExprICmp:
+		folded = FoldICmp(c.Pred, Fold(c.X), Fold(c.Y))
+	case *ExprFCmp:
+		folded = FoldFCmp(c.Pred, Fold(c.X), Fold(c.Y))
+	case *ExprSelect:
+		folded = FoldSelect(Fold(c.Cond), Fold(c.X), Fold(c.Y))
+	}
+	if folded == nil {
+		return c
+	}
+	return folded
+}
+
+// ### [ Helper functions ] ####################################################
+
+// isPoison reports whether the given constant is a poison value.
+func isPoison(c Constant) bool {
+	_, ok := c.(*Poison)
+	return ok
+}
+
+// zeroOf returns the zero value of the given type.
+func zeroOf(t types.Type) Constant {
+	switch t := t.(type) {
+	case *types.IntType:
+		return &Int{Typ: t, X: new(big.Int)}
+	case *types.FloatType:
+		return &Float{Typ: t, X: new(big.Float).SetPrec(formatOf(t.Kind).prec)}
+	case *types.PointerType:
+		return NewNull(t)
+	default:
+		return NewZeroInitializer(t)
+	}
+}
+
+// vectorElems returns the elements of the given vector constant; or nil if not
+// a vector constant of fixed length with known elements.
+func vectorElems(c Constant) []Constant {
+	t, ok := c.Type().(*types.VectorType)
+	if !ok || t.Scalable {
+		return nil
+	}
+	switch c := c.(type) {
+	case *Vector:
+		return c.Elems
+	case *ZeroInitializer:
+		return splat(zeroOf(t.ElemType), t.Len)
+	case *Undef:
+		return splat(NewUndef(t.ElemType), t.Len)
+	case *Poison:
+		return splat(NewPoison(t.ElemType), t.Len)
+	}
+	return nil
+}
+
+// splat returns a list of n copies of the given element.
+func splat(elem Constant, n uint64) []Constant {
+	elems := make([]Constant, n)
+	for i := range elems {
+		elems[i] = elem
+	}
+	return elems
+}
+
+// foldVector folds the given operation element-wise on the given vector
+// operands, producing a vector of the given type. Non-vector operands are
+// passed as is to each application of the operation. The operation returns
+// nil if unable to fold an element.
+func foldVector(t *types.VectorType, op func(ops ...Constant) Constant, operands ...Constant) Constant {
+	if t.Scalable {
+		return nil
+	}
+	opsElems := make([][]Constant, len(operands))
+	for i, operand := range operands {
+		if _, ok := operand.Type().(*types.VectorType); !ok {
+			continue
+		}
+		elems := vectorElems(operand)
+		if uint64(len(elems)) != t.Len {
+			return nil
+		}
+		opsElems[i] = elems
+	}
+	elems := make([]Constant, t.Len)
+	for i := range elems {
+		ops := make([]Constant, len(operands))
+		for j, operand := range operands {
+			if opsElems[j] != nil {
+				ops[j] = opsElems[j][i]
+			} else {
+				ops[j] = operand
+			}
+		}
+		elem := op(ops...)
+		if elem == nil {
+			return nil
+		}
+		elems[i] = elem
+	}
+	return NewVector(t, elems...)
+}
@@ -0,0 +1,416 @@
+package constant
+
+import (
+	"math/big"
+
+	"github.com/llir/llvm/ir/types"
+	"github.com/mewmew/float/binary128"
+	"github.com/mewmew/float/float128ppc"
+	"github.com/mewmew/float/float80x86"
+)
+
+// --- [ Conversion folding ] --------------------------------------------------
+
+// FoldTrunc returns the result of folding trunc x to to; or nil if unable to
+// fold.
+func FoldTrunc(x Constant, to types.Type) Constant {
+	return foldConv(x, to, func(x Constant, to types.Type) Constant {
+		xi, ok := intOf(x)
+		toT, ok2 := to.(*types.IntType)
+		if !ok || !ok2 {
+			return nil
+		}
+		return newInt(toT, unsigned(xi))
+	})
+}
+
+// FoldZExt returns the result of folding zext x to to; or nil if unable to
+// fold.
+func FoldZExt(x Constant, to types.Type) Constant {
+	return foldConv(x, to, func(x Constant, to types.Type) Constant {
+		xi, ok := intOf(x)
+		toT, ok2 := to.(*types.IntType)
+		if !ok || !ok2 {
+			return nil
+		}
+		return newInt(toT, unsigned(xi))
+	})
+}
+
+// FoldSExt returns the result of folding sext x to to; or nil if unable to
+// fold.
+func FoldSExt(x Constant, to types.Type) Constant {
+	return foldConv(x, to, func(x Constant, to types.Type) Constant {
+		xi, ok := intOf(x)
+		toT, ok2 := to.(*types.IntType)
+		if !ok || !ok2 {
+			return nil
+		}
+		return newInt(toT, signed(xi))
+	})
+}
+
+// FoldFPTrunc returns the result of folding fptrunc x to to; or nil if unable
+// to fold.
+func FoldFPTrunc(x Constant, to types.Type) Constant {
+	return foldConv(x, to, convertFloat)
+}
+
+// FoldFPExt returns the result of folding fpext x to to; or nil if unable to
+// fold.
+func FoldFPExt(x Constant, to types.Type) Constant {
+	return foldConv(x, to, convertFloat)
+}
+
+// FoldFPToUI returns the result of folding fptoui x to to; or nil if unable to
+// fold.
+func FoldFPToUI(x Constant, to types.Type) Constant {
+	return foldConv(x, to, func(x Constant, to types.Type) Constant {
+		xf, ok := floatOf(x)
+		toT, ok2 := to.(*types.IntType)
+		if !ok || !ok2 {
+			return nil
+		}
+		if xf.NaN || xf.X.IsInf() {
+			return NewPoison(toT)
+		}
+		// Round toward zero.
+		n, _ := xf.X.Int(nil)
+		if !fitsUnsigned(n, toT) {
+			return NewPoison(toT)
+		}
+		return newInt(toT, n)
+	})
+}
+
+// FoldFPToSI returns the result of folding fptosi x to to; or nil if unable to
+// fold.
+func FoldFPToSI(x Constant, to types.Type) Constant {
+	return foldConv(x, to, func(x Constant, to types.Type) Constant {
+		xf, ok := floatOf(x)
+		toT, ok2 := to.(*types.IntType)
+		if !ok || !ok2 {
+			return nil
+		}
+		if xf.NaN || xf.X.IsInf() {
+			return NewPoison(toT)
+		}
+		// Round toward zero.
+		n, _ := xf.X.Int(nil)
+		if !fitsSigned(n, toT) {
+			return NewPoison(toT)
+		}
+		return newInt(toT, n)
+	})
+}
+
+// FoldUIToFP returns the result of folding uitofp x to to; or nil if unable to
+// fold.
+func FoldUIToFP(x Constant, to types.Type) Constant {
+	return foldConv(x, to, func(x Constant, to types.Type) Constant {
+		xi, ok := intOf(x)
+		toT, ok2 := to.(*types.FloatType)
+		if !ok || !ok2 {
+			return nil
+		}
+		return roundRat(toT, new(big.Rat).SetInt(unsigned(xi)), false)
+	})
+}
+
+// FoldSIToFP returns the result of folding sitofp x to to; or nil if unable to
+// fold.
+func FoldSIToFP(x Constant, to types.Type) Constant {
+	return foldConv(x, to, func(x Constant, to types.Type) Constant {
+		xi, ok := intOf(x)
+		toT, ok2 := to.(*types.FloatType)
+		if !ok || !ok2 {
+			return nil
+		}
+		return roundRat(toT, new(big.Rat).SetInt(signed(xi)), false)
+	})
+}
+
+// FoldPtrToInt returns the result of folding ptrtoint x to to; or nil if unable
+// to fold. Only null pointers are folded, as the addresses of global values are
+// unknown.
+func FoldPtrToInt(x Constant, to types.Type) Constant {
+	return foldConv(x, to, func(x Constant, to types.Type) Constant {
+		toT, ok := to.(*types.IntType)
+		if !ok || !isNull(x) {
+			return nil
+		}
+		return zeroOf(toT)
+	})
+}
+
+// FoldIntToPtr returns the result of folding inttoptr x to to; or nil if unable
+// to fold. Only zero is folded, to a null pointer.
+func FoldIntToPtr(x Constant, to types.Type) Constant {
+	return foldConv(x, to, func(x Constant, to types.Type) Constant {
+		xi, ok := intOf(x)
+		toT, ok2 := to.(*types.PointerType)
+		if !ok || !ok2 || xi.X.Sign() != 0 {
+			return nil
+		}
+		return NewNull(toT)
+	})
+}
+
+// FoldBitCast returns the result of folding bitcast x to to; or nil if unable
+// to fold.
+//
+// Bitcasts between integers and floating-point values of the same bit size are
+// folded, and bitcasts of null pointers are folded to null pointers.
+func FoldBitCast(x Constant, to types.Type) Constant {
+	if x == nil {
+		return nil
+	}
+	if x.Type().Equal(to) {
+		return x
+	}
+	return foldConv(x, to, func(x Constant, to types.Type) Constant {
+		switch toT := to.(type) {
+		case *types.IntType:
+			xf, ok := floatOf(x)
+			if !ok || floatWidth(xf.Typ.Kind) != toT.BitSize {
+				return nil
+			}
+			return newInt(toT, floatBits(xf))
+		case *types.FloatType:
+			xi, ok := intOf(x)
+			if !ok || floatWidth(toT.Kind) != xi.Typ.BitSize {
+				return nil
+			}
+			return floatFromBits(toT, unsigned(xi))
+		case *types.PointerType:
+			if isNull(x) {
+				return NewNull(toT)
+			}
+		}
+		return nil
+	})
+}
+
+// ### [ Helper functions ] ####################################################
+
+// foldConv folds the given conversion of x to the type to. The conversion is
+// applied element-wise on vectors of the same length. Poison operands produce
+// poison, and undef operands are not folded.
+func foldConv(x Constant, to types.Type, conv func(x Constant, to types.Type) Constant) Constant {
+	if x == nil {
+		return nil
+	}
+	if isPoison(x) {
+		return NewPoison(to)
+	}
+	xt, isVec := x.Type().(*types.VectorType)
+	if toT, ok := to.(*types.VectorType); ok {
+		if !isVec || xt.Len != toT.Len {
+			// Bitcasts between vectors of different lengths are not folded.
+			return nil
+		}
+		return foldVector(toT, func(ops ...Constant) Constant {
+			return conv(ops[0], toT.ElemType)
+		}, x)
+	}
+	if isVec {
+		return nil
+	}
+	return conv(x, to)
+}
+
+// convertFloat converts the floating-point constant x to the floating-point
+// type to, rounding to nearest if inexact.
+func convertFloat(x Constant, to types.Type) Constant {
+	xf, ok := floatOf(x)
+	toT, ok2 := to.(*types.FloatType)
+	if !ok || !ok2 {
+		return nil
+	}
+	switch {
+	case xf.NaN:
+		return newNaN(toT, xf.X.Signbit())
+	case xf.X.IsInf():
+		return newInf(toT, xf.X.Signbit())
+	}
+	return roundRat(toT, toRat(xf), xf.X.Signbit())
+}
+
+// isNull reports whether the given constant is a null pointer.
+func isNull(c Constant) bool {
+	switch c := c.(type) {
+	case *Null:
+		return true
+	case *ZeroInitializer:
+		_, ok := c.Typ.(*types.PointerType)
+		return ok
+	}
+	return false
+}
+
+// floatWidth returns the bit size of the given floating-point kind.
+func floatWidth(kind types.FloatKind) uint64 {
+	switch kind {
+	case types.FloatKindHalf:
+		return 16
+	case types.FloatKindFloat:
+		return 32
+	case types.FloatKindDouble:
+		return 64
+	case types.FloatKindX86_FP80:
+		return 80
+	default:
+		// fp128 and ppc_fp128.
+		return 128
+	}
+}
+
+// floatBits returns the binary representation of x. NaN values are represented
+// by quiet NaNs.
+func floatBits(x *Float) *big.Int {
+	neg := x.X != nil && x.X.Signbit()
+	var hi, lo uint64
+	switch x.Typ.Kind {
+	case types.FloatKindX86_FP80:
+		if x.NaN {
+			hi, lo = 0x7FFF, 0xC000000000000000
+			if neg {
+				hi |= 0x8000
+			}
+			break
+		}
+		f, _ := float80x86.NewFromBig(x.X)
+		se, m := f.Bits()
+		hi, lo = uint64(se), m
+	case types.FloatKindFP128:
+		if x.NaN {
+			hi = 0x7FFF800000000000
+			if neg {
+				hi |= 1 << 63
+			}
+			break
+		}
+		f, _ := binary128.NewFromBig(x.X)
+		hi, lo = f.Bits()
+	case types.FloatKindPPC_FP128:
+		// The high-order double is stored in the low-order bits.
+		if x.NaN {
+			lo = 0x7FF8000000000000
+			if neg {
+				lo |= 1 << 63
+			}
+			break
+		}
+		f, _ := float128ppc.NewFromBig(x.X)
+		lo, hi = f.Bits()
+	default:
+		return ieeeBits(x)
+	}
+	bits := new(big.Int).SetUint64(hi)
+	bits.Lsh(bits, 64)
+	return bits.Or(bits, new(big.Int).SetUint64(lo))
+}
+
+// floatFromBits returns the floating-point constant of the given type with the
+// given binary representation.
+func floatFromBits(t *types.FloatType, b *big.Int) *Float {
+	lo := new(big.Int).And(b, new(big.Int).SetUint64(1<<64-1)).Uint64()
+	hi := new(big.Int).Rsh(b, 64).Uint64()
+	var (
+		x   *big.Float
+		nan bool
+	)
+	switch t.Kind {
+	case types.FloatKindX86_FP80:
+		x, nan = float80x86.NewFromBits(uint16(hi), lo).Big()
+		// Like LLVM, treat unnormals (numbers with a non-zero exponent and a
+		// clear integer bit) as NaN.
+		exp, intBit := hi&0x7FFF, lo>>63
+		if nan || exp == 0x7FFF && lo<<1 != 0 || exp != 0 && intBit == 0 {
+			return newNaN(t, hi&0x8000 != 0)
+		}
+	case types.FloatKindFP128:
+		x, nan = binary128.NewFromBits(hi, lo).Big()
+		if nan {
+			return newNaN(t, hi>>63 != 0)
+		}
+	case types.FloatKindPPC_FP128:
+		// The high-order double is stored in the low-order bits.
+		x, nan = float128ppc.NewFromBits(lo, hi).Big()
+		if nan {
+			return newNaN(t, lo>>63 != 0)
+		}
+	default:
+		return ieeeFromBits(t, b)
+	}
+	x.SetPrec(formatOf(t.Kind).prec)
+	return &Float{Typ: t, X: x}
+}
+
+// ieeeBits returns the IEEE 754 binary representation of x, which has the
+// half, float or double floating-point kind. NaN values are represented by
+// quiet NaNs.
+func ieeeBits(x *Float) *big.Int {
+	f := formatOf(x.Typ.Kind)
+	width := uint(floatWidth(x.Typ.Kind))
+	fracBits := f.prec - 1
+	expMax := uint64(1)<<(width-fracBits-1) - 1
+	var bits uint64
+	if x.X.Signbit() {
+		bits |= 1 << (width - 1)
+	}
+	switch {
+	case x.NaN:
+		bits |= expMax<<fracBits | 1<<(fracBits-1)
+	case x.X.IsInf():
+		bits |= expMax << fracBits
+	case x.X.Sign() != 0:
+		emin := 1 - f.emax
+		e := x.X.MantExp(nil) - 1
+		// Significand, scaled to an integer.
+		m := new(big.Float).Abs(x.X)
+		if e >= emin {
+			bits |= uint64(e+f.emax) << fracBits
+			m.SetMantExp(m, int(fracBits)-e)
+		} else {
+			// Subnormal number.
+			m.SetMantExp(m, int(fracBits)-emin)
+		}
+		mant, _ := m.Uint64()
+		bits |= mant & (1<<fracBits - 1)
+	}
+	return new(big.Int).SetUint64(bits)
+}
+
+// ieeeFromBits returns the floating-point constant of the given half, float or
+// double floating-point type with the given IEEE 754 binary representation.
+func ieeeFromBits(t *types.FloatType, b *big.Int) *Float {
+	f := formatOf(t.Kind)
+	width := uint(floatWidth(t.Kind))
+	fracBits := f.prec - 1
+	expMax := uint64(1)<<(width-fracBits-1) - 1
+	bits := b.Uint64()
+	neg := bits>>(width-1) != 0
+	exp := bits >> fracBits & expMax
+	mant := bits & (1<<fracBits - 1)
+	switch {
+	case exp == expMax && mant != 0:
+		return newNaN(t, neg)
+	case exp == expMax:
+		return newInf(t, neg)
+	}
+	e := int(exp) - f.emax
+	if exp == 0 {
+		// Subnormal number.
+		e = 1 - f.emax
+	} else {
+		// Implicit leading bit.
+		mant |= 1 << fracBits
+	}
+	x := new(big.Float).SetPrec(f.prec).SetUint64(mant)
+	x.SetMantExp(x, e-int(fracBits))
+	if neg {
+		x.Neg(x)
+	}
+	return &Float{Typ: t, X: x}
+}
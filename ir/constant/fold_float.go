@@ -0,0 +1,304 @@
+package constant
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/llir/llvm/ir/types"
+)
+
+// --- [ Floating-point folding ] ----------------------------------------------
+
+// FoldFNeg returns the result of folding fneg x; or nil if unable to fold.
+func FoldFNeg(x Constant) Constant {
+	if x == nil {
+		return nil
+	}
+	if isPoison(x) {
+		return x
+	}
+	if t, ok := x.Type().(*types.VectorType); ok {
+		return foldVector(t, func(ops ...Constant) Constant {
+			return FoldFNeg(ops[0])
+		}, x)
+	}
+	xf, ok := floatOf(x)
+	if !ok {
+		return nil
+	}
+	// fneg only flips the sign bit, also of NaN values.
+	return &Float{Typ: xf.Typ, X: new(big.Float).Neg(xf.X), NaN: xf.NaN}
+}
+
+// FoldFAdd returns the result of folding fadd x, y; or nil if unable to fold.
+func FoldFAdd(x, y Constant) Constant {
+	return foldFloatBinary(x, y, fadd)
+}
+
+// FoldFSub returns the result of folding fsub x, y; or nil if unable to fold.
+func FoldFSub(x, y Constant) Constant {
+	return foldFloatBinary(x, y, func(t *types.FloatType, x, y *Float) Constant {
+		return fadd(t, x, &Float{Typ: y.Typ, X: new(big.Float).Neg(y.X)})
+	})
+}
+
+// FoldFMul returns the result of folding fmul x, y; or nil if unable to fold.
+func FoldFMul(x, y Constant) Constant {
+	return foldFloatBinary(x, y, func(t *types.FloatType, x, y *Float) Constant {
+		neg := x.X.Signbit() != y.X.Signbit()
+		switch {
+		case x.X.IsInf() || y.X.IsInf():
+			if x.X.Sign() == 0 || y.X.Sign() == 0 {
+				// inf * 0
+				return newNaN(t, false)
+			}
+			return newInf(t, neg)
+		}
+		return roundRat(t, new(big.Rat).Mul(toRat(x), toRat(y)), neg)
+	})
+}
+
+// FoldFDiv returns the result of folding fdiv x, y; or nil if unable to fold.
+func FoldFDiv(x, y Constant) Constant {
+	return foldFloatBinary(x, y, func(t *types.FloatType, x, y *Float) Constant {
+		neg := x.X.Signbit() != y.X.Signbit()
+		switch {
+		case x.X.IsInf() && y.X.IsInf():
+			// inf / inf
+			return newNaN(t, false)
+		case x.X.IsInf():
+			return newInf(t, neg)
+		case y.X.IsInf():
+			return newZero(t, neg)
+		case y.X.Sign() == 0:
+			if x.X.Sign() == 0 {
+				// 0 / 0
+				return newNaN(t, false)
+			}
+			return newInf(t, neg)
+		}
+		return roundRat(t, new(big.Rat).Quo(toRat(x), toRat(y)), neg)
+	})
+}
+
+// FoldFRem returns the result of folding frem x, y; or nil if unable to fold.
+//
+// The remainder has the same sign as x, as computed by the fmod function of C.
+func FoldFRem(x, y Constant) Constant {
+	return foldFloatBinary(x, y, func(t *types.FloatType, x, y *Float) Constant {
+		switch {
+		case x.X.IsInf() || y.X.Sign() == 0:
+			return newNaN(t, false)
+		case y.X.IsInf():
+			return x
+		}
+		// r = x - trunc(x/y)*y, which is exact.
+		xr, yr := toRat(x), toRat(y)
+		q := new(big.Rat).Quo(xr, yr)
+		n := new(big.Int).Quo(q.Num(), q.Denom())
+		r := new(big.Rat).Sub(xr, new(big.Rat).Mul(new(big.Rat).SetInt(n), yr))
+		return roundRat(t, r, x.X.Signbit())
+	})
+}
+
+// ### [ Helper functions ] ####################################################
+
+// fadd returns the sum of the non-NaN floating-point constants x and y.
+func fadd(t *types.FloatType, x, y *Float) Constant {
+	switch {
+	case x.X.IsInf() && y.X.IsInf():
+		if x.X.Signbit() != y.X.Signbit() {
+			// inf + -inf
+			return newNaN(t, false)
+		}
+		return x
+	case x.X.IsInf():
+		return x
+	case y.X.IsInf():
+		return y
+	}
+	// The sum of zeroes is -0 only if both are -0.
+	neg := x.X.Signbit() && y.X.Signbit()
+	return roundRat(t, new(big.Rat).Add(toRat(x), toRat(y)), neg)
+}
+
+// foldFloatBinary folds the given binary floating-point operation on x and y,
+// which are floating-point scalar or vector constants. The operation is applied
+// element-wise on vectors, and is only invoked for non-NaN operands. Poison
+// operands produce poison, NaN operands produce NaN, and undef operands are
+// not folded.
+func foldFloatBinary(x, y Constant, op func(t *types.FloatType, x, y *Float) Constant) Constant {
+	if x == nil || y == nil {
+		return nil
+	}
+	if isPoison(x) || isPoison(y) {
+		return NewPoison(x.Type())
+	}
+	if t, ok := x.Type().(*types.VectorType); ok {
+		return foldVector(t, func(ops ...Constant) Constant {
+			return foldFloatBinary(ops[0], ops[1], op)
+		}, x, y)
+	}
+	xf, ok := floatOf(x)
+	if !ok {
+		return nil
+	}
+	yf, ok := floatOf(y)
+	if !ok {
+		return nil
+	}
+	// Propagate NaN operands.
+	switch {
+	case xf.NaN:
+		return xf
+	case yf.NaN:
+		return yf
+	}
+	return op(xf.Typ, xf, yf)
+}
+
+// floatOf returns the floating-point constant of the given constant; e.g. 0.0
+// for zeroinitializer of floating-point type.
+func floatOf(c Constant) (*Float, bool) {
+	switch c := c.(type) {
+	case *Float:
+		if c.X == nil {
+			return nil, false
+		}
+		return c, true
+	case *ZeroInitializer:
+		if t, ok := c.Typ.(*types.FloatType); ok {
+			return newZero(t, false), true
+		}
+	}
+	return nil, false
+}
+
+// floatFormat is the binary format of a floating-point kind.
+type floatFormat struct {
+	// Precision in bits of the significand, including the leading bit.
+	prec uint
+	// Maximum exponent; the minimum exponent of normal numbers is 1-emax.
+	emax int
+}
+
+// formatOf returns the binary format of the given floating-point kind.
+//
+// Note, ppc_fp128 (double-double arithmetic) is approximated by a binary format
+// with 106 bits of precision and the exponent range of double.
+func formatOf(kind types.FloatKind) floatFormat {
+	switch kind {
+	case types.FloatKindHalf:
+		return floatFormat{prec: 11, emax: 15}
+	case types.FloatKindFloat:
+		return floatFormat{prec: 24, emax: 127}
+	case types.FloatKindDouble:
+		return floatFormat{prec: 53, emax: 1023}
+	case types.FloatKindFP128:
+		return floatFormat{prec: 113, emax: 16383}
+	case types.FloatKindX86_FP80:
+		return floatFormat{prec: 64, emax: 16383}
+	case types.FloatKindPPC_FP128:
+		return floatFormat{prec: 106, emax: 1023}
+	default:
+		panic(fmt.Errorf("support for floating-point kind %v not yet implemented", kind))
+	}
+}
+
+// roundRat returns the floating-point constant of the given type nearest to x,
+// rounding ties to even. Values too large in magnitude for the type are
+// rounded to infinity, and values too small are rounded to subnormal numbers
+// or zero. The sign of zero results is negative if neg is set.
+func roundRat(t *types.FloatType, x *big.Rat, neg bool) *Float {
+	f := formatOf(t.Kind)
+	if x.Sign() == 0 {
+		return newZero(t, neg)
+	}
+	neg = x.Sign() < 0
+	emin := 1 - f.emax
+	// Exponent e of x, such that 2^e <= |x| < 2^(e+1).
+	e := ratExp(x) - 1
+	prec := f.prec
+	if e < emin {
+		// Subnormal numbers have fewer bits of precision.
+		bits := e - emin + int(f.prec)
+		switch {
+		case bits >= 1:
+			prec = uint(bits)
+		case bits == 0:
+			// 2^e <= |x| < 2^(e+1), where 2^(e+1) is the smallest subnormal
+			// number; round to nearest, ties to even (zero).
+			half := new(big.Rat).SetFrac(big.NewInt(1), pow2(uint64(-e)))
+			if new(big.Rat).Abs(x).Cmp(half) == 0 {
+				return newZero(t, neg)
+			}
+			z := new(big.Float).SetPrec(f.prec).SetMantExp(big.NewFloat(1), e+1)
+			if neg {
+				z.Neg(z)
+			}
+			return &Float{Typ: t, X: z}
+		default:
+			return newZero(t, neg)
+		}
+	}
+	z := new(big.Float).SetPrec(prec).SetMode(big.ToNearestEven).SetRat(x)
+	if z.MantExp(nil)-1 > f.emax {
+		return newInf(t, neg)
+	}
+	// Widen precision of subnormal numbers to the precision of the type.
+	z.SetPrec(f.prec)
+	return &Float{Typ: t, X: z}
+}
+
+// ratExp returns the exponent e of x, such that 2^(e-1) <= |x| < 2^e. The
+// exponent matches the exponent of big.Float.MantExp.
+func ratExp(x *big.Rat) int {
+	num := new(big.Int).Abs(x.Num())
+	den := x.Denom()
+	// 2^(e-1) < |x| < 2^(e+1)
+	e := num.BitLen() - den.BitLen()
+	// Check if |x| >= 2^e.
+	if e >= 0 {
+		den = new(big.Int).Lsh(den, uint(e))
+	} else {
+		num = new(big.Int).Lsh(num, uint(-e))
+	}
+	if num.Cmp(den) >= 0 {
+		return e + 1
+	}
+	return e
+}
+
+// toRat returns the exact value of the given finite floating-point constant.
+func toRat(x *Float) *big.Rat {
+	r, _ := x.X.Rat(nil)
+	return r
+}
+
+// newZero returns a new floating-point zero of the given type, with a negative
+// sign if neg is set.
+func newZero(t *types.FloatType, neg bool) *Float {
+	z := new(big.Float).SetPrec(formatOf(t.Kind).prec)
+	if neg {
+		z.Neg(z)
+	}
+	return &Float{Typ: t, X: z}
+}
+
+// newInf returns a new floating-point infinity of the given type, with a
+// negative sign if neg is set.
+func newInf(t *types.FloatType, neg bool) *Float {
+	z := new(big.Float).SetPrec(formatOf(t.Kind).prec).SetInf(neg)
+	return &Float{Typ: t, X: z}
+}
+
+// newNaN returns a new floating-point NaN of the given type, with a negative
+// sign if neg is set.
+func newNaN(t *types.FloatType, neg bool) *Float {
+	z := &big.Float{}
+	// Store sign of NaN.
+	if neg {
+		z.SetFloat64(-1)
+	}
+	return &Float{Typ: t, X: z, NaN: true}
+}
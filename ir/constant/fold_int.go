@@ -0,0 +1,301 @@
+package constant
+
+import (
+	"math/big"
+
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+)
+
+// --- [ Integer folding ] -----------------------------------------------------
+
+// FoldAdd returns the result of folding add x, y; or nil if unable to fold.
+func FoldAdd(x, y Constant, flags []enum.OverflowFlag) Constant {
+	return foldIntBinary(x, y, func(t *types.IntType, x, y *Int) Constant {
+		s := new(big.Int).Add(signed(x), signed(y))
+		u := new(big.Int).Add(unsigned(x), unsigned(y))
+		if (hasFlag(flags, enum.OverflowFlagNSW) && !fitsSigned(s, t)) || (hasFlag(flags, enum.OverflowFlagNUW) && !fitsUnsigned(u, t)) {
+			return NewPoison(t)
+		}
+		return newInt(t, u)
+	})
+}
+
+// FoldSub returns the result of folding sub x, y; or nil if unable to fold.
+func FoldSub(x, y Constant, flags []enum.OverflowFlag) Constant {
+	return foldIntBinary(x, y, func(t *types.IntType, x, y *Int) Constant {
+		s := new(big.Int).Sub(signed(x), signed(y))
+		u := new(big.Int).Sub(unsigned(x), unsigned(y))
+		if (hasFlag(flags, enum.OverflowFlagNSW) && !fitsSigned(s, t)) || (hasFlag(flags, enum.OverflowFlagNUW) && !fitsUnsigned(u, t)) {
+			return NewPoison(t)
+		}
+		return newInt(t, u)
+	})
+}
+
+// FoldMul returns the result of folding mul x, y; or nil if unable to fold.
+func FoldMul(x, y Constant, flags []enum.OverflowFlag) Constant {
+	return foldIntBinary(x, y, func(t *types.IntType, x, y *Int) Constant {
+		s := new(big.Int).Mul(signed(x), signed(y))
+		u := new(big.Int).Mul(unsigned(x), unsigned(y))
+		if (hasFlag(flags, enum.OverflowFlagNSW) && !fitsSigned(s, t)) || (hasFlag(flags, enum.OverflowFlagNUW) && !fitsUnsigned(u, t)) {
+			return NewPoison(t)
+		}
+		return newInt(t, u)
+	})
+}
+
+// FoldUDiv returns the result of folding udiv x, y; or nil if unable to fold
+// (e.g. division by zero).
+func FoldUDiv(x, y Constant, exact bool) Constant {
+	return foldIntBinary(x, y, func(t *types.IntType, x, y *Int) Constant {
+		if unsigned(y).Sign() == 0 {
+			// Undefined behaviour.
+			return nil
+		}
+		q, r := new(big.Int).QuoRem(unsigned(x), unsigned(y), new(big.Int))
+		if exact && r.Sign() != 0 {
+			return NewPoison(t)
+		}
+		return newInt(t, q)
+	})
+}
+
+// FoldSDiv returns the result of folding sdiv x, y; or nil if unable to fold
+// (e.g. division by zero or overflow).
+func FoldSDiv(x, y Constant, exact bool) Constant {
+	return foldIntBinary(x, y, func(t *types.IntType, x, y *Int) Constant {
+		if unsigned(y).Sign() == 0 {
+			// Undefined behaviour.
+			return nil
+		}
+		q, r := new(big.Int).QuoRem(signed(x), signed(y), new(big.Int))
+		if !fitsSigned(q, t) {
+			// Undefined behaviour; e.g. INT_MIN / -1.
+			return nil
+		}
+		if exact && r.Sign() != 0 {
+			return NewPoison(t)
+		}
+		return newInt(t, q)
+	})
+}
+
+// FoldURem returns the result of folding urem x, y; or nil if unable to fold
+// (e.g. division by zero).
+func FoldURem(x, y Constant) Constant {
+	return foldIntBinary(x, y, func(t *types.IntType, x, y *Int) Constant {
+		if unsigned(y).Sign() == 0 {
+			// Undefined behaviour.
+			return nil
+		}
+		return newInt(t, new(big.Int).Rem(unsigned(x), unsigned(y)))
+	})
+}
+
+// FoldSRem returns the result of folding srem x, y; or nil if unable to fold
+// (e.g. division by zero or overflow).
+func FoldSRem(x, y Constant) Constant {
+	return foldIntBinary(x, y, func(t *types.IntType, x, y *Int) Constant {
+		if unsigned(y).Sign() == 0 {
+			// Undefined behaviour.
+			return nil
+		}
+		q, r := new(big.Int).QuoRem(signed(x), signed(y), new(big.Int))
+		if !fitsSigned(q, t) {
+			// Undefined behaviour; e.g. INT_MIN % -1.
+			return nil
+		}
+		return newInt(t, r)
+	})
+}
+
+// FoldShl returns the result of folding shl x, y; or nil if unable to fold.
+func FoldShl(x, y Constant, flags []enum.OverflowFlag) Constant {
+	return foldIntBinary(x, y, func(t *types.IntType, x, y *Int) Constant {
+		n, ok := shiftAmount(t, y)
+		if !ok {
+			return NewPoison(t)
+		}
+		u := new(big.Int).Lsh(unsigned(x), n)
+		z := newInt(t, u)
+		if hasFlag(flags, enum.OverflowFlagNUW) && !fitsUnsigned(u, t) {
+			// Non-zero bits shifted out.
+			return NewPoison(t)
+		}
+		if hasFlag(flags, enum.OverflowFlagNSW) && new(big.Int).Rsh(signed(z), n).Cmp(signed(x)) != 0 {
+			// Bits shifted out which disagree with the sign bit of the result.
+			return NewPoison(t)
+		}
+		return z
+	})
+}
+
+// FoldLShr returns the result of folding lshr x, y; or nil if unable to fold.
+func FoldLShr(x, y Constant, exact bool) Constant {
+	return foldIntBinary(x, y, func(t *types.IntType, x, y *Int) Constant {
+		n, ok := shiftAmount(t, y)
+		if !ok {
+			return NewPoison(t)
+		}
+		u := unsigned(x)
+		if exact && hasLowBits(u, n) {
+			return NewPoison(t)
+		}
+		return newInt(t, new(big.Int).Rsh(u, n))
+	})
+}
+
+// FoldAShr returns the result of folding ashr x, y; or nil if unable to fold.
+func FoldAShr(x, y Constant, exact bool) Constant {
+	return foldIntBinary(x, y, func(t *types.IntType, x, y *Int) Constant {
+		n, ok := shiftAmount(t, y)
+		if !ok {
+			return NewPoison(t)
+		}
+		if exact && hasLowBits(unsigned(x), n) {
+			return NewPoison(t)
+		}
+		// Rsh of negative integers is an arithmetic shift.
+		return newInt(t, new(big.Int).Rsh(signed(x), n))
+	})
+}
+
+// FoldAnd returns the result of folding and x, y; or nil if unable to fold.
+func FoldAnd(x, y Constant) Constant {
+	return foldIntBinary(x, y, func(t *types.IntType, x, y *Int) Constant {
+		return newInt(t, new(big.Int).And(unsigned(x), unsigned(y)))
+	})
+}
+
+// FoldOr returns the result of folding or x, y; or nil if unable to fold.
+func FoldOr(x, y Constant) Constant {
+	return foldIntBinary(x, y, func(t *types.IntType, x, y *Int) Constant {
+		return newInt(t, new(big.Int).Or(unsigned(x), unsigned(y)))
+	})
+}
+
+// FoldXor returns the result of folding xor x, y; or nil if unable to fold.
+func FoldXor(x, y Constant) Constant {
+	return foldIntBinary(x, y, func(t *types.IntType, x, y *Int) Constant {
+		return newInt(t, new(big.Int).Xor(unsigned(x), unsigned(y)))
+	})
+}
+
+// ### [ Helper functions ] ####################################################
+
+// foldIntBinary folds the given binary integer operation on x and y, which are
+// integer scalar or vector constants. The operation is applied element-wise on
+// vectors. Poison operands produce poison, and undef operands are not folded.
+func foldIntBinary(x, y Constant, op func(t *types.IntType, x, y *Int) Constant) Constant {
+	if x == nil || y == nil {
+		return nil
+	}
+	if isPoison(x) || isPoison(y) {
+		return NewPoison(x.Type())
+	}
+	if t, ok := x.Type().(*types.VectorType); ok {
+		return foldVector(t, func(ops ...Constant) Constant {
+			return foldIntBinary(ops[0], ops[1], op)
+		}, x, y)
+	}
+	xi, ok := intOf(x)
+	if !ok {
+		return nil
+	}
+	yi, ok := intOf(y)
+	if !ok {
+		return nil
+	}
+	return op(xi.Typ, xi, yi)
+}
+
+// intOf returns the integer constant of the given constant; e.g. 0 for
+// zeroinitializer of integer type.
+func intOf(c Constant) (*Int, bool) {
+	switch c := c.(type) {
+	case *Int:
+		return c, true
+	case *ZeroInitializer:
+		if t, ok := c.Typ.(*types.IntType); ok {
+			return &Int{Typ: t, X: new(big.Int)}, true
+		}
+	}
+	return nil, false
+}
+
+// newInt returns a new integer constant of the given type, with the value of x
+// truncated to the bit size of the type. The value is stored in the canonical
+// signed representation of LLVM (0 and 1 for booleans).
+func newInt(t *types.IntType, x *big.Int) *Int {
+	u := truncate(x, t.BitSize)
+	if t.BitSize > 1 && u.Bit(int(t.BitSize)-1) == 1 {
+		u.Sub(u, pow2(t.BitSize))
+	}
+	return &Int{Typ: t, X: u}
+}
+
+// unsigned returns the unsigned value of the given integer constant.
+func unsigned(c *Int) *big.Int {
+	return truncate(c.X, c.Typ.BitSize)
+}
+
+// signed returns the signed (two's complement) value of the given integer
+// constant.
+func signed(c *Int) *big.Int {
+	u := unsigned(c)
+	if u.Bit(int(c.Typ.BitSize)-1) == 1 {
+		u.Sub(u, pow2(c.Typ.BitSize))
+	}
+	return u
+}
+
+// truncate returns x modulo 2^n, as a non-negative integer.
+func truncate(x *big.Int, n uint64) *big.Int {
+	mask := new(big.Int).Sub(pow2(n), big.NewInt(1))
+	// And uses two's complement semantics for negative integers.
+	return new(big.Int).And(x, mask)
+}
+
+// pow2 returns 2^n.
+func pow2(n uint64) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(n))
+}
+
+// fitsSigned reports whether x is representable as a signed integer of the
+// given type.
+func fitsSigned(x *big.Int, t *types.IntType) bool {
+	limit := pow2(t.BitSize - 1)
+	return x.Cmp(new(big.Int).Neg(limit)) >= 0 && x.Cmp(limit) < 0
+}
+
+// fitsUnsigned reports whether x is representable as an unsigned integer of
+// the given type.
+func fitsUnsigned(x *big.Int, t *types.IntType) bool {
+	return x.Sign() >= 0 && x.Cmp(pow2(t.BitSize)) < 0
+}
+
+// shiftAmount returns the shift amount y of a shift of the given integer type,
+// and reports whether it is less than the bit size of the type.
+func shiftAmount(t *types.IntType, y *Int) (uint, bool) {
+	n := unsigned(y)
+	if !n.IsUint64() || n.Uint64() >= t.BitSize {
+		return 0, false
+	}
+	return uint(n.Uint64()), true
+}
+
+// hasLowBits reports whether any of the n low bits of x are set.
+func hasLowBits(x *big.Int, n uint) bool {
+	return truncate(x, uint64(n)).Sign() != 0
+}
+
+// hasFlag reports whether the given overflow flag is present.
+func hasFlag(flags []enum.OverflowFlag, flag enum.OverflowFlag) bool {
+	for _, f := range flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
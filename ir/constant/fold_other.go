@@ -0,0 +1,362 @@
+package constant
+
+import (
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+)
+
+// --- [ Vector folding ] ------------------------------------------------------
+
+// FoldExtractElement returns the result of folding extractelement x, index; or
+// nil if unable to fold.
+func FoldExtractElement(x, index Constant) Constant {
+	if x == nil || index == nil {
+		return nil
+	}
+	t, ok := x.Type().(*types.VectorType)
+	if !ok {
+		return nil
+	}
+	if isPoison(x) || isPoison(index) {
+		return NewPoison(t.ElemType)
+	}
+	elems := vectorElems(x)
+	idx, ok := intOf(index)
+	if elems == nil || !ok {
+		return nil
+	}
+	i := unsigned(idx)
+	if !i.IsUint64() || i.Uint64() >= t.Len {
+		return NewPoison(t.ElemType)
+	}
+	return elems[i.Uint64()]
+}
+
+// FoldInsertElement returns the result of folding insertelement x, elem,
+// index; or nil if unable to fold.
+func FoldInsertElement(x, elem, index Constant) Constant {
+	if x == nil || elem == nil || index == nil {
+		return nil
+	}
+	t, ok := x.Type().(*types.VectorType)
+	if !ok {
+		return nil
+	}
+	if isPoison(index) {
+		return NewPoison(t)
+	}
+	elems := vectorElems(x)
+	idx, ok := intOf(index)
+	if elems == nil || !ok {
+		return nil
+	}
+	i := unsigned(idx)
+	if !i.IsUint64() || i.Uint64() >= t.Len {
+		return NewPoison(t)
+	}
+	newElems := make([]Constant, len(elems))
+	copy(newElems, elems)
+	newElems[i.Uint64()] = elem
+	return NewVector(t, newElems...)
+}
+
+// FoldShuffleVector returns the result of folding shufflevector x, y, mask; or
+// nil if unable to fold. Undef and poison mask elements produce poison
+// elements.
+func FoldShuffleVector(x, y, mask Constant) Constant {
+	if x == nil || y == nil || mask == nil {
+		return nil
+	}
+	xt, ok := x.Type().(*types.VectorType)
+	if !ok {
+		return nil
+	}
+	maskType, ok := mask.Type().(*types.VectorType)
+	if !ok || maskType.Scalable {
+		return nil
+	}
+	t := types.NewVector(maskType.Len, xt.ElemType)
+	maskElems := vectorElems(mask)
+	xElems := vectorElems(x)
+	yElems := vectorElems(y)
+	if maskElems == nil || xElems == nil || yElems == nil {
+		return nil
+	}
+	n := uint64(len(xElems))
+	elems := make([]Constant, len(maskElems))
+	for i, maskElem := range maskElems {
+		switch maskElem := maskElem.(type) {
+		case *Undef, *Poison:
+			elems[i] = NewPoison(xt.ElemType)
+		default:
+			idx, ok := intOf(maskElem)
+			if !ok {
+				return nil
+			}
+			j := unsigned(idx).Uint64()
+			switch {
+			case j < n:
+				elems[i] = xElems[j]
+			case j < 2*n:
+				elems[i] = yElems[j-n]
+			default:
+				return nil
+			}
+		}
+	}
+	return NewVector(t, elems...)
+}
+
+// --- [ Aggregate folding ] ---------------------------------------------------
+
+// FoldExtractValue returns the result of folding extractvalue x, indices; or
+// nil if unable to fold.
+func FoldExtractValue(x Constant, indices []uint64) Constant {
+	if x == nil {
+		return nil
+	}
+	for _, index := range indices {
+		elems := aggregateElems(x)
+		if elems == nil || index >= uint64(len(elems)) {
+			return nil
+		}
+		x = elems[index]
+	}
+	return x
+}
+
+// FoldInsertValue returns the result of folding insertvalue x, elem, indices;
+// or nil if unable to fold.
+func FoldInsertValue(x, elem Constant, indices []uint64) Constant {
+	if x == nil || elem == nil {
+		return nil
+	}
+	if len(indices) == 0 {
+		return elem
+	}
+	elems := aggregateElems(x)
+	if elems == nil || indices[0] >= uint64(len(elems)) {
+		return nil
+	}
+	newElem := FoldInsertValue(elems[indices[0]], elem, indices[1:])
+	if newElem == nil {
+		return nil
+	}
+	newElems := make([]Constant, len(elems))
+	copy(newElems, elems)
+	newElems[indices[0]] = newElem
+	switch t := x.Type().(type) {
+	case *types.StructType:
+		return NewStruct(t, newElems...)
+	case *types.ArrayType:
+		return NewArray(t, newElems...)
+	}
+	return nil
+}
+
+// --- [ Other folding ] -------------------------------------------------------
+
+// FoldICmp returns the result of folding icmp pred x, y; or nil if unable to
+// fold.
+func FoldICmp(pred enum.IPred, x, y Constant) Constant {
+	if x == nil || y == nil {
+		return nil
+	}
+	if isPoison(x) || isPoison(y) {
+		return NewPoison(cmpType(x.Type()))
+	}
+	if t, ok := x.Type().(*types.VectorType); ok {
+		return foldVector(types.NewVector(t.Len, types.I1), func(ops ...Constant) Constant {
+			return FoldICmp(pred, ops[0], ops[1])
+		}, x, y)
+	}
+	if isNull(x) && isNull(y) {
+		return NewBool(pred == enum.IPredEQ || pred == enum.IPredSGE || pred == enum.IPredSLE || pred == enum.IPredUGE || pred == enum.IPredULE)
+	}
+	xi, ok := intOf(x)
+	if !ok {
+		return nil
+	}
+	yi, ok := intOf(y)
+	if !ok {
+		return nil
+	}
+	s := signed(xi).Cmp(signed(yi))
+	u := unsigned(xi).Cmp(unsigned(yi))
+	switch pred {
+	case enum.IPredEQ:
+		return NewBool(u == 0)
+	case enum.IPredNE:
+		return NewBool(u != 0)
+	case enum.IPredSGE:
+		return NewBool(s >= 0)
+	case enum.IPredSGT:
+		return NewBool(s > 0)
+	case enum.IPredSLE:
+		return NewBool(s <= 0)
+	case enum.IPredSLT:
+		return NewBool(s < 0)
+	case enum.IPredUGE:
+		return NewBool(u >= 0)
+	case enum.IPredUGT:
+		return NewBool(u > 0)
+	case enum.IPredULE:
+		return NewBool(u <= 0)
+	case enum.IPredULT:
+		return NewBool(u < 0)
+	}
+	return nil
+}
+
+// FoldFCmp returns the result of folding fcmp pred x, y; or nil if unable to
+// fold.
+func FoldFCmp(pred enum.FPred, x, y Constant) Constant {
+	if x == nil || y == nil {
+		return nil
+	}
+	if isPoison(x) || isPoison(y) {
+		return NewPoison(cmpType(x.Type()))
+	}
+	if t, ok := x.Type().(*types.VectorType); ok {
+		return foldVector(types.NewVector(t.Len, types.I1), func(ops ...Constant) Constant {
+			return FoldFCmp(pred, ops[0], ops[1])
+		}, x, y)
+	}
+	xf, ok := floatOf(x)
+	if !ok {
+		return nil
+	}
+	yf, ok := floatOf(y)
+	if !ok {
+		return nil
+	}
+	// Ordered predicates are false and unordered predicates true if either
+	// operand is NaN.
+	unordered := xf.NaN || yf.NaN
+	var c int
+	if !unordered {
+		c = xf.X.Cmp(yf.X)
+	}
+	switch pred {
+	case enum.FPredFalse:
+		return False
+	case enum.FPredOEQ:
+		return NewBool(!unordered && c == 0)
+	case enum.FPredOGE:
+		return NewBool(!unordered && c >= 0)
+	case enum.FPredOGT:
+		return NewBool(!unordered && c > 0)
+	case enum.FPredOLE:
+		return NewBool(!unordered && c <= 0)
+	case enum.FPredOLT:
+		return NewBool(!unordered && c < 0)
+	case enum.FPredONE:
+		return NewBool(!unordered && c != 0)
+	case enum.FPredORD:
+		return NewBool(!unordered)
+	case enum.FPredTrue:
+		return True
+	case enum.FPredUEQ:
+		return NewBool(unordered || c == 0)
+	case enum.FPredUGE:
+		return NewBool(unordered || c >= 0)
+	case enum.FPredUGT:
+		return NewBool(unordered || c > 0)
+	case enum.FPredULE:
+		return NewBool(unordered || c <= 0)
+	case enum.FPredULT:
+		return NewBool(unordered || c < 0)
+	case enum.FPredUNE:
+		return NewBool(unordered || c != 0)
+	case enum.FPredUNO:
+		return NewBool(unordered)
+	}
+	return nil
+}
+
+// FoldSelect returns the result of folding select cond, x, y; or nil if unable
+// to fold.
+func FoldSelect(cond, x, y Constant) Constant {
+	if cond == nil || x == nil || y == nil {
+		return nil
+	}
+	if isPoison(cond) {
+		return NewPoison(x.Type())
+	}
+	if _, ok := cond.Type().(*types.VectorType); ok {
+		t, ok := x.Type().(*types.VectorType)
+		if !ok {
+			return nil
+		}
+		return foldVector(t, func(ops ...Constant) Constant {
+			return FoldSelect(ops[0], ops[1], ops[2])
+		}, cond, x, y)
+	}
+	c, ok := intOf(cond)
+	if !ok {
+		return nil
+	}
+	if c.X.Sign() != 0 {
+		return x
+	}
+	return y
+}
+
+// ### [ Helper functions ] ####################################################
+
+// cmpType returns the result type of comparisons of operands of the given type.
+func cmpType(t types.Type) types.Type {
+	if t, ok := t.(*types.VectorType); ok {
+		return types.NewVector(t.Len, types.I1)
+	}
+	return types.I1
+}
+
+// aggregateElems returns the elements of the given struct or array constant;
+// or nil if not an aggregate constant with known elements.
+func aggregateElems(c Constant) []Constant {
+	switch c := c.(type) {
+	case *Struct:
+		return c.Fields
+	case *Array:
+		return c.Elems
+	case *CharArray:
+		elems := make([]Constant, len(c.X))
+		for i, b := range c.X {
+			elems[i] = NewInt(types.I8, int64(b))
+		}
+		return elems
+	case *ZeroInitializer, *Undef, *Poison:
+		var elemTypes []types.Type
+		switch t := c.Type().(type) {
+		case *types.StructType:
+			elemTypes = t.Fields
+		case *types.ArrayType:
+			elemTypes = splatType(t.ElemType, t.Len)
+		default:
+			return nil
+		}
+		elems := make([]Constant, len(elemTypes))
+		for i, elemType := range elemTypes {
+			switch c.(type) {
+			case *ZeroInitializer:
+				elems[i] = zeroOf(elemType)
+			case *Undef:
+				elems[i] = NewUndef(elemType)
+			case *Poison:
+				elems[i] = NewPoison(elemType)
+			}
+		}
+		return elems
+	}
+	return nil
+}
+
+// splatType returns a list of n copies of the given type.
+func splatType(t types.Type, n uint64) []types.Type {
+	ts := make([]types.Type, n)
+	for i := range ts {
+		ts[i] = t
+	}
+	return ts
+}
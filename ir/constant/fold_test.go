@@ -0,0 +1,206 @@
+package constant_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+)
+
+func TestFold(t *testing.T) {
+	var (
+		i8   = func(x int64) *constant.Int { return constant.NewInt(types.I8, x) }
+		i32  = func(x int64) *constant.Int { return constant.NewInt(types.I32, x) }
+		f32  = func(x float64) *constant.Float { return constant.NewFloat(types.Float, x) }
+		f64  = func(x float64) *constant.Float { return constant.NewFloat(types.Double, x) }
+		v4i8 = types.NewVector(4, types.I8)
+		v2i8 = types.NewVector(2, types.I8)
+		ptr  = types.NewPointer(types.I8)
+	)
+	nsw := func(e *constant.ExprAdd) *constant.ExprAdd {
+		e.OverflowFlags = []enum.OverflowFlag{enum.OverflowFlagNSW}
+		return e
+	}
+	nuw := func(e *constant.ExprAdd) *constant.ExprAdd {
+		e.OverflowFlags = []enum.OverflowFlag{enum.OverflowFlagNUW}
+		return e
+	}
+	exact := func(e *constant.ExprLShr) *constant.ExprLShr {
+		e.Exact = true
+		return e
+	}
+	shlNSW := func(e *constant.ExprShl) *constant.ExprShl {
+		e.OverflowFlags = []enum.OverflowFlag{enum.OverflowFlagNSW}
+		return e
+	}
+	g := constant.NewUndef(ptr)
+	golden := []struct {
+		in   constant.Constant
+		want string
+	}{
+		// Integer arithmetic.
+		{in: constant.NewAdd(i8(100), i8(100)), want: "i8 -56"},
+		{in: nsw(constant.NewAdd(i8(100), i8(100))), want: "i8 poison"},
+		{in: nuw(constant.NewAdd(i8(100), i8(100))), want: "i8 -56"},
+		{in: nuw(constant.NewAdd(i8(-1), i8(1))), want: "i8 poison"},
+		{in: constant.NewSub(i32(0), i32(1)), want: "i32 -1"},
+		{in: constant.NewMul(i32(65536), i32(65536)), want: "i32 0"},
+		{in: constant.NewAdd(constant.True, constant.True), want: "i1 false"},
+		{in: constant.NewAdd(constant.NewMul(i32(6), i32(7)), i32(0)), want: "i32 42"},
+		// Bitwise operations.
+		{in: constant.NewShl(i8(1), i8(7)), want: "i8 -128"},
+		{in: shlNSW(constant.NewShl(i8(1), i8(7))), want: "i8 poison"},
+		{in: shlNSW(constant.NewShl(i8(-1), i8(7))), want: "i8 -128"},
+		{in: constant.NewShl(i8(1), i8(8)), want: "i8 poison"},
+		{in: constant.NewLShr(i8(-128), i8(7)), want: "i8 1"},
+		{in: constant.NewAShr(i8(-128), i8(7)), want: "i8 -1"},
+		{in: exact(constant.NewLShr(i8(3), i8(1))), want: "i8 poison"},
+		{in: constant.NewAnd(i8(-1), i8(0x0F)), want: "i8 15"},
+		{in: constant.NewOr(i8(0x70), i8(0x0F)), want: "i8 127"},
+		{in: constant.NewXor(i8(-1), i8(0x0F)), want: "i8 -16"},
+		// Floating-point arithmetic.
+		{in: constant.NewFNeg(f64(0)), want: "double -0.0"},
+		{in: constant.NewFNeg(constant.NewFPTrunc(f64(1.5), types.Float)), want: "float -1.5"},
+		// Conversions.
+		{in: constant.NewTrunc(i32(0x1FF), types.I8), want: "i8 -1"},
+		{in: constant.NewZExt(i8(-1), types.I32), want: "i32 255"},
+		{in: constant.NewSExt(i8(-1), types.I32), want: "i32 -1"},
+		{in: constant.NewFPTrunc(f64(0.1), types.Float), want: "float 0x3FB99999A0000000"},
+		{in: constant.NewFPTrunc(f64(1e300), types.Float), want: "float 0x7FF0000000000000"},
+		{in: constant.NewFPTrunc(f64(1e-300), types.Float), want: "float 0.0"},
+		{in: constant.NewFPExt(f32(0.5), types.Double), want: "double 0.5"},
+		{in: constant.NewFPToSI(f64(-3.9), types.I8), want: "i8 -3"},
+		{in: constant.NewFPToSI(f64(128), types.I8), want: "i8 poison"},
+		{in: constant.NewFPToUI(f64(255.5), types.I8), want: "i8 -1"},
+		{in: constant.NewFPToUI(f64(math.NaN()), types.I8), want: "i8 poison"},
+		{in: constant.NewUIToFP(i8(-1), types.Double), want: "double 255.0"},
+		{in: constant.NewSIToFP(i8(-1), types.Double), want: "double -1.0"},
+		{in: constant.NewSIToFP(constant.NewInt(types.I32, 16777217), types.Float), want: "float 1.6777216e+07"},
+		{in: constant.NewBitCast(f32(1), types.I32), want: "i32 1065353216"},
+		{in: constant.NewBitCast(constant.NewInt(types.I64, -4503599627370496), types.Double), want: "double 0xFFF0000000000000"},
+		{in: constant.NewBitCast(constant.NewFPExt(f64(1), types.X86_FP80), types.NewInt(80)), want: "i80 u0x3FFF8000000000000000"},
+		{in: constant.NewBitCast(constant.NewFPExt(f64(-2), types.FP128), types.NewInt(128)), want: "i128 -85070591730234615865843651857942052864"},
+		{in: constant.NewFPTrunc(constant.NewBitCast(constant.NewInt(types.NewInt(80), 1), types.X86_FP80), types.Double), want: "double 0.0"},
+		{in: constant.NewPtrToInt(constant.NewNull(ptr), types.I64), want: "i64 0"},
+		{in: constant.NewIntToPtr(i32(0), ptr), want: "i8* null"},
+		// Comparisons.
+		{in: constant.NewICmp(enum.IPredSLT, i8(-1), i8(0)), want: "i1 true"},
+		{in: constant.NewICmp(enum.IPredULT, i8(-1), i8(0)), want: "i1 false"},
+		{in: constant.NewICmp(enum.IPredEQ, constant.NewNull(ptr), constant.NewNull(ptr)), want: "i1 true"},
+		{in: constant.NewFCmp(enum.FPredOLT, f64(1), f64(2)), want: "i1 true"},
+		{in: constant.NewFCmp(enum.FPredOEQ, f64(math.NaN()), f64(math.NaN())), want: "i1 false"},
+		{in: constant.NewFCmp(enum.FPredUNE, f64(math.NaN()), f64(1)), want: "i1 true"},
+		{in: constant.NewFCmp(enum.FPredOEQ, f64(0), constant.NewFNeg(f64(0))), want: "i1 true"},
+		// Select.
+		{in: constant.NewSelect(constant.True, i8(1), i8(2)), want: "i8 1"},
+		{in: constant.NewSelect(constant.NewICmp(enum.IPredSGT, i8(1), i8(2)), i8(1), i8(2)), want: "i8 2"},
+		// Vectors.
+		{
+			in:   constant.NewAdd(constant.NewVector(v2i8, i8(1), i8(127)), constant.NewVector(v2i8, i8(2), i8(1))),
+			want: "<2 x i8> <i8 3, i8 -128>",
+		},
+		{
+			in:   constant.NewICmp(enum.IPredEQ, constant.NewVector(v2i8, i8(1), i8(2)), constant.NewZeroInitializer(v2i8)),
+			want: "<2 x i1> <i1 false, i1 false>",
+		},
+		{
+			in:   constant.NewExtractElement(constant.NewVector(v2i8, i8(1), i8(2)), i32(1)),
+			want: "i8 2",
+		},
+		{
+			in:   constant.NewExtractElement(constant.NewVector(v2i8, i8(1), i8(2)), i32(2)),
+			want: "i8 poison",
+		},
+		{
+			in:   constant.NewInsertElement(constant.NewZeroInitializer(v2i8), i8(5), i32(0)),
+			want: "<2 x i8> <i8 5, i8 0>",
+		},
+		{
+			in:   constant.NewShuffleVector(constant.NewVector(v2i8, i8(1), i8(2)), constant.NewVector(v2i8, i8(3), i8(4)), constant.NewVector(types.NewVector(4, types.I32), i32(3), i32(0), constant.NewUndef(types.I32), i32(2))),
+			want: "<4 x i8> <i8 4, i8 1, i8 poison, i8 3>",
+		},
+		{
+			in:   constant.NewTrunc(constant.NewVector(types.NewVector(4, types.I32), i32(256), i32(257), i32(-1), i32(128)), v4i8),
+			want: "<4 x i8> <i8 0, i8 1, i8 -1, i8 -128>",
+		},
+		// Not folded.
+		{in: constant.NewAdd(i8(1), constant.NewUndef(types.I8)), want: "i8 add (i8 1, i8 undef)"},
+		{in: constant.NewPtrToInt(g, types.I64), want: "i64 ptrtoint (i8* undef to i64)"},
+	}
+	for _, g := range golden {
+		got := constant.Fold(g.in).String()
+		if g.want != got {
+			t.Errorf("constant folding mismatch of %q; expected %q, got %q", g.in, g.want, got)
+		}
+	}
+}
+
+func TestFoldBinary(t *testing.T) {
+	var (
+		i32 = func(x int64) *constant.Int { return constant.NewInt(types.I32, x) }
+		f64 = func(x float64) *constant.Float { return constant.NewFloat(types.Double, x) }
+		f32 = func(x float64) constant.Constant { return constant.FoldFPTrunc(f64(x), types.Float) }
+		h   = func(x float64) constant.Constant { return constant.FoldFPTrunc(f64(x), types.Half) }
+	)
+	golden := []struct {
+		in   constant.Constant
+		want string
+	}{
+		// Integer division.
+		{in: constant.FoldUDiv(i32(-1), i32(2), false), want: "i32 u0x7FFFFFFF"},
+		{in: constant.FoldSDiv(i32(-7), i32(2), false), want: "i32 -3"},
+		{in: constant.FoldSDiv(i32(-7), i32(2), true), want: "i32 poison"},
+		{in: constant.FoldURem(i32(-1), i32(10)), want: "i32 5"},
+		{in: constant.FoldSRem(i32(-7), i32(2)), want: "i32 -1"},
+		// Floating-point arithmetic.
+		{in: constant.FoldFAdd(f64(0.1), f64(0.2)), want: "double 0x3FD3333333333334"},
+		{in: constant.FoldFAdd(f32(0.1), f32(0.2)), want: "float 0x3FD3333340000000"},
+		{in: constant.FoldFSub(f64(1), f64(1)), want: "double 0.0"},
+		{in: constant.FoldFAdd(f64(math.Copysign(0, -1)), f64(math.Copysign(0, -1))), want: "double -0.0"},
+		{in: constant.FoldFMul(f64(math.Inf(1)), f64(0)), want: "double 0x7FF8000000000000"},
+		{in: constant.FoldFMul(f64(1e308), f64(10)), want: "double 0x7FF0000000000000"},
+		{in: constant.FoldFMul(f64(5e-324), f64(0.5)), want: "double 0.0"},
+		{in: constant.FoldFMul(f64(5e-324), f64(1.5)), want: "double 0x2"},
+		{in: constant.FoldFDiv(f64(1), f64(0)), want: "double 0x7FF0000000000000"},
+		{in: constant.FoldFDiv(f64(-1), f64(0)), want: "double 0xFFF0000000000000"},
+		{in: constant.FoldFDiv(f64(1), f64(3)), want: "double 0x3FD5555555555555"},
+		{in: constant.FoldFRem(f64(5.5), f64(2)), want: "double 1.5"},
+		{in: constant.FoldFRem(f64(-5.5), f64(2)), want: "double -1.5"},
+		{in: constant.FoldFAdd(h(65504), h(16)), want: "half 0xH7C00"},
+		{in: constant.FoldFAdd(h(65504), h(15)), want: "half 0xH7BFF"},
+		// Aggregates.
+		{in: constant.FoldExtractValue(constant.NewCharArrayFromString("abc"), []uint64{1}), want: "i8 98"},
+		{in: constant.FoldInsertValue(constant.NewZeroInitializer(types.NewStruct(types.I32, types.NewArray(2, types.I32))), i32(7), []uint64{1, 0}), want: "{ i32, [2 x i32] } { i32 0, [2 x i32] [i32 7, i32 0] }"},
+	}
+	for _, g := range golden {
+		if g.in == nil {
+			t.Errorf("unable to fold; expected %q", g.want)
+			continue
+		}
+		got := g.in.String()
+		if g.want != got {
+			t.Errorf("constant folding mismatch; expected %q, got %q", g.want, got)
+		}
+	}
+	// Undefined behaviour is not folded; note that integer literals are
+	// truncated to the bit size of their type (e.g. i3 8 is zero).
+	i3 := func(x int64) *constant.Int { return constant.NewInt(types.NewInt(3), x) }
+	undefined := []struct {
+		in   constant.Constant
+		desc string
+	}{
+		{in: constant.FoldUDiv(i32(1), i32(0), false), desc: "division by zero"},
+		{in: constant.FoldUDiv(i3(-1), i3(-1036561864), false), desc: "division by truncated zero"},
+		{in: constant.FoldSDiv(i3(-1), i3(8), false), desc: "division by truncated zero"},
+		{in: constant.FoldURem(i3(-1), i3(8)), desc: "remainder by truncated zero"},
+		{in: constant.FoldSRem(i3(-1), i3(-1036561864)), desc: "remainder by truncated zero"},
+		{in: constant.FoldSDiv(i32(math.MinInt32), i32(-1), false), desc: "signed division overflow"},
+	}
+	for _, g := range undefined {
+		if g.in != nil {
+			t.Errorf("unexpected folding of %s; got %q", g.desc, g.in)
+		}
+	}
+}
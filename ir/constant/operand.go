@@ -0,0 +1,107 @@
+package constant
+
+// Operands returns a mutable list of the constant operands of the given
+// constant; e.g. the elements of an array constant or the operands of a
+// constant expression. Simple constants have no operands.
+//
+// The indices of getelementptr expressions are returned as stored, and may
+// thus be wrapped in *constant.Index. The basic block of blockaddress constants
+// is not a constant, and is therefore not included.
+func Operands(c Constant) []*Constant {
+	switch c := c.(type) {
+	// Complex constants.
+	case *Struct:
+		return sliceOperands(c.Fields)
+	case *Array:
+		return sliceOperands(c.Elems)
+	case *Vector:
+		return sliceOperands(c.Elems)
+	// Addresses of functions and basic blocks.
+	case *BlockAddress:
+		return []*Constant{&c.Func}
+	case *DSOLocalEquivalent:
+		return []*Constant{&c.Func}
+	case *NoCFI:
+		return []*Constant{&c.Func}
+	// Unary expressions.
+	case *ExprFNeg:
+		return []*Constant{&c.X}
+	// Binary expressions.
+	case *ExprAdd:
+		return []*Constant{&c.X, &c.Y}
+	case *ExprSub:
+		return []*Constant{&c.X, &c.Y}
+	case *ExprMul:
+		return []*Constant{&c.X, &c.Y}
+	// Bitwise expressions.
+	case *ExprShl:
+		return []*Constant{&c.X, &c.Y}
+	case *ExprLShr:
+		return []*Constant{&c.X, &c.Y}
+	case *ExprAShr:
+		return []*Constant{&c.X, &c.Y}
+	case *ExprAnd:
+		return []*Constant{&c.X, &c.Y}
+	case *ExprOr:
+		return []*Constant{&c.X, &c.Y}
+	case *ExprXor:
+		return []*Constant{&c.X, &c.Y}
+	// Vector expressions.
+	case *ExprExtractElement:
+		return []*Constant{&c.X, &c.Index}
+	case *ExprInsertElement:
+		return []*Constant{&c.X, &c.Elem, &c.Index}
+	case *ExprShuffleVector:
+		return []*Constant{&c.X, &c.Y, &c.Mask}
+	// Memory expressions.
+	case *ExprGetElementPtr:
+		ops := make([]*Constant, 0, 1+len(c.Indices))
+		ops = append(ops, &c.Src)
+		return append(ops, sliceOperands(c.Indices)...)
+	// Conversion expressions.
+	case *ExprTrunc:
+		return []*Constant{&c.From}
+	case *ExprZExt:
+		return []*Constant{&c.From}
+	case *ExprSExt:
+		return []*Constant{&c.From}
+	case *ExprFPTrunc:
+		return []*Constant{&c.From}
+	case *ExprFPExt:
+		return []*Constant{&c.From}
+	case *ExprFPToUI:
+		return []*Constant{&c.From}
+	case *ExprFPToSI:
+		return []*Constant{&c.From}
+	case *ExprUIToFP:
+		return []*Constant{&c.From}
+	case *ExprSIToFP:
+		return []*Constant{&c.From}
+	case *ExprPtrToInt:
+		return []*Constant{&c.From}
+	case *ExprIntToPtr:
+		return []*Constant{&c.From}
+	case *ExprBitCast:
+		return []*Constant{&c.From}
+	case *ExprAddrSpaceCast:
+		return []*Constant{&c.From}
+	// Other expressions.
+	case *ExprICmp:
+		return []*Constant{&c.X, &c.Y}
+	case *ExprFCmp:
+		return []*Constant{&c.X, &c.Y}
+	case *ExprSelect:
+		return []*Constant{&c.Cond, &c.X, &c.Y}
+	default:
+		return nil
+	}
+}
+
+// sliceOperands returns a mutable list of the given constants.
+func sliceOperands(cs []Constant) []*Constant {
+	ops := make([]*Constant, len(cs))
+	for i := range cs {
+		ops[i] = &cs[i]
+	}
+	return ops
+}
@@ -0,0 +1,447 @@
+// Package datalayout implements parsing of LLVM IR data layout strings, and
+// queries of the size, alignment and layout of types in memory.
+//
+// ref: https://llvm.org/docs/LangRef.html#data-layout
+package datalayout
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/llir/llvm/ir/types"
+	"github.com/pkg/errors"
+)
+
+// DataLayout is a parsed LLVM IR data layout, which specifies how data is laid
+// out in memory.
+//
+// Sizes are specified in bits and alignments in bytes, unless otherwise noted.
+type DataLayout struct {
+	// Byte order.
+	Endianness Endianness
+	// Natural alignment of the stack in bytes; or 0 if unspecified.
+	StackAlign uint64
+	// Address space of functions.
+	ProgramAddrSpace types.AddrSpace
+	// Address space of global variables.
+	GlobalsAddrSpace types.AddrSpace
+	// Address space of alloca instructions.
+	AllocaAddrSpace types.AddrSpace
+	// Pointer layouts, sorted by address space. The layout of address space 0 is
+	// always present, and used for address spaces without a layout.
+	Pointers []PointerLayout
+	// Alignment of integer types, sorted by bit size.
+	IntAligns []TypeAlign
+	// Alignment of floating-point types, sorted by bit size.
+	FloatAligns []TypeAlign
+	// Alignment of vector types, sorted by bit size.
+	VectorAligns []TypeAlign
+	// Alignment of aggregate types. The bit size of AggregateAlign is zero.
+	AggregateAlign TypeAlign
+	// Alignment of function pointers in bytes; or 0 if unspecified.
+	FuncPtrAlign uint64
+	// Function pointer alignment kind.
+	FuncPtrAlignKind FuncPtrAlignKind
+	// Native integer bit sizes of the target CPU.
+	NativeIntSizes []uint64
+	// Non-integral pointer address spaces.
+	NonIntegralAddrSpaces []types.AddrSpace
+	// Name mangling of symbols in the output object file.
+	Mangling Mangling
+
+	// Data layout string.
+	str string
+	// Cached struct layouts.
+	mu      sync.Mutex
+	structs map[*types.StructType]*StructLayout
+}
+
+// PointerLayout specifies the layout of pointers of an address space.
+type PointerLayout struct {
+	// Address space.
+	AddrSpace types.AddrSpace
+	// Size in bits.
+	Size uint64
+	// ABI alignment in bytes.
+	ABIAlign uint64
+	// Preferred alignment in bytes.
+	PrefAlign uint64
+	// Size in bits of indices used in address computations (e.g. of
+	// getelementptr).
+	IndexSize uint64
+}
+
+// TypeAlign specifies the alignment of a type of a given bit size.
+type TypeAlign struct {
+	// Bit size of the type.
+	Size uint64
+	// ABI alignment in bytes.
+	ABIAlign uint64
+	// Preferred alignment in bytes.
+	PrefAlign uint64
+}
+
+//go:generate stringer -linecomment -type Endianness
+
+// Endianness specifies the byte order of data.
+type Endianness uint8
+
+// Byte orders.
+const (
+	LittleEndian Endianness = iota // e
+	BigEndian                      // E
+)
+
+//go:generate stringer -linecomment -type FuncPtrAlignKind
+
+// FuncPtrAlignKind specifies how the alignment of function pointers relates to
+// the alignment of functions.
+type FuncPtrAlignKind uint8
+
+// Function pointer alignment kinds.
+const (
+	// Function pointer alignment is independent of the alignment of functions.
+	FuncPtrAlignIndependent FuncPtrAlignKind = iota // i
+	// Function pointer alignment is a multiple of the explicit alignment of
+	// functions.
+	FuncPtrAlignMultipleOfFuncAlign // n
+)
+
+//go:generate stringer -linecomment -type Mangling
+
+// Mangling specifies the name mangling of symbols.
+type Mangling uint8
+
+// Name mangling modes.
+const (
+	ManglingNone       Mangling = iota // none
+	ManglingELF                        // e
+	ManglingMachO                      // o
+	ManglingMips                       // m
+	ManglingWinCOFF                    // w
+	ManglingWinCOFFX86                 // x
+	ManglingXCOFF                      // a
+	ManglingGOFF                       // l
+)
+
+// defaultLayout is the data layout specified by an empty data layout string.
+const defaultLayout = "e-p:64:64:64-i1:8:8-i8:8:8-i16:16:16-i32:32:32-i64:32:64-f16:16:16-f32:32:32-f64:64:64-f128:128:128-v64:64:64-v128:128:128-a:0:64"
+
+// Default returns the default data layout of LLVM, as used by modules without
+// data layout strings.
+func Default() *DataLayout {
+	dl, err := Parse("")
+	if err != nil {
+		panic(err)
+	}
+	return dl
+}
+
+// Parse parses the given LLVM IR data layout string. Unspecified properties are
+// given the default values of LLVM.
+func Parse(s string) (*DataLayout, error) {
+	dl := &DataLayout{
+		str:     s,
+		structs: make(map[*types.StructType]*StructLayout),
+	}
+	for _, spec := range strings.Split(defaultLayout, "-") {
+		if err := dl.parseSpec(spec); err != nil {
+			panic(err)
+		}
+	}
+	if len(s) == 0 {
+		return dl, nil
+	}
+	for _, spec := range strings.Split(s, "-") {
+		if err := dl.parseSpec(spec); err != nil {
+			return nil, errors.Wrapf(err, "invalid data layout %q", s)
+		}
+	}
+	return dl, nil
+}
+
+// String returns the data layout string of the data layout.
+func (dl *DataLayout) String() string {
+	return dl.str
+}
+
+// parseSpec parses the given data layout specification.
+func (dl *DataLayout) parseSpec(spec string) error {
+	if len(spec) == 0 {
+		return errors.New("empty specification")
+	}
+	switch {
+	case spec == "e":
+		dl.Endianness = LittleEndian
+	case spec == "E":
+		dl.Endianness = BigEndian
+	case strings.HasPrefix(spec, "ni:"):
+		for _, field := range strings.Split(spec[len("ni:"):], ":") {
+			addrSpace, err := parseUint(spec, field)
+			if err != nil {
+				return err
+			}
+			if addrSpace == 0 {
+				return errors.Errorf("invalid specification %q; address space 0 cannot be non-integral", spec)
+			}
+			dl.NonIntegralAddrSpaces = append(dl.NonIntegralAddrSpaces, types.AddrSpace(addrSpace))
+		}
+	case spec[0] == 'S':
+		align, err := parseAlign(spec, spec[1:])
+		if err != nil {
+			return err
+		}
+		dl.StackAlign = align
+	case spec[0] == 'P', spec[0] == 'G', spec[0] == 'A':
+		addrSpace, err := parseUint(spec, spec[1:])
+		if err != nil {
+			return err
+		}
+		switch spec[0] {
+		case 'P':
+			dl.ProgramAddrSpace = types.AddrSpace(addrSpace)
+		case 'G':
+			dl.GlobalsAddrSpace = types.AddrSpace(addrSpace)
+		case 'A':
+			dl.AllocaAddrSpace = types.AddrSpace(addrSpace)
+		}
+	case spec[0] == 'p':
+		return dl.parsePointerSpec(spec)
+	case spec[0] == 'i', spec[0] == 'f', spec[0] == 'v', spec[0] == 'a':
+		return dl.parseAlignSpec(spec)
+	case spec[0] == 'F':
+		if len(spec) < 2 {
+			return errors.Errorf("invalid specification %q; missing function pointer alignment kind", spec)
+		}
+		switch spec[1] {
+		case 'i':
+			dl.FuncPtrAlignKind = FuncPtrAlignIndependent
+		case 'n':
+			dl.FuncPtrAlignKind = FuncPtrAlignMultipleOfFuncAlign
+		default:
+			return errors.Errorf("invalid specification %q; unknown function pointer alignment kind %q", spec, spec[1])
+		}
+		align, err := parseAlign(spec, spec[2:])
+		if err != nil {
+			return err
+		}
+		dl.FuncPtrAlign = align
+	case spec[0] == 'n':
+		for _, field := range strings.Split(spec[1:], ":") {
+			size, err := parseUint(spec, field)
+			if err != nil {
+				return err
+			}
+			if size == 0 {
+				return errors.Errorf("invalid specification %q; zero width native integer type", spec)
+			}
+			dl.NativeIntSizes = append(dl.NativeIntSizes, size)
+		}
+	case spec[0] == 'm':
+		if !strings.HasPrefix(spec, "m:") || len(spec) != len("m:")+1 {
+			return errors.Errorf("invalid specification %q; expected mangling of the form m:<mangling>", spec)
+		}
+		switch spec[2] {
+		case 'e':
+			dl.Mangling = ManglingELF
+		case 'o':
+			dl.Mangling = ManglingMachO
+		case 'm':
+			dl.Mangling = ManglingMips
+		case 'w':
+			dl.Mangling = ManglingWinCOFF
+		case 'x':
+			dl.Mangling = ManglingWinCOFFX86
+		case 'a':
+			dl.Mangling = ManglingXCOFF
+		case 'l':
+			dl.Mangling = ManglingGOFF
+		default:
+			return errors.Errorf("invalid specification %q; unknown mangling mode %q", spec, spec[2])
+		}
+	default:
+		return errors.Errorf("invalid specification %q; unknown specifier %q", spec, spec[0])
+	}
+	return nil
+}
+
+// parsePointerSpec parses the given pointer specification, of the form
+// p[n]:<size>:<abi>[:<pref>][:<idx>].
+func (dl *DataLayout) parsePointerSpec(spec string) error {
+	fields := strings.Split(spec, ":")
+	if len(fields) < 3 || len(fields) > 5 {
+		return errors.Errorf("invalid specification %q; expected pointer specification of the form p[n]:<size>:<abi>[:<pref>][:<idx>]", spec)
+	}
+	var addrSpace uint64
+	if len(fields[0]) > 1 {
+		var err error
+		if addrSpace, err = parseUint(spec, fields[0][1:]); err != nil {
+			return err
+		}
+	}
+	size, err := parseUint(spec, fields[1])
+	if err != nil {
+		return err
+	}
+	if size == 0 {
+		return errors.Errorf("invalid specification %q; pointer size must be non-zero", spec)
+	}
+	abi, err := parseAlign(spec, fields[2])
+	if err != nil {
+		return err
+	}
+	if abi == 0 {
+		return errors.Errorf("invalid specification %q; pointer ABI alignment must be non-zero", spec)
+	}
+	pref := abi
+	if len(fields) >= 4 {
+		if pref, err = parseAlign(spec, fields[3]); err != nil {
+			return err
+		}
+	}
+	if pref < abi {
+		return errors.Errorf("invalid specification %q; preferred alignment cannot be less than the ABI alignment", spec)
+	}
+	idx := size
+	if len(fields) == 5 {
+		if idx, err = parseUint(spec, fields[4]); err != nil {
+			return err
+		}
+		if idx > size {
+			return errors.Errorf("invalid specification %q; index size cannot be larger than the pointer size", spec)
+		}
+	}
+	p := PointerLayout{
+		AddrSpace: types.AddrSpace(addrSpace),
+		Size:      size,
+		ABIAlign:  abi,
+		PrefAlign: pref,
+		IndexSize: idx,
+	}
+	i := sort.Search(len(dl.Pointers), func(i int) bool {
+		return dl.Pointers[i].AddrSpace >= p.AddrSpace
+	})
+	if i < len(dl.Pointers) && dl.Pointers[i].AddrSpace == p.AddrSpace {
+		dl.Pointers[i] = p
+		return nil
+	}
+	dl.Pointers = append(dl.Pointers, PointerLayout{})
+	copy(dl.Pointers[i+1:], dl.Pointers[i:])
+	dl.Pointers[i] = p
+	return nil
+}
+
+// parseAlignSpec parses the given integer, floating-point, vector or aggregate
+// alignment specification, of the form [ifv]<size>:<abi>[:<pref>] or
+// a:<abi>[:<pref>].
+func (dl *DataLayout) parseAlignSpec(spec string) error {
+	fields := strings.Split(spec, ":")
+	if len(fields) < 2 || len(fields) > 3 {
+		return errors.Errorf("invalid specification %q; expected alignment specification of the form %c<size>:<abi>[:<pref>]", spec, spec[0])
+	}
+	var size uint64
+	if spec[0] == 'a' {
+		if len(fields[0]) > 1 {
+			// Sizes of aggregate alignments are ignored.
+			if _, err := parseUint(spec, fields[0][1:]); err != nil {
+				return err
+			}
+		}
+	} else {
+		var err error
+		if size, err = parseUint(spec, fields[0][1:]); err != nil {
+			return err
+		}
+		if size == 0 {
+			return errors.Errorf("invalid specification %q; type size must be non-zero", spec)
+		}
+	}
+	abi, err := parseAlign(spec, fields[1])
+	if err != nil {
+		return err
+	}
+	if spec[0] != 'a' && abi == 0 {
+		return errors.Errorf("invalid specification %q; ABI alignment of non-aggregate types must be non-zero", spec)
+	}
+	if spec[0] == 'i' && size == 8 && abi != 1 {
+		return errors.Errorf("invalid specification %q; i8 must be naturally aligned", spec)
+	}
+	pref := abi
+	if len(fields) == 3 {
+		if pref, err = parseAlign(spec, fields[2]); err != nil {
+			return err
+		}
+	}
+	if pref < abi {
+		return errors.Errorf("invalid specification %q; preferred alignment cannot be less than the ABI alignment", spec)
+	}
+	a := TypeAlign{Size: size, ABIAlign: abi, PrefAlign: pref}
+	switch spec[0] {
+	case 'i':
+		dl.IntAligns = setAlign(dl.IntAligns, a)
+	case 'f':
+		dl.FloatAligns = setAlign(dl.FloatAligns, a)
+	case 'v':
+		dl.VectorAligns = setAlign(dl.VectorAligns, a)
+	case 'a':
+		dl.AggregateAlign = a
+	}
+	return nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// setAlign sets the given type alignment in the list of type alignments sorted
+// by bit size.
+func setAlign(aligns []TypeAlign, a TypeAlign) []TypeAlign {
+	i := lowerBound(aligns, a.Size)
+	if i < len(aligns) && aligns[i].Size == a.Size {
+		aligns[i] = a
+		return aligns
+	}
+	aligns = append(aligns, TypeAlign{})
+	copy(aligns[i+1:], aligns[i:])
+	aligns[i] = a
+	return aligns
+}
+
+// lowerBound returns the index of the first type alignment in the sorted list
+// with a bit size greater than or equal to the given size; or len(aligns) if
+// none.
+func lowerBound(aligns []TypeAlign, size uint64) int {
+	return sort.Search(len(aligns), func(i int) bool {
+		return aligns[i].Size >= size
+	})
+}
+
+// parseUint parses the given decimal integer field of a specification.
+func parseUint(spec, field string) (uint64, error) {
+	if len(field) == 0 {
+		return 0, errors.Errorf("invalid specification %q; missing integer", spec)
+	}
+	x, err := strconv.ParseUint(field, 10, 32)
+	if err != nil {
+		return 0, errors.Errorf("invalid specification %q; invalid integer %q", spec, field)
+	}
+	return x, nil
+}
+
+// parseAlign parses the given alignment field of a specification, specified
+// in bits, and returns the alignment in bytes.
+func parseAlign(spec, field string) (uint64, error) {
+	bits, err := parseUint(spec, field)
+	if err != nil {
+		return 0, err
+	}
+	if bits%8 != 0 || !isPow2(bits/8) && bits != 0 {
+		return 0, errors.Errorf("invalid specification %q; alignment %d is not a power of two multiple of 8 bits", spec, bits)
+	}
+	return bits / 8, nil
+}
+
+// isPow2 reports whether x is a power of two.
+func isPow2(x uint64) bool {
+	return x != 0 && x&(x-1) == 0
+}
@@ -0,0 +1,290 @@
+package datalayout_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/llir/llvm/ir/datalayout"
+	"github.com/llir/llvm/ir/types"
+)
+
+const (
+	// Data layout of x86_64-unknown-linux-gnu.
+	x86_64 = "e-m:e-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128"
+	// Data layout of i386-unknown-linux-gnu.
+	i386 = "e-m:e-p:32:32-p270:32:32-p271:32:32-p272:64:64-f64:32:64-f80:32-n8:16:32-S128"
+	// Data layout of i686-pc-windows-msvc.
+	i686Win = "e-m:x-p:32:32-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:32-n8:16:32-a:0:32-S32"
+	// Data layout of aarch64-unknown-linux-gnu.
+	aarch64 = "e-m:e-i8:8:32-i16:16:32-i64:64-i128:128-n32:64-S128"
+)
+
+func TestParse(t *testing.T) {
+	golden := []struct {
+		in string
+		// Error message of invalid data layouts.
+		err string
+	}{
+		{in: ""},
+		{in: x86_64},
+		{in: i386},
+		{in: i686Win},
+		{in: aarch64},
+		{in: "E-m:o-p:32:32:32:16-ni:1:2-A5-G1-P2-Fn32-v256:256:256"},
+		{in: "q", err: `invalid data layout "q": invalid specification "q"; unknown specifier 'q'`},
+		{in: "e-p:0:64", err: `invalid data layout "e-p:0:64": invalid specification "p:0:64"; pointer size must be non-zero`},
+		{in: "i32:24", err: `invalid data layout "i32:24": invalid specification "i32:24"; alignment 24 is not a power of two multiple of 8 bits`},
+		{in: "i8:16", err: `invalid data layout "i8:16": invalid specification "i8:16"; i8 must be naturally aligned`},
+		{in: "i64:64:32", err: `invalid data layout "i64:64:32": invalid specification "i64:64:32"; preferred alignment cannot be less than the ABI alignment`},
+		{in: "m:z", err: `invalid data layout "m:z": invalid specification "m:z"; unknown mangling mode 'z'`},
+		{in: "ni:0", err: `invalid data layout "ni:0": invalid specification "ni:0"; address space 0 cannot be non-integral`},
+	}
+	for _, g := range golden {
+		dl, err := datalayout.Parse(g.in)
+		if len(g.err) > 0 {
+			if err == nil {
+				t.Errorf("%q: expected error, got data layout", g.in)
+			} else if got := err.Error(); got != g.err {
+				t.Errorf("%q: error mismatch; expected %q, got %q", g.in, g.err, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unable to parse data layout; %+v", g.in, err)
+			continue
+		}
+		if got := dl.String(); got != g.in {
+			t.Errorf("%q: data layout string mismatch; expected %q, got %q", g.in, g.in, got)
+		}
+	}
+}
+
+func TestDataLayoutFields(t *testing.T) {
+	dl, err := datalayout.Parse("E-m:o-p:32:32:32:16-p1:64:64-ni:1:2-A5-G1-P2-Fn32-n8:16:32-S64")
+	if err != nil {
+		t.Fatalf("unable to parse data layout; %+v", err)
+	}
+	if dl.Endianness != datalayout.BigEndian {
+		t.Errorf("endianness mismatch; expected %v, got %v", datalayout.BigEndian, dl.Endianness)
+	}
+	if dl.Mangling != datalayout.ManglingMachO {
+		t.Errorf("mangling mismatch; expected %v, got %v", datalayout.ManglingMachO, dl.Mangling)
+	}
+	if got := dl.GlobalPrefix(); got != "_" {
+		t.Errorf("global prefix mismatch; expected %q, got %q", "_", got)
+	}
+	if dl.StackAlign != 8 {
+		t.Errorf("stack alignment mismatch; expected 8, got %d", dl.StackAlign)
+	}
+	if dl.AllocaAddrSpace != 5 || dl.GlobalsAddrSpace != 1 || dl.ProgramAddrSpace != 2 {
+		t.Errorf("address space mismatch; expected A5, G1 and P2, got A%d, G%d and P%d", dl.AllocaAddrSpace, dl.GlobalsAddrSpace, dl.ProgramAddrSpace)
+	}
+	if dl.FuncPtrAlign != 4 || dl.FuncPtrAlignKind != datalayout.FuncPtrAlignMultipleOfFuncAlign {
+		t.Errorf("function pointer alignment mismatch; expected Fn32, got F%v%d", dl.FuncPtrAlignKind, dl.FuncPtrAlign*8)
+	}
+	wantPointers := []datalayout.PointerLayout{
+		{AddrSpace: 0, Size: 32, ABIAlign: 4, PrefAlign: 4, IndexSize: 16},
+		{AddrSpace: 1, Size: 64, ABIAlign: 8, PrefAlign: 8, IndexSize: 64},
+	}
+	if diff := cmp.Diff(wantPointers, dl.Pointers); diff != "" {
+		t.Errorf("pointer layout mismatch (-want +got):\n%s", diff)
+	}
+	if got := dl.PointerLayout(7); got.Size != 32 {
+		t.Errorf("pointer size mismatch of address space 7; expected 32, got %d", got.Size)
+	}
+	if got := dl.IndexType(0); !got.Equal(types.I16) {
+		t.Errorf("index type mismatch; expected %q, got %q", types.I16, got)
+	}
+	if got := dl.IntPtrType(1); !got.Equal(types.I64) {
+		t.Errorf("pointer-sized integer type mismatch; expected %q, got %q", types.I64, got)
+	}
+	for _, g := range []struct {
+		addrSpace types.AddrSpace
+		want      bool
+	}{{0, false}, {1, true}, {2, true}, {3, false}} {
+		if got := dl.IsNonIntegral(g.addrSpace); got != g.want {
+			t.Errorf("address space %d: non-integral mismatch; expected %v, got %v", g.addrSpace, g.want, got)
+		}
+	}
+	for _, g := range []struct {
+		size uint64
+		want bool
+	}{{8, true}, {32, true}, {64, false}} {
+		if got := dl.IsLegalInt(g.size); got != g.want {
+			t.Errorf("i%d: legal integer mismatch; expected %v, got %v", g.size, g.want, got)
+		}
+	}
+}
+
+func TestSizeOf(t *testing.T) {
+	// Expected sizes and alignments have been verified against LLVM 14.
+	golden := []struct {
+		layout string
+		typ    types.Type
+		// Size, store size and ABI alignment in bytes.
+		size, storeSize, align uint64
+	}{
+		{layout: "", typ: types.I1, size: 1, storeSize: 1, align: 1},
+		{layout: "", typ: types.I64, size: 8, storeSize: 8, align: 4},
+		{layout: "", typ: types.NewInt(24), size: 4, storeSize: 3, align: 4},
+		{layout: "", typ: types.NewInt(128), size: 16, storeSize: 16, align: 4},
+		{layout: "", typ: types.X86_FP80, size: 16, storeSize: 10, align: 16},
+		{layout: "", typ: types.I8Ptr, size: 8, storeSize: 8, align: 8},
+		{layout: x86_64, typ: types.I64, size: 8, storeSize: 8, align: 8},
+		{layout: x86_64, typ: types.X86_FP80, size: 16, storeSize: 10, align: 16},
+		{layout: x86_64, typ: types.NewPointer(types.I8), size: 8, storeSize: 8, align: 8},
+		{layout: x86_64, typ: &types.PointerType{ElemType: types.I8, AddrSpace: 270}, size: 4, storeSize: 4, align: 4},
+		{layout: x86_64, typ: types.NewInt(256), size: 32, storeSize: 32, align: 8},
+		{layout: i386, typ: types.I64, size: 8, storeSize: 8, align: 4},
+		{layout: i386, typ: types.Double, size: 8, storeSize: 8, align: 4},
+		{layout: i386, typ: types.X86_FP80, size: 12, storeSize: 10, align: 4},
+		{layout: i386, typ: types.I8Ptr, size: 4, storeSize: 4, align: 4},
+		{layout: i686Win, typ: types.I64, size: 8, storeSize: 8, align: 8},
+		{layout: aarch64, typ: types.NewInt(128), size: 16, storeSize: 16, align: 16},
+		{layout: aarch64, typ: types.FP128, size: 16, storeSize: 16, align: 16},
+		// Vectors.
+		{layout: x86_64, typ: types.NewVector(4, types.I32), size: 16, storeSize: 16, align: 16},
+		{layout: x86_64, typ: types.NewVector(3, types.Float), size: 16, storeSize: 12, align: 16},
+		{layout: x86_64, typ: types.NewVector(8, types.Double), size: 64, storeSize: 64, align: 64},
+		{layout: x86_64, typ: types.NewVector(3, types.I1), size: 1, storeSize: 1, align: 1},
+		// Arrays.
+		{layout: x86_64, typ: types.NewArray(3, types.I16), size: 6, storeSize: 6, align: 2},
+		{layout: x86_64, typ: types.NewArray(5, types.X86_FP80), size: 80, storeSize: 80, align: 16},
+		{layout: i386, typ: types.NewArray(5, types.X86_FP80), size: 60, storeSize: 60, align: 4},
+		{layout: x86_64, typ: types.NewArray(0, types.I32), size: 0, storeSize: 0, align: 4},
+		// Structs.
+		{layout: x86_64, typ: types.NewStruct(), size: 0, storeSize: 0, align: 1},
+		{layout: x86_64, typ: types.NewStruct(types.I8, types.I64), size: 16, storeSize: 16, align: 8},
+		{layout: i386, typ: types.NewStruct(types.I8, types.I64), size: 12, storeSize: 12, align: 4},
+		{layout: i686Win, typ: types.NewStruct(types.I8, types.I64), size: 16, storeSize: 16, align: 8},
+		{layout: "e-p:32:32-i64:64-a:32", typ: types.NewStruct(types.I8), size: 4, storeSize: 1, align: 4},
+		{layout: x86_64, typ: &types.StructType{Packed: true, Fields: []types.Type{types.I8, types.I32, types.I16}}, size: 7, storeSize: 7, align: 1},
+	}
+	for _, g := range golden {
+		dl, err := datalayout.Parse(g.layout)
+		if err != nil {
+			t.Errorf("%q: unable to parse data layout; %+v", g.layout, err)
+			continue
+		}
+		if got := dl.SizeOf(g.typ); got != g.size {
+			t.Errorf("%q: size mismatch of %q; expected %d, got %d", g.layout, g.typ, g.size, got)
+		}
+		if got := dl.StoreSizeOf(g.typ); got != g.storeSize {
+			t.Errorf("%q: store size mismatch of %q; expected %d, got %d", g.layout, g.typ, g.storeSize, got)
+		}
+		if got := dl.AlignOf(g.typ); got != g.align {
+			t.Errorf("%q: alignment mismatch of %q; expected %d, got %d", g.layout, g.typ, g.align, got)
+		}
+	}
+}
+
+func TestStructLayout(t *testing.T) {
+	inner := types.NewStruct(types.I8, types.I16, types.Double)
+	golden := []struct {
+		layout string
+		typ    *types.StructType
+		want   *datalayout.StructLayout
+	}{
+		{
+			layout: x86_64,
+			typ:    types.NewStruct(types.I8, types.I16, types.I8),
+			want:   &datalayout.StructLayout{Size: 6, Align: 2, Offsets: []uint64{0, 2, 4}, HasPadding: true},
+		},
+		{
+			layout: x86_64,
+			typ:    types.NewStruct(types.I32, types.I32),
+			want:   &datalayout.StructLayout{Size: 8, Align: 4, Offsets: []uint64{0, 4}},
+		},
+		{
+			layout: x86_64,
+			typ:    types.NewStruct(types.I8, inner, types.X86_FP80, types.NewArray(3, types.I8)),
+			want:   &datalayout.StructLayout{Size: 64, Align: 16, Offsets: []uint64{0, 8, 32, 48}, HasPadding: true},
+		},
+		{
+			layout: i386,
+			typ:    types.NewStruct(types.I8, inner, types.X86_FP80, types.NewArray(3, types.I8)),
+			want:   &datalayout.StructLayout{Size: 32, Align: 4, Offsets: []uint64{0, 4, 16, 28}, HasPadding: true},
+		},
+		{
+			layout: x86_64,
+			typ:    &types.StructType{Packed: true, Fields: []types.Type{types.I8, types.I64}},
+			want:   &datalayout.StructLayout{Size: 9, Align: 1, Offsets: []uint64{0, 1}},
+		},
+	}
+	for _, g := range golden {
+		dl, err := datalayout.Parse(g.layout)
+		if err != nil {
+			t.Errorf("%q: unable to parse data layout; %+v", g.layout, err)
+			continue
+		}
+		got := dl.StructLayout(g.typ)
+		if diff := cmp.Diff(g.want, got); diff != "" {
+			t.Errorf("%q: struct layout mismatch of %q (-want +got):\n%s", g.layout, g.typ, diff)
+		}
+	}
+}
+
+func TestFieldAt(t *testing.T) {
+	l := &datalayout.StructLayout{Size: 16, Align: 8, Offsets: []uint64{0, 2, 8}}
+	golden := []struct {
+		offset uint64
+		want   int
+	}{
+		{offset: 0, want: 0},
+		{offset: 1, want: 0},
+		{offset: 2, want: 1},
+		{offset: 7, want: 1},
+		{offset: 8, want: 2},
+		{offset: 15, want: 2},
+	}
+	for _, g := range golden {
+		if got := l.FieldAt(g.offset); got != g.want {
+			t.Errorf("offset %d: field index mismatch; expected %d, got %d", g.offset, g.want, got)
+		}
+	}
+}
+
+func TestGEPOffset(t *testing.T) {
+	dl, err := datalayout.Parse(x86_64)
+	if err != nil {
+		t.Fatalf("unable to parse data layout; %+v", err)
+	}
+	// %T = type { i8, [4 x { i16, double }], <4 x i32> }
+	elem := types.NewStruct(types.I16, types.Double)
+	st := types.NewStruct(types.I8, types.NewArray(4, elem), types.NewVector(4, types.I32))
+	golden := []struct {
+		elemType types.Type
+		indices  []int64
+		want     int64
+		// Error message of invalid indices.
+		err string
+	}{
+		{elemType: st, indices: nil, want: 0},
+		{elemType: st, indices: []int64{1}, want: 96},
+		{elemType: st, indices: []int64{-1}, want: -96},
+		{elemType: st, indices: []int64{0, 1}, want: 8},
+		{elemType: st, indices: []int64{0, 1, 2, 1}, want: 48},
+		{elemType: st, indices: []int64{1, 2, 3}, want: 96 + 80 + 12},
+		{elemType: types.I32, indices: []int64{3}, want: 12},
+		{elemType: st, indices: []int64{0, 3}, err: `invalid struct index 3 of type "{ i8, [4 x { i16, double }], <4 x i32> }" with 3 fields`},
+		{elemType: st, indices: []int64{0, 0, 1}, err: `unable to index into non-aggregate type "i8"`},
+	}
+	for _, g := range golden {
+		got, err := dl.GEPOffset(g.elemType, g.indices...)
+		if len(g.err) > 0 {
+			if err == nil {
+				t.Errorf("%v: expected error, got offset %d", g.indices, got)
+			} else if got := err.Error(); got != g.err {
+				t.Errorf("%v: error mismatch; expected %q, got %q", g.indices, g.err, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unable to compute offset; %+v", g.indices, err)
+			continue
+		}
+		if got != g.want {
+			t.Errorf("%v: offset mismatch; expected %d, got %d", g.indices, g.want, got)
+		}
+	}
+}
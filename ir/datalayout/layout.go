@@ -0,0 +1,331 @@
+package datalayout
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/llir/llvm/ir/types"
+	"github.com/pkg/errors"
+)
+
+// === [ Type sizes ] ==========================================================
+
+// BitSizeOf returns the number of bits required to hold a value of the given
+// type; e.g. 1 for i1 and 80 for x86_fp80.
+//
+// The size of scalable vectors is their minimum size. BitSizeOf panics if t is
+// unsized (e.g. void, label, function and opaque struct types).
+func (dl *DataLayout) BitSizeOf(t types.Type) uint64 {
+	switch t := t.(type) {
+	case *types.IntType:
+		return t.BitSize
+	case *types.FloatType:
+		return floatBitSize(t.Kind)
+	case *types.MMXType:
+		return 64
+	case *types.PointerType:
+		return dl.PointerLayout(t.AddrSpace).Size
+	case *types.VectorType:
+		return t.Len * dl.BitSizeOf(t.ElemType)
+	case *types.ArrayType:
+		return t.Len * dl.SizeOf(t.ElemType) * 8
+	case *types.StructType:
+		return dl.StructLayout(t).Size * 8
+	default:
+		panic(fmt.Errorf("unable to compute size of unsized type %q", t))
+	}
+}
+
+// StoreSizeOf returns the maximum number of bytes written when storing a value
+// of the given type; e.g. 1 for i1 and 10 for x86_fp80.
+func (dl *DataLayout) StoreSizeOf(t types.Type) uint64 {
+	return (dl.BitSizeOf(t) + 7) / 8
+}
+
+// SizeOf returns the offset in bytes between successive values of the given
+// type in memory (e.g. in arrays), including alignment padding; e.g. 1 for i1
+// and 16 for x86_fp80 with 16 byte alignment. This is the size reported by
+// sizeof in C.
+func (dl *DataLayout) SizeOf(t types.Type) uint64 {
+	return alignTo(dl.StoreSizeOf(t), dl.AlignOf(t))
+}
+
+// === [ Type alignments ] =====================================================
+
+// AlignOf returns the ABI alignment in bytes of the given type.
+func (dl *DataLayout) AlignOf(t types.Type) uint64 {
+	return dl.alignOf(t, true)
+}
+
+// PrefAlignOf returns the preferred alignment in bytes of the given type.
+func (dl *DataLayout) PrefAlignOf(t types.Type) uint64 {
+	return dl.alignOf(t, false)
+}
+
+// alignOf returns the ABI or preferred alignment in bytes of the given type.
+func (dl *DataLayout) alignOf(t types.Type, abi bool) uint64 {
+	switch t := t.(type) {
+	case *types.IntType:
+		// Use the alignment of the next larger integer type if there is no exact
+		// match, or the largest integer type if none is larger.
+		i := lowerBound(dl.IntAligns, t.BitSize)
+		if i == len(dl.IntAligns) {
+			i--
+		}
+		return pick(dl.IntAligns[i], abi)
+	case *types.FloatType:
+		size := floatBitSize(t.Kind)
+		if i := lowerBound(dl.FloatAligns, size); i < len(dl.FloatAligns) && dl.FloatAligns[i].Size == size {
+			return pick(dl.FloatAligns[i], abi)
+		}
+		// Fall back to the natural alignment of the store size; e.g. 16 for
+		// x86_fp80.
+		return pow2Ceil((size + 7) / 8)
+	case *types.PointerType:
+		p := dl.PointerLayout(t.AddrSpace)
+		if abi {
+			return p.ABIAlign
+		}
+		return p.PrefAlign
+	case *types.MMXType, *types.VectorType:
+		size := dl.BitSizeOf(t)
+		if i := lowerBound(dl.VectorAligns, size); i < len(dl.VectorAligns) && dl.VectorAligns[i].Size == size {
+			return pick(dl.VectorAligns[i], abi)
+		}
+		// Fall back to the natural alignment of the store size.
+		return pow2Ceil((size + 7) / 8)
+	case *types.ArrayType:
+		return dl.alignOf(t.ElemType, abi)
+	case *types.StructType:
+		if t.Packed && abi {
+			// Packed structs always have an ABI alignment of one.
+			return 1
+		}
+		align := pick(dl.AggregateAlign, abi)
+		if structAlign := dl.StructLayout(t).Align; structAlign > align {
+			align = structAlign
+		}
+		return align
+	default:
+		panic(fmt.Errorf("unable to compute alignment of unsized type %q", t))
+	}
+}
+
+// === [ Pointers ] ============================================================
+
+// PointerLayout returns the pointer layout of the given address space. The
+// pointer layout of address space 0 is used for address spaces without a
+// pointer layout.
+func (dl *DataLayout) PointerLayout(addrSpace types.AddrSpace) PointerLayout {
+	i := sort.Search(len(dl.Pointers), func(i int) bool {
+		return dl.Pointers[i].AddrSpace >= addrSpace
+	})
+	if i < len(dl.Pointers) && dl.Pointers[i].AddrSpace == addrSpace {
+		return dl.Pointers[i]
+	}
+	if addrSpace != 0 {
+		return dl.PointerLayout(0)
+	}
+	// Unreachable, as the default data layout specifies address space 0.
+	panic("missing pointer layout of address space 0")
+}
+
+// IntPtrType returns the integer type with the same bit size as pointers of
+// the given address space.
+func (dl *DataLayout) IntPtrType(addrSpace types.AddrSpace) *types.IntType {
+	return types.NewInt(dl.PointerLayout(addrSpace).Size)
+}
+
+// IndexType returns the integer type of indices used in address computations
+// of pointers of the given address space.
+func (dl *DataLayout) IndexType(addrSpace types.AddrSpace) *types.IntType {
+	return types.NewInt(dl.PointerLayout(addrSpace).IndexSize)
+}
+
+// IsNonIntegral reports whether pointers of the given address space are
+// non-integral.
+func (dl *DataLayout) IsNonIntegral(addrSpace types.AddrSpace) bool {
+	for _, a := range dl.NonIntegralAddrSpaces {
+		if a == addrSpace {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLegalInt reports whether integers of the given bit size are native to the
+// target CPU.
+func (dl *DataLayout) IsLegalInt(size uint64) bool {
+	for _, s := range dl.NativeIntSizes {
+		if s == size {
+			return true
+		}
+	}
+	return false
+}
+
+// GlobalPrefix returns the prefix added to the names of global symbols by the
+// name mangling of the data layout; e.g. "_" for Mach-O.
+func (dl *DataLayout) GlobalPrefix() string {
+	switch dl.Mangling {
+	case ManglingMachO, ManglingWinCOFFX86:
+		return "_"
+	default:
+		return ""
+	}
+}
+
+// === [ Struct layouts ] ======================================================
+
+// StructLayout is the memory layout of a struct type.
+type StructLayout struct {
+	// Size in bytes, including tail padding.
+	Size uint64
+	// Alignment in bytes; the maximum ABI alignment of the fields, or 1 for
+	// packed structs.
+	Align uint64
+	// Offset in bytes of each field.
+	Offsets []uint64
+	// Specifies whether the struct contains padding between fields or at the
+	// end.
+	HasPadding bool
+}
+
+// StructLayout returns the memory layout of the given struct type. StructLayout
+// panics if t is opaque.
+//
+// The layout is cached by the data layout, and thus not updated if the fields
+// of the struct type are later modified.
+func (dl *DataLayout) StructLayout(t *types.StructType) *StructLayout {
+	dl.mu.Lock()
+	layout, ok := dl.structs[t]
+	dl.mu.Unlock()
+	if ok {
+		return layout
+	}
+	if t.Opaque {
+		panic(fmt.Errorf("unable to compute layout of opaque struct type %q", t))
+	}
+	layout = &StructLayout{Align: 1}
+	var offset uint64
+	for _, field := range t.Fields {
+		align := uint64(1)
+		if !t.Packed {
+			align = dl.AlignOf(field)
+		}
+		if offset%align != 0 {
+			layout.HasPadding = true
+			offset = alignTo(offset, align)
+		}
+		if align > layout.Align {
+			layout.Align = align
+		}
+		layout.Offsets = append(layout.Offsets, offset)
+		offset += dl.SizeOf(field)
+	}
+	// Add tail padding, so that arrays of the struct are aligned.
+	if offset%layout.Align != 0 {
+		layout.HasPadding = true
+		offset = alignTo(offset, layout.Align)
+	}
+	layout.Size = offset
+	dl.mu.Lock()
+	dl.structs[t] = layout
+	dl.mu.Unlock()
+	return layout
+}
+
+// FieldAt returns the index of the field containing the given byte offset of
+// the struct, or of the padding following the field; or -1 if the struct has
+// no fields. The offset must be less than the size of the struct.
+func (l *StructLayout) FieldAt(offset uint64) int {
+	// Index of the first field with an offset greater than the given offset.
+	i := sort.Search(len(l.Offsets), func(i int) bool {
+		return l.Offsets[i] > offset
+	})
+	return i - 1
+}
+
+// === [ Address computations ] ================================================
+
+// GEPOffset returns the byte offset computed by a getelementptr instruction
+// with the given source element type and constant indices; e.g. the offset of
+// field 1 of the struct {i8, i32} is computed by the indices 0, 1.
+//
+// The first index steps over values of the source element type, and
+// subsequent indices step into arrays, vectors and structs. Struct indices
+// must be in range.
+func (dl *DataLayout) GEPOffset(elemType types.Type, indices ...int64) (int64, error) {
+	if len(indices) == 0 {
+		return 0, nil
+	}
+	offset := indices[0] * int64(dl.SizeOf(elemType))
+	t := elemType
+	for _, index := range indices[1:] {
+		switch tt := t.(type) {
+		case *types.ArrayType:
+			t = tt.ElemType
+			offset += index * int64(dl.SizeOf(t))
+		case *types.VectorType:
+			t = tt.ElemType
+			offset += index * int64(dl.SizeOf(t))
+		case *types.StructType:
+			if index < 0 || index >= int64(len(tt.Fields)) {
+				return 0, errors.Errorf("invalid struct index %d of type %q with %d fields", index, tt, len(tt.Fields))
+			}
+			offset += int64(dl.StructLayout(tt).Offsets[index])
+			t = tt.Fields[index]
+		default:
+			return 0, errors.Errorf("unable to index into non-aggregate type %q", t)
+		}
+	}
+	return offset, nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// floatBitSize returns the bit size of the given floating-point kind.
+func floatBitSize(kind types.FloatKind) uint64 {
+	switch kind {
+	case types.FloatKindHalf:
+		return 16
+	case types.FloatKindFloat:
+		return 32
+	case types.FloatKindDouble:
+		return 64
+	case types.FloatKindX86_FP80:
+		return 80
+	case types.FloatKindFP128, types.FloatKindPPC_FP128:
+		return 128
+	default:
+		panic(fmt.Errorf("support for floating-point kind %v not yet implemented", kind))
+	}
+}
+
+// pick returns the ABI or preferred alignment of the given type alignment. An
+// alignment of 0 is returned as 1.
+func pick(a TypeAlign, abi bool) uint64 {
+	align := a.PrefAlign
+	if abi {
+		align = a.ABIAlign
+	}
+	if align == 0 {
+		return 1
+	}
+	return align
+}
+
+// alignTo returns x rounded up to a multiple of align.
+func alignTo(x, align uint64) uint64 {
+	return (x + align - 1) / align * align
+}
+
+// pow2Ceil returns the smallest power of two greater than or equal to x, and at
+// least 1.
+func pow2Ceil(x uint64) uint64 {
+	p := uint64(1)
+	for p < x {
+		p <<= 1
+	}
+	return p
+}
@@ -0,0 +1,478 @@
+// Package dom provides dominator and post-dominator trees of LLVM IR functions.
+//
+// The immediate dominators are computed using the algorithm of Cooper, Harvey
+// and Kennedy [1], on top of the control flow graph of the function (see
+// package cfg). Dominance queries are answered in constant time, based on a
+// depth-first numbering of the dominator tree.
+//
+// [1]: Cooper, K. D., Harvey, T. J., & Kennedy, K. (2001). A Simple, Fast
+// Dominance Algorithm.
+package dom
+
+import (
+	"fmt"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/cfg"
+)
+
+// === [ Dominator tree ] ======================================================
+
+// Tree is a dominator tree or a post-dominator tree of a function.
+//
+// A basic block a dominates a basic block b if every path from the entry basic
+// block to b passes through a. A basic block a post-dominates b if every path
+// from b to an exit of the function passes through a. The exits of a function
+// are the basic blocks without successors (e.g. ret and unreachable
+// terminators); post-dominator trees are rooted at a virtual exit node, which
+// is the immediate post-dominator of every exit.
+//
+// Basic blocks unreachable from the entry basic block (or, for post-dominator
+// trees, from which no exit is reachable) are not part of the tree. As in LLVM,
+// every basic block dominates unreachable basic blocks.
+//
+// The tree is a snapshot of the function at the time of creation; it is not
+// updated as the function is modified.
+type Tree struct {
+	// Function of the tree.
+	Func *ir.Func
+	// Control flow graph of the function.
+	Graph *cfg.Graph
+	// Post-dominator tree.
+	post bool
+	// Basic blocks of the function, indexed by node ID.
+	blocks []*ir.Block
+	// Node ID of each basic block.
+	index map[*ir.Block]int
+	// Immediate dominator of each node; -1 for the root and unreachable nodes.
+	// The exits of post-dominator trees are immediately post-dominated by the
+	// virtual exit node.
+	idom []int
+	// Children of each node in the tree, in order of node ID; with an extra
+	// entry for the children of the virtual exit node.
+	children [][]int
+	// Depth-first numbering of the tree; -1 for unreachable nodes.
+	in, out []int
+	// Node ID of the root; the entry basic block for dominator trees, and the
+	// virtual exit node (len(blocks)) for post-dominator trees.
+	root int
+
+	// Lazily computed information.
+
+	// Dominance frontiers of each node.
+	frontiers [][]*ir.Block
+	// Positions of instructions.
+	pos map[ir.Instruction]position
+}
+
+// position is the position of an instruction within a function.
+type position struct {
+	// Node ID of parent basic block.
+	block int
+	// Index of the instruction within its parent basic block.
+	idx int
+}
+
+// New returns the dominator tree of the function of the given control flow
+// graph.
+func New(g *cfg.Graph) *Tree {
+	t := newTree(g, false)
+	if len(t.blocks) == 0 {
+		return t
+	}
+	n := len(t.blocks)
+	succs := make([][]int, n)
+	preds := make([][]int, n)
+	for i, block := range t.blocks {
+		succs[i] = t.ids(g.Succs(block))
+		preds[i] = t.ids(g.Preds(block))
+	}
+	t.compute(0, succs, preds)
+	return t
+}
+
+// NewPost returns the post-dominator tree of the function of the given control
+// flow graph.
+func NewPost(g *cfg.Graph) *Tree {
+	t := newTree(g, true)
+	// The control flow graph is reversed, and a virtual exit node is added with
+	// edges to every exit of the function.
+	n := len(t.blocks)
+	succs := make([][]int, n+1)
+	preds := make([][]int, n+1)
+	for i, block := range t.blocks {
+		succs[i] = t.ids(g.Preds(block))
+		preds[i] = t.ids(g.Succs(block))
+		if len(preds[i]) == 0 {
+			succs[n] = append(succs[n], i)
+			preds[i] = append(preds[i], n)
+		}
+	}
+	t.compute(n, succs, preds)
+	return t
+}
+
+// newTree returns a new empty tree of the function of the given control flow
+// graph.
+func newTree(g *cfg.Graph, post bool) *Tree {
+	f := g.Func
+	t := &Tree{
+		Func:   f,
+		Graph:  g,
+		post:   post,
+		blocks: f.Blocks,
+		index:  make(map[*ir.Block]int, len(f.Blocks)),
+	}
+	for i, block := range f.Blocks {
+		t.index[block] = i
+	}
+	return t
+}
+
+// IsPost reports whether t is a post-dominator tree.
+func (t *Tree) IsPost() bool {
+	return t.post
+}
+
+// Roots returns the roots of the tree, in order of Func.Blocks; the entry basic
+// block for dominator trees, and the exits of the function for post-dominator
+// trees.
+func (t *Tree) Roots() []*ir.Block {
+	switch {
+	case len(t.blocks) == 0:
+		return nil
+	case t.post:
+		return t.blocksOf(t.children[t.root])
+	default:
+		return []*ir.Block{t.blocks[t.root]}
+	}
+}
+
+// IDom returns the immediate dominator (or post-dominator) of the given basic
+// block; or nil if the basic block is a root of the tree or unreachable.
+func (t *Tree) IDom(block *ir.Block) *ir.Block {
+	idom := t.idom[t.id(block)]
+	if idom == -1 || idom >= len(t.blocks) {
+		return nil
+	}
+	return t.blocks[idom]
+}
+
+// Children returns the basic blocks immediately dominated (or post-dominated)
+// by the given basic block, in order of Func.Blocks.
+func (t *Tree) Children(block *ir.Block) []*ir.Block {
+	return t.blocksOf(t.children[t.id(block)])
+}
+
+// Reachable reports whether the given basic block is part of the tree; i.e.
+// reachable from the entry basic block for dominator trees, and able to reach
+// an exit for post-dominator trees.
+func (t *Tree) Reachable(block *ir.Block) bool {
+	return t.in[t.id(block)] != -1
+}
+
+// Dominates reports whether basic block a dominates (or post-dominates) basic
+// block b. Every basic block dominates itself and unreachable basic blocks, and
+// unreachable basic blocks dominate no reachable basic block.
+func (t *Tree) Dominates(a, b *ir.Block) bool {
+	ai, bi := t.id(a), t.id(b)
+	if t.in[bi] == -1 {
+		return true
+	}
+	if t.in[ai] == -1 {
+		return false
+	}
+	return t.in[ai] <= t.in[bi] && t.out[bi] <= t.out[ai]
+}
+
+// StrictlyDominates reports whether basic block a dominates (or
+// post-dominates) basic block b, and a is not b.
+func (t *Tree) StrictlyDominates(a, b *ir.Block) bool {
+	return a != b && t.Dominates(a, b)
+}
+
+// DominatesInst reports whether instruction a dominates (or post-dominates)
+// instruction b; i.e. whether the parent basic block of a dominates that of b
+// and, if in the same basic block, a precedes b (or succeeds b for
+// post-dominator trees). As in LLVM, an instruction does not dominate itself.
+//
+// Terminators are located at the end of their parent basic block, and are thus
+// dominated by every instruction of the basic block; use Dominates on the
+// parent basic blocks for queries involving terminators.
+func (t *Tree) DominatesInst(a, b ir.Instruction) bool {
+	if a == b {
+		return false
+	}
+	pa, pb := t.instPos(a), t.instPos(b)
+	if pa.block != pb.block {
+		return t.Dominates(t.blocks[pa.block], t.blocks[pb.block])
+	}
+	if t.in[pb.block] == -1 {
+		return true
+	}
+	if t.post {
+		return pa.idx > pb.idx
+	}
+	return pa.idx < pb.idx
+}
+
+// PreOrder returns the basic blocks of the tree in depth-first pre-order; every
+// basic block is visited before the basic blocks it dominates. Children are
+// visited in order of Func.Blocks.
+func (t *Tree) PreOrder() []*ir.Block {
+	if len(t.blocks) == 0 {
+		return nil
+	}
+	var blocks []*ir.Block
+	var stack []int
+	if t.post {
+		roots := t.children[t.root]
+		for i := len(roots) - 1; i >= 0; i-- {
+			stack = append(stack, roots[i])
+		}
+	} else {
+		stack = append(stack, t.root)
+	}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		blocks = append(blocks, t.blocks[n])
+		children := t.children[n]
+		for i := len(children) - 1; i >= 0; i-- {
+			stack = append(stack, children[i])
+		}
+	}
+	return blocks
+}
+
+// Frontier returns the dominance frontier (or post-dominance frontier) of the
+// given basic block, in order of Func.Blocks. The dominance frontier of a is
+// the set of basic blocks b such that a dominates a predecessor of b, but does
+// not strictly dominate b. The post-dominance frontier of a is the set of basic
+// blocks on which a is control dependent.
+func (t *Tree) Frontier(block *ir.Block) []*ir.Block {
+	if t.frontiers == nil {
+		t.computeFrontiers()
+	}
+	return t.frontiers[t.id(block)]
+}
+
+// --- [ Computation ] ---------------------------------------------------------
+
+// compute computes the immediate dominators and depth-first numbering of the
+// tree rooted at the given node, based on the given successors and
+// predecessors of each node.
+func (t *Tree) compute(root int, succs, preds [][]int) {
+	n := len(succs)
+	t.root = root
+	// Compute reverse post-order of nodes reachable from the root.
+	rpoNum := make([]int, n)
+	for i := range rpoNum {
+		rpoNum[i] = -1
+	}
+	order := reversePostOrder(root, succs)
+	for i, node := range order {
+		rpoNum[node] = i
+	}
+	// Compute immediate dominators.
+	idom := make([]int, n)
+	for i := range idom {
+		idom[i] = -1
+	}
+	idom[root] = root
+	intersect := func(a, b int) int {
+		for a != b {
+			for rpoNum[a] > rpoNum[b] {
+				a = idom[a]
+			}
+			for rpoNum[b] > rpoNum[a] {
+				b = idom[b]
+			}
+		}
+		return a
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, node := range order[1:] {
+			newIDom := -1
+			for _, pred := range preds[node] {
+				if idom[pred] == -1 {
+					// Predecessor not yet processed or unreachable.
+					continue
+				}
+				if newIDom == -1 {
+					newIDom = pred
+					continue
+				}
+				newIDom = intersect(pred, newIDom)
+			}
+			if idom[node] != newIDom {
+				idom[node] = newIDom
+				changed = true
+			}
+		}
+	}
+	idom[root] = -1
+	t.idom = idom
+	// Record children, with an extra entry for the children of the virtual
+	// root of post-dominator trees.
+	t.children = make([][]int, len(t.blocks)+1)
+	for node, parent := range idom {
+		if parent != -1 {
+			t.children[parent] = append(t.children[parent], node)
+		}
+	}
+	// Compute depth-first numbering of the tree.
+	t.in = make([]int, n)
+	t.out = make([]int, n)
+	for i := range t.in {
+		t.in[i] = -1
+		t.out[i] = -1
+	}
+	num := 0
+	type frame struct {
+		node int
+		next int // index of next child to visit
+	}
+	t.in[root] = num
+	num++
+	stack := []frame{{node: root}}
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		if children := t.children[top.node]; top.next < len(children) {
+			child := children[top.next]
+			top.next++
+			t.in[child] = num
+			num++
+			stack = append(stack, frame{node: child})
+			continue
+		}
+		t.out[top.node] = num
+		num++
+		stack = stack[:len(stack)-1]
+	}
+	// Drop the virtual exit node from the numbering of post-dominator trees.
+	t.in = t.in[:len(t.blocks)]
+	t.out = t.out[:len(t.blocks)]
+}
+
+// computeFrontiers computes the dominance frontiers of each node, using the
+// algorithm of Cooper, Harvey and Kennedy.
+func (t *Tree) computeFrontiers() {
+	sets := make([]map[int]bool, len(t.blocks))
+	for i := range sets {
+		sets[i] = make(map[int]bool)
+	}
+	for b, block := range t.blocks {
+		if t.in[b] == -1 {
+			continue
+		}
+		// Predecessors in the (possibly reversed) control flow graph.
+		var preds []*ir.Block
+		if t.post {
+			preds = t.Graph.Succs(block)
+		} else {
+			preds = t.Graph.Preds(block)
+		}
+		if len(preds) < 2 {
+			continue
+		}
+		for _, pred := range preds {
+			runner := t.index[pred]
+			if t.in[runner] == -1 {
+				continue
+			}
+			for runner != t.idom[b] && runner != -1 && runner < len(t.blocks) {
+				sets[runner][b] = true
+				runner = t.idom[runner]
+			}
+		}
+	}
+	t.frontiers = make([][]*ir.Block, len(t.blocks))
+	for i, set := range sets {
+		for b := range t.blocks {
+			if set[b] {
+				t.frontiers[i] = append(t.frontiers[i], t.blocks[b])
+			}
+		}
+	}
+}
+
+// instPos returns the position of the given instruction, panicking if not part
+// of the function.
+func (t *Tree) instPos(inst ir.Instruction) position {
+	if t.pos == nil {
+		t.pos = make(map[ir.Instruction]position)
+		for b, block := range t.blocks {
+			for i, inst := range block.Insts {
+				t.pos[inst] = position{block: b, idx: i}
+			}
+		}
+	}
+	pos, ok := t.pos[inst]
+	if !ok {
+		panic(fmt.Errorf("instruction %v not part of function %s", inst, t.Func.Ident()))
+	}
+	return pos
+}
+
+// id returns the node ID of the given basic block, panicking if not part of the
+// function.
+func (t *Tree) id(block *ir.Block) int {
+	i, ok := t.index[block]
+	if !ok {
+		panic(fmt.Errorf("basic block %s not part of function %s", block.Ident(), t.Func.Ident()))
+	}
+	return i
+}
+
+// ids returns the node IDs of the given basic blocks.
+func (t *Tree) ids(blocks []*ir.Block) []int {
+	ids := make([]int, len(blocks))
+	for i, block := range blocks {
+		ids[i] = t.index[block]
+	}
+	return ids
+}
+
+// blocksOf returns the basic blocks of the given node IDs.
+func (t *Tree) blocksOf(ids []int) []*ir.Block {
+	var blocks []*ir.Block
+	for _, id := range ids {
+		blocks = append(blocks, t.blocks[id])
+	}
+	return blocks
+}
+
+// ### [ Helper functions ] ####################################################
+
+// reversePostOrder returns the nodes reachable from the given root in reverse
+// depth-first post-order.
+func reversePostOrder(root int, succs [][]int) []int {
+	visited := make([]bool, len(succs))
+	var post []int
+	type frame struct {
+		node int
+		next int // index of next successor to visit
+	}
+	visited[root] = true
+	stack := []frame{{node: root}}
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		if top.next < len(succs[top.node]) {
+			succ := succs[top.node][top.next]
+			top.next++
+			if !visited[succ] {
+				visited[succ] = true
+				stack = append(stack, frame{node: succ})
+			}
+			continue
+		}
+		post = append(post, top.node)
+		stack = stack[:len(stack)-1]
+	}
+	for i, j := 0, len(post)-1; i < j; i, j = i+1, j-1 {
+		post[i], post[j] = post[j], post[i]
+	}
+	return post
+}
@@ -0,0 +1,162 @@
+package dom_test
+
+import (
+	"testing"
+
+	"github.com/llir/llvm/asm"
+	"github.com/llir/llvm/internal/irtest"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/cfg"
+	"github.com/llir/llvm/ir/dom"
+)
+
+func TestTree(t *testing.T) {
+	m, err := asm.ParseFile("testdata/dom.ll")
+	if err != nil {
+		t.Fatalf("unable to parse module; %+v", err)
+	}
+	f := m.Funcs[0]
+	g := cfg.New(f)
+	blocks := make(map[string]*ir.Block)
+	for _, block := range f.Blocks {
+		blocks[block.Name()] = block
+	}
+	golden := []struct {
+		tree *dom.Tree
+		// Roots and pre-order traversal of tree.
+		roots, preOrder string
+		// Immediate dominator and dominance frontier of each basic block.
+		idoms, frontiers map[string]string
+	}{
+		// Dominator tree.
+		{
+			tree:     dom.New(g),
+			roots:    "[%entry]",
+			preOrder: "[%entry %a %b %d %inf %c %e %exit]",
+			idoms: map[string]string{
+				"entry": "<nil>",
+				"a":     "%entry",
+				"b":     "%entry",
+				"c":     "%entry",
+				"e":     "%c",
+				"d":     "%b",
+				"inf":   "%d",
+				"dead":  "<nil>",
+				"exit":  "%entry",
+			},
+			frontiers: map[string]string{
+				"entry": "[]",
+				"a":     "[%c]",
+				"b":     "[%c %exit]",
+				"c":     "[%c %exit]",
+				"e":     "[%c %exit]",
+				"d":     "[%exit]",
+				"inf":   "[%inf]",
+				"dead":  "[]",
+				"exit":  "[]",
+			},
+		},
+		// Post-dominator tree.
+		{
+			tree:     dom.NewPost(g),
+			roots:    "[%exit]",
+			preOrder: "[%exit %entry %b %e %c %a %d %dead]",
+			idoms: map[string]string{
+				"entry": "%exit",
+				"a":     "%c",
+				"b":     "%exit",
+				"c":     "%e",
+				"e":     "%exit",
+				"d":     "%exit",
+				"inf":   "<nil>",
+				"dead":  "%exit",
+				"exit":  "<nil>",
+			},
+			frontiers: map[string]string{
+				"entry": "[]",
+				"a":     "[%entry]",
+				"b":     "[%entry]",
+				"c":     "[%entry %b %e]",
+				"e":     "[%entry %b %e]",
+				"d":     "[%b]",
+				"inf":   "[]",
+				"dead":  "[]",
+				"exit":  "[]",
+			},
+		},
+	}
+	for _, gold := range golden {
+		tree := gold.tree
+		if got := irtest.Idents(tree.Roots()); got != gold.roots {
+			t.Errorf("post=%v: roots mismatch; expected %s, got %s", tree.IsPost(), gold.roots, got)
+		}
+		if got := irtest.Idents(tree.PreOrder()); got != gold.preOrder {
+			t.Errorf("post=%v: pre-order mismatch; expected %s, got %s", tree.IsPost(), gold.preOrder, got)
+		}
+		for name, want := range gold.idoms {
+			got := "<nil>"
+			if idom := tree.IDom(blocks[name]); idom != nil {
+				got = idom.Ident()
+			}
+			if got != want {
+				t.Errorf("post=%v: immediate dominator of %%%s mismatch; expected %s, got %s", tree.IsPost(), name, want, got)
+			}
+		}
+		for name, want := range gold.frontiers {
+			if got := irtest.Idents(tree.Frontier(blocks[name])); got != want {
+				t.Errorf("post=%v: dominance frontier of %%%s mismatch; expected %s, got %s", tree.IsPost(), name, want, got)
+			}
+		}
+	}
+}
+
+func TestDominates(t *testing.T) {
+	m, err := asm.ParseFile("testdata/dom.ll")
+	if err != nil {
+		t.Fatalf("unable to parse module; %+v", err)
+	}
+	f := m.Funcs[0]
+	blocks := make(map[string]*ir.Block)
+	for _, block := range f.Blocks {
+		blocks[block.Name()] = block
+	}
+	g := cfg.New(f)
+	domTree, postTree := dom.New(g), dom.NewPost(g)
+	golden := []struct {
+		tree *dom.Tree
+		a, b string
+		want bool
+	}{
+		{tree: domTree, a: "entry", b: "exit", want: true},
+		{tree: domTree, a: "c", b: "e", want: true},
+		{tree: domTree, a: "e", b: "c", want: false},
+		{tree: domTree, a: "b", b: "c", want: false},
+		{tree: domTree, a: "c", b: "c", want: true},
+		// Unreachable basic blocks are dominated by every basic block.
+		{tree: domTree, a: "a", b: "dead", want: true},
+		{tree: domTree, a: "dead", b: "exit", want: false},
+		{tree: postTree, a: "exit", b: "entry", want: true},
+		{tree: postTree, a: "e", b: "a", want: true},
+		{tree: postTree, a: "c", b: "b", want: false},
+		{tree: postTree, a: "exit", b: "inf", want: true},
+	}
+	for _, gold := range golden {
+		got := gold.tree.Dominates(blocks[gold.a], blocks[gold.b])
+		if got != gold.want {
+			t.Errorf("post=%v: dominance of %%%s over %%%s mismatch; expected %v, got %v", gold.tree.IsPost(), gold.a, gold.b, gold.want, got)
+		}
+	}
+	if domTree.StrictlyDominates(blocks["c"], blocks["c"]) {
+		t.Errorf("expected %%c not to strictly dominate itself")
+	}
+
+	// Dominance of instructions.
+	c := blocks["c"]
+	x, y := c.Insts[0], c.Insts[1]
+	if !domTree.DominatesInst(x, y) || domTree.DominatesInst(y, x) || domTree.DominatesInst(x, x) {
+		t.Errorf("dominance mismatch of instructions in basic block %%c")
+	}
+	if !postTree.DominatesInst(y, x) || postTree.DominatesInst(x, y) {
+		t.Errorf("post-dominance mismatch of instructions in basic block %%c")
+	}
+}
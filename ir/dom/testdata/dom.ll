@@ -0,0 +1,30 @@
+define void @f(i1 %cond) {
+entry:
+	br i1 %cond, label %a, label %b
+
+a:
+	br label %c
+
+b:
+	br i1 %cond, label %c, label %d
+
+c:
+	%x = add i32 1, 2
+	%y = add i32 %x, 3
+	br label %e
+
+e:
+	br i1 %cond, label %c, label %exit
+
+d:
+	br i1 %cond, label %exit, label %inf
+
+inf:
+	br label %inf
+
+dead:
+	br label %exit
+
+exit:
+	ret void
+}
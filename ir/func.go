@@ -2,6 +2,7 @@ package ir
 
 import (
 	"fmt"
+	"io"
 	"strings"
 	"sync"
 
@@ -76,6 +77,16 @@ type Func struct {
 
 	// mu prevents races on AssignIDs.
 	mu sync.Mutex
+	// renumber specifies whether the unnamed local variables of the function
+	// are renumbered by the next call to AssignIDs; set when instructions or
+	// basic blocks are inserted or removed.
+	renumber bool
+	// useIndex is the use index kept consistent with modifications of the
+	// function body; nil if not present.
+	useIndex *UseIndex
+	// materialize translates the body of a lazily parsed function definition;
+	// nil if the body of the function is materialized.
+	materialize func() error
 }
 
 // NewFunc returns a new function based on the given function name, return type
@@ -112,6 +123,17 @@ func (f *Func) Type() types.Type {
 // LLString returns the LLVM syntax representation of the function definition or
 // declaration.
 func (f *Func) LLString() string {
+	buf := &strings.Builder{}
+	if _, err := f.WriteTo(buf); err != nil {
+		panic(fmt.Errorf("unable to write to string buffer; %v", err))
+	}
+	return buf.String()
+}
+
+// WriteTo writes the LLVM syntax representation of the function definition or
+// declaration to w. The body of function definitions is written one
+// instruction at a time, without building its string representation.
+func (f *Func) WriteTo(w io.Writer) (n int64, err error) {
 	// Function declaration.
 	//
 	//	'declare' Metadata=MetadataAttachment* Header=FuncHeader
@@ -122,37 +144,44 @@ func (f *Func) LLString() string {
 	if err := f.AssignIDs(); err != nil {
 		panic(fmt.Errorf("unable to assign IDs of function %q; %v", f.Ident(), err))
 	}
-	buf := &strings.Builder{}
+	fw := &fmtWriter{w: w}
 	if len(f.Blocks) == 0 {
 		// Function declaration.
-		buf.WriteString("declare")
+		fw.Fprint("declare")
 		for _, md := range f.Metadata {
-			fmt.Fprintf(buf, " %s", md)
-		}
-		if f.Linkage != enum.LinkageNone {
-			fmt.Fprintf(buf, " %s", f.Linkage)
+			fw.Fprintf(" %s", md)
 		}
-		buf.WriteString(headerString(f))
-		return buf.String()
-	} else {
-		// Function definition.
-		buf.WriteString("define")
 		if f.Linkage != enum.LinkageNone {
-			fmt.Fprintf(buf, " %s", f.Linkage)
+			fw.Fprintf(" %s", f.Linkage)
 		}
-		buf.WriteString(headerString(f))
-		for _, md := range f.Metadata {
-			fmt.Fprintf(buf, " %s", md)
-		}
-		fmt.Fprintf(buf, " %s", bodyString(f))
-		return buf.String()
+		fw.Fprint(headerString(f))
+		return fw.size, fw.err
+	}
+	// Function definition.
+	fw.Fprint("define")
+	if f.Linkage != enum.LinkageNone {
+		fw.Fprintf(" %s", f.Linkage)
+	}
+	fw.Fprint(headerString(f))
+	for _, md := range f.Metadata {
+		fw.Fprintf(" %s", md)
 	}
+	fw.Fprint(" ")
+	writeBody(fw, f)
+	return fw.size, fw.err
 }
 
 // AssignIDs assigns IDs to unnamed local variables.
 func (f *Func) AssignIDs() error {
+	if err := f.Materialize(); err != nil {
+		return errors.WithStack(err)
+	}
 	f.mu.Lock()
 	defer f.mu.Unlock()
+	if f.renumber {
+		resetLocalIDs(f)
+		f.renumber = false
+	}
 	id := int64(0)
 	setName := func(n namedVar) error {
 		if n.IsUnnamed() {
@@ -206,8 +235,70 @@ func (f *Func) AssignIDs() error {
 	return nil
 }
 
+// ResetIDs marks the unnamed local variables of the function for renumbering by
+// the next call to AssignIDs; e.g. after instructions or basic blocks have been
+// inserted or removed by direct modification of Func.Blocks or Block.Insts,
+// after which the local IDs of unnamed local variables are no longer
+// sequential.
+//
+// Functions modified using the IR modification APIs of the ir package (e.g.
+// Block.InsertBefore, Block.Remove and Func.SplitBlock) are marked for
+// renumbering automatically.
+func (f *Func) ResetIDs() {
+	f.renumber = true
+}
+
+// --- [ Lazy materialization ] -----------------------------------------------
+
+// Materialize materializes the body of the function, if lazily parsed (e.g.
+// using the Lazy option of asm.Config). Materialize is a no-op for functions
+// already materialized, and for function declarations.
+//
+// The bodies of lazily parsed functions are materialized on first use by
+// LLString and AssignIDs, and by the APIs operating on function bodies (e.g.
+// Clone, ReplaceAllUsesWith, NewFuncUseIndex, cfg.New and the transforms of
+// the transform package), which panic if materialization fails. Materialize
+// must be invoked before accessing the basic blocks of lazily parsed functions
+// directly.
+//
+// Materialize is not safe for concurrent use.
+func (f *Func) Materialize() error {
+	materialize := f.materialize
+	if materialize == nil {
+		return nil
+	}
+	// Clear materializer before invocation, as materialization may use the
+	// function (e.g. AssignIDs).
+	f.materialize = nil
+	if err := materialize(); err != nil {
+		f.materialize = materialize
+		return err
+	}
+	return nil
+}
+
+// IsMaterialized reports whether the body of the function is materialized.
+func (f *Func) IsMaterialized() bool {
+	return f.materialize == nil
+}
+
+// SetMaterializer sets the function used to materialize the body of the lazily
+// parsed function definition on first use. The materializer is invoked at most
+// once, unless it returns an error.
+func (f *Func) SetMaterializer(materialize func() error) {
+	f.materialize = materialize
+}
+
 // ### [ Helper functions ] ####################################################
 
+// mustMaterialize materializes the body of the given function, if lazily
+// parsed, and panics on error.
+func mustMaterialize(f *Func) {
+	if err := f.Materialize(); err != nil {
+		panic(fmt.Errorf("unable to materialize function %q; %v", f.Ident(), err))
+	}
+}
+
 // headerString returns the string representation of the function header.
 func headerString(f *Func) string {
 	// (Linkage | ExternLinkage)? Preemptionopt Visibilityopt DLLStorageClassopt CallingConvopt ReturnAttrs=ReturnAttribute* RetType=Type Name=GlobalIdent '(' Params ')' UnnamedAddropt AddrSpaceopt FuncAttrs=FuncAttribute* Sectionopt Partitionopt Comdatopt Alignopt GCopt Prefixopt Prologueopt Personalityopt
@@ -282,23 +373,22 @@ func headerString(f *Func) string {
 	return buf.String()
 }
 
-// bodyString returns the string representation of the function body.
-func bodyString(body *Func) string {
+// writeBody writes the LLVM syntax representation of the function body to fw.
+func writeBody(fw *fmtWriter, body *Func) {
 	// '{' Blocks=Block+ UseListOrders=UseListOrder* '}'
-	buf := &strings.Builder{}
-	buf.WriteString("{\n")
+	fw.Fprint("{\n")
 	for i, block := range body.Blocks {
 		if i != 0 {
-			buf.WriteString("\n")
+			fw.Fprint("\n")
 		}
-		fmt.Fprintf(buf, "%s\n", block.LLString())
+		fw.writeFrom(block)
+		fw.Fprint("\n")
 	}
 	if len(body.UseListOrders) > 0 {
-		buf.WriteString("\n")
+		fw.Fprint("\n")
 	}
 	for _, u := range body.UseListOrders {
-		fmt.Fprintf(buf, "\t%s\n", u)
+		fw.Fprintf("\t%s\n", u)
 	}
-	buf.WriteString("}")
-	return buf.String()
+	fw.Fprint("}")
 }
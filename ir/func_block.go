@@ -1,5 +1,7 @@
 package ir
 
+import "fmt"
+
 // NewBlock appends a new basic block to the function based on the given label
 // name. An empty label name indicates an unnamed basic block.
 //
@@ -10,3 +12,172 @@ func (f *Func) NewBlock(name string) *Block {
 	f.Blocks = append(f.Blocks, block)
 	return block
 }
+
+// RemoveBlock removes the given basic block from the function. Incoming values
+// of phi instructions in successor basic blocks of the removed block are
+// removed, and the Parent field of the block is cleared.
+//
+// Branches to the removed block from other basic blocks are not updated; they
+// should be redirected or removed by the caller. The use index registered on
+// the function, if any, is updated.
+//
+// Unnamed local variables of the function are renumbered by the next call to
+// f.AssignIDs.
+func (f *Func) RemoveBlock(block *Block) {
+	pos := indexOfBlock(f.Blocks, block)
+	if pos == -1 {
+		panic(fmt.Errorf("unable to remove basic block %s; not present in function %s", block.Ident(), f.Ident()))
+	}
+	copy(f.Blocks[pos:], f.Blocks[pos+1:])
+	// Clear tail to allow garbage collection of the removed basic block.
+	f.Blocks[len(f.Blocks)-1] = nil
+	f.Blocks = f.Blocks[:len(f.Blocks)-1]
+	for _, succ := range succsOf(block) {
+		if succ == block {
+			continue
+		}
+		for _, inst := range succ.Insts {
+			phi, ok := inst.(*InstPhi)
+			if !ok {
+				continue
+			}
+			incs := phi.Incs[:0]
+			for _, inc := range phi.Incs {
+				if inc.Pred != block {
+					incs = append(incs, inc)
+				}
+			}
+			phi.Incs = incs
+			if f.useIndex != nil {
+				f.useIndex.UpdateUser(phi)
+			}
+		}
+	}
+	if f.useIndex != nil {
+		f.useIndex.RemoveBlock(block)
+	}
+	block.Parent = nil
+	f.renumber = true
+}
+
+// SplitBlock splits the basic block containing the given instruction in two.
+// The instruction, the instructions following it and the terminator are moved
+// to a new unnamed basic block, which is inserted directly after the original
+// block in the function. The original block is terminated by an unconditional
+// branch to the new block, using the debug location of the given instruction.
+// Incoming values of phi instructions in successor basic blocks are updated to
+// refer to the new block, as is the use index registered on the function, if
+// any. The new basic block is returned.
+//
+// Unnamed local variables of the function are renumbered by the next call to
+// f.AssignIDs.
+func (f *Func) SplitBlock(at Instruction) *Block {
+	if _, ok := at.(*InstPhi); ok {
+		panic(fmt.Errorf("unable to split basic block at phi instruction %q", at.LLString()))
+	}
+	var block *Block
+	var pos int
+	for _, b := range f.Blocks {
+		if pos = indexOfInst(b.Insts, at); pos != -1 {
+			block = b
+			break
+		}
+	}
+	if block == nil {
+		panic(fmt.Errorf("unable to split basic block at instruction %q; not present in function %s", at.LLString(), f.Ident()))
+	}
+	split := NewBlock("")
+	split.Parent = f
+	split.Insts = append([]Instruction(nil), block.Insts[pos:]...)
+	split.Term = block.Term
+	// Clear tail to allow garbage collection of the moved instructions.
+	for i := pos; i < len(block.Insts); i++ {
+		block.Insts[i] = nil
+	}
+	block.Insts = block.Insts[:pos]
+	br := NewBr(split)
+	if md, ok := at.(mdAttacher); ok {
+		for _, attachment := range md.MDAttachments() {
+			if attachment.Name == "dbg" {
+				br.Metadata = append(br.Metadata, attachment)
+			}
+		}
+	}
+	block.Term = br
+	blockPos := indexOfBlock(f.Blocks, block)
+	f.Blocks = append(f.Blocks, nil)
+	copy(f.Blocks[blockPos+2:], f.Blocks[blockPos+1:])
+	f.Blocks[blockPos+1] = split
+	for _, succ := range succsOf(split) {
+		for _, inst := range succ.Insts {
+			phi, ok := inst.(*InstPhi)
+			if !ok {
+				continue
+			}
+			updated := false
+			for _, inc := range phi.Incs {
+				if inc.Pred == block {
+					inc.Pred = split
+					updated = true
+				}
+			}
+			if updated && f.useIndex != nil {
+				f.useIndex.UpdateUser(phi)
+			}
+		}
+	}
+	if f.useIndex != nil {
+		f.useIndex.AddUser(br)
+	}
+	f.renumber = true
+	return split
+}
+
+// ### [ Helper functions ] ####################################################
+
+// indexOfBlock returns the index of the given basic block in blocks; or -1 if
+// not present.
+func indexOfBlock(blocks []*Block, block *Block) int {
+	for i, b := range blocks {
+		if b == block {
+			return i
+		}
+	}
+	return -1
+}
+
+// succsOf returns the unique successor basic blocks of the given basic block.
+// The successors are computed from the operands of the terminator, as cached
+// successors of terminators may be stale.
+func succsOf(block *Block) []*Block {
+	if block.Term == nil {
+		return nil
+	}
+	var succs []*Block
+	for _, op := range block.Term.Operands() {
+		if succ, ok := (*op).(*Block); ok && indexOfBlock(succs, succ) == -1 {
+			succs = append(succs, succ)
+		}
+	}
+	return succs
+}
+
+// resetLocalIDs resets the IDs of the unnamed local variables of the given
+// function, so that they are renumbered sequentially by f.AssignIDs.
+func resetLocalIDs(f *Func) {
+	reset := func(v interface{}) {
+		if n, ok := v.(namedVar); ok && n.IsUnnamed() {
+			n.SetID(0)
+		}
+	}
+	for _, param := range f.Params {
+		reset(param)
+	}
+	for _, block := range f.Blocks {
+		reset(block)
+		for _, inst := range block.Insts {
+			reset(inst)
+		}
+		reset(block.Term)
+	}
+}
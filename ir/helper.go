@@ -381,6 +381,11 @@ func (mds Metadata) MDAttachments() []*metadata.Attachment {
 	return mds
 }
 
+// SetMDAttachments sets the metadata attachments of the value.
+func (mds *Metadata) SetMDAttachments(attachments []*metadata.Attachment) {
+	*mds = attachments
+}
+
 // OperandBundle is a tagged set of SSA values associated with a call-site.
 type OperandBundle struct {
 	Tag    string
@@ -515,6 +520,66 @@ func callingConvString(callingConv enum.CallingConv) string {
 	return s
 }
 
+// calleeSig returns the function signature of the given callee. The explicit
+// signature sig takes precedence if non-nil. Otherwise, the signature is
+// determined by the element type of the callee pointer type, or for callees of
+// opaque pointer type, by the signature of the function callee.
+func calleeSig(callee value.Value, sig *types.FuncType) *types.FuncType {
+	if sig != nil {
+		return sig
+	}
+	t, ok := callee.Type().(*types.PointerType)
+	if !ok {
+		panic(fmt.Errorf("invalid callee type; expected *types.PointerType, got %T", callee.Type()))
+	}
+	if !t.IsOpaque() {
+		sig, ok := t.ElemType.(*types.FuncType)
+		if !ok {
+			panic(fmt.Errorf("invalid callee type; expected *types.FuncType, got %T", t.ElemType))
+		}
+		return sig
+	}
+	if f, ok := callee.(*Func); ok {
+		return f.Sig
+	}
+	panic(fmt.Errorf("unable to determine function signature of callee %q of opaque pointer type; explicit function signature required", callee.Ident()))
+}
+
+// valueContentType returns the content type of the given global value; or nil
+// if unknown.
+func valueContentType(v value.Value) types.Type {
+	switch v := v.(type) {
+	case *Global:
+		return v.ContentType
+	case *Func:
+		return v.Sig
+	case *Alias:
+		if v.ContentType != nil {
+			return v.ContentType
+		}
+		return v.Typ.ElemType
+	case *IFunc:
+		if v.ContentType != nil {
+			return v.ContentType
+		}
+		return v.Typ.ElemType
+	}
+	return nil
+}
+
+// indirectSymbolContentType returns the content type of the indirect symbol
+// (alias or IFunc) with the given identifier, explicit content type and
+// pointer type.
+func indirectSymbolContentType(ident string, contentType types.Type, typ *types.PointerType) types.Type {
+	if contentType != nil {
+		return contentType
+	}
+	if typ.IsOpaque() {
+		panic(fmt.Errorf("missing content type of indirect symbol %q with opaque pointer type", ident))
+	}
+	return typ.ElemType
+}
+
 // tlsModelString returns the string representation of the given thread local
 // storage model.
 func tlsModelString(model enum.TLSModel) string {
@@ -580,6 +645,34 @@ func (fw *fmtWriter) Fprintln(a ...interface{}) (n int, err error) {
 	return n, err
 }
 
+// writeFrom writes the contents of r to w.
+func (fw *fmtWriter) writeFrom(r io.WriterTo) (n int64, err error) {
+	if fw.err != nil {
+		// early return if a previous error has been encountered.
+		return 0, nil
+	}
+	n, err = r.WriteTo(fw.w)
+	fw.size += n
+	fw.err = err
+	return n, err
+}
+
+// countWriter is an I/O writer which keeps track of the total number of bytes
+// written to w.
+type countWriter struct {
+	// underlying io.Writer.
+	w io.Writer
+	// Number of bytes written to w.
+	n int64
+}
+
+// Write writes p to w.
+func (cw *countWriter) Write(p []byte) (n int, err error) {
+	n, err = cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
 // namedVar is a named variable.
 type namedVar interface {
 	value.Named
@@ -20,6 +20,9 @@ type IFunc struct {
 
 	// Pointer type of resolver.
 	Typ *types.PointerType
+	// (optional) Content type of IFunc; or nil to use the element type of Typ.
+	// Required if Typ is an opaque pointer type.
+	ContentType types.Type
 	// (optional) Linkage; zero value if not present.
 	Linkage enum.Linkage
 	// (optional) Preemption; zero value if not present.
@@ -89,7 +92,7 @@ func (i *IFunc) LLString() string {
 		fmt.Fprintf(buf, " %s", i.UnnamedAddr)
 	}
 	buf.WriteString(" ifunc")
-	fmt.Fprintf(buf, " %s, %s", i.Typ.ElemType, i.Resolver)
+	fmt.Fprintf(buf, " %s, %s", indirectSymbolContentType(i.Ident(), i.ContentType, i.Typ), i.Resolver)
 	if len(i.Partition) > 0 {
 		fmt.Fprintf(buf, ", partition %s", quote(i.Partition))
 	}
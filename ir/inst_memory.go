@@ -218,7 +218,7 @@ func NewStore(src, dst value.Value) *InstStore {
 	if !ok {
 		panic(fmt.Errorf("invalid store dst operand type; expected *types.Pointer, got %T", dst.Type()))
 	}
-	if !src.Type().Equal(dstPtrType.ElemType) {
+	if !dstPtrType.IsOpaque() && !src.Type().Equal(dstPtrType.ElemType) {
 		panic(fmt.Errorf("store operands are not compatible: src=%v; dst=%v", src.Type(), dst.Type()))
 	}
 	return &InstStore{Src: src, Dst: dst}
@@ -437,11 +437,9 @@ func (inst *InstAtomicRMW) String() string {
 func (inst *InstAtomicRMW) Type() types.Type {
 	// Cache type if not present.
 	if inst.Typ == nil {
-		t, ok := inst.Dst.Type().(*types.PointerType)
-		if !ok {
-			panic(fmt.Errorf("invalid destination type; expected *types.PointerType, got %T", inst.Dst.Type()))
-		}
-		inst.Typ = t.ElemType
+		// The result type of atomicrmw is the type of the operand, which is
+		// also the type stored at the destination address.
+		inst.Typ = inst.X.Type()
 	}
 	return inst.Typ
 }
@@ -19,6 +19,8 @@ func TestTypeCheckStore(t *testing.T) {
 
 		{types.I64, types.I8Ptr,
 			"store operands are not compatible: src=i64; dst=i8*"},
+		{types.I64, types.Ptr,
+			"OK"},
 		{types.I8, types.I8,
 			"invalid store dst operand type; expected *types.Pointer, got *types.IntType"},
 	}
@@ -401,6 +401,10 @@ type InstCall struct {
 
 	// Type of result produced by the instruction.
 	Typ types.Type
+	// Function signature of the callee; or nil to derive the signature from the
+	// callee. Required for callees of opaque pointer type which are not
+	// functions.
+	CalleeSig *types.FuncType
 	// (optional) Tail; zero if not present.
 	Tail enum.Tail
 	// (optional) Fast math flags.
@@ -430,6 +434,16 @@ func NewCall(callee value.Value, args ...value.Value) *InstCall {
 	return inst
 }
 
+// NewCallSig returns a new call instruction based on the given function
+// signature, callee and function arguments. The explicit function signature is
+// required for callees of opaque pointer type which are not functions.
+func NewCallSig(sig *types.FuncType, callee value.Value, args ...value.Value) *InstCall {
+	inst := &InstCall{Callee: callee, Args: args, CalleeSig: sig}
+	// Compute type.
+	inst.Type()
+	return inst
+}
+
 // String returns the LLVM syntax representation of the instruction as a
 // type-value pair.
 func (inst *InstCall) String() string {
@@ -514,15 +528,7 @@ func (inst *InstCall) Operands() []*value.Value {
 
 // Sig returns the function signature of the callee.
 func (inst *InstCall) Sig() *types.FuncType {
-	t, ok := inst.Callee.Type().(*types.PointerType)
-	if !ok {
-		panic(fmt.Errorf("invalid callee type; expected *types.PointerType, got %T", inst.Callee.Type()))
-	}
-	sig, ok := t.ElemType.(*types.FuncType)
-	if !ok {
-		panic(fmt.Errorf("invalid callee type; expected *types.FuncType, got %T", t.ElemType))
-	}
-	return sig
+	return calleeSig(inst.Callee, inst.CalleeSig)
 }
 
 // ~~~ [ va_arg ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
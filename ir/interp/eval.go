@@ -0,0 +1,672 @@
+package interp
+
+import (
+	"math/big"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+	"github.com/pkg/errors"
+)
+
+// === [ Values ] ==============================================================
+
+// eval returns the value of the given operand in the given stack frame. A nil
+// stack frame is used to evaluate operands of constant expressions.
+func (in *Interpreter) eval(fr *frame, v value.Value) (constant.Constant, error) {
+	if fr != nil {
+		if x, ok := fr.locals[v]; ok {
+			return x, nil
+		}
+	}
+	c, ok := v.(constant.Constant)
+	if !ok {
+		return nil, errors.Errorf("use of value %s before definition", v.Ident())
+	}
+	return in.evalConst(c)
+}
+
+// evalConst returns the value of the given constant. Addresses of global
+// variables and functions are evaluated to pointer values, undefined values
+// to zero and constant expressions to the value of the expression.
+func (in *Interpreter) evalConst(c constant.Constant) (constant.Constant, error) {
+	switch c := c.(type) {
+	case *ir.Global, *ir.Func:
+		return NewPointer(c.Type().(*types.PointerType), in.addrs[c]), nil
+	case *ir.Alias:
+		x, err := in.evalConst(c.Aliasee)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		addr, ok := AddrOf(x)
+		if !ok {
+			return nil, errors.Errorf("invalid aliasee %q of alias %s", x, c.Ident())
+		}
+		return NewPointer(c.Type().(*types.PointerType), addr), nil
+	case *Pointer, *constant.Int, *constant.Float, *constant.Null, *constant.Poison, *constant.CharArray:
+		return c, nil
+	case *constant.Undef, *constant.ZeroInitializer:
+		// Undefined values are given the value zero.
+		return zeroOf(c.Type()), nil
+	case *constant.Array:
+		elems, err := in.evalConsts(c.Elems)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return constant.NewArray(c.Typ, elems...), nil
+	case *constant.Struct:
+		fields, err := in.evalConsts(c.Fields)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return constant.NewStruct(c.Typ, fields...), nil
+	case *constant.Vector:
+		elems, err := in.evalConsts(c.Elems)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return constant.NewVector(c.Typ, elems...), nil
+	case *constant.Index:
+		return in.evalConst(c.Constant)
+	case constant.Expression:
+		x, err := in.evalExpr(c)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to evaluate constant expression %q", c.Ident())
+		}
+		return x, nil
+	default:
+		return nil, errors.Errorf("support for constant %T not yet implemented", c)
+	}
+}
+
+// evalConsts returns the values of the given constants.
+func (in *Interpreter) evalConsts(cs []constant.Constant) ([]constant.Constant, error) {
+	xs := make([]constant.Constant, len(cs))
+	for i, c := range cs {
+		x, err := in.evalConst(c)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		xs[i] = x
+	}
+	return xs, nil
+}
+
+// evalExpr returns the value of the given constant expression.
+func (in *Interpreter) evalExpr(expr constant.Expression) (constant.Constant, error) {
+	switch expr := expr.(type) {
+	// Unary expressions.
+	case *constant.ExprFNeg:
+		return in.unary(nil, expr.X, constant.FoldFNeg)
+	// Binary expressions.
+	case *constant.ExprAdd:
+		return in.binary(nil, expr.X, expr.Y, func(x, y constant.Constant) constant.Constant {
+			return constant.FoldAdd(x, y, expr.OverflowFlags)
+		})
+	case *constant.ExprSub:
+		return in.binary(nil, expr.X, expr.Y, func(x, y constant.Constant) constant.Constant {
+			return constant.FoldSub(x, y, expr.OverflowFlags)
+		})
+	case *constant.ExprMul:
+		return in.binary(nil, expr.X, expr.Y, func(x, y constant.Constant) constant.Constant {
+			return constant.FoldMul(x, y, expr.OverflowFlags)
+		})
+	// Bitwise expressions.
+	case *constant.ExprShl:
+		return in.binary(nil, expr.X, expr.Y, func(x, y constant.Constant) constant.Constant {
+			return constant.FoldShl(x, y, expr.OverflowFlags)
+		})
+	case *constant.ExprLShr:
+		return in.binary(nil, expr.X, expr.Y, func(x, y constant.Constant) constant.Constant {
+			return constant.FoldLShr(x, y, expr.Exact)
+		})
+	case *constant.ExprAShr:
+		return in.binary(nil, expr.X, expr.Y, func(x, y constant.Constant) constant.Constant {
+			return constant.FoldAShr(x, y, expr.Exact)
+		})
+	case *constant.ExprAnd:
+		return in.binary(nil, expr.X, expr.Y, constant.FoldAnd)
+	case *constant.ExprOr:
+		return in.binary(nil, expr.X, expr.Y, constant.FoldOr)
+	case *constant.ExprXor:
+		return in.binary(nil, expr.X, expr.Y, constant.FoldXor)
+	// Vector expressions.
+	case *constant.ExprExtractElement:
+		return in.binary(nil, expr.X, expr.Index, constant.FoldExtractElement)
+	case *constant.ExprInsertElement:
+		return in.ternary(nil, expr.X, expr.Elem, expr.Index, constant.FoldInsertElement)
+	case *constant.ExprShuffleVector:
+		return in.ternary(nil, expr.X, expr.Y, expr.Mask, constant.FoldShuffleVector)
+	// Memory expressions.
+	case *constant.ExprGetElementPtr:
+		indices := make([]value.Value, len(expr.Indices))
+		for i, index := range expr.Indices {
+			indices[i] = index
+		}
+		return in.gep(nil, expr.ElemType, expr.Src, indices, expr.Type())
+	// Conversion expressions.
+	case *constant.ExprTrunc:
+		return in.conv(nil, expr.From, expr.To, constant.FoldTrunc)
+	case *constant.ExprZExt:
+		return in.conv(nil, expr.From, expr.To, constant.FoldZExt)
+	case *constant.ExprSExt:
+		return in.conv(nil, expr.From, expr.To, constant.FoldSExt)
+	case *constant.ExprFPTrunc:
+		return in.conv(nil, expr.From, expr.To, constant.FoldFPTrunc)
+	case *constant.ExprFPExt:
+		return in.conv(nil, expr.From, expr.To, constant.FoldFPExt)
+	case *constant.ExprFPToUI:
+		return in.conv(nil, expr.From, expr.To, constant.FoldFPToUI)
+	case *constant.ExprFPToSI:
+		return in.conv(nil, expr.From, expr.To, constant.FoldFPToSI)
+	case *constant.ExprUIToFP:
+		return in.conv(nil, expr.From, expr.To, constant.FoldUIToFP)
+	case *constant.ExprSIToFP:
+		return in.conv(nil, expr.From, expr.To, constant.FoldSIToFP)
+	case *constant.ExprPtrToInt:
+		return in.conv(nil, expr.From, expr.To, in.ptrToInt)
+	case *constant.ExprIntToPtr:
+		return in.conv(nil, expr.From, expr.To, in.intToPtr)
+	case *constant.ExprBitCast:
+		return in.conv(nil, expr.From, expr.To, in.bitCast)
+	case *constant.ExprAddrSpaceCast:
+		return in.conv(nil, expr.From, expr.To, in.bitCast)
+	// Other expressions.
+	case *constant.ExprICmp:
+		return in.binary(nil, expr.X, expr.Y, func(x, y constant.Constant) constant.Constant {
+			return in.icmp(expr.Pred, x, y)
+		})
+	case *constant.ExprFCmp:
+		return in.binary(nil, expr.X, expr.Y, func(x, y constant.Constant) constant.Constant {
+			return constant.FoldFCmp(expr.Pred, x, y)
+		})
+	case *constant.ExprSelect:
+		return in.ternary(nil, expr.Cond, expr.X, expr.Y, constant.FoldSelect)
+	default:
+		return nil, errors.Errorf("support for constant expression %T not yet implemented", expr)
+	}
+}
+
+// === [ Instructions ] ========================================================
+
+// execInst executes the given instruction, and returns its result; or nil if
+// the instruction produces no value.
+func (in *Interpreter) execInst(fr *frame, inst ir.Instruction) (constant.Constant, error) {
+	switch inst := inst.(type) {
+	// Unary instructions.
+	case *ir.InstFNeg:
+		return in.unary(fr, inst.X, constant.FoldFNeg)
+	// Binary instructions.
+	case *ir.InstAdd:
+		return in.binary(fr, inst.X, inst.Y, func(x, y constant.Constant) constant.Constant {
+			return constant.FoldAdd(x, y, inst.OverflowFlags)
+		})
+	case *ir.InstFAdd:
+		return in.binary(fr, inst.X, inst.Y, constant.FoldFAdd)
+	case *ir.InstSub:
+		return in.binary(fr, inst.X, inst.Y, func(x, y constant.Constant) constant.Constant {
+			return constant.FoldSub(x, y, inst.OverflowFlags)
+		})
+	case *ir.InstFSub:
+		return in.binary(fr, inst.X, inst.Y, constant.FoldFSub)
+	case *ir.InstMul:
+		return in.binary(fr, inst.X, inst.Y, func(x, y constant.Constant) constant.Constant {
+			return constant.FoldMul(x, y, inst.OverflowFlags)
+		})
+	case *ir.InstFMul:
+		return in.binary(fr, inst.X, inst.Y, constant.FoldFMul)
+	case *ir.InstUDiv:
+		return in.binary(fr, inst.X, inst.Y, func(x, y constant.Constant) constant.Constant {
+			return constant.FoldUDiv(x, y, inst.Exact)
+		})
+	case *ir.InstSDiv:
+		return in.binary(fr, inst.X, inst.Y, func(x, y constant.Constant) constant.Constant {
+			return constant.FoldSDiv(x, y, inst.Exact)
+		})
+	case *ir.InstFDiv:
+		return in.binary(fr, inst.X, inst.Y, constant.FoldFDiv)
+	case *ir.InstURem:
+		return in.binary(fr, inst.X, inst.Y, constant.FoldURem)
+	case *ir.InstSRem:
+		return in.binary(fr, inst.X, inst.Y, constant.FoldSRem)
+	case *ir.InstFRem:
+		return in.binary(fr, inst.X, inst.Y, constant.FoldFRem)
+	// Bitwise instructions.
+	case *ir.InstShl:
+		return in.binary(fr, inst.X, inst.Y, func(x, y constant.Constant) constant.Constant {
+			return constant.FoldShl(x, y, inst.OverflowFlags)
+		})
+	case *ir.InstLShr:
+		return in.binary(fr, inst.X, inst.Y, func(x, y constant.Constant) constant.Constant {
+			return constant.FoldLShr(x, y, inst.Exact)
+		})
+	case *ir.InstAShr:
+		return in.binary(fr, inst.X, inst.Y, func(x, y constant.Constant) constant.Constant {
+			return constant.FoldAShr(x, y, inst.Exact)
+		})
+	case *ir.InstAnd:
+		return in.binary(fr, inst.X, inst.Y, constant.FoldAnd)
+	case *ir.InstOr:
+		return in.binary(fr, inst.X, inst.Y, constant.FoldOr)
+	case *ir.InstXor:
+		return in.binary(fr, inst.X, inst.Y, constant.FoldXor)
+	// Vector instructions.
+	case *ir.InstExtractElement:
+		return in.binary(fr, inst.X, inst.Index, constant.FoldExtractElement)
+	case *ir.InstInsertElement:
+		return in.ternary(fr, inst.X, inst.Elem, inst.Index, constant.FoldInsertElement)
+	case *ir.InstShuffleVector:
+		return in.ternary(fr, inst.X, inst.Y, inst.Mask, constant.FoldShuffleVector)
+	// Aggregate instructions.
+	case *ir.InstExtractValue:
+		return in.unary(fr, inst.X, func(x constant.Constant) constant.Constant {
+			return constant.FoldExtractValue(x, inst.Indices)
+		})
+	case *ir.InstInsertValue:
+		return in.binary(fr, inst.X, inst.Elem, func(x, elem constant.Constant) constant.Constant {
+			return constant.FoldInsertValue(x, elem, inst.Indices)
+		})
+	// Memory instructions.
+	case *ir.InstAlloca:
+		return in.execAlloca(fr, inst)
+	case *ir.InstLoad:
+		src, err := in.evalAddr(fr, inst.Src)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return in.Load(src, inst.ElemType)
+	case *ir.InstStore:
+		x, err := in.eval(fr, inst.Src)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		dst, err := in.evalAddr(fr, inst.Dst)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return nil, in.Store(dst, x)
+	case *ir.InstFence:
+		// Memory ordering has no effect, as the interpreter is single-threaded.
+		return nil, nil
+	case *ir.InstCmpXchg:
+		return in.execCmpXchg(fr, inst)
+	case *ir.InstAtomicRMW:
+		return in.execAtomicRMW(fr, inst)
+	case *ir.InstGetElementPtr:
+		return in.gep(fr, inst.ElemType, inst.Src, inst.Indices, inst.Type())
+	// Conversion instructions.
+	case *ir.InstTrunc:
+		return in.conv(fr, inst.From, inst.To, constant.FoldTrunc)
+	case *ir.InstZExt:
+		return in.conv(fr, inst.From, inst.To, constant.FoldZExt)
+	case *ir.InstSExt:
+		return in.conv(fr, inst.From, inst.To, constant.FoldSExt)
+	case *ir.InstFPTrunc:
+		return in.conv(fr, inst.From, inst.To, constant.FoldFPTrunc)
+	case *ir.InstFPExt:
+		return in.conv(fr, inst.From, inst.To, constant.FoldFPExt)
+	case *ir.InstFPToUI:
+		return in.conv(fr, inst.From, inst.To, constant.FoldFPToUI)
+	case *ir.InstFPToSI:
+		return in.conv(fr, inst.From, inst.To, constant.FoldFPToSI)
+	case *ir.InstUIToFP:
+		return in.conv(fr, inst.From, inst.To, constant.FoldUIToFP)
+	case *ir.InstSIToFP:
+		return in.conv(fr, inst.From, inst.To, constant.FoldSIToFP)
+	case *ir.InstPtrToInt:
+		return in.conv(fr, inst.From, inst.To, in.ptrToInt)
+	case *ir.InstIntToPtr:
+		return in.conv(fr, inst.From, inst.To, in.intToPtr)
+	case *ir.InstBitCast:
+		return in.conv(fr, inst.From, inst.To, in.bitCast)
+	case *ir.InstAddrSpaceCast:
+		return in.conv(fr, inst.From, inst.To, in.bitCast)
+	// Other instructions.
+	case *ir.InstICmp:
+		return in.binary(fr, inst.X, inst.Y, func(x, y constant.Constant) constant.Constant {
+			return in.icmp(inst.Pred, x, y)
+		})
+	case *ir.InstFCmp:
+		return in.binary(fr, inst.X, inst.Y, func(x, y constant.Constant) constant.Constant {
+			return constant.FoldFCmp(inst.Pred, x, y)
+		})
+	case *ir.InstSelect:
+		return in.ternary(fr, inst.Cond, inst.ValueTrue, inst.ValueFalse, constant.FoldSelect)
+	case *ir.InstFreeze:
+		return in.unary(fr, inst.X, freeze)
+	case *ir.InstCall:
+		return in.execCall(fr, inst.Callee, inst.Args)
+	default:
+		return nil, errors.Errorf("support for instruction %T not yet implemented", inst)
+	}
+}
+
+// execAlloca executes the given alloca instruction.
+func (in *Interpreter) execAlloca(fr *frame, inst *ir.InstAlloca) (constant.Constant, error) {
+	n := uint64(1)
+	if inst.NElems != nil {
+		nelems, err := in.eval(fr, inst.NElems)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		x, ok := intOf(nelems)
+		if !ok {
+			return nil, errors.Errorf("invalid number of elements %q", nelems)
+		}
+		n = unsigned(x).Uint64()
+	}
+	align := uint64(inst.Align)
+	if align == 0 {
+		align = in.dl.AlignOf(inst.ElemType)
+	}
+	addr := in.Alloc(n*in.dl.SizeOf(inst.ElemType), align)
+	fr.allocas = append(fr.allocas, addr)
+	return NewPointer(inst.Type().(*types.PointerType), addr), nil
+}
+
+// execCmpXchg executes the given cmpxchg instruction.
+func (in *Interpreter) execCmpXchg(fr *frame, inst *ir.InstCmpXchg) (constant.Constant, error) {
+	ops, err := in.evalOps(fr, inst.Cmp, inst.New)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	ptr, err := in.evalAddr(fr, inst.Ptr)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	old, err := in.Load(ptr, ops[0].Type())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	success := in.icmp(enum.IPredEQ, old, ops[0])
+	if isTrue(success) {
+		if err := in.Store(ptr, ops[1]); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return constant.NewStruct(inst.Type().(*types.StructType), old, success), nil
+}
+
+// execAtomicRMW executes the given atomicrmw instruction.
+func (in *Interpreter) execAtomicRMW(fr *frame, inst *ir.InstAtomicRMW) (constant.Constant, error) {
+	x, err := in.eval(fr, inst.X)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	dst, err := in.evalAddr(fr, inst.Dst)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	old, err := in.Load(dst, x.Type())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var v constant.Constant
+	switch inst.Op {
+	case enum.AtomicOpXChg:
+		v = x
+	case enum.AtomicOpAdd:
+		v = constant.FoldAdd(old, x, nil)
+	case enum.AtomicOpSub:
+		v = constant.FoldSub(old, x, nil)
+	case enum.AtomicOpAnd:
+		v = constant.FoldAnd(old, x)
+	case enum.AtomicOpNAnd:
+		v = constant.FoldXor(constant.FoldAnd(old, x), constant.FoldSub(zeroOf(x.Type()), constant.NewInt(x.Type().(*types.IntType), 1), nil))
+	case enum.AtomicOpOr:
+		v = constant.FoldOr(old, x)
+	case enum.AtomicOpXor:
+		v = constant.FoldXor(old, x)
+	case enum.AtomicOpMax:
+		v = constant.FoldSelect(in.icmp(enum.IPredSGT, old, x), old, x)
+	case enum.AtomicOpMin:
+		v = constant.FoldSelect(in.icmp(enum.IPredSLT, old, x), old, x)
+	case enum.AtomicOpUMax:
+		v = constant.FoldSelect(in.icmp(enum.IPredUGT, old, x), old, x)
+	case enum.AtomicOpUMin:
+		v = constant.FoldSelect(in.icmp(enum.IPredULT, old, x), old, x)
+	case enum.AtomicOpFAdd:
+		v = constant.FoldFAdd(old, x)
+	case enum.AtomicOpFSub:
+		v = constant.FoldFSub(old, x)
+	case enum.AtomicOpFMax:
+		// maxnum semantics; NaN operands are ignored.
+		v = constant.FoldSelect(constant.FoldFCmp(enum.FPredUNO, x, x), old, constant.FoldSelect(constant.FoldFCmp(enum.FPredOGT, old, x), old, x))
+	case enum.AtomicOpFMin:
+		// minnum semantics; NaN operands are ignored.
+		v = constant.FoldSelect(constant.FoldFCmp(enum.FPredUNO, x, x), old, constant.FoldSelect(constant.FoldFCmp(enum.FPredOLT, old, x), old, x))
+	default:
+		return nil, errors.Errorf("support for atomic operation %q not yet implemented", inst.Op)
+	}
+	if v == nil {
+		return nil, errors.Errorf("invalid operands %q and %q of atomic operation %q", old, x, inst.Op)
+	}
+	if err := in.Store(dst, v); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return old, nil
+}
+
+// --- [ Operations ] ----------------------------------------------------------
+
+// evalOps returns the values of the given operands in the given stack frame.
+func (in *Interpreter) evalOps(fr *frame, operands ...value.Value) ([]constant.Constant, error) {
+	xs := make([]constant.Constant, len(operands))
+	for i, operand := range operands {
+		x, err := in.eval(fr, operand)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		xs[i] = x
+	}
+	return xs, nil
+}
+
+// evalAddr returns the address of the given pointer operand in the given stack
+// frame.
+func (in *Interpreter) evalAddr(fr *frame, v value.Value) (uint64, error) {
+	x, err := in.eval(fr, v)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	addr, ok := AddrOf(x)
+	if !ok {
+		return 0, errors.Errorf("invalid pointer operand %q", x)
+	}
+	return addr, nil
+}
+
+// unary evaluates the given unary operation on the value of x.
+func (in *Interpreter) unary(fr *frame, x value.Value, op func(x constant.Constant) constant.Constant) (constant.Constant, error) {
+	ops, err := in.evalOps(fr, x)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	v := op(ops[0])
+	if v == nil {
+		return nil, errors.Errorf("invalid operand %q", ops[0])
+	}
+	return v, nil
+}
+
+// binary evaluates the given binary operation on the values of x and y.
+func (in *Interpreter) binary(fr *frame, x, y value.Value, op func(x, y constant.Constant) constant.Constant) (constant.Constant, error) {
+	ops, err := in.evalOps(fr, x, y)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	v := op(ops[0], ops[1])
+	if v == nil {
+		// Division by zero is the most common reason for integer operations which
+		// cannot be evaluated.
+		if hasZero(ops[1]) {
+			return nil, errors.Errorf("division by zero; invalid operands %q and %q", ops[0], ops[1])
+		}
+		return nil, errors.Errorf("invalid operands %q and %q", ops[0], ops[1])
+	}
+	return v, nil
+}
+
+// ternary evaluates the given ternary operation on the values of x, y and z.
+func (in *Interpreter) ternary(fr *frame, x, y, z value.Value, op func(x, y, z constant.Constant) constant.Constant) (constant.Constant, error) {
+	ops, err := in.evalOps(fr, x, y, z)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	v := op(ops[0], ops[1], ops[2])
+	if v == nil {
+		return nil, errors.Errorf("invalid operands %q, %q and %q", ops[0], ops[1], ops[2])
+	}
+	return v, nil
+}
+
+// conv evaluates the given conversion of the value of from to the type to.
+func (in *Interpreter) conv(fr *frame, from value.Value, to types.Type, op func(x constant.Constant, to types.Type) constant.Constant) (constant.Constant, error) {
+	return in.unary(fr, from, func(x constant.Constant) constant.Constant {
+		return op(x, to)
+	})
+}
+
+// gep evaluates the getelementptr operation with the given element type,
+// source address and indices, producing a value of type t.
+func (in *Interpreter) gep(fr *frame, elemType types.Type, src value.Value, indices []value.Value, t types.Type) (constant.Constant, error) {
+	ops, err := in.evalOps(fr, append([]value.Value{src}, indices...)...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return in.gepOf(elemType, ops[0], ops[1:], t)
+}
+
+// gepOf returns the result of getelementptr with the given element type,
+// source address and indices, producing a value of type t.
+func (in *Interpreter) gepOf(elemType types.Type, src constant.Constant, indices []constant.Constant, t types.Type) (constant.Constant, error) {
+	// Vector getelementptr is evaluated element-wise.
+	if t, ok := t.(*types.VectorType); ok {
+		elems := make([]constant.Constant, t.Len)
+		for i := range elems {
+			laneIndices := make([]constant.Constant, len(indices))
+			for j, index := range indices {
+				laneIndices[j] = lane(index, i)
+			}
+			elem, err := in.gepOf(elemType, lane(src, i), laneIndices, t.ElemType)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			elems[i] = elem
+		}
+		return constant.NewVector(t, elems...), nil
+	}
+	if isPoison(src) {
+		return constant.NewPoison(t), nil
+	}
+	base, ok := AddrOf(src)
+	if !ok {
+		return nil, errors.Errorf("invalid source address %q", src)
+	}
+	is := make([]int64, len(indices))
+	for i, index := range indices {
+		if isPoison(index) {
+			return constant.NewPoison(t), nil
+		}
+		x, ok := intOf(index)
+		if !ok {
+			return nil, errors.Errorf("invalid index %q", index)
+		}
+		is[i] = x.X.Int64()
+	}
+	off, err := in.dl.GEPOffset(elemType, is...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	pt := t.(*types.PointerType)
+	return NewPointer(pt, in.wrapAddr(pt, base+uint64(off))), nil
+}
+
+// ptrToInt returns the result of ptrtoint x to to; or nil if invalid.
+func (in *Interpreter) ptrToInt(x constant.Constant, to types.Type) constant.Constant {
+	switch {
+	case isPoison(x):
+		return constant.NewPoison(to)
+	case isVector(to):
+		return lanes(x, to.(*types.VectorType), in.ptrToInt)
+	}
+	addr, ok := AddrOf(x)
+	toT, ok2 := to.(*types.IntType)
+	if !ok || !ok2 {
+		return nil
+	}
+	return newInt(toT, new(big.Int).SetUint64(addr))
+}
+
+// intToPtr returns the result of inttoptr x to to; or nil if invalid.
+func (in *Interpreter) intToPtr(x constant.Constant, to types.Type) constant.Constant {
+	switch {
+	case isPoison(x):
+		return constant.NewPoison(to)
+	case isVector(to):
+		return lanes(x, to.(*types.VectorType), in.intToPtr)
+	}
+	xi, ok := intOf(x)
+	toT, ok2 := to.(*types.PointerType)
+	if !ok || !ok2 {
+		return nil
+	}
+	return NewPointer(toT, in.wrapAddr(toT, unsigned(xi).Uint64()))
+}
+
+// bitCast returns the result of bitcast x to to; or nil if invalid. Bitcasts
+// between non-pointer types reinterpret the in-memory representation of x.
+func (in *Interpreter) bitCast(x constant.Constant, to types.Type) constant.Constant {
+	switch {
+	case x.Type().Equal(to):
+		return x
+	case isPoison(x):
+		return constant.NewPoison(to)
+	}
+	switch toT := to.(type) {
+	case *types.PointerType:
+		addr, ok := AddrOf(x)
+		if !ok {
+			return nil
+		}
+		return NewPointer(toT, in.wrapAddr(toT, addr))
+	case *types.VectorType:
+		if _, ok := toT.ElemType.(*types.PointerType); ok {
+			return lanes(x, toT, in.bitCast)
+		}
+	}
+	buf := make([]byte, in.dl.StoreSizeOf(x.Type()))
+	if in.dl.BitSizeOf(x.Type()) != in.dl.BitSizeOf(to) || in.encode(buf, x, x.Type()) != nil {
+		return nil
+	}
+	v, err := in.decode(buf, to)
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+// icmp returns the result of icmp pred x, y; or nil if invalid. Pointer
+// operands are compared by address.
+func (in *Interpreter) icmp(pred enum.IPred, x, y constant.Constant) constant.Constant {
+	if isPtr(x.Type()) {
+		intPtrType := types.Type(types.I64)
+		if t, ok := x.Type().(*types.VectorType); ok {
+			intPtrType = types.NewVector(t.Len, types.I64)
+		}
+		x, y = in.ptrToInt(x, intPtrType), in.ptrToInt(y, intPtrType)
+	}
+	return constant.FoldICmp(pred, x, y)
+}
+
+// wrapAddr returns the given address modulo 2^n, where n is the bit size of
+// pointers of the given pointer type.
+func (in *Interpreter) wrapAddr(t *types.PointerType, addr uint64) uint64 {
+	size := in.dl.PointerLayout(t.AddrSpace).Size
+	if size >= 64 {
+		return addr
+	}
+	return addr & (1<<size - 1)
+}
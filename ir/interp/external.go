@@ -0,0 +1,582 @@
+package interp
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/pkg/errors"
+)
+
+// ExternalFunc is an external function of the interpreter, which implements a
+// function declaration of the interpreted module (e.g. printf or an LLVM
+// intrinsic).
+//
+// The return value of the external function is converted to the return type of
+// the function declaration; integers are truncated or sign-extended, and
+// pointers are converted to the pointer type. The return value is ignored (and
+// may be nil) if the function declaration has void return type.
+type ExternalFunc func(in *Interpreter, args []constant.Constant) (constant.Constant, error)
+
+// defaultExternals returns the default external functions of the interpreter;
+// a subset of the C standard library and LLVM intrinsics.
+func defaultExternals() map[string]ExternalFunc {
+	return map[string]ExternalFunc{
+		// C standard library.
+		"abort":   abort,
+		"calloc":  calloc,
+		"exit":    exit,
+		"free":    free,
+		"malloc":  malloc,
+		"memcmp":  memcmp,
+		"memcpy":  memmove,
+		"memmove": memmove,
+		"memset":  memset,
+		"printf":  printf,
+		"putchar": putchar,
+		"puts":    puts,
+		"realloc": realloc,
+		"sprintf": sprintf,
+		"strcmp":  strcmp,
+		"strlen":  strlen,
+		// LLVM intrinsics.
+		"llvm.abs":            abs,
+		"llvm.assume":         nop,
+		"llvm.dbg.declare":    nop,
+		"llvm.dbg.label":      nop,
+		"llvm.dbg.value":      nop,
+		"llvm.donothing":      nop,
+		"llvm.expect":         expect,
+		"llvm.fabs":           fabs,
+		"llvm.lifetime.end":   nop,
+		"llvm.lifetime.start": nop,
+		"llvm.memcpy":         memmove,
+		"llvm.memmove":        memmove,
+		"llvm.memset":         memset,
+		"llvm.smax":           minMax(enum.IPredSGT),
+		"llvm.smin":           minMax(enum.IPredSLT),
+		"llvm.trap":           trap,
+		"llvm.umax":           minMax(enum.IPredUGT),
+		"llvm.umin":           minMax(enum.IPredULT),
+	}
+}
+
+// === [ C standard library ] ==================================================
+
+// --- [ Memory management ] ---------------------------------------------------
+
+// malloc implements void *malloc(size_t size).
+func malloc(in *Interpreter, args []constant.Constant) (constant.Constant, error) {
+	size, err := uintArg(args, 0)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return NewPointer(types.I8Ptr, in.Alloc(size, mallocAlign)), nil
+}
+
+// calloc implements void *calloc(size_t n, size_t size).
+func calloc(in *Interpreter, args []constant.Constant) (constant.Constant, error) {
+	n, err := uintArg(args, 0)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	size, err := uintArg(args, 1)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return NewPointer(types.I8Ptr, in.Alloc(n*size, mallocAlign)), nil
+}
+
+// realloc implements void *realloc(void *p, size_t size).
+func realloc(in *Interpreter, args []constant.Constant) (constant.Constant, error) {
+	p, err := addrArg(args, 0)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	size, err := uintArg(args, 1)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	addr := in.Alloc(size, mallocAlign)
+	if p != 0 {
+		old := in.mem.find(p)
+		if old == nil || old.addr != p || old.f != nil || old.freed {
+			return nil, errors.Errorf("invalid realloc of address 0x%X", p)
+		}
+		copy(in.mem.find(addr).data, old.data)
+		if err := in.Free(p); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return NewPointer(types.I8Ptr, addr), nil
+}
+
+// free implements void free(void *p).
+func free(in *Interpreter, args []constant.Constant) (constant.Constant, error) {
+	p, err := addrArg(args, 0)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if p == 0 {
+		return nil, nil
+	}
+	return nil, in.Free(p)
+}
+
+// mallocAlign specifies the alignment in bytes of memory allocated by malloc.
+const mallocAlign = 16
+
+// --- [ Memory and strings ] --------------------------------------------------
+
+// memmove implements void *memmove(void *dst, const void *src, size_t n). It is
+// also used for memcpy.
+func memmove(in *Interpreter, args []constant.Constant) (constant.Constant, error) {
+	dst, err := addrArg(args, 0)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	src, err := addrArg(args, 1)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	n, err := uintArg(args, 2)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	b, err := in.ReadBytes(src, n)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := in.WriteBytes(dst, b); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return args[0], nil
+}
+
+// memset implements void *memset(void *p, int c, size_t n).
+func memset(in *Interpreter, args []constant.Constant) (constant.Constant, error) {
+	p, err := addrArg(args, 0)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	c, err := uintArg(args, 1)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	n, err := uintArg(args, 2)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(c)
+	}
+	if err := in.WriteBytes(p, b); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return args[0], nil
+}
+
+// memcmp implements int memcmp(const void *a, const void *b, size_t n).
+func memcmp(in *Interpreter, args []constant.Constant) (constant.Constant, error) {
+	a, err := addrArg(args, 0)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	b, err := addrArg(args, 1)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	n, err := uintArg(args, 2)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	x, err := in.ReadBytes(a, n)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	y, err := in.ReadBytes(b, n)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return constant.NewInt(types.I32, int64(strings.Compare(string(x), string(y)))), nil
+}
+
+// strcmp implements int strcmp(const char *a, const char *b).
+func strcmp(in *Interpreter, args []constant.Constant) (constant.Constant, error) {
+	a, err := stringArg(in, args, 0)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	b, err := stringArg(in, args, 1)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return constant.NewInt(types.I32, int64(strings.Compare(a, b))), nil
+}
+
+// strlen implements size_t strlen(const char *s).
+func strlen(in *Interpreter, args []constant.Constant) (constant.Constant, error) {
+	s, err := stringArg(in, args, 0)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return constant.NewInt(types.I64, int64(len(s))), nil
+}
+
+// --- [ Input/output ] --------------------------------------------------------
+
+// putchar implements int putchar(int c).
+func putchar(in *Interpreter, args []constant.Constant) (constant.Constant, error) {
+	c, err := uintArg(args, 0)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if _, err := in.Stdout.Write([]byte{byte(c)}); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return constant.NewInt(types.I32, int64(byte(c))), nil
+}
+
+// puts implements int puts(const char *s).
+func puts(in *Interpreter, args []constant.Constant) (constant.Constant, error) {
+	s, err := stringArg(in, args, 0)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if _, err := io.WriteString(in.Stdout, s+"\n"); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return constant.NewInt(types.I32, int64(len(s)+1)), nil
+}
+
+// printf implements int printf(const char *format, ...).
+func printf(in *Interpreter, args []constant.Constant) (constant.Constant, error) {
+	format, err := stringArg(in, args, 0)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	s, err := in.format(format, args[1:])
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if _, err := io.WriteString(in.Stdout, s); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return constant.NewInt(types.I32, int64(len(s))), nil
+}
+
+// sprintf implements int sprintf(char *buf, const char *format, ...).
+func sprintf(in *Interpreter, args []constant.Constant) (constant.Constant, error) {
+	buf, err := addrArg(args, 0)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	format, err := stringArg(in, args, 1)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	s, err := in.format(format, args[2:])
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := in.WriteBytes(buf, append([]byte(s), 0)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return constant.NewInt(types.I32, int64(len(s))), nil
+}
+
+// format returns the string produced by formatting the given arguments
+// according to the given printf format string.
+func (in *Interpreter) format(format string, args []constant.Constant) (string, error) {
+	buf := &strings.Builder{}
+	next := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			buf.WriteByte(format[i])
+			continue
+		}
+		// Translate the C conversion specification into a Go format verb.
+		start := i
+		i++
+		spec := "%"
+		for i < len(format) && strings.IndexByte("-+ #0", format[i]) != -1 {
+			spec += format[i : i+1]
+			i++
+		}
+		// Width and precision; '*' takes the value from the arguments.
+		hasPrec := false
+		for i < len(format) && (format[i] == '.' || format[i] == '*' || ('0' <= format[i] && format[i] <= '9')) {
+			switch format[i] {
+			case '.':
+				hasPrec = true
+				spec += "."
+			case '*':
+				x, err := intArg(args, next)
+				if err != nil {
+					return "", errors.WithStack(err)
+				}
+				next++
+				spec += fmt.Sprint(signed(x).Int64())
+			default:
+				spec += format[i : i+1]
+			}
+			i++
+		}
+		// Length modifier.
+		size := uint64(32)
+		for i < len(format) && strings.IndexByte("hljztqL", format[i]) != -1 {
+			switch format[i] {
+			case 'h':
+				size /= 2
+				if size < 8 {
+					size = 8
+				}
+			default:
+				size = 64
+			}
+			i++
+		}
+		if i >= len(format) {
+			return "", errors.Errorf("invalid conversion specification %q in format string", format[start:])
+		}
+		verb := format[i]
+		if verb == '%' {
+			buf.WriteByte('%')
+			continue
+		}
+		arg := next
+		next++
+		switch verb {
+		case 'd', 'i':
+			x, err := intArg(args, arg)
+			if err != nil {
+				return "", errors.WithStack(err)
+			}
+			fmt.Fprintf(buf, spec+"d", signed(resize(x, size)).Int64())
+		case 'u', 'o', 'x', 'X':
+			x, err := intArg(args, arg)
+			if err != nil {
+				return "", errors.WithStack(err)
+			}
+			if verb == 'u' {
+				verb = 'd'
+			}
+			fmt.Fprintf(buf, spec+string(verb), unsigned(resize(x, size)).Uint64())
+		case 'c':
+			c, err := uintArg(args, arg)
+			if err != nil {
+				return "", errors.WithStack(err)
+			}
+			fmt.Fprintf(buf, spec+"s", string([]byte{byte(c)}))
+		case 's':
+			p, err := addrArg(args, arg)
+			if err != nil {
+				return "", errors.WithStack(err)
+			}
+			s := "(null)"
+			if p != 0 {
+				if s, err = in.ReadString(p); err != nil {
+					return "", errors.WithStack(err)
+				}
+			}
+			fmt.Fprintf(buf, spec+"s", s)
+		case 'p':
+			p, err := addrArg(args, arg)
+			if err != nil {
+				return "", errors.WithStack(err)
+			}
+			if p == 0 {
+				buf.WriteString("(nil)")
+			} else {
+				fmt.Fprintf(buf, "0x%x", p)
+			}
+		case 'f', 'F', 'e', 'E', 'g', 'G':
+			x, err := floatArg(args, arg)
+			if err != nil {
+				return "", errors.WithStack(err)
+			}
+			v := math.NaN()
+			if !x.NaN {
+				v, _ = x.X.Float64()
+			}
+			switch {
+			case math.IsInf(v, 0) || math.IsNaN(v):
+				// C prints inf and nan, rather than +Inf and NaN.
+				s := "inf"
+				if math.IsNaN(v) {
+					s = "nan"
+				}
+				if v < 0 || (x.NaN && x.X.Signbit()) {
+					s = "-" + s
+				}
+				if 'A' <= verb && verb <= 'Z' {
+					s = strings.ToUpper(s)
+				}
+				buf.WriteString(s)
+			default:
+				// The default precision of C is 6.
+				if !hasPrec {
+					spec += ".6"
+				}
+				fmt.Fprintf(buf, spec+string(verb), v)
+			}
+		default:
+			return "", errors.Errorf("support for conversion specification %q in format string not yet implemented", format[start:i+1])
+		}
+	}
+	return buf.String(), nil
+}
+
+// --- [ Process control ] -----------------------------------------------------
+
+// abort implements void abort(void).
+func abort(in *Interpreter, args []constant.Constant) (constant.Constant, error) {
+	return nil, errors.New("program aborted")
+}
+
+// exit implements void exit(int code).
+func exit(in *Interpreter, args []constant.Constant) (constant.Constant, error) {
+	code, err := intArg(args, 0)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return nil, &ExitError{Code: int(signed(code).Int64())}
+}
+
+// === [ LLVM intrinsics ] =====================================================
+
+// nop implements intrinsics without effect on the execution of the interpreter
+// (e.g. llvm.lifetime.start and llvm.dbg.value).
+func nop(in *Interpreter, args []constant.Constant) (constant.Constant, error) {
+	return nil, nil
+}
+
+// trap implements void @llvm.trap().
+func trap(in *Interpreter, args []constant.Constant) (constant.Constant, error) {
+	return nil, errors.New("trap executed")
+}
+
+// expect implements T @llvm.expect(T x, T expected).
+func expect(in *Interpreter, args []constant.Constant) (constant.Constant, error) {
+	if len(args) < 1 {
+		return nil, errors.New("missing argument 0")
+	}
+	return args[0], nil
+}
+
+// minMax returns the implementation of the integer minimum or maximum
+// intrinsic selecting x if x pred y (e.g. llvm.smax for sgt).
+func minMax(pred enum.IPred) ExternalFunc {
+	return func(in *Interpreter, args []constant.Constant) (constant.Constant, error) {
+		if len(args) < 2 {
+			return nil, errors.Errorf("invalid number of arguments; expected 2, got %d", len(args))
+		}
+		x, y := args[0], args[1]
+		return constant.FoldSelect(in.icmp(pred, x, y), x, y), nil
+	}
+}
+
+// abs implements T @llvm.abs(T x, i1 is_int_min_poison).
+func abs(in *Interpreter, args []constant.Constant) (constant.Constant, error) {
+	if len(args) < 1 {
+		return nil, errors.New("missing argument 0")
+	}
+	x := args[0]
+	neg := constant.FoldSub(zeroOf(x.Type()), x, nil)
+	return constant.FoldSelect(in.icmp(enum.IPredSLT, x, zeroOf(x.Type())), neg, x), nil
+}
+
+// fabs implements T @llvm.fabs(T x).
+func fabs(in *Interpreter, args []constant.Constant) (constant.Constant, error) {
+	if len(args) < 1 {
+		return nil, errors.New("missing argument 0")
+	}
+	if t, ok := args[0].Type().(*types.VectorType); ok {
+		return lanes(args[0], t, func(x constant.Constant, to types.Type) constant.Constant {
+			v, err := fabs(in, []constant.Constant{x})
+			if err != nil {
+				return nil
+			}
+			return v
+		}), nil
+	}
+	x, ok := args[0].(*constant.Float)
+	if !ok {
+		return args[0], nil
+	}
+	if x.X.Signbit() {
+		return constant.FoldFNeg(x), nil
+	}
+	return x, nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// intArg returns the integer argument at index i.
+func intArg(args []constant.Constant, i int) (*constant.Int, error) {
+	if i >= len(args) {
+		return nil, errors.Errorf("missing argument %d", i)
+	}
+	x, ok := intOf(args[i])
+	if !ok {
+		return nil, errors.Errorf("invalid argument %d; expected integer value, got %q", i, args[i])
+	}
+	return x, nil
+}
+
+// uintArg returns the unsigned integer value of the argument at index i.
+func uintArg(args []constant.Constant, i int) (uint64, error) {
+	x, err := intArg(args, i)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return unsigned(x).Uint64(), nil
+}
+
+// floatArg returns the floating-point argument at index i.
+func floatArg(args []constant.Constant, i int) (*constant.Float, error) {
+	if i >= len(args) {
+		return nil, errors.Errorf("missing argument %d", i)
+	}
+	x, ok := args[i].(*constant.Float)
+	if !ok {
+		return nil, errors.Errorf("invalid argument %d; expected floating-point value, got %q", i, args[i])
+	}
+	return x, nil
+}
+
+// addrArg returns the address of the pointer argument at index i.
+func addrArg(args []constant.Constant, i int) (uint64, error) {
+	if i >= len(args) {
+		return 0, errors.Errorf("missing argument %d", i)
+	}
+	addr, ok := AddrOf(args[i])
+	if !ok {
+		return 0, errors.Errorf("invalid argument %d; expected pointer value, got %q", i, args[i])
+	}
+	return addr, nil
+}
+
+// stringArg returns the NUL-terminated string pointed to by the argument at
+// index i.
+func stringArg(in *Interpreter, args []constant.Constant, i int) (string, error) {
+	addr, err := addrArg(args, i)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return in.ReadString(addr)
+}
+
+// resize returns the integer x truncated to the given bit size, if smaller than
+// the bit size of x.
+func resize(x *constant.Int, size uint64) *constant.Int {
+	if x.Typ.BitSize <= size {
+		return x
+	}
+	return newInt(types.NewInt(size), x.X)
+}
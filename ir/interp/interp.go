@@ -0,0 +1,641 @@
+// Package interp implements an interpreter for LLVM IR modules.
+//
+// The interpreter executes the functions of a module directly on the in-memory
+// representation of LLVM IR, without the need for an LLVM installation. Values
+// of the interpreter are represented by constants (e.g. *constant.Int and
+// *constant.Float), with the addition of pointer values (*interp.Pointer) which
+// refer to the memory of the interpreter. The memory of the interpreter is laid
+// out as specified by the data layout of the module.
+//
+// Calls to function declarations are resolved by name to external functions of
+// the interpreter (e.g. printf and malloc), which may be extended by users of
+// the interpreter.
+package interp
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/datalayout"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+	"github.com/pkg/errors"
+)
+
+// maxCallDepth specifies the maximum call depth of the interpreter.
+const maxCallDepth = 10000
+
+// ErrStackOverflow is the cause of errors returned when the maximum call depth
+// of the interpreter is exceeded.
+var ErrStackOverflow = errors.New("stack overflow")
+
+// Interpreter is an interpreter of LLVM IR modules.
+type Interpreter struct {
+	// External functions, by function name; called for function declarations of
+	// the module. The default external functions are added by New.
+	Externals map[string]ExternalFunc
+	// Standard output of external functions (e.g. printf); defaults to
+	// os.Stdout.
+	Stdout io.Writer
+
+	// Module being interpreted.
+	m *ir.Module
+	// Data layout of the module.
+	dl *datalayout.DataLayout
+	// Memory of the interpreter.
+	mem memory
+	// Addresses of global variables and functions.
+	addrs map[value.Value]uint64
+	// Functions, by address.
+	funcs map[uint64]*ir.Func
+	// Current call depth.
+	depth int
+}
+
+// New returns a new interpreter for the given module. The global variables of
+// the module are allocated and initialized in the memory of the interpreter;
+// global variable declarations are zero-initialized.
+func New(m *ir.Module) (*Interpreter, error) {
+	dl, err := datalayout.Parse(m.DataLayout)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	in := &Interpreter{
+		Externals: defaultExternals(),
+		Stdout:    os.Stdout,
+		m:         m,
+		dl:        dl,
+		addrs:     make(map[value.Value]uint64),
+		funcs:     make(map[uint64]*ir.Func),
+	}
+	// Allocate functions, to give each function a unique address.
+	for _, f := range m.Funcs {
+		a := in.mem.alloc(0, 1)
+		a.f = f
+		in.addrs[f] = a.addr
+		in.funcs[a.addr] = f
+	}
+	// Allocate global variables before initializing them, as initializers may
+	// refer to the address of any global variable.
+	for _, g := range m.Globals {
+		align := in.dl.AlignOf(g.ContentType)
+		if uint64(g.Align) > align {
+			align = uint64(g.Align)
+		}
+		in.addrs[g] = in.mem.alloc(in.dl.SizeOf(g.ContentType), align).addr
+	}
+	for _, g := range m.Globals {
+		if g.Init == nil {
+			continue
+		}
+		init, err := in.evalConst(g.Init)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to initialize global variable %s", g.Ident())
+		}
+		if err := in.Store(in.addrs[g], init); err != nil {
+			return nil, errors.Wrapf(err, "unable to initialize global variable %s", g.Ident())
+		}
+	}
+	return in, nil
+}
+
+// DataLayout returns the data layout used by the interpreter.
+func (in *Interpreter) DataLayout() *datalayout.DataLayout {
+	return in.dl
+}
+
+// AddrOfGlobal returns the address of the global variable or function with the
+// given name (without '@' prefix). The boolean return value indicates success.
+func (in *Interpreter) AddrOfGlobal(name string) (uint64, bool) {
+	for v, addr := range in.addrs {
+		if v.(value.Named).Name() == name {
+			return addr, true
+		}
+	}
+	return 0, false
+}
+
+// Call calls the given function with the given arguments, and returns the
+// return value of the function; or nil if the function has void return type.
+func (in *Interpreter) Call(f *ir.Func, args ...constant.Constant) (constant.Constant, error) {
+	vs := make([]constant.Constant, len(args))
+	for i, arg := range args {
+		v, err := in.evalConst(arg)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid argument %d of function %s", i, f.Ident())
+		}
+		vs[i] = v
+	}
+	return in.call(f, vs)
+}
+
+// RunMain runs the main function of the module with the given command line
+// arguments (including the program name), and returns its exit code; either
+// the return value of main or the exit code of a call to exit.
+//
+// The main function has no parameters, or takes the parameters argc and argv
+// of C.
+func (in *Interpreter) RunMain(args []string) (int, error) {
+	var main *ir.Func
+	for _, f := range in.m.Funcs {
+		if f.Name() != "main" {
+			continue
+		}
+		// Materialize function body if lazily parsed.
+		if err := f.Materialize(); err != nil {
+			return 0, errors.WithStack(err)
+		}
+		if len(f.Blocks) > 0 {
+			main = f
+			break
+		}
+	}
+	if main == nil {
+		return 0, errors.New("unable to locate definition of function @main")
+	}
+	var mainArgs []constant.Constant
+	if len(main.Params) >= 2 {
+		argcType, ok := main.Params[0].Type().(*types.IntType)
+		if !ok {
+			return 0, errors.Errorf("invalid argc parameter type of function @main; expected integer type, got %q", main.Params[0].Type())
+		}
+		argvType, ok := main.Params[1].Type().(*types.PointerType)
+		if !ok {
+			return 0, errors.Errorf("invalid argv parameter type of function @main; expected pointer type, got %q", main.Params[1].Type())
+		}
+		// Store argv as a null-terminated array of pointers to NUL-terminated
+		// strings.
+		ptrSize := in.dl.PointerLayout(0).Size / 8
+		argv := in.Alloc(uint64(len(args)+1)*ptrSize, ptrSize)
+		for i, arg := range args {
+			s := in.Alloc(uint64(len(arg)+1), 1)
+			if err := in.WriteBytes(s, append([]byte(arg), 0)); err != nil {
+				return 0, errors.WithStack(err)
+			}
+			if err := in.Store(argv+uint64(i)*ptrSize, NewPointer(types.I8Ptr, s)); err != nil {
+				return 0, errors.WithStack(err)
+			}
+		}
+		mainArgs = append(mainArgs, constant.NewInt(argcType, int64(len(args))), NewPointer(argvType, argv))
+	}
+	ret, err := in.call(main, mainArgs)
+	if err != nil {
+		if e, ok := errors.Cause(err).(*ExitError); ok {
+			return e.Code, nil
+		}
+		return 0, errors.WithStack(err)
+	}
+	if x, ok := ret.(*constant.Int); ok {
+		return int(x.X.Int64()), nil
+	}
+	return 0, nil
+}
+
+// ExitError is the error returned when the interpreted program calls exit.
+type ExitError struct {
+	// Exit code.
+	Code int
+}
+
+// Error returns the error message of the exit error.
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// === [ Function execution ] ==================================================
+
+// frame is the stack frame of a function call.
+type frame struct {
+	// Function being executed.
+	f *ir.Func
+	// Values of parameters and local variables.
+	locals map[value.Value]constant.Constant
+	// Addresses of memory allocated by alloca instructions; freed on return.
+	allocas []uint64
+}
+
+// call calls the given function with the given arguments.
+func (in *Interpreter) call(f *ir.Func, args []constant.Constant) (constant.Constant, error) {
+	// Materialize function body on first call if lazily parsed.
+	if err := f.Materialize(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(f.Blocks) == 0 {
+		return in.callExternal(f, args)
+	}
+	if len(args) < len(f.Params) || (len(args) > len(f.Params) && !f.Sig.Variadic) {
+		return nil, errors.Errorf("invalid number of arguments in call to function %s; expected %d, got %d", f.Ident(), len(f.Params), len(args))
+	}
+	if in.depth >= maxCallDepth {
+		return nil, errors.Wrapf(ErrStackOverflow, "maximum call depth %d exceeded in call to function %s", maxCallDepth, f.Ident())
+	}
+	in.depth++
+	defer func() { in.depth-- }()
+	fr := &frame{
+		f:      f,
+		locals: make(map[value.Value]constant.Constant),
+	}
+	for i, param := range f.Params {
+		fr.locals[param] = args[i]
+	}
+	ret, err := in.execFunc(fr)
+	// Free memory allocated by alloca instructions on return.
+	for _, addr := range fr.allocas {
+		// Ignore invalid frees of stack memory, as these are reported on free.
+		_ = in.mem.free(addr)
+	}
+	return ret, err
+}
+
+// execFunc executes the body of the function of the given stack frame.
+func (in *Interpreter) execFunc(fr *frame) (constant.Constant, error) {
+	var pred *ir.Block
+	block := fr.f.Blocks[0]
+	for {
+		if err := in.execPhis(fr, block, pred); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for _, inst := range block.Insts {
+			if _, ok := inst.(*ir.InstPhi); ok {
+				continue
+			}
+			v, err := in.execInst(fr, inst)
+			if err != nil {
+				if isUnwinding(err) {
+					return nil, err
+				}
+				return nil, errors.Wrapf(err, "unable to execute %q in function %s", inst.LLString(), fr.f.Ident())
+			}
+			if v != nil {
+				fr.locals[inst.(value.Value)] = v
+			}
+		}
+		next, ret, err := in.execTerm(fr, block.Term)
+		if err != nil {
+			if isUnwinding(err) {
+				return nil, err
+			}
+			return nil, errors.Wrapf(err, "unable to execute %q in function %s", block.Term.LLString(), fr.f.Ident())
+		}
+		if next == nil {
+			return ret, nil
+		}
+		pred, block = block, next
+	}
+}
+
+// execPhis executes the phi instructions at the start of the given basic block,
+// entered from the given predecessor basic block. The incoming values of all
+// phi instructions are evaluated before any phi instruction is assigned.
+func (in *Interpreter) execPhis(fr *frame, block, pred *ir.Block) error {
+	var phis []*ir.InstPhi
+	var vs []constant.Constant
+	for _, inst := range block.Insts {
+		phi, ok := inst.(*ir.InstPhi)
+		if !ok {
+			break
+		}
+		found := false
+		for _, inc := range phi.Incs {
+			if inc.Pred != pred {
+				continue
+			}
+			v, err := in.eval(fr, inc.X)
+			if err != nil {
+				return errors.Wrapf(err, "unable to execute %q in function %s", phi.LLString(), fr.f.Ident())
+			}
+			phis = append(phis, phi)
+			vs = append(vs, v)
+			found = true
+			break
+		}
+		if !found {
+			return errors.Errorf("unable to execute %q in function %s; missing incoming value of predecessor basic block", phi.LLString(), fr.f.Ident())
+		}
+	}
+	for i, phi := range phis {
+		fr.locals[phi] = vs[i]
+	}
+	return nil
+}
+
+// execTerm executes the given terminator, and returns the successor basic block
+// to execute; or the return value of the function if the successor basic block
+// is nil.
+func (in *Interpreter) execTerm(fr *frame, term ir.Terminator) (*ir.Block, constant.Constant, error) {
+	switch term := term.(type) {
+	case *ir.TermRet:
+		if term.X == nil {
+			return nil, nil, nil
+		}
+		x, err := in.eval(fr, term.X)
+		return nil, x, err
+	case *ir.TermBr:
+		return term.Target.(*ir.Block), nil, nil
+	case *ir.TermCondBr:
+		cond, err := in.eval(fr, term.Cond)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		c, ok := intOf(cond)
+		if !ok {
+			return nil, nil, errors.Errorf("branch on poison value %q", cond)
+		}
+		if c.X.Sign() != 0 {
+			return term.TargetTrue.(*ir.Block), nil, nil
+		}
+		return term.TargetFalse.(*ir.Block), nil, nil
+	case *ir.TermSwitch:
+		x, err := in.eval(fr, term.X)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		if _, ok := intOf(x); !ok {
+			return nil, nil, errors.Errorf("switch on poison value %q", x)
+		}
+		for _, c := range term.Cases {
+			y, err := in.eval(fr, c.X)
+			if err != nil {
+				return nil, nil, errors.WithStack(err)
+			}
+			if isTrue(in.icmp(enum.IPredEQ, x, y)) {
+				return c.Target.(*ir.Block), nil, nil
+			}
+		}
+		return term.TargetDefault.(*ir.Block), nil, nil
+	case *ir.TermInvoke:
+		v, err := in.execCall(fr, term.Invokee, term.Args)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		if v != nil {
+			fr.locals[term] = v
+		}
+		return term.NormalRetTarget.(*ir.Block), nil, nil
+	case *ir.TermUnreachable:
+		return nil, nil, errors.New("unreachable executed")
+	default:
+		return nil, nil, errors.Errorf("support for terminator %T not yet implemented", term)
+	}
+}
+
+// execCall calls the given callee with the given arguments, and returns the
+// return value of the callee; or nil if void.
+func (in *Interpreter) execCall(fr *frame, callee value.Value, args []value.Value) (constant.Constant, error) {
+	f, ok := callee.(*ir.Func)
+	if !ok {
+		c, err := in.eval(fr, callee)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		addr, ok := AddrOf(c)
+		if !ok {
+			return nil, errors.Errorf("invalid callee %q", c)
+		}
+		if f, ok = in.funcs[addr]; !ok {
+			return nil, errors.Errorf("invalid call to address 0x%X; not a function", addr)
+		}
+	}
+	vs := make([]constant.Constant, len(args))
+	for i, arg := range args {
+		v, err := in.eval(fr, arg)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		vs[i] = v
+	}
+	return in.call(f, vs)
+}
+
+// callExternal calls the external function corresponding to the given function
+// declaration.
+func (in *Interpreter) callExternal(f *ir.Func, args []constant.Constant) (constant.Constant, error) {
+	ext := in.lookupExternal(f.Name())
+	if ext == nil {
+		return nil, errors.Errorf("unable to call external function %s; no implementation provided", f.Ident())
+	}
+	ret, err := ext(in, args)
+	if err != nil {
+		if isUnwinding(err) {
+			return nil, err
+		}
+		return nil, errors.Wrapf(err, "error in call to external function %s", f.Ident())
+	}
+	retType := f.Sig.RetType
+	if retType.Equal(types.Void) {
+		return nil, nil
+	}
+	if ret == nil {
+		return nil, errors.Errorf("missing return value of external function %s", f.Ident())
+	}
+	return in.coerce(ret, retType)
+}
+
+// lookupExternal returns the external function of the given name; or nil if not
+// present. Overloaded intrinsics are looked up by their base name if no
+// external function of the full name is present (e.g. llvm.memcpy for
+// llvm.memcpy.p0i8.p0i8.i64).
+func (in *Interpreter) lookupExternal(name string) ExternalFunc {
+	for {
+		if ext, ok := in.Externals[name]; ok {
+			return ext
+		}
+		pos := strings.LastIndex(name, ".")
+		if !strings.HasPrefix(name, "llvm.") || pos <= len("llvm") {
+			return nil
+		}
+		name = name[:pos]
+	}
+}
+
+// coerce converts the return value of an external function to the given
+// return type; e.g. to let external functions return i64 integers for
+// functions returning i32, or i8* pointers for functions returning other
+// pointer types.
+func (in *Interpreter) coerce(v constant.Constant, t types.Type) (constant.Constant, error) {
+	if v.Type().Equal(t) {
+		return v, nil
+	}
+	switch t := t.(type) {
+	case *types.IntType:
+		if x, ok := intOf(v); ok {
+			return newInt(t, x.X), nil
+		}
+	case *types.PointerType:
+		if addr, ok := AddrOf(v); ok {
+			return NewPointer(t, addr), nil
+		}
+	}
+	return nil, errors.Errorf("invalid return value %q of external function; expected value of type %q", v, t)
+}
+
+// ### [ Helper functions ] ####################################################
+
+// isUnwinding reports whether the given error unwinds the call stack without
+// being annotated by each stack frame; i.e. calls to exit and stack overflows.
+func isUnwinding(err error) bool {
+	if _, ok := errors.Cause(err).(*ExitError); ok {
+		return true
+	}
+	return errors.Cause(err) == ErrStackOverflow
+}
+
+// zeroOf returns the zero value of the given type.
+func zeroOf(t types.Type) constant.Constant {
+	switch t := t.(type) {
+	case *types.IntType:
+		return constant.NewInt(t, 0)
+	case *types.FloatType:
+		return constant.NewFloat(t, 0)
+	case *types.PointerType:
+		return NewPointer(t, 0)
+	default:
+		return constant.NewZeroInitializer(t)
+	}
+}
+
+// intOf returns the integer constant of the given value; e.g. 0 for
+// zeroinitializer of integer type. The boolean return value indicates success.
+func intOf(c constant.Constant) (*constant.Int, bool) {
+	switch c := c.(type) {
+	case *constant.Int:
+		return c, true
+	case *constant.ZeroInitializer, *constant.Undef:
+		if t, ok := c.Type().(*types.IntType); ok {
+			return constant.NewInt(t, 0), true
+		}
+	}
+	return nil, false
+}
+
+// newInt returns a new integer constant of the given type, with the value of x
+// truncated to the bit size of the type, in the canonical signed representation
+// of integer constants.
+func newInt(t *types.IntType, x *big.Int) *constant.Int {
+	u := truncate(x, t.BitSize)
+	if t.BitSize > 1 && u.Bit(int(t.BitSize)-1) == 1 {
+		u.Sub(u, new(big.Int).Lsh(big.NewInt(1), uint(t.BitSize)))
+	}
+	return &constant.Int{Typ: t, X: u}
+}
+
+// unsigned returns the unsigned value of the given integer constant.
+func unsigned(x *constant.Int) *big.Int {
+	return truncate(x.X, x.Typ.BitSize)
+}
+
+// signed returns the signed value of the given integer constant.
+func signed(x *constant.Int) *big.Int {
+	return newInt(x.Typ, x.X).X
+}
+
+// truncate returns x modulo 2^n, as a non-negative integer.
+func truncate(x *big.Int, n uint64) *big.Int {
+	mask := new(big.Int).Lsh(big.NewInt(1), uint(n))
+	mask.Sub(mask, big.NewInt(1))
+	return new(big.Int).And(x, mask)
+}
+
+// isTrue reports whether the given value is the boolean value true.
+func isTrue(c constant.Constant) bool {
+	x, ok := intOf(c)
+	return ok && x.X.Sign() != 0
+}
+
+// isPoison reports whether the given value is a poison value.
+func isPoison(c constant.Constant) bool {
+	_, ok := c.(*constant.Poison)
+	return ok
+}
+
+// hasZero reports whether the given integer value is zero, or is a vector with
+// a zero element.
+func hasZero(c constant.Constant) bool {
+	if t, ok := c.Type().(*types.VectorType); ok {
+		for i := 0; uint64(i) < t.Len; i++ {
+			if hasZero(lane(c, i)) {
+				return true
+			}
+		}
+		return false
+	}
+	x, ok := intOf(c)
+	return ok && x.X.Sign() == 0
+}
+
+// isVector reports whether the given type is a vector type.
+func isVector(t types.Type) bool {
+	_, ok := t.(*types.VectorType)
+	return ok
+}
+
+// isPtr reports whether the given type is a pointer type or a vector of
+// pointers.
+func isPtr(t types.Type) bool {
+	if vt, ok := t.(*types.VectorType); ok {
+		t = vt.ElemType
+	}
+	_, ok := t.(*types.PointerType)
+	return ok
+}
+
+// lane returns the element at index i of the given vector value; or c itself
+// if not a vector value.
+func lane(c constant.Constant, i int) constant.Constant {
+	if !isVector(c.Type()) {
+		return c
+	}
+	return constant.FoldExtractElement(c, constant.NewInt(types.I64, int64(i)))
+}
+
+// lanes returns the vector of type t produced by applying the given conversion
+// element-wise to the elements of x; or nil if the conversion of an element is
+// invalid.
+func lanes(x constant.Constant, t *types.VectorType, conv func(x constant.Constant, to types.Type) constant.Constant) constant.Constant {
+	elems := make([]constant.Constant, t.Len)
+	for i := range elems {
+		elem := lane(x, i)
+		if elem == nil {
+			return nil
+		}
+		if elems[i] = conv(elem, t.ElemType); elems[i] == nil {
+			return nil
+		}
+	}
+	return constant.NewVector(t, elems...)
+}
+
+// freeze returns the value of freeze x; poison elements are given the value
+// zero.
+func freeze(x constant.Constant) constant.Constant {
+	switch x := x.(type) {
+	case *constant.Poison:
+		return zeroOf(x.Typ)
+	case *constant.Vector:
+		elems := make([]constant.Constant, len(x.Elems))
+		for i, elem := range x.Elems {
+			elems[i] = freeze(elem)
+		}
+		return constant.NewVector(x.Typ, elems...)
+	case *constant.Array:
+		elems := make([]constant.Constant, len(x.Elems))
+		for i, elem := range x.Elems {
+			elems[i] = freeze(elem)
+		}
+		return constant.NewArray(x.Typ, elems...)
+	case *constant.Struct:
+		fields := make([]constant.Constant, len(x.Fields))
+		for i, field := range x.Fields {
+			fields[i] = freeze(field)
+		}
+		return constant.NewStruct(x.Typ, fields...)
+	default:
+		return x
+	}
+}
@@ -0,0 +1,205 @@
+package interp_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/llir/llvm/asm"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/interp"
+	"github.com/llir/llvm/ir/types"
+)
+
+func TestRunMain(t *testing.T) {
+	// The golden output of each program is the output of lli (LLVM 14).
+	golden := []struct {
+		path string
+		// Command line arguments, after the program name.
+		args []string
+		// Exit code.
+		exit int
+	}{
+		{path: "testdata/arith.ll", exit: 57},
+		{path: "testdata/memory.ll", args: []string{"foo", "bar"}, exit: 3},
+		{path: "testdata/float.ll", exit: 5},
+		{path: "testdata/vector.ll", exit: 37},
+	}
+	for _, g := range golden {
+		m, err := asm.ParseFile(g.path)
+		if err != nil {
+			t.Errorf("unable to parse %q; %+v", g.path, err)
+			continue
+		}
+		in, err := interp.New(m)
+		if err != nil {
+			t.Errorf("%q: unable to create interpreter; %+v", g.path, err)
+			continue
+		}
+		stdout := &strings.Builder{}
+		in.Stdout = stdout
+		exit, err := in.RunMain(append([]string{filepath.Base(g.path)}, g.args...))
+		if err != nil {
+			t.Errorf("%q: unable to run main; %+v", g.path, err)
+			continue
+		}
+		if exit != g.exit {
+			t.Errorf("%q: exit code mismatch; expected %d, got %d", g.path, g.exit, exit)
+		}
+		buf, err := ioutil.ReadFile(g.path + ".golden")
+		if err != nil {
+			t.Errorf("unable to read %q; %+v", g.path+".golden", err)
+			continue
+		}
+		want := string(buf)
+		got := stdout.String()
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("output of %q mismatch (-want +got):\n%s", g.path, diff)
+		}
+	}
+}
+
+func TestCall(t *testing.T) {
+	golden := []struct {
+		path string
+		// Function name.
+		name string
+		args []constant.Constant
+		// Return value; or empty if void.
+		want string
+	}{
+		{path: "testdata/arith.ll", name: "fact", args: []constant.Constant{constant.NewInt(types.I64, 10)}, want: "i64 3628800"},
+		{path: "testdata/arith.ll", name: "fib", args: []constant.Constant{constant.NewInt(types.I32, 10)}, want: "i32 55"},
+		{path: "testdata/arith.ll", name: "classify", args: []constant.Constant{constant.NewInt(types.I32, -7)}, want: "i32 102"},
+		{path: "testdata/arith.ll", name: "classify", args: []constant.Constant{constant.NewUndef(types.I32)}, want: "i32 100"},
+	}
+	for _, g := range golden {
+		m, err := asm.ParseFile(g.path)
+		if err != nil {
+			t.Errorf("unable to parse %q; %+v", g.path, err)
+			continue
+		}
+		in, err := interp.New(m)
+		if err != nil {
+			t.Errorf("%q: unable to create interpreter; %+v", g.path, err)
+			continue
+		}
+		f := findFunc(m, g.name)
+		if f == nil {
+			t.Errorf("%q: unable to locate function @%s", g.path, g.name)
+			continue
+		}
+		v, err := in.Call(f, g.args...)
+		if err != nil {
+			t.Errorf("%q: unable to call function %s; %+v", g.path, f.Ident(), err)
+			continue
+		}
+		got := ""
+		if v != nil {
+			got = v.String()
+		}
+		if got != g.want {
+			t.Errorf("%q: return value of function %s mismatch; expected %q, got %q", g.path, f.Ident(), g.want, got)
+		}
+	}
+}
+
+func TestExternals(t *testing.T) {
+	const path = "testdata/external.ll"
+	m, err := asm.ParseFile(path)
+	if err != nil {
+		t.Fatalf("unable to parse %q; %+v", path, err)
+	}
+	in, err := interp.New(m)
+	if err != nil {
+		t.Fatalf("%q: unable to create interpreter; %+v", path, err)
+	}
+	// The return value of external functions is converted to the return type of
+	// the function declaration.
+	in.Externals["square"] = func(in *interp.Interpreter, args []constant.Constant) (constant.Constant, error) {
+		x := args[0].(*constant.Int).X.Int64()
+		return constant.NewInt(types.I32, x*x), nil
+	}
+	in.Externals["greeting"] = func(in *interp.Interpreter, args []constant.Constant) (constant.Constant, error) {
+		const s = "hello\x00"
+		addr := in.Alloc(uint64(len(s)), 1)
+		if err := in.WriteBytes(addr, []byte(s)); err != nil {
+			return nil, err
+		}
+		return interp.NewPointer(types.I8Ptr, addr), nil
+	}
+	stdout := &strings.Builder{}
+	in.Stdout = stdout
+	exit, err := in.RunMain(nil)
+	if err != nil {
+		t.Fatalf("%q: unable to run main; %+v", path, err)
+	}
+	if exit != 144 {
+		t.Errorf("%q: exit code mismatch; expected %d, got %d", path, 144, exit)
+	}
+	if got, want := stdout.String(), "hello\n"; got != want {
+		t.Errorf("%q: output mismatch; expected %q, got %q", path, want, got)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	golden := []struct {
+		path string
+		// Function name.
+		name string
+		args []constant.Constant
+		// Error message.
+		err string
+	}{
+		{path: "testdata/errors.ll", name: "div_zero", args: []constant.Constant{constant.NewInt(types.I32, 1)}, err: `unable to execute "%y = sdiv i32 %x, 0" in function @div_zero: division by zero; invalid operands "i32 1" and "i32 0"`},
+		{path: "testdata/errors.ll", name: "out_of_bounds", err: `unable to execute "%x = load i32, i32* %p" in function @out_of_bounds: invalid 4-byte memory access at address 0x100F0`},
+		{path: "testdata/errors.ll", name: "null_deref", err: `unable to execute "%x = load i32, i32* null" in function @null_deref: invalid 4-byte memory access at address 0x0`},
+		{path: "testdata/errors.ll", name: "use_after_free", err: `unable to execute "%x = load i8, i8* %p" in function @use_after_free: invalid 1-byte memory access at address 0x100E0; use after free`},
+		{path: "testdata/errors.ll", name: "double_free", err: `unable to execute "call void @free(i8* %p)" in function @double_free: error in call to external function @free: invalid free of address 0x100E0; double free`},
+		{path: "testdata/errors.ll", name: "unreachable", err: `unable to execute "unreachable" in function @unreachable: unreachable executed`},
+		{path: "testdata/errors.ll", name: "call_missing", err: `unable to execute "call void @missing()" in function @call_missing: unable to call external function @missing; no implementation provided`},
+		{path: "testdata/errors.ll", name: "branch_poison", err: `unable to execute "br i1 poison, label %exit, label %exit" in function @branch_poison: branch on poison value "i1 poison"`},
+		{path: "testdata/errors.ll", name: "recurse", args: []constant.Constant{constant.NewInt(types.I32, 1)}, err: "maximum call depth 10000 exceeded in call to function @recurse: stack overflow"},
+		{path: "testdata/errors.ll", name: "main", err: "exit status 3"},
+	}
+	for _, g := range golden {
+		m, err := asm.ParseFile(g.path)
+		if err != nil {
+			t.Errorf("unable to parse %q; %+v", g.path, err)
+			continue
+		}
+		in, err := interp.New(m)
+		if err != nil {
+			t.Errorf("%q: unable to create interpreter; %+v", g.path, err)
+			continue
+		}
+		f := findFunc(m, g.name)
+		if f == nil {
+			t.Errorf("%q: unable to locate function @%s", g.path, g.name)
+			continue
+		}
+		_, err = in.Call(f, g.args...)
+		if err == nil {
+			t.Errorf("%q: expected error in call to function %s, got nil", g.path, f.Ident())
+			continue
+		}
+		if got := err.Error(); got != g.err {
+			t.Errorf("%q: error mismatch; expected %q, got %q", g.path, g.err, got)
+		}
+	}
+}
+
+// ### [ Helper functions ] ####################################################
+
+// findFunc returns the function of the given name in m; or nil if not present.
+func findFunc(m *ir.Module, name string) *ir.Func {
+	for _, f := range m.Funcs {
+		if f.Name() == name {
+			return f
+		}
+	}
+	return nil
+}
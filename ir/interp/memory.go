@@ -0,0 +1,418 @@
+package interp
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/datalayout"
+	"github.com/llir/llvm/ir/types"
+	"github.com/pkg/errors"
+)
+
+// === [ Pointers ] ============================================================
+
+// Pointer is a pointer value of the interpreter; an address in the memory of
+// the interpreter.
+type Pointer struct {
+	// Pointer type.
+	Typ *types.PointerType
+	// Address.
+	Addr uint64
+}
+
+// NewPointer returns a new pointer value based on the given pointer type and
+// address.
+func NewPointer(typ *types.PointerType, addr uint64) *Pointer {
+	return &Pointer{Typ: typ, Addr: addr}
+}
+
+// String returns the LLVM syntax representation of the pointer as a type-value
+// pair.
+func (p *Pointer) String() string {
+	return fmt.Sprintf("%s %s", p.Type(), p.Ident())
+}
+
+// Type returns the type of the pointer.
+func (p *Pointer) Type() types.Type {
+	return p.Typ
+}
+
+// Ident returns the identifier associated with the pointer.
+func (p *Pointer) Ident() string {
+	if p.Addr == 0 {
+		return "null"
+	}
+	return fmt.Sprintf("inttoptr (i64 %d to %s)", p.Addr, p.Typ)
+}
+
+// IsConstant ensures that only constants can be assigned to the
+// constant.Constant interface.
+func (*Pointer) IsConstant() {}
+
+// AddrOf returns the address of the given pointer value; e.g. 0 for null
+// pointers. The boolean return value indicates success.
+func AddrOf(c constant.Constant) (uint64, bool) {
+	if _, ok := c.Type().(*types.PointerType); !ok {
+		return 0, false
+	}
+	switch c := c.(type) {
+	case *Pointer:
+		return c.Addr, true
+	case *constant.Null, *constant.ZeroInitializer, *constant.Undef:
+		return 0, true
+	}
+	return 0, false
+}
+
+// === [ Memory ] ==============================================================
+
+const (
+	// Address of the first allocation; lower addresses (e.g. null) are never
+	// valid.
+	memStart = 0x10000
+	// Number of unallocated bytes between consecutive allocations, to detect
+	// out-of-bounds accesses past the end of allocations.
+	memGap = 16
+)
+
+// memory is the memory of an interpreter; a set of disjoint allocations in a
+// flat address space.
+type memory struct {
+	// Allocations, sorted by address.
+	allocs []*allocation
+	// Lowest address of the next allocation.
+	next uint64
+}
+
+// allocation is a contiguous region of memory.
+type allocation struct {
+	// Start address.
+	addr uint64
+	// Contents.
+	data []byte
+	// Function located at the address; or nil if the allocation holds data.
+	f *ir.Func
+	// Specifies whether the allocation has been freed.
+	freed bool
+}
+
+// alloc allocates size bytes of zeroed memory, aligned to align bytes.
+func (m *memory) alloc(size, align uint64) *allocation {
+	if m.next == 0 {
+		m.next = memStart
+	}
+	if align == 0 {
+		align = 1
+	}
+	addr := (m.next + align - 1) / align * align
+	a := &allocation{addr: addr, data: make([]byte, size)}
+	m.allocs = append(m.allocs, a)
+	m.next = addr + size + memGap
+	return a
+}
+
+// find returns the allocation containing the given address; or nil if not
+// present.
+func (m *memory) find(addr uint64) *allocation {
+	i := sort.Search(len(m.allocs), func(i int) bool {
+		return m.allocs[i].addr > addr
+	})
+	if i == 0 {
+		return nil
+	}
+	a := m.allocs[i-1]
+	if addr-a.addr > uint64(len(a.data)) {
+		return nil
+	}
+	return a
+}
+
+// access returns the size bytes of memory at the given address.
+func (m *memory) access(addr, size uint64) ([]byte, error) {
+	a := m.find(addr)
+	if a == nil || a.f != nil || addr-a.addr+size > uint64(len(a.data)) {
+		return nil, errors.Errorf("invalid %d-byte memory access at address 0x%X", size, addr)
+	}
+	if a.freed {
+		return nil, errors.Errorf("invalid %d-byte memory access at address 0x%X; use after free", size, addr)
+	}
+	start := addr - a.addr
+	return a.data[start : start+size], nil
+}
+
+// free frees the allocation at the given address.
+func (m *memory) free(addr uint64) error {
+	a := m.find(addr)
+	if a == nil || a.addr != addr || a.f != nil {
+		return errors.Errorf("invalid free of address 0x%X", addr)
+	}
+	if a.freed {
+		return errors.Errorf("invalid free of address 0x%X; double free", addr)
+	}
+	a.freed = true
+	return nil
+}
+
+// --- [ Memory access ] -------------------------------------------------------
+
+// Alloc allocates size bytes of zeroed memory, aligned to align bytes, and
+// returns its address.
+func (in *Interpreter) Alloc(size, align uint64) uint64 {
+	return in.mem.alloc(size, align).addr
+}
+
+// Free frees the memory allocated at the given address.
+func (in *Interpreter) Free(addr uint64) error {
+	return in.mem.free(addr)
+}
+
+// ReadBytes returns a copy of the n bytes of memory at the given address.
+func (in *Interpreter) ReadBytes(addr, n uint64) ([]byte, error) {
+	buf, err := in.mem.access(addr, n)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return append([]byte(nil), buf...), nil
+}
+
+// WriteBytes writes the given bytes to memory at the given address.
+func (in *Interpreter) WriteBytes(addr uint64, b []byte) error {
+	buf, err := in.mem.access(addr, uint64(len(b)))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	copy(buf, b)
+	return nil
+}
+
+// ReadString returns the NUL-terminated string at the given address, without
+// the terminating NUL byte.
+func (in *Interpreter) ReadString(addr uint64) (string, error) {
+	var s []byte
+	for {
+		buf, err := in.mem.access(addr+uint64(len(s)), 1)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		if buf[0] == 0 {
+			return string(s), nil
+		}
+		s = append(s, buf[0])
+	}
+}
+
+// Load loads a value of the given type from memory at the given address.
+func (in *Interpreter) Load(addr uint64, t types.Type) (constant.Constant, error) {
+	buf, err := in.mem.access(addr, in.dl.StoreSizeOf(t))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return in.decode(buf, t)
+}
+
+// Store stores the given value to memory at the given address.
+func (in *Interpreter) Store(addr uint64, v constant.Constant) error {
+	t := v.Type()
+	buf, err := in.mem.access(addr, in.dl.StoreSizeOf(t))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return in.encode(buf, v, t)
+}
+
+// --- [ Encoding ] ------------------------------------------------------------
+
+// encode encodes the given value of type t into buf, using the in-memory
+// representation of the data layout. The length of buf is the store size of t.
+func (in *Interpreter) encode(buf []byte, v constant.Constant, t types.Type) error {
+	// Poison values are stored as zero.
+	if _, ok := v.(*constant.Poison); ok {
+		v = zeroOf(t)
+	}
+	switch t := t.(type) {
+	case *types.IntType:
+		x, ok := intOf(v)
+		if !ok {
+			return errors.Errorf("unable to store value %q of integer type", v)
+		}
+		in.putUint(buf, unsigned(x))
+	case *types.FloatType:
+		bits := constant.FoldBitCast(v, types.NewInt(in.dl.BitSizeOf(t)))
+		x, ok := bits.(*constant.Int)
+		if !ok {
+			return errors.Errorf("unable to store value %q of floating-point type", v)
+		}
+		in.putUint(buf, unsigned(x))
+	case *types.PointerType:
+		addr, ok := AddrOf(v)
+		if !ok {
+			return errors.Errorf("unable to store value %q of pointer type", v)
+		}
+		in.putUint(buf, new(big.Int).SetUint64(addr))
+	case *types.VectorType:
+		elemSize := in.dl.BitSizeOf(t.ElemType)
+		if elemSize%8 == 0 {
+			for i := uint64(0); i < t.Len; i++ {
+				elem := constant.FoldExtractElement(v, constant.NewInt(types.I64, int64(i)))
+				start := i * elemSize / 8
+				if err := in.encode(buf[start:start+elemSize/8], elem, t.ElemType); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+			return nil
+		}
+		// Vectors of elements which are not byte-sized (e.g. <8 x i1>) are bit
+		// packed, with the first element at the lowest address in memory.
+		packed := new(big.Int)
+		for i := uint64(0); i < t.Len; i++ {
+			elem := constant.FoldExtractElement(v, constant.NewInt(types.I64, int64(i)))
+			if _, ok := elem.(*constant.Poison); ok {
+				continue
+			}
+			x, ok := intOf(elem)
+			if !ok {
+				return errors.Errorf("unable to store element %q of vector type", elem)
+			}
+			pos := i
+			if in.dl.Endianness == datalayout.BigEndian {
+				pos = t.Len - 1 - i
+			}
+			packed.Or(packed, new(big.Int).Lsh(unsigned(x), uint(pos*elemSize)))
+		}
+		in.putUint(buf, packed)
+	case *types.ArrayType:
+		elemSize := in.dl.StoreSizeOf(t.ElemType)
+		stride := in.dl.SizeOf(t.ElemType)
+		for i := uint64(0); i < t.Len; i++ {
+			elem := constant.FoldExtractValue(v, []uint64{i})
+			if elem == nil {
+				return errors.Errorf("unable to store value %q of array type", v)
+			}
+			if err := in.encode(buf[i*stride:i*stride+elemSize], elem, t.ElemType); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	case *types.StructType:
+		layout := in.dl.StructLayout(t)
+		for i, field := range t.Fields {
+			elem := constant.FoldExtractValue(v, []uint64{uint64(i)})
+			if elem == nil {
+				return errors.Errorf("unable to store value %q of struct type", v)
+			}
+			start := layout.Offsets[i]
+			if err := in.encode(buf[start:start+in.dl.StoreSizeOf(field)], elem, field); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	default:
+		return errors.Errorf("support for storing value of type %q not yet implemented", t)
+	}
+	return nil
+}
+
+// decode decodes a value of type t from buf, using the in-memory
+// representation of the data layout. The length of buf is the store size of t.
+func (in *Interpreter) decode(buf []byte, t types.Type) (constant.Constant, error) {
+	switch t := t.(type) {
+	case *types.IntType:
+		return newInt(t, in.getUint(buf)), nil
+	case *types.FloatType:
+		bits := newInt(types.NewInt(in.dl.BitSizeOf(t)), in.getUint(buf))
+		x := constant.FoldBitCast(bits, t)
+		if x == nil {
+			return nil, errors.Errorf("unable to load value of floating-point type %q", t)
+		}
+		return x, nil
+	case *types.PointerType:
+		return NewPointer(t, in.getUint(buf).Uint64()), nil
+	case *types.VectorType:
+		elems := make([]constant.Constant, t.Len)
+		elemSize := in.dl.BitSizeOf(t.ElemType)
+		if elemSize%8 == 0 {
+			for i := range elems {
+				start := uint64(i) * elemSize / 8
+				elem, err := in.decode(buf[start:start+elemSize/8], t.ElemType)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				elems[i] = elem
+			}
+			return constant.NewVector(t, elems...), nil
+		}
+		elemType, ok := t.ElemType.(*types.IntType)
+		if !ok {
+			return nil, errors.Errorf("support for loading value of vector type %q not yet implemented", t)
+		}
+		packed := in.getUint(buf)
+		for i := range elems {
+			pos := uint64(i)
+			if in.dl.Endianness == datalayout.BigEndian {
+				pos = t.Len - 1 - uint64(i)
+			}
+			elems[i] = newInt(elemType, new(big.Int).Rsh(packed, uint(pos*elemSize)))
+		}
+		return constant.NewVector(t, elems...), nil
+	case *types.ArrayType:
+		elems := make([]constant.Constant, t.Len)
+		elemSize := in.dl.StoreSizeOf(t.ElemType)
+		stride := in.dl.SizeOf(t.ElemType)
+		for i := range elems {
+			start := uint64(i) * stride
+			elem, err := in.decode(buf[start:start+elemSize], t.ElemType)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			elems[i] = elem
+		}
+		return constant.NewArray(t, elems...), nil
+	case *types.StructType:
+		layout := in.dl.StructLayout(t)
+		fields := make([]constant.Constant, len(t.Fields))
+		for i, fieldType := range t.Fields {
+			start := layout.Offsets[i]
+			field, err := in.decode(buf[start:start+in.dl.StoreSizeOf(fieldType)], fieldType)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			fields[i] = field
+		}
+		return constant.NewStruct(t, fields...), nil
+	default:
+		return nil, errors.Errorf("support for loading value of type %q not yet implemented", t)
+	}
+}
+
+// putUint stores the unsigned integer x into buf, in the byte order of the
+// data layout. Bits of x which do not fit into buf are discarded.
+func (in *Interpreter) putUint(buf []byte, x *big.Int) {
+	b := x.Bytes()
+	for i := range buf {
+		var v byte
+		if i < len(b) {
+			v = b[len(b)-1-i]
+		}
+		// Little-endian byte index i holds the i:th least significant byte.
+		if in.dl.Endianness == datalayout.BigEndian {
+			buf[len(buf)-1-i] = v
+		} else {
+			buf[i] = v
+		}
+	}
+}
+
+// getUint returns the unsigned integer stored in buf, in the byte order of the
+// data layout.
+func (in *Interpreter) getUint(buf []byte) *big.Int {
+	b := make([]byte, len(buf))
+	for i := range buf {
+		if in.dl.Endianness == datalayout.BigEndian {
+			b[i] = buf[i]
+		} else {
+			b[len(b)-1-i] = buf[i]
+		}
+	}
+	return new(big.Int).SetBytes(b)
+}
@@ -0,0 +1,113 @@
+; Integer arithmetic, control flow and calls.
+
+target datalayout = "e-m:e-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128"
+target triple = "x86_64-unknown-linux-gnu"
+
+@.fmt = private unnamed_addr constant [24 x i8] c"%s: %d %u %x %ld %5.3d\0A\00"
+@.fact = private unnamed_addr constant [5 x i8] c"fact\00"
+@.fib = private unnamed_addr constant [4 x i8] c"fib\00"
+@.div = private unnamed_addr constant [4 x i8] c"div\00"
+@.sw = private unnamed_addr constant [7 x i8] c"switch\00"
+
+declare i32 @printf(i8*, ...)
+
+define i64 @fact(i64 %n) {
+entry:
+	%cond = icmp sle i64 %n, 1
+	br i1 %cond, label %base, label %rec
+
+base:
+	ret i64 1
+
+rec:
+	%n1 = sub nsw i64 %n, 1
+	%r = call i64 @fact(i64 %n1)
+	%res = mul i64 %n, %r
+	ret i64 %res
+}
+
+define i32 @fib(i32 %n) {
+entry:
+	br label %loop
+
+loop:
+	%i = phi i32 [ 0, %entry ], [ %i1, %loop ]
+	%a = phi i32 [ 0, %entry ], [ %b, %loop ]
+	%b = phi i32 [ 1, %entry ], [ %c, %loop ]
+	%c = add i32 %a, %b
+	%i1 = add i32 %i, 1
+	%done = icmp eq i32 %i1, %n
+	br i1 %done, label %exit, label %loop
+
+exit:
+	ret i32 %b
+}
+
+define i32 @classify(i32 %x) {
+entry:
+	switch i32 %x, label %default [
+		i32 0, label %zero
+		i32 1, label %one
+		i32 -7, label %neg
+	]
+
+zero:
+	ret i32 100
+
+one:
+	ret i32 101
+
+neg:
+	ret i32 102
+
+default:
+	%big = icmp sgt i32 %x, 10
+	%r = select i1 %big, i32 200, i32 201
+	ret i32 %r
+}
+
+define void @print(i8* %name, i64 %x) {
+	%fmt = getelementptr [24 x i8], [24 x i8]* @.fmt, i64 0, i64 0
+	%x32 = trunc i64 %x to i32
+	%x8 = trunc i64 %x to i8
+	%x8s = sext i8 %x8 to i32
+	call i32 (i8*, ...) @printf(i8* %fmt, i8* %name, i32 %x32, i32 %x32, i32 %x32, i64 %x, i32 %x8s)
+	ret void
+}
+
+define i32 @main() {
+	%fact = call i64 @fact(i64 20)
+	call void @print(i8* getelementptr ([5 x i8], [5 x i8]* @.fact, i64 0, i64 0), i64 %fact)
+	%fib = call i32 @fib(i32 40)
+	%fib64 = zext i32 %fib to i64
+	call void @print(i8* getelementptr ([4 x i8], [4 x i8]* @.fib, i64 0, i64 0), i64 %fib64)
+	%q = sdiv i32 -17, 5
+	%r = srem i32 -17, 5
+	%u = udiv i32 -17, 5
+	%s = ashr i32 -17, 2
+	%l = lshr i32 -17, 28
+	%t1 = add i32 %q, %r
+	%t2 = xor i32 %t1, %u
+	%t3 = or i32 %t2, %s
+	%t4 = and i32 %t3, %l
+	%t5 = shl i32 %t4, 3
+	%t = sext i32 %t5 to i64
+	call void @print(i8* getelementptr ([4 x i8], [4 x i8]* @.div, i64 0, i64 0), i64 %t)
+	%c0 = call i32 @classify(i32 0)
+	%c1 = call i32 @classify(i32 1)
+	%c2 = call i32 @classify(i32 -7)
+	%c3 = call i32 @classify(i32 11)
+	%c4 = call i32 @classify(i32 5)
+	%s1 = mul i32 %c0, 10000
+	%s2 = mul i32 %c1, 1000
+	%s3 = mul i32 %c2, 100
+	%s4 = mul i32 %c3, 10
+	%a1 = add i32 %s1, %s2
+	%a2 = add i32 %a1, %s3
+	%a3 = add i32 %a2, %s4
+	%a4 = add i32 %a3, %c4
+	%a = sext i32 %a4 to i64
+	call void @print(i8* getelementptr ([7 x i8], [7 x i8]* @.sw, i64 0, i64 0), i64 %a)
+	%ret = urem i32 %a4, 256
+	ret i32 %ret
+}
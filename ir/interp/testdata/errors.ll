@@ -0,0 +1,64 @@
+; Undefined behaviour and other runtime errors.
+
+declare i8* @malloc(i64)
+declare void @free(i8*)
+declare void @exit(i32)
+declare void @missing()
+
+define i32 @div_zero(i32 %x) {
+	%y = sdiv i32 %x, 0
+	ret i32 %y
+}
+
+define i32 @out_of_bounds() {
+	%a = alloca [4 x i32]
+	%p = getelementptr [4 x i32], [4 x i32]* %a, i64 0, i64 4
+	%x = load i32, i32* %p
+	ret i32 %x
+}
+
+define i32 @null_deref() {
+	%x = load i32, i32* null
+	ret i32 %x
+}
+
+define i8 @use_after_free() {
+	%p = call i8* @malloc(i64 8)
+	call void @free(i8* %p)
+	%x = load i8, i8* %p
+	ret i8 %x
+}
+
+define void @double_free() {
+	%p = call i8* @malloc(i64 8)
+	call void @free(i8* %p)
+	call void @free(i8* %p)
+	ret void
+}
+
+define void @unreachable() {
+	unreachable
+}
+
+define void @call_missing() {
+	call void @missing()
+	ret void
+}
+
+define i1 @branch_poison() {
+entry:
+	br i1 poison, label %exit, label %exit
+
+exit:
+	ret i1 true
+}
+
+define i32 @recurse(i32 %x) {
+	%y = call i32 @recurse(i32 %x)
+	ret i32 %y
+}
+
+define i32 @main() {
+	call void @exit(i32 3)
+	ret i32 0
+}
@@ -0,0 +1,13 @@
+; Calls to user-provided external functions.
+
+declare i64 @square(i32)
+declare i8* @greeting()
+declare i32 @puts(i8*)
+
+define i32 @main() {
+	%x = call i64 @square(i32 -12)
+	%msg = call i8* @greeting()
+	call i32 @puts(i8* %msg)
+	%r = trunc i64 %x to i32
+	ret i32 %r
+}
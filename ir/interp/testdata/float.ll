@@ -0,0 +1,91 @@
+; Floating-point arithmetic, conversions and formatting.
+
+target datalayout = "e-m:e-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128"
+target triple = "x86_64-unknown-linux-gnu"
+
+@.fmt.f = private unnamed_addr constant [22 x i8] c"%f %e %g %.2f %10.3f\0A\00"
+@.fmt.i = private unnamed_addr constant [16 x i8] c"%d %u %lld %#x\0A\00"
+@.fmt.s = private unnamed_addr constant [21 x i8] c"%f %f %f %G %-6.1f|\0A\00"
+
+@pi = global double 0x400921FB54442D18
+@half = global float 5.000000e-01
+@ext = global x86_fp80 0xK4000C90FDAA22168C235
+
+declare i32 @printf(i8*, ...)
+declare double @llvm.fabs.f64(double)
+
+define void @print(double %x) {
+	%a = fmul double %x, 1.000000e+03
+	%b = fdiv double %x, 3.000000e+00
+	%c = frem double %a, 7.000000e+00
+	%d = fneg double %x
+	call i32 (i8*, ...) @printf(i8* getelementptr ([22 x i8], [22 x i8]* @.fmt.f, i64 0, i64 0), double %x, double %a, double %b, double %c, double %d)
+	ret void
+}
+
+; newton returns the square root of x, by Newton's method.
+define double @newton(double %x) {
+entry:
+	br label %loop
+
+loop:
+	%g = phi double [ %x, %entry ], [ %g1, %loop ]
+	%i = phi i32 [ 0, %entry ], [ %i1, %loop ]
+	%q = fdiv double %x, %g
+	%s = fadd double %g, %q
+	%g1 = fmul double %s, 5.000000e-01
+	%i1 = add i32 %i, 1
+	%done = icmp eq i32 %i1, 20
+	br i1 %done, label %exit, label %loop
+
+exit:
+	ret double %g1
+}
+
+define i32 @main() {
+	%pi = load double, double* @pi
+	call void @print(double %pi)
+	%half = load float, float* @half
+	%halfd = fpext float %half to double
+	%third = fsub double %halfd, 0x3FC5555555555555
+	call void @print(double %third)
+	%sqrt2 = call double @newton(double 2.000000e+00)
+	call void @print(double %sqrt2)
+	%ext = load x86_fp80, x86_fp80* @ext
+	%extd = fptrunc x86_fp80 %ext to double
+	call void @print(double %extd)
+	%big = fmul double %pi, 1.000000e+20
+	call void @print(double %big)
+	%tiny = fdiv double %pi, 1.000000e+20
+	call void @print(double %tiny)
+	; Conversions to integers.
+	%neg = fneg double %pi
+	%i = fptosi double %neg to i32
+	%u = fptoui double %big to i64
+	%bits = bitcast double %pi to i64
+	%fbits = bitcast float %half to i32
+	call i32 (i8*, ...) @printf(i8* getelementptr ([16 x i8], [16 x i8]* @.fmt.i, i64 0, i64 0), i32 %i, i32 %i, i64 %bits, i32 %fbits)
+	; Special values.
+	%inf = fdiv double 1.000000e+00, 0.000000e+00
+	%ninf = fneg double %inf
+	%nan = fsub double %inf, %inf
+	%abs = call double @llvm.fabs.f64(double %ninf)
+	%m = sitofp i32 -7 to double
+	call i32 (i8*, ...) @printf(i8* getelementptr ([21 x i8], [21 x i8]* @.fmt.s, i64 0, i64 0), double %inf, double %ninf, double %abs, double %nan, double %m)
+	; Comparisons.
+	%c1 = fcmp olt double %pi, %sqrt2
+	%c2 = fcmp uno double %nan, %pi
+	%c3 = fcmp oeq double %nan, %nan
+	%c4 = fcmp une double %nan, %nan
+	%z1 = zext i1 %c1 to i32
+	%z2 = zext i1 %c2 to i32
+	%z3 = zext i1 %c3 to i32
+	%z4 = zext i1 %c4 to i32
+	%s1 = shl i32 %z1, 3
+	%s2 = shl i32 %z2, 2
+	%s3 = shl i32 %z3, 1
+	%r1 = or i32 %s1, %s2
+	%r2 = or i32 %r1, %s3
+	%r = or i32 %r2, %z4
+	ret i32 %r
+}
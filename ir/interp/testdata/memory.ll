@@ -0,0 +1,192 @@
+; Memory; global variables, allocas, getelementptr, heap allocation and
+; function pointers.
+
+target datalayout = "e-m:e-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128"
+target triple = "x86_64-unknown-linux-gnu"
+
+%node = type { i32, %node* }
+%pair = type { i8, i64, i16 }
+
+@.fmt.sum = private unnamed_addr constant [12 x i8] c"sum: %d %d\0A\00"
+@.fmt.str = private unnamed_addr constant [13 x i8] c"str: %s %zu\0A\00"
+@.fmt.pair = private unnamed_addr constant [24 x i8] c"pair: %d %lld %d %d %d\0A\00"
+@.fmt.arg = private unnamed_addr constant [15 x i8] c"arg %d: %s %c\0A\00"
+@.fmt.op = private unnamed_addr constant [8 x i8] c"op: %d\0A\00"
+@.fmt.bytes = private unnamed_addr constant [22 x i8] c"bytes: %x %x %hhx %s\0A\00"
+@.hello = private unnamed_addr constant [6 x i8] c"hello\00"
+@.world = private unnamed_addr constant [7 x i8] c" world\00"
+
+@primes = global [5 x i32] [i32 2, i32 3, i32 5, i32 7, i32 11]
+@counter = global i32 0
+@pair = global %pair { i8 -1, i64 1099511627776, i16 -3 }
+@ops = global [2 x i32 (i32, i32)*] [i32 (i32, i32)* @add, i32 (i32, i32)* @mul]
+@last = global i32* getelementptr ([5 x i32], [5 x i32]* @primes, i64 0, i64 4)
+@end = global i64 sub (i64 ptrtoint (i32* getelementptr ([5 x i32], [5 x i32]* @primes, i64 1, i64 0) to i64), i64 ptrtoint ([5 x i32]* @primes to i64))
+@bss = global [16 x i8] zeroinitializer
+
+declare i32 @printf(i8*, ...)
+declare i8* @malloc(i64)
+declare void @free(i8*)
+declare i64 @strlen(i8*)
+declare void @llvm.memcpy.p0i8.p0i8.i64(i8*, i8*, i64, i1)
+declare void @llvm.memset.p0i8.i64(i8*, i8, i64, i1)
+
+define i32 @add(i32 %x, i32 %y) {
+	%z = add i32 %x, %y
+	ret i32 %z
+}
+
+define i32 @mul(i32 %x, i32 %y) {
+	%z = mul i32 %x, %y
+	ret i32 %z
+}
+
+; sum_array returns the sum of the n first elements of the array.
+define i32 @sum_array(i32* %a, i64 %n) {
+entry:
+	%sum = alloca i32
+	%i = alloca i64
+	store i32 0, i32* %sum
+	store i64 0, i64* %i
+	br label %cond
+
+cond:
+	%iv = load i64, i64* %i
+	%c = icmp ult i64 %iv, %n
+	br i1 %c, label %body, label %exit
+
+body:
+	%p = getelementptr inbounds i32, i32* %a, i64 %iv
+	%x = load i32, i32* %p
+	%s = load i32, i32* %sum
+	%s1 = add i32 %s, %x
+	store i32 %s1, i32* %sum
+	%i1 = add i64 %iv, 1
+	store i64 %i1, i64* %i
+	%cnt = atomicrmw add i32* @counter, i32 1 seq_cst
+	br label %cond
+
+exit:
+	%ret = load i32, i32* %sum
+	ret i32 %ret
+}
+
+; build_list returns a linked list of n nodes, with values n-1 to 0.
+define %node* @build_list(i32 %n) {
+entry:
+	br label %loop
+
+loop:
+	%i = phi i32 [ 0, %entry ], [ %i1, %body ]
+	%head = phi %node* [ null, %entry ], [ %new, %body ]
+	%done = icmp eq i32 %i, %n
+	br i1 %done, label %exit, label %body
+
+body:
+	%mem = call i8* @malloc(i64 16)
+	%new = bitcast i8* %mem to %node*
+	%val = getelementptr %node, %node* %new, i32 0, i32 0
+	store i32 %i, i32* %val
+	%next = getelementptr %node, %node* %new, i32 0, i32 1
+	store %node* %head, %node** %next
+	%i1 = add i32 %i, 1
+	br label %loop
+
+exit:
+	ret %node* %head
+}
+
+; sum_list returns the sum of the values of the list, and frees the nodes.
+define i32 @sum_list(%node* %head) {
+entry:
+	br label %loop
+
+loop:
+	%n = phi %node* [ %head, %entry ], [ %next, %body ]
+	%sum = phi i32 [ 0, %entry ], [ %sum1, %body ]
+	%end = icmp eq %node* %n, null
+	br i1 %end, label %exit, label %body
+
+body:
+	%node = load %node, %node* %n
+	%val = extractvalue %node %node, 0
+	%next = extractvalue %node %node, 1
+	%sum1 = add i32 %sum, %val
+	%mem = bitcast %node* %n to i8*
+	call void @free(i8* %mem)
+	br label %loop
+
+exit:
+	ret i32 %sum
+}
+
+define i32 @main(i32 %argc, i8** %argv) {
+entry:
+	; Global arrays.
+	%p = getelementptr [5 x i32], [5 x i32]* @primes, i64 0, i64 0
+	%sum = call i32 @sum_array(i32* %p, i64 5)
+	%cnt = load i32, i32* @counter
+	call i32 (i8*, ...) @printf(i8* getelementptr ([12 x i8], [12 x i8]* @.fmt.sum, i64 0, i64 0), i32 %sum, i32 %cnt)
+	; Heap allocation.
+	%list = call %node* @build_list(i32 100)
+	%lsum = call i32 @sum_list(%node* %list)
+	%last = load i32*, i32** @last
+	%lastv = load i32, i32* %last
+	call i32 (i8*, ...) @printf(i8* getelementptr ([12 x i8], [12 x i8]* @.fmt.sum, i64 0, i64 0), i32 %lsum, i32 %lastv)
+	; Strings.
+	%buf = alloca [32 x i8], align 16
+	%b = getelementptr [32 x i8], [32 x i8]* %buf, i64 0, i64 0
+	call void @llvm.memset.p0i8.i64(i8* %b, i8 120, i64 32, i1 false)
+	call void @llvm.memcpy.p0i8.p0i8.i64(i8* %b, i8* getelementptr ([6 x i8], [6 x i8]* @.hello, i64 0, i64 0), i64 5, i1 false)
+	%b5 = getelementptr i8, i8* %b, i64 5
+	call void @llvm.memcpy.p0i8.p0i8.i64(i8* %b5, i8* getelementptr ([7 x i8], [7 x i8]* @.world, i64 0, i64 0), i64 7, i1 false)
+	%len = call i64 @strlen(i8* %b)
+	call i32 (i8*, ...) @printf(i8* getelementptr ([13 x i8], [13 x i8]* @.fmt.str, i64 0, i64 0), i8* %b, i64 %len)
+	; Structs with padding.
+	%pair = load %pair, %pair* @pair
+	%f0 = extractvalue %pair %pair, 0
+	%f0x = sext i8 %f0 to i32
+	%f1 = extractvalue %pair %pair, 1
+	%f2 = extractvalue %pair %pair, 2
+	%f2x = zext i16 %f2 to i32
+	%end = load i64, i64* @end
+	%endx = trunc i64 %end to i32
+	%size = ptrtoint %pair* getelementptr (%pair, %pair* null, i32 1) to i32
+	call i32 (i8*, ...) @printf(i8* getelementptr ([24 x i8], [24 x i8]* @.fmt.pair, i64 0, i64 0), i32 %f0x, i64 %f1, i32 %f2x, i32 %endx, i32 %size)
+	; Reinterpretation of memory.
+	%tmp = alloca i64
+	store i64 72623859790382856, i64* %tmp
+	%tmp32 = bitcast i64* %tmp to i32*
+	%lo = load i32, i32* %tmp32
+	%hi.p = getelementptr i32, i32* %tmp32, i32 1
+	%hi = load i32, i32* %hi.p
+	%tmp8 = bitcast i64* %tmp to i8*
+	%byte = load i8, i8* %tmp8
+	%bss = getelementptr [16 x i8], [16 x i8]* @bss, i64 0, i64 0
+	call i32 (i8*, ...) @printf(i8* getelementptr ([22 x i8], [22 x i8]* @.fmt.bytes, i64 0, i64 0), i32 %lo, i32 %hi, i8 %byte, i8* %bss)
+	; Function pointers.
+	%op0.p = getelementptr [2 x i32 (i32, i32)*], [2 x i32 (i32, i32)*]* @ops, i64 0, i64 0
+	%op0 = load i32 (i32, i32)*, i32 (i32, i32)** %op0.p
+	%op1.p = getelementptr [2 x i32 (i32, i32)*], [2 x i32 (i32, i32)*]* @ops, i64 0, i64 1
+	%op1 = load i32 (i32, i32)*, i32 (i32, i32)** %op1.p
+	%r0 = call i32 %op0(i32 6, i32 7)
+	%r1 = call i32 %op1(i32 %r0, i32 3)
+	call i32 (i8*, ...) @printf(i8* getelementptr ([8 x i8], [8 x i8]* @.fmt.op, i64 0, i64 0), i32 %r1)
+	; Command line arguments.
+	br label %loop
+
+loop:
+	%i = phi i32 [ 0, %entry ], [ %i1, %loop ]
+	%arg.p = getelementptr i8*, i8** %argv, i32 %i
+	%arg = load i8*, i8** %arg.p
+	%c.p = getelementptr i8, i8* %arg, i32 1
+	%c = load i8, i8* %c.p
+	%c32 = sext i8 %c to i32
+	call i32 (i8*, ...) @printf(i8* getelementptr ([15 x i8], [15 x i8]* @.fmt.arg, i64 0, i64 0), i32 %i, i8* %arg, i32 %c32)
+	%i1 = add i32 %i, 1
+	%done = icmp eq i32 %i1, %argc
+	br i1 %done, label %exit, label %loop
+
+exit:
+	ret i32 %argc
+}
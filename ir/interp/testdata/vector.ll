@@ -0,0 +1,111 @@
+; Vectors, aggregates, atomics and intrinsics.
+
+target datalayout = "e-m:e-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128"
+target triple = "x86_64-unknown-linux-gnu"
+
+%pair = type { i32, float }
+
+@.fmt.v = private unnamed_addr constant [18 x i8] c"<%d, %d, %d, %d>\0A\00"
+@.fmt.m = private unnamed_addr constant [20 x i8] c"mask: %#x %x %d %d\0A\00"
+@.fmt.a = private unnamed_addr constant [20 x i8] c"agg: %d %.1f %d %d\0A\00"
+@.fmt.x = private unnamed_addr constant [22 x i8] c"xchg: %d %d %d %d %d\0A\00"
+
+@vec = global <4 x i32> <i32 1, i32 -2, i32 3, i32 -4>
+@arr = global [4 x i16] [i16 10, i16 20, i16 30, i16 40]
+@lock = global i32 7
+
+declare i32 @printf(i8*, ...)
+declare <4 x i32> @llvm.smax.v4i32(<4 x i32>, <4 x i32>)
+declare i32 @llvm.umin.i32(i32, i32)
+declare i32 @llvm.abs.i32(i32, i1)
+
+define void @print(<4 x i32> %v) {
+	%e0 = extractelement <4 x i32> %v, i32 0
+	%e1 = extractelement <4 x i32> %v, i32 1
+	%e2 = extractelement <4 x i32> %v, i32 2
+	%e3 = extractelement <4 x i32> %v, i64 3
+	call i32 (i8*, ...) @printf(i8* getelementptr ([18 x i8], [18 x i8]* @.fmt.v, i64 0, i64 0), i32 %e0, i32 %e1, i32 %e2, i32 %e3)
+	ret void
+}
+
+define i32 @main() {
+	; Vector arithmetic.
+	%v = load <4 x i32>, <4 x i32>* @vec
+	%sq = mul <4 x i32> %v, %v
+	%sum = add <4 x i32> %sq, <i32 100, i32 200, i32 300, i32 400>
+	call void @print(<4 x i32> %sum)
+	%neg = icmp slt <4 x i32> %v, zeroinitializer
+	%abs.n = sub <4 x i32> zeroinitializer, %v
+	%abs = select <4 x i1> %neg, <4 x i32> %abs.n, <4 x i32> %v
+	call void @print(<4 x i32> %abs)
+	%shuf = shufflevector <4 x i32> %v, <4 x i32> %sum, <4 x i32> <i32 7, i32 0, i32 5, i32 2>
+	call void @print(<4 x i32> %shuf)
+	%ins = insertelement <4 x i32> %shuf, i32 42, i32 1
+	%max = call <4 x i32> @llvm.smax.v4i32(<4 x i32> %ins, <4 x i32> <i32 50, i32 50, i32 0, i32 0>)
+	call void @print(<4 x i32> %max)
+	%sh = ashr <4 x i32> %v, <i32 1, i32 1, i32 2, i32 31>
+	call void @print(<4 x i32> %sh)
+	; Vector memory.
+	%tmp = alloca <4 x i32>
+	store <4 x i32> %max, <4 x i32>* %tmp
+	%tmp.e = bitcast <4 x i32>* %tmp to i32*
+	%p2 = getelementptr i32, i32* %tmp.e, i64 2
+	store i32 -1, i32* %p2
+	%reload = load <4 x i32>, <4 x i32>* %tmp
+	call void @print(<4 x i32> %reload)
+	%arr = bitcast [4 x i16]* @arr to <4 x i16>*
+	%av = load <4 x i16>, <4 x i16>* %arr
+	%aw = sext <4 x i16> %av to <4 x i32>
+	call void @print(<4 x i32> %aw)
+	; Vector of pointers.
+	%base = getelementptr [4 x i16], [4 x i16]* @arr, i64 0, i64 0
+	%ptrs = getelementptr i16, i16* %base, <4 x i64> <i64 3, i64 2, i64 1, i64 0>
+	%p0 = extractelement <4 x i16*> %ptrs, i32 0
+	%x0 = load i16, i16* %p0
+	%x0w = sext i16 %x0 to i32
+	%pi = ptrtoint <4 x i16*> %ptrs to <4 x i64>
+	%pd = sub <4 x i64> %pi, <i64 0, i64 2, i64 4, i64 6>
+	%pdt = trunc <4 x i64> %pd to <4 x i32>
+	%d0 = extractelement <4 x i32> %pdt, i32 0
+	%d3 = extractelement <4 x i32> %pdt, i32 3
+	%dd = sub i32 %d0, %d3
+	; Boolean vectors.
+	%mask = bitcast <4 x i1> %neg to i4
+	%maskw = zext i4 %mask to i32
+	%b8 = bitcast <8 x i1> <i1 1, i1 1, i1 0, i1 0, i1 0, i1 0, i1 0, i1 1> to i8
+	%b8w = zext i8 %b8 to i32
+	call i32 (i8*, ...) @printf(i8* getelementptr ([20 x i8], [20 x i8]* @.fmt.m, i64 0, i64 0), i32 %maskw, i32 %b8w, i32 %x0w, i32 %dd)
+	; Aggregates.
+	%p = insertvalue %pair undef, i32 5, 0
+	%p1 = insertvalue %pair %p, float 2.5, 1
+	%pm = alloca %pair
+	store %pair %p1, %pair* %pm
+	%pl = load %pair, %pair* %pm
+	%f0 = extractvalue %pair %pl, 0
+	%f1 = extractvalue %pair %pl, 1
+	%f1d = fpext float %f1 to double
+	%fr = freeze i32 poison
+	%fz = icmp eq i32 %fr, %fr
+	%fzw = zext i1 %fz to i32
+	%av2 = insertvalue [2 x <2 x i8>] zeroinitializer, <2 x i8> <i8 1, i8 2>, 1
+	%ae = extractvalue [2 x <2 x i8>] %av2, 1
+	%ae1 = extractelement <2 x i8> %ae, i32 1
+	%ae1w = zext i8 %ae1 to i32
+	call i32 (i8*, ...) @printf(i8* getelementptr ([20 x i8], [20 x i8]* @.fmt.a, i64 0, i64 0), i32 %f0, double %f1d, i32 %fzw, i32 %ae1w)
+	; Atomics.
+	%cx1 = cmpxchg i32* @lock, i32 7, i32 8 seq_cst seq_cst
+	%cx1.ok = extractvalue { i32, i1 } %cx1, 1
+	%cx1.okw = zext i1 %cx1.ok to i32
+	%cx2 = cmpxchg i32* @lock, i32 7, i32 9 seq_cst seq_cst
+	%cx2.old = extractvalue { i32, i1 } %cx2, 0
+	%old = atomicrmw xchg i32* @lock, i32 3 seq_cst
+	%old2 = atomicrmw umax i32* @lock, i32 10 seq_cst
+	%old3 = atomicrmw nand i32* @lock, i32 6 seq_cst
+	%final = load i32, i32* @lock
+	call i32 (i8*, ...) @printf(i8* getelementptr ([22 x i8], [22 x i8]* @.fmt.x, i64 0, i64 0), i32 %cx1.okw, i32 %cx2.old, i32 %old, i32 %old3, i32 %final)
+	; Scalar intrinsics.
+	%m1 = call i32 @llvm.umin.i32(i32 -1, i32 17)
+	%m2 = call i32 @llvm.abs.i32(i32 -20, i1 false)
+	%r = add i32 %m1, %m2
+	ret i32 %r
+}
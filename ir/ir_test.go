@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
 	"github.com/llir/llvm/ir/metadata"
 	"github.com/llir/llvm/ir/types"
 	"github.com/llir/llvm/ir/value"
@@ -39,6 +40,72 @@ func TestModuleString(t *testing.T) {
 	}
 }
 
+func TestOpaquePointers(t *testing.T) {
+	m := NewModule()
+	m.OpaquePointers = true
+	g := m.NewGlobalDef("x", constant.NewInt(types.I32, 42))
+	puts := m.NewFunc("puts", types.I32, NewParam("s", types.Ptr))
+	f := m.NewFunc("main", types.I32)
+	entry := f.NewBlock("")
+	a := entry.NewAlloca(types.NewArray(2, types.I32))
+	elem := entry.NewGetElementPtr(a.ElemType, a, constant.NewInt(types.I64, 0), constant.NewInt(types.I64, 1))
+	v := entry.NewLoad(types.I32, g)
+	entry.NewStore(v, elem)
+	entry.NewCall(puts, a)
+	entry.NewRet(v)
+	want := `@x = global i32 42
+
+declare i32 @puts(ptr %s)
+
+define i32 @main() {
+0:
+	%1 = alloca [2 x i32]
+	%2 = getelementptr [2 x i32], ptr %1, i64 0, i64 1
+	%3 = load i32, ptr @x
+	store i32 %3, ptr %2
+	%4 = call i32 @puts(ptr %1)
+	ret i32 %3
+}`
+	got := strings.TrimSpace(m.String())
+	if want != got {
+		t.Errorf("module mismatch; expected `%v`, got `%v`", want, got)
+	}
+}
+
+func TestOpaquePointerCallSig(t *testing.T) {
+	m := NewModule()
+	m.OpaquePointers = true
+	fp := m.NewGlobal("fp", types.Ptr)
+	fp.Linkage = enum.LinkageExternal
+	f := m.NewFunc("main", types.I32, NewParam("s", types.Ptr))
+	entry := f.NewBlock("")
+	callee := entry.NewLoad(types.Ptr, fp)
+	sig := types.NewFunc(types.I32, types.Ptr)
+	sig.Variadic = true
+	call := entry.NewCallSig(sig, callee, f.Params[0], constant.NewInt(types.I32, 42))
+	entry.NewRet(call)
+	want := `@fp = external global ptr
+
+define i32 @main(ptr %s) {
+0:
+	%1 = load ptr, ptr @fp
+	%2 = call i32 (ptr, ...) %1(ptr %s, i32 42)
+	ret i32 %2
+}`
+	got := strings.TrimSpace(m.String())
+	if want != got {
+		t.Errorf("module mismatch; expected `%v`, got `%v`", want, got)
+	}
+	// Calls through opaque pointers which are not functions require an
+	// explicit function signature.
+	defer func() {
+		if e := recover(); e == nil {
+			t.Errorf("expected panic for call through opaque pointer without function signature")
+		}
+	}()
+	entry.NewCall(callee, f.Params[0])
+}
+
 // Assert that each constant implements the constant.Constant interface.
 var (
 	// Constants.
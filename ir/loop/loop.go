@@ -0,0 +1,278 @@
+// Package loop provides natural loop detection and loop nesting information of
+// LLVM IR functions.
+//
+// A natural loop is defined by a header basic block which dominates the source
+// of one or more back edges to the header (latches); the loop contains the
+// header and every basic block which can reach a latch without passing through
+// the header. Loops with the same header are merged. Cycles without a
+// dominating header (irreducible control flow) are not identified as loops.
+package loop
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/cfg"
+	"github.com/llir/llvm/ir/dom"
+)
+
+// === [ Loop information ] ====================================================
+
+// Info holds the natural loops of a function and their nesting.
+//
+// The information is a snapshot of the function at the time of creation; it is
+// not updated as the function is modified.
+type Info struct {
+	// Function of the loop information.
+	Func *ir.Func
+	// Dominator tree of the function.
+	Dom *dom.Tree
+	// Outermost loops of the function, in order of their header in Func.Blocks.
+	TopLevel []*Loop
+	// Innermost loop of each basic block contained in a loop.
+	loopOf map[*ir.Block]*Loop
+}
+
+// New returns the loop information of the function of the given dominator
+// tree.
+func New(tree *dom.Tree) *Info {
+	if tree.IsPost() {
+		panic("unable to compute loop information based on post-dominator tree")
+	}
+	info := &Info{
+		Func:   tree.Func,
+		Dom:    tree,
+		loopOf: make(map[*ir.Block]*Loop),
+	}
+	g := tree.Graph
+	// Visit headers in post-order of the dominator tree, so that inner loops are
+	// discovered before their outer loops.
+	order := tree.PreOrder()
+	for i := len(order) - 1; i >= 0; i-- {
+		header := order[i]
+		var latches []*ir.Block
+		for _, pred := range g.Preds(header) {
+			if tree.Reachable(pred) && tree.Dominates(header, pred) {
+				latches = append(latches, pred)
+			}
+		}
+		if len(latches) == 0 {
+			continue
+		}
+		l := &Loop{Header: header, Latches: latches, graph: g}
+		info.discover(l)
+	}
+	// Record the basic blocks of each loop, and the nesting of loops.
+	for _, block := range info.Func.Blocks {
+		for l := info.loopOf[block]; l != nil; l = l.Parent {
+			l.Blocks = append(l.Blocks, block)
+			if l.blocks == nil {
+				l.blocks = make(map[*ir.Block]bool)
+			}
+			l.blocks[block] = true
+		}
+		if l := info.loopOf[block]; l != nil && l.Header == block {
+			// Loops are recorded in order of their header in Func.Blocks.
+			if l.Parent == nil {
+				info.TopLevel = append(info.TopLevel, l)
+			} else {
+				l.Parent.Children = append(l.Parent.Children, l)
+			}
+		}
+	}
+	var setDepth func(loops []*Loop, depth int)
+	setDepth = func(loops []*Loop, depth int) {
+		for _, l := range loops {
+			l.Depth = depth
+			setDepth(l.Children, depth+1)
+		}
+	}
+	setDepth(info.TopLevel, 1)
+	return info
+}
+
+// discover discovers the basic blocks of the given loop, by walking backwards
+// from its latches to its header. Inner loops encountered along the way are
+// made sub-loops of the given loop.
+func (info *Info) discover(l *Loop) {
+	tree := info.Dom
+	g := tree.Graph
+	info.loopOf[l.Header] = l
+	work := append([]*ir.Block(nil), l.Latches...)
+	for len(work) > 0 {
+		block := work[len(work)-1]
+		work = work[:len(work)-1]
+		sub, ok := info.loopOf[block]
+		if !ok {
+			// Basic block not yet part of any loop.
+			info.loopOf[block] = l
+			for _, pred := range g.Preds(block) {
+				if tree.Reachable(pred) {
+					work = append(work, pred)
+				}
+			}
+			continue
+		}
+		// Basic block part of this loop or of an inner loop; find the outermost
+		// loop discovered so far.
+		for sub.Parent != nil {
+			sub = sub.Parent
+		}
+		if sub == l {
+			continue
+		}
+		// Make inner loop a sub-loop of this loop, and continue from the
+		// predecessors of its header.
+		sub.Parent = l
+		for _, pred := range g.Preds(sub.Header) {
+			if tree.Reachable(pred) && !tree.Dominates(sub.Header, pred) {
+				work = append(work, pred)
+			}
+		}
+	}
+}
+
+// Loops returns all loops of the function in pre-order; every loop precedes its
+// sub-loops.
+func (info *Info) Loops() []*Loop {
+	var loops []*Loop
+	var visit func(ls []*Loop)
+	visit = func(ls []*Loop) {
+		for _, l := range ls {
+			loops = append(loops, l)
+			visit(l.Children)
+		}
+	}
+	visit(info.TopLevel)
+	return loops
+}
+
+// LoopFor returns the innermost loop containing the given basic block; or nil
+// if not contained in a loop.
+func (info *Info) LoopFor(block *ir.Block) *Loop {
+	return info.loopOf[block]
+}
+
+// Depth returns the loop nesting depth of the given basic block; 0 if not
+// contained in a loop.
+func (info *Info) Depth(block *ir.Block) int {
+	if l := info.loopOf[block]; l != nil {
+		return l.Depth
+	}
+	return 0
+}
+
+// IsHeader reports whether the given basic block is the header of a loop.
+func (info *Info) IsHeader(block *ir.Block) bool {
+	l := info.loopOf[block]
+	return l != nil && l.Header == block
+}
+
+// --- [ Loop ] ----------------------------------------------------------------
+
+// Loop is a natural loop.
+type Loop struct {
+	// Header of the loop; dominates every basic block of the loop.
+	Header *ir.Block
+	// Latches of the loop; basic blocks of the loop with a back edge to the
+	// header, in order of Func.Blocks.
+	Latches []*ir.Block
+	// Basic blocks of the loop (including those of sub-loops), in order of
+	// Func.Blocks.
+	Blocks []*ir.Block
+	// Parent loop; or nil if outermost loop.
+	Parent *Loop
+	// Sub-loops, in order of their header in Func.Blocks.
+	Children []*Loop
+	// Loop nesting depth; 1 for outermost loops.
+	Depth int
+
+	// Basic blocks of the loop.
+	blocks map[*ir.Block]bool
+	// Control flow graph of the function.
+	graph *cfg.Graph
+}
+
+// Contains reports whether the given basic block is part of the loop (or of a
+// sub-loop).
+func (l *Loop) Contains(block *ir.Block) bool {
+	return l.blocks[block]
+}
+
+// ContainsLoop reports whether the given loop is l or nested within l.
+func (l *Loop) ContainsLoop(sub *Loop) bool {
+	for ; sub != nil; sub = sub.Parent {
+		if sub == l {
+			return true
+		}
+	}
+	return false
+}
+
+// Latch returns the unique latch of the loop; or nil if the loop has multiple
+// latches.
+func (l *Loop) Latch() *ir.Block {
+	if len(l.Latches) != 1 {
+		return nil
+	}
+	return l.Latches[0]
+}
+
+// ExitingBlocks returns the basic blocks of the loop with successors outside
+// of the loop, in order of Func.Blocks.
+func (l *Loop) ExitingBlocks() []*ir.Block {
+	var blocks []*ir.Block
+	for _, block := range l.Blocks {
+		for _, succ := range l.graph.Succs(block) {
+			if !l.Contains(succ) {
+				blocks = append(blocks, block)
+				break
+			}
+		}
+	}
+	return blocks
+}
+
+// ExitBlocks returns the unique basic blocks outside of the loop which are
+// successors of basic blocks of the loop, in order of discovery.
+func (l *Loop) ExitBlocks() []*ir.Block {
+	var blocks []*ir.Block
+	seen := make(map[*ir.Block]bool)
+	for _, block := range l.Blocks {
+		for _, succ := range l.graph.Succs(block) {
+			if !l.Contains(succ) && !seen[succ] {
+				seen[succ] = true
+				blocks = append(blocks, succ)
+			}
+		}
+	}
+	return blocks
+}
+
+// Preheader returns the preheader of the loop; i.e. the unique predecessor of
+// the header outside of the loop, provided that its only successor is the
+// header. Nil is returned if the loop has no preheader.
+func (l *Loop) Preheader() *ir.Block {
+	var preheader *ir.Block
+	for _, pred := range l.graph.Preds(l.Header) {
+		if l.Contains(pred) {
+			continue
+		}
+		if preheader != nil {
+			// Multiple predecessors outside of the loop.
+			return nil
+		}
+		preheader = pred
+	}
+	if preheader == nil {
+		return nil
+	}
+	if succs := l.graph.Succs(preheader); len(succs) != 1 {
+		return nil
+	}
+	return preheader
+}
+
+// String returns a string representation of the loop, as identified by its
+// header.
+func (l *Loop) String() string {
+	return "loop " + l.Header.Ident()
+}
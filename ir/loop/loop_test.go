@@ -0,0 +1,142 @@
+package loop_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/llir/llvm/asm"
+	"github.com/llir/llvm/internal/irtest"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/cfg"
+	"github.com/llir/llvm/ir/dom"
+	"github.com/llir/llvm/ir/loop"
+)
+
+func TestInfo(t *testing.T) {
+	m, err := asm.ParseFile("testdata/loop.ll")
+	if err != nil {
+		t.Fatalf("unable to parse module; %+v", err)
+	}
+	f := m.Funcs[0]
+	blocks := make(map[string]*ir.Block)
+	for _, block := range f.Blocks {
+		blocks[block.Name()] = block
+	}
+	info := loop.New(dom.New(cfg.New(f)))
+	golden := []struct {
+		header                                    string
+		parent                                    string
+		depth                                     int
+		blocks, latches, exiting, exits, children string
+		preheader                                 string
+	}{
+		{
+			header:    "outer",
+			parent:    "<nil>",
+			depth:     1,
+			blocks:    "[%outer %inner %inner.body %outer.latch]",
+			latches:   "[%outer.latch]",
+			exiting:   "[%inner.body %outer.latch]",
+			exits:     "[%exit]",
+			children:  "[loop %inner]",
+			preheader: "%entry",
+		},
+		{
+			header:    "inner",
+			parent:    "loop %outer",
+			depth:     2,
+			blocks:    "[%inner %inner.body]",
+			latches:   "[%inner.body]",
+			exiting:   "[%inner %inner.body]",
+			exits:     "[%outer.latch %exit]",
+			children:  "[]",
+			preheader: "%outer",
+		},
+		// Self loop with multiple predecessors outside of the loop.
+		{
+			header:    "self",
+			parent:    "<nil>",
+			depth:     1,
+			blocks:    "[%self]",
+			latches:   "[%self]",
+			exiting:   "[%self]",
+			exits:     "[%ret]",
+			children:  "[]",
+			preheader: "<nil>",
+		},
+	}
+	loops := info.Loops()
+	if len(loops) != len(golden) {
+		t.Fatalf("number of loops mismatch; expected %d, got %d", len(golden), len(loops))
+	}
+	for i, gold := range golden {
+		l := loops[i]
+		if got := l.Header.Name(); got != gold.header {
+			t.Errorf("loop %d: header mismatch; expected %%%s, got %%%s", i, gold.header, got)
+			continue
+		}
+		if got := str(l.Parent); got != gold.parent {
+			t.Errorf("%v: parent mismatch; expected %s, got %s", l, gold.parent, got)
+		}
+		if l.Depth != gold.depth {
+			t.Errorf("%v: depth mismatch; expected %d, got %d", l, gold.depth, l.Depth)
+		}
+		if got := irtest.Idents(l.Blocks); got != gold.blocks {
+			t.Errorf("%v: blocks mismatch; expected %s, got %s", l, gold.blocks, got)
+		}
+		if got := irtest.Idents(l.Latches); got != gold.latches {
+			t.Errorf("%v: latches mismatch; expected %s, got %s", l, gold.latches, got)
+		}
+		if got := irtest.Idents(l.ExitingBlocks()); got != gold.exiting {
+			t.Errorf("%v: exiting blocks mismatch; expected %s, got %s", l, gold.exiting, got)
+		}
+		if got := irtest.Idents(l.ExitBlocks()); got != gold.exits {
+			t.Errorf("%v: exit blocks mismatch; expected %s, got %s", l, gold.exits, got)
+		}
+		if got := fmt.Sprintf("%v", l.Children); got != gold.children {
+			t.Errorf("%v: children mismatch; expected %s, got %s", l, gold.children, got)
+		}
+		preheader := "<nil>"
+		if p := l.Preheader(); p != nil {
+			preheader = p.Ident()
+		}
+		if preheader != gold.preheader {
+			t.Errorf("%v: preheader mismatch; expected %s, got %s", l, gold.preheader, preheader)
+		}
+	}
+
+	// Innermost loop and depth of basic blocks.
+	depths := map[string]int{
+		"entry":       0,
+		"outer":       1,
+		"inner":       2,
+		"inner.body":  2,
+		"outer.latch": 1,
+		"exit":        0,
+		"self":        1,
+		"other":       0,
+		"ret":         0,
+	}
+	for name, want := range depths {
+		if got := info.Depth(blocks[name]); got != want {
+			t.Errorf("depth of %%%s mismatch; expected %d, got %d", name, want, got)
+		}
+	}
+	if got := info.LoopFor(blocks["inner.body"]); got == nil || got.Header != blocks["inner"] {
+		t.Errorf("innermost loop of %%inner.body mismatch; expected loop %%inner, got %v", got)
+	}
+	if !info.IsHeader(blocks["outer"]) || info.IsHeader(blocks["outer.latch"]) {
+		t.Errorf("loop header mismatch")
+	}
+	if outer, inner := loops[0], loops[1]; !outer.ContainsLoop(inner) || inner.ContainsLoop(outer) {
+		t.Errorf("loop nesting mismatch")
+	}
+}
+
+// str returns a string representation of the given loop.
+func str(l *loop.Loop) string {
+	if l == nil {
+		return "<nil>"
+	}
+	return l.String()
+}
@@ -0,0 +1,28 @@
+define void @f(i1 %cond) {
+entry:
+	br label %outer
+
+outer:
+	br label %inner
+
+inner:
+	br i1 %cond, label %inner.body, label %outer.latch
+
+inner.body:
+	br i1 %cond, label %inner, label %exit
+
+outer.latch:
+	br i1 %cond, label %outer, label %exit
+
+exit:
+	br i1 %cond, label %self, label %other
+
+self:
+	br i1 %cond, label %self, label %ret
+
+other:
+	br label %self
+
+ret:
+	ret void
+}
@@ -1,13 +1,17 @@
 package ir
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
 	"io"
+	"reflect"
 	"strings"
 	"sync"
 
 	"github.com/llir/llvm/internal/enc"
 	"github.com/llir/llvm/internal/natsort"
+	"github.com/llir/llvm/ir/constant"
 	"github.com/llir/llvm/ir/enum"
 	"github.com/llir/llvm/ir/metadata"
 	"github.com/llir/llvm/ir/types"
@@ -53,6 +57,10 @@ type Module struct {
 	UseListOrders []*UseListOrder
 	// (optional) Basic block specific use-list order directives.
 	UseListOrderBBs []*UseListOrderBB
+	// (optional) Use opaque pointer types (i.e. ptr) for the types of global
+	// variables, functions and alloca instructions created through the module
+	// API (e.g. Module.NewGlobal, Module.NewFunc and Block.NewAlloca).
+	OpaquePointers bool
 
 	// mu prevents races on AssignGlobalIDs and AssignMetadataIDs.
 	mu sync.Mutex
@@ -77,8 +85,34 @@ func (m *Module) String() string {
 
 // WriteTo write the string representation of the module in LLVM IR assembly
 // syntax to w.
+//
+// The module is streamed to w through a buffer, without building its string
+// representation. Use WriteConfig to write function definitions concurrently.
 func (m *Module) WriteTo(w io.Writer) (n int64, err error) {
-	fw := &fmtWriter{w: w}
+	return (&WriteConfig{}).WriteModule(w, m)
+}
+
+// --- [ Writer configuration ] ------------------------------------------------
+
+// WriteConfig specifies optional behaviour of writing LLVM IR modules in LLVM
+// IR assembly syntax. The zero value is the default configuration, as used by
+// Module.WriteTo.
+type WriteConfig struct {
+	// Maximum number of goroutines used to write functions concurrently, each to
+	// a separate buffer. The buffered functions are written to the output in
+	// order of occurrence in the module, and at most Workers functions are
+	// buffered at any time. Functions are written sequentially without separate
+	// buffers if Workers is less than or equal to 1.
+	Workers int
+}
+
+// WriteModule writes the string representation of the given module in LLVM IR
+// assembly syntax to w.
+func (cfg *WriteConfig) WriteModule(w io.Writer, m *Module) (n int64, err error) {
+	// Count bytes written to w, rather than to the buffer.
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	fw := &fmtWriter{w: bw}
 	// Assign global IDs.
 	if err := m.AssignGlobalIDs(); err != nil {
 		panic(fmt.Errorf("unable to assign globals IDs of module; %v", err))
@@ -152,12 +186,7 @@ func (m *Module) WriteTo(w io.Writer) (n int64, err error) {
 	if len(m.Funcs) > 0 && fw.size > 0 {
 		fw.Fprint("\n")
 	}
-	for i, f := range m.Funcs {
-		if i != 0 {
-			fw.Fprint("\n")
-		}
-		fw.Fprintln(f.LLString())
-	}
+	cfg.writeFuncs(fw, m.Funcs)
 	// Attribute group definitions.
 	if len(m.AttrGroupDefs) > 0 && fw.size > 0 {
 		fw.Fprint("\n")
@@ -203,7 +232,222 @@ func (m *Module) WriteTo(w io.Writer) (n int64, err error) {
 	for _, u := range m.UseListOrderBBs {
 		fw.Fprintln(u)
 	}
-	return fw.size, fw.err
+	if fw.err == nil {
+		fw.err = bw.Flush()
+	}
+	return cw.n, fw.err
+}
+
+// writeFuncs writes the given function declarations and definitions to fw,
+// separated by blank lines.
+func (cfg *WriteConfig) writeFuncs(fw *fmtWriter, funcs []*Func) {
+	if cfg.Workers <= 1 {
+		for i, f := range funcs {
+			if i != 0 {
+				fw.Fprint("\n")
+			}
+			fw.writeFrom(f)
+			fw.Fprint("\n")
+		}
+		return
+	}
+	// Pending functions, in order of occurrence; at most cfg.Workers functions
+	// are pending at any time.
+	pending := make(chan chan funcBuffer, cfg.Workers)
+	go func() {
+		// Values and metadata nodes with types computed.
+		visited := make(map[interface{}]bool)
+		for _, f := range funcs {
+			done := make(chan funcBuffer, 1)
+			pending <- done
+			if p := prepareFunc(f, visited); p != nil {
+				// Functions following the failed function are not written.
+				done <- funcBuffer{p: p}
+				break
+			}
+			go func(f *Func) {
+				done <- writeFuncBuffer(f)
+			}(f)
+		}
+		close(pending)
+	}()
+	i := 0
+	for done := range pending {
+		res := <-done
+		if res.p != nil {
+			// Drain pending functions before propagating panic to the calling
+			// goroutine.
+			for done := range pending {
+				<-done
+			}
+			panic(res.p)
+		}
+		if i != 0 {
+			fw.Fprint("\n")
+		}
+		fw.writeFrom(res.buf)
+		fw.Fprint("\n")
+		funcBufferPool.Put(res.buf)
+		i++
+	}
+}
+
+// funcBuffer is a function written to a separate buffer.
+type funcBuffer struct {
+	// Buffer containing the string representation of the function.
+	buf *bytes.Buffer
+	// Panic raised while writing the function; or nil if not present.
+	p interface{}
+}
+
+// funcBufferPool is a pool of buffers used to write functions concurrently.
+var funcBufferPool = sync.Pool{
+	New: func() interface{} {
+		return &bytes.Buffer{}
+	},
+}
+
+// prepareFunc prepares the given function for being written concurrently with
+// other functions, recovering from any panic so that it may be propagated to
+// the calling goroutine. Function bodies are materialized sequentially, as
+// materialization is not safe for concurrent use. Types are computed
+// sequentially (see computeTypes), as types are cached on first use of Type,
+// and values (e.g. constant expressions) may be shared between functions
+// written concurrently.
+func prepareFunc(f *Func, visited map[interface{}]bool) (p interface{}) {
+	defer func() {
+		if e := recover(); e != nil {
+			p = e
+		}
+	}()
+	if err := f.Materialize(); err != nil {
+		return fmt.Errorf("unable to materialize function %q; %v", f.Ident(), err)
+	}
+	computeTypes(f, visited)
+	return nil
+}
+
+// writeFuncBuffer writes the given function to a separate buffer, recovering
+// from any panic so that it may be propagated to the calling goroutine.
+func writeFuncBuffer(f *Func) (res funcBuffer) {
+	defer func() {
+		res.p = recover()
+	}()
+	buf := funcBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	// Writes to bytes.Buffer never fail.
+	f.WriteTo(buf)
+	return funcBuffer{buf: buf}
+}
+
+// computeTypes computes the types of the given function, its parameters,
+// instructions and terminators, and of the values used by these (including
+// values of operand bundles and of metadata attachments); recursively for the
+// operands of constants and the fields of metadata nodes. Values and metadata
+// nodes present in visited have their types computed already, and are skipped.
+func computeTypes(f *Func, visited map[interface{}]bool) {
+	var compute func(v value.Value)
+	var computeMetadata func(v reflect.Value)
+	compute = func(v value.Value) {
+		if v == nil || visited[v] {
+			return
+		}
+		visited[v] = true
+		switch v := v.(type) {
+		case *Arg:
+			compute(v.Value)
+		case *metadata.Value:
+			computeMetadata(reflect.ValueOf(&v.Value).Elem())
+		case *constant.Index:
+			compute(v.Constant)
+		case constant.Constant:
+			for _, op := range constant.Operands(v) {
+				if *op != nil {
+					compute(*op)
+				}
+			}
+		}
+		v.Type()
+	}
+	computeMetadata = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Interface:
+			if v.IsNil() {
+				return
+			}
+			elem := v.Elem()
+			if elem.Kind() == reflect.Ptr && elem.Type().Elem().PkgPath() == metadataPkgPath {
+				computeMetadata(elem)
+				return
+			}
+			if x, ok := elem.Interface().(value.Value); ok {
+				compute(x)
+			}
+		case reflect.Ptr:
+			if v.IsNil() || v.Type().Elem().PkgPath() != metadataPkgPath {
+				return
+			}
+			x := v.Interface()
+			if visited[x] {
+				return
+			}
+			visited[x] = true
+			computeMetadata(v.Elem())
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				if field := v.Field(i); field.CanInterface() {
+					computeMetadata(field)
+				}
+			}
+		case reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				computeMetadata(v.Index(i))
+			}
+		}
+	}
+	computeUser := func(user value.User) {
+		for _, op := range user.Operands() {
+			compute(*op)
+		}
+		var bundles []*OperandBundle
+		switch user := user.(type) {
+		case *InstCall:
+			bundles = user.OperandBundles
+		case *TermInvoke:
+			bundles = user.OperandBundles
+		case *TermCallBr:
+			bundles = user.OperandBundles
+		}
+		for _, bundle := range bundles {
+			for _, input := range bundle.Inputs {
+				compute(input)
+			}
+		}
+		if md, ok := user.(mdAttacher); ok {
+			computeMetadata(reflect.ValueOf(md.MDAttachments()))
+		}
+		if v, ok := user.(value.Value); ok {
+			compute(v)
+		}
+	}
+	for _, c := range []constant.Constant{f.Prefix, f.Prologue, f.Personality} {
+		if c != nil {
+			compute(c)
+		}
+	}
+	compute(f)
+	for _, param := range f.Params {
+		compute(param)
+	}
+	computeMetadata(reflect.ValueOf(f.Metadata))
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			computeUser(inst)
+		}
+		if block.Term != nil {
+			computeUser(block.Term)
+		}
+	}
 }
 
 // ~~~ [ Comdat Definition ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -321,6 +565,19 @@ func (u *UseListOrderBB) String() string {
 	return buf.String()
 }
 
+// --- [ Lazy materialization ] -----------------------------------------------
+
+// Materialize materializes the bodies of all lazily parsed functions of the
+// module (see Func.Materialize).
+func (m *Module) Materialize() error {
+	for _, f := range m.Funcs {
+		if err := f.Materialize(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ### [ Helper functions ] ####################################################
 
 // AssignGlobalIDs assigns IDs to unnamed global variables.
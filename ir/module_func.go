@@ -10,6 +10,9 @@ import "github.com/llir/llvm/ir/types"
 // The Parent field of the function is set to m.
 func (m *Module) NewFunc(name string, retType types.Type, params ...*Param) *Func {
 	f := NewFunc(name, retType, params...)
+	if m.OpaquePointers {
+		f.Typ = types.NewOpaquePointer(f.AddrSpace)
+	}
 	f.Parent = m
 	m.Funcs = append(m.Funcs, f)
 	return f
@@ -11,6 +11,9 @@ import (
 // the given global variable name and content type.
 func (m *Module) NewGlobal(name string, contentType types.Type) *Global {
 	g := NewGlobal(name, contentType)
+	if m.OpaquePointers {
+		g.Typ = types.NewOpaquePointer(g.AddrSpace)
+	}
 	m.Globals = append(m.Globals, g)
 	return g
 }
@@ -19,6 +22,9 @@ func (m *Module) NewGlobal(name string, contentType types.Type) *Global {
 // the given global variable name and initial value.
 func (m *Module) NewGlobalDef(name string, init constant.Constant) *Global {
 	g := NewGlobalDef(name, init)
+	if m.OpaquePointers {
+		g.Typ = types.NewOpaquePointer(g.AddrSpace)
+	}
 	m.Globals = append(m.Globals, g)
 	return g
 }
@@ -0,0 +1,161 @@
+package pass
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/cfg"
+	"github.com/llir/llvm/ir/dom"
+	"github.com/llir/llvm/ir/loop"
+)
+
+// === [ Analyses ] ============================================================
+
+// Analyses is a cache of analyses of a module and its functions. Analyses are
+// computed on first request, and cached until invalidated.
+type Analyses struct {
+	// Module of the analyses.
+	Module *ir.Module
+	// Use-def chains of the module; or nil if not yet computed.
+	uses *ir.UseIndex
+	// Analyses of each function.
+	funcs map[*ir.Func]*funcAnalyses
+}
+
+// funcAnalyses holds the cached analyses of a function; nil if not yet
+// computed.
+type funcAnalyses struct {
+	// Control flow graph.
+	cfg *cfg.Graph
+	// Dominator tree.
+	dom *dom.Tree
+	// Post-dominator tree.
+	postDom *dom.Tree
+	// Loop information.
+	loops *loop.Info
+	// Use-def chains.
+	uses *ir.UseIndex
+}
+
+// NewAnalyses returns a new empty analysis cache of the given module.
+func NewAnalyses(m *ir.Module) *Analyses {
+	return &Analyses{
+		Module: m,
+		funcs:  make(map[*ir.Func]*funcAnalyses),
+	}
+}
+
+// CFG returns the control flow graph of the given function.
+func (am *Analyses) CFG(f *ir.Func) *cfg.Graph {
+	fa := am.funcAnalyses(f)
+	if fa.cfg == nil {
+		fa.cfg = cfg.New(f)
+	}
+	return fa.cfg
+}
+
+// Dom returns the dominator tree of the given function.
+func (am *Analyses) Dom(f *ir.Func) *dom.Tree {
+	fa := am.funcAnalyses(f)
+	if fa.dom == nil {
+		fa.dom = dom.New(am.CFG(f))
+	}
+	return fa.dom
+}
+
+// PostDom returns the post-dominator tree of the given function.
+func (am *Analyses) PostDom(f *ir.Func) *dom.Tree {
+	fa := am.funcAnalyses(f)
+	if fa.postDom == nil {
+		fa.postDom = dom.NewPost(am.CFG(f))
+	}
+	return fa.postDom
+}
+
+// Loops returns the loop information of the given function.
+func (am *Analyses) Loops(f *ir.Func) *loop.Info {
+	fa := am.funcAnalyses(f)
+	if fa.loops == nil {
+		fa.loops = loop.New(am.Dom(f))
+	}
+	return fa.loops
+}
+
+// FuncUses returns the use-def chains of the given function.
+func (am *Analyses) FuncUses(f *ir.Func) *ir.UseIndex {
+	fa := am.funcAnalyses(f)
+	if fa.uses == nil {
+		fa.uses = ir.NewFuncUseIndex(f)
+	}
+	return fa.uses
+}
+
+// ModuleUses returns the use-def chains of the module.
+func (am *Analyses) ModuleUses() *ir.UseIndex {
+	if am.uses == nil {
+		am.uses = ir.NewModuleUseIndex(am.Module)
+	}
+	return am.uses
+}
+
+// InvalidateFunc invalidates the analyses of the given function which are not
+// preserved. Use-def chains of the module are invalidated unless preserved.
+func (am *Analyses) InvalidateFunc(f *ir.Func, preserved Preserved) {
+	preserved = preserved.normalize()
+	if preserved&PreserveUses == 0 {
+		am.uses = nil
+	}
+	fa, ok := am.funcs[f]
+	if !ok {
+		return
+	}
+	fa.invalidate(preserved)
+}
+
+// Invalidate invalidates the analyses of the module and of each function which
+// are not preserved.
+func (am *Analyses) Invalidate(preserved Preserved) {
+	preserved = preserved.normalize()
+	if preserved&PreserveUses == 0 {
+		am.uses = nil
+	}
+	funcs := make(map[*ir.Func]bool)
+	for _, f := range am.Module.Funcs {
+		funcs[f] = true
+	}
+	for f, fa := range am.funcs {
+		if !funcs[f] {
+			// Drop analyses of functions removed from the module.
+			delete(am.funcs, f)
+			continue
+		}
+		fa.invalidate(preserved)
+	}
+}
+
+// invalidate invalidates the analyses of the function which are not preserved.
+func (fa *funcAnalyses) invalidate(preserved Preserved) {
+	if preserved&PreserveCFG == 0 {
+		fa.cfg = nil
+	}
+	if preserved&PreserveDom == 0 {
+		fa.dom = nil
+	}
+	if preserved&PreservePostDom == 0 {
+		fa.postDom = nil
+	}
+	if preserved&PreserveLoops == 0 {
+		fa.loops = nil
+	}
+	if preserved&PreserveUses == 0 {
+		fa.uses = nil
+	}
+}
+
+// funcAnalyses returns the cached analyses of the given function.
+func (am *Analyses) funcAnalyses(f *ir.Func) *funcAnalyses {
+	fa, ok := am.funcs[f]
+	if !ok {
+		fa = &funcAnalyses{}
+		am.funcs[f] = fa
+	}
+	return fa
+}
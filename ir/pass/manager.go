@@ -0,0 +1,270 @@
+package pass
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/llir/llvm/ir"
+	"github.com/pkg/errors"
+)
+
+// === [ Pass manager ] ========================================================
+
+// Manager is a pass manager which runs a pipeline of passes on modules.
+//
+// The pass manager is itself a module pass, and may thus be nested.
+type Manager struct {
+	// Passes of the pipeline; consecutive function passes are grouped and run on
+	// each function definition in turn.
+	passes []ModulePass
+	// Timing of each pass, in order of the pipeline; nil for nested pass
+	// managers.
+	timings []*Timing
+}
+
+// NewManager returns a new pass manager with the given passes.
+func NewManager(passes ...Pass) *Manager {
+	pm := &Manager{}
+	for _, p := range passes {
+		pm.Add(p)
+	}
+	return pm
+}
+
+// Add adds the given module or function pass to the pipeline of the pass
+// manager.
+func (pm *Manager) Add(p Pass) {
+	switch p := p.(type) {
+	case *Manager, *FuncManager:
+		pm.passes = append(pm.passes, p.(ModulePass))
+		pm.timings = append(pm.timings, nil)
+	case FunctionPass:
+		// Group consecutive function passes.
+		var fpm *FuncManager
+		if n := len(pm.passes); n > 0 {
+			fpm, _ = pm.passes[n-1].(*FuncManager)
+		}
+		if fpm == nil || fpm.explicit {
+			fpm = &FuncManager{}
+			pm.passes = append(pm.passes, fpm)
+			pm.timings = append(pm.timings, nil)
+		}
+		fpm.Add(p)
+	case ModulePass:
+		pm.passes = append(pm.passes, p)
+		pm.timings = append(pm.timings, &Timing{Pass: p})
+	default:
+		panic(fmt.Errorf("support for pass type %T not yet implemented", p))
+	}
+}
+
+// Name returns the pipeline description of the pass manager.
+func (pm *Manager) Name() string {
+	var names []string
+	for _, p := range pm.passes {
+		switch p := p.(type) {
+		case *FuncManager:
+			if !p.explicit {
+				// Implicit function pass groups are described by their passes.
+				names = append(names, p.pipeline())
+				continue
+			}
+			names = append(names, p.Name())
+		case *Manager:
+			names = append(names, "module("+p.Name()+")")
+		default:
+			names = append(names, p.Name())
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// String returns the pipeline description of the pass manager.
+func (pm *Manager) String() string {
+	return pm.Name()
+}
+
+// Run runs the pipeline of the pass manager on the given module.
+func (pm *Manager) Run(m *ir.Module) error {
+	_, err := pm.RunOnModule(m, NewAnalyses(m))
+	return err
+}
+
+// RunOnModule runs the pipeline of the pass manager on the given module, using
+// the given analysis cache.
+func (pm *Manager) RunOnModule(m *ir.Module, am *Analyses) (Preserved, error) {
+	all := PreserveAll
+	// Materialize function bodies if lazily parsed.
+	if err := m.Materialize(); err != nil {
+		return all, errors.WithStack(err)
+	}
+	for i, p := range pm.passes {
+		preserved, err := pm.runPass(i, m, am)
+		if err != nil {
+			return all, err
+		}
+		switch p.(type) {
+		case *Manager, *FuncManager:
+			// Nested pass managers invalidate analyses after each of their passes.
+		default:
+			am.Invalidate(preserved)
+		}
+		all &= preserved.normalize()
+	}
+	return all, nil
+}
+
+// runPass runs the i:th pass of the pipeline on the given module, and records
+// its timing.
+func (pm *Manager) runPass(i int, m *ir.Module, am *Analyses) (Preserved, error) {
+	switch p := pm.passes[i].(type) {
+	case *Manager, *FuncManager:
+		// Passes of nested pass managers are timed individually.
+		return p.RunOnModule(m, am)
+	}
+	p := pm.passes[i]
+	start := time.Now()
+	preserved, err := p.RunOnModule(m, am)
+	pm.timings[i].add(time.Since(start))
+	if err != nil {
+		return preserved, errors.Wrapf(err, "pass %q failed", p.Name())
+	}
+	return preserved, nil
+}
+
+// Timings returns the timing of each pass run by the pass manager, in order of
+// the pipeline. Function passes are listed in place of their function pass
+// manager.
+func (pm *Manager) Timings() []*Timing {
+	var timings []*Timing
+	for i, p := range pm.passes {
+		switch p := p.(type) {
+		case *FuncManager:
+			timings = append(timings, p.timings...)
+		case *Manager:
+			timings = append(timings, p.Timings()...)
+		default:
+			timings = append(timings, pm.timings[i])
+		}
+	}
+	return timings
+}
+
+// WriteTimings writes a report of the timing of each pass run by the pass
+// manager to w.
+func (pm *Manager) WriteTimings(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	var total time.Duration
+	if _, err := fmt.Fprintf(tw, "pass\truns\ttime\t\n"); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, t := range pm.Timings() {
+		total += t.Total
+		if _, err := fmt.Fprintf(tw, "%s\t%d\t%v\t\n", t.Pass.Name(), t.Runs, t.Total); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if _, err := fmt.Fprintf(tw, "total\t\t%v\t\n", total); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(tw.Flush())
+}
+
+// --- [ Function pass manager ] -----------------------------------------------
+
+// FuncManager is a function pass manager which runs a pipeline of function
+// passes on each function definition of a module in turn.
+//
+// The function pass manager is a module pass, and is described by
+// "function(...)" in pipeline descriptions.
+type FuncManager struct {
+	// Function passes of the pipeline.
+	passes []FunctionPass
+	// Timing of each pass, in order of the pipeline.
+	timings []*Timing
+	// The function pass manager was explicitly created (e.g. "function(...)"),
+	// and is not extended by subsequent function passes of the parent pass
+	// manager.
+	explicit bool
+}
+
+// NewFuncManager returns a new function pass manager with the given function
+// passes.
+func NewFuncManager(passes ...FunctionPass) *FuncManager {
+	fpm := &FuncManager{explicit: true}
+	for _, p := range passes {
+		fpm.Add(p)
+	}
+	return fpm
+}
+
+// Add adds the given function pass to the pipeline of the function pass
+// manager.
+func (fpm *FuncManager) Add(p FunctionPass) {
+	fpm.passes = append(fpm.passes, p)
+	fpm.timings = append(fpm.timings, &Timing{Pass: p})
+}
+
+// Name returns the pipeline description of the function pass manager.
+func (fpm *FuncManager) Name() string {
+	return "function(" + fpm.pipeline() + ")"
+}
+
+// pipeline returns the pipeline description of the passes of the function
+// pass manager.
+func (fpm *FuncManager) pipeline() string {
+	var names []string
+	for _, p := range fpm.passes {
+		names = append(names, p.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+// RunOnModule runs the pipeline of the function pass manager on each function
+// definition of the given module, using the given analysis cache.
+func (fpm *FuncManager) RunOnModule(m *ir.Module, am *Analyses) (Preserved, error) {
+	all := PreserveAll
+	for _, f := range m.Funcs {
+		// Materialize function body if lazily parsed.
+		if err := f.Materialize(); err != nil {
+			return all, errors.WithStack(err)
+		}
+		if len(f.Blocks) == 0 {
+			// Skip function declarations.
+			continue
+		}
+		for i, p := range fpm.passes {
+			start := time.Now()
+			preserved, err := p.RunOnFunc(f, am)
+			fpm.timings[i].add(time.Since(start))
+			if err != nil {
+				return all, errors.Wrapf(err, "pass %q failed on function %s", p.Name(), f.Ident())
+			}
+			am.InvalidateFunc(f, preserved)
+			all &= preserved.normalize()
+		}
+	}
+	return all, nil
+}
+
+// --- [ Timing ] --------------------------------------------------------------
+
+// Timing is the execution time of a pass.
+type Timing struct {
+	// Pass.
+	Pass Pass
+	// Number of runs of the pass; for function passes, the number of function
+	// definitions on which the pass has been run.
+	Runs int
+	// Total execution time of the pass.
+	Total time.Duration
+}
+
+// add records a run of the pass with the given execution time.
+func (t *Timing) add(d time.Duration) {
+	t.Runs++
+	t.Total += d
+}
@@ -0,0 +1,84 @@
+// Package pass implements a pass manager for LLVM IR modules.
+//
+// Passes operate either on modules (ModulePass) or on function definitions
+// (FunctionPass). A pass manager runs a pipeline of passes on a module, caches
+// analyses (control flow graphs, dominator trees, loop information and use-def
+// chains) shared between passes, and invalidates analyses based on the
+// analyses preserved by each pass.
+//
+// Pipelines may be described by strings in the style of the -passes flag of
+// LLVM opt; e.g.
+//
+//	mem2reg,verify
+//	module(function(mem2reg),verify)
+package pass
+
+import (
+	"github.com/llir/llvm/ir"
+)
+
+// Pass is a module or function pass.
+//
+// Pass has one of the following underlying types.
+//
+//   - ModulePass
+//   - FunctionPass
+type Pass interface {
+	// Name returns the name of the pass, as used in pipeline descriptions.
+	Name() string
+}
+
+// ModulePass is a pass which operates on modules.
+type ModulePass interface {
+	Pass
+	// RunOnModule runs the pass on the given module, and returns the set of
+	// analyses preserved by the pass. Cached analyses are provided by am.
+	RunOnModule(m *ir.Module, am *Analyses) (Preserved, error)
+}
+
+// FunctionPass is a pass which operates on function definitions.
+type FunctionPass interface {
+	Pass
+	// RunOnFunc runs the pass on the given function definition, and returns the
+	// set of analyses preserved by the pass. Cached analyses are provided by am.
+	RunOnFunc(f *ir.Func, am *Analyses) (Preserved, error)
+}
+
+// --- [ Preserved analyses ] --------------------------------------------------
+
+// Preserved is a set of analyses preserved by a pass.
+type Preserved uint
+
+// Preserved analyses.
+const (
+	// Control flow graphs; i.e. the basic blocks of functions and the targets of
+	// their terminators are unchanged.
+	PreserveCFG Preserved = 1 << iota
+	// Dominator trees.
+	PreserveDom
+	// Post-dominator trees.
+	PreservePostDom
+	// Loop information.
+	PreserveLoops
+	// Use-def chains.
+	PreserveUses
+
+	// No analyses preserved.
+	PreserveNone Preserved = 0
+	// All analyses preserved; e.g. by passes which do not modify the IR.
+	PreserveAll = PreserveCFG | PreserveDom | PreservePostDom | PreserveLoops | PreserveUses
+)
+
+// normalize returns the set of analyses preserved, taking dependencies
+// between analyses into account; e.g. dominator trees are computed from
+// control flow graphs, and are thus not preserved if the control flow graphs
+// are not preserved.
+func (p Preserved) normalize() Preserved {
+	if p&PreserveCFG == 0 {
+		p &^= PreserveDom | PreservePostDom
+	}
+	if p&PreserveDom == 0 {
+		p &^= PreserveLoops
+	}
+	return p
+}
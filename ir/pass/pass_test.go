@@ -0,0 +1,116 @@
+package pass_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/llir/llvm/asm"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/pass"
+)
+
+func TestParsePipeline(t *testing.T) {
+	golden := []struct {
+		in string
+		// Pipeline description of parsed pass manager; or prefix of error
+		// message.
+		want string
+		err  bool
+	}{
+		{in: "mem2reg", want: "mem2reg"},
+		{in: "mem2reg, verify", want: "mem2reg,verify"},
+		{in: "function(mem2reg,dce),globaldce", want: "function(mem2reg,dce),globaldce"},
+		{in: "mem2reg,constprop,dce", want: "mem2reg,constprop,dce"},
+		{in: "module(mem2reg,verify)", want: "mem2reg,verify"},
+		{in: "function(mem2reg),verify", want: "function(mem2reg),verify"},
+		{in: "verify,module(mem2reg)", want: "verify,module(mem2reg)"},
+		{in: "", want: `invalid pipeline "" at offset 0; expected pass name, got end of pipeline`, err: true},
+		{in: "mem2reg,foo", want: `invalid pipeline "mem2reg,foo" at offset 8; unknown pass "foo"`, err: true},
+		{in: "function(verify)", want: `invalid pipeline "function(verify)" at offset 16; "verify" is not a function pass`, err: true},
+		{in: "function(mem2reg", want: `invalid pipeline "function(mem2reg" at offset 16; expected ')' to close "function"`, err: true},
+		{in: "mem2reg)", want: `invalid pipeline "mem2reg)" at offset 7; unexpected ')'`, err: true},
+	}
+	for _, g := range golden {
+		pm, err := pass.ParsePipeline(g.in)
+		if g.err {
+			if err == nil {
+				t.Errorf("%q: expected error, got pipeline %q", g.in, pm)
+			} else if got := err.Error(); got != g.want {
+				t.Errorf("%q: error mismatch; expected %q, got %q", g.in, g.want, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unable to parse pipeline; %v", g.in, err)
+			continue
+		}
+		if got := pm.String(); got != g.want {
+			t.Errorf("%q: pipeline mismatch; expected %q, got %q", g.in, g.want, got)
+		}
+	}
+}
+
+func TestManager(t *testing.T) {
+	m, err := asm.ParseFile("testdata/pass.ll")
+	if err != nil {
+		t.Fatalf("unable to parse module; %+v", err)
+	}
+	pm, err := pass.ParsePipeline("mem2reg,verify")
+	if err != nil {
+		t.Fatalf("unable to parse pipeline; %v", err)
+	}
+	if err := pm.Run(m); err != nil {
+		t.Fatalf("unable to run pipeline; %+v", err)
+	}
+	f := m.Funcs[1]
+	if _, ok := f.Blocks[2].Insts[0].(*ir.InstPhi); !ok {
+		t.Errorf("expected phi instruction in %%exit of %s, got %v", f.Ident(), f.Blocks[2].Insts)
+	}
+	// Timings; function passes are run on each function definition.
+	want := map[string]int{"mem2reg": 2, "verify": 1}
+	timings := pm.Timings()
+	if len(timings) != len(want) {
+		t.Fatalf("number of timings mismatch; expected %d, got %d", len(want), len(timings))
+	}
+	for _, timing := range timings {
+		if timing.Runs != want[timing.Pass.Name()] {
+			t.Errorf("number of runs of pass %q mismatch; expected %d, got %d", timing.Pass.Name(), want[timing.Pass.Name()], timing.Runs)
+		}
+	}
+	buf := &strings.Builder{}
+	if err := pm.WriteTimings(buf); err != nil {
+		t.Fatalf("unable to write timings; %+v", err)
+	}
+	if !strings.Contains(buf.String(), "mem2reg") {
+		t.Errorf("expected mem2reg in timing report, got %q", buf.String())
+	}
+}
+
+func TestAnalyses(t *testing.T) {
+	m, err := asm.ParseFile("testdata/pass.ll")
+	if err != nil {
+		t.Fatalf("unable to parse module; %+v", err)
+	}
+	f := m.Funcs[1]
+	am := pass.NewAnalyses(m)
+	g, tree, loops, uses := am.CFG(f), am.Dom(f), am.Loops(f), am.FuncUses(f)
+	if am.CFG(f) != g || am.Dom(f) != tree || am.Loops(f) != loops || am.FuncUses(f) != uses {
+		t.Fatalf("expected cached analyses")
+	}
+	if tree.Graph != g {
+		t.Errorf("expected dominator tree of cached control flow graph")
+	}
+	// Invalidation of use-def chains preserves control flow analyses.
+	am.InvalidateFunc(f, pass.PreserveAll&^pass.PreserveUses)
+	if am.CFG(f) != g || am.Dom(f) != tree || am.Loops(f) != loops {
+		t.Errorf("expected control flow analyses to be preserved")
+	}
+	if am.FuncUses(f) == uses {
+		t.Errorf("expected use-def chains to be invalidated")
+	}
+	// Invalidation of control flow graphs invalidates dependent analyses.
+	am.Invalidate(pass.PreserveDom | pass.PreserveLoops | pass.PreserveUses)
+	if am.CFG(f) == g || am.Dom(f) == tree || am.Loops(f) == loops {
+		t.Errorf("expected control flow analyses to be invalidated")
+	}
+}
@@ -0,0 +1,137 @@
+package pass
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/transform"
+	"github.com/llir/llvm/ir/verify"
+)
+
+func init() {
+	Register("constprop", func() Pass { return ConstProp{} })
+	Register("dce", func() Pass { return DCE{} })
+	Register("globaldce", func() Pass { return GlobalDCE{} })
+	Register("inline", func() Pass { return Inline{} })
+	Register("mem2reg", func() Pass { return Mem2Reg{} })
+	Register("verify", func() Pass { return Verify{} })
+}
+
+// === [ Passes ] ==============================================================
+
+// --- [ constprop ] -----------------------------------------------------------
+
+// ConstProp is a function pass which folds instructions with constant operands
+// into constants, as implemented by transform.PropagateConstants.
+type ConstProp struct{}
+
+// Name returns the name of the pass.
+func (ConstProp) Name() string {
+	return "constprop"
+}
+
+// RunOnFunc runs the pass on the given function definition.
+func (ConstProp) RunOnFunc(f *ir.Func, am *Analyses) (Preserved, error) {
+	if transform.PropagateConstants(f) == 0 {
+		return PreserveAll, nil
+	}
+	// Instructions are removed, but basic blocks and terminators are unchanged.
+	return PreserveAll &^ PreserveUses, nil
+}
+
+// --- [ dce ] -----------------------------------------------------------------
+
+// DCE is a function pass which removes trivially dead instructions, as
+// implemented by transform.EliminateDeadCode.
+type DCE struct{}
+
+// Name returns the name of the pass.
+func (DCE) Name() string {
+	return "dce"
+}
+
+// RunOnFunc runs the pass on the given function definition.
+func (DCE) RunOnFunc(f *ir.Func, am *Analyses) (Preserved, error) {
+	if transform.EliminateDeadCode(f) == 0 {
+		return PreserveAll, nil
+	}
+	// Instructions are removed, but basic blocks and terminators are unchanged.
+	return PreserveAll &^ PreserveUses, nil
+}
+
+// --- [ globaldce ] -----------------------------------------------------------
+
+// GlobalDCE is a module pass which removes unreferenced global values, type
+// definitions and metadata definitions, as implemented by
+// transform.EliminateDeadGlobals.
+type GlobalDCE struct{}
+
+// Name returns the name of the pass.
+func (GlobalDCE) Name() string {
+	return "globaldce"
+}
+
+// RunOnModule runs the pass on the given module.
+func (GlobalDCE) RunOnModule(m *ir.Module, am *Analyses) (Preserved, error) {
+	if transform.EliminateDeadGlobals(m) == 0 {
+		return PreserveAll, nil
+	}
+	// Analyses of removed functions are dropped on invalidation; analyses of
+	// remaining functions are unaffected.
+	return PreserveAll &^ PreserveUses, nil
+}
+
+// --- [ inline ] --------------------------------------------------------------
+
+// Inline is a module pass which inlines function calls, as implemented by
+// transform.Inliner with the default cost model and threshold.
+type Inline struct{}
+
+// Name returns the name of the pass.
+func (Inline) Name() string {
+	return "inline"
+}
+
+// RunOnModule runs the pass on the given module.
+func (Inline) RunOnModule(m *ir.Module, am *Analyses) (Preserved, error) {
+	if transform.NewInliner().InlineModule(m) == 0 {
+		return PreserveAll, nil
+	}
+	// Basic blocks of callers are split and inlined function bodies inserted.
+	return PreserveNone, nil
+}
+
+// --- [ mem2reg ] -------------------------------------------------------------
+
+// Mem2Reg is a function pass which promotes alloca instructions to SSA
+// registers, as implemented by transform.PromoteMemToReg.
+type Mem2Reg struct{}
+
+// Name returns the name of the pass.
+func (Mem2Reg) Name() string {
+	return "mem2reg"
+}
+
+// RunOnFunc runs the pass on the given function definition.
+func (Mem2Reg) RunOnFunc(f *ir.Func, am *Analyses) (Preserved, error) {
+	if transform.PromoteMemToReg(f) == 0 {
+		return PreserveAll, nil
+	}
+	// Instructions are added and removed, but basic blocks and terminators are
+	// unchanged.
+	return PreserveAll &^ PreserveUses, nil
+}
+
+// --- [ verify ] --------------------------------------------------------------
+
+// Verify is a module pass which verifies the well-formedness of modules, as
+// implemented by verify.Module.
+type Verify struct{}
+
+// Name returns the name of the pass.
+func (Verify) Name() string {
+	return "verify"
+}
+
+// RunOnModule runs the pass on the given module.
+func (Verify) RunOnModule(m *ir.Module, am *Analyses) (Preserved, error) {
+	return PreserveAll, verify.Module(m)
+}
@@ -0,0 +1,188 @@
+package pass
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// === [ Pass registry ] =======================================================
+
+// registry maps from pass name to pass constructor.
+var registry = map[string]func() Pass{}
+
+// Register registers the given pass constructor with the given name, for use
+// in pipeline descriptions. Register panics if a pass with the same name has
+// already been registered.
+func Register(name string, newPass func() Pass) {
+	if name == "module" || name == "function" {
+		panic(fmt.Errorf("invalid pass name %q; reserved for pass managers", name))
+	}
+	if _, ok := registry[name]; ok {
+		panic(fmt.Errorf("pass %q already registered", name))
+	}
+	registry[name] = newPass
+}
+
+// Names returns the sorted names of the registered passes.
+func Names() []string {
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// === [ Pipeline descriptions ] ===============================================
+
+// ParsePipeline returns a new pass manager for the given pipeline description,
+// in the style of the -passes flag of LLVM opt.
+//
+// A pipeline description is a comma-separated list of pass names and nested
+// pipelines; "module(...)" for a nested module pipeline and "function(...)" for
+// a pipeline of function passes run on each function definition in turn. White
+// space is ignored.
+//
+// Example pipeline descriptions.
+//
+//	mem2reg,verify
+//	module(function(mem2reg),verify)
+func ParsePipeline(pipeline string) (*Manager, error) {
+	p := &pipelineParser{s: pipeline}
+	passes, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.s) {
+		return nil, p.errorf("unexpected %q", p.s[p.pos])
+	}
+	// A single top-level module pipeline is the pipeline of the pass manager.
+	if len(passes) == 1 {
+		if pm, ok := passes[0].(*Manager); ok {
+			return pm, nil
+		}
+	}
+	return NewManager(passes...), nil
+}
+
+// pipelineParser is a parser of pipeline descriptions.
+type pipelineParser struct {
+	// Pipeline description.
+	s string
+	// Current position in s.
+	pos int
+}
+
+// parseList parses a comma-separated list of passes.
+//
+//	list = elem { ',' elem }
+func (p *pipelineParser) parseList() ([]Pass, error) {
+	var passes []Pass
+	for {
+		pass, err := p.parseElem()
+		if err != nil {
+			return nil, err
+		}
+		passes = append(passes, pass)
+		if !p.consume(',') {
+			return passes, nil
+		}
+	}
+}
+
+// parseElem parses a pass or nested pipeline.
+//
+//	elem = name | 'module' '(' list ')' | 'function' '(' list ')'
+func (p *pipelineParser) parseElem() (Pass, error) {
+	start := p.pos
+	name := p.parseName()
+	if len(name) == 0 {
+		if p.pos < len(p.s) {
+			return nil, p.errorf("expected pass name, got %q", p.s[p.pos])
+		}
+		return nil, p.errorf("expected pass name, got end of pipeline")
+	}
+	switch name {
+	case "module", "function":
+		if !p.consume('(') {
+			return nil, p.errorf("expected '(' after %q", name)
+		}
+		passes, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(')') {
+			return nil, p.errorf("expected ')' to close %q", name)
+		}
+		if name == "module" {
+			return NewManager(passes...), nil
+		}
+		fpm := NewFuncManager()
+		for _, pass := range passes {
+			fp, ok := pass.(FunctionPass)
+			if !ok {
+				return nil, p.errorf("%q is not a function pass", pass.Name())
+			}
+			fpm.Add(fp)
+		}
+		return fpm, nil
+	}
+	newPass, ok := registry[name]
+	if !ok {
+		p.pos = start
+		return nil, p.errorf("unknown pass %q", name)
+	}
+	return newPass(), nil
+}
+
+// parseName parses a pass name.
+func (p *pipelineParser) parseName() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && isNameChar(p.s[p.pos]) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// consume consumes the given character if next in the pipeline description
+// (after white space), and reports whether it was consumed.
+func (p *pipelineParser) consume(c byte) bool {
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// skipSpace skips white space.
+func (p *pipelineParser) skipSpace() {
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) != -1 {
+		p.pos++
+	}
+}
+
+// errorf returns an error at the current position of the pipeline
+// description.
+func (p *pipelineParser) errorf(format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	return errors.Errorf("invalid pipeline %q at offset %d; %s", p.s, p.pos, msg)
+}
+
+// ### [ Helper functions ] ####################################################
+
+// isNameChar reports whether the given character may be part of a pass name.
+func isNameChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	case c == '-', c == '_', c == '.', c == '<', c == '>', c == ';', c == '=':
+		// Pass parameters; e.g. "loop-unroll<O2>".
+		return true
+	}
+	return false
+}
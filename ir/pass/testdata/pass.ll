@@ -0,0 +1,21 @@
+declare void @g(i32)
+
+define i32 @f(i1 %cond) {
+entry:
+	%a = alloca i32
+	br i1 %cond, label %then, label %exit
+
+then:
+	store i32 1, i32* %a
+	br label %exit
+
+exit:
+	%v = load i32, i32* %a
+	ret i32 %v
+}
+
+define void @h() {
+entry:
+	call void @g(i32 0)
+	ret void
+}
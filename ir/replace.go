@@ -0,0 +1,401 @@
+package ir
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/metadata"
+	"github.com/llir/llvm/ir/value"
+)
+
+// === [ Replace all uses ] ====================================================
+
+// ReplaceAllUsesWith replaces all uses of old with new within the function;
+// i.e. in the operands of instructions and terminators (including phi incoming
+// values, switch cases, function arguments and metadata values), in the
+// metadata attachments of instructions and terminators (including metadata
+// nodes nested within these), and in the prefix, prologue and personality of
+// the function.
+//
+// Constants using old (e.g. constant expressions) are not modified in place,
+// as they may be shared with users outside of the function; instead, uses of
+// such constants within the function are replaced with updated copies.
+//
+// The use index registered on the function, if any, is updated.
+//
+// The types of old and new must be identical. Uses of old within constants may
+// only be replaced if new is a constant.
+func (f *Func) ReplaceAllUsesWith(old, new value.Value) {
+	r := newReplacer(map[value.Value]value.Value{old: new})
+	r.replaceFunc(f)
+}
+
+// ReplaceAllUses replaces all uses of values within the function, as specified
+// by repl, which maps from old value to new value. Replacement values are
+// themselves replaced if present in repl, and must therefore not form cycles.
+// The uses are replaced in a single pass over the function, as by
+// ReplaceAllUsesWith for each entry of repl.
+func (f *Func) ReplaceAllUses(repl map[value.Value]value.Value) {
+	if len(repl) == 0 {
+		return
+	}
+	r := newReplacer(repl)
+	r.replaceFunc(f)
+}
+
+// ReplaceAllUsesWith replaces all uses of old with new within the module; i.e.
+// within the initializers of global variables, the aliasees of aliases, the
+// resolvers of IFuncs, the bodies of functions (see Func.ReplaceAllUsesWith)
+// and metadata; i.e. metadata definitions, named metadata definitions and
+// metadata attachments of global variables and functions, including metadata
+// nodes nested within these (e.g. the fields of metadata tuples and of
+// specialized metadata nodes).
+//
+// Constants using old (e.g. constant expressions) are not modified in place;
+// instead, uses of such constants are replaced with updated copies.
+//
+// The use indices registered on functions of the module, if any, are updated;
+// including the uses by global variables, aliases and IFuncs.
+//
+// The types of old and new must be identical. Uses of old within constants may
+// only be replaced if new is a constant.
+func (m *Module) ReplaceAllUsesWith(old, new value.Value) {
+	r := newReplacer(map[value.Value]value.Value{old: new})
+	// Materialize function bodies if lazily parsed, before replacing uses of
+	// global values referenced by function bodies yet to be translated.
+	for _, f := range m.Funcs {
+		mustMaterialize(f)
+	}
+	var idxs []*UseIndex
+	for _, f := range m.Funcs {
+		if f.useIndex != nil && !containsUseIndex(idxs, f.useIndex) {
+			idxs = append(idxs, f.useIndex)
+		}
+	}
+	update := func(user interface{}) {
+		for _, idx := range idxs {
+			idx.UpdateUser(user)
+		}
+	}
+	for _, g := range m.Globals {
+		if g.Init != nil {
+			if init := r.replaceConst(g.Init); init != g.Init {
+				g.Init = init
+				update(g)
+			}
+		}
+	}
+	for _, f := range m.Funcs {
+		r.replaceFunc(f)
+	}
+	for _, a := range m.Aliases {
+		if aliasee := r.replaceConst(a.Aliasee); aliasee != a.Aliasee {
+			a.Aliasee = aliasee
+			update(a)
+		}
+	}
+	for _, i := range m.IFuncs {
+		if resolver := r.replaceConst(i.Resolver); resolver != i.Resolver {
+			i.Resolver = resolver
+			update(i)
+		}
+	}
+	for _, def := range m.MetadataDefs {
+		r.replaceMetadata(reflect.ValueOf(def))
+	}
+	for _, def := range m.NamedMetadataDefs {
+		r.replaceMetadata(reflect.ValueOf(def))
+	}
+	for _, g := range m.Globals {
+		r.replaceMetadata(reflect.ValueOf(g.Metadata))
+	}
+	for _, f := range m.Funcs {
+		r.replaceMetadata(reflect.ValueOf(f.Metadata))
+	}
+}
+
+// replacer replaces all uses of values with other values.
+type replacer struct {
+	// Replacement value of each value to replace.
+	repl map[value.Value]value.Value
+	// Updated copies of constants using replaced values, indexed by original
+	// constant; or the original constant if unchanged.
+	consts map[constant.Constant]constant.Constant
+	// replaced specifies whether a use has been replaced since last reset.
+	replaced bool
+	// Metadata nodes visited by replaceMetadata.
+	mds map[interface{}]bool
+}
+
+// newReplacer returns a new replacer of the values specified by repl, which
+// maps from old value to new value.
+func newReplacer(repl map[value.Value]value.Value) *replacer {
+	for old, new := range repl {
+		if !old.Type().Equal(new.Type()) {
+			panic(fmt.Errorf("unable to replace uses of %q with %q; type mismatch between %v and %v", old.Ident(), new.Ident(), old.Type(), new.Type()))
+		}
+	}
+	return &replacer{
+		repl:   repl,
+		consts: make(map[constant.Constant]constant.Constant),
+		mds:    make(map[interface{}]bool),
+	}
+}
+
+// lookup returns the replacement value of the given value, and a boolean
+// indicating if the value is replaced. Replacement values are themselves
+// replaced if present in repl.
+func (r *replacer) lookup(v value.Value) (value.Value, bool) {
+	new, ok := r.repl[v]
+	if !ok {
+		return v, false
+	}
+	for {
+		w, ok := r.repl[new]
+		if !ok {
+			return new, true
+		}
+		new = w
+	}
+}
+
+// replaceFunc replaces all uses of replaced values within the given function.
+func (r *replacer) replaceFunc(f *Func) {
+	// Materialize function body if lazily parsed.
+	mustMaterialize(f)
+	idx := f.useIndex
+	r.replaced = false
+	if f.Prefix != nil {
+		f.Prefix = r.replaceConst(f.Prefix)
+	}
+	if f.Prologue != nil {
+		f.Prologue = r.replaceConst(f.Prologue)
+	}
+	if f.Personality != nil {
+		f.Personality = r.replaceConst(f.Personality)
+	}
+	if r.replaced && idx != nil {
+		idx.UpdateUser(f)
+	}
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			if r.replaceOperands(inst) && idx != nil {
+				idx.UpdateUser(inst)
+			}
+			r.replaceMDAttachments(inst)
+		}
+		if block.Term != nil {
+			if r.replaceOperands(block.Term) {
+				resetSuccs(block.Term)
+				if idx != nil {
+					idx.UpdateUser(block.Term)
+				}
+			}
+			r.replaceMDAttachments(block.Term)
+		}
+	}
+}
+
+// replaceMDAttachments replaces all uses of replaced values within the metadata
+// attachments of the given instruction or terminator.
+func (r *replacer) replaceMDAttachments(user value.User) {
+	if md, ok := user.(mdAttacher); ok {
+		r.replaceMetadata(reflect.ValueOf(md.MDAttachments()))
+	}
+}
+
+// replaceOperands replaces all uses of replaced values within the operands of
+// the given instruction or terminator, and reports whether any use was replaced
+// (including within wrapper values updated in place, such as function
+// arguments).
+func (r *replacer) replaceOperands(user value.User) bool {
+	r.replaced = false
+	for _, op := range user.Operands() {
+		if *op == nil {
+			continue
+		}
+		if v := r.replaceValue(*op); v != *op {
+			*op = v
+		}
+	}
+	return r.replaced
+}
+
+// replaceValue returns the replacement of the given operand value. Wrapper
+// values (e.g. function arguments) are updated in place.
+func (r *replacer) replaceValue(v value.Value) value.Value {
+	if new, ok := r.lookup(v); ok {
+		r.replaced = true
+		return new
+	}
+	switch x := v.(type) {
+	case *Arg:
+		x.Value = r.replaceValue(x.Value)
+	case *metadata.Value:
+		if y, ok := x.Value.(value.Value); ok {
+			x.Value = r.replaceValue(y)
+		}
+	case constant.Constant:
+		return r.replaceConst(x)
+	}
+	return v
+}
+
+// replaceConst returns the replacement of the given constant; an updated copy
+// if the constant uses replaced values, and the constant itself otherwise.
+func (r *replacer) replaceConst(c constant.Constant) constant.Constant {
+	if new, ok := r.lookup(c); ok {
+		r.replaced = true
+		return newConst(c, new)
+	}
+	if v, ok := r.consts[c]; ok {
+		if v != c {
+			r.replaced = true
+		}
+		return v
+	}
+	var dup constant.Constant
+	ensureCopy := func() constant.Constant {
+		if dup == nil {
+			dup = cloneConst(c)
+		}
+		return dup
+	}
+	for i, op := range constant.Operands(c) {
+		if *op == nil {
+			continue
+		}
+		if v := r.replaceConst(*op); v != *op {
+			*constant.Operands(ensureCopy())[i] = v
+		}
+	}
+	switch c := c.(type) {
+	case *constant.Index:
+		if v := r.replaceConst(c.Constant); v != c.Constant {
+			ensureCopy().(*constant.Index).Constant = v
+		}
+	case *constant.BlockAddress:
+		if v, ok := r.lookup(c.Block); ok {
+			new, ok := v.(value.Named)
+			if !ok {
+				panic(fmt.Errorf("unable to replace basic block %q of blockaddress constant with %q; expected value.Named, got %T", c.Block.Ident(), v.Ident(), v))
+			}
+			ensureCopy().(*constant.BlockAddress).Block = new
+		}
+	}
+	if dup == nil {
+		r.consts[c] = c
+		return c
+	}
+	r.replaced = true
+	r.consts[c] = dup
+	return dup
+}
+
+// replaceMetadata replaces all uses of replaced values within the given
+// metadata node or metadata field, recursively. Each metadata node is visited
+// once, to handle shared and cyclic metadata.
+func (r *replacer) replaceMetadata(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		elem := v.Elem()
+		if elem.Kind() == reflect.Ptr && elem.Type().Elem().PkgPath() == metadataPkgPath {
+			r.replaceMetadata(elem)
+			return
+		}
+		if val, ok := elem.Interface().(value.Value); ok && v.CanSet() {
+			if new := r.replaceValue(val); new != val {
+				v.Set(reflect.ValueOf(new))
+			}
+		}
+	case reflect.Ptr:
+		if v.IsNil() || v.Type().Elem().PkgPath() != metadataPkgPath {
+			return
+		}
+		x := v.Interface()
+		if r.mds[x] {
+			return
+		}
+		r.mds[x] = true
+		r.replaceMetadata(v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if field := v.Field(i); field.CanSet() {
+				r.replaceMetadata(field)
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			r.replaceMetadata(v.Index(i))
+		}
+	}
+}
+
+// newConst returns the replacement value new of old as a constant, for use
+// within constants.
+func newConst(old, new value.Value) constant.Constant {
+	c, ok := new.(constant.Constant)
+	if !ok {
+		panic(fmt.Errorf("unable to replace use of %q within constant with non-constant value %q", old.Ident(), new.Ident()))
+	}
+	return c
+}
+
+// ### [ Helper functions ] ####################################################
+
+// cloneConst returns a shallow copy of the given constant. Slices of operands
+// are copied, so that operands of the copy may be updated independently.
+func cloneConst(c constant.Constant) constant.Constant {
+	orig := reflect.ValueOf(c).Elem()
+	dup := reflect.New(orig.Type())
+	dup.Elem().Set(orig)
+	switch c := dup.Interface().(type) {
+	case *constant.Struct:
+		c.Fields = append([]constant.Constant(nil), c.Fields...)
+	case *constant.Array:
+		c.Elems = append([]constant.Constant(nil), c.Elems...)
+	case *constant.Vector:
+		c.Elems = append([]constant.Constant(nil), c.Elems...)
+	case *constant.ExprGetElementPtr:
+		c.Indices = append([]constant.Constant(nil), c.Indices...)
+	}
+	return dup.Interface().(constant.Constant)
+}
+
+// containsUseIndex reports whether the given use index is present in idxs.
+func containsUseIndex(idxs []*UseIndex, idx *UseIndex) bool {
+	for _, v := range idxs {
+		if v == idx {
+			return true
+		}
+	}
+	return false
+}
+
+// resetSuccs resets the cached successor basic blocks of the given terminator.
+func resetSuccs(term Terminator) {
+	switch term := term.(type) {
+	case *TermBr:
+		term.Successors = nil
+	case *TermCondBr:
+		term.Successors = nil
+	case *TermSwitch:
+		term.Successors = nil
+	case *TermIndirectBr:
+		term.Successors = nil
+	case *TermInvoke:
+		term.Successors = nil
+	case *TermCallBr:
+		term.Successors = nil
+	case *TermCatchSwitch:
+		term.Successors = nil
+	case *TermCatchRet:
+		term.Successors = nil
+	case *TermCleanupRet:
+		term.Successors = nil
+	}
+}
@@ -0,0 +1,168 @@
+package ir
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/metadata"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+func TestFuncReplaceAllUsesWith(t *testing.T) {
+	m := NewModule()
+	g := m.NewGlobalDef("g", constant.NewInt(types.I32, 0))
+	use := m.NewFunc("use", types.Void, NewParam("", types.I32), NewParam("", types.Metadata))
+	// Constant expression shared between functions.
+	expr := constant.NewPtrToInt(g, types.I32)
+	x := NewParam("x", types.I32)
+	f := m.NewFunc("f", types.I32, x)
+	entry := f.NewBlock("entry")
+	exit := f.NewBlock("exit")
+	a := entry.NewAdd(x, constant.NewInt(types.I32, 1))
+	b := entry.NewMul(a, a)
+	entry.NewCall(use, NewArg(a), &metadata.Value{Value: a})
+	sub := entry.NewSub(expr, b)
+	// Metadata attachment of instruction.
+	sub.Metadata = append(sub.Metadata, &metadata.Attachment{Name: "foo", Node: &metadata.Tuple{MetadataID: -1, Fields: []metadata.Field{g}}})
+	entry.NewBr(exit)
+	phi := exit.NewPhi(NewIncoming(a, entry))
+	exit.NewRet(phi)
+	h := m.NewFunc("h", types.I32)
+	h.NewBlock("").NewRet(expr)
+
+	f.ReplaceAllUsesWith(a, x)
+	f.ReplaceAllUsesWith(g, constant.NewNull(types.I32Ptr))
+
+	const want = `@g = global i32 0
+
+declare void @use(i32 %0, metadata %1)
+
+define i32 @f(i32 %x) {
+entry:
+	%0 = add i32 %x, 1
+	%1 = mul i32 %x, %x
+	call void @use(i32 %x, metadata i32 %x)
+	%2 = sub i32 ptrtoint (i32* null to i32), %1, !foo !{i32* null}
+	br label %exit
+
+exit:
+	%3 = phi i32 [ %x, %entry ]
+	ret i32 %3
+}
+
+define i32 @h() {
+0:
+	ret i32 ptrtoint (i32* @g to i32)
+}
+`
+	got := m.String()
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("module mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFuncReplaceAllUses(t *testing.T) {
+	x := NewParam("x", types.I32)
+	f := NewFunc("f", types.I32, x)
+	entry := f.NewBlock("")
+	a := entry.NewAdd(x, constant.NewInt(types.I32, 1))
+	b := entry.NewMul(a, x)
+	c := entry.NewSub(b, a)
+	entry.NewRet(c)
+
+	// Replacement values are themselves replaced; %a is replaced by %b, which is
+	// replaced by %x.
+	f.ReplaceAllUses(map[value.Value]value.Value{
+		a: b,
+		b: x,
+	})
+
+	const want = `define i32 @f(i32 %x) {
+0:
+	%1 = add i32 %x, 1
+	%2 = mul i32 %x, %x
+	%3 = sub i32 %x, %x
+	ret i32 %3
+}`
+	got := f.LLString()
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("function mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestModuleReplaceAllUsesWith(t *testing.T) {
+	m := NewModule()
+	g := m.NewGlobalDef("g", constant.NewInt(types.I32, 0))
+	g2 := m.NewGlobalDef("g2", constant.NewInt(types.I32, 1))
+	m.NewGlobalDef("p", constant.NewArray(types.NewArray(2, types.I32Ptr), g, constant.NewGetElementPtr(types.I32, g, constant.NewInt(types.I64, 1))))
+	f := m.NewFunc("f", types.Void)
+	entry := f.NewBlock("entry")
+	exit := f.NewBlock("exit")
+	entry.NewStore(constant.NewInt(types.I32, 2), g)
+	entry.NewSwitch(constant.NewInt(types.I32, 0), exit, NewCase(constant.NewPtrToInt(g, types.I32), exit))
+	ret := exit.NewRet(nil)
+	// Metadata attachment of terminator.
+	ret.Metadata = append(ret.Metadata, &metadata.Attachment{Name: "bar", Node: &metadata.Tuple{MetadataID: -1, Fields: []metadata.Field{&metadata.Value{Value: g}}}})
+	md := &metadata.Tuple{MetadataID: 0, Fields: []metadata.Field{g}}
+	m.MetadataDefs = append(m.MetadataDefs, md)
+	// Nested metadata tuple.
+	nested := &metadata.Tuple{MetadataID: -1, Fields: []metadata.Field{g}}
+	m.MetadataDefs = append(m.MetadataDefs, &metadata.Tuple{MetadataID: 1, Fields: []metadata.Field{nested}})
+	// Cyclic metadata tuple used by named metadata definition.
+	cyclic := &metadata.Tuple{MetadataID: 2, Distinct: true}
+	cyclic.Fields = []metadata.Field{cyclic, &metadata.Value{Value: g}}
+	m.MetadataDefs = append(m.MetadataDefs, cyclic)
+	m.NamedMetadataDefs["foo"] = &metadata.NamedDef{Name: "foo", Nodes: []metadata.Node{cyclic}}
+	// Specialized metadata node.
+	param := &metadata.DITemplateValueParameter{MetadataID: 3, Type: &metadata.DIBasicType{MetadataID: -1, Name: "int"}, Value: g}
+	m.MetadataDefs = append(m.MetadataDefs, param)
+
+	m.ReplaceAllUsesWith(g, g2)
+
+	const want = `@g = global i32 0
+@g2 = global i32 1
+@p = global [2 x i32*] [i32* @g2, i32* getelementptr (i32, i32* @g2, i64 1)]
+
+define void @f() {
+entry:
+	store i32 2, i32* @g2
+	switch i32 0, label %exit [
+		i32 ptrtoint (i32* @g2 to i32), label %exit
+	]
+
+exit:
+	ret void, !bar !{metadata i32* @g2}
+}
+
+!foo = !{!2}
+
+!0 = !{i32* @g2}
+!1 = !{!{i32* @g2}}
+!2 = distinct !{!2, metadata i32* @g2}
+!3 = !DITemplateValueParameter(type: !DIBasicType(name: "int"), value: i32* @g2)
+`
+	got := m.String()
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("module mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReplaceAllUsesWithBlock(t *testing.T) {
+	f := NewFunc("f", types.Void)
+	entry := f.NewBlock("entry")
+	a := f.NewBlock("a")
+	b := f.NewBlock("b")
+	br := entry.NewBr(a)
+	a.NewRet(nil)
+	b.NewRet(nil)
+	// Cache successors.
+	if succs := br.Succs(); len(succs) != 1 || succs[0] != a {
+		t.Fatalf("successor mismatch; expected %%a, got %v", succs)
+	}
+	f.ReplaceAllUsesWith(a, b)
+	if succs := br.Succs(); len(succs) != 1 || succs[0] != b {
+		t.Errorf("successor mismatch; expected %%b, got %v", succs)
+	}
+}
@@ -0,0 +1,396 @@
+// Package target provides parsing of target triples, and information about
+// common targets; the default data layout and basic properties of the C ABI.
+//
+// The target information mirrors the defaults of LLVM and Clang, for use by
+// frontends which generate LLVM IR without invoking Clang.
+package target
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/types"
+	"github.com/pkg/errors"
+)
+
+// Info is target information of a target triple.
+type Info struct {
+	// Target triple.
+	Triple Triple
+	// Default data layout string of the target.
+	DataLayout string
+
+	// Basic properties of the C ABI of the target.
+
+	// Specifies whether char is signed.
+	CharSigned bool
+	// Size in bits of short.
+	ShortSize uint64
+	// Size in bits of int.
+	IntSize uint64
+	// Size in bits of long.
+	LongSize uint64
+	// Size in bits of long long.
+	LongLongSize uint64
+	// Size in bits of pointers.
+	PointerSize uint64
+	// Floating-point type of long double.
+	LongDouble *types.FloatType
+	// Type of va_list. Struct types of va_list are named (e.g.
+	// struct.__va_list_tag), and unique to the target information.
+	VaList types.Type
+}
+
+// Lookup returns target information of the given target triple. An error is
+// returned if the architecture of the target triple is not supported.
+func Lookup(triple string) (*Info, error) {
+	t := ParseTriple(triple)
+	newInfo, ok := targets[t.Arch]
+	if !ok {
+		return nil, errors.Errorf("unsupported target triple %q; unknown architecture %q", triple, t.Arch)
+	}
+	return newInfo(t), nil
+}
+
+// LookupModule returns target information of the target triple of the given
+// module.
+func LookupModule(m *ir.Module) (*Info, error) {
+	if len(m.TargetTriple) == 0 {
+		return nil, errors.New("unable to locate target information; module has no target triple")
+	}
+	return Lookup(m.TargetTriple)
+}
+
+// NewModule returns a new LLVM IR module with the target triple and default
+// data layout of the target.
+func (info *Info) NewModule() *ir.Module {
+	m := ir.NewModule()
+	m.TargetTriple = info.Triple.String()
+	m.DataLayout = info.DataLayout
+	return m
+}
+
+// targets maps from supported architectures to functions returning target
+// information of target triples of the architecture.
+var targets = map[Arch]func(t Triple) *Info{
+	ArchAArch64:   newAArch64Info,
+	ArchAArch64BE: newAArch64Info,
+	ArchARM:       newARMInfo,
+	ArchARMEB:     newARMInfo,
+	ArchAVR:       newAVRInfo,
+	ArchMips:      newMipsInfo,
+	ArchMipsel:    newMipsInfo,
+	ArchMips64:    newMipsInfo,
+	ArchMips64el:  newMipsInfo,
+	ArchPPC:       newPPCInfo,
+	ArchPPC64:     newPPCInfo,
+	ArchPPC64LE:   newPPCInfo,
+	ArchRISCV32:   newRISCVInfo,
+	ArchRISCV64:   newRISCVInfo,
+	ArchSystemZ:   newSystemZInfo,
+	ArchThumb:     newARMInfo,
+	ArchThumbEB:   newARMInfo,
+	ArchWasm32:    newWasmInfo,
+	ArchWasm64:    newWasmInfo,
+	ArchX86:       newX86Info,
+	ArchX86_64:    newX86Info,
+}
+
+// newInfo returns new target information of the given target triple, with the
+// C ABI defaults of LP64 targets if the triple is 64-bit, and ILP32 targets
+// otherwise; long double is double and va_list is i8*. The data layout is left
+// unspecified.
+func newInfo(t Triple) *Info {
+	info := &Info{
+		Triple:       t,
+		CharSigned:   true,
+		ShortSize:    16,
+		IntSize:      32,
+		LongSize:     32,
+		LongLongSize: 64,
+		PointerSize:  32,
+		LongDouble:   types.Double,
+		VaList:       types.I8Ptr,
+	}
+	if t.Is64Bit() {
+		info.LongSize = 64
+		info.PointerSize = 64
+	}
+	return info
+}
+
+// --- [ x86 ] -----------------------------------------------------------------
+
+// newX86Info returns target information of the given x86 or x86-64 target
+// triple.
+func newX86Info(t Triple) *Info {
+	info := newInfo(t)
+	is64 := t.Arch == ArchX86_64
+	msvc := t.IsWindows() && t.Env == EnvMSVC
+	// Data layout.
+	dl := "e" + mangling(t)
+	if !t.Is64Bit() {
+		dl += "-p:32:32"
+	}
+	dl += "-p270:32:32-p271:32:32-p272:64:64"
+	if is64 || t.IsWindows() {
+		dl += "-i64:64"
+	} else {
+		dl += "-f64:32:64"
+	}
+	if is64 || t.IsDarwin() || msvc {
+		dl += "-f80:128"
+	} else {
+		dl += "-f80:32"
+	}
+	if is64 {
+		dl += "-n8:16:32:64"
+	} else {
+		dl += "-n8:16:32"
+	}
+	if !is64 && t.IsWindows() {
+		dl += "-a:0:32-S32"
+	} else {
+		dl += "-S128"
+	}
+	info.DataLayout = dl
+	// C ABI.
+	if msvc {
+		info.LongDouble = types.Double
+	} else {
+		info.LongDouble = types.X86_FP80
+	}
+	if is64 && t.IsWindows() && t.Env != EnvCygnus {
+		// LLP64.
+		info.LongSize = 32
+	} else if is64 {
+		// typedef struct {
+		//    unsigned int gp_offset;
+		//    unsigned int fp_offset;
+		//    void *overflow_arg_area;
+		//    void *reg_save_area;
+		// } va_list[1];
+		vaListTag := types.NewStruct(types.I32, types.I32, types.I8Ptr, types.I8Ptr)
+		vaListTag.SetName("struct.__va_list_tag")
+		info.VaList = types.NewArray(1, vaListTag)
+	}
+	return info
+}
+
+// --- [ AArch64 ] -------------------------------------------------------------
+
+// newAArch64Info returns target information of the given AArch64 target triple.
+func newAArch64Info(t Triple) *Info {
+	info := newInfo(t)
+	switch {
+	case t.IsDarwin():
+		info.DataLayout = "e-m:o-i64:64-i128:128-n32:64-S128"
+	case t.IsWindows():
+		info.DataLayout = "e-m:w-p:64:64-i32:32-i64:64-i128:128-n32:64-S128"
+		// LLP64.
+		info.LongSize = 32
+	default:
+		info.DataLayout = endian(t) + "-m:e-i8:8:32-i16:16:32-i64:64-i128:128-n32:64-S128"
+		info.CharSigned = false
+		info.LongDouble = types.FP128
+		// typedef struct {
+		//    void *__stack;
+		//    void *__gr_top;
+		//    void *__vr_top;
+		//    int __gr_offs;
+		//    int __vr_offs;
+		// } va_list;
+		vaList := types.NewStruct(types.I8Ptr, types.I8Ptr, types.I8Ptr, types.I32, types.I32)
+		vaList.SetName("struct.__va_list")
+		info.VaList = vaList
+	}
+	return info
+}
+
+// --- [ ARM ] -----------------------------------------------------------------
+
+// newARMInfo returns target information of the given ARM or Thumb target
+// triple.
+func newARMInfo(t Triple) *Info {
+	info := newInfo(t)
+	dl := endian(t) + mangling(t) + "-p:32:32-Fi8"
+	switch {
+	case t.OS == OSWatchOS:
+		// AAPCS16 ABI.
+		dl += "-i64:64-a:0:32-n32-S128"
+	case t.IsDarwin() && t.Env != EnvEABI && t.Env != EnvEABIHF:
+		// APCS ABI.
+		dl += "-f64:32:64-v64:32:64-v128:32:128-a:0:32-n32-S32"
+	default:
+		// AAPCS ABI.
+		dl += "-i64:64-v128:64:128-a:0:32-n32-S64"
+		if !t.IsDarwin() && !t.IsWindows() {
+			info.CharSigned = false
+			// typedef struct {
+			//    void *__ap;
+			// } va_list;
+			vaList := types.NewStruct(types.I8Ptr)
+			vaList.SetName("struct.__va_list")
+			info.VaList = vaList
+		}
+	}
+	info.DataLayout = dl
+	return info
+}
+
+// --- [ AVR ] -----------------------------------------------------------------
+
+// newAVRInfo returns target information of the given AVR target triple.
+func newAVRInfo(t Triple) *Info {
+	info := newInfo(t)
+	info.DataLayout = "e-P1-p:16:8-i8:8-i16:8-i32:8-i64:8-f32:8-f64:8-n8-a:8"
+	info.IntSize = 16
+	info.PointerSize = 16
+	info.LongDouble = types.Float
+	return info
+}
+
+// --- [ MIPS ] ----------------------------------------------------------------
+
+// newMipsInfo returns target information of the given MIPS target triple. The
+// O32 ABI is used for 32-bit MIPS, and the N64 ABI for 64-bit MIPS.
+func newMipsInfo(t Triple) *Info {
+	info := newInfo(t)
+	if t.Is64Bit() {
+		info.DataLayout = endian(t) + "-m:e-i8:8:32-i16:16:32-i64:64-n32:64-S128"
+		info.LongDouble = types.FP128
+	} else {
+		info.DataLayout = endian(t) + "-m:m-p:32:32-i8:8:32-i16:16:32-i64:64-n32-S64"
+	}
+	return info
+}
+
+// --- [ PowerPC ] -------------------------------------------------------------
+
+// newPPCInfo returns target information of the given PowerPC target triple.
+func newPPCInfo(t Triple) *Info {
+	info := newInfo(t)
+	info.CharSigned = false
+	dl := endian(t) + "-m:e"
+	if t.OS == OSAIX {
+		// XCOFF.
+		dl = endian(t) + "-m:a"
+	}
+	if !t.Is64Bit() {
+		dl += "-p:32:32"
+	}
+	dl += "-i64:64"
+	if t.Is64Bit() {
+		dl += "-n32:64"
+		if t.OS == OSLinux || t.OS == OSAIX {
+			dl += "-S128-v256:256:256-v512:512:512"
+		}
+	} else {
+		dl += "-n32"
+	}
+	info.DataLayout = dl
+	switch {
+	case t.OS == OSAIX, t.OS == OSFreeBSD, t.Env == EnvMusl:
+		info.LongDouble = types.Double
+	default:
+		info.LongDouble = types.PPC_FP128
+	}
+	if !t.Is64Bit() && t.OS != OSAIX {
+		// typedef struct {
+		//    unsigned char gpr;
+		//    unsigned char fpr;
+		//    unsigned short reserved;
+		//    void *overflow_arg_area;
+		//    void *reg_save_area;
+		// } va_list[1];
+		vaListTag := types.NewStruct(types.I8, types.I8, types.I16, types.I8Ptr, types.I8Ptr)
+		vaListTag.SetName("struct.__va_list_tag")
+		info.VaList = types.NewArray(1, vaListTag)
+	}
+	return info
+}
+
+// --- [ RISC-V ] --------------------------------------------------------------
+
+// newRISCVInfo returns target information of the given RISC-V target triple.
+func newRISCVInfo(t Triple) *Info {
+	info := newInfo(t)
+	if t.Is64Bit() {
+		info.DataLayout = "e-m:e-p:64:64-i64:64-i128:128-n64-S128"
+	} else {
+		info.DataLayout = "e-m:e-p:32:32-i64:64-n32-S128"
+	}
+	info.CharSigned = false
+	info.LongDouble = types.FP128
+	return info
+}
+
+// --- [ SystemZ ] -------------------------------------------------------------
+
+// newSystemZInfo returns target information of the given SystemZ target triple.
+func newSystemZInfo(t Triple) *Info {
+	info := newInfo(t)
+	info.DataLayout = "E-m:e-i1:8:16-i8:8:16-i64:64-f128:64-a:8:16-n32:64"
+	info.CharSigned = false
+	info.LongDouble = types.FP128
+	// typedef struct {
+	//    long __gpr;
+	//    long __fpr;
+	//    void *__overflow_arg_area;
+	//    void *__reg_save_area;
+	// } va_list[1];
+	vaListTag := types.NewStruct(types.I64, types.I64, types.I8Ptr, types.I8Ptr)
+	vaListTag.SetName("struct.__va_list_tag")
+	info.VaList = types.NewArray(1, vaListTag)
+	return info
+}
+
+// --- [ WebAssembly ] ---------------------------------------------------------
+
+// newWasmInfo returns target information of the given WebAssembly target
+// triple.
+func newWasmInfo(t Triple) *Info {
+	info := newInfo(t)
+	dl := "e-m:e"
+	if t.Is64Bit() {
+		dl += "-p:64:64"
+	} else {
+		dl += "-p:32:32"
+	}
+	dl += "-p10:8:8-p20:8:8-i64:64"
+	if t.OS == OSEmscripten {
+		dl += "-f128:64"
+	}
+	dl += "-n32:64-S128-ni:1:10:20"
+	info.DataLayout = dl
+	info.LongDouble = types.FP128
+	return info
+}
+
+// ### [ Helper functions ] ####################################################
+
+// endian returns the endianness specification of the data layout of the given
+// target triple.
+func endian(t Triple) string {
+	if t.IsLittleEndian() {
+		return "e"
+	}
+	return "E"
+}
+
+// mangling returns the mangling specification of the data layout of the given
+// target triple, including the leading dash.
+func mangling(t Triple) string {
+	switch {
+	case t.IsDarwin():
+		// Mach-O.
+		return "-m:o"
+	case t.IsWindows():
+		// COFF.
+		if t.Arch == ArchX86 {
+			return "-m:x"
+		}
+		return "-m:w"
+	default:
+		// ELF.
+		return "-m:e"
+	}
+}
@@ -0,0 +1,186 @@
+package target_test
+
+import (
+	"testing"
+
+	"github.com/llir/llvm/ir/datalayout"
+	"github.com/llir/llvm/ir/target"
+	"github.com/llir/llvm/ir/types"
+)
+
+func TestParseTriple(t *testing.T) {
+	golden := []struct {
+		in     string
+		arch   target.Arch
+		vendor target.Vendor
+		os     target.OS
+		env    target.Environment
+	}{
+		{in: "x86_64-unknown-linux-gnu", arch: target.ArchX86_64, vendor: target.VendorUnknown, os: target.OSLinux, env: target.EnvGNU},
+		{in: "x86_64-linux-gnu", arch: target.ArchX86_64, vendor: target.VendorUnknown, os: target.OSLinux, env: target.EnvGNU},
+		{in: "amd64-unknown-freebsd12.2", arch: target.ArchX86_64, vendor: target.VendorUnknown, os: target.OSFreeBSD, env: target.EnvUnknown},
+		{in: "x86_64-pc-windows-msvc19.29.30133", arch: target.ArchX86_64, vendor: target.VendorPC, os: target.OSWindows, env: target.EnvMSVC},
+		{in: "i686-pc-win32", arch: target.ArchX86, vendor: target.VendorPC, os: target.OSWindows, env: target.EnvMSVC},
+		{in: "i686-w64-mingw32", arch: target.ArchX86, vendor: target.VendorUnknown, os: target.OSWindows, env: target.EnvGNU},
+		{in: "x86_64-pc-cygwin", arch: target.ArchX86_64, vendor: target.VendorPC, os: target.OSWindows, env: target.EnvCygnus},
+		{in: "arm64-apple-macosx12.0.0", arch: target.ArchAArch64, vendor: target.VendorApple, os: target.OSMacOSX, env: target.EnvUnknown},
+		{in: "arm64-apple-ios15.0-simulator", arch: target.ArchAArch64, vendor: target.VendorApple, os: target.OSIOS, env: target.EnvSimulator},
+		{in: "aarch64-linux-android30", arch: target.ArchAArch64, vendor: target.VendorUnknown, os: target.OSLinux, env: target.EnvAndroid},
+		{in: "armv7-unknown-linux-gnueabihf", arch: target.ArchARM, vendor: target.VendorUnknown, os: target.OSLinux, env: target.EnvGNUEABIHF},
+		{in: "arm-none-eabi", arch: target.ArchARM, vendor: target.VendorUnknown, os: target.OSUnknown, env: target.EnvEABI},
+		{in: "thumbv7em-none-eabihf", arch: target.ArchThumb, vendor: target.VendorUnknown, os: target.OSUnknown, env: target.EnvEABIHF},
+		{in: "armebv7-unknown-linux-musleabi", arch: target.ArchARMEB, vendor: target.VendorUnknown, os: target.OSLinux, env: target.EnvMuslEABI},
+		{in: "riscv64-unknown-linux-gnu", arch: target.ArchRISCV64, vendor: target.VendorUnknown, os: target.OSLinux, env: target.EnvGNU},
+		{in: "riscv32-unknown-elf", arch: target.ArchRISCV32, vendor: target.VendorUnknown, os: target.OSUnknown, env: target.EnvUnknown},
+		{in: "wasm32-wasi", arch: target.ArchWasm32, vendor: target.VendorUnknown, os: target.OSWASI, env: target.EnvUnknown},
+		{in: "wasm64-unknown-emscripten", arch: target.ArchWasm64, vendor: target.VendorUnknown, os: target.OSEmscripten, env: target.EnvUnknown},
+		{in: "ppc64le-redhat-linux", arch: target.ArchPPC64LE, vendor: target.VendorRedHat, os: target.OSLinux, env: target.EnvUnknown},
+		{in: "powerpc64-ibm-aix7.2.0.0", arch: target.ArchPPC64, vendor: target.VendorIBM, os: target.OSAIX, env: target.EnvUnknown},
+		{in: "mips64el-linux-gnuabi64", arch: target.ArchMips64el, vendor: target.VendorUnknown, os: target.OSLinux, env: target.EnvGNUABI64},
+		{in: "s390x-ibm-linux", arch: target.ArchSystemZ, vendor: target.VendorIBM, os: target.OSLinux, env: target.EnvUnknown},
+		{in: "avr", arch: target.ArchAVR, vendor: target.VendorUnknown, os: target.OSUnknown, env: target.EnvUnknown},
+		{in: "foo-bar-baz-qux", arch: target.ArchUnknown, vendor: target.VendorUnknown, os: target.OSUnknown, env: target.EnvUnknown},
+	}
+	for _, g := range golden {
+		got := target.ParseTriple(g.in)
+		if got.Arch != g.arch {
+			t.Errorf("%q: architecture mismatch; expected %v, got %v", g.in, g.arch, got.Arch)
+		}
+		if got.Vendor != g.vendor {
+			t.Errorf("%q: vendor mismatch; expected %v, got %v", g.in, g.vendor, got.Vendor)
+		}
+		if got.OS != g.os {
+			t.Errorf("%q: operating system mismatch; expected %v, got %v", g.in, g.os, got.OS)
+		}
+		if got.Env != g.env {
+			t.Errorf("%q: environment mismatch; expected %v, got %v", g.in, g.env, got.Env)
+		}
+		if got.String() != g.in {
+			t.Errorf("%q: triple string mismatch; expected %q, got %q", g.in, g.in, got.String())
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	// Expected data layouts have been verified against LLVM 14.
+	golden := []struct {
+		triple     string
+		dataLayout string
+		charSigned bool
+		// Size in bits of int, long and pointers.
+		intSize, longSize, ptrSize uint64
+		longDouble                 types.Type
+		vaList                     string
+	}{
+		{triple: "x86_64-unknown-linux-gnu", dataLayout: "e-m:e-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128", charSigned: true, intSize: 32, longSize: 64, ptrSize: 64, longDouble: types.X86_FP80, vaList: "[1 x %struct.__va_list_tag]"},
+		{triple: "x86_64-unknown-linux-gnux32", dataLayout: "e-m:e-p:32:32-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128", charSigned: true, intSize: 32, longSize: 32, ptrSize: 32, longDouble: types.X86_FP80, vaList: "[1 x %struct.__va_list_tag]"},
+		{triple: "x86_64-apple-macosx10.15", dataLayout: "e-m:o-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128", charSigned: true, intSize: 32, longSize: 64, ptrSize: 64, longDouble: types.X86_FP80, vaList: "[1 x %struct.__va_list_tag]"},
+		{triple: "x86_64-pc-windows-msvc", dataLayout: "e-m:w-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128", charSigned: true, intSize: 32, longSize: 32, ptrSize: 64, longDouble: types.Double, vaList: "i8*"},
+		{triple: "x86_64-w64-windows-gnu", dataLayout: "e-m:w-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128", charSigned: true, intSize: 32, longSize: 32, ptrSize: 64, longDouble: types.X86_FP80, vaList: "i8*"},
+		{triple: "i686-unknown-linux-gnu", dataLayout: "e-m:e-p:32:32-p270:32:32-p271:32:32-p272:64:64-f64:32:64-f80:32-n8:16:32-S128", charSigned: true, intSize: 32, longSize: 32, ptrSize: 32, longDouble: types.X86_FP80, vaList: "i8*"},
+		{triple: "i386-apple-macosx", dataLayout: "e-m:o-p:32:32-p270:32:32-p271:32:32-p272:64:64-f64:32:64-f80:128-n8:16:32-S128", charSigned: true, intSize: 32, longSize: 32, ptrSize: 32, longDouble: types.X86_FP80, vaList: "i8*"},
+		{triple: "i686-pc-windows-msvc", dataLayout: "e-m:x-p:32:32-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32-a:0:32-S32", charSigned: true, intSize: 32, longSize: 32, ptrSize: 32, longDouble: types.Double, vaList: "i8*"},
+		{triple: "i686-w64-mingw32", dataLayout: "e-m:x-p:32:32-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:32-n8:16:32-a:0:32-S32", charSigned: true, intSize: 32, longSize: 32, ptrSize: 32, longDouble: types.X86_FP80, vaList: "i8*"},
+		{triple: "aarch64-unknown-linux-gnu", dataLayout: "e-m:e-i8:8:32-i16:16:32-i64:64-i128:128-n32:64-S128", charSigned: false, intSize: 32, longSize: 64, ptrSize: 64, longDouble: types.FP128, vaList: "%struct.__va_list"},
+		{triple: "aarch64_be-unknown-linux-gnu", dataLayout: "E-m:e-i8:8:32-i16:16:32-i64:64-i128:128-n32:64-S128", charSigned: false, intSize: 32, longSize: 64, ptrSize: 64, longDouble: types.FP128, vaList: "%struct.__va_list"},
+		{triple: "arm64-apple-macosx", dataLayout: "e-m:o-i64:64-i128:128-n32:64-S128", charSigned: true, intSize: 32, longSize: 64, ptrSize: 64, longDouble: types.Double, vaList: "i8*"},
+		{triple: "aarch64-pc-windows-msvc", dataLayout: "e-m:w-p:64:64-i32:32-i64:64-i128:128-n32:64-S128", charSigned: true, intSize: 32, longSize: 32, ptrSize: 64, longDouble: types.Double, vaList: "i8*"},
+		{triple: "armv7-unknown-linux-gnueabihf", dataLayout: "e-m:e-p:32:32-Fi8-i64:64-v128:64:128-a:0:32-n32-S64", charSigned: false, intSize: 32, longSize: 32, ptrSize: 32, longDouble: types.Double, vaList: "%struct.__va_list"},
+		{triple: "armeb-unknown-linux-gnueabi", dataLayout: "E-m:e-p:32:32-Fi8-i64:64-v128:64:128-a:0:32-n32-S64", charSigned: false, intSize: 32, longSize: 32, ptrSize: 32, longDouble: types.Double, vaList: "%struct.__va_list"},
+		{triple: "thumbv7m-none-eabi", dataLayout: "e-m:e-p:32:32-Fi8-i64:64-v128:64:128-a:0:32-n32-S64", charSigned: false, intSize: 32, longSize: 32, ptrSize: 32, longDouble: types.Double, vaList: "%struct.__va_list"},
+		{triple: "armv7-apple-ios", dataLayout: "e-m:o-p:32:32-Fi8-f64:32:64-v64:32:64-v128:32:128-a:0:32-n32-S32", charSigned: true, intSize: 32, longSize: 32, ptrSize: 32, longDouble: types.Double, vaList: "i8*"},
+		{triple: "thumbv7-pc-windows-msvc", dataLayout: "e-m:w-p:32:32-Fi8-i64:64-v128:64:128-a:0:32-n32-S64", charSigned: true, intSize: 32, longSize: 32, ptrSize: 32, longDouble: types.Double, vaList: "i8*"},
+		{triple: "riscv32-unknown-elf", dataLayout: "e-m:e-p:32:32-i64:64-n32-S128", charSigned: false, intSize: 32, longSize: 32, ptrSize: 32, longDouble: types.FP128, vaList: "i8*"},
+		{triple: "riscv64-unknown-linux-gnu", dataLayout: "e-m:e-p:64:64-i64:64-i128:128-n64-S128", charSigned: false, intSize: 32, longSize: 64, ptrSize: 64, longDouble: types.FP128, vaList: "i8*"},
+		{triple: "wasm32-unknown-unknown", dataLayout: "e-m:e-p:32:32-p10:8:8-p20:8:8-i64:64-n32:64-S128-ni:1:10:20", charSigned: true, intSize: 32, longSize: 32, ptrSize: 32, longDouble: types.FP128, vaList: "i8*"},
+		{triple: "wasm32-unknown-emscripten", dataLayout: "e-m:e-p:32:32-p10:8:8-p20:8:8-i64:64-f128:64-n32:64-S128-ni:1:10:20", charSigned: true, intSize: 32, longSize: 32, ptrSize: 32, longDouble: types.FP128, vaList: "i8*"},
+		{triple: "wasm64-unknown-unknown", dataLayout: "e-m:e-p:64:64-p10:8:8-p20:8:8-i64:64-n32:64-S128-ni:1:10:20", charSigned: true, intSize: 32, longSize: 64, ptrSize: 64, longDouble: types.FP128, vaList: "i8*"},
+		{triple: "powerpc-unknown-linux-gnu", dataLayout: "E-m:e-p:32:32-i64:64-n32", charSigned: false, intSize: 32, longSize: 32, ptrSize: 32, longDouble: types.PPC_FP128, vaList: "[1 x %struct.__va_list_tag]"},
+		{triple: "powerpc64-unknown-linux-gnu", dataLayout: "E-m:e-i64:64-n32:64-S128-v256:256:256-v512:512:512", charSigned: false, intSize: 32, longSize: 64, ptrSize: 64, longDouble: types.PPC_FP128, vaList: "i8*"},
+		{triple: "powerpc64le-unknown-linux-gnu", dataLayout: "e-m:e-i64:64-n32:64-S128-v256:256:256-v512:512:512", charSigned: false, intSize: 32, longSize: 64, ptrSize: 64, longDouble: types.PPC_FP128, vaList: "i8*"},
+		{triple: "powerpc64-ibm-aix", dataLayout: "E-m:a-i64:64-n32:64-S128-v256:256:256-v512:512:512", charSigned: false, intSize: 32, longSize: 64, ptrSize: 64, longDouble: types.Double, vaList: "i8*"},
+		{triple: "powerpc64-unknown-freebsd", dataLayout: "E-m:e-i64:64-n32:64", charSigned: false, intSize: 32, longSize: 64, ptrSize: 64, longDouble: types.Double, vaList: "i8*"},
+		{triple: "mips-unknown-linux-gnu", dataLayout: "E-m:m-p:32:32-i8:8:32-i16:16:32-i64:64-n32-S64", charSigned: true, intSize: 32, longSize: 32, ptrSize: 32, longDouble: types.Double, vaList: "i8*"},
+		{triple: "mips64el-unknown-linux-gnuabi64", dataLayout: "e-m:e-i8:8:32-i16:16:32-i64:64-n32:64-S128", charSigned: true, intSize: 32, longSize: 64, ptrSize: 64, longDouble: types.FP128, vaList: "i8*"},
+		{triple: "s390x-unknown-linux-gnu", dataLayout: "E-m:e-i1:8:16-i8:8:16-i64:64-f128:64-a:8:16-n32:64", charSigned: false, intSize: 32, longSize: 64, ptrSize: 64, longDouble: types.FP128, vaList: "[1 x %struct.__va_list_tag]"},
+		{triple: "avr-unknown-unknown", dataLayout: "e-P1-p:16:8-i8:8-i16:8-i32:8-i64:8-f32:8-f64:8-n8-a:8", charSigned: true, intSize: 16, longSize: 32, ptrSize: 16, longDouble: types.Float, vaList: "i8*"},
+	}
+	for _, g := range golden {
+		info, err := target.Lookup(g.triple)
+		if err != nil {
+			t.Errorf("%q: unable to locate target information; %+v", g.triple, err)
+			continue
+		}
+		if info.DataLayout != g.dataLayout {
+			t.Errorf("%q: data layout mismatch; expected %q, got %q", g.triple, g.dataLayout, info.DataLayout)
+		}
+		if info.CharSigned != g.charSigned {
+			t.Errorf("%q: char signedness mismatch; expected %v, got %v", g.triple, g.charSigned, info.CharSigned)
+		}
+		if info.IntSize != g.intSize {
+			t.Errorf("%q: int size mismatch; expected %d, got %d", g.triple, g.intSize, info.IntSize)
+		}
+		if info.LongSize != g.longSize {
+			t.Errorf("%q: long size mismatch; expected %d, got %d", g.triple, g.longSize, info.LongSize)
+		}
+		if info.PointerSize != g.ptrSize {
+			t.Errorf("%q: pointer size mismatch; expected %d, got %d", g.triple, g.ptrSize, info.PointerSize)
+		}
+		if !info.LongDouble.Equal(g.longDouble) {
+			t.Errorf("%q: long double mismatch; expected %q, got %q", g.triple, g.longDouble, info.LongDouble)
+		}
+		if got := info.VaList.String(); got != g.vaList {
+			t.Errorf("%q: va_list mismatch; expected %q, got %q", g.triple, g.vaList, got)
+		}
+		// Check consistency of the C ABI with the data layout.
+		dl, err := datalayout.Parse(info.DataLayout)
+		if err != nil {
+			t.Errorf("%q: unable to parse data layout; %+v", g.triple, err)
+			continue
+		}
+		if got := dl.PointerLayout(0).Size; got != info.PointerSize {
+			t.Errorf("%q: pointer size mismatch of data layout; expected %d, got %d", g.triple, info.PointerSize, got)
+		}
+		if got := dl.BitSizeOf(info.LongDouble); got > dl.SizeOf(info.LongDouble)*8 {
+			t.Errorf("%q: invalid size of long double; bit size %d exceeds size %d", g.triple, got, dl.SizeOf(info.LongDouble)*8)
+		}
+	}
+}
+
+func TestLookupError(t *testing.T) {
+	golden := []struct {
+		triple string
+		err    string
+	}{
+		{triple: "", err: `unsupported target triple ""; unknown architecture "unknown"`},
+		{triple: "sparc-sun-solaris", err: `unsupported target triple "sparc-sun-solaris"; unknown architecture "unknown"`},
+	}
+	for _, g := range golden {
+		if _, err := target.Lookup(g.triple); err == nil {
+			t.Errorf("%q: expected error, got target information", g.triple)
+		} else if got := err.Error(); got != g.err {
+			t.Errorf("%q: error mismatch; expected %q, got %q", g.triple, g.err, got)
+		}
+	}
+}
+
+func TestNewModule(t *testing.T) {
+	info, err := target.Lookup("x86_64-unknown-linux-gnu")
+	if err != nil {
+		t.Fatalf("unable to locate target information; %+v", err)
+	}
+	m := info.NewModule()
+	const want = `target datalayout = "e-m:e-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128"
+target triple = "x86_64-unknown-linux-gnu"
+`
+	if got := m.String(); got != want {
+		t.Errorf("module mismatch; expected %q, got %q", want, got)
+	}
+	got, err := target.LookupModule(m)
+	if err != nil {
+		t.Fatalf("unable to locate target information of module; %+v", err)
+	}
+	if got.DataLayout != info.DataLayout {
+		t.Errorf("data layout mismatch; expected %q, got %q", info.DataLayout, got.DataLayout)
+	}
+}
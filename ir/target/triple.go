@@ -0,0 +1,367 @@
+package target
+
+import "strings"
+
+// Triple is a parsed target triple of the form arch-vendor-os-environment; e.g.
+// x86_64-unknown-linux-gnu.
+//
+// ref: https://clang.llvm.org/docs/CrossCompilation.html#target-triple
+type Triple struct {
+	// Architecture.
+	Arch Arch
+	// Vendor.
+	Vendor Vendor
+	// Operating system.
+	OS OS
+	// Environment (e.g. C library or ABI).
+	Env Environment
+
+	// Target triple string.
+	str string
+}
+
+// ParseTriple parses the given target triple. Components of the target triple
+// which are missing or not recognized are given unknown values.
+//
+// Like the triple normalization of LLVM, components may be omitted (e.g.
+// x86_64-linux-gnu and arm-none-eabi), in which case the remaining components
+// are matched by kind.
+func ParseTriple(s string) Triple {
+	t := Triple{str: s}
+	parts := strings.Split(s, "-")
+	t.Arch = parseArch(parts[0])
+	// Index of the next component kind to match; 0 for vendor, 1 for operating
+	// system and 2 for environment.
+	kind := 0
+	for _, part := range parts[1:] {
+		if kind <= 0 {
+			if vendor, ok := parseVendor(part); ok {
+				t.Vendor = vendor
+				kind = 1
+				continue
+			}
+		}
+		if kind <= 1 {
+			if os, ok := parseOS(part); ok {
+				t.OS = os
+				// MinGW and Cygwin imply the environment of Windows.
+				switch {
+				case strings.HasPrefix(part, "mingw"):
+					t.Env = EnvGNU
+				case strings.HasPrefix(part, "cygwin"):
+					t.Env = EnvCygnus
+				}
+				kind = 2
+				continue
+			}
+		}
+		if kind <= 2 {
+			if env, ok := parseEnv(part); ok {
+				t.Env = env
+				kind = 3
+				continue
+			}
+		}
+		// Skip unrecognized component (e.g. "none" of arm-none-eabi).
+		kind++
+	}
+	// Windows defaults to the MSVC environment.
+	if t.OS == OSWindows && t.Env == EnvUnknown {
+		t.Env = EnvMSVC
+	}
+	return t
+}
+
+// String returns the string representation of the target triple.
+func (t Triple) String() string {
+	return t.str
+}
+
+// IsDarwin reports whether the operating system of the target triple is a
+// Darwin-based Apple OS (macOS, iOS, tvOS or watchOS).
+func (t Triple) IsDarwin() bool {
+	switch t.OS {
+	case OSDarwin, OSMacOSX, OSIOS, OSTvOS, OSWatchOS:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsWindows reports whether the operating system of the target triple is
+// Windows.
+func (t Triple) IsWindows() bool {
+	return t.OS == OSWindows
+}
+
+// Is64Bit reports whether the architecture of the target triple has 64-bit
+// pointers.
+func (t Triple) Is64Bit() bool {
+	switch t.Arch {
+	case ArchAArch64, ArchAArch64BE, ArchMips64, ArchMips64el, ArchPPC64, ArchPPC64LE, ArchRISCV64, ArchSystemZ, ArchWasm64:
+		return true
+	case ArchX86_64:
+		// The x32 ABI uses 32-bit pointers.
+		return t.Env != EnvGNUX32
+	default:
+		return false
+	}
+}
+
+// IsLittleEndian reports whether the architecture of the target triple is
+// little-endian.
+func (t Triple) IsLittleEndian() bool {
+	switch t.Arch {
+	case ArchAArch64BE, ArchARMEB, ArchThumbEB, ArchMips, ArchMips64, ArchPPC, ArchPPC64, ArchSystemZ:
+		return false
+	default:
+		return true
+	}
+}
+
+//go:generate stringer -linecomment -type Arch
+
+// Arch is the architecture of a target triple.
+type Arch uint8
+
+// Architectures.
+const (
+	ArchUnknown   Arch = iota // unknown
+	ArchAArch64               // aarch64
+	ArchAArch64BE             // aarch64_be
+	ArchARM                   // arm
+	ArchARMEB                 // armeb
+	ArchAVR                   // avr
+	ArchMips                  // mips
+	ArchMipsel                // mipsel
+	ArchMips64                // mips64
+	ArchMips64el              // mips64el
+	ArchPPC                   // powerpc
+	ArchPPC64                 // powerpc64
+	ArchPPC64LE               // powerpc64le
+	ArchRISCV32               // riscv32
+	ArchRISCV64               // riscv64
+	ArchSystemZ               // s390x
+	ArchThumb                 // thumb
+	ArchThumbEB               // thumbeb
+	ArchWasm32                // wasm32
+	ArchWasm64                // wasm64
+	ArchX86                   // i386
+	ArchX86_64                // x86_64
+)
+
+//go:generate stringer -linecomment -type Vendor
+
+// Vendor is the vendor of a target triple.
+type Vendor uint8
+
+// Vendors.
+const (
+	VendorUnknown Vendor = iota // unknown
+	VendorApple                 // apple
+	VendorIBM                   // ibm
+	VendorPC                    // pc
+	VendorRedHat                // redhat
+	VendorSUSE                  // suse
+)
+
+//go:generate stringer -linecomment -type OS
+
+// OS is the operating system of a target triple.
+type OS uint8
+
+// Operating systems.
+const (
+	OSUnknown    OS = iota // unknown
+	OSAIX                  // aix
+	OSDarwin               // darwin
+	OSEmscripten           // emscripten
+	OSFreeBSD              // freebsd
+	OSFuchsia              // fuchsia
+	OSIOS                  // ios
+	OSLinux                // linux
+	OSMacOSX               // macosx
+	OSNetBSD               // netbsd
+	OSOpenBSD              // openbsd
+	OSSolaris              // solaris
+	OSTvOS                 // tvos
+	OSWASI                 // wasi
+	OSWatchOS              // watchos
+	OSWindows              // windows
+)
+
+//go:generate stringer -linecomment -type Environment
+
+// Environment is the environment of a target triple.
+type Environment uint8
+
+// Environments.
+const (
+	EnvUnknown    Environment = iota // unknown
+	EnvAndroid                       // android
+	EnvCygnus                        // cygnus
+	EnvEABI                          // eabi
+	EnvEABIHF                        // eabihf
+	EnvGNU                           // gnu
+	EnvGNUABI64                      // gnuabi64
+	EnvGNUEABI                       // gnueabi
+	EnvGNUEABIHF                     // gnueabihf
+	EnvGNUX32                        // gnux32
+	EnvItanium                       // itanium
+	EnvMacABI                        // macabi
+	EnvMSVC                          // msvc
+	EnvMusl                          // musl
+	EnvMuslEABI                      // musleabi
+	EnvMuslEABIHF                    // musleabihf
+	EnvSimulator                     // simulator
+)
+
+// ### [ Helper functions ] ####################################################
+
+// parseArch parses the given architecture component of a target triple.
+func parseArch(s string) Arch {
+	switch s {
+	case "aarch64", "arm64":
+		return ArchAArch64
+	case "aarch64_be":
+		return ArchAArch64BE
+	case "avr":
+		return ArchAVR
+	case "mips", "mipseb":
+		return ArchMips
+	case "mipsel":
+		return ArchMipsel
+	case "mips64", "mips64eb":
+		return ArchMips64
+	case "mips64el":
+		return ArchMips64el
+	case "powerpc", "ppc", "ppc32":
+		return ArchPPC
+	case "powerpc64", "ppc64":
+		return ArchPPC64
+	case "powerpc64le", "ppc64le":
+		return ArchPPC64LE
+	case "riscv32":
+		return ArchRISCV32
+	case "riscv64":
+		return ArchRISCV64
+	case "s390x", "systemz":
+		return ArchSystemZ
+	case "wasm32":
+		return ArchWasm32
+	case "wasm64":
+		return ArchWasm64
+	case "i386", "i486", "i586", "i686", "i786", "i886", "i986":
+		return ArchX86
+	case "x86_64", "amd64", "x86_64h":
+		return ArchX86_64
+	}
+	// ARM architectures may have a version suffix; e.g. armv7 and thumbv7m.
+	switch {
+	case s == "armeb" || strings.HasPrefix(s, "armebv"):
+		return ArchARMEB
+	case s == "arm" || strings.HasPrefix(s, "armv"):
+		return ArchARM
+	case s == "thumbeb" || strings.HasPrefix(s, "thumbebv"):
+		return ArchThumbEB
+	case s == "thumb" || strings.HasPrefix(s, "thumbv"):
+		return ArchThumb
+	}
+	return ArchUnknown
+}
+
+// parseVendor parses the given vendor component of a target triple. The
+// boolean return value indicates success.
+func parseVendor(s string) (Vendor, bool) {
+	switch s {
+	case "unknown":
+		return VendorUnknown, true
+	case "apple":
+		return VendorApple, true
+	case "ibm":
+		return VendorIBM, true
+	case "pc":
+		return VendorPC, true
+	case "redhat":
+		return VendorRedHat, true
+	case "suse":
+		return VendorSUSE, true
+	}
+	return VendorUnknown, false
+}
+
+// osPrefixes maps from operating system component prefixes of target triples
+// to operating systems. The operating system component may have a version
+// suffix; e.g. macosx10.15.
+var osPrefixes = []struct {
+	prefix string
+	os     OS
+}{
+	{prefix: "unknown", os: OSUnknown},
+	{prefix: "aix", os: OSAIX},
+	{prefix: "cygwin", os: OSWindows},
+	{prefix: "darwin", os: OSDarwin},
+	{prefix: "emscripten", os: OSEmscripten},
+	{prefix: "freebsd", os: OSFreeBSD},
+	{prefix: "fuchsia", os: OSFuchsia},
+	{prefix: "ios", os: OSIOS},
+	{prefix: "linux", os: OSLinux},
+	{prefix: "macos", os: OSMacOSX},
+	{prefix: "mingw32", os: OSWindows},
+	{prefix: "netbsd", os: OSNetBSD},
+	{prefix: "openbsd", os: OSOpenBSD},
+	{prefix: "solaris", os: OSSolaris},
+	{prefix: "tvos", os: OSTvOS},
+	{prefix: "wasi", os: OSWASI},
+	{prefix: "watchos", os: OSWatchOS},
+	{prefix: "win32", os: OSWindows},
+	{prefix: "windows", os: OSWindows},
+}
+
+// parseOS parses the given operating system component of a target triple. The
+// boolean return value indicates success.
+func parseOS(s string) (OS, bool) {
+	for _, p := range osPrefixes {
+		if strings.HasPrefix(s, p.prefix) {
+			return p.os, true
+		}
+	}
+	return OSUnknown, false
+}
+
+// envPrefixes maps from environment component prefixes of target triples to
+// environments. Longer prefixes precede their own prefixes; e.g. gnueabihf
+// precedes gnueabi, which precedes gnu.
+var envPrefixes = []struct {
+	prefix string
+	env    Environment
+}{
+	{prefix: "unknown", env: EnvUnknown},
+	{prefix: "android", env: EnvAndroid},
+	{prefix: "cygnus", env: EnvCygnus},
+	{prefix: "eabihf", env: EnvEABIHF},
+	{prefix: "eabi", env: EnvEABI},
+	{prefix: "gnuabi64", env: EnvGNUABI64},
+	{prefix: "gnueabihf", env: EnvGNUEABIHF},
+	{prefix: "gnueabi", env: EnvGNUEABI},
+	{prefix: "gnux32", env: EnvGNUX32},
+	{prefix: "gnu", env: EnvGNU},
+	{prefix: "itanium", env: EnvItanium},
+	{prefix: "macabi", env: EnvMacABI},
+	{prefix: "msvc", env: EnvMSVC},
+	{prefix: "musleabihf", env: EnvMuslEABIHF},
+	{prefix: "musleabi", env: EnvMuslEABI},
+	{prefix: "musl", env: EnvMusl},
+	{prefix: "simulator", env: EnvSimulator},
+}
+
+// parseEnv parses the given environment component of a target triple. The
+// boolean return value indicates success.
+func parseEnv(s string) (Environment, bool) {
+	for _, p := range envPrefixes {
+		if strings.HasPrefix(s, p.prefix) {
+			return p.env, true
+		}
+	}
+	return EnvUnknown, false
+}
@@ -374,6 +374,10 @@ type TermInvoke struct {
 
 	// Type of result produced by the terminator.
 	Typ types.Type
+	// Function signature of the invokee; or nil to derive the signature from
+	// the invokee. Required for invokees of opaque pointer type which are not
+	// functions.
+	InvokeeSig *types.FuncType
 	// Successor basic blocks of the terminator.
 	Successors []*Block
 	// (optional) Calling convention; zero if not present.
@@ -402,6 +406,17 @@ func NewInvoke(invokee value.Value, args []value.Value, normalRetTarget, excepti
 	return term
 }
 
+// NewInvokeSig returns a new invoke terminator based on the given function
+// signature, invokee, function arguments and control flow return points for
+// normal and exceptional execution. The explicit function signature is
+// required for invokees of opaque pointer type which are not functions.
+func NewInvokeSig(sig *types.FuncType, invokee value.Value, args []value.Value, normalRetTarget, exceptionRetTarget *Block) *TermInvoke {
+	term := &TermInvoke{Invokee: invokee, Args: args, NormalRetTarget: normalRetTarget, ExceptionRetTarget: exceptionRetTarget, InvokeeSig: sig}
+	// Compute type.
+	term.Type()
+	return term
+}
+
 // String returns the LLVM syntax representation of the terminator as a type-
 // value pair.
 func (term *TermInvoke) String() string {
@@ -492,15 +507,7 @@ func (term *TermInvoke) LLString() string {
 
 // Sig returns the function signature of the invokee.
 func (term *TermInvoke) Sig() *types.FuncType {
-	t, ok := term.Invokee.Type().(*types.PointerType)
-	if !ok {
-		panic(fmt.Errorf("invalid invokee type; expected *types.PointerType, got %T", term.Invokee.Type()))
-	}
-	sig, ok := t.ElemType.(*types.FuncType)
-	if !ok {
-		panic(fmt.Errorf("invalid invokee type; expected *types.FuncType, got %T", t.ElemType))
-	}
-	return sig
+	return calleeSig(term.Invokee, term.InvokeeSig)
 }
 
 // --- [ callbr ] --------------------------------------------------------------
@@ -530,6 +537,10 @@ type TermCallBr struct {
 
 	// Type of result produced by the terminator.
 	Typ types.Type
+	// Function signature of the callee; or nil to derive the signature from the
+	// callee. Required for callees of opaque pointer type which are not
+	// functions.
+	CalleeSig *types.FuncType
 	// Successor basic blocks of the terminator.
 	Successors []*Block
 	// (optional) Calling convention; zero if not present.
@@ -563,6 +574,22 @@ func NewCallBr(callee value.Value, args []value.Value, normalRetTarget *Block, o
 	return term
 }
 
+// NewCallBrSig returns a new callbr terminator based on the given function
+// signature, callee, function arguments and control flow return points for
+// normal and exceptional execution. The explicit function signature is
+// required for callees of opaque pointer type which are not functions.
+func NewCallBrSig(sig *types.FuncType, callee value.Value, args []value.Value, normalRetTarget *Block, otherRetTargets ...*Block) *TermCallBr {
+	// Convert otherRetTargets slice to []value.Value.
+	var otherRets []value.Value
+	for _, otherRetTarget := range otherRetTargets {
+		otherRets = append(otherRets, otherRetTarget)
+	}
+	term := &TermCallBr{Callee: callee, Args: args, NormalRetTarget: normalRetTarget, OtherRetTargets: otherRets, CalleeSig: sig}
+	// Compute type.
+	term.Type()
+	return term
+}
+
 // String returns the LLVM syntax representation of the terminator as a type-
 // value pair.
 func (term *TermCallBr) String() string {
@@ -666,15 +693,7 @@ func (term *TermCallBr) LLString() string {
 
 // Sig returns the function signature of the callee.
 func (term *TermCallBr) Sig() *types.FuncType {
-	t, ok := term.Callee.Type().(*types.PointerType)
-	if !ok {
-		panic(fmt.Errorf("invalid callee type; expected *types.PointerType, got %T", term.Callee.Type()))
-	}
-	sig, ok := t.ElemType.(*types.FuncType)
-	if !ok {
-		panic(fmt.Errorf("invalid callee type; expected *types.FuncType, got %T", t.ElemType))
-	}
-	return sig
+	return calleeSig(term.Callee, term.CalleeSig)
 }
 
 // --- [ resume ] --------------------------------------------------------------
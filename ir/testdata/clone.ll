@@ -0,0 +1,59 @@
+$f = comdat any
+
+@g = global i32 7, comdat($f)
+@addr = global i8* blockaddress(@f, %loop)
+@a = alias i32, i32* @g
+@i = ifunc void (), void ()* ()* @resolver
+
+define i32 @f(i32 %x) #0 comdat personality i32 (...)* @personality !dbg !4 {
+entry:
+	call void @llvm.dbg.value(metadata i32 %x, metadata !7, metadata !DIExpression()), !dbg !8
+	switch i32 %x, label %loop [
+		i32 0, label %exit
+		i32 1, label %unwind
+	]
+
+loop:
+	%i = phi i32 [ 0, %entry ], [ %inc, %loop ], [ 1, %lpad ]
+	%inc = add i32 %i, 1
+	%done = icmp eq i32 %inc, %x
+	br i1 %done, label %exit, label %loop, !dbg !8
+
+unwind:
+	%r = invoke i32 @f(i32 %x) [ "deopt"(i32 %x) ]
+			to label %exit unwind label %lpad, !dbg !8
+
+lpad:
+	%lp = landingpad { i8*, i32 }
+			cleanup
+	indirectbr i8* blockaddress(@f, %loop), [label %loop, label %exit]
+
+exit:
+	%res = phi i32 [ %x, %entry ], [ %inc, %loop ], [ %r, %unwind ], [ 0, %lpad ]
+	%v = load i32, i32* @a
+	%sum = add i32 %res, %v
+	ret i32 %sum, !dbg !8
+}
+
+define void ()* @resolver() {
+	ret void ()* null
+}
+
+declare i32 @personality(...)
+
+declare void @llvm.dbg.value(metadata, metadata, metadata)
+
+attributes #0 = { noinline nounwind }
+
+!llvm.dbg.cu = !{!0}
+!llvm.module.flags = !{!3}
+
+!0 = distinct !DICompileUnit(language: DW_LANG_C99, file: !1, producer: "clang", isOptimized: false, runtimeVersion: 0, emissionKind: FullDebug, enums: !2)
+!1 = !DIFile(filename: "f.c", directory: "/tmp")
+!2 = !{}
+!3 = !{i32 2, !"Debug Info Version", i32 3}
+!4 = distinct !DISubprogram(name: "f", scope: !1, file: !1, line: 1, type: !5, scopeLine: 1, spFlags: DISPFlagDefinition, unit: !0, retainedNodes: !2)
+!5 = !DISubroutineType(types: !6)
+!6 = !{null}
+!7 = !DILocalVariable(name: "x", arg: 1, scope: !4, file: !1, line: 1, type: null)
+!8 = !DILocation(line: 2, column: 3, scope: !4)
@@ -0,0 +1,377 @@
+package transform
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// === [ Constant propagation ] ================================================
+
+// PropagateConstants folds the instructions of the given function with
+// constant operands into constants, replaces the uses of folded instructions
+// with the resulting constants, and removes the folded instructions. The
+// number of folded instructions is returned.
+//
+// Instructions which have constant operands after the folding of other
+// instructions are folded as well, using a worklist of the users of folded
+// instructions.
+func PropagateConstants(f *ir.Func) int {
+	// Materialize function body if lazily parsed.
+	materialize(f)
+	idx := ir.NewFuncUseIndex(f)
+	var work []ir.Instruction
+	for _, block := range f.Blocks {
+		work = append(work, block.Insts...)
+	}
+	folded := make(map[ir.Instruction]bool)
+	for len(work) > 0 {
+		inst := work[0]
+		work = work[1:]
+		if folded[inst] {
+			continue
+		}
+		c := FoldInst(inst)
+		if c == nil {
+			continue
+		}
+		folded[inst] = true
+		// Replace uses of the folded instruction, and revisit its users, as they
+		// may now be foldable.
+		v := inst.(value.Value)
+		for _, user := range idx.Users(v) {
+			u, ok := user.(value.User)
+			if !ok {
+				continue
+			}
+			replaceOperand(u, v, c)
+			idx.UpdateUser(u)
+			if userInst, ok := u.(ir.Instruction); ok && !folded[userInst] {
+				work = append(work, userInst)
+			}
+		}
+	}
+	removeInsts(f, folded)
+	return len(folded)
+}
+
+// FoldInst returns the constant result of the given instruction, if all its
+// operands are constants; or nil if unable to fold the instruction.
+//
+// Instructions with side effects, or with results that depend on memory or
+// on the addresses of global values, are not folded. Fast-math flags nnan and
+// ninf are taken into account, producing poison for NaN and infinite operands
+// and results respectively.
+func FoldInst(inst ir.Instruction) constant.Constant {
+	switch inst := inst.(type) {
+	// Unary instructions.
+	case *ir.InstFNeg:
+		if x, ok := constOf(inst.X); ok {
+			return foldFMF(inst.FastMathFlags, constant.FoldFNeg(x), x)
+		}
+	// Binary instructions.
+	case *ir.InstAdd:
+		if x, y, ok := constOf2(inst.X, inst.Y); ok {
+			return constant.FoldAdd(x, y, inst.OverflowFlags)
+		}
+	case *ir.InstFAdd:
+		if x, y, ok := constOf2(inst.X, inst.Y); ok {
+			return foldFMF(inst.FastMathFlags, constant.FoldFAdd(x, y), x, y)
+		}
+	case *ir.InstSub:
+		if x, y, ok := constOf2(inst.X, inst.Y); ok {
+			return constant.FoldSub(x, y, inst.OverflowFlags)
+		}
+	case *ir.InstFSub:
+		if x, y, ok := constOf2(inst.X, inst.Y); ok {
+			return foldFMF(inst.FastMathFlags, constant.FoldFSub(x, y), x, y)
+		}
+	case *ir.InstMul:
+		if x, y, ok := constOf2(inst.X, inst.Y); ok {
+			return constant.FoldMul(x, y, inst.OverflowFlags)
+		}
+	case *ir.InstFMul:
+		if x, y, ok := constOf2(inst.X, inst.Y); ok {
+			return foldFMF(inst.FastMathFlags, constant.FoldFMul(x, y), x, y)
+		}
+	case *ir.InstUDiv:
+		if x, y, ok := constOf2(inst.X, inst.Y); ok {
+			return constant.FoldUDiv(x, y, inst.Exact)
+		}
+	case *ir.InstSDiv:
+		if x, y, ok := constOf2(inst.X, inst.Y); ok {
+			return constant.FoldSDiv(x, y, inst.Exact)
+		}
+	case *ir.InstFDiv:
+		if x, y, ok := constOf2(inst.X, inst.Y); ok {
+			return foldFMF(inst.FastMathFlags, constant.FoldFDiv(x, y), x, y)
+		}
+	case *ir.InstURem:
+		if x, y, ok := constOf2(inst.X, inst.Y); ok {
+			return constant.FoldURem(x, y)
+		}
+	case *ir.InstSRem:
+		if x, y, ok := constOf2(inst.X, inst.Y); ok {
+			return constant.FoldSRem(x, y)
+		}
+	case *ir.InstFRem:
+		if x, y, ok := constOf2(inst.X, inst.Y); ok {
+			return foldFMF(inst.FastMathFlags, constant.FoldFRem(x, y), x, y)
+		}
+	// Bitwise instructions.
+	case *ir.InstShl:
+		if x, y, ok := constOf2(inst.X, inst.Y); ok {
+			return constant.FoldShl(x, y, inst.OverflowFlags)
+		}
+	case *ir.InstLShr:
+		if x, y, ok := constOf2(inst.X, inst.Y); ok {
+			return constant.FoldLShr(x, y, inst.Exact)
+		}
+	case *ir.InstAShr:
+		if x, y, ok := constOf2(inst.X, inst.Y); ok {
+			return constant.FoldAShr(x, y, inst.Exact)
+		}
+	case *ir.InstAnd:
+		if x, y, ok := constOf2(inst.X, inst.Y); ok {
+			return constant.FoldAnd(x, y)
+		}
+	case *ir.InstOr:
+		if x, y, ok := constOf2(inst.X, inst.Y); ok {
+			return constant.FoldOr(x, y)
+		}
+	case *ir.InstXor:
+		if x, y, ok := constOf2(inst.X, inst.Y); ok {
+			return constant.FoldXor(x, y)
+		}
+	// Vector instructions.
+	case *ir.InstExtractElement:
+		if x, index, ok := constOf2(inst.X, inst.Index); ok {
+			return constant.FoldExtractElement(x, index)
+		}
+	case *ir.InstInsertElement:
+		if x, elem, ok := constOf2(inst.X, inst.Elem); ok {
+			if index, ok := constOf(inst.Index); ok {
+				return constant.FoldInsertElement(x, elem, index)
+			}
+		}
+	case *ir.InstShuffleVector:
+		if x, y, ok := constOf2(inst.X, inst.Y); ok {
+			if mask, ok := constOf(inst.Mask); ok {
+				return constant.FoldShuffleVector(x, y, mask)
+			}
+		}
+	// Aggregate instructions.
+	case *ir.InstExtractValue:
+		if x, ok := constOf(inst.X); ok {
+			return constant.FoldExtractValue(x, inst.Indices)
+		}
+	case *ir.InstInsertValue:
+		if x, elem, ok := constOf2(inst.X, inst.Elem); ok {
+			return constant.FoldInsertValue(x, elem, inst.Indices)
+		}
+	// Conversion instructions.
+	case *ir.InstTrunc:
+		if from, ok := constOf(inst.From); ok {
+			return constant.FoldTrunc(from, inst.To)
+		}
+	case *ir.InstZExt:
+		if from, ok := constOf(inst.From); ok {
+			return constant.FoldZExt(from, inst.To)
+		}
+	case *ir.InstSExt:
+		if from, ok := constOf(inst.From); ok {
+			return constant.FoldSExt(from, inst.To)
+		}
+	case *ir.InstFPTrunc:
+		if from, ok := constOf(inst.From); ok {
+			return constant.FoldFPTrunc(from, inst.To)
+		}
+	case *ir.InstFPExt:
+		if from, ok := constOf(inst.From); ok {
+			return constant.FoldFPExt(from, inst.To)
+		}
+	case *ir.InstFPToUI:
+		if from, ok := constOf(inst.From); ok {
+			return constant.FoldFPToUI(from, inst.To)
+		}
+	case *ir.InstFPToSI:
+		if from, ok := constOf(inst.From); ok {
+			return constant.FoldFPToSI(from, inst.To)
+		}
+	case *ir.InstUIToFP:
+		if from, ok := constOf(inst.From); ok {
+			return constant.FoldUIToFP(from, inst.To)
+		}
+	case *ir.InstSIToFP:
+		if from, ok := constOf(inst.From); ok {
+			return constant.FoldSIToFP(from, inst.To)
+		}
+	case *ir.InstPtrToInt:
+		if from, ok := constOf(inst.From); ok {
+			return constant.FoldPtrToInt(from, inst.To)
+		}
+	case *ir.InstIntToPtr:
+		if from, ok := constOf(inst.From); ok {
+			return constant.FoldIntToPtr(from, inst.To)
+		}
+	case *ir.InstBitCast:
+		if from, ok := constOf(inst.From); ok {
+			return constant.FoldBitCast(from, inst.To)
+		}
+	// Other instructions.
+	case *ir.InstICmp:
+		if x, y, ok := constOf2(inst.X, inst.Y); ok {
+			return constant.FoldICmp(inst.Pred, x, y)
+		}
+	case *ir.InstFCmp:
+		if x, y, ok := constOf2(inst.X, inst.Y); ok {
+			c := constant.FoldFCmp(inst.Pred, x, y)
+			if c != nil && hasPoisonFMF(inst.FastMathFlags, x, y) {
+				return constant.NewPoison(c.Type())
+			}
+			return c
+		}
+	case *ir.InstPhi:
+		return foldPhi(inst)
+	case *ir.InstSelect:
+		if cond, ok := constOf(inst.Cond); ok {
+			if x, y, ok := constOf2(inst.ValueTrue, inst.ValueFalse); ok {
+				return foldFMF(inst.FastMathFlags, constant.FoldSelect(cond, x, y))
+			}
+		}
+	case *ir.InstFreeze:
+		if x, ok := constOf(inst.X); ok && isWellDefined(x) {
+			return x
+		}
+	}
+	return nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// constOf returns the folded constant of the given operand, and reports
+// whether the operand is a constant. Global values are not considered
+// constants, as their addresses are unknown.
+func constOf(v value.Value) (constant.Constant, bool) {
+	switch v.(type) {
+	case *ir.Global, *ir.Func, *ir.Alias, *ir.IFunc:
+		return nil, false
+	}
+	c, ok := v.(constant.Constant)
+	if !ok {
+		return nil, false
+	}
+	return constant.Fold(c), true
+}
+
+// constOf2 returns the folded constants of the given operands, and reports
+// whether both operands are constants.
+func constOf2(x, y value.Value) (constant.Constant, constant.Constant, bool) {
+	xc, ok := constOf(x)
+	if !ok {
+		return nil, nil, false
+	}
+	yc, ok := constOf(y)
+	if !ok {
+		return nil, nil, false
+	}
+	return xc, yc, true
+}
+
+// foldPhi returns the constant incoming value of the given phi instruction, if
+// all incoming values (other than the phi instruction itself) are the same
+// constant; or nil otherwise.
+func foldPhi(inst *ir.InstPhi) constant.Constant {
+	var c constant.Constant
+	for _, inc := range inst.Incs {
+		if inc.X == inst {
+			continue
+		}
+		x, ok := constOf(inc.X)
+		if !ok || !isWellDefined(x) {
+			return nil
+		}
+		if c == nil {
+			c = x
+			continue
+		}
+		if !c.Type().Equal(x.Type()) || c.Ident() != x.Ident() {
+			return nil
+		}
+	}
+	return c
+}
+
+// foldFMF returns the folded result c of a floating-point operation with the
+// given fast-math flags and operands; or poison if the flags are violated by
+// the operands or the result. foldFMF returns nil if c is nil.
+func foldFMF(flags []enum.FastMathFlag, c constant.Constant, operands ...constant.Constant) constant.Constant {
+	if c == nil {
+		return nil
+	}
+	if hasPoisonFMF(flags, append(operands, c)...) {
+		return constant.NewPoison(c.Type())
+	}
+	return c
+}
+
+// hasPoisonFMF reports whether any of the given constants is NaN while the
+// fast-math flags include nnan, or infinite while the flags include ninf.
+func hasPoisonFMF(flags []enum.FastMathFlag, cs ...constant.Constant) bool {
+	var nnan, ninf bool
+	for _, flag := range flags {
+		switch flag {
+		case enum.FastMathFlagNNaN:
+			nnan = true
+		case enum.FastMathFlagNInf:
+			ninf = true
+		case enum.FastMathFlagFast:
+			nnan, ninf = true, true
+		}
+	}
+	if !nnan && !ninf {
+		return false
+	}
+	for _, c := range cs {
+		var elems []constant.Constant
+		if v, ok := c.(*constant.Vector); ok {
+			elems = v.Elems
+		} else {
+			elems = []constant.Constant{c}
+		}
+		for _, elem := range elems {
+			f, ok := elem.(*constant.Float)
+			if !ok {
+				continue
+			}
+			if (nnan && f.NaN) || (ninf && !f.NaN && f.X.IsInf()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isWellDefined reports whether the given constant is a scalar or vector
+// constant without undef and poison values.
+func isWellDefined(c constant.Constant) bool {
+	switch c := c.(type) {
+	case *constant.Int, *constant.Float, *constant.Null:
+		return true
+	case *constant.ZeroInitializer:
+		switch c.Typ.(type) {
+		case *types.IntType, *types.FloatType, *types.PointerType, *types.VectorType:
+			return true
+		}
+	case *constant.Vector:
+		for _, elem := range c.Elems {
+			if !isWellDefined(elem) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
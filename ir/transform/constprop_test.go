@@ -0,0 +1,51 @@
+package transform_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/llir/llvm/asm"
+	"github.com/llir/llvm/ir/transform"
+	"github.com/llir/llvm/ir/verify"
+)
+
+func TestPropagateConstants(t *testing.T) {
+	golden := []struct {
+		path string
+		// Parse function bodies lazily.
+		lazy bool
+		// Number of folded instructions of each function.
+		nFolded []int
+	}{
+		{path: "testdata/constprop.ll", nFolded: []int{9, 5, 11, 5, 7, 0}},
+		{path: "testdata/constprop.ll", lazy: true, nFolded: []int{9, 5, 11, 5, 7, 0}},
+	}
+	for _, g := range golden {
+		cfg := &asm.Config{Lazy: g.lazy}
+		m, err := cfg.ParseFile(g.path)
+		if err != nil {
+			t.Errorf("unable to parse %q; %+v", g.path, err)
+			continue
+		}
+		for i, f := range m.Funcs {
+			n := transform.PropagateConstants(f)
+			if n != g.nFolded[i] {
+				t.Errorf("%q: number of folded instructions of function %s mismatch; expected %d, got %d", g.path, f.Ident(), g.nFolded[i], n)
+			}
+		}
+		if err := verify.Module(m); err != nil {
+			t.Errorf("%q: invalid module after transformation; %v", g.path, err)
+		}
+		buf, err := ioutil.ReadFile(g.path + ".golden")
+		if err != nil {
+			t.Errorf("unable to read %q; %+v", g.path+".golden", err)
+			continue
+		}
+		want := string(buf)
+		got := m.String()
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("module %q mismatch (-want +got):\n%s", g.path, diff)
+		}
+	}
+}
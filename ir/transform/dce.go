@@ -0,0 +1,85 @@
+package transform
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/value"
+)
+
+// === [ Dead code elimination ] ===============================================
+
+// EliminateDeadCode removes the trivially dead instructions of the given
+// function (DCE), and returns the number of removed instructions.
+//
+// An instruction is trivially dead if its result is unused and it has no side
+// effects. Instructions which become trivially dead by the removal of other
+// instructions are removed as well.
+func EliminateDeadCode(f *ir.Func) int {
+	// Materialize function body if lazily parsed.
+	materialize(f)
+	uses := ir.NewFuncUseIndex(f)
+	dead := make(map[ir.Instruction]bool)
+	var work []ir.Instruction
+	for _, block := range f.Blocks {
+		work = append(work, block.Insts...)
+	}
+	for len(work) > 0 {
+		inst := work[len(work)-1]
+		work = work[:len(work)-1]
+		if dead[inst] || hasSideEffects(inst) || uses.NumUses(inst.(value.Value)) > 0 {
+			continue
+		}
+		dead[inst] = true
+		// Revisit instructions used by the removed instruction, as they may have
+		// become trivially dead.
+		var ops []ir.Instruction
+		for _, use := range uses.Operands(inst) {
+			if op, ok := use.Value.(ir.Instruction); ok {
+				ops = append(ops, op)
+			}
+		}
+		uses.RemoveUser(inst)
+		work = append(work, ops...)
+	}
+	removeInsts(f, dead)
+	return len(dead)
+}
+
+// ### [ Helper functions ] ####################################################
+
+// hasSideEffects reports whether the given instruction may have side effects,
+// other than producing its result; e.g. writing to memory, synchronizing or
+// transferring control. Instructions without side effects produce a result.
+func hasSideEffects(inst ir.Instruction) bool {
+	switch inst := inst.(type) {
+	// Unary instructions.
+	case *ir.InstFNeg:
+		return false
+	// Binary instructions.
+	case *ir.InstAdd, *ir.InstFAdd, *ir.InstSub, *ir.InstFSub, *ir.InstMul, *ir.InstFMul, *ir.InstUDiv, *ir.InstSDiv, *ir.InstFDiv, *ir.InstURem, *ir.InstSRem, *ir.InstFRem:
+		return false
+	// Bitwise instructions.
+	case *ir.InstShl, *ir.InstLShr, *ir.InstAShr, *ir.InstAnd, *ir.InstOr, *ir.InstXor:
+		return false
+	// Vector instructions.
+	case *ir.InstExtractElement, *ir.InstInsertElement, *ir.InstShuffleVector:
+		return false
+	// Aggregate instructions.
+	case *ir.InstExtractValue, *ir.InstInsertValue:
+		return false
+	// Memory instructions.
+	case *ir.InstAlloca, *ir.InstGetElementPtr:
+		return false
+	case *ir.InstLoad:
+		return inst.Volatile || inst.Atomic
+	// Conversion instructions.
+	case *ir.InstTrunc, *ir.InstZExt, *ir.InstSExt, *ir.InstFPTrunc, *ir.InstFPExt, *ir.InstFPToUI, *ir.InstFPToSI, *ir.InstUIToFP, *ir.InstSIToFP, *ir.InstPtrToInt, *ir.InstIntToPtr, *ir.InstBitCast, *ir.InstAddrSpaceCast:
+		return false
+	// Other instructions.
+	case *ir.InstICmp, *ir.InstFCmp, *ir.InstPhi, *ir.InstSelect, *ir.InstFreeze:
+		return false
+	default:
+		// Stores, fences, atomic instructions, calls, va_arg and exception
+		// handling pads.
+		return true
+	}
+}
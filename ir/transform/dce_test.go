@@ -0,0 +1,88 @@
+package transform_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/llir/llvm/asm"
+	"github.com/llir/llvm/ir/transform"
+	"github.com/llir/llvm/ir/verify"
+)
+
+func TestEliminateDeadCode(t *testing.T) {
+	golden := []struct {
+		path string
+		// Parse function bodies lazily.
+		lazy bool
+		// Number of removed instructions of each function.
+		nRemoved []int
+	}{
+		{path: "testdata/dce.ll", nRemoved: []int{0, 7, 1}},
+		{path: "testdata/dce.ll", lazy: true, nRemoved: []int{0, 7, 1}},
+	}
+	for _, g := range golden {
+		cfg := &asm.Config{Lazy: g.lazy}
+		m, err := cfg.ParseFile(g.path)
+		if err != nil {
+			t.Errorf("unable to parse %q; %+v", g.path, err)
+			continue
+		}
+		for i, f := range m.Funcs {
+			n := transform.EliminateDeadCode(f)
+			if n != g.nRemoved[i] {
+				t.Errorf("%q: number of removed instructions of function %s mismatch; expected %d, got %d", g.path, f.Ident(), g.nRemoved[i], n)
+			}
+		}
+		if err := verify.Module(m); err != nil {
+			t.Errorf("%q: invalid module after transformation; %v", g.path, err)
+		}
+		buf, err := ioutil.ReadFile(g.path + ".golden")
+		if err != nil {
+			t.Errorf("unable to read %q; %+v", g.path+".golden", err)
+			continue
+		}
+		want := string(buf)
+		got := m.String()
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("module %q mismatch (-want +got):\n%s", g.path, diff)
+		}
+	}
+}
+
+func TestEliminateDeadGlobals(t *testing.T) {
+	golden := []struct {
+		path string
+		// Parse function bodies lazily.
+		lazy bool
+		// Number of removed definitions.
+		nRemoved int
+	}{
+		{path: "testdata/globaldce.ll", nRemoved: 11},
+		{path: "testdata/globaldce.ll", lazy: true, nRemoved: 11},
+	}
+	for _, g := range golden {
+		cfg := &asm.Config{Lazy: g.lazy}
+		m, err := cfg.ParseFile(g.path)
+		if err != nil {
+			t.Errorf("unable to parse %q; %+v", g.path, err)
+			continue
+		}
+		if n := transform.EliminateDeadGlobals(m); n != g.nRemoved {
+			t.Errorf("%q: number of removed definitions mismatch; expected %d, got %d", g.path, g.nRemoved, n)
+		}
+		if err := verify.Module(m); err != nil {
+			t.Errorf("%q: invalid module after transformation; %v", g.path, err)
+		}
+		buf, err := ioutil.ReadFile(g.path + ".golden")
+		if err != nil {
+			t.Errorf("unable to read %q; %+v", g.path+".golden", err)
+			continue
+		}
+		want := string(buf)
+		got := m.String()
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("module %q mismatch (-want +got):\n%s", g.path, diff)
+		}
+	}
+}
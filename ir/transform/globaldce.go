@@ -0,0 +1,316 @@
+package transform
+
+import (
+	"reflect"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/metadata"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// === [ Global dead code elimination ] ========================================
+
+// EliminateDeadGlobals removes the unreferenced global values, type
+// definitions and metadata definitions of the given module (global DCE), and
+// returns the number of removed definitions.
+//
+// Global variables, functions, aliases and IFuncs are removed if unreferenced
+// by live parts of the module, and either have internal or private linkage, or
+// are declarations. Type definitions are removed if unused by live parts of
+// the module. Unnamed metadata definitions are removed if unreferenced by
+// named metadata and by metadata attachments of live parts of the module.
+//
+// Global values referenced from live metadata are kept, as are global values
+// with linkage other than internal and private; e.g. the llvm.used global
+// variable and the global values it references.
+func EliminateDeadGlobals(m *ir.Module) int {
+	// Materialize function bodies if lazily parsed, as function definitions
+	// without basic blocks would otherwise be treated as declarations.
+	for _, f := range m.Funcs {
+		materialize(f)
+	}
+	d := &globalDCE{
+		live:  make(map[interface{}]bool),
+		types: make(map[string]bool),
+	}
+	// Mark roots.
+	for _, g := range m.Globals {
+		if g.Init != nil && !isLocalLinkage(g.Linkage) {
+			d.markGlobal(g)
+		}
+	}
+	for _, f := range m.Funcs {
+		if len(f.Blocks) > 0 && !isLocalLinkage(f.Linkage) {
+			d.markGlobal(f)
+		}
+	}
+	for _, alias := range m.Aliases {
+		if !isLocalLinkage(alias.Linkage) {
+			d.markGlobal(alias)
+		}
+	}
+	for _, ifunc := range m.IFuncs {
+		if !isLocalLinkage(ifunc.Linkage) {
+			d.markGlobal(ifunc)
+		}
+	}
+	for _, md := range m.NamedMetadataDefs {
+		for _, node := range md.Nodes {
+			d.walk(reflect.ValueOf(node))
+		}
+	}
+	// Propagate liveness.
+	for len(d.work) > 0 {
+		g := d.work[len(d.work)-1]
+		d.work = d.work[:len(d.work)-1]
+		d.walkGlobal(g)
+	}
+	// Remove dead definitions.
+	n := 0
+	globals := m.Globals[:0]
+	for _, g := range m.Globals {
+		if d.live[g] {
+			globals = append(globals, g)
+		}
+	}
+	n += len(m.Globals) - len(globals)
+	m.Globals = globals
+	funcs := m.Funcs[:0]
+	for _, f := range m.Funcs {
+		if d.live[f] {
+			funcs = append(funcs, f)
+		}
+	}
+	n += len(m.Funcs) - len(funcs)
+	m.Funcs = funcs
+	aliases := m.Aliases[:0]
+	for _, alias := range m.Aliases {
+		if d.live[alias] {
+			aliases = append(aliases, alias)
+		}
+	}
+	n += len(m.Aliases) - len(aliases)
+	m.Aliases = aliases
+	ifuncs := m.IFuncs[:0]
+	for _, ifunc := range m.IFuncs {
+		if d.live[ifunc] {
+			ifuncs = append(ifuncs, ifunc)
+		}
+	}
+	n += len(m.IFuncs) - len(ifuncs)
+	m.IFuncs = ifuncs
+	typeDefs := m.TypeDefs[:0]
+	for _, t := range m.TypeDefs {
+		if d.types[t.Name()] {
+			typeDefs = append(typeDefs, t)
+		}
+	}
+	n += len(m.TypeDefs) - len(typeDefs)
+	m.TypeDefs = typeDefs
+	mds := m.MetadataDefs[:0]
+	for _, md := range m.MetadataDefs {
+		if d.live[md] {
+			mds = append(mds, md)
+		}
+	}
+	n += len(m.MetadataDefs) - len(mds)
+	m.MetadataDefs = mds
+	if n > 0 {
+		resetGlobalIDs(m)
+	}
+	return n
+}
+
+// globalDCE keeps track of the live parts of a module during global dead code
+// elimination.
+type globalDCE struct {
+	// Live global values, constants and metadata definitions.
+	live map[interface{}]bool
+	// Names of used named types.
+	types map[string]bool
+	// Live global values not yet walked.
+	work []constant.Constant
+}
+
+// markGlobal marks the given global value as live.
+func (d *globalDCE) markGlobal(g constant.Constant) {
+	if d.live[g] {
+		return
+	}
+	d.live[g] = true
+	d.work = append(d.work, g)
+}
+
+// walkGlobal marks the values, types and metadata referenced by the given live
+// global value as live.
+func (d *globalDCE) walkGlobal(g constant.Constant) {
+	d.walkFields(reflect.ValueOf(g).Elem())
+	f, ok := g.(*ir.Func)
+	if !ok {
+		return
+	}
+	for _, param := range f.Params {
+		d.walkFields(reflect.ValueOf(param).Elem())
+	}
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			d.walkLocal(inst)
+		}
+		if block.Term != nil {
+			d.walkLocal(block.Term)
+		}
+	}
+}
+
+// walkLocal marks the values, types and metadata referenced by the given
+// instruction or terminator as live.
+func (d *globalDCE) walkLocal(inst interface{}) {
+	// The result type of instructions may be computed on demand, and is thus
+	// not necessarily stored in a field.
+	if v, ok := inst.(value.Value); ok {
+		d.markType(v.Type())
+	}
+	d.walkFields(reflect.ValueOf(inst).Elem())
+}
+
+// walk marks the values, types and metadata referenced by the given value as
+// live.
+func (d *globalDCE) walk(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return
+		}
+		if v.CanInterface() && d.mark(v.Interface()) {
+			return
+		}
+		d.walk(v.Elem())
+	case reflect.Struct:
+		d.walkFields(v)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			d.walk(v.Index(i))
+		}
+	}
+}
+
+// walkFields marks the values, types and metadata referenced by the exported
+// fields of the given struct as live. References to parent modules and
+// functions are ignored.
+func (d *globalDCE) walkFields(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || field.Name == "Parent" {
+			// Skip unexported fields and parents.
+			continue
+		}
+		d.walk(v.Field(i))
+	}
+}
+
+// mark marks the given value, type or metadata as live, and reports whether
+// it was handled. Unhandled values are walked by the caller.
+func (d *globalDCE) mark(x interface{}) bool {
+	switch x := x.(type) {
+	case types.Type:
+		d.markType(x)
+		return true
+	case *ir.Block:
+		// Basic blocks are walked by walkGlobal.
+		return true
+	case metadata.Definition:
+		if !d.live[x] {
+			d.live[x] = true
+			d.walk(reflect.ValueOf(x).Elem())
+		}
+		return true
+	case *ir.Global, *ir.Func, *ir.Alias, *ir.IFunc:
+		d.markGlobal(x.(constant.Constant))
+		return true
+	case ir.Instruction, ir.Terminator, *ir.Param:
+		// Local values are walked by walkGlobal.
+		return true
+	case constant.Constant:
+		if isComparable(x) {
+			if d.live[x] {
+				return true
+			}
+			d.live[x] = true
+		}
+		d.walk(reflect.Indirect(reflect.ValueOf(x)))
+		return true
+	}
+	return false
+}
+
+// markType marks the given type and the types it references as used.
+func (d *globalDCE) markType(t types.Type) {
+	if name := t.Name(); len(name) > 0 {
+		if d.types[name] {
+			return
+		}
+		d.types[name] = true
+	}
+	switch t := t.(type) {
+	case *types.FuncType:
+		d.markType(t.RetType)
+		for _, param := range t.Params {
+			d.markType(param)
+		}
+	case *types.PointerType:
+		if t.ElemType != nil {
+			d.markType(t.ElemType)
+		}
+	case *types.VectorType:
+		d.markType(t.ElemType)
+	case *types.ArrayType:
+		d.markType(t.ElemType)
+	case *types.StructType:
+		for _, field := range t.Fields {
+			d.markType(field)
+		}
+	}
+}
+
+// ### [ Helper functions ] ####################################################
+
+// isLocalLinkage reports whether the given linkage is internal or private.
+func isLocalLinkage(linkage enum.Linkage) bool {
+	return linkage == enum.LinkageInternal || linkage == enum.LinkagePrivate
+}
+
+// isComparable reports whether the given value may be used as a map key.
+func isComparable(x interface{}) bool {
+	return reflect.TypeOf(x).Comparable()
+}
+
+// resetGlobalIDs resets the IDs of the unnamed global values of the given
+// module, so that they are renumbered sequentially by the next call to
+// m.AssignGlobalIDs (e.g. when printing the module).
+func resetGlobalIDs(m *ir.Module) {
+	type unnamed interface {
+		IsUnnamed() bool
+		SetID(id int64)
+	}
+	reset := func(v value.Value) {
+		if n, ok := v.(unnamed); ok && n.IsUnnamed() {
+			n.SetID(0)
+		}
+	}
+	for _, g := range m.Globals {
+		reset(g)
+	}
+	for _, alias := range m.Aliases {
+		reset(alias)
+	}
+	for _, ifunc := range m.IFuncs {
+		reset(ifunc)
+	}
+	for _, f := range m.Funcs {
+		reset(f)
+	}
+}
@@ -0,0 +1,778 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/metadata"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// === [ Function inlining ] ===================================================
+
+// DefaultInlineThreshold is the default maximum cost of call sites inlined by
+// an inliner.
+const DefaultInlineThreshold = 50
+
+// Inliner inlines function calls, based on a cost model and the alwaysinline
+// and noinline function attributes.
+//
+// A call site is inlined if the callee or the call site has the alwaysinline
+// function attribute, or if the cost of inlining the call site is at most the
+// threshold of the inliner. A call site is never inlined if the callee or the
+// call site has the noinline function attribute, if the call site is a
+// directly recursive call, or if the call site may not be inlined by
+// InlineCall.
+type Inliner struct {
+	// Cost returns the cost of inlining the callee at the given call site of
+	// caller; an *ir.InstCall or *ir.TermInvoke.
+	Cost func(caller, callee *ir.Func, site value.User) int
+	// Maximum cost of inlined call sites.
+	Threshold int
+}
+
+// NewInliner returns a new inliner based on the default cost model and
+// threshold.
+func NewInliner() *Inliner {
+	return &Inliner{
+		Cost:      DefaultInlineCost,
+		Threshold: DefaultInlineThreshold,
+	}
+}
+
+// InlineModule inlines the call sites of the function definitions of the given
+// module, and returns the number of inlined call sites.
+//
+// Functions are visited in post-order of the call graph, so that call sites
+// within callees are inlined before the callees are inlined into their
+// callers.
+func (in *Inliner) InlineModule(m *ir.Module) int {
+	// Materialize function bodies if lazily parsed.
+	for _, f := range m.Funcs {
+		materialize(f)
+	}
+	visited := make(map[*ir.Func]bool)
+	var order []*ir.Func
+	var visit func(f *ir.Func)
+	visit = func(f *ir.Func) {
+		if visited[f] {
+			return
+		}
+		visited[f] = true
+		for _, site := range callSites(f) {
+			if callee := calleeOf(site); callee != nil {
+				visit(callee)
+			}
+		}
+		order = append(order, f)
+	}
+	for _, f := range m.Funcs {
+		visit(f)
+	}
+	n := 0
+	for _, f := range order {
+		n += in.InlineFunc(f)
+	}
+	return n
+}
+
+// InlineFunc inlines the call sites of the given function, and returns the
+// number of inlined call sites. Call sites within inlined function bodies are
+// not inlined.
+func (in *Inliner) InlineFunc(f *ir.Func) int {
+	// Materialize function body if lazily parsed.
+	materialize(f)
+	n := 0
+	for _, site := range callSites(f) {
+		callee := calleeOf(site)
+		if callee == nil || callee == f {
+			continue
+		}
+		// Materialize callee body if lazily parsed, before computing the cost of
+		// inlining.
+		materialize(callee)
+		if !in.shouldInline(f, callee, site) {
+			continue
+		}
+		if InlineCall(f, site) {
+			n++
+		}
+	}
+	return n
+}
+
+// shouldInline reports whether the given call site of caller should be
+// inlined, based on the function attributes of the callee and the call site,
+// and the cost model of the inliner.
+func (in *Inliner) shouldInline(caller, callee *ir.Func, site value.User) bool {
+	attrs := siteFuncAttrs(site)
+	if hasFuncAttr(callee.FuncAttrs, enum.FuncAttrNoInline) || hasFuncAttr(attrs, enum.FuncAttrNoInline) {
+		return false
+	}
+	if hasFuncAttr(callee.FuncAttrs, enum.FuncAttrAlwaysInline) || hasFuncAttr(attrs, enum.FuncAttrAlwaysInline) {
+		return true
+	}
+	cost := in.Cost
+	if cost == nil {
+		cost = DefaultInlineCost
+	}
+	return cost(caller, callee, site) <= in.Threshold
+}
+
+// DefaultInlineCost returns the default cost of inlining the callee at the
+// given call site of caller; the number of instructions and terminators of the
+// callee, excluding debug intrinsics.
+func DefaultInlineCost(caller, callee *ir.Func, site value.User) int {
+	cost := 0
+	for _, block := range callee.Blocks {
+		for _, inst := range block.Insts {
+			if call, ok := inst.(*ir.InstCall); ok {
+				if f, ok := call.Callee.(*ir.Func); ok && strings.HasPrefix(f.Name(), "llvm.dbg.") {
+					continue
+				}
+			}
+			cost++
+		}
+		cost++
+	}
+	return cost
+}
+
+// InlineCall inlines the body of the callee at the given call site of f; an
+// *ir.InstCall or *ir.TermInvoke. InlineCall reports whether the call site was
+// inlined.
+//
+// The basic block of the call site is split at the call site, and the basic
+// blocks of a copy of the callee are inserted in between, with the parameters
+// of the callee replaced by the arguments of the call site. Return values of
+// the callee are merged into a phi instruction replacing the result of the
+// call site. Static allocas of the entry block of the callee are moved to the
+// entry block of f. Named local variables of the inlined body are given the
+// suffix ".i".
+//
+// When inlining an invoke terminator, calls within the inlined body which may
+// unwind are converted to invokes unwinding to the exception target of the
+// invoke, resume terminators branch to the exception target (after its
+// landingpad instruction), and the clauses of its landingpad are appended to
+// inlined landingpad instructions.
+//
+// Debug locations of the inlined body are updated with inlinedAt chains ending
+// in the debug location of the call site, if present.
+//
+// Call sites are not inlined if the callee is not a function definition (e.g.
+// an indirect call), is variadic, contains indirectbr or callbr terminators or
+// musttail calls, or uses a personality function other than the personality
+// function of f; if the types of arguments or the result of the call site
+// mismatch the callee; or when inlining an invoke terminator, if the exception
+// target is not a landingpad or the callee uses funclet-based exception
+// handling.
+func InlineCall(f *ir.Func, site value.User) bool {
+	callee := calleeOf(site)
+	if callee == nil || !canInline(f, callee, site) {
+		return false
+	}
+	block := blockOf(f, site)
+	if block == nil {
+		return false
+	}
+	var args []value.Value
+	var mds []*metadata.Attachment
+	var unwind *ir.Block
+	switch site := site.(type) {
+	case *ir.InstCall:
+		args, mds = site.Args, site.Metadata
+	case *ir.TermInvoke:
+		args, mds = site.Args, site.Metadata
+		unwind = site.ExceptionRetTarget.(*ir.Block)
+	}
+	names := localNames(f)
+	body, _ := callee.Clone()
+	for i, param := range body.Params {
+		arg := args[i]
+		if a, ok := arg.(*ir.Arg); ok {
+			arg = a.Value
+		}
+		body.ReplaceAllUsesWith(param, arg)
+	}
+	for _, b := range body.Blocks {
+		renameInlined(b, names)
+		for _, inst := range b.Insts {
+			renameInlined(inst, names)
+		}
+		renameInlined(b.Term, names)
+	}
+	if loc := debugLoc(mds); loc != nil {
+		locs := make(map[*metadata.DILocation]*metadata.DILocation)
+		for _, b := range body.Blocks {
+			for _, inst := range b.Insts {
+				setInlinedAt(inst, loc, locs)
+			}
+			setInlinedAt(b.Term, loc, locs)
+		}
+	}
+	// Split the basic block of the call site. Inlined return terminators branch
+	// to the basic block after the call site.
+	var after *ir.Block
+	switch site := site.(type) {
+	case *ir.InstCall:
+		after = f.SplitBlock(site)
+		after.Remove(site)
+	case *ir.TermInvoke:
+		after = ir.NewBlock("")
+		after.Parent = f
+		after.Term = ir.NewBr(site.NormalRetTarget.(*ir.Block))
+		insertBlocks(f, block, after)
+		replacePred(site.NormalRetTarget.(*ir.Block), block, after)
+	}
+	entry := body.Blocks[0]
+	br := ir.NewBr(entry)
+	br.Metadata = mds
+	block.Term = br
+	for _, b := range body.Blocks {
+		b.Parent = f
+	}
+	insertBlocks(f, block, body.Blocks...)
+	// Move static allocas to the entry block of the caller.
+	movedAllocas := false
+	pos := 0
+	for _, inst := range append([]ir.Instruction(nil), entry.Insts...) {
+		if alloca, ok := inst.(*ir.InstAlloca); ok && isStaticAlloca(alloca) {
+			entry.MoveTo(alloca, f.Blocks[0], pos)
+			pos++
+			movedAllocas = true
+		}
+	}
+	// Tail calls of the inlined body may not access the moved allocas.
+	if movedAllocas {
+		for _, b := range body.Blocks {
+			for _, inst := range b.Insts {
+				if call, ok := inst.(*ir.InstCall); ok && call.Tail == enum.TailTail {
+					call.Tail = enum.TailNone
+				}
+			}
+		}
+	}
+	// Merge return values.
+	var incs []*ir.Incoming
+	for _, b := range body.Blocks {
+		ret, ok := b.Term.(*ir.TermRet)
+		if !ok {
+			continue
+		}
+		br := ir.NewBr(after)
+		br.Metadata = ret.Metadata
+		b.Term = br
+		if ret.X != nil {
+			incs = append(incs, ir.NewIncoming(ret.X, b))
+		}
+	}
+	if result := site.(value.Value); !result.Type().Equal(types.Void) {
+		var v value.Value
+		switch len(incs) {
+		case 0:
+			// The callee never returns.
+			v = constant.NewUndef(result.Type())
+		case 1:
+			v = incs[0].X
+		default:
+			phi := ir.NewPhi(incs...)
+			if named := result.(value.Named); !isUnnamed(named) {
+				phi.SetName(named.Name())
+			}
+			after.InsertAt(phi, 0)
+			v = phi
+		}
+		f.ReplaceAllUsesWith(result, v)
+	}
+	if hasEHPads(body) && f.Personality == nil {
+		f.Personality = callee.Personality
+	}
+	if unwind != nil {
+		inlineUnwind(f, block, unwind, body.Blocks, names)
+	}
+	f.ResetIDs()
+	return true
+}
+
+// inlineUnwind redirects the unwinding of the given basic blocks, inlined at
+// an invoke terminator of block, to the exception target of the invoke.
+func inlineUnwind(f *ir.Func, block, unwind *ir.Block, blocks []*ir.Block, names map[string]bool) {
+	lpad := landingPadOf(unwind)
+	// Values of phi instructions of the exception target incoming from the
+	// basic block of the invoke.
+	var phis []*ir.InstPhi
+	var vals []value.Value
+	for _, inst := range unwind.Insts {
+		phi, ok := inst.(*ir.InstPhi)
+		if !ok {
+			break
+		}
+		phis = append(phis, phi)
+		vals = append(vals, incomingFrom(phi, block))
+	}
+	addIncomings := func(pred *ir.Block) {
+		for i, phi := range phis {
+			phi.Incs = append(phi.Incs, ir.NewIncoming(vals[i], pred))
+		}
+	}
+	// Append the clauses of the landingpad of the exception target to inlined
+	// landingpads.
+	var resumes []*ir.Block
+	for _, b := range blocks {
+		for _, inst := range b.Insts {
+			if lp, ok := inst.(*ir.InstLandingPad); ok {
+				for _, clause := range lpad.Clauses {
+					lp.Clauses = append(lp.Clauses, ir.NewClause(clause.Type, clause.X))
+				}
+				if lpad.Cleanup {
+					lp.Cleanup = true
+				}
+			}
+		}
+		if _, ok := b.Term.(*ir.TermResume); ok {
+			resumes = append(resumes, b)
+		}
+	}
+	// Branch from resume terminators to the exception target, after its
+	// landingpad.
+	if len(resumes) > 0 {
+		var innerPhis []*ir.InstPhi
+		for _, phi := range phis {
+			innerPhi := ir.NewPhi(ir.NewIncoming(phi, unwind))
+			if !isUnnamed(phi) {
+				innerPhi.SetName(uniqueName(names, phi.Name()+".lpad-body"))
+			}
+			f.ReplaceAllUsesWith(phi, innerPhi)
+			innerPhis = append(innerPhis, innerPhi)
+		}
+		ehPhi := ir.NewPhi(ir.NewIncoming(lpad, unwind))
+		ehPhi.SetName(uniqueName(names, "eh.lpad-body"))
+		f.ReplaceAllUsesWith(lpad, ehPhi)
+		body := splitAfter(f, unwind, lpad)
+		if !isUnnamed(unwind) {
+			body.SetName(uniqueName(names, unwind.Name()+".body"))
+		}
+		insts := make([]ir.Instruction, 0, len(innerPhis)+1+len(body.Insts))
+		for _, innerPhi := range innerPhis {
+			insts = append(insts, innerPhi)
+		}
+		insts = append(insts, ehPhi)
+		body.Insts = append(insts, body.Insts...)
+		for _, b := range resumes {
+			resume := b.Term.(*ir.TermResume)
+			br := ir.NewBr(body)
+			br.Metadata = resume.Metadata
+			b.Term = br
+			for i, innerPhi := range innerPhis {
+				innerPhi.Incs = append(innerPhi.Incs, ir.NewIncoming(vals[i], b))
+			}
+			ehPhi.Incs = append(ehPhi.Incs, ir.NewIncoming(resume.X, b))
+		}
+	}
+	// Convert calls which may unwind to invokes.
+	var calls []*ir.InstCall
+	for _, b := range blocks {
+		for _, inst := range b.Insts {
+			if call, ok := inst.(*ir.InstCall); ok && mayUnwind(call) {
+				calls = append(calls, call)
+			}
+		}
+	}
+	for _, call := range calls {
+		pred := blockOf(f, call)
+		normal := f.SplitBlock(call)
+		invoke := ir.NewInvokeSig(call.CalleeSig, call.Callee, call.Args, normal, unwind)
+		invoke.LocalIdent = call.LocalIdent
+		invoke.Typ = call.Typ
+		invoke.CallingConv = call.CallingConv
+		invoke.ReturnAttrs = call.ReturnAttrs
+		invoke.AddrSpace = call.AddrSpace
+		invoke.FuncAttrs = call.FuncAttrs
+		invoke.OperandBundles = call.OperandBundles
+		invoke.Metadata = call.Metadata
+		pred.Term = invoke
+		normal.Remove(call)
+		if !call.Type().Equal(types.Void) {
+			f.ReplaceAllUsesWith(call, invoke)
+		}
+		addIncomings(pred)
+	}
+	// The basic block of the invoke no longer branches to the exception target.
+	for _, phi := range phis {
+		incs := phi.Incs[:0]
+		for _, inc := range phi.Incs {
+			if inc.Pred != block {
+				incs = append(incs, inc)
+			}
+		}
+		phi.Incs = incs
+	}
+}
+
+// ### [ Helper functions ] ####################################################
+
+// canInline reports whether the callee of the given call site of f may be
+// inlined.
+func canInline(f, callee *ir.Func, site value.User) bool {
+	// Materialize callee body if lazily parsed.
+	if err := callee.Materialize(); err != nil {
+		return false
+	}
+	if len(callee.Blocks) == 0 || callee.Sig.Variadic {
+		return false
+	}
+	var args []value.Value
+	invoke := false
+	switch site := site.(type) {
+	case *ir.InstCall:
+		args = site.Args
+	case *ir.TermInvoke:
+		args = site.Args
+		invoke = true
+		unwind, ok := site.ExceptionRetTarget.(*ir.Block)
+		if !ok || landingPadOf(unwind) == nil {
+			return false
+		}
+	}
+	if len(args) != len(callee.Params) {
+		return false
+	}
+	for i, param := range callee.Params {
+		if types.Equal(param.Type(), types.Metadata) || !param.Type().Equal(args[i].Type()) {
+			return false
+		}
+	}
+	if !callee.Sig.RetType.Equal(site.(value.Value).Type()) {
+		return false
+	}
+	for _, block := range callee.Blocks {
+		for _, inst := range block.Insts {
+			switch inst := inst.(type) {
+			case *ir.InstCall:
+				if inst.Tail == enum.TailMustTail {
+					return false
+				}
+			case *ir.InstCatchPad, *ir.InstCleanupPad:
+				if invoke {
+					return false
+				}
+			}
+		}
+		switch block.Term.(type) {
+		case *ir.TermIndirectBr, *ir.TermCallBr:
+			return false
+		case *ir.TermCatchSwitch, *ir.TermCatchRet, *ir.TermCleanupRet:
+			if invoke {
+				return false
+			}
+		}
+	}
+	if hasEHPads(callee) && f.Personality != nil && callee.Personality != nil && f.Personality.Ident() != callee.Personality.Ident() {
+		return false
+	}
+	return true
+}
+
+// callSites returns the call instructions and invoke terminators of the given
+// function.
+func callSites(f *ir.Func) []value.User {
+	var sites []value.User
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			if call, ok := inst.(*ir.InstCall); ok {
+				sites = append(sites, call)
+			}
+		}
+		if invoke, ok := block.Term.(*ir.TermInvoke); ok {
+			sites = append(sites, invoke)
+		}
+	}
+	return sites
+}
+
+// calleeOf returns the function called directly by the given call site; or nil
+// if not a direct call of a function.
+func calleeOf(site value.User) *ir.Func {
+	var callee value.Value
+	switch site := site.(type) {
+	case *ir.InstCall:
+		callee = site.Callee
+	case *ir.TermInvoke:
+		callee = site.Invokee
+	}
+	f, _ := callee.(*ir.Func)
+	return f
+}
+
+// siteFuncAttrs returns the function attributes of the given call site.
+func siteFuncAttrs(site value.User) []ir.FuncAttribute {
+	switch site := site.(type) {
+	case *ir.InstCall:
+		return site.FuncAttrs
+	case *ir.TermInvoke:
+		return site.FuncAttrs
+	}
+	return nil
+}
+
+// hasFuncAttr reports whether the given function attributes, including those of
+// attribute groups, contain attr.
+func hasFuncAttr(attrs []ir.FuncAttribute, attr enum.FuncAttr) bool {
+	for _, a := range attrs {
+		switch a := a.(type) {
+		case enum.FuncAttr:
+			if a == attr {
+				return true
+			}
+		case *ir.AttrGroupDef:
+			if hasFuncAttr(a.FuncAttrs, attr) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mayUnwind reports whether the given call instruction may unwind.
+func mayUnwind(call *ir.InstCall) bool {
+	if hasFuncAttr(call.FuncAttrs, enum.FuncAttrNoUnwind) {
+		return false
+	}
+	switch callee := call.Callee.(type) {
+	case *ir.InlineAsm:
+		return false
+	case *ir.Func:
+		return !strings.HasPrefix(callee.Name(), "llvm.") && !hasFuncAttr(callee.FuncAttrs, enum.FuncAttrNoUnwind)
+	}
+	return true
+}
+
+// hasEHPads reports whether the given function contains exception handling
+// pads or resume terminators.
+func hasEHPads(f *ir.Func) bool {
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			switch inst.(type) {
+			case *ir.InstLandingPad, *ir.InstCatchPad, *ir.InstCleanupPad:
+				return true
+			}
+		}
+		switch block.Term.(type) {
+		case *ir.TermResume, *ir.TermCatchSwitch:
+			return true
+		}
+	}
+	return false
+}
+
+// isStaticAlloca reports whether the given alloca instruction has a constant
+// number of elements.
+func isStaticAlloca(alloca *ir.InstAlloca) bool {
+	if alloca.NElems == nil {
+		return true
+	}
+	_, ok := alloca.NElems.(constant.Constant)
+	return ok
+}
+
+// blockOf returns the basic block of f containing the given instruction or
+// terminator; or nil if not present.
+func blockOf(f *ir.Func, user value.User) *ir.Block {
+	for _, block := range f.Blocks {
+		if value.User(block.Term) == user {
+			return block
+		}
+		for _, inst := range block.Insts {
+			if value.User(inst) == user {
+				return block
+			}
+		}
+	}
+	return nil
+}
+
+// landingPadOf returns the landingpad instruction of the given basic block; or
+// nil if the first non-phi instruction of block is not a landingpad.
+func landingPadOf(block *ir.Block) *ir.InstLandingPad {
+	for _, inst := range block.Insts {
+		if _, ok := inst.(*ir.InstPhi); ok {
+			continue
+		}
+		lpad, _ := inst.(*ir.InstLandingPad)
+		return lpad
+	}
+	return nil
+}
+
+// incomingFrom returns the incoming value of the given phi instruction from
+// pred; or nil if not present.
+func incomingFrom(phi *ir.InstPhi, pred *ir.Block) value.Value {
+	for _, inc := range phi.Incs {
+		if inc.Pred == pred {
+			return inc.X
+		}
+	}
+	return nil
+}
+
+// replacePred replaces the predecessor old of incoming values of phi
+// instructions in the given basic block with new.
+func replacePred(block, old, new *ir.Block) {
+	for _, inst := range block.Insts {
+		phi, ok := inst.(*ir.InstPhi)
+		if !ok {
+			break
+		}
+		for _, inc := range phi.Incs {
+			if inc.Pred == old {
+				inc.Pred = new
+			}
+		}
+	}
+}
+
+// insertBlocks inserts the given basic blocks into f, directly after the basic
+// block prev.
+func insertBlocks(f *ir.Func, prev *ir.Block, blocks ...*ir.Block) {
+	for i, block := range f.Blocks {
+		if block == prev {
+			tail := append(append([]*ir.Block(nil), blocks...), f.Blocks[i+1:]...)
+			f.Blocks = append(f.Blocks[:i+1], tail...)
+			return
+		}
+	}
+	panic(fmt.Errorf("unable to locate basic block %s in function %s", prev.Ident(), f.Ident()))
+}
+
+// splitAfter splits the given basic block of f after the instruction inst,
+// moving the instructions following inst and the terminator to a new unnamed
+// basic block, which is returned. The original basic block branches to the new
+// basic block.
+func splitAfter(f *ir.Func, block *ir.Block, inst ir.Instruction) *ir.Block {
+	pos := 0
+	for i, v := range block.Insts {
+		if v == inst {
+			pos = i + 1
+			break
+		}
+	}
+	split := ir.NewBlock("")
+	split.Parent = f
+	split.Insts = append([]ir.Instruction(nil), block.Insts[pos:]...)
+	split.Term = block.Term
+	block.Insts = block.Insts[:pos]
+	block.Term = ir.NewBr(split)
+	insertBlocks(f, block, split)
+	for _, succ := range split.Term.Succs() {
+		replacePred(succ, block, split)
+	}
+	return split
+}
+
+// localNames returns the set of names of named parameters, basic blocks and
+// local variables of the given function.
+func localNames(f *ir.Func) map[string]bool {
+	names := make(map[string]bool)
+	add := func(v interface{}) {
+		if n, ok := v.(value.Named); ok && !isUnnamed(n) {
+			names[n.Name()] = true
+		}
+	}
+	for _, param := range f.Params {
+		add(param)
+	}
+	for _, block := range f.Blocks {
+		add(block)
+		for _, inst := range block.Insts {
+			add(inst)
+		}
+		add(block.Term)
+	}
+	return names
+}
+
+// renameInlined renames the given inlined basic block, instruction or
+// terminator if named, adding the suffix ".i" and ensuring that the name is
+// unique.
+func renameInlined(v interface{}, names map[string]bool) {
+	if n, ok := v.(value.Named); ok && !isUnnamed(n) {
+		n.SetName(uniqueName(names, n.Name()+".i"))
+	}
+}
+
+// uniqueName returns a local name based on the given name which is not present
+// in names, and adds it to names.
+func uniqueName(names map[string]bool, name string) string {
+	unique := name
+	for i := 1; names[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	names[unique] = true
+	return unique
+}
+
+// isUnnamed reports whether the given value is an unnamed local value.
+func isUnnamed(v value.Named) bool {
+	n, ok := v.(interface{ IsUnnamed() bool })
+	return ok && n.IsUnnamed()
+}
+
+// debugLoc returns the debug location of the given metadata attachments; or nil
+// if not present.
+func debugLoc(mds []*metadata.Attachment) *metadata.DILocation {
+	for _, md := range mds {
+		if loc, ok := md.Node.(*metadata.DILocation); ok && md.Name == "dbg" {
+			return loc
+		}
+	}
+	return nil
+}
+
+// setInlinedAt updates the debug location of the given inlined instruction or
+// terminator, appending the call site location at to its inlinedAt chain. The
+// updated debug locations are memoized in locs.
+func setInlinedAt(v interface{}, at *metadata.DILocation, locs map[*metadata.DILocation]*metadata.DILocation) {
+	md, ok := v.(interface {
+		MDAttachments() []*metadata.Attachment
+		SetMDAttachments(attachments []*metadata.Attachment)
+	})
+	if !ok {
+		return
+	}
+	for i, attachment := range md.MDAttachments() {
+		loc, ok := attachment.Node.(*metadata.DILocation)
+		if !ok || attachment.Name != "dbg" {
+			continue
+		}
+		mds := append([]*metadata.Attachment(nil), md.MDAttachments()...)
+		mds[i] = &metadata.Attachment{Name: "dbg", Node: inlinedLoc(loc, at, locs)}
+		md.SetMDAttachments(mds)
+		return
+	}
+}
+
+// inlinedLoc returns a copy of the given debug location, with the call site
+// location at appended to its inlinedAt chain. The copies are memoized in locs.
+func inlinedLoc(loc, at *metadata.DILocation, locs map[*metadata.DILocation]*metadata.DILocation) *metadata.DILocation {
+	if dup, ok := locs[loc]; ok {
+		return dup
+	}
+	inlinedAt := at
+	if loc.InlinedAt != nil {
+		inlinedAt = inlinedLoc(loc.InlinedAt, at, locs)
+	}
+	dup := &metadata.DILocation{
+		MetadataID:     -1,
+		Line:           loc.Line,
+		Column:         loc.Column,
+		Scope:          loc.Scope,
+		InlinedAt:      inlinedAt,
+		IsImplicitCode: loc.IsImplicitCode,
+	}
+	locs[loc] = dup
+	return dup
+}
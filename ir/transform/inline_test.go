@@ -0,0 +1,73 @@
+package transform_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/llir/llvm/asm"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/transform"
+	"github.com/llir/llvm/ir/value"
+	"github.com/llir/llvm/ir/verify"
+)
+
+func TestInliner(t *testing.T) {
+	golden := []struct {
+		path string
+		// Parse function bodies lazily.
+		lazy bool
+		// Number of inlined call sites.
+		nInlined int
+	}{
+		{path: "testdata/inline.ll", nInlined: 8},
+		{path: "testdata/inline.ll", lazy: true, nInlined: 8},
+	}
+	for _, g := range golden {
+		cfg := &asm.Config{Lazy: g.lazy}
+		m, err := cfg.ParseFile(g.path)
+		if err != nil {
+			t.Errorf("unable to parse %q; %+v", g.path, err)
+			continue
+		}
+		n := transform.NewInliner().InlineModule(m)
+		if n != g.nInlined {
+			t.Errorf("%q: number of inlined call sites mismatch; expected %d, got %d", g.path, g.nInlined, n)
+		}
+		if err := verify.Module(m); err != nil {
+			t.Errorf("%q: invalid module after transformation; %v", g.path, err)
+		}
+		buf, err := ioutil.ReadFile(g.path + ".golden")
+		if err != nil {
+			t.Errorf("unable to read %q; %+v", g.path+".golden", err)
+			continue
+		}
+		want := string(buf)
+		got := m.String()
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("module %q mismatch (-want +got):\n%s", g.path, diff)
+		}
+	}
+}
+
+func TestInlinerCost(t *testing.T) {
+	const path = "testdata/inline.ll"
+	m, err := asm.ParseFile(path)
+	if err != nil {
+		t.Fatalf("unable to parse %q; %+v", path, err)
+	}
+	// Only call sites of alwaysinline functions are inlined if the cost of each
+	// call site exceeds the threshold.
+	in := &transform.Inliner{
+		Cost: func(caller, callee *ir.Func, site value.User) int {
+			return 1
+		},
+		Threshold: 0,
+	}
+	if n := in.InlineModule(m); n != 1 {
+		t.Errorf("%q: number of inlined call sites mismatch; expected %d, got %d", path, 1, n)
+	}
+	if err := verify.Module(m); err != nil {
+		t.Errorf("%q: invalid module after transformation; %v", path, err)
+	}
+}
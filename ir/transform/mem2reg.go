@@ -0,0 +1,427 @@
+package transform
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/cfg"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/dom"
+	"github.com/llir/llvm/ir/metadata"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// === [ Promote memory to register ] ==========================================
+
+// PromoteMemToReg promotes the promotable alloca instructions of the entry
+// basic block of the given function to SSA registers (mem2reg), and returns the
+// number of promoted alloca instructions.
+//
+// Phi instructions are inserted at the iterated dominance frontier of the
+// stores to each promoted alloca instruction (pruned to the basic blocks in
+// which the value of the alloca instruction is live), and loads are replaced
+// by the reaching stored values. Loads not reached by any store are replaced by
+// undef, as are the incoming values of phi instructions for unreachable
+// predecessors.
+//
+// An alloca instruction is promotable if it allocates a single element, and is
+// only used as the address of non-volatile, non-atomic loads and stores of its
+// element type, and as the address of llvm.dbg.declare intrinsic calls.
+//
+// As in LLVM, the llvm.dbg.declare intrinsic calls of promoted alloca
+// instructions are converted to llvm.dbg.value intrinsic calls of the values
+// stored to the alloca instruction and of the inserted phi instructions. The
+// declaration of llvm.dbg.value is added to the parent module of the function
+// if not present. Functions without a parent module have their llvm.dbg.declare
+// intrinsic calls of promoted alloca instructions removed.
+func PromoteMemToReg(f *ir.Func) int {
+	// Materialize function body if lazily parsed.
+	materialize(f)
+	if len(f.Blocks) == 0 {
+		return 0
+	}
+	allocas, declares := promotableAllocas(f)
+	if len(allocas) == 0 {
+		return 0
+	}
+	p := &promoter{
+		f:        f,
+		tree:     dom.New(cfg.New(f)),
+		allocas:  allocas,
+		index:    make(map[*ir.InstAlloca]int),
+		declares: make([][]*ir.InstCall, len(allocas)),
+		phis:     make(map[*ir.Block][]*phiInfo),
+		repl:     make(map[value.Value]value.Value),
+		dead:     make(map[ir.Instruction]bool),
+	}
+	for i, alloca := range allocas {
+		p.index[alloca] = i
+		p.dead[alloca] = true
+		p.declares[i] = declares[alloca]
+		for _, declare := range declares[alloca] {
+			p.dead[declare] = true
+		}
+	}
+	for i := range allocas {
+		p.placePhis(i)
+	}
+	p.rename()
+	// Insert phi instructions at the beginning of their basic blocks, followed by
+	// llvm.dbg.value intrinsic calls of the phi instructions.
+	for _, block := range f.Blocks {
+		phis := p.phis[block]
+		for i, phi := range phis {
+			block.InsertAt(phi.inst, i)
+		}
+		pos := len(phis)
+		for _, phi := range phis {
+			for _, declare := range p.declares[phi.alloca] {
+				if dbgValue := p.newDbgValue(phi.inst, declare); dbgValue != nil {
+					block.InsertAt(dbgValue, pos)
+					pos++
+				}
+			}
+		}
+	}
+	// Insert llvm.dbg.value intrinsic calls of stored values, and remove promoted
+	// allocas, loads and stores.
+	for _, store := range p.stores {
+		i, _ := p.allocaIndex(store.inst.Dst)
+		for _, declare := range p.declares[i] {
+			if dbgValue := p.newDbgValue(store.inst.Src, declare); dbgValue != nil {
+				store.block.InsertBefore(dbgValue, store.inst)
+			}
+		}
+	}
+	f.ReplaceAllUses(p.repl)
+	removeInsts(f, p.dead)
+	return len(allocas)
+}
+
+// promoter keeps track of the state of promoting alloca instructions of a
+// function to SSA registers.
+type promoter struct {
+	// Function being transformed.
+	f *ir.Func
+	// Dominator tree of the function.
+	tree *dom.Tree
+	// Promotable alloca instructions.
+	allocas []*ir.InstAlloca
+	// Index of each promotable alloca instruction in allocas.
+	index map[*ir.InstAlloca]int
+	// llvm.dbg.declare intrinsic calls of each promotable alloca instruction.
+	declares [][]*ir.InstCall
+	// Stores to promotable alloca instructions with llvm.dbg.declare intrinsic
+	// calls, in order of occurrence.
+	stores []*storeInfo
+	// Declaration of llvm.dbg.value; or nil if not yet located.
+	dbgValue *ir.Func
+	// Phi instructions inserted into each basic block.
+	phis map[*ir.Block][]*phiInfo
+	// Replacement values of removed loads.
+	repl map[value.Value]value.Value
+	// Instructions to remove.
+	dead map[ir.Instruction]bool
+}
+
+// phiInfo is a phi instruction inserted for a promoted alloca instruction.
+type phiInfo struct {
+	// Phi instruction.
+	inst *ir.InstPhi
+	// Index of the promoted alloca instruction.
+	alloca int
+}
+
+// storeInfo is a store to a promoted alloca instruction.
+type storeInfo struct {
+	// Store instruction.
+	inst *ir.InstStore
+	// Basic block of the store instruction.
+	block *ir.Block
+}
+
+// placePhis inserts phi instructions for the alloca instruction with the given
+// index at the iterated dominance frontier of the basic blocks storing to the
+// alloca instruction, pruned to the basic blocks in which the value of the
+// alloca instruction is live.
+func (p *promoter) placePhis(i int) {
+	alloca := p.allocas[i]
+	defBlocks := make(map[*ir.Block]bool)
+	var work []*ir.Block
+	for _, block := range p.f.Blocks {
+		for _, inst := range block.Insts {
+			if store, ok := inst.(*ir.InstStore); ok && store.Dst == alloca {
+				defBlocks[block] = true
+				work = append(work, block)
+				break
+			}
+		}
+	}
+	liveIn := p.liveInBlocks(alloca, defBlocks)
+	hasPhi := make(map[*ir.Block]bool)
+	for len(work) > 0 {
+		block := work[len(work)-1]
+		work = work[:len(work)-1]
+		for _, df := range p.tree.Frontier(block) {
+			if hasPhi[df] || !liveIn[df] {
+				continue
+			}
+			hasPhi[df] = true
+			phi := &ir.InstPhi{Typ: alloca.ElemType}
+			p.phis[df] = append(p.phis[df], &phiInfo{inst: phi, alloca: i})
+			if !defBlocks[df] {
+				work = append(work, df)
+			}
+		}
+	}
+}
+
+// liveInBlocks returns the basic blocks in which the value of the given alloca
+// instruction is live on entry; i.e. basic blocks from which a load of the
+// alloca instruction is reachable without passing through a store to the
+// alloca instruction.
+func (p *promoter) liveInBlocks(alloca *ir.InstAlloca, defBlocks map[*ir.Block]bool) map[*ir.Block]bool {
+	liveIn := make(map[*ir.Block]bool)
+	var work []*ir.Block
+	for _, block := range p.f.Blocks {
+	loop:
+		for _, inst := range block.Insts {
+			switch inst := inst.(type) {
+			case *ir.InstStore:
+				if inst.Dst == alloca {
+					// Stored before loaded.
+					break loop
+				}
+			case *ir.InstLoad:
+				if inst.Src == alloca {
+					liveIn[block] = true
+					work = append(work, block)
+					break loop
+				}
+			}
+		}
+	}
+	g := p.tree.Graph
+	for len(work) > 0 {
+		block := work[len(work)-1]
+		work = work[:len(work)-1]
+		for _, pred := range g.Preds(block) {
+			if defBlocks[pred] || liveIn[pred] {
+				continue
+			}
+			liveIn[pred] = true
+			work = append(work, pred)
+		}
+	}
+	return liveIn
+}
+
+// rename replaces the loads of promoted alloca instructions with the reaching
+// stored values, by traversing the dominator tree, and records the incoming
+// values of inserted phi instructions.
+func (p *promoter) rename() {
+	type item struct {
+		block *ir.Block
+		vals  []value.Value
+	}
+	vals := make([]value.Value, len(p.allocas))
+	for i, alloca := range p.allocas {
+		vals[i] = constant.NewUndef(alloca.ElemType)
+	}
+	entry := p.f.Blocks[0]
+	stack := []item{{block: entry, vals: vals}}
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		block, vals := top.block, top.vals
+		for _, phi := range p.phis[block] {
+			vals[phi.alloca] = phi.inst
+		}
+		p.renameInsts(block, vals)
+		if block.Term != nil {
+			for _, succ := range block.Term.Succs() {
+				for _, phi := range p.phis[succ] {
+					phi.inst.Incs = append(phi.inst.Incs, ir.NewIncoming(vals[phi.alloca], block))
+				}
+			}
+		}
+		// Push children in reverse order, to visit them in order.
+		children := p.tree.Children(block)
+		for i := len(children) - 1; i >= 0; i-- {
+			childVals := make([]value.Value, len(vals))
+			copy(childVals, vals)
+			stack = append(stack, item{block: children[i], vals: childVals})
+		}
+	}
+	// Loads in unreachable basic blocks are replaced by undef, and inserted phi
+	// instructions have undef incoming values for unreachable predecessors.
+	for _, block := range p.f.Blocks {
+		if p.tree.Reachable(block) {
+			continue
+		}
+		vals := make([]value.Value, len(p.allocas))
+		for i, alloca := range p.allocas {
+			vals[i] = constant.NewUndef(alloca.ElemType)
+		}
+		p.renameInsts(block, vals)
+		if block.Term == nil {
+			continue
+		}
+		for _, succ := range block.Term.Succs() {
+			for _, phi := range p.phis[succ] {
+				undef := constant.NewUndef(p.allocas[phi.alloca].ElemType)
+				phi.inst.Incs = append(phi.inst.Incs, ir.NewIncoming(undef, block))
+			}
+		}
+	}
+}
+
+// renameInsts replaces the loads of promoted alloca instructions in the given
+// basic block with the reaching stored values, starting with the given values
+// on entry. The values are updated to the values on exit of the basic block.
+func (p *promoter) renameInsts(block *ir.Block, vals []value.Value) {
+	for _, inst := range block.Insts {
+		switch inst := inst.(type) {
+		case *ir.InstLoad:
+			if i, ok := p.allocaIndex(inst.Src); ok {
+				p.repl[inst] = vals[i]
+				p.dead[inst] = true
+			}
+		case *ir.InstStore:
+			if i, ok := p.allocaIndex(inst.Dst); ok {
+				vals[i] = inst.Src
+				p.dead[inst] = true
+				if len(p.declares[i]) > 0 {
+					p.stores = append(p.stores, &storeInfo{inst: inst, block: block})
+				}
+			}
+		}
+	}
+}
+
+// allocaIndex returns the index of the given value if a promoted alloca
+// instruction.
+func (p *promoter) allocaIndex(v value.Value) (int, bool) {
+	alloca, ok := v.(*ir.InstAlloca)
+	if !ok {
+		return 0, false
+	}
+	i, ok := p.index[alloca]
+	return i, ok
+}
+
+// newDbgValue returns a new llvm.dbg.value intrinsic call of the given value,
+// describing the variable of the given llvm.dbg.declare intrinsic call; or nil
+// if the function has no parent module.
+func (p *promoter) newDbgValue(v value.Value, declare *ir.InstCall) *ir.InstCall {
+	if p.dbgValue == nil {
+		m := p.f.Parent
+		if m == nil {
+			return nil
+		}
+		for _, f := range m.Funcs {
+			if f.Name() == "llvm.dbg.value" {
+				p.dbgValue = f
+				break
+			}
+		}
+		if p.dbgValue == nil {
+			p.dbgValue = m.NewFunc("llvm.dbg.value", types.Void, ir.NewParam("", types.Metadata), ir.NewParam("", types.Metadata), ir.NewParam("", types.Metadata))
+		}
+	}
+	args := []value.Value{&metadata.Value{Value: v}}
+	args = append(args, declare.Args[1:]...)
+	inst := ir.NewCall(p.dbgValue, args...)
+	for _, md := range declare.Metadata {
+		if md.Name == "dbg" {
+			inst.Metadata = append(inst.Metadata, md)
+		}
+	}
+	return inst
+}
+
+// ### [ Helper functions ] ####################################################
+
+// promotableAllocas returns the promotable alloca instructions of the entry
+// basic block of the given function, and the llvm.dbg.declare intrinsic calls
+// of each promotable alloca instruction.
+func promotableAllocas(f *ir.Func) ([]*ir.InstAlloca, map[*ir.InstAlloca][]*ir.InstCall) {
+	candidates := make(map[*ir.InstAlloca]bool)
+	declares := make(map[*ir.InstAlloca][]*ir.InstCall)
+	var allocas []*ir.InstAlloca
+	for _, inst := range f.Blocks[0].Insts {
+		alloca, ok := inst.(*ir.InstAlloca)
+		if !ok || !isSingleElem(alloca) || alloca.InAlloca || alloca.SwiftError {
+			continue
+		}
+		candidates[alloca] = true
+		allocas = append(allocas, alloca)
+	}
+	if len(allocas) == 0 {
+		return nil, nil
+	}
+	// Disqualify alloca instructions with uses other than as the address of
+	// simple loads and stores, and of llvm.dbg.declare intrinsic calls.
+	check := func(user value.User) {
+		for i, op := range user.Operands() {
+			alloca, ok := unwrap(*op).(*ir.InstAlloca)
+			if !ok || !candidates[alloca] {
+				continue
+			}
+			if *op == alloca && isSimpleAccess(user, i, alloca) {
+				continue
+			}
+			if call, ok := user.(*ir.InstCall); ok && isDbgDeclare(call) && i == 1 {
+				declares[alloca] = append(declares[alloca], call)
+				continue
+			}
+			delete(candidates, alloca)
+		}
+	}
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			check(inst)
+		}
+		if block.Term != nil {
+			check(block.Term)
+		}
+	}
+	promotable := allocas[:0]
+	for _, alloca := range allocas {
+		if candidates[alloca] {
+			promotable = append(promotable, alloca)
+		}
+	}
+	return promotable, declares
+}
+
+// isDbgDeclare reports whether the given call instruction is a call to the
+// llvm.dbg.declare intrinsic.
+func isDbgDeclare(call *ir.InstCall) bool {
+	callee, ok := call.Callee.(*ir.Func)
+	return ok && callee.Name() == "llvm.dbg.declare" && len(call.Args) == 3
+}
+
+// isSingleElem reports whether the given alloca instruction allocates a single
+// element.
+func isSingleElem(alloca *ir.InstAlloca) bool {
+	if alloca.NElems == nil {
+		return true
+	}
+	n, ok := alloca.NElems.(*constant.Int)
+	return ok && n.X.IsInt64() && n.X.Int64() == 1
+}
+
+// isSimpleAccess reports whether operand i of the given user is the address of
+// a non-volatile, non-atomic load or store of the element type of the given
+// alloca instruction.
+func isSimpleAccess(user value.User, i int, alloca *ir.InstAlloca) bool {
+	switch user := user.(type) {
+	case *ir.InstLoad:
+		return !user.Volatile && !user.Atomic && types.Equal(user.ElemType, alloca.ElemType)
+	case *ir.InstStore:
+		// Operand 0 is the stored value, and operand 1 the address.
+		return i == 1 && user.Src != alloca && !user.Volatile && !user.Atomic && types.Equal(user.Src.Type(), alloca.ElemType)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,77 @@
+package transform_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/llir/llvm/asm"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/transform"
+	"github.com/llir/llvm/ir/value"
+	"github.com/llir/llvm/ir/verify"
+)
+
+func TestPromoteMemToReg(t *testing.T) {
+	golden := []struct {
+		path string
+		// Parse function bodies lazily.
+		lazy bool
+		// Number of promoted alloca instructions of each function.
+		nPromoted []int
+	}{
+		{path: "testdata/mem2reg.ll", nPromoted: []int{0, 1, 1, 2, 1, 1, 1, 1, 1, 1, 0}},
+		{path: "testdata/mem2reg.ll", lazy: true, nPromoted: []int{0, 1, 1, 2, 1, 1, 1, 1, 1, 1, 0}},
+	}
+	for _, g := range golden {
+		cfg := &asm.Config{Lazy: g.lazy}
+		m, err := cfg.ParseFile(g.path)
+		if err != nil {
+			t.Errorf("unable to parse %q; %+v", g.path, err)
+			continue
+		}
+		for i, f := range m.Funcs {
+			// The use index registered on the function is kept consistent by the
+			// transformation.
+			idx := ir.NewFuncUseIndex(f)
+			f.SetUseIndex(idx)
+			n := transform.PromoteMemToReg(f)
+			if n != g.nPromoted[i] {
+				t.Errorf("%q: number of promoted allocas of function %s mismatch; expected %d, got %d", g.path, f.Ident(), g.nPromoted[i], n)
+			}
+			checkUseIndex(t, idx, f)
+		}
+		if err := verify.Module(m); err != nil {
+			t.Errorf("%q: invalid module after transformation; %v", g.path, err)
+		}
+		buf, err := ioutil.ReadFile(g.path + ".golden")
+		if err != nil {
+			t.Errorf("unable to read %q; %+v", g.path+".golden", err)
+			continue
+		}
+		want := string(buf)
+		got := m.String()
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("module %q mismatch (-want +got):\n%s", g.path, diff)
+		}
+	}
+}
+
+// checkUseIndex checks that the given use index is consistent with a use index
+// created from scratch for the given function.
+func checkUseIndex(t *testing.T, idx *ir.UseIndex, f *ir.Func) {
+	t.Helper()
+	want := ir.NewFuncUseIndex(f)
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			if got, want := len(idx.Operands(inst)), len(want.Operands(inst)); got != want {
+				t.Errorf("function %s: number of operands of %q mismatch; expected %d, got %d", f.Ident(), inst.LLString(), want, got)
+			}
+			if v, ok := inst.(value.Value); ok {
+				if got, want := idx.NumUses(v), want.NumUses(v); got != want {
+					t.Errorf("function %s: number of uses of %q mismatch; expected %d, got %d", f.Ident(), inst.LLString(), want, got)
+				}
+			}
+		}
+	}
+}
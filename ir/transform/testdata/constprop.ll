@@ -0,0 +1,89 @@
+@g = global i32 0
+
+define i32 @arith() {
+	%a = add i32 20, 22
+	%b = mul i32 %a, 2
+	%c = sub i32 %b, 84
+	%d = shl i32 1, 31
+	%e = ashr i32 %d, 31
+	%f = xor i32 %e, %c
+	%g = trunc i32 300 to i8
+	%h = sext i8 %g to i32
+	%i = add i32 %f, %h
+	ret i32 %i
+}
+
+define i8 @poison() {
+	%a = add nsw i8 127, 1
+	%b = add i8 127, 1
+	%c = lshr exact i8 %b, 1
+	%d = lshr exact i8 3, 1
+	%e = shl i8 1, 8
+	ret i8 %b
+}
+
+define double @float(float %x) {
+	%a = fadd double 0.1, 0.2
+	%b = fdiv double 1.0, 0.0
+	%c = fmul double %b, 0.0
+	%d = fptrunc double 0.1 to float
+	%e = fpext float %d to double
+	%f = fadd nnan double %c, 1.0
+	%g = fcmp olt double %a, %e
+	%h = select i1 %g, double %a, double %e
+	%i = fdiv ninf double 1.0, 0.0
+	%j = fptosi double 1.0e10 to i32
+	%k = sitofp i32 -3 to float
+	%l = fadd float %x, %k
+	ret double %h
+}
+
+define i32 @phi(i1 %cond) {
+entry:
+	%a = add i32 1, 2
+	br i1 %cond, label %left, label %right
+
+left:
+	%b = mul i32 %a, 1
+	br label %exit
+
+right:
+	br label %exit
+
+exit:
+	%c = phi i32 [ %b, %left ], [ 3, %right ]
+	%d = phi i32 [ %a, %left ], [ 4, %right ]
+	%e = icmp eq i32 %c, 3
+	%f = select i1 %e, i32 %c, i32 0
+	%g = add i32 %f, %d
+	ret i32 %g
+}
+
+define i32 @aggregate() {
+	%a = insertvalue { i32, [2 x i32] } zeroinitializer, i32 7, 1, 0
+	%b = extractvalue { i32, [2 x i32] } %a, 1
+	%c = extractvalue [2 x i32] %b, 0
+	%d = insertelement <2 x i32> zeroinitializer, i32 %c, i32 1
+	%e = add <2 x i32> %d, <i32 1, i32 1>
+	%f = shufflevector <2 x i32> %e, <2 x i32> undef, <2 x i32> <i32 1, i32 0>
+	%g = extractelement <2 x i32> %f, i32 0
+	ret i32 %g
+}
+
+define i32 @nofold(i32 %x, i32* %p) {
+	%a = udiv i32 1, 0
+	%b = add i32 %x, 1
+	%c = ptrtoint i32* @g to i32
+	%d = add i32 %c, 1
+	%e = load i32, i32* %p
+	%f = add i32 undef, 1
+	%g = freeze i32 undef
+	%h = sdiv i32 -2147483648, -1
+	%i = add i32 %a, %b
+	%j = add i32 %i, %d
+	%k = add i32 %j, %e
+	%l = add i32 %k, %f
+	%m = add i32 %l, %g
+	%n = add i32 %m, %h
+	ret i32 %n
+}
@@ -0,0 +1,36 @@
+declare i32 @g(i32)
+
+define i32 @f(i32 %x, i32* %p) {
+entry:
+	; Chain of dead instructions.
+	%a = add i32 %x, 1
+	%b = mul i32 %a, 2
+	%c = icmp eq i32 %b, 0
+	%d = select i1 %c, i32 %a, i32 %b
+	; Dead instructions without side effects.
+	%e = load i32, i32* %p
+	%q = getelementptr i32, i32* %p, i64 1
+	%s = alloca i32
+	; Instructions with side effects.
+	%v = load volatile i32, i32* %p
+	%r = call i32 @g(i32 %x)
+	store i32 %x, i32* %p
+	%live = sub i32 %r, %x
+	br label %loop
+
+loop:
+	; Phi instruction only used by itself.
+	%i = phi i32 [ 0, %entry ], [ %i, %loop ]
+	%cond = icmp slt i32 %x, 10
+	br i1 %cond, label %loop, label %exit
+
+exit:
+	ret i32 %live
+}
+
+define void @unnamed(i32) {
+	%2 = add i32 %0, 1
+	%3 = add i32 %0, 2
+	%4 = call i32 @g(i32 %3)
+	ret void
+}
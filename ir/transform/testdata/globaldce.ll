@@ -0,0 +1,54 @@
+%used = type { i32, %nested* }
+%nested = type { i8 }
+%unused = type { i64 }
+%param = type { i16 }
+
+@0 = private global i32 0
+@1 = private global i32 1
+@live = global i32* @1
+@dead = internal global i32 2
+@dead.cycle = internal global i8* bitcast (i8** @dead.cycle to i8*)
+@ext = external global i32
+@llvm.used = appending global [1 x i8*] [i8* bitcast (void ()* @kept.used to i8*)], section "llvm.metadata"
+
+@alias = alias i32, i32* @aliasee
+@aliasee = internal global i32 3
+@alias.dead = internal alias i32, i32* @dead
+
+declare void @unused.decl()
+declare void @used.decl(%param* byval(%param))
+
+define void @main() !dbg !3 {
+	%1 = alloca %used
+	call void @used.decl(%param* byval(%param) null)
+	call void @helper()
+	ret void
+}
+
+define internal void @helper() {
+	ret void
+}
+
+define internal void @dead.func() {
+	call void @dead.func2()
+	ret void
+}
+
+define internal void @dead.func2() {
+	call void @dead.func()
+	ret void
+}
+
+define internal void @kept.used() {
+	ret void
+}
+
+!llvm.module.flags = !{!0}
+!llvm.dbg.cu = !{!5}
+
+!0 = !{i32 2, !"Debug Info Version", i32 3}
+!1 = !{!"unused"}
+!2 = !{!1}
+!3 = distinct !DISubprogram(name: "main", scope: !4, file: !4, unit: !5)
+!4 = !DIFile(filename: "a.c", directory: "/")
+!5 = distinct !DICompileUnit(language: DW_LANG_C99, file: !4, isOptimized: false, runtimeVersion: 0, emissionKind: FullDebug)
@@ -0,0 +1,152 @@
+target datalayout = "e-m:e-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128"
+target triple = "x86_64-pc-linux-gnu"
+
+@counter = global i32 0
+
+; Single return.
+define internal i32 @add(i32 %x, i32 %y) {
+entry:
+	%sum = add i32 %x, %y
+	ret i32 %sum
+}
+
+; Multiple returns, and names shared with the caller.
+define internal i32 @abs(i32 %x) {
+entry:
+	%neg = icmp slt i32 %x, 0
+	br i1 %neg, label %negative, label %positive
+
+negative:
+	%sum = sub i32 0, %x
+	ret i32 %sum
+
+positive:
+	ret i32 %x
+}
+
+; Static alloca.
+define internal i32 @local(i32 %x) {
+entry:
+	%p = alloca i32
+	store i32 %x, i32* %p
+	%v = load i32, i32* %p
+	%inc = add i32 %v, 1
+	ret i32 %inc
+}
+
+define internal void @bump() noinline {
+entry:
+	%v = load i32, i32* @counter
+	%inc = add i32 %v, 1
+	store i32 %inc, i32* @counter
+	ret void
+}
+
+define internal i32 @always(i32 %x) #0 {
+entry:
+	%a = add i32 %x, 1
+	%b = add i32 %a, 1
+	%c = add i32 %b, 1
+	%d = add i32 %c, 1
+	%e = add i32 %d, 1
+	%f = add i32 %e, 1
+	ret i32 %f
+}
+
+define internal i32 @rec(i32 %n) {
+entry:
+	%done = icmp eq i32 %n, 0
+	br i1 %done, label %exit, label %recurse
+
+recurse:
+	%m = sub i32 %n, 1
+	%r = call i32 @rec(i32 %m)
+	%s = add i32 %r, 2
+	ret i32 %s
+
+exit:
+	ret i32 0
+}
+
+define i32 @may_throw(i32 %x) noinline {
+entry:
+	ret i32 %x
+}
+
+define i32 @personality(...) {
+entry:
+	ret i32 0
+}
+
+; Callee with landingpad and resume, inlined at invoke.
+define internal i32 @eh(i32 %x) personality i32 (...)* @personality {
+entry:
+	%a = call i32 @may_throw(i32 %x)
+	%b = invoke i32 @may_throw(i32 %a)
+			to label %ok unwind label %lpad
+
+ok:
+	ret i32 %b
+
+lpad:
+	%lp = landingpad { i8*, i32 }
+			cleanup
+	call void @bump()
+	resume { i8*, i32 } %lp
+}
+
+define i32 @invoker(i32 %x) personality i32 (...)* @personality {
+entry:
+	%r = invoke i32 @eh(i32 %x)
+			to label %cont unwind label %lpad
+
+cont:
+	ret i32 %r
+
+lpad:
+	%p = phi i32 [ 1, %entry ]
+	%lp = landingpad { i8*, i32 }
+			catch i8* null
+	ret i32 %p
+}
+
+define internal i32 @loc(i32 %x) !dbg !6 {
+entry:
+	%y = mul i32 %x, 3, !dbg !9
+	ret i32 %y, !dbg !10
+}
+
+define i32 @main() !dbg !11 {
+entry:
+	%sum = call i32 @add(i32 1, i32 2)
+	%a = call i32 @abs(i32 -5)
+	%l = call i32 @local(i32 %a)
+	call void @bump()
+	%al = call i32 @always(i32 %l)
+	%r = call i32 @rec(i32 3)
+	%e = call i32 @invoker(i32 4)
+	%d = call i32 @loc(i32 %e), !dbg !12
+	%t0 = add i32 %sum, %al
+	%t1 = add i32 %t0, %r
+	%t2 = add i32 %t1, %d
+	%c = load i32, i32* @counter
+	%t3 = add i32 %t2, %c
+	ret i32 %t3
+}
+
+attributes #0 = { alwaysinline }
+
+!llvm.dbg.cu = !{!0}
+!llvm.module.flags = !{!3}
+
+!0 = distinct !DICompileUnit(language: DW_LANG_C99, file: !1, producer: "clang", isOptimized: false, runtimeVersion: 0, emissionKind: FullDebug, enums: !2)
+!1 = !DIFile(filename: "inline.c", directory: "/tmp")
+!2 = !{}
+!3 = !{i32 2, !"Debug Info Version", i32 3}
+!4 = !DISubroutineType(types: !5)
+!5 = !{null}
+!6 = distinct !DISubprogram(name: "loc", scope: !1, file: !1, line: 1, type: !4, scopeLine: 1, spFlags: DISPFlagDefinition, unit: !0, retainedNodes: !2)
+!9 = !DILocation(line: 2, column: 3, scope: !6)
+!10 = !DILocation(line: 3, column: 3, scope: !6)
+!11 = distinct !DISubprogram(name: "main", scope: !1, file: !1, line: 5, type: !4, scopeLine: 5, spFlags: DISPFlagDefinition, unit: !0, retainedNodes: !2)
+!12 = !DILocation(line: 6, column: 7, scope: !11)
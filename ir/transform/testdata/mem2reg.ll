@@ -0,0 +1,184 @@
+declare void @use(i32*)
+
+; Straight-line code; the load before any store yields undef.
+define i32 @straight(i32 %x) {
+entry:
+	%a = alloca i32
+	%u = load i32, i32* %a
+	store i32 %x, i32* %a
+	%v = load i32, i32* %a
+	%sum = add i32 %u, %v
+	ret i32 %sum
+}
+
+; Diamond; a phi instruction is inserted at the join point.
+define i32 @diamond(i1 %cond, i32 %x, i32 %y) {
+entry:
+	%a = alloca i32
+	br i1 %cond, label %then, label %else
+
+then:
+	store i32 %x, i32* %a
+	br label %join
+
+else:
+	store i32 %y, i32* %a
+	br label %join
+
+join:
+	%v = load i32, i32* %a
+	ret i32 %v
+}
+
+; Loop; phi instructions are inserted at the loop header.
+define i32 @loop(i32 %n) {
+entry:
+	%i = alloca i32
+	%sum = alloca i32
+	store i32 0, i32* %i
+	store i32 0, i32* %sum
+	br label %cond
+
+cond:
+	%i.0 = load i32, i32* %i
+	%cmp = icmp slt i32 %i.0, %n
+	br i1 %cmp, label %body, label %exit
+
+body:
+	%i.1 = load i32, i32* %i
+	%sum.0 = load i32, i32* %sum
+	%sum.1 = add i32 %sum.0, %i.1
+	store i32 %sum.1, i32* %sum
+	%i.2 = add i32 %i.1, 1
+	store i32 %i.2, i32* %i
+	br label %cond
+
+exit:
+	%ret = load i32, i32* %sum
+	ret i32 %ret
+}
+
+; Dead store; the value is not live at the join point, so no phi instruction is
+; inserted.
+define void @dead(i1 %cond) {
+entry:
+	%a = alloca i32
+	br i1 %cond, label %then, label %join
+
+then:
+	store i32 1, i32* %a
+	br label %join
+
+join:
+	ret void
+}
+
+; Switch with the same target twice; the phi instruction has an incoming value
+; for each edge.
+define i32 @dup(i32 %x) {
+entry:
+	%a = alloca i32
+	store i32 1, i32* %a
+	switch i32 %x, label %other [
+		i32 0, label %join
+		i32 1, label %join
+	]
+
+other:
+	store i32 2, i32* %a
+	br label %join
+
+join:
+	%v = load i32, i32* %a
+	ret i32 %v
+}
+
+; Unpromotable allocas; escaping address, volatile access, type punning and
+; array allocation.
+define i32 @unpromotable() {
+entry:
+	%escape = alloca i32
+	%volatile = alloca i32
+	%pun = alloca i32
+	%array = alloca i32, i32 2
+	%ok = alloca i32
+	call void @use(i32* %escape)
+	store volatile i32 1, i32* %volatile
+	%p = bitcast i32* %pun to float*
+	store i32 2, i32* %array
+	store i32 3, i32* %ok
+	%v = load i32, i32* %ok
+	br label %dead.succ
+
+dead:
+	store i32 4, i32* %ok
+	%w = load i32, i32* %ok
+	br label %dead.succ
+
+dead.succ:
+	ret i32 %v
+}
+
+; Unnamed local variables are renumbered.
+define i32 @unnamed(i32) {
+	%2 = alloca i32
+	store i32 %0, i32* %2
+	%3 = load i32, i32* %2
+	%4 = add i32 %3, 1
+	ret i32 %4
+}
+
+; Unreachable predecessor; the phi instruction has an undef incoming value for
+; the unreachable predecessor.
+define i32 @unreachable_pred(i1 %cond) {
+entry:
+	%x = alloca i32
+	store i32 1, i32* %x
+	br i1 %cond, label %then, label %join
+
+then:
+	store i32 2, i32* %x
+	br label %join
+
+dead:
+	store i32 3, i32* %x
+	br label %join
+
+join:
+	%v = load i32, i32* %x
+	ret i32 %v
+}
+
+; Debug info; the llvm.dbg.declare intrinsic call is converted to llvm.dbg.value
+; intrinsic calls of the stored values and of the inserted phi instruction.
+define i32 @debug(i1 %cond, i32 %x) !dbg !4 {
+entry:
+	%a = alloca i32
+	call void @llvm.dbg.declare(metadata i32* %a, metadata !7, metadata !DIExpression()), !dbg !9
+	store i32 %x, i32* %a
+	br i1 %cond, label %then, label %join
+
+then:
+	store i32 0, i32* %a
+	br label %join
+
+join:
+	%v = load i32, i32* %a
+	ret i32 %v
+}
+
+declare void @llvm.dbg.declare(metadata, metadata, metadata)
+
+!llvm.dbg.cu = !{!0}
+!llvm.module.flags = !{!3}
+
+!0 = distinct !DICompileUnit(language: DW_LANG_C99, file: !1, producer: "clang", isOptimized: false, runtimeVersion: 0, emissionKind: FullDebug, enums: !2)
+!1 = !DIFile(filename: "foo.c", directory: "/tmp")
+!2 = !{}
+!3 = !{i32 2, !"Debug Info Version", i32 3}
+!4 = distinct !DISubprogram(name: "debug", scope: !1, file: !1, line: 1, type: !5, scopeLine: 1, spFlags: DISPFlagDefinition, unit: !0, retainedNodes: !2)
+!5 = !DISubroutineType(types: !6)
+!6 = !{null}
+!7 = !DILocalVariable(name: "a", scope: !4, file: !1, line: 2, type: !8)
+!8 = !DIBasicType(name: "int", size: 32, encoding: DW_ATE_signed)
+!9 = !DILocation(line: 2, column: 7, scope: !4)
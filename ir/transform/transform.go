@@ -0,0 +1,71 @@
+// Package transform implements transformations of LLVM IR functions and
+// modules, such as promotion of stack allocations to SSA registers.
+package transform
+
+import (
+	"fmt"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/metadata"
+	"github.com/llir/llvm/ir/value"
+)
+
+// ### [ Helper functions ] ####################################################
+
+// materialize materializes the body of the given function, if lazily parsed
+// (see ir.Func.Materialize), and panics on error.
+func materialize(f *ir.Func) {
+	if err := f.Materialize(); err != nil {
+		panic(fmt.Errorf("unable to materialize function %q; %v", f.Ident(), err))
+	}
+}
+
+// unwrap returns the value wrapped by the given operand; e.g. the value of a
+// function argument with parameter attributes.
+func unwrap(v value.Value) value.Value {
+	switch x := v.(type) {
+	case *ir.Arg:
+		return unwrap(x.Value)
+	case *metadata.Value:
+		if y, ok := x.Value.(value.Value); ok {
+			return unwrap(y)
+		}
+	}
+	return v
+}
+
+// replaceOperand replaces the uses of old with new within the operands of the
+// given instruction or terminator, including the values of function arguments
+// and metadata values.
+func replaceOperand(user value.User, old, new value.Value) {
+	for _, op := range user.Operands() {
+		switch x := (*op).(type) {
+		case *ir.Arg:
+			if x.Value == old {
+				x.Value = new
+			}
+		case *metadata.Value:
+			if x.Value == old {
+				x.Value = new
+			}
+		default:
+			if *op == old {
+				*op = new
+			}
+		}
+	}
+}
+
+// removeInsts removes the given instructions from the basic blocks of the
+// given function. Unnamed local variables are renumbered by the next call to
+// f.AssignIDs.
+func removeInsts(f *ir.Func, dead map[ir.Instruction]bool) {
+	if len(dead) == 0 {
+		return
+	}
+	for _, block := range f.Blocks {
+		block.RemoveIf(func(inst ir.Instruction) bool {
+			return dead[inst]
+		})
+	}
+}
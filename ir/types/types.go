@@ -48,6 +48,8 @@ var (
 	I32Ptr  = &PointerType{ElemType: I32}  // i32*
 	I64Ptr  = &PointerType{ElemType: I64}  // i64*
 	I128Ptr = &PointerType{ElemType: I128} // i128*
+	// Opaque pointer type.
+	Ptr = &PointerType{} // ptr
 )
 
 // Convenience functions.
@@ -88,6 +90,14 @@ func IsPointer(t Type) bool {
 	return ok
 }
 
+// IsOpaquePointer reports whether the given type is an opaque pointer type.
+func IsOpaquePointer(t Type) bool {
+	if t, ok := t.(*PointerType); ok {
+		return t.IsOpaque()
+	}
+	return false
+}
+
 // IsVector reports whether the given type is a vector type.
 func IsVector(t Type) bool {
 	_, ok := t.(*VectorType)
@@ -442,19 +452,34 @@ func (t *MMXType) SetName(name string) {
 type PointerType struct {
 	// Type name; or empty if not present.
 	TypeName string
-	// Element type.
+	// Element type; or nil if opaque pointer type.
 	ElemType Type
 	// Address space; or zero value for default address space.
 	AddrSpace AddrSpace
 }
 
-// NewPointer returns a new pointer type based on the given element type.
+// NewPointer returns a new pointer type based on the given element type. A nil
+// element type denotes an opaque pointer type.
 func NewPointer(elemType Type) *PointerType {
 	return &PointerType{
 		ElemType: elemType,
 	}
 }
 
+// NewOpaquePointer returns a new opaque pointer type based on the given address
+// space.
+func NewOpaquePointer(addrSpace AddrSpace) *PointerType {
+	return &PointerType{
+		AddrSpace: addrSpace,
+	}
+}
+
+// IsOpaque reports whether the given pointer type is an opaque pointer type
+// (i.e. without element type).
+func (t *PointerType) IsOpaque() bool {
+	return t.ElemType == nil
+}
+
 // Equal reports whether t and u are of equal type.
 func (t *PointerType) Equal(u Type) bool {
 	// HACK: to prevent infinite loops (e.g. struct foo containing field of type
@@ -473,8 +498,21 @@ func (t *PointerType) String() string {
 // LLString returns the LLVM syntax representation of the definition of the
 // type.
 func (t *PointerType) LLString() string {
-	// Elem=Type AddrSpaceopt '*'
+	// Opaque pointer type.
+	//
+	//	'ptr' AddrSpaceopt
+	//
+	// Typed pointer type.
+	//
+	//	Elem=Type AddrSpaceopt '*'
 	buf := &strings.Builder{}
+	if t.IsOpaque() {
+		buf.WriteString("ptr")
+		if t.AddrSpace != 0 {
+			fmt.Fprintf(buf, " %s", t.AddrSpace)
+		}
+		return buf.String()
+	}
 	buf.WriteString(t.ElemType.String())
 	if t.AddrSpace != 0 {
 		fmt.Fprintf(buf, " %s", t.AddrSpace)
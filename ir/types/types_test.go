@@ -152,6 +152,8 @@ func TestIsPointer(t *testing.T) {
 	}{
 		{t: &PointerType{ElemType: I8}, want: true},
 		{t: NewPointer(I8), want: true},
+		{t: Ptr, want: true},
+		{t: NewOpaquePointer(1), want: true},
 		{t: I8, want: false},
 	}
 	for _, g := range golden {
@@ -162,6 +164,24 @@ func TestIsPointer(t *testing.T) {
 	}
 }
 
+func TestIsOpaquePointer(t *testing.T) {
+	golden := []struct {
+		t    Type
+		want bool
+	}{
+		{t: Ptr, want: true},
+		{t: NewOpaquePointer(1), want: true},
+		{t: NewPointer(I8), want: false},
+		{t: I8, want: false},
+	}
+	for _, g := range golden {
+		got := IsOpaquePointer(g.t)
+		if g.want != got {
+			t.Errorf("check if `%s` is an opaque pointer type mismatch; expected %t, got %t", g.t, g.want, got)
+		}
+	}
+}
+
 func TestIsVector(t *testing.T) {
 	golden := []struct {
 		t    Type
@@ -290,6 +310,9 @@ func TestEqual(t *testing.T) {
 		{t: NewPointer(I8), u: &PointerType{ElemType: I8}, want: true},
 		{t: NewPointer(I8), u: NewPointer(Double), want: false},
 		{t: NewPointer(I8), u: I8, want: false},
+		{t: Ptr, u: &PointerType{}, want: true},
+		{t: Ptr, u: NewOpaquePointer(1), want: false},
+		{t: Ptr, u: NewPointer(I8), want: false},
 		{t: NewVector(5, I8), u: &VectorType{Len: 5, ElemType: I8}, want: true},
 		{t: NewVector(5, I8), u: NewVector(3, I8), want: false},
 		{t: NewVector(5, I8), u: I8, want: false},
@@ -311,6 +334,24 @@ func TestEqual(t *testing.T) {
 	}
 }
 
+func TestPointerTypeLLString(t *testing.T) {
+	golden := []struct {
+		t    *PointerType
+		want string
+	}{
+		{t: NewPointer(I8), want: "i8*"},
+		{t: &PointerType{ElemType: I8, AddrSpace: 1}, want: "i8 addrspace(1)*"},
+		{t: Ptr, want: "ptr"},
+		{t: NewOpaquePointer(1), want: "ptr addrspace(1)"},
+	}
+	for _, g := range golden {
+		got := g.t.LLString()
+		if g.want != got {
+			t.Errorf("pointer type string mismatch; expected %q, got %q", g.want, got)
+		}
+	}
+}
+
 func TestStructTypeEqual(t *testing.T) {
 	// Identified (named) struct types are uniqued by type names, not by
 	// structural identity.
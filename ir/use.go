@@ -0,0 +1,405 @@
+package ir
+
+import (
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/metadata"
+	"github.com/llir/llvm/ir/value"
+)
+
+// === [ Use-def chains ] ======================================================
+
+// Use is a use of a value by a user.
+type Use struct {
+	// Used value; a value.Named or a constant with operands (e.g. a constant
+	// expression).
+	Value value.Value
+	// User of the value.
+	//
+	// User has one of the following underlying types.
+	//
+	//   - [ir.Instruction]
+	//   - [ir.Terminator]
+	//   - [*ir.Global] (initializer)
+	//   - [*ir.Func] (prefix, prologue and personality)
+	//   - [*ir.Alias] (aliasee)
+	//   - [*ir.IFunc] (resolver)
+	//   - [constant.Constant] (constant expressions and complex constants)
+	User interface{}
+
+	// Position of the use in the list of uses of the used value.
+	pos int
+}
+
+// UseIndex is an index of the uses of values (def-use chains) and of the
+// values used by users (use-def chains).
+//
+// Uses are tracked for named values (e.g. global variables, functions,
+// parameters, basic blocks and instructions) and for constants with operands
+// (e.g. constant expressions); uses of simple constants such as integer
+// literals are not tracked. Constants with operands are indexed as users when
+// first used, and removed from the index when no longer used.
+//
+// The index does not observe direct changes to the IR; instead, it is kept
+// consistent by notifying it of each instruction inserted (AddUser), removed
+// (RemoveUser) or modified (UpdateUser). Use indices registered on a function
+// (see Func.SetUseIndex) are notified by the IR modification APIs of the ir
+// package; i.e. Block.InsertBefore, Block.InsertAfter, Block.InsertAt,
+// Block.Remove, Block.MoveTo, the instruction and terminator constructors of Builder,
+// Func.SplitBlock, Func.RemoveBlock and ReplaceAllUsesWith.
+type UseIndex struct {
+	// uses maps from used value to its uses, in order of insertion.
+	uses map[value.Value]*useList
+	// userUses maps from user to the uses of its operands, in operand order.
+	userUses map[interface{}][]*Use
+}
+
+// NewUseIndex returns a new empty use index.
+func NewUseIndex() *UseIndex {
+	return &UseIndex{
+		uses:     make(map[value.Value]*useList),
+		userUses: make(map[interface{}][]*Use),
+	}
+}
+
+// NewModuleUseIndex returns a new use index of the given module.
+func NewModuleUseIndex(m *Module) *UseIndex {
+	idx := NewUseIndex()
+	idx.AddModule(m)
+	return idx
+}
+
+// NewFuncUseIndex returns a new use index of the given function.
+func NewFuncUseIndex(f *Func) *UseIndex {
+	idx := NewUseIndex()
+	idx.AddFunc(f)
+	return idx
+}
+
+// SetUseIndex registers the given use index on the function, so that the index
+// is kept consistent with modifications of the function body made using the
+// IR modification APIs of the ir package (see UseIndex). A nil index
+// unregisters the use index of the function.
+//
+// The index should contain the uses of the function (e.g. as created by
+// NewFuncUseIndex or NewModuleUseIndex). The same index may be registered on
+// several functions of a module.
+func (f *Func) SetUseIndex(idx *UseIndex) {
+	f.useIndex = idx
+}
+
+// Uses returns the uses of the given value, in order of insertion into the
+// index. The returned slice is owned by the index, and is only valid until the
+// index is next modified.
+func (idx *UseIndex) Uses(v value.Value) []*Use {
+	l, ok := idx.uses[v]
+	if !ok {
+		return nil
+	}
+	l.compact()
+	return l.uses
+}
+
+// NumUses returns the number of uses of the given value.
+func (idx *UseIndex) NumUses(v value.Value) int {
+	if l, ok := idx.uses[v]; ok {
+		return l.n
+	}
+	return 0
+}
+
+// Users returns the unique users of the given value, in order of first use.
+func (idx *UseIndex) Users(v value.Value) []interface{} {
+	var users []interface{}
+	seen := make(map[interface{}]bool)
+	for _, use := range idx.Uses(v) {
+		if !seen[use.User] {
+			seen[use.User] = true
+			users = append(users, use.User)
+		}
+	}
+	return users
+}
+
+// Operands returns the uses of values by the given user, in operand order.
+func (idx *UseIndex) Operands(user interface{}) []*Use {
+	return idx.userUses[user]
+}
+
+// AddModule adds the uses of global variables, functions, aliases and IFuncs
+// of the given module to the index.
+func (idx *UseIndex) AddModule(m *Module) {
+	for _, g := range m.Globals {
+		idx.AddUser(g)
+	}
+	for _, f := range m.Funcs {
+		idx.AddFunc(f)
+	}
+	for _, a := range m.Aliases {
+		idx.AddUser(a)
+	}
+	for _, i := range m.IFuncs {
+		idx.AddUser(i)
+	}
+}
+
+// AddFunc adds the uses of the given function, its instructions and
+// terminators to the index.
+func (idx *UseIndex) AddFunc(f *Func) {
+	// Materialize function body if lazily parsed.
+	mustMaterialize(f)
+	idx.AddUser(f)
+	for _, block := range f.Blocks {
+		idx.AddBlock(block)
+	}
+}
+
+// RemoveFunc removes the uses of the given function, its instructions and
+// terminators from the index.
+func (idx *UseIndex) RemoveFunc(f *Func) {
+	for _, block := range f.Blocks {
+		idx.RemoveBlock(block)
+	}
+	idx.RemoveUser(f)
+}
+
+// AddBlock adds the uses of the instructions and terminator of the given basic
+// block to the index.
+func (idx *UseIndex) AddBlock(block *Block) {
+	for _, inst := range block.Insts {
+		idx.AddUser(inst)
+	}
+	if block.Term != nil {
+		idx.AddUser(block.Term)
+	}
+}
+
+// RemoveBlock removes the uses of the instructions and terminator of the given
+// basic block from the index.
+func (idx *UseIndex) RemoveBlock(block *Block) {
+	for _, inst := range block.Insts {
+		idx.RemoveUser(inst)
+	}
+	if block.Term != nil {
+		idx.RemoveUser(block.Term)
+	}
+}
+
+// AddUser adds the uses of the operands of the given user to the index. The
+// user has one of the underlying types listed for Use.User. Users already
+// present in the index are left unchanged.
+//
+// For functions, only the uses of the prefix, prologue and personality are
+// added; use AddFunc to add the uses of the function body.
+func (idx *UseIndex) AddUser(user interface{}) {
+	if _, ok := idx.userUses[user]; ok {
+		return
+	}
+	var uses []*Use
+	for _, op := range userOperands(user) {
+		v := unwrapOperand(op)
+		if !isTracked(v) {
+			continue
+		}
+		if c, ok := v.(constant.Constant); ok && !isNamed(v) {
+			// Index constants with operands as users on first use.
+			idx.AddUser(c)
+		}
+		use := &Use{Value: v, User: user}
+		l, ok := idx.uses[v]
+		if !ok {
+			l = &useList{}
+			idx.uses[v] = l
+		}
+		l.add(use)
+		uses = append(uses, use)
+	}
+	// Record user even if it has no tracked operands, so that later calls to
+	// RemoveUser and UpdateUser are consistent.
+	if uses == nil {
+		uses = []*Use{}
+	}
+	idx.userUses[user] = uses
+}
+
+// RemoveUser removes the uses of the operands of the given user from the index.
+// Constants with operands which are no longer used are removed as well.
+func (idx *UseIndex) RemoveUser(user interface{}) {
+	uses, ok := idx.userUses[user]
+	if !ok {
+		return
+	}
+	delete(idx.userUses, user)
+	idx.dropUses(uses)
+}
+
+// UpdateUser updates the index to reflect the current operands of the given
+// user; e.g. after an operand of an instruction has been modified.
+func (idx *UseIndex) UpdateUser(user interface{}) {
+	// Add the new uses before removing the old ones, to keep constants used by
+	// both the old and the new operands in the index.
+	old := idx.userUses[user]
+	delete(idx.userUses, user)
+	idx.AddUser(user)
+	idx.dropUses(old)
+}
+
+// dropUses removes the given uses from the lists of uses of the used values.
+// Constants with operands which are no longer used are removed from the index.
+func (idx *UseIndex) dropUses(uses []*Use) {
+	for _, use := range uses {
+		l, ok := idx.uses[use.Value]
+		if !ok {
+			continue
+		}
+		l.remove(use)
+		if l.n > 0 {
+			continue
+		}
+		delete(idx.uses, use.Value)
+		if !isNamed(use.Value) {
+			// Unused constant with operands.
+			idx.RemoveUser(use.Value)
+		}
+	}
+}
+
+// ### [ Helper functions ] ####################################################
+
+// userOperands returns the operands of the given user.
+func userOperands(user interface{}) []value.Value {
+	var ops []value.Value
+	addConst := func(cs ...constant.Constant) {
+		for _, c := range cs {
+			if c != nil {
+				ops = append(ops, c)
+			}
+		}
+	}
+	switch user := user.(type) {
+	case value.User:
+		// Instructions and terminators.
+		for _, op := range user.Operands() {
+			if *op != nil {
+				ops = append(ops, *op)
+			}
+		}
+	case *Global:
+		addConst(user.Init)
+	case *Func:
+		addConst(user.Prefix, user.Prologue, user.Personality)
+	case *Alias:
+		addConst(user.Aliasee)
+	case *IFunc:
+		addConst(user.Resolver)
+	case *constant.BlockAddress:
+		addConst(user.Func)
+		if user.Block != nil {
+			ops = append(ops, user.Block)
+		}
+	case constant.Constant:
+		for _, op := range constant.Operands(user) {
+			addConst(*op)
+		}
+	}
+	return ops
+}
+
+// unwrapOperand returns the value wrapped by the given operand; e.g. the value
+// of a function argument with parameter attributes.
+func unwrapOperand(v value.Value) value.Value {
+	for {
+		switch x := v.(type) {
+		case *Arg:
+			v = x.Value
+		case *constant.Index:
+			v = x.Constant
+		case *metadata.Value:
+			x2, ok := x.Value.(value.Value)
+			if !ok {
+				return v
+			}
+			v = x2
+		default:
+			return v
+		}
+	}
+}
+
+// isTracked reports whether the uses of the given value are tracked by the use
+// index.
+func isTracked(v value.Value) bool {
+	if isNamed(v) {
+		return true
+	}
+	switch c := v.(type) {
+	case *constant.BlockAddress:
+		return true
+	case constant.Constant:
+		return len(constant.Operands(c)) > 0
+	default:
+		return false
+	}
+}
+
+// isNamed reports whether the given value is a named value.
+func isNamed(v value.Value) bool {
+	switch v.(type) {
+	case *Global, *Func, *Alias, *IFunc, *Param, *Block, Instruction, Terminator:
+		return true
+	default:
+		return false
+	}
+}
+
+// useList is a list of uses of a value, in order of insertion.
+//
+// Uses are removed in constant time by clearing their position in the list;
+// the list is compacted once more than half of its entries have been cleared
+// (or when the uses are requested), so that removal takes amortized constant
+// time while preserving the order of the remaining uses.
+type useList struct {
+	// Uses of the value; nil entries denote removed uses.
+	uses []*Use
+	// Number of non-nil entries in uses.
+	n int
+}
+
+// add appends the given use to the list of uses.
+func (l *useList) add(use *Use) {
+	use.pos = len(l.uses)
+	l.uses = append(l.uses, use)
+	l.n++
+}
+
+// remove removes the given use from the list of uses.
+func (l *useList) remove(use *Use) {
+	if use.pos >= len(l.uses) || l.uses[use.pos] != use {
+		return
+	}
+	l.uses[use.pos] = nil
+	l.n--
+	if len(l.uses) > 2*l.n {
+		l.compact()
+	}
+}
+
+// compact removes the cleared entries from the list of uses, and updates the
+// positions of the remaining uses.
+func (l *useList) compact() {
+	if len(l.uses) == l.n {
+		return
+	}
+	uses := l.uses[:0]
+	for _, use := range l.uses {
+		if use == nil {
+			continue
+		}
+		use.pos = len(uses)
+		uses = append(uses, use)
+	}
+	// Clear trailing entries to let removed uses be garbage collected.
+	for i := len(uses); i < len(l.uses); i++ {
+		l.uses[i] = nil
+	}
+	l.uses = uses
+}
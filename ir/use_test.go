@@ -0,0 +1,225 @@
+package ir
+
+import (
+	"testing"
+
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+func TestUseIndex(t *testing.T) {
+	// @g = global i32 0
+	// @p = global i32* @g
+	//
+	// define i32 @f(i32 %x) {
+	//    %a = load i32, i32* @g
+	//    %b = add i32 %a, %x
+	//    %c = load i8, i8* bitcast (i32* @g to i8*)
+	//    ret i32 %b
+	// }
+	m := NewModule()
+	g := m.NewGlobalDef("g", constant.NewInt(types.I32, 0))
+	p := m.NewGlobalDef("p", g)
+	x := NewParam("x", types.I32)
+	f := m.NewFunc("f", types.I32, x)
+	entry := f.NewBlock("")
+	a := entry.NewLoad(types.I32, g)
+	b := entry.NewAdd(a, x)
+	expr := constant.NewBitCast(g, types.I8Ptr)
+	c := entry.NewLoad(types.I8, expr)
+	ret := entry.NewRet(b)
+
+	idx := NewModuleUseIndex(m)
+	checkUsers(t, idx, "@g", g, p, a, expr)
+	checkUsers(t, idx, "bitcast", expr, c)
+	checkUsers(t, idx, "%a", a, b)
+	checkUsers(t, idx, "%x", x, b)
+	checkUsers(t, idx, "%b", b, ret)
+	if got := len(idx.Operands(b)); got != 2 {
+		t.Errorf("number of operands of %%b mismatch; expected 2, got %d", got)
+	}
+
+	// Modify operand of %b; replace %a with %x.
+	b.X = x
+	idx.UpdateUser(b)
+	checkUsers(t, idx, "%a", a)
+	checkUsers(t, idx, "%x", x, b)
+	if got := idx.NumUses(x); got != 2 {
+		t.Errorf("number of uses of %%x mismatch; expected 2, got %d", got)
+	}
+
+	// Remove %c; the unused bitcast expression is removed from the index.
+	entry.Insts = entry.Insts[:len(entry.Insts)-1]
+	idx.RemoveUser(c)
+	checkUsers(t, idx, "bitcast", expr)
+	checkUsers(t, idx, "@g", g, p, a)
+
+	// Insert new instruction using %a.
+	d := entry.NewMul(a, a)
+	idx.AddUser(d)
+	checkUsers(t, idx, "%a", a, d)
+	if got := idx.NumUses(a); got != 2 {
+		t.Errorf("number of uses of %%a mismatch; expected 2, got %d", got)
+	}
+
+	// Remove function.
+	idx.RemoveFunc(f)
+	checkUsers(t, idx, "@g", g, p)
+	checkUsers(t, idx, "%x", x)
+}
+
+func TestUseIndexRemoveUser(t *testing.T) {
+	// define i32 @f(i32 %x) {
+	//    %0 = add i32 %x, 0
+	//    ...
+	//    %9 = add i32 %x, 9
+	//    ret i32 %x
+	// }
+	x := NewParam("x", types.I32)
+	f := NewFunc("f", types.I32, x)
+	entry := f.NewBlock("")
+	var adds []interface{}
+	for i := 0; i < 10; i++ {
+		adds = append(adds, entry.NewAdd(x, constant.NewInt(types.I32, int64(i))))
+	}
+	entry.NewRet(x)
+
+	// Remove every other user of %x, in reverse order; the order of the
+	// remaining uses is preserved.
+	idx := NewFuncUseIndex(f)
+	var want []interface{}
+	for i := len(adds) - 1; i >= 0; i-- {
+		if i%2 == 0 {
+			want = append([]interface{}{adds[i]}, want...)
+			continue
+		}
+		idx.RemoveUser(adds[i])
+	}
+	want = append(want, entry.Term)
+	checkUsers(t, idx, "%x", x, want...)
+	if got := idx.NumUses(x); got != len(want) {
+		t.Errorf("number of uses of %%x mismatch; expected %d, got %d", len(want), got)
+	}
+
+	// Remove the remaining users of %x, and add a new one.
+	for _, user := range want {
+		idx.RemoveUser(user)
+	}
+	checkUsers(t, idx, "%x", x)
+	idx.AddUser(adds[0])
+	checkUsers(t, idx, "%x", x, adds[0])
+}
+
+func TestUseIndexRegistered(t *testing.T) {
+	// @g = global i32 0
+	//
+	// define i32 @f(i32 %x) {
+	// entry:
+	//    %a = load i32, i32* @g
+	//    %b = add i32 %a, %x
+	//    br label %exit
+	//
+	// exit:
+	//    %c = phi i32 [ %b, %entry ]
+	//    ret i32 %c
+	// }
+	m := NewModule()
+	g := m.NewGlobalDef("g", constant.NewInt(types.I32, 0))
+	x := NewParam("x", types.I32)
+	f := m.NewFunc("f", types.I32, x)
+	entry := f.NewBlock("entry")
+	exit := f.NewBlock("exit")
+	a := entry.NewLoad(types.I32, g)
+	b := entry.NewAdd(a, x)
+	entry.NewBr(exit)
+	c := exit.NewPhi(NewIncoming(b, entry))
+	exit.NewRet(c)
+
+	idx := NewModuleUseIndex(m)
+	f.SetUseIndex(idx)
+
+	// Insert instruction using %a and %x.
+	d := NewMul(a, x)
+	entry.InsertAfter(d, b)
+	checkUsers(t, idx, "%a", a, b, d)
+	checkUseIndex(t, idx, m)
+
+	// Insert instructions using builder.
+	builder := NewBuilder(exit)
+	e := builder.NewSub(c, d)
+	checkUsers(t, idx, "%d", d, e)
+	checkUseIndex(t, idx, m)
+
+	// Replace all uses of %b with %d.
+	f.ReplaceAllUsesWith(b, d)
+	checkUsers(t, idx, "%b", b)
+	checkUsers(t, idx, "%d", d, e, c)
+	checkUseIndex(t, idx, m)
+
+	// Remove %b.
+	entry.Remove(b)
+	checkUsers(t, idx, "%a", a, d)
+	checkUsers(t, idx, "%x", x, d)
+	checkUseIndex(t, idx, m)
+
+	// Split entry block at %d.
+	split := f.SplitBlock(d)
+	checkUsers(t, idx, "%entry", entry)
+	checkUsers(t, idx, "split", split, c, entry.Term)
+	checkUseIndex(t, idx, m)
+
+	// Replace all uses of @g with a new global variable.
+	h := m.NewGlobalDef("h", constant.NewInt(types.I32, 1))
+	idx.AddUser(h)
+	m.ReplaceAllUsesWith(g, h)
+	checkUsers(t, idx, "@g", g)
+	checkUsers(t, idx, "@h", h, a)
+	checkUseIndex(t, idx, m)
+}
+
+// checkUseIndex checks that the given use index is consistent with a use index
+// created from scratch for the given module.
+func checkUseIndex(t *testing.T, idx *UseIndex, m *Module) {
+	t.Helper()
+	want := NewModuleUseIndex(m)
+	if len(want.userUses) != len(idx.userUses) {
+		t.Errorf("number of users mismatch; expected %d, got %d", len(want.userUses), len(idx.userUses))
+	}
+	for user, wantUses := range want.userUses {
+		gotUses := idx.userUses[user]
+		if len(gotUses) != len(wantUses) {
+			t.Errorf("number of operands of %v mismatch; expected %d, got %d", user, len(wantUses), len(gotUses))
+			continue
+		}
+		for i := range wantUses {
+			if gotUses[i].Value != wantUses[i].Value {
+				t.Errorf("operand %d of %v mismatch; expected %v, got %v", i, user, wantUses[i].Value, gotUses[i].Value)
+			}
+		}
+	}
+	if len(want.uses) != len(idx.uses) {
+		t.Errorf("number of used values mismatch; expected %d, got %d", len(want.uses), len(idx.uses))
+	}
+	for v, wantUses := range want.uses {
+		if got := idx.NumUses(v); got != wantUses.n {
+			t.Errorf("number of uses of %v mismatch; expected %d, got %d", v, wantUses.n, got)
+		}
+	}
+}
+
+// checkUsers checks that the users of v in the use index match the expected
+// users.
+func checkUsers(t *testing.T, idx *UseIndex, name string, v value.Value, want ...interface{}) {
+	t.Helper()
+	got := idx.Users(v)
+	if len(got) != len(want) {
+		t.Errorf("number of users of %s mismatch; expected %d, got %d", name, len(want), len(got))
+		return
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("user %d of %s mismatch; expected %v, got %v", i, name, want[i], got[i])
+		}
+	}
+}
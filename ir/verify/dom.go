@@ -0,0 +1,47 @@
+package verify
+
+import (
+	"github.com/llir/llvm/ir"
+)
+
+// dominatesEdge reports whether the control flow edge from start to end
+// dominates the given basic block; i.e. whether every path from the entry
+// block to the given block passes through the edge.
+func (v *verifier) dominatesEdge(start, end, block *ir.Block) bool {
+	if !v.dom.Dominates(end, block) {
+		return false
+	}
+	// The edge must be the only way into end, apart from back edges dominated
+	// by end.
+	n := 0
+	if start.Term != nil {
+		for _, succ := range succsOf(start.Term) {
+			if succ == end {
+				n++
+			}
+		}
+	}
+	if n != 1 {
+		return false
+	}
+	for _, pred := range v.cfg.Preds(end) {
+		if pred != start && !v.dom.Dominates(end, pred) {
+			return false
+		}
+	}
+	return true
+}
+
+// ### [ Helper functions ] ####################################################
+
+// succsOf returns the successor basic blocks of the given terminator, including
+// duplicates. Contrary to Terminator.Succs, the successors are not cached.
+func succsOf(term ir.Terminator) []*ir.Block {
+	var succs []*ir.Block
+	for _, op := range term.Operands() {
+		if block, ok := (*op).(*ir.Block); ok {
+			succs = append(succs, block)
+		}
+	}
+	return succs
+}
@@ -0,0 +1,374 @@
+package verify
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/cfg"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/dom"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// position is the position of an instruction or terminator within a function.
+type position struct {
+	// Parent basic block.
+	block *ir.Block
+	// Index of the instruction within the parent basic block; terminators have
+	// index len(block.Insts).
+	idx int
+}
+
+// verifyFunc verifies the given function.
+func (v *verifier) verifyFunc(f *ir.Func) {
+	v.f = f
+	defer func() {
+		v.f = nil
+		v.block = nil
+		v.cfg = nil
+		v.dom = nil
+		v.pos = nil
+		v.params = nil
+		v.blocks = nil
+	}()
+	if f.Sig == nil {
+		v.errorf(f, "missing function signature")
+		return
+	}
+	v.verifySig(f)
+	v.verifyFuncAttachments(f)
+	if len(f.Blocks) == 0 {
+		// Function declaration.
+		if !isDeclLinkage(f.Linkage) {
+			v.errorf(f, "function declaration with invalid linkage %q; expected external or extern_weak", f.Linkage)
+		}
+		return
+	}
+	// Function definition.
+	if f.Linkage == enum.LinkageExternWeak {
+		v.errorf(f, "function definition with extern_weak linkage")
+	}
+	v.verifyLocalNames(f)
+	// Index parameters, instructions and terminators.
+	v.params = make(map[*ir.Param]bool)
+	for _, param := range f.Params {
+		v.params[param] = true
+	}
+	v.pos = make(map[interface{}]position)
+	v.blocks = make(map[*ir.Block]bool)
+	for _, block := range f.Blocks {
+		v.block = block
+		if v.blocks[block] {
+			v.errorf(block, "basic block present more than once in function")
+			continue
+		}
+		v.blocks[block] = true
+		if block.Parent != nil && block.Parent != f {
+			v.errorf(block, "parent of basic block is function %s", block.Parent.Ident())
+		}
+		for i, inst := range block.Insts {
+			v.index(inst, block, i)
+		}
+		if block.Term == nil {
+			v.errorf(block, "missing terminator")
+			continue
+		}
+		v.index(block.Term, block, len(block.Insts))
+	}
+	v.block = nil
+	v.cfg = cfg.New(f)
+	v.dom = dom.New(v.cfg)
+	if entry := f.Blocks[0]; len(v.cfg.Preds(entry)) > 0 {
+		v.errorf(entry, "entry block has predecessors")
+	}
+	for _, block := range f.Blocks {
+		v.verifyBlock(block)
+	}
+}
+
+// verifySig verifies the signature and parameters of the given function.
+func (v *verifier) verifySig(f *ir.Func) {
+	sig := f.Sig
+	switch {
+	case sig.RetType == nil:
+		v.errorf(f, "missing return type")
+	case !isValidReturnType(sig.RetType):
+		v.errorf(f, "invalid return type %v", sig.RetType)
+	}
+	for _, param := range sig.Params {
+		if !isValidParamType(param) {
+			v.errorf(f, "invalid parameter type %v", param)
+		}
+	}
+	if f.Typ != nil && !f.Typ.IsOpaque() && !f.Typ.ElemType.Equal(sig) {
+		v.errorf(f, "pointer type %v does not match function signature %v", f.Typ, sig)
+	}
+	if len(f.Params) != len(sig.Params) {
+		v.errorf(f, "number of parameters (%d) does not match function signature (%d)", len(f.Params), len(sig.Params))
+		return
+	}
+	for i, param := range f.Params {
+		if param.Typ == nil || !param.Typ.Equal(sig.Params[i]) {
+			v.errorf(f, "type %v of parameter %s does not match function signature (%v)", param.Typ, param.Ident(), sig.Params[i])
+		}
+	}
+}
+
+// verifyLocalNames verifies that the local identifiers of the given function
+// are unique.
+func (v *verifier) verifyLocalNames(f *ir.Func) {
+	names := make(map[string]bool)
+	check := func(entity interface{}) {
+		named, ok := entity.(interface {
+			value.Named
+			IsUnnamed() bool
+		})
+		if !ok || named.IsUnnamed() {
+			// Local IDs are assigned when printing the function.
+			return
+		}
+		name := named.Name()
+		if names[name] {
+			v.errorf(entity, "local identifier %q already present", named.Ident())
+			return
+		}
+		names[name] = true
+	}
+	for _, param := range f.Params {
+		check(param)
+	}
+	for _, block := range f.Blocks {
+		v.block = block
+		check(block)
+		for _, inst := range block.Insts {
+			check(inst)
+		}
+		if block.Term != nil {
+			check(block.Term)
+		}
+	}
+	v.block = nil
+}
+
+// index records the position of the given instruction or terminator.
+func (v *verifier) index(inst interface{}, block *ir.Block, idx int) {
+	if _, ok := v.pos[inst]; ok {
+		v.errorf(inst, "instruction present more than once in function")
+		return
+	}
+	v.pos[inst] = position{block: block, idx: idx}
+}
+
+// verifyBlock verifies the given basic block.
+func (v *verifier) verifyBlock(block *ir.Block) {
+	v.block = block
+	defer func() {
+		v.block = nil
+	}()
+	nonPhi := false
+	for i, inst := range block.Insts {
+		if inst == nil {
+			v.errorf(nil, "missing instruction at index %d", i)
+			continue
+		}
+		switch inst := inst.(type) {
+		case *ir.InstPhi:
+			if nonPhi {
+				v.errorf(inst, "phi instruction not grouped at top of basic block")
+			}
+			v.verifyPhi(inst)
+			continue
+		case *ir.InstLandingPad, *ir.InstCatchPad, *ir.InstCleanupPad:
+			if nonPhi {
+				v.errorf(inst, "exception handling pad must be the first non-phi instruction of basic block")
+			}
+		}
+		nonPhi = true
+		v.verifyInst(inst)
+		v.verifyOperands(inst, i)
+		v.verifyInstAttachments(inst)
+	}
+	if block.Term == nil {
+		return
+	}
+	v.verifyTerm(block.Term)
+	v.verifyOperands(block.Term, len(block.Insts))
+	v.verifyInstAttachments(block.Term)
+}
+
+// verifyPhi verifies the given phi instruction, including the consistency of
+// its incoming values with the predecessors of its parent basic block.
+func (v *verifier) verifyPhi(inst *ir.InstPhi) {
+	typ := inst.Typ
+	if typ == nil && len(inst.Incs) > 0 {
+		typ = typeOf(inst.Incs[0].X)
+	}
+	if typ != nil && !isFirstClass(typ) {
+		v.errorf(inst, "invalid phi type %v; expected first class type", typ)
+	}
+	preds := v.cfg.Preds(v.block)
+	isPred := make(map[*ir.Block]bool)
+	for _, pred := range preds {
+		isPred[pred] = true
+	}
+	incs := make(map[*ir.Block]value.Value)
+	for _, inc := range inst.Incs {
+		if inc == nil || inc.X == nil || inc.Pred == nil {
+			v.errorf(inst, "missing incoming value or predecessor")
+			continue
+		}
+		pred, ok := inc.Pred.(*ir.Block)
+		if !ok {
+			v.errorf(inst, "invalid incoming predecessor %v; expected *ir.Block, got %T", inc.Pred.Ident(), inc.Pred)
+			continue
+		}
+		if t := typeOf(inc.X); typ != nil && t != nil && !t.Equal(typ) {
+			v.errorf(inst, "type %v of incoming value %s does not match phi type %v", t, inc.X.Ident(), typ)
+		}
+		if !isPred[pred] {
+			v.errorf(inst, "incoming block %s is not a predecessor of basic block", pred.Ident())
+			continue
+		}
+		if prev, ok := incs[pred]; ok {
+			if !sameValue(prev, inc.X) {
+				v.errorf(inst, "different incoming values for the same predecessor %s", pred.Ident())
+			}
+			continue
+		}
+		incs[pred] = inc.X
+		// The incoming value must be available at the end of the predecessor.
+		v.verifyUse(inst, inc.X, pred, len(pred.Insts)+1, v.block)
+	}
+	for _, pred := range preds {
+		if _, ok := incs[pred]; !ok {
+			v.errorf(inst, "missing incoming value for predecessor %s", pred.Ident())
+		}
+	}
+}
+
+// verifyOperands verifies that the operands of the given instruction or
+// terminator at the specified index of the current basic block are valid and
+// dominate the use.
+func (v *verifier) verifyOperands(user value.User, idx int) {
+	for _, op := range user.Operands() {
+		if *op == nil {
+			v.errorf(user, "missing operand")
+			continue
+		}
+		if block, ok := (*op).(*ir.Block); ok {
+			if _, isTerm := user.(ir.Terminator); !isTerm {
+				v.errorf(user, "invalid basic block operand %s", block.Ident())
+				continue
+			}
+		}
+		v.verifyUse(user, *op, v.block, idx, nil)
+	}
+}
+
+// verifyUse verifies the use of x by the given user at the specified index of
+// the given basic block. For phi instructions, phiBlock is the parent basic
+// block of the phi instruction, and the use is located at the end of the
+// incoming basic block.
+func (v *verifier) verifyUse(user interface{}, x value.Value, block *ir.Block, idx int, phiBlock *ir.Block) {
+	switch x := x.(type) {
+	case *ir.Param:
+		if !v.params[x] {
+			v.errorf(user, "parameter %s not part of function", x.Ident())
+		}
+		return
+	case *ir.Block:
+		if !v.blocks[x] {
+			v.errorf(user, "basic block %s not part of function", x.Ident())
+		}
+		return
+	case *ir.Global, *ir.Func, *ir.Alias, *ir.IFunc:
+		if v.m != nil && !v.globals[x] {
+			v.errorf(user, "global value %s not part of module", x.Ident())
+		}
+		return
+	}
+	if !isLocalDef(x) {
+		// Constants, inline assembly and metadata.
+		return
+	}
+	def, ok := v.pos[x]
+	if !ok {
+		v.errorf(user, "operand %s not defined in function", x.Ident())
+		return
+	}
+	if user == x && phiBlock == nil && v.dom.Reachable(block) {
+		v.errorf(user, "only phi instructions may reference their own value")
+		return
+	}
+	var normal *ir.Block
+	switch x := x.(type) {
+	case *ir.TermInvoke:
+		normal, _ = x.NormalRetTarget.(*ir.Block)
+	case *ir.TermCallBr:
+		normal, _ = x.NormalRetTarget.(*ir.Block)
+	}
+	var valid bool
+	switch {
+	case normal != nil:
+		// The result of invoke and callbr is only available on the normal
+		// return edge.
+		if phiBlock != nil && def.block == block {
+			valid = phiBlock == normal
+		} else {
+			valid = !v.dom.Reachable(block) || v.dominatesEdge(def.block, normal, block)
+		}
+	case def.block == block:
+		valid = def.idx < idx || !v.dom.Reachable(block)
+	default:
+		valid = v.dom.Dominates(def.block, block)
+	}
+	if !valid {
+		v.errorf(user, "operand %s does not dominate use", x.Ident())
+	}
+}
+
+// ### [ Helper functions ] ####################################################
+
+// isLocalDef reports whether the given value is defined by an instruction or
+// terminator.
+func isLocalDef(x value.Value) bool {
+	switch x.(type) {
+	case ir.Instruction, *ir.TermInvoke, *ir.TermCallBr, *ir.TermCatchSwitch:
+		return true
+	default:
+		return false
+	}
+}
+
+// sameValue reports whether the given values are identical; constants are
+// compared by value.
+func sameValue(x, y value.Value) bool {
+	if x == y {
+		return true
+	}
+	_, ok1 := x.(constant.Constant)
+	_, ok2 := y.(constant.Constant)
+	return ok1 && ok2 && x.String() == y.String()
+}
+
+// isValidReturnType reports whether the given type is a valid function return
+// type.
+func isValidReturnType(t types.Type) bool {
+	switch t.(type) {
+	case *types.FuncType, *types.LabelType, *types.MetadataType:
+		return false
+	default:
+		return true
+	}
+}
+
+// isValidParamType reports whether the given type is a valid function
+// parameter type.
+func isValidParamType(t types.Type) bool {
+	switch t.(type) {
+	case nil, *types.VoidType, *types.FuncType, *types.LabelType:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,650 @@
+package verify
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// verifyInst verifies the operand types of the given non-phi instruction.
+func (v *verifier) verifyInst(inst ir.Instruction) {
+	switch inst := inst.(type) {
+	// Unary instructions.
+	case *ir.InstFNeg:
+		v.verifyFloatOp(inst, inst.X)
+	// Binary instructions.
+	case *ir.InstAdd:
+		v.verifyIntBinOp(inst, inst.X, inst.Y)
+	case *ir.InstFAdd:
+		v.verifyFloatBinOp(inst, inst.X, inst.Y)
+	case *ir.InstSub:
+		v.verifyIntBinOp(inst, inst.X, inst.Y)
+	case *ir.InstFSub:
+		v.verifyFloatBinOp(inst, inst.X, inst.Y)
+	case *ir.InstMul:
+		v.verifyIntBinOp(inst, inst.X, inst.Y)
+	case *ir.InstFMul:
+		v.verifyFloatBinOp(inst, inst.X, inst.Y)
+	case *ir.InstUDiv:
+		v.verifyIntBinOp(inst, inst.X, inst.Y)
+	case *ir.InstSDiv:
+		v.verifyIntBinOp(inst, inst.X, inst.Y)
+	case *ir.InstFDiv:
+		v.verifyFloatBinOp(inst, inst.X, inst.Y)
+	case *ir.InstURem:
+		v.verifyIntBinOp(inst, inst.X, inst.Y)
+	case *ir.InstSRem:
+		v.verifyIntBinOp(inst, inst.X, inst.Y)
+	case *ir.InstFRem:
+		v.verifyFloatBinOp(inst, inst.X, inst.Y)
+	// Bitwise instructions.
+	case *ir.InstShl:
+		v.verifyIntBinOp(inst, inst.X, inst.Y)
+	case *ir.InstLShr:
+		v.verifyIntBinOp(inst, inst.X, inst.Y)
+	case *ir.InstAShr:
+		v.verifyIntBinOp(inst, inst.X, inst.Y)
+	case *ir.InstAnd:
+		v.verifyIntBinOp(inst, inst.X, inst.Y)
+	case *ir.InstOr:
+		v.verifyIntBinOp(inst, inst.X, inst.Y)
+	case *ir.InstXor:
+		v.verifyIntBinOp(inst, inst.X, inst.Y)
+	// Vector instructions.
+	case *ir.InstExtractElement:
+		v.verifyExtractElement(inst)
+	case *ir.InstInsertElement:
+		v.verifyInsertElement(inst)
+	case *ir.InstShuffleVector:
+		v.verifyShuffleVector(inst)
+	// Aggregate instructions.
+	case *ir.InstExtractValue:
+		v.indexedType(inst, typeOf(inst.X), inst.Indices)
+	case *ir.InstInsertValue:
+		t, ok := v.indexedType(inst, typeOf(inst.X), inst.Indices)
+		if !ok {
+			return
+		}
+		if elemType := typeOf(inst.Elem); t != nil && elemType != nil && !elemType.Equal(t) {
+			v.errorf(inst, "inserted value type %v does not match indexed type %v", elemType, t)
+		}
+	// Memory instructions.
+	case *ir.InstAlloca:
+		v.verifyAlloca(inst)
+	case *ir.InstLoad:
+		v.verifyLoad(inst)
+	case *ir.InstStore:
+		v.verifyStore(inst)
+	case *ir.InstFence:
+		switch inst.Ordering {
+		case enum.AtomicOrderingAcquire, enum.AtomicOrderingRelease, enum.AtomicOrderingAcquireRelease, enum.AtomicOrderingSequentiallyConsistent:
+			// valid ordering.
+		default:
+			v.errorf(inst, "invalid fence ordering %q; expected acquire, release, acq_rel or seq_cst", inst.Ordering)
+		}
+	case *ir.InstCmpXchg:
+		v.verifyCmpXchg(inst)
+	case *ir.InstAtomicRMW:
+		v.verifyAtomicRMW(inst)
+	case *ir.InstGetElementPtr:
+		v.verifyGetElementPtr(inst)
+	// Conversion instructions.
+	case *ir.InstTrunc:
+		v.verifyIntCast(inst, inst.From, inst.To, false)
+	case *ir.InstZExt:
+		v.verifyIntCast(inst, inst.From, inst.To, true)
+	case *ir.InstSExt:
+		v.verifyIntCast(inst, inst.From, inst.To, true)
+	case *ir.InstFPTrunc:
+		v.verifyFloatCast(inst, inst.From, inst.To, false)
+	case *ir.InstFPExt:
+		v.verifyFloatCast(inst, inst.From, inst.To, true)
+	case *ir.InstFPToUI:
+		v.verifyCast(inst, inst.From, inst.To, isFloatOrFloatVector, isIntOrIntVector)
+	case *ir.InstFPToSI:
+		v.verifyCast(inst, inst.From, inst.To, isFloatOrFloatVector, isIntOrIntVector)
+	case *ir.InstUIToFP:
+		v.verifyCast(inst, inst.From, inst.To, isIntOrIntVector, isFloatOrFloatVector)
+	case *ir.InstSIToFP:
+		v.verifyCast(inst, inst.From, inst.To, isIntOrIntVector, isFloatOrFloatVector)
+	case *ir.InstPtrToInt:
+		v.verifyCast(inst, inst.From, inst.To, isPtrOrPtrVector, isIntOrIntVector)
+	case *ir.InstIntToPtr:
+		v.verifyCast(inst, inst.From, inst.To, isIntOrIntVector, isPtrOrPtrVector)
+	case *ir.InstBitCast:
+		v.verifyBitCast(inst)
+	case *ir.InstAddrSpaceCast:
+		if v.verifyCast(inst, inst.From, inst.To, isPtrOrPtrVector, isPtrOrPtrVector) {
+			if addrSpaceOf(typeOf(inst.From)) == addrSpaceOf(inst.To) {
+				v.errorf(inst, "addrspacecast between pointers of the same address space")
+			}
+		}
+	// Other instructions.
+	case *ir.InstICmp:
+		x, ok := v.sameTypeOperands(inst, inst.X, inst.Y)
+		if ok && !isIntOrIntVector(x) && !isPtrOrPtrVector(x) {
+			v.errorf(inst, "invalid operand type %v; expected integer or pointer type", x)
+		}
+	case *ir.InstFCmp:
+		x, ok := v.sameTypeOperands(inst, inst.X, inst.Y)
+		if ok && !isFloatOrFloatVector(x) {
+			v.errorf(inst, "invalid operand type %v; expected floating-point type", x)
+		}
+	case *ir.InstSelect:
+		v.verifySelect(inst)
+	case *ir.InstFreeze:
+		// Any operand type is valid.
+	case *ir.InstCall:
+		v.verifyCall(inst, inst.Callee, inst.CalleeSig, inst.Typ, inst.Args)
+	case *ir.InstVAArg:
+		if t := typeOf(inst.ArgList); t != nil && !types.IsPointer(t) {
+			v.errorf(inst, "invalid va_list type %v; expected pointer type", t)
+		}
+	case *ir.InstLandingPad:
+		v.verifyLandingPad(inst)
+	case *ir.InstCatchPad:
+		v.verifyPersonality(inst, "catchpad")
+		if _, ok := inst.CatchSwitch.(*ir.TermCatchSwitch); !ok {
+			v.errorf(inst, "invalid catchswitch operand; expected *ir.TermCatchSwitch, got %T", inst.CatchSwitch)
+		}
+	case *ir.InstCleanupPad:
+		v.verifyPersonality(inst, "cleanuppad")
+		v.verifyParentPad(inst, inst.ParentPad)
+	case *ir.InstPhi:
+		// Phi instructions are verified by verifyPhi.
+	default:
+		v.errorf(inst, "support for instruction %T not yet implemented", inst)
+	}
+}
+
+// --- [ Unary and binary instructions ] ---------------------------------------
+
+// verifyFloatOp verifies the operand of the given floating-point unary
+// instruction.
+func (v *verifier) verifyFloatOp(inst ir.Instruction, x value.Value) {
+	if t := typeOf(x); t != nil && !isFloatOrFloatVector(t) {
+		v.errorf(inst, "invalid operand type %v; expected floating-point type", t)
+	}
+}
+
+// verifyIntBinOp verifies the operands of the given integer binary
+// instruction.
+func (v *verifier) verifyIntBinOp(inst ir.Instruction, x, y value.Value) {
+	if t, ok := v.sameTypeOperands(inst, x, y); ok && !isIntOrIntVector(t) {
+		v.errorf(inst, "invalid operand type %v; expected integer type", t)
+	}
+}
+
+// verifyFloatBinOp verifies the operands of the given floating-point binary
+// instruction.
+func (v *verifier) verifyFloatBinOp(inst ir.Instruction, x, y value.Value) {
+	if t, ok := v.sameTypeOperands(inst, x, y); ok && !isFloatOrFloatVector(t) {
+		v.errorf(inst, "invalid operand type %v; expected floating-point type", t)
+	}
+}
+
+// sameTypeOperands verifies that the given operands have the same type, and
+// returns their type. The boolean return value indicates success.
+func (v *verifier) sameTypeOperands(inst ir.Instruction, x, y value.Value) (types.Type, bool) {
+	xt, yt := typeOf(x), typeOf(y)
+	if xt == nil || yt == nil {
+		// Missing operands are reported by verifyOperands.
+		return nil, false
+	}
+	if !xt.Equal(yt) {
+		v.errorf(inst, "operand type mismatch; %v and %v", xt, yt)
+		return nil, false
+	}
+	return xt, true
+}
+
+// --- [ Vector instructions ] -------------------------------------------------
+
+// verifyExtractElement verifies the given extractelement instruction.
+func (v *verifier) verifyExtractElement(inst *ir.InstExtractElement) {
+	if t := typeOf(inst.X); t != nil && !types.IsVector(t) {
+		v.errorf(inst, "invalid vector operand type %v; expected vector type", t)
+	}
+	if t := typeOf(inst.Index); t != nil && !types.IsInt(t) {
+		v.errorf(inst, "invalid index type %v; expected integer type", t)
+	}
+}
+
+// verifyInsertElement verifies the given insertelement instruction.
+func (v *verifier) verifyInsertElement(inst *ir.InstInsertElement) {
+	if t := typeOf(inst.X); t != nil {
+		vt, ok := t.(*types.VectorType)
+		if !ok {
+			v.errorf(inst, "invalid vector operand type %v; expected vector type", t)
+		} else if et := typeOf(inst.Elem); et != nil && !et.Equal(vt.ElemType) {
+			v.errorf(inst, "inserted element type %v does not match vector element type %v", et, vt.ElemType)
+		}
+	}
+	if t := typeOf(inst.Index); t != nil && !types.IsInt(t) {
+		v.errorf(inst, "invalid index type %v; expected integer type", t)
+	}
+}
+
+// verifyShuffleVector verifies the given shufflevector instruction.
+func (v *verifier) verifyShuffleVector(inst *ir.InstShuffleVector) {
+	if t, ok := v.sameTypeOperands(inst, inst.X, inst.Y); ok && !types.IsVector(t) {
+		v.errorf(inst, "invalid vector operand type %v; expected vector type", t)
+	}
+	if t := typeOf(inst.Mask); t != nil {
+		if !types.IsVector(t) || !types.Equal(scalarType(t), types.I32) {
+			v.errorf(inst, "invalid mask type %v; expected vector of i32", t)
+		}
+	}
+}
+
+// --- [ Aggregate instructions ] ----------------------------------------------
+
+// indexedType returns the type indexed by the given indices into the aggregate
+// type t. The boolean return value indicates success; the returned type is nil
+// if t is unknown.
+func (v *verifier) indexedType(inst ir.Instruction, t types.Type, indices []uint64) (types.Type, bool) {
+	if t == nil {
+		return nil, true
+	}
+	if len(indices) == 0 {
+		v.errorf(inst, "missing aggregate indices")
+		return nil, false
+	}
+	for _, index := range indices {
+		switch tt := t.(type) {
+		case *types.StructType:
+			if index >= uint64(len(tt.Fields)) {
+				v.errorf(inst, "index %d out of bounds of %v", index, t)
+				return nil, false
+			}
+			t = tt.Fields[index]
+		case *types.ArrayType:
+			if index >= tt.Len {
+				v.errorf(inst, "index %d out of bounds of %v", index, t)
+				return nil, false
+			}
+			t = tt.ElemType
+		default:
+			v.errorf(inst, "invalid aggregate type %v; expected struct or array type", t)
+			return nil, false
+		}
+	}
+	return t, true
+}
+
+// --- [ Memory instructions ] -------------------------------------------------
+
+// verifyAlloca verifies the given alloca instruction.
+func (v *verifier) verifyAlloca(inst *ir.InstAlloca) {
+	if inst.ElemType == nil {
+		v.errorf(inst, "missing element type")
+	} else if !isSized(inst.ElemType) {
+		v.errorf(inst, "invalid element type %v; expected sized type", inst.ElemType)
+	}
+	if inst.NElems != nil {
+		if t := typeOf(inst.NElems); t != nil && !types.IsInt(t) {
+			v.errorf(inst, "invalid number of elements type %v; expected integer type", t)
+		}
+	}
+}
+
+// verifyLoad verifies the given load instruction.
+func (v *verifier) verifyLoad(inst *ir.InstLoad) {
+	if inst.ElemType == nil {
+		v.errorf(inst, "missing element type")
+		return
+	}
+	if !isSized(inst.ElemType) {
+		v.errorf(inst, "invalid element type %v; expected sized type", inst.ElemType)
+	}
+	v.verifyPointee(inst, inst.Src, inst.ElemType)
+	if inst.Atomic {
+		switch inst.Ordering {
+		case enum.AtomicOrderingRelease, enum.AtomicOrderingAcquireRelease:
+			v.errorf(inst, "invalid atomic load ordering %q", inst.Ordering)
+		}
+		if inst.Align == 0 {
+			v.errorf(inst, "atomic load must have explicit alignment")
+		}
+	}
+}
+
+// verifyStore verifies the given store instruction.
+func (v *verifier) verifyStore(inst *ir.InstStore) {
+	if t := typeOf(inst.Src); t != nil {
+		if !isSized(t) {
+			v.errorf(inst, "invalid stored value type %v; expected sized type", t)
+		}
+		v.verifyPointee(inst, inst.Dst, t)
+	}
+	if inst.Atomic {
+		switch inst.Ordering {
+		case enum.AtomicOrderingAcquire, enum.AtomicOrderingAcquireRelease:
+			v.errorf(inst, "invalid atomic store ordering %q", inst.Ordering)
+		}
+		if inst.Align == 0 {
+			v.errorf(inst, "atomic store must have explicit alignment")
+		}
+	}
+}
+
+// verifyPointee verifies that ptr is a pointer which may point to a value of
+// the given type.
+func (v *verifier) verifyPointee(inst ir.Instruction, ptr value.Value, elemType types.Type) {
+	t := typeOf(ptr)
+	if t == nil {
+		return
+	}
+	pt, ok := t.(*types.PointerType)
+	if !ok {
+		v.errorf(inst, "invalid pointer operand type %v; expected pointer type", t)
+		return
+	}
+	if !pt.IsOpaque() && !pt.ElemType.Equal(elemType) {
+		v.errorf(inst, "pointer operand type %v does not match element type %v", t, elemType)
+	}
+}
+
+// verifyCmpXchg verifies the given cmpxchg instruction.
+func (v *verifier) verifyCmpXchg(inst *ir.InstCmpXchg) {
+	if t, ok := v.sameTypeOperands(inst, inst.Cmp, inst.New); ok {
+		if !types.IsInt(t) && !types.IsPointer(t) {
+			v.errorf(inst, "invalid operand type %v; expected integer or pointer type", t)
+		}
+		v.verifyPointee(inst, inst.Ptr, t)
+	}
+	if inst.SuccessOrdering < enum.AtomicOrderingMonotonic {
+		v.errorf(inst, "invalid success ordering %q; expected at least monotonic", inst.SuccessOrdering)
+	}
+	switch inst.FailureOrdering {
+	case enum.AtomicOrderingMonotonic, enum.AtomicOrderingAcquire, enum.AtomicOrderingSequentiallyConsistent:
+		// valid ordering.
+	default:
+		v.errorf(inst, "invalid failure ordering %q", inst.FailureOrdering)
+	}
+}
+
+// verifyAtomicRMW verifies the given atomicrmw instruction.
+func (v *verifier) verifyAtomicRMW(inst *ir.InstAtomicRMW) {
+	t := typeOf(inst.X)
+	if t == nil {
+		return
+	}
+	switch inst.Op {
+	case enum.AtomicOpXChg:
+		if !types.IsInt(t) && !types.IsFloat(t) && !types.IsPointer(t) {
+			v.errorf(inst, "invalid operand type %v; expected integer, floating-point or pointer type", t)
+		}
+	case enum.AtomicOpFAdd, enum.AtomicOpFSub, enum.AtomicOpFMax, enum.AtomicOpFMin:
+		if !types.IsFloat(t) {
+			v.errorf(inst, "invalid operand type %v; expected floating-point type", t)
+		}
+	default:
+		if !types.IsInt(t) {
+			v.errorf(inst, "invalid operand type %v; expected integer type", t)
+		}
+	}
+	v.verifyPointee(inst, inst.Dst, t)
+	if inst.Ordering < enum.AtomicOrderingMonotonic {
+		v.errorf(inst, "invalid ordering %q; expected at least monotonic", inst.Ordering)
+	}
+}
+
+// verifyGetElementPtr verifies the given getelementptr instruction.
+func (v *verifier) verifyGetElementPtr(inst *ir.InstGetElementPtr) {
+	if inst.ElemType == nil {
+		v.errorf(inst, "missing element type")
+		return
+	}
+	if t := typeOf(inst.Src); t != nil {
+		if !isPtrOrPtrVector(t) {
+			v.errorf(inst, "invalid source type %v; expected pointer type", t)
+		} else if pt := scalarType(t).(*types.PointerType); !pt.IsOpaque() && !pt.ElemType.Equal(inst.ElemType) {
+			v.errorf(inst, "source type %v does not match element type %v", t, inst.ElemType)
+		}
+	}
+	if len(inst.Indices) > 1 && !isSized(inst.ElemType) {
+		v.errorf(inst, "invalid element type %v; expected sized type", inst.ElemType)
+	}
+	t := inst.ElemType
+	for i, index := range inst.Indices {
+		it := typeOf(index)
+		if it != nil && !isIntOrIntVector(it) {
+			v.errorf(inst, "invalid index type %v; expected integer type", it)
+			return
+		}
+		if i == 0 {
+			// The first index steps through the source pointer.
+			continue
+		}
+		switch tt := t.(type) {
+		case *types.StructType:
+			c, ok := index.(*constant.Int)
+			if !ok {
+				v.errorf(inst, "invalid struct index %s; expected integer constant", index.Ident())
+				return
+			}
+			if !c.X.IsUint64() || c.X.Uint64() >= uint64(len(tt.Fields)) {
+				v.errorf(inst, "struct index %s out of bounds of %v", c.X, t)
+				return
+			}
+			t = tt.Fields[c.X.Uint64()]
+		case *types.ArrayType:
+			t = tt.ElemType
+		case *types.VectorType:
+			t = tt.ElemType
+		default:
+			v.errorf(inst, "invalid index into non-aggregate type %v", t)
+			return
+		}
+	}
+}
+
+// --- [ Conversion instructions ] ---------------------------------------------
+
+// verifyCast verifies that the source value and target type of the given
+// conversion instruction satisfy from and to respectively, and are of the
+// same shape. The boolean return value indicates success.
+func (v *verifier) verifyCast(inst ir.Instruction, x value.Value, to types.Type, from, toOK func(types.Type) bool) bool {
+	t := typeOf(x)
+	if t == nil {
+		return false
+	}
+	if to == nil {
+		v.errorf(inst, "missing target type")
+		return false
+	}
+	switch {
+	case !from(t):
+		v.errorf(inst, "invalid source type %v", t)
+	case !toOK(to):
+		v.errorf(inst, "invalid target type %v", to)
+	case !sameShape(t, to):
+		v.errorf(inst, "source type %v and target type %v differ in shape", t, to)
+	default:
+		return true
+	}
+	return false
+}
+
+// verifyIntCast verifies the given trunc (grow = false), zext or sext (grow =
+// true) instruction.
+func (v *verifier) verifyIntCast(inst ir.Instruction, x value.Value, to types.Type, grow bool) {
+	if !v.verifyCast(inst, x, to, isIntOrIntVector, isIntOrIntVector) {
+		return
+	}
+	v.verifySizeChange(inst, typeOf(x), to, grow)
+}
+
+// verifyFloatCast verifies the given fptrunc (grow = false) or fpext (grow =
+// true) instruction.
+func (v *verifier) verifyFloatCast(inst ir.Instruction, x value.Value, to types.Type, grow bool) {
+	if !v.verifyCast(inst, x, to, isFloatOrFloatVector, isFloatOrFloatVector) {
+		return
+	}
+	v.verifySizeChange(inst, typeOf(x), to, grow)
+}
+
+// verifySizeChange verifies that the scalar size of the target type is larger
+// (grow = true) or smaller (grow = false) than the scalar size of the source
+// type.
+func (v *verifier) verifySizeChange(inst ir.Instruction, from, to types.Type, grow bool) {
+	fromSize, toSize := bitSize(scalarType(from)), bitSize(scalarType(to))
+	switch {
+	case grow && toSize <= fromSize:
+		v.errorf(inst, "target type %v must be larger than source type %v", to, from)
+	case !grow && toSize >= fromSize:
+		v.errorf(inst, "target type %v must be smaller than source type %v", to, from)
+	}
+}
+
+// verifyBitCast verifies the given bitcast instruction.
+func (v *verifier) verifyBitCast(inst *ir.InstBitCast) {
+	from := typeOf(inst.From)
+	if from == nil {
+		return
+	}
+	to := inst.To
+	if to == nil {
+		v.errorf(inst, "missing target type")
+		return
+	}
+	fromPtr, toPtr := isPtrOrPtrVector(from), isPtrOrPtrVector(to)
+	switch {
+	case fromPtr != toPtr:
+		v.errorf(inst, "invalid bitcast between pointer and non-pointer types %v and %v", from, to)
+	case fromPtr:
+		if !sameShape(from, to) {
+			v.errorf(inst, "source type %v and target type %v differ in shape", from, to)
+		} else if addrSpaceOf(from) != addrSpaceOf(to) {
+			v.errorf(inst, "invalid bitcast between address spaces; use addrspacecast")
+		}
+	default:
+		fromSize, toSize := bitSize(from), bitSize(to)
+		if fromSize == 0 || toSize == 0 {
+			v.errorf(inst, "invalid bitcast between types %v and %v; expected primitive types", from, to)
+		} else if fromSize != toSize {
+			v.errorf(inst, "invalid bitcast between types of different size; %v and %v", from, to)
+		}
+	}
+}
+
+// --- [ Other instructions ] --------------------------------------------------
+
+// verifySelect verifies the given select instruction.
+func (v *verifier) verifySelect(inst *ir.InstSelect) {
+	t, ok := v.sameTypeOperands(inst, inst.ValueTrue, inst.ValueFalse)
+	ct := typeOf(inst.Cond)
+	if ct == nil {
+		return
+	}
+	if !isBoolOrBoolVector(ct) {
+		v.errorf(inst, "invalid condition type %v; expected i1 or vector of i1", ct)
+		return
+	}
+	if ok && types.IsVector(ct) && !sameShape(ct, t) {
+		v.errorf(inst, "condition type %v and value type %v differ in shape", ct, t)
+	}
+}
+
+// verifyCall verifies the callee and arguments of the given call, invoke or
+// callbr instruction, where calleeSig is the explicit callee signature (if
+// any) and typ is the result type (if known).
+func (v *verifier) verifyCall(user interface{}, callee value.Value, calleeSig *types.FuncType, typ types.Type, args []value.Value) {
+	ct := typeOf(callee)
+	if ct == nil {
+		return
+	}
+	pt, ok := ct.(*types.PointerType)
+	if !ok {
+		v.errorf(user, "invalid callee type %v; expected pointer type", ct)
+		return
+	}
+	sig := calleeSig
+	if !pt.IsOpaque() {
+		fsig, ok := pt.ElemType.(*types.FuncType)
+		if !ok {
+			v.errorf(user, "invalid callee type %v; expected pointer to function type", ct)
+			return
+		}
+		if sig != nil && !sig.Equal(fsig) {
+			v.errorf(user, "callee signature %v does not match callee type %v", sig, ct)
+		}
+		sig = fsig
+	}
+	if sig == nil {
+		if f, ok := callee.(*ir.Func); ok {
+			sig = f.Sig
+		}
+	}
+	if sig == nil {
+		// Signature derived from arguments and result type.
+		return
+	}
+	if len(args) < len(sig.Params) || (!sig.Variadic && len(args) != len(sig.Params)) {
+		v.errorf(user, "incorrect number of arguments; expected %d, got %d", len(sig.Params), len(args))
+		return
+	}
+	for i, arg := range args {
+		at := typeOf(arg)
+		if at == nil {
+			continue
+		}
+		if i < len(sig.Params) {
+			if !at.Equal(sig.Params[i]) {
+				v.errorf(user, "type %v of argument %d does not match parameter type %v", at, i, sig.Params[i])
+			}
+		} else if !isFirstClass(at) {
+			v.errorf(user, "invalid variadic argument type %v", at)
+		}
+	}
+	if typ != nil && !typ.Equal(sig.RetType) {
+		v.errorf(user, "result type %v does not match callee return type %v", typ, sig.RetType)
+	}
+}
+
+// verifyLandingPad verifies the given landingpad instruction.
+func (v *verifier) verifyLandingPad(inst *ir.InstLandingPad) {
+	v.verifyPersonality(inst, "landingpad")
+	if !inst.Cleanup && len(inst.Clauses) == 0 {
+		v.errorf(inst, "landingpad without clauses must be a cleanup")
+	}
+	for _, clause := range inst.Clauses {
+		if clause == nil || clause.X == nil {
+			v.errorf(inst, "missing landingpad clause operand")
+			continue
+		}
+		t := typeOf(clause.X)
+		switch clause.Type {
+		case enum.ClauseTypeCatch:
+			if t != nil && !types.IsPointer(t) {
+				v.errorf(inst, "invalid catch clause type %v; expected pointer type", t)
+			}
+		case enum.ClauseTypeFilter:
+			if t != nil && !types.IsArray(t) {
+				v.errorf(inst, "invalid filter clause type %v; expected array type", t)
+			}
+		}
+	}
+}
+
+// verifyParentPad verifies that the given parent pad is an exception pad or
+// the none token.
+func (v *verifier) verifyParentPad(user interface{}, parentPad value.Value) {
+	switch parentPad.(type) {
+	case *ir.InstCatchPad, *ir.InstCleanupPad, *ir.TermCatchSwitch, *constant.NoneToken:
+		// valid parent pad.
+	default:
+		v.errorf(user, "invalid parent pad %T; expected exception pad or none", parentPad)
+	}
+}
+
+// verifyPersonality verifies that the function containing the given exception
+// handling instruction has a personality function.
+func (v *verifier) verifyPersonality(user interface{}, name string) {
+	if v.f.Personality == nil {
+		v.errorf(user, "%s in function without personality", name)
+	}
+}
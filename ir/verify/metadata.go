@@ -0,0 +1,137 @@
+package verify
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/metadata"
+	"github.com/llir/llvm/ir/types"
+)
+
+// verifyGlobalAttachments verifies the metadata attachments of the given global
+// variable.
+func (v *verifier) verifyGlobalAttachments(g *ir.Global, mds ir.Metadata) {
+	for _, md := range mds {
+		if !v.verifyAttachment(g, md) {
+			continue
+		}
+		switch md.Name {
+		case "dbg":
+			if _, ok := md.Node.(*metadata.DIGlobalVariableExpression); !ok {
+				v.errorf(md, "invalid !dbg attachment of global variable; expected *metadata.DIGlobalVariableExpression, got %T", md.Node)
+			}
+		}
+	}
+}
+
+// verifyFuncAttachments verifies the metadata attachments of the given
+// function.
+func (v *verifier) verifyFuncAttachments(f *ir.Func) {
+	for _, md := range f.Metadata {
+		if !v.verifyAttachment(f, md) {
+			continue
+		}
+		switch md.Name {
+		case "dbg":
+			if _, ok := md.Node.(*metadata.DISubprogram); !ok {
+				v.errorf(md, "invalid !dbg attachment of function; expected *metadata.DISubprogram, got %T", md.Node)
+			}
+		}
+	}
+}
+
+// verifyInstAttachments verifies the metadata attachments of the given
+// instruction or terminator.
+func (v *verifier) verifyInstAttachments(inst interface{}) {
+	attacher, ok := inst.(interface {
+		MDAttachments() []*metadata.Attachment
+	})
+	if !ok {
+		return
+	}
+	for _, md := range attacher.MDAttachments() {
+		if !v.verifyAttachment(inst, md) {
+			continue
+		}
+		switch md.Name {
+		case "dbg":
+			if _, ok := md.Node.(*metadata.DILocation); !ok {
+				v.errorf(md, "invalid !dbg attachment of instruction; expected *metadata.DILocation, got %T", md.Node)
+			}
+		case "range":
+			v.verifyRange(inst, md)
+		case "nonnull":
+			load, ok := inst.(*ir.InstLoad)
+			if !ok {
+				v.errorf(md, "!nonnull attachment only valid on load instructions")
+				continue
+			}
+			if !types.IsPointer(load.ElemType) {
+				v.errorf(md, "!nonnull attachment of load of non-pointer type %v", load.ElemType)
+			}
+		case "tbaa":
+			switch inst.(type) {
+			case *ir.InstLoad, *ir.InstStore, *ir.InstAtomicRMW, *ir.InstCmpXchg, *ir.InstVAArg, *ir.InstCall, *ir.TermInvoke:
+				// valid.
+			default:
+				v.errorf(md, "!tbaa attachment only valid on memory access instructions and calls")
+			}
+		}
+	}
+}
+
+// verifyAttachment verifies the name and node of the given metadata attachment
+// of entity. The boolean return value indicates success.
+func (v *verifier) verifyAttachment(entity interface{}, md *metadata.Attachment) bool {
+	if md == nil {
+		v.errorf(entity, "missing metadata attachment")
+		return false
+	}
+	if len(md.Name) == 0 {
+		v.errorf(entity, "missing metadata attachment name")
+		return false
+	}
+	if md.Node == nil {
+		v.errorf(entity, "missing node of metadata attachment !%s", md.Name)
+		return false
+	}
+	return true
+}
+
+// verifyRange verifies the given !range metadata attachment of inst.
+func (v *verifier) verifyRange(inst interface{}, md *metadata.Attachment) {
+	var typ types.Type
+	switch inst := inst.(type) {
+	case *ir.InstLoad:
+		typ = inst.ElemType
+	case *ir.InstCall:
+		typ = inst.Typ
+	case *ir.TermInvoke:
+		typ = inst.Typ
+	default:
+		v.errorf(md, "!range attachment only valid on load, call and invoke instructions")
+		return
+	}
+	if typ != nil && !isIntOrIntVector(typ) {
+		v.errorf(md, "!range attachment of non-integer type %v", typ)
+		return
+	}
+	tuple, ok := md.Node.(*metadata.Tuple)
+	if !ok {
+		v.errorf(md, "invalid !range attachment; expected *metadata.Tuple, got %T", md.Node)
+		return
+	}
+	if len(tuple.Fields) == 0 || len(tuple.Fields)%2 != 0 {
+		v.errorf(md, "invalid number of !range fields (%d); expected non-zero multiple of two", len(tuple.Fields))
+		return
+	}
+	for _, field := range tuple.Fields {
+		x, ok := field.(*constant.Int)
+		if !ok {
+			v.errorf(md, "invalid !range field %v; expected integer constant", field)
+			continue
+		}
+		if typ != nil && !x.Typ.Equal(scalarType(typ)) {
+			v.errorf(md, "type %v of !range field does not match type %v", x.Typ, scalarType(typ))
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package verify
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+)
+
+// verifyModule verifies the module of the verifier.
+func (v *verifier) verifyModule() {
+	v.verifyGlobalNames()
+	for _, g := range v.m.Globals {
+		v.verifyGlobal(g)
+	}
+	for _, a := range v.m.Aliases {
+		v.verifyAlias(a)
+	}
+	for _, i := range v.m.IFuncs {
+		v.verifyIFunc(i)
+	}
+	for _, f := range v.m.Funcs {
+		v.verifyFunc(f)
+	}
+}
+
+// verifyGlobalNames verifies that the global identifiers of the module are
+// unique.
+func (v *verifier) verifyGlobalNames() {
+	names := make(map[string]bool)
+	check := func(entity interface{}, ident ir.GlobalIdent) {
+		if ident.IsUnnamed() {
+			// Global IDs are assigned when printing the module.
+			return
+		}
+		if names[ident.GlobalName] {
+			v.errorf(entity, "global identifier %q already present", ident.Ident())
+			return
+		}
+		names[ident.GlobalName] = true
+	}
+	for _, g := range v.m.Globals {
+		check(g, g.GlobalIdent)
+	}
+	for _, f := range v.m.Funcs {
+		check(f, f.GlobalIdent)
+	}
+	for _, a := range v.m.Aliases {
+		check(a, a.GlobalIdent)
+	}
+	for _, i := range v.m.IFuncs {
+		check(i, i.GlobalIdent)
+	}
+}
+
+// verifyGlobal verifies the given global variable.
+func (v *verifier) verifyGlobal(g *ir.Global) {
+	if g.ContentType == nil {
+		v.errorf(g, "missing content type")
+		return
+	}
+	if g.Init != nil && !isSized(g.ContentType) {
+		v.errorf(g, "invalid content type %v; expected sized type", g.ContentType)
+	}
+	if g.Typ != nil && !g.Typ.IsOpaque() && !g.Typ.ElemType.Equal(g.ContentType) {
+		v.errorf(g, "pointer type %v does not match content type %v", g.Typ, g.ContentType)
+	}
+	if g.Init == nil {
+		if !isDeclLinkage(g.Linkage) {
+			v.errorf(g, "global variable declaration with invalid linkage %q; expected external or extern_weak", g.Linkage)
+		}
+	} else {
+		if !g.Init.Type().Equal(g.ContentType) {
+			v.errorf(g, "initializer type %v does not match content type %v", g.Init.Type(), g.ContentType)
+		}
+		if g.Linkage == enum.LinkageExternWeak {
+			v.errorf(g, "global variable definition with extern_weak linkage")
+		}
+	}
+	v.verifyGlobalAttachments(g, g.Metadata)
+}
+
+// verifyAlias verifies the given alias.
+func (v *verifier) verifyAlias(a *ir.Alias) {
+	if a.Aliasee == nil {
+		v.errorf(a, "missing aliasee")
+		return
+	}
+	if !types.IsPointer(a.Aliasee.Type()) {
+		v.errorf(a, "invalid aliasee type %v; expected pointer type", a.Aliasee.Type())
+	}
+	switch a.Linkage {
+	case enum.LinkageNone, enum.LinkageExternal, enum.LinkageInternal, enum.LinkagePrivate, enum.LinkageWeak, enum.LinkageWeakODR, enum.LinkageLinkOnce, enum.LinkageLinkOnceODR, enum.LinkageAvailableExternally:
+		// valid linkage.
+	default:
+		v.errorf(a, "alias with invalid linkage %q", a.Linkage)
+	}
+}
+
+// verifyIFunc verifies the given IFunc.
+func (v *verifier) verifyIFunc(i *ir.IFunc) {
+	if i.Resolver == nil {
+		v.errorf(i, "missing resolver")
+		return
+	}
+	if !types.IsPointer(i.Resolver.Type()) {
+		v.errorf(i, "invalid resolver type %v; expected pointer type", i.Resolver.Type())
+	}
+}
+
+// ### [ Helper functions ] ####################################################
+
+// isDeclLinkage reports whether the given linkage is valid for declarations.
+func isDeclLinkage(linkage enum.Linkage) bool {
+	switch linkage {
+	case enum.LinkageNone, enum.LinkageExternal, enum.LinkageExternWeak:
+		return true
+	default:
+		return false
+	}
+}
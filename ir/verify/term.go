@@ -0,0 +1,190 @@
+package verify
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// verifyTerm verifies the operand types and successors of the given
+// terminator.
+func (v *verifier) verifyTerm(term ir.Terminator) {
+	switch term := term.(type) {
+	case *ir.TermRet:
+		v.verifyRet(term)
+	case *ir.TermBr:
+		v.verifyTarget(term, term.Target)
+	case *ir.TermCondBr:
+		if t := typeOf(term.Cond); t != nil && !types.Equal(t, types.I1) {
+			v.errorf(term, "invalid condition type %v; expected i1", t)
+		}
+		v.verifyTarget(term, term.TargetTrue)
+		v.verifyTarget(term, term.TargetFalse)
+	case *ir.TermSwitch:
+		v.verifySwitch(term)
+	case *ir.TermIndirectBr:
+		if t := typeOf(term.Addr); t != nil && !types.IsPointer(t) {
+			v.errorf(term, "invalid address type %v; expected pointer type", t)
+		}
+		for _, target := range term.ValidTargets {
+			v.verifyTarget(term, target)
+		}
+	case *ir.TermInvoke:
+		v.verifyCall(term, term.Invokee, term.InvokeeSig, term.Typ, term.Args)
+		v.verifyTarget(term, term.NormalRetTarget)
+		if v.verifyTarget(term, term.ExceptionRetTarget) && !isEHPad(term.ExceptionRetTarget.(*ir.Block)) {
+			v.errorf(term, "unwind destination %s is not an exception handling pad", term.ExceptionRetTarget.Ident())
+		}
+	case *ir.TermCallBr:
+		v.verifyCall(term, term.Callee, term.CalleeSig, term.Typ, term.Args)
+		v.verifyTarget(term, term.NormalRetTarget)
+		for _, target := range term.OtherRetTargets {
+			v.verifyTarget(term, target)
+		}
+	case *ir.TermResume:
+		v.verifyPersonality(term, "resume")
+	case *ir.TermCatchSwitch:
+		v.verifyPersonality(term, "catchswitch")
+		v.verifyParentPad(term, term.ParentPad)
+		if len(term.Handlers) == 0 {
+			v.errorf(term, "catchswitch without handlers")
+		}
+		for _, handler := range term.Handlers {
+			if !v.verifyTarget(term, handler) {
+				continue
+			}
+			if inst := firstNonPhi(handler.(*ir.Block)); inst == nil || !isCatchPad(inst) {
+				v.errorf(term, "handler %s does not begin with a catchpad", handler.Ident())
+			}
+		}
+		v.verifyUnwindTarget(term, term.DefaultUnwindTarget)
+	case *ir.TermCatchRet:
+		if _, ok := term.CatchPad.(*ir.InstCatchPad); !ok {
+			v.errorf(term, "invalid catchpad operand; expected *ir.InstCatchPad, got %T", term.CatchPad)
+		}
+		v.verifyTarget(term, term.Target)
+	case *ir.TermCleanupRet:
+		if _, ok := term.CleanupPad.(*ir.InstCleanupPad); !ok {
+			v.errorf(term, "invalid cleanuppad operand; expected *ir.InstCleanupPad, got %T", term.CleanupPad)
+		}
+		v.verifyUnwindTarget(term, term.UnwindTarget)
+	case *ir.TermUnreachable:
+		// nothing to do.
+	default:
+		v.errorf(term, "support for terminator %T not yet implemented", term)
+	}
+}
+
+// verifyRet verifies the given ret terminator against the return type of the
+// function.
+func (v *verifier) verifyRet(term *ir.TermRet) {
+	retType := v.f.Sig.RetType
+	if term.X == nil {
+		if retType != nil && !types.IsVoid(retType) {
+			v.errorf(term, "missing return value of type %v", retType)
+		}
+		return
+	}
+	t := typeOf(term.X)
+	if t == nil || retType == nil {
+		return
+	}
+	if types.IsVoid(retType) {
+		v.errorf(term, "return value in function with void return type")
+		return
+	}
+	if !t.Equal(retType) {
+		v.errorf(term, "return value type %v does not match function return type %v", t, retType)
+	}
+}
+
+// verifySwitch verifies the given switch terminator.
+func (v *verifier) verifySwitch(term *ir.TermSwitch) {
+	v.verifyTarget(term, term.TargetDefault)
+	t := typeOf(term.X)
+	if t != nil && !types.IsInt(t) {
+		v.errorf(term, "invalid switch condition type %v; expected integer type", t)
+		t = nil
+	}
+	cases := make(map[string]bool)
+	for _, c := range term.Cases {
+		if c == nil || c.X == nil {
+			v.errorf(term, "missing switch case value")
+			continue
+		}
+		v.verifyTarget(term, c.Target)
+		if _, ok := c.X.(constant.Constant); !ok {
+			v.errorf(term, "invalid switch case value %s; expected constant", c.X.Ident())
+			continue
+		}
+		if ct := typeOf(c.X); t != nil && ct != nil && !ct.Equal(t) {
+			v.errorf(term, "type %v of switch case value %s does not match condition type %v", ct, c.X.Ident(), t)
+		}
+		key := c.X.Ident()
+		if cases[key] {
+			v.errorf(term, "duplicate switch case value %s", key)
+		}
+		cases[key] = true
+	}
+}
+
+// verifyTarget verifies that the given branch target is a basic block. The
+// boolean return value indicates success.
+func (v *verifier) verifyTarget(term ir.Terminator, target value.Value) bool {
+	if target == nil {
+		// Missing operands are reported by verifyOperands.
+		return false
+	}
+	if _, ok := target.(*ir.Block); !ok {
+		v.errorf(term, "invalid branch target %s; expected *ir.Block, got %T", target.Ident(), target)
+		return false
+	}
+	return true
+}
+
+// verifyUnwindTarget verifies that the given unwind target (if present) is an
+// exception handling pad.
+func (v *verifier) verifyUnwindTarget(term ir.Terminator, target value.Value) {
+	if target == nil {
+		// Unwind to caller.
+		return
+	}
+	if v.verifyTarget(term, target) && !isEHPad(target.(*ir.Block)) {
+		v.errorf(term, "unwind destination %s is not an exception handling pad", target.Ident())
+	}
+}
+
+// ### [ Helper functions ] ####################################################
+
+// firstNonPhi returns the first non-phi instruction of the given basic block;
+// or nil if not present.
+func firstNonPhi(block *ir.Block) ir.Instruction {
+	for _, inst := range block.Insts {
+		if _, ok := inst.(*ir.InstPhi); !ok {
+			return inst
+		}
+	}
+	return nil
+}
+
+// isEHPad reports whether the given basic block is an exception handling pad;
+// i.e. begins with a landingpad, catchpad or cleanuppad instruction, or is
+// terminated by a catchswitch terminator.
+func isEHPad(block *ir.Block) bool {
+	switch firstNonPhi(block).(type) {
+	case *ir.InstLandingPad, *ir.InstCatchPad, *ir.InstCleanupPad:
+		return true
+	case nil:
+		_, ok := block.Term.(*ir.TermCatchSwitch)
+		return ok
+	default:
+		return false
+	}
+}
+
+// isCatchPad reports whether the given instruction is a catchpad instruction.
+func isCatchPad(inst ir.Instruction) bool {
+	_, ok := inst.(*ir.InstCatchPad)
+	return ok
+}
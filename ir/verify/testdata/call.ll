@@ -0,0 +1,8 @@
+declare i32 @g(i32)
+
+define void @f() {
+entry:
+	%x = call i32 @g(i64 1)
+	%y = call i32 @g()
+	ret void
+}
@@ -0,0 +1,11 @@
+define i32 @f(i1 %cond) {
+entry:
+	br i1 %cond, label %a, label %b
+
+a:
+	%x = add i32 1, 2
+	br label %b
+
+b:
+	ret i32 %x
+}
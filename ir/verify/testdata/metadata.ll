@@ -0,0 +1,10 @@
+define i32 @f(i32* %p) {
+entry:
+	%x = load i32, i32* %p, !dbg !0, !nonnull !1, !range !2
+	ret i32 %x, !range !3
+}
+
+!0 = !{}
+!1 = !{}
+!2 = !{i64 0, i64 10}
+!3 = !{i32 0}
@@ -0,0 +1,12 @@
+define i32 @f(i1 %cond) {
+entry:
+	br i1 %cond, label %a, label %b
+
+a:
+	br label %b
+
+b:
+	%x = add i32 1, 2
+	%y = phi i32 [ 1, %a ]
+	ret i32 %y
+}
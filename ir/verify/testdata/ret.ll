@@ -0,0 +1,9 @@
+define i32 @f() {
+entry:
+	ret void
+}
+
+define void @g() {
+entry:
+	ret i32 1
+}
@@ -0,0 +1,11 @@
+define void @f(i32 %x) {
+entry:
+	switch i32 %x, label %exit [
+		i64 1, label %exit
+		i32 2, label %exit
+		i32 2, label %exit
+	]
+
+exit:
+	ret void
+}
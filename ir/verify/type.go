@@ -0,0 +1,138 @@
+package verify
+
+import (
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// typeOf returns the type of the given value; or nil if the type is not
+// computable (e.g. missing operands of a malformed instruction).
+func typeOf(x value.Value) (t types.Type) {
+	if x == nil {
+		return nil
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			t = nil
+		}
+	}()
+	return x.Type()
+}
+
+// isFirstClass reports whether the given type is a first class type which may
+// be produced by instructions.
+func isFirstClass(t types.Type) bool {
+	switch t.(type) {
+	case nil, *types.VoidType, *types.FuncType, *types.LabelType, *types.MetadataType:
+		return false
+	default:
+		return true
+	}
+}
+
+// isSized reports whether the given type has a size.
+func isSized(t types.Type) bool {
+	switch t := t.(type) {
+	case *types.IntType, *types.FloatType, *types.PointerType, *types.MMXType:
+		return true
+	case *types.VectorType:
+		return isSized(t.ElemType)
+	case *types.ArrayType:
+		return isSized(t.ElemType)
+	case *types.StructType:
+		if t.Opaque {
+			return false
+		}
+		for _, field := range t.Fields {
+			if !isSized(field) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// scalarType returns the element type of the given vector type, or the type
+// itself if not a vector type.
+func scalarType(t types.Type) types.Type {
+	if t, ok := t.(*types.VectorType); ok {
+		return t.ElemType
+	}
+	return t
+}
+
+// isIntOrIntVector reports whether the given type is an integer type or a
+// vector of integers.
+func isIntOrIntVector(t types.Type) bool {
+	return types.IsInt(scalarType(t))
+}
+
+// isFloatOrFloatVector reports whether the given type is a floating-point type
+// or a vector of floating-point values.
+func isFloatOrFloatVector(t types.Type) bool {
+	return types.IsFloat(scalarType(t))
+}
+
+// isPtrOrPtrVector reports whether the given type is a pointer type or a vector
+// of pointers.
+func isPtrOrPtrVector(t types.Type) bool {
+	return types.IsPointer(scalarType(t))
+}
+
+// isBoolOrBoolVector reports whether the given type is i1 or a vector of i1.
+func isBoolOrBoolVector(t types.Type) bool {
+	i, ok := scalarType(t).(*types.IntType)
+	return ok && i.BitSize == 1
+}
+
+// sameShape reports whether the given types are both scalar types or both
+// vector types of the same length.
+func sameShape(t, u types.Type) bool {
+	tv, ok1 := t.(*types.VectorType)
+	uv, ok2 := u.(*types.VectorType)
+	if ok1 != ok2 {
+		return false
+	}
+	if !ok1 {
+		return true
+	}
+	return tv.Len == uv.Len && tv.Scalable == uv.Scalable
+}
+
+// bitSize returns the size in bits of the given primitive type or vector of
+// primitive types; or 0 if not a primitive type.
+func bitSize(t types.Type) uint64 {
+	switch t := t.(type) {
+	case *types.IntType:
+		return t.BitSize
+	case *types.FloatType:
+		switch t.Kind {
+		case types.FloatKindHalf:
+			return 16
+		case types.FloatKindFloat:
+			return 32
+		case types.FloatKindDouble:
+			return 64
+		case types.FloatKindX86_FP80:
+			return 80
+		case types.FloatKindFP128, types.FloatKindPPC_FP128:
+			return 128
+		}
+	case *types.MMXType:
+		return 64
+	case *types.VectorType:
+		return t.Len * bitSize(t.ElemType)
+	}
+	return 0
+}
+
+// addrSpaceOf returns the address space of the given pointer type or vector of
+// pointers.
+func addrSpaceOf(t types.Type) types.AddrSpace {
+	if t, ok := scalarType(t).(*types.PointerType); ok {
+		return t.AddrSpace
+	}
+	return 0
+}
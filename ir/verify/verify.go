@@ -0,0 +1,211 @@
+// Package verify implements a verifier for LLVM IR modules.
+//
+// The verifier checks the well-formedness of LLVM IR modules, in the spirit of
+// the Verifier of LLVM; such as terminator placement, SSA dominance of
+// instruction operands, consistency of phi incoming values, operand type rules
+// of instructions, call signatures and the validity of metadata attachments.
+//
+// Verification does not stop at the first problem; all problems found are
+// reported as an ErrorList.
+package verify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/cfg"
+	"github.com/llir/llvm/ir/dom"
+)
+
+// Module verifies the given LLVM IR module. The returned error is either nil
+// or an ErrorList describing each problem found.
+func Module(m *ir.Module) error {
+	// Materialize function bodies if lazily parsed.
+	if err := m.Materialize(); err != nil {
+		return err
+	}
+	v := newVerifier(m)
+	v.verifyModule()
+	return v.errs.Err()
+}
+
+// Func verifies the given LLVM IR function. The returned error is either nil
+// or an ErrorList describing each problem found.
+//
+// References to global values are not validated against a parent module.
+func Func(f *ir.Func) error {
+	// Materialize function body if lazily parsed.
+	if err := f.Materialize(); err != nil {
+		return err
+	}
+	v := newVerifier(nil)
+	v.verifyFunc(f)
+	return v.errs.Err()
+}
+
+// === [ Errors ] ==============================================================
+
+// Error is a verification error describing a problem with an LLVM IR entity.
+type Error struct {
+	// Function containing the invalid entity; or nil if not contained within a
+	// function.
+	Func *ir.Func
+	// Basic block containing the invalid entity; or nil if not contained within
+	// a basic block.
+	Block *ir.Block
+	// Invalid entity; e.g. *ir.Global, *ir.Func, *ir.Block, ir.Instruction,
+	// ir.Terminator or *metadata.Attachment.
+	Entity interface{}
+	// Description of the problem.
+	Msg string
+}
+
+// Error returns a string representation of the verification error.
+//
+// The string representation has the following format.
+//
+//	function @f: block %entry: %x = add i32 %y, %z: error message
+func (e *Error) Error() string {
+	var parts []string
+	if e.Func != nil {
+		parts = append(parts, fmt.Sprintf("function %s", e.Func.Ident()))
+	}
+	if e.Block != nil {
+		parts = append(parts, fmt.Sprintf("block %s", e.Block.Ident()))
+	}
+	switch e.Entity.(type) {
+	case nil, *ir.Func, *ir.Block:
+		// Already part of the context.
+	default:
+		parts = append(parts, describe(e.Entity))
+	}
+	parts = append(parts, e.Msg)
+	return strings.Join(parts, ": ")
+}
+
+// ErrorList is a list of verification errors, in the order encountered while
+// traversing the module.
+type ErrorList []*Error
+
+// Error returns a string representation of the list of verification errors,
+// with one error per line.
+func (es ErrorList) Error() string {
+	switch len(es) {
+	case 0:
+		return "no errors"
+	case 1:
+		return es[0].Error()
+	}
+	buf := &strings.Builder{}
+	for i, e := range es {
+		if i != 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(e.Error())
+	}
+	return buf.String()
+}
+
+// Err returns an error equivalent to the error list; nil if the list is empty.
+func (es ErrorList) Err() error {
+	if len(es) == 0 {
+		return nil
+	}
+	return es
+}
+
+// === [ Verifier ] ============================================================
+
+// verifier keeps track of the state of the verifier.
+type verifier struct {
+	// Module being verified; nil if verifying a single function.
+	m *ir.Module
+	// Global values of the module; used to validate operands.
+	globals map[interface{}]bool
+	// Verification errors.
+	errs ErrorList
+
+	// Per-function state.
+
+	// Function being verified.
+	f *ir.Func
+	// Basic block being verified.
+	block *ir.Block
+	// Control flow graph of the function being verified.
+	cfg *cfg.Graph
+	// Dominator tree of the function being verified.
+	dom *dom.Tree
+	// Position of instructions and terminators defined in the function being
+	// verified.
+	pos map[interface{}]position
+	// Parameters of the function being verified.
+	params map[*ir.Param]bool
+	// Basic blocks of the function being verified.
+	blocks map[*ir.Block]bool
+}
+
+// newVerifier returns a new verifier for the given module (nil if verifying a
+// single function).
+func newVerifier(m *ir.Module) *verifier {
+	v := &verifier{m: m}
+	if m != nil {
+		v.globals = make(map[interface{}]bool)
+		for _, g := range m.Globals {
+			v.globals[g] = true
+		}
+		for _, f := range m.Funcs {
+			v.globals[f] = true
+		}
+		for _, a := range m.Aliases {
+			v.globals[a] = true
+		}
+		for _, i := range m.IFuncs {
+			v.globals[i] = true
+		}
+	}
+	return v
+}
+
+// errorf records a verification error of the given entity.
+func (v *verifier) errorf(entity interface{}, format string, args ...interface{}) {
+	e := &Error{
+		Func:   v.f,
+		Block:  v.block,
+		Entity: entity,
+		Msg:    fmt.Sprintf(format, args...),
+	}
+	v.errs = append(v.errs, e)
+}
+
+// ### [ Helper functions ] ####################################################
+
+// describe returns a short description of the given entity, for use in error
+// messages. Only the first line of multi-line entities (e.g. switch
+// terminators) is included.
+func describe(entity interface{}) string {
+	s := llString(entity)
+	if pos := strings.IndexByte(s, '\n'); pos != -1 {
+		return s[:pos] + " ..."
+	}
+	return s
+}
+
+// llString returns the LLVM syntax representation of the given entity.
+func llString(entity interface{}) (s string) {
+	// The LLVM syntax representation of malformed IR may not be computable
+	// (e.g. missing types); fall back to the Go type of the entity.
+	defer func() {
+		if e := recover(); e != nil {
+			s = fmt.Sprintf("%T", entity)
+		}
+	}()
+	switch entity := entity.(type) {
+	case ir.LLStringer:
+		return entity.LLString()
+	case fmt.Stringer:
+		return entity.String()
+	default:
+		return fmt.Sprintf("%T", entity)
+	}
+}
@@ -0,0 +1,165 @@
+package verify_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/llir/llvm/asm"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/verify"
+)
+
+func TestModuleValid(t *testing.T) {
+	golden := []struct {
+		path string
+	}{
+		{path: "../../asm/testdata/hexfloat.ll"},
+		{path: "../../asm/testdata/hexint.ll"},
+		{path: "../../asm/testdata/inst_aggregate.ll"},
+		{path: "../../asm/testdata/inst_binary.ll"},
+		{path: "../../asm/testdata/inst_bitwise.ll"},
+		{path: "../../asm/testdata/inst_conversion.ll"},
+		{path: "../../asm/testdata/inst_memory.ll"},
+		{path: "../../asm/testdata/inst_vector.ll"},
+		{path: "../../asm/testdata/terminator.ll"},
+		{path: "../../asm/testdata/diexpression.ll"},
+		{path: "../../asm/testdata/param_attrs.ll"},
+		{path: "../../asm/testdata/func_align.ll"},
+		{path: "../../asm/testdata/global_align.ll"},
+		{path: "../../bitcode/testdata/debug_info.ll"},
+		{path: "../../bitcode/testdata/misc.ll"},
+	}
+	for _, g := range golden {
+		m, err := asm.ParseFile(g.path)
+		if err != nil {
+			t.Errorf("unable to parse %q; %+v", g.path, err)
+			continue
+		}
+		if err := verify.Module(m); err != nil {
+			t.Errorf("%q: unexpected verification errors:\n%v", g.path, err)
+		}
+	}
+}
+
+func TestModuleInvalid(t *testing.T) {
+	golden := []struct {
+		path string
+		want []string
+	}{
+		// Use of instruction before its definition.
+		{
+			path: "testdata/use_before_def.ll",
+			want: []string{
+				"function @f: block %entry: %y = add i32 %z, 1: operand %z does not dominate use",
+			},
+		},
+		// Use of instruction not dominated by its definition.
+		{
+			path: "testdata/dominance.ll",
+			want: []string{
+				"function @f: block %b: ret i32 %x: operand %x does not dominate use",
+			},
+		},
+		// Phi instructions not grouped at top of basic block, and missing
+		// incoming values.
+		{
+			path: "testdata/phi.ll",
+			want: []string{
+				"function @f: block %b: %y = phi i32 [ 1, %a ]: phi instruction not grouped at top of basic block",
+				"function @f: block %b: %y = phi i32 [ 1, %a ]: missing incoming value for predecessor %entry",
+			},
+		},
+		// Mismatched and duplicate switch case values.
+		{
+			path: "testdata/switch.ll",
+			want: []string{
+				"function @f: block %entry: switch i32 %x, label %exit [ ...: type i64 of switch case value 1 does not match condition type i32",
+				"function @f: block %entry: switch i32 %x, label %exit [ ...: duplicate switch case value 2",
+			},
+		},
+		// Call arguments not matching callee signature.
+		{
+			path: "testdata/call.ll",
+			want: []string{
+				"function @f: block %entry: %x = call i32 @g(i64 1): type i64 of argument 0 does not match parameter type i32",
+				"function @f: block %entry: %y = call i32 @g(): incorrect number of arguments; expected 1, got 0",
+			},
+		},
+		// Return values not matching function return type.
+		{
+			path: "testdata/ret.ll",
+			want: []string{
+				"function @f: block %entry: ret void: missing return value of type i32",
+				"function @g: block %entry: ret i32 1: return value in function with void return type",
+			},
+		},
+		// Invalid metadata attachments.
+		{
+			path: "testdata/metadata.ll",
+			want: []string{
+				"function @f: block %entry: !dbg !0: invalid !dbg attachment of instruction; expected *metadata.DILocation, got *metadata.Tuple",
+				"function @f: block %entry: !nonnull !1: !nonnull attachment of load of non-pointer type i32",
+				"function @f: block %entry: !range !2: type i64 of !range field does not match type i32",
+				"function @f: block %entry: !range !2: type i64 of !range field does not match type i32",
+				"function @f: block %entry: !range !3: !range attachment only valid on load, call and invoke instructions",
+			},
+		},
+		// Invalid exception handling.
+		{
+			path: "../../asm/testdata/inst_other.ll",
+			want: []string{
+				"function @f: block %baz: %3 = phi i32 [ 10, %foo ], [ 20, %bar ], [ 30, %baz ]: incoming block %baz is not a predecessor of basic block",
+				"function @f: block %baz: %3 = phi i32 [ 10, %foo ], [ 20, %bar ], [ 30, %baz ]: missing incoming value for predecessor %0",
+				"function @f: block %baz: %7 = landingpad { i8*, i32 } ...: exception handling pad must be the first non-phi instruction of basic block",
+				"function @f: block %baz: %7 = landingpad { i8*, i32 } ...: landingpad in function without personality",
+				"function @f: block %handler0: %8 = catchpad within %cs [i8** null]: catchpad in function without personality",
+				"function @f: block %handler1: %9 = cleanuppad within %cs [i8** null]: cleanuppad in function without personality",
+				"function @f: block %dispatch: %cs = catchswitch within none [label %handler0, label %handler1] unwind to caller: catchswitch in function without personality",
+				"function @f: block %dispatch: %cs = catchswitch within none [label %handler0, label %handler1] unwind to caller: handler %handler1 does not begin with a catchpad",
+			},
+		},
+	}
+	for _, g := range golden {
+		m, err := asm.ParseFile(g.path)
+		if err != nil {
+			t.Errorf("unable to parse %q; %+v", g.path, err)
+			continue
+		}
+		err = verify.Module(m)
+		if err == nil {
+			t.Errorf("%q: expected verification errors, got none", g.path)
+			continue
+		}
+		got := strings.Split(err.Error(), "\n")
+		if diff := cmp.Diff(g.want, got); diff != "" {
+			t.Errorf("%q: verification errors mismatch (-want +got):\n%s", g.path, diff)
+		}
+	}
+}
+
+func TestFunc(t *testing.T) {
+	// Function with missing terminator.
+	f := ir.NewFunc("f", types.I32)
+	entry := f.NewBlock("entry")
+	entry.NewAdd(constant.NewInt(types.I32, 1), constant.NewInt(types.I32, 2))
+	err := verify.Func(f)
+	es, ok := err.(verify.ErrorList)
+	if !ok {
+		t.Fatalf("invalid error type; expected verify.ErrorList, got %T", err)
+	}
+	if len(es) != 1 {
+		t.Fatalf("invalid number of errors; expected 1, got %d:\n%v", len(es), err)
+	}
+	const want = "missing terminator"
+	if es[0].Msg != want || es[0].Block != entry {
+		t.Errorf("invalid error; expected %q in block %s, got %v", want, entry.Ident(), es[0])
+	}
+	// Terminated function.
+	entry.NewRet(constant.NewInt(types.I32, 3))
+	if err := verify.Func(f); err != nil {
+		t.Errorf("unexpected verification errors:\n%v", err)
+	}
+}